@@ -3,18 +3,35 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
-	"kubegraph/config"
-	"kubegraph/pkg/logger"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	_ "k8s-graph/pkg/kubernetes/handlers" // registers the handlers
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+	"k8s-graph/pkg/site"
+	"k8s-graph/pkg/vulns"
 
 	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var (
@@ -25,8 +42,71 @@ var (
 	ctx         context.Context
 	showQuery   bool
 	clusterName string
+	team        string
 	showEmojis  bool
 	showRelated bool
+	quiet       bool
+
+	failThreshold int
+	resultCount   = -1 // number of results the command reported, -1 if it reports none
+
+	importDatabase  string
+	importBatchSize int
+	importForce     bool
+
+	graphDepth  int
+	graphFormat string
+	graphOutput string
+
+	impactDepth int
+
+	pathFromNamespace string
+	pathToNamespace   string
+	pathMaxHops       int
+
+	siteNamespace     string
+	siteOut           string
+	siteIncludeEvents bool
+
+	riskyRolesIncludeSystem bool
+
+	attackPathsIncludeSystem bool
+
+	saExposureIncludeSystem bool
+	saExposureMountedOnly   bool
+
+	unprotectedIncludeSystem bool
+
+	missingProbesIncludeSystem bool
+
+	incidentsOpenOnly bool
+
+	rolloutsKind string
+
+	driftKind string
+
+	spreadKind string
+
+	webhookExpiryDays int
+
+	topSortBy string
+
+	selector string
+
+	eventsType      string
+	eventsReason    string
+	eventsNamespace string
+	eventsInvolves  string
+	eventsSince     string
+
+	queriesFile      string
+	queryDescription string
+	queryParams      []string
+
+	efficiencyMaxUtilization float64
+	efficiencyIdleCPU        int64
+
+	securityProfile string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -53,6 +133,9 @@ Examples:
   # Use .env file
   kubegraph-cli --env-file .env nodes`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if offline(cmd) {
+			return nil
+		}
 		return initializeClient()
 	},
 }
@@ -64,10 +147,14 @@ var nodesCmd = &cobra.Command{
 	Long: `List nodes in the graph database. Without arguments, shows all node types
 and their counts. With a node type, lists nodes of that type.
 
+With -l/--selector, only the nodes whose labels match the selector are
+listed (see docs/promoted_labels.md).
+
 Examples:
   kubegraph-cli nodes                    # Show all node types
   kubegraph-cli nodes Pod 20             # Show 20 Pod nodes
-  kubegraph-cli nodes Service            # Show all Service nodes`,
+  kubegraph-cli nodes Service            # Show all Service nodes
+  kubegraph-cli nodes Deployment -l app.kubernetes.io/name=api,env!=dev`,
 	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		handleNodes(args)
@@ -95,7 +182,11 @@ Examples:
 var resourcesCmd = &cobra.Command{
 	Use:   "resources",
 	Short: "Show resource counts by type",
-	Long:  `Show a summary of all resource types and their counts in the database.`,
+	Long: `Show a summary of all resource types and their counts in the database.
+
+Examples:
+  kubegraph-cli resources                  # Count all resources
+  kubegraph-cli resources -l app=api       # Count the resources labeled app=api`,
 	Run: func(cmd *cobra.Command, args []string) {
 		handleResources()
 	},
@@ -109,7 +200,10 @@ var podsCmd = &cobra.Command{
 
 Examples:
   kubegraph-cli pods                    # Show all pods
-  kubegraph-cli pods default            # Show pods in default namespace`,
+  kubegraph-cli pods default            # Show pods in default namespace
+  kubegraph-cli pods --filter status!=Running --sort-by -name
+  kubegraph-cli pods shop -l app=api          # Show the pods labeled app=api in shop
+  kubegraph-cli pods --limit 50 --page 2      # Show pods 51 to 100`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		handlePods(args)
@@ -124,7 +218,8 @@ var servicesCmd = &cobra.Command{
 
 Examples:
   kubegraph-cli services                    # Show all services
-  kubegraph-cli services default            # Show services in default namespace`,
+  kubegraph-cli services default            # Show services in default namespace
+  kubegraph-cli services -l app.kubernetes.io/name=api  # Show services by label`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		handleServices(args)
@@ -139,7 +234,8 @@ var deploymentsCmd = &cobra.Command{
 
 Examples:
   kubegraph-cli deployments                    # Show all deployments
-  kubegraph-cli deployments default            # Show deployments in default namespace`,
+  kubegraph-cli deployments default            # Show deployments in default namespace
+  kubegraph-cli deployments -l env=prod,!canary  # Show deployments by label`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		handleDeployments(args)
@@ -150,11 +246,16 @@ Examples:
 var eventsCmd = &cobra.Command{
 	Use:   "events [limit]",
 	Short: "Show recent events",
-	Long: `Show recent events in the database. Optionally specify a limit.
+	Long: `Show recent events in the database, most recent first. Optionally specify a limit,
+and narrow the events down by type, reason, namespace, involved object or age.
 
 Examples:
   kubegraph-cli events                    # Show 20 recent events
-  kubegraph-cli events 50                 # Show 50 recent events`,
+  kubegraph-cli events 50                 # Show 50 recent events
+  kubegraph-cli events --type Warning --since 30m                 # Warnings of the last 30 minutes
+  kubegraph-cli events --reason BackOff --namespace shop
+  kubegraph-cli events --involves Pod/web-0                      # Events about a pod
+  kubegraph-cli events --filter message~back-off`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		handleEvents(args)
@@ -222,11 +323,66 @@ Examples:
 	},
 }
 
+// querySaveCmd represents the query save command
+var querySaveCmd = &cobra.Command{
+	Use:   "save <name> <cypher>",
+	Short: "Save a named query to the query library",
+	Long: `Save a Cypher query under a name in the query library, replacing any query with
+the same name. Queries take parameters as Cypher $parameters, given with --param when
+the query is run; $clusterName defaults to the selected cluster.
+
+The library is ~/.kubegraph-cli/queries.yaml, or the file given with --queries-file
+or KUBEGRAPH_QUERIES_FILE, which can be checked into a repository to share it.
+
+Examples:
+  kubegraph-cli query save pods-on-node "MATCH (p:Pod)-[:SCHEDULED_ON]->(n:Node {name: \$node}) RETURN p.namespace, p.name"
+  kubegraph-cli query save restarts "MATCH (p:Pod) WHERE p.restartCount > \$min RETURN p.name, p.restartCount" --description "Pods restarting often"`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleQuerySave(args[0], args[1])
+	},
+}
+
+// queryRunCmd represents the query run command
+var queryRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a query from the query library",
+	Long: `Run a named query from the query library. Each $parameter of the query is given
+with --param name=value; integers, floats and booleans are passed as such, other values
+as strings.
+
+Examples:
+  kubegraph-cli query run pods-on-node --param node=worker-1
+  kubegraph-cli query run restarts --param min=5 --cluster-name production`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArgs(completeSavedQueries),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleQueryRun(args[0])
+	},
+}
+
+// queryListCmd represents the query list command
+var queryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the queries in the query library",
+	Long: `List the named queries in the query library with their parameters.
+
+Examples:
+  kubegraph-cli query list
+  kubegraph-cli query list --queries-file ./team-queries.yaml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleQueryList()
+	},
+}
+
 // statsCmd represents the stats command
 var statsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show database statistics",
-	Long:  `Show comprehensive statistics about the database contents.`,
+	Long: `Show comprehensive statistics about the database contents: nodes per type and
+cluster, relationships per type, nodes without relationships, the last write per kind
+(when the audit trail is enabled) and the indexes and constraints.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		handleStats()
 	},
@@ -277,8 +433,9 @@ Examples:
 var rootPodsCmd = &cobra.Command{
 	Use:   "root-pods",
 	Short: "Find pods running as root",
-	Long: `Find pods that are running as root (runAsUser: 0) or have privileged security contexts.
-This command helps identify security risks in your Kubernetes cluster.
+	Long: `Find pods with a container whose effective runAsUser is 0, set on the container or
+inherited from the pod security context. Pods that set no user and do not set runAsNonRoot
+may also run as root, depending on their image; security-risks --profile restricted lists them.
 
 Examples:
   kubegraph-cli root-pods                    # Show all pods running as root
@@ -293,7 +450,7 @@ Examples:
 var privilegedPodsCmd = &cobra.Command{
 	Use:   "privileged-pods",
 	Short: "Find privileged pods",
-	Long: `Find pods that are running in privileged mode, which is a significant security risk.
+	Long: `Find pods with a privileged container, init container or ephemeral container.
 Privileged containers have access to host resources and can bypass security controls.
 
 Examples:
@@ -308,16 +465,19 @@ Examples:
 // securityRisksCmd represents the security-risks command
 var securityRisksCmd = &cobra.Command{
 	Use:   "security-risks",
-	Short: "Find pods with security risks",
-	Long: `Find pods with various security risks including:
-- Running as root (runAsUser: 0)
-- Privileged containers
-- Containers without read-only root filesystem
-- Containers with privilege escalation allowed
+	Short: "Find pods violating a Pod Security Standards profile",
+	Long: `Find pods violating the baseline or restricted Pod Security Standards profile, with
+the checks they fail. Pods are evaluated when they are synced, following the checks of
+the PodSecurity admission controller:
+- baseline: host namespaces, privileged containers, added capabilities, hostPath volumes,
+  host ports, AppArmor, SELinux, /proc mount type, Unconfined seccomp profiles and unsafe sysctls
+- restricted: the baseline checks, plus volume types, privilege escalation, runAsNonRoot,
+  runAsUser 0, a seccomp profile and dropping ALL capabilities
 
 Examples:
-  kubegraph-cli security-risks                    # Show all pods with security risks
-  kubegraph-cli security-risks --cluster-name my-cluster  # Show security risks for specific cluster`,
+  kubegraph-cli security-risks                                 # Pods violating the baseline profile
+  kubegraph-cli security-risks --profile restricted            # Pods violating the restricted profile
+  kubegraph-cli security-risks --cluster-name my-cluster       # Pods of a specific cluster`,
 	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		handleSecurityRisks()
@@ -330,6 +490,9 @@ var resourcePressureCmd = &cobra.Command{
 	Short: "Find nodes with resource pressure",
 	Long: `Find nodes that are under resource pressure based on CPU, memory, and disk usage.
 Thresholds are percentages (0-100). Default thresholds are 80% for CPU and memory, 85% for disk.
+CPU and memory pressure is the share of the allocatable resources of the node requested by
+the pods scheduled on it or, when k8s-graph polls metrics-server (--usage-metrics) and it is
+higher, actually used.
 
 Examples:
   kubegraph-cli resource-pressure                    # Show nodes with default thresholds (80% CPU/memory, 85% disk)
@@ -395,376 +558,5079 @@ Examples:
 	},
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
+// anomaliesCmd represents the anomalies command
+var anomaliesCmd = &cobra.Command{
+	Use:   "anomalies [limit]",
+	Short: "Show detected workload anomalies",
+	Long: `Show restart and warning event spikes detected by KubeGraph's anomaly detector.
+Anomalies are only recorded when KubeGraph runs with --anomaly-detection.
 
-	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubegraph-cli.yaml)")
-	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", ".env file (default is $HOME/.kubegraph-cli.env, or set KUBEGRAPH_ENV_FILE env var)")
-	rootCmd.PersistentFlags().String("uri", "", "Neo4j database URI (default: from NEO4J_URI env var)")
-	rootCmd.PersistentFlags().String("user", "", "Neo4j username (default: from NEO4J_USERNAME env var)")
-	rootCmd.PersistentFlags().String("pass", "", "Neo4j password (default: from NEO4J_PASSWORD env var)")
-	rootCmd.PersistentFlags().StringVar(&clusterName, "cluster-name", "", "Kubernetes cluster name to filter by")
-	rootCmd.PersistentFlags().String("log-level", "INFO", "Log level (DEBUG, INFO, WARN, ERROR)")
-	rootCmd.PersistentFlags().String("output", "table", "Output format: table, json, csv")
-	rootCmd.PersistentFlags().BoolVar(&showQuery, "show-query", false, "Show the executed Cypher query")
-	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode to show configuration details")
-	rootCmd.PersistentFlags().BoolVar(&showEmojis, "show-emojis", true, "Show emojis in output")
-	rootCmd.PersistentFlags().BoolVar(&showRelated, "related", false, "Show related resources when displaying resource details")
+Examples:
+  kubegraph-cli anomalies                    # Show 20 most recent anomalies
+  kubegraph-cli anomalies 50                 # Show 50 most recent anomalies`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleAnomalies(args)
+	},
+}
 
-	// Bind flags to viper
-	viper.BindPFlag("neo4j.uri", rootCmd.PersistentFlags().Lookup("uri"))
-	viper.BindPFlag("neo4j.user", rootCmd.PersistentFlags().Lookup("user"))
-	viper.BindPFlag("neo4j.pass", rootCmd.PersistentFlags().Lookup("pass"))
-	viper.BindPFlag("kubernetes.cluster", rootCmd.PersistentFlags().Lookup("cluster-name"))
-	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
-	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
-	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
-	viper.BindPFlag("show-emojis", rootCmd.PersistentFlags().Lookup("show-emojis"))
-	viper.BindPFlag("show-related", rootCmd.PersistentFlags().Lookup("related"))
+// incidentsCmd represents the incidents command
+var incidentsCmd = &cobra.Command{
+	Use:   "incidents [limit]",
+	Short: "Show incidents of correlated warning events",
+	Long: `Show incidents grouping the warning events of a workload and its pods,
+with their severity. Incidents are only recorded when KubeGraph runs with
+--event-correlation.
 
-	// Add subcommands
-	rootCmd.AddCommand(nodesCmd)
-	rootCmd.AddCommand(relationshipsCmd)
-	rootCmd.AddCommand(resourcesCmd)
-	rootCmd.AddCommand(podsCmd)
-	rootCmd.AddCommand(servicesCmd)
-	rootCmd.AddCommand(deploymentsCmd)
-	rootCmd.AddCommand(eventsCmd)
-	rootCmd.AddCommand(dbEventsCmd)
-	rootCmd.AddCommand(dbResourcesCmd)
-	rootCmd.AddCommand(clustersCmd)
-	rootCmd.AddCommand(queryCmd)
-	rootCmd.AddCommand(statsCmd)
-	rootCmd.AddCommand(healthCmd)
-	rootCmd.AddCommand(k8sNodesCmd)
-	rootCmd.AddCommand(neo4jDatabasesCmd)
-	rootCmd.AddCommand(rootPodsCmd)
-	rootCmd.AddCommand(privilegedPodsCmd)
-	rootCmd.AddCommand(securityRisksCmd)
-	rootCmd.AddCommand(resourcePressureCmd)
-	rootCmd.AddCommand(resourcePressureSummaryCmd)
-	rootCmd.AddCommand(debugDiskCmd)
-	rootCmd.AddCommand(resourceCmd)
+Examples:
+  kubegraph-cli incidents                    # Show the 20 most severe incidents
+  kubegraph-cli incidents --open             # Only show incidents still receiving warnings
+  kubegraph-cli incidents --filter severity=critical`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleIncidents(args)
+	},
 }
 
-// initConfig reads in config file and ENV variables if set
-func initConfig() {
-	// Load .env file first if specified or if default exists
-	loadEnvFile()
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <type> <name> [namespace]",
+	Short: "Show the version history of a resource",
+	Long: `Show how a resource changed over time. Versions are only recorded when
+KubeGraph runs with --history-mode.
 
-	if cfgFile != "" {
-		// Use config file from the flag
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory
-		home, err := os.UserHomeDir()
-		cobra.CheckErr(err)
+Examples:
+  kubegraph-cli history Pod my-pod                  # Show versions of my-pod
+  kubegraph-cli history Deployment api production   # Show versions of api in production`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleHistory(args)
+	},
+}
 
-		// Search config in home directory with name ".kubegraph-cli" (without extension)
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".kubegraph-cli")
-	}
+// rolloutsCmd represents the rollouts command
+var rolloutsCmd = &cobra.Command{
+	Use:   "rollouts <name> [namespace]",
+	Short: "Show the rollout history of a workload",
+	Long: `Show the revisions of a Deployment, newest first, with the container images of
+each revision and what changed from the previous one. Deployment revisions come from
+their ReplicaSets; StatefulSet and DaemonSet revisions, selected with --kind, from
+their ControllerRevisions. Only revisions still kept by the cluster are shown
+(revisionHistoryLimit).
 
-	// Read environment variables
-	viper.AutomaticEnv()
+Examples:
+  kubegraph-cli rollouts api production           # Rollout history of the api Deployment
+  kubegraph-cli rollouts db --kind StatefulSet    # Revisions of the db StatefulSet`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleRollouts(args)
+	},
+}
 
-	// Set defaults
-	viper.SetDefault("neo4j.uri", "neo4j://localhost:7687")
-	viper.SetDefault("neo4j.user", "neo4j")
-	viper.SetDefault("neo4j.pass", "password")
-	viper.SetDefault("log.level", "INFO")
-	viper.SetDefault("output", "table")
+// driftCmd represents the drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift <name> [namespace]",
+	Short: "Compare the pod template of a workload across clusters",
+	Long: `Compare the pod template of a Deployment, or of a StatefulSet or DaemonSet with --kind,
+between every cluster and namespace it is deployed to, and show the fields that differ:
+container images, names of environment variables, resource requests and limits, probes,
+lifecycle hooks and replicas. Use it to spot configuration drift between environments, e.g. staging and
+production. Environment variable values are not stored and therefore not compared.
 
-	// If a config file is found, read it in
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
-	}
+Exits with code 2 when the templates are identical.
 
-	// Override with environment variables if they exist
-	if uri := os.Getenv("NEO4J_URI"); uri != "" {
-		viper.Set("neo4j.uri", uri)
-	}
-	if user := os.Getenv("NEO4J_USERNAME"); user != "" {
-		viper.Set("neo4j.user", user)
-	}
-	if pass := os.Getenv("NEO4J_PASSWORD"); pass != "" {
-		viper.Set("neo4j.pass", pass)
-	}
-	if cluster := os.Getenv("KUBEGRAPH_CLUSTER_NAME"); cluster != "" {
-		viper.Set("kubernetes.cluster", cluster)
-	}
-	if logLevel := os.Getenv("KUBEGRAPH_LOG_LEVEL"); logLevel != "" {
-		viper.Set("log.level", logLevel)
-	}
+Examples:
+  kubegraph-cli drift api production                  # Drift of the api Deployment between clusters
+  kubegraph-cli drift db --kind StatefulSet --cluster-name staging`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleDrift(args)
+	},
 }
 
-// loadEnvFile loads environment variables from a .env file
-func loadEnvFile() {
-	var envFilePath string
-
-	if envFile != "" {
-		// Use env file from the flag (highest priority)
-		envFilePath = envFile
-	} else if envFileEnv := os.Getenv("KUBEGRAPH_ENV_FILE"); envFileEnv != "" {
-		// Use env file from environment variable (second priority)
-		envFilePath = envFileEnv
-	} else {
-		// Try to find default .env file in current directory
-		if _, err := os.Stat(".env"); err == nil {
-			envFilePath = ".env"
-		} else {
-			// Try to find default .env file in home directory
-			home, err := os.UserHomeDir()
-			if err == nil {
-				defaultEnvFile := home + "/.kubegraph-cli.env"
-				if _, err := os.Stat(defaultEnvFile); err == nil {
-					envFilePath = defaultEnvFile
-				}
-			}
-		}
-	}
+// spreadCmd represents the spread command
+var spreadCmd = &cobra.Command{
+	Use:   "spread <name> [namespace]",
+	Short: "Show how the replicas of a workload are spread across zones and nodes",
+	Long: `Show how the pods of a Deployment, or of a StatefulSet with --kind, are distributed
+across the zones and nodes of their cluster, and flag placements that do not survive the
+loss of a zone or node: all replicas in a single zone of a multi-zone cluster, all replicas
+on a single node, or a zone skew above 1. Zones are read from the
+topology.kubernetes.io/zone label of the nodes.
 
-	if envFilePath == "" {
-		return // No .env file found
-	}
+Examples:
+  kubegraph-cli spread api production
+  kubegraph-cli spread db --kind StatefulSet --cluster-name production`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleSpread(args)
+	},
+}
 
-	file, err := os.Open(envFilePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not open .env file %s: %v\n", envFilePath, err)
-		return
-	}
-	defer file.Close()
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Compare the recorded topology at different points in time",
+	Long: `Compare the topology recorded in history mode at different points in time.
+Times are RFC3339 timestamps (2024-05-01T10:00:00Z), durations before now (2h, 30m), or "now".`,
+}
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
+// snapshotDiffCmd represents the snapshot diff command
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <t1> <t2>",
+	Short: "Show resources added, removed or changed between two times",
+	Long: `Show resources that were added, removed or changed between two points in time.
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+Examples:
+  kubegraph-cli snapshot diff 24h now                                  # Changes during the last day
+  kubegraph-cli snapshot diff 2024-05-01T10:00:00Z 2024-05-01T12:00:00Z  # Changes between two timestamps`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleSnapshotDiff(args)
+	},
+}
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+// changesCmd represents the changes command
+var changesCmd = &cobra.Command{
+	Use:   "changes [type] [since]",
+	Short: "Show graph mutations written by KubeGraph",
+	Long: `Show the upserts and deletes KubeGraph wrote to the graph, newest first.
+Changes are only recorded when KubeGraph runs with --audit-trail.
+The since argument is an RFC3339 timestamp or a duration before now (default 1h).
+Use "all" as type to show changes of every resource type.
 
-		// Parse KEY=VALUE format
-		if strings.Contains(line, "=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
+Examples:
+  kubegraph-cli changes                    # Changes during the last hour
+  kubegraph-cli changes Pod                # Pod changes during the last hour
+  kubegraph-cli changes Deployment 24h     # Deployment changes during the last day
+  kubegraph-cli changes all 15m            # All changes during the last 15 minutes`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleChanges(args)
+	},
+}
 
-				// Remove quotes if present
-				if (strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"")) ||
-					(strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'")) {
-					value = value[1 : len(value)-1]
-				}
+// portMismatchesCmd represents the port-mismatches command
+var portMismatchesCmd = &cobra.Command{
+	Use:   "port-mismatches",
+	Short: "Find Services whose targetPort matches no container port of their pods",
+	Long: `Find Services whose targetPort doesn't match any container port declared by the pods
+they select. A named targetPort that no pod defines never receives traffic; a numeric
+targetPort that no pod declares usually points at the wrong port.
 
-				// Set environment variable if not already set
-				if os.Getenv(key) == "" {
-					os.Setenv(key, value)
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "Warning: Invalid line %d in .env file %s: %s\n", lineNum, envFilePath, line)
-			}
-		}
-	}
+Examples:
+  kubegraph-cli port-mismatches                              # Check all clusters
+  kubegraph-cli port-mismatches --cluster-name production    # Check a single cluster`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handlePortMismatches()
+	},
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Error reading .env file %s: %v\n", envFilePath, err)
-		return
-	}
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Export the graph to a file",
+	Long: `Export all nodes and relationships as JSON lines, for use with the import command.
+With --cluster-name only that cluster is exported. Use "-" to write to stdout.
 
-	fmt.Fprintf(os.Stderr, "Loaded environment variables from: %s\n", envFilePath)
+Examples:
+  kubegraph-cli export graph.jsonl                              # Export the whole graph
+  kubegraph-cli export prod.jsonl --cluster-name production     # Export a single cluster`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleExport(args)
+	},
 }
 
-func initializeClient() error {
-	// Initialize logger
-	logLevel := viper.GetString("log.level")
-	logger.Init(logger.ParseLogLevel(logLevel))
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a previously exported graph",
+	Long: `Bulk-load a file written by the export command, for offline forensics and demo environments.
+The target database must be empty unless --force is given. Use "-" to read from stdin.
 
-	// Create configuration
+Examples:
+  kubegraph-cli import graph.jsonl                        # Import into the default database
+  kubegraph-cli import graph.jsonl --database forensics   # Import into a separate database`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleImport(args)
+	},
+}
+
+// loadCmd represents the load command
+var loadCmd = &cobra.Command{
+	Use:   "load <file>",
+	Short: "Replay a mutation file into Neo4j",
+	Long: `Replay the node, relationship and delete mutations recorded by a file store, for clusters
+without access to Neo4j. Mutations are applied in the order they were recorded; loading
+stops at the first one that fails. Use "-" to read from stdin.
+
+Examples:
+  kubegraph-cli load mutations.jsonl                   # Load into the default database
+  kubegraph-cli load mutations.jsonl --db airgapped    # Load into a separate database`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleLoad(args)
+	},
+}
+
+// graphCmd represents the graph command
+var graphCmd = &cobra.Command{
+	Use:   "graph <type> <name> [namespace]",
+	Short: "Render the neighborhood of a resource as a Mermaid or DOT diagram",
+	Long: `Render a resource and the nodes and relationships around it as a diagram definition,
+for embedding in incident docs. Mermaid renders in GitHub and most wikis; DOT renders with Graphviz.
+
+Examples:
+  kubegraph-cli graph Pod web-0 production                     # Mermaid diagram to stdout
+  kubegraph-cli graph Deployment api --depth 2                 # Include nodes two hops away
+  kubegraph-cli graph Service api --format dot -o api.dot      # DOT diagram to a file`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleGraph(args)
+	},
+}
+
+// pendingRebootsCmd represents the pending-reboots command
+var pendingRebootsCmd = &cobra.Command{
+	Use:   "pending-reboots",
+	Short: "Show nodes pending a reboot and the workloads it would disrupt",
+	Long: `Show nodes that kured or the Flatcar update operator marked as requiring a reboot,
+with the workloads running on them and the PodDisruptionBudgets that currently allow
+no disruptions and would block draining the node.
+
+Examples:
+  kubegraph-cli pending-reboots                              # All clusters
+  kubegraph-cli pending-reboots --cluster-name production    # A single cluster`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handlePendingReboots()
+	},
+}
+
+// drainImpactCmd represents the drain-impact command
+var drainImpactCmd = &cobra.Command{
+	Use:   "drain-impact <node>",
+	Short: "Preview what draining a node would disrupt",
+	Long: `Preview the impact of draining a node before running kubectl drain: the pods
+scheduled on it with their controllers, the PodDisruptionBudgets protecting them and
+whether they currently allow a disruption, and the Services that would lose all their
+ready endpoints because every pod they select runs on the node.
+
+DaemonSet pods are listed but not evicted by kubectl drain --ignore-daemonsets, and
+pods without a controller are only deleted with --force. The command exits with code 2
+when no pod runs on the node.
+
+Examples:
+  kubegraph-cli drain-impact worker-1
+  kubegraph-cli drain-impact worker-1 --cluster-name production`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: withClient(completeArgs(completeNodes)),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleDrainImpact(args[0])
+	},
+}
+
+// vulnsCmd represents the vulns command
+var vulnsCmd = &cobra.Command{
+	Use:   "vulns [limit]",
+	Short: "Rank workloads by the vulnerabilities of their images",
+	Long: `Rank workloads by the critical and high vulnerabilities of the distinct images
+their pods run. Image nodes get their counts from the trivy-operator VulnerabilityReports
+KubeGraph watches, or from Trivy and Grype JSON reports loaded with vulns import.
+Pods owned by a ReplicaSet are reported as their Deployment and pods owned by a Job
+as their CronJob.
+
+Examples:
+  kubegraph-cli vulns                                   # 20 most exposed workloads
+  kubegraph-cli vulns 50 --filter namespace=production  # 50 most exposed in a namespace
+  kubegraph-cli vulns --sort-by -fixable                # Most fixable vulnerabilities first`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleVulns(args)
+	},
+}
+
+// vulnsImportCmd represents the vulns import command
+var vulnsImportCmd = &cobra.Command{
+	Use:   "import <report.json>...",
+	Short: "Attach Trivy or Grype JSON reports to Image nodes",
+	Long: `Attach the vulnerability counts of Trivy (trivy image -f json) or Grype
+(grype -o json) reports to the Image nodes of the selected cluster. An Image node is
+created if no pod runs the image yet, and is linked once a pod runs it.
+
+Examples:
+  trivy image -f json -o nginx.json nginx:1.25
+  kubegraph-cli vulns import nginx.json --cluster-name production
+  grype -o json registry.example.com/api:2.1 > api.json && kubegraph-cli vulns import api.json`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleVulnsImport(args)
+	},
+}
+
+// impactCmd represents the impact command
+var impactCmd = &cobra.Command{
+	Use:   "impact <type> <name> [namespace]",
+	Short: "List the resources affected if a resource were deleted or failed",
+	Long: `Traverse the dependents of a resource to estimate its blast radius, following
+OWNED_BY, MANAGES, USES, SELECTS, ROUTES_TO and SCHEDULED_ON relationships,
+e.g. Node -> Pods -> Deployments and Services -> Ingresses.
+
+Examples:
+  kubegraph-cli impact Node worker-1                                 # Everything depending on a node
+  kubegraph-cli impact ConfigMap app-config production               # Pods using a ConfigMap and what depends on them
+  kubegraph-cli impact Service api production --depth 1              # Direct dependents only
+  kubegraph-cli impact Node worker-1 --cluster-name production       # Restrict to one cluster`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleImpact(args)
+	},
+}
+
+// treeCmd represents the tree command
+var treeCmd = &cobra.Command{
+	Use:   "tree <type> <name> [namespace]",
+	Short: "Show the ownership tree of a resource with the status of its descendants",
+	Long: `Show the resources a resource owns, following OWNED_BY relationships down to the
+containers of its pods, e.g. Deployment -> ReplicaSets -> Pods -> Containers or
+CronJob -> Jobs -> Pods -> Containers, with the status of each. Without
+--cluster-name, the tree of the resource is shown for every cluster holding it.
+
+With --output json or csv, the tree is listed flat, one row per resource with its depth and owner.
+
+Examples:
+  kubegraph-cli tree Deployment api production                   # Ownership tree of a Deployment
+  kubegraph-cli tree CronJob nightly-backup                      # Jobs and pods of a CronJob in every cluster
+  kubegraph-cli tree StatefulSet db --cluster-name staging`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleTree(args)
+	},
+}
+
+// registriesCmd represents the registries command
+var registriesCmd = &cobra.Command{
+	Use:   "registries",
+	Short: "Show the image registries workloads pull from and the namespaces holding credentials for them",
+	Long: `Show every image registry the cluster depends on, with the number of workloads pulling
+from it, the namespaces they run in, the namespaces holding image pull secrets for it,
+and the namespaces pulling from it without a pull secret for it.
+
+Examples:
+  kubegraph-cli registries                              # All clusters
+  kubegraph-cli registries --cluster-name production    # A single cluster`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleRegistries()
+	},
+}
+
+// kindsCmd represents the kinds command
+var kindsCmd = &cobra.Command{
+	Use:   "kinds",
+	Short: "List the kinds kubegraph watches",
+	Long: `List the kinds kubegraph has a handler for, with the resource they are watched
+from, whether they are namespaced and whether their handler queries the API server.
+The list comes from the handlers built into this binary and needs no database.
+
+Examples:
+  kubegraph-cli kinds`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleKinds()
+	},
+}
+
+// pathCmd represents the path command
+var pathCmd = &cobra.Command{
+	Use:   "path <typeA> <nameA> <typeB> <nameB>",
+	Short: "Show the shortest chain of relationships connecting two resources",
+	Long: `Find the shortest path between two resources, ignoring relationship direction,
+and print the chain of relationships connecting them, e.g. to answer
+"how is this Pod related to that Secret?".
+
+Examples:
+  kubegraph-cli path Pod web-0 Secret db-credentials                                  # Any namespace
+  kubegraph-cli path Pod web-0 Secret db-credentials --from-namespace production \
+    --to-namespace production                                                         # Disambiguate by namespace
+  kubegraph-cli path Ingress shop Node worker-1 --max-hops 6                          # Limit the path length`,
+	Args: cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		handlePath(args)
+	},
+}
+
+// storageByWorkloadCmd represents the storage-by-workload command
+var storageByWorkloadCmd = &cobra.Command{
+	Use:   "storage-by-workload [limit]",
+	Short: "Rank workloads by the storage provisioned for their PVCs",
+	Long: `Rank Deployments, StatefulSets, DaemonSets, CronJobs and standalone ReplicaSets and Jobs
+by the total capacity of the PersistentVolumeClaims used by their pods, across namespaces.
+
+Examples:
+  kubegraph-cli storage-by-workload                             # Top 20 workloads
+  kubegraph-cli storage-by-workload 50                          # Top 50 workloads
+  kubegraph-cli storage-by-workload --cluster-name production   # A single cluster`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleStorageByWorkload(args)
+	},
+}
+
+// exportSiteCmd represents the export-site command
+var exportSiteCmd = &cobra.Command{
+	Use:   "export-site",
+	Short: "Export a namespace as a self-contained HTML site for browsing offline",
+	Long: `Write the resources of a namespace, the cluster-scoped resources they are connected to
+and the relationships between them to a directory holding index.html and graph.json.
+The page embeds the graph and its renderer, so it can be opened from disk and shared
+with people without access to Neo4j, e.g. auditors. ConfigMap data is left out.
+
+Examples:
+  kubegraph-cli export-site --namespace payments                       # Write ./site
+  kubegraph-cli export-site --namespace payments --out ./audit-site    # Write another directory
+  kubegraph-cli export-site --namespace payments --include-events      # Include Kubernetes Events`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleExportSite()
+	},
+}
+
+// riskyRolesCmd represents the risky-roles command
+var riskyRolesCmd = &cobra.Command{
+	Use:   "risky-roles",
+	Short: "List Roles and ClusterRoles with dangerous permissions and the subjects bound to them",
+	Long: `List Roles and ClusterRoles granting wildcard verbs, resources or API groups, access to
+Secrets, exec into pods or escalation verbs (escalate, bind, impersonate), with the users,
+groups and ServiceAccounts bound to them. Critical roles grant every verb on every resource.
+Built-in "system:" roles are hidden unless --include-system is given.
+
+Examples:
+  kubegraph-cli risky-roles                                 # Risky roles across clusters
+  kubegraph-cli risky-roles --include-system                # Include built-in system: roles
+  kubegraph-cli risky-roles --cluster-name production -q    # For scripts, combined with --fail-threshold`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleRiskyRoles()
+	},
+}
+
+// attackPathsCmd represents the attack-paths command
+var attackPathsCmd = &cobra.Command{
+	Use:   "attack-paths",
+	Short: "Find chains of resources an attacker could follow to escalate",
+	Long: `Traverse the graph for chains of resources an attacker could follow from an entry
+point to the nodes or the Secrets of a cluster, ranked by severity:
+- Internet exposure to a node: an Ingress, HTTPRoute, VirtualService, LoadBalancer or
+  NodePort Service routing to a pod that is privileged, uses host namespaces or hostPath
+  volumes, or runs as root, and the node it runs on
+- Internet exposure to the API: an exposed pod mounting the token of a ServiceAccount
+  bound to a critical or high risk role
+- Secret access: a ServiceAccount whose token pods mount, bound to a role that grants
+  every verb on every resource or reads Secrets
+
+Pods are only flagged as privileged or root once synced by a version evaluating the Pod
+Security Standards. Paths through kube-* namespaces and built-in "system:" roles are
+hidden unless --include-system is given.
+
+Examples:
+  kubegraph-cli attack-paths                                # Attack paths across clusters
+  kubegraph-cli attack-paths --cluster-name production -q   # For scripts, combined with --fail-threshold`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleAttackPaths()
+	},
+}
+
+// saExposureCmd represents the sa-exposure command
+var saExposureCmd = &cobra.Command{
+	Use:   "sa-exposure",
+	Short: "List pods whose ServiceAccounts are bound to dangerous roles",
+	Long: `List the pods running as a ServiceAccount bound to a critical or high risk Role or
+ClusterRole (see risky-roles), with the bindings granting them and whether the pod mounts
+the ServiceAccount token. A pod mounts the token unless the pod or its ServiceAccount sets
+automountServiceAccountToken: false, and anyone who compromises a pod mounting the token
+holds the permissions of its ServiceAccount. Pods in kube-* namespaces and built-in
+"system:" roles are hidden unless --include-system is given.
+
+Examples:
+  kubegraph-cli sa-exposure                                 # Exposed pods across clusters
+  kubegraph-cli sa-exposure --mounted-only -q               # Only pods mounting the token, for scripts`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleSAExposure()
+	},
+}
+
+// healthReportCmd represents the health-report command
+var healthReportCmd = &cobra.Command{
+	Use:   "health-report",
+	Short: "List unavailable aggregated APIs and unhealthy nodes",
+	Long: `List the APIServices the aggregator reports as not Available, along with the state of
+the Service behind them, and the nodes that are not Ready, under resource pressure or cordoned.
+A broken aggregated API such as metrics-server breaks discovery for every client and can block
+namespace deletion, so it is ranked critical like a node that is not Ready.
+
+Examples:
+  kubegraph-cli health-report
+  kubegraph-cli health-report --cluster-name production -q --fail-threshold 0`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleHealthReport()
+	},
+}
+
+// webhookAuditCmd represents the webhook-audit command
+var webhookAuditCmd = &cobra.Command{
+	Use:   "webhook-audit",
+	Short: "List admission webhooks that can block or break the cluster",
+	Long: `List the webhooks of ValidatingWebhookConfigurations and MutatingWebhookConfigurations
+that fail closed (failurePolicy Fail, the default), whose backing Service is missing or has
+no running pods, or whose caBundle has expired or expires within --expiry-days. A webhook
+failing closed whose backend is unreachable rejects every request it intercepts, a common
+cause of cluster-wide outages.
+
+Webhooks are ranked critical when they fail closed and their backend is broken or their
+CA expired, high when the backend is broken or the CA expired but failures are ignored, or
+when they fail closed and the CA expires soon, and medium otherwise.
+
+Examples:
+  kubegraph-cli webhook-audit
+  kubegraph-cli webhook-audit --expiry-days 60 --cluster-name production`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleWebhookAudit()
+	},
+}
+
+// unprotectedWorkloadsCmd represents the unprotected-workloads command
+var unprotectedWorkloadsCmd = &cobra.Command{
+	Use:   "unprotected-workloads",
+	Short: "List workloads not covered by any active Velero backup schedule",
+	Long: `List the Deployments, StatefulSets, DaemonSets and CronJobs that no unpaused Velero
+Schedule protects, either through their namespace or through its resource and label
+filters. The backups column counts the completed backups holding the workload anyway,
+e.g. taken manually. Workloads in kube-* namespaces are hidden unless --include-system
+is given.
+
+Examples:
+  kubegraph-cli unprotected-workloads                              # Across clusters
+  kubegraph-cli unprotected-workloads --cluster-name production    # A single cluster
+  kubegraph-cli unprotected-workloads -q --fail-threshold 0        # Fail a check when any is found`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleUnprotectedWorkloads()
+	},
+}
+
+// taintBlockedCmd represents the taint-blocked command
+var taintBlockedCmd = &cobra.Command{
+	Use:   "taint-blocked",
+	Short: "List pods that no node accepts because of its taints",
+	Long: `List the pods for which every node allowed by their nodeSelector and node affinity has a
+NoSchedule or NoExecute taint they do not tolerate, so they cannot be scheduled anywhere, or
+rescheduled should they be evicted. The taints column shows the taints of those nodes. Pods
+whose constraints match no node at all are not listed.
+
+Examples:
+  kubegraph-cli taint-blocked
+  kubegraph-cli taint-blocked --cluster-name production -q --fail-threshold 0`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleTaintBlocked()
+	},
+}
+
+// preemptionRiskCmd represents the preemption-risk command
+var preemptionRiskCmd = &cobra.Command{
+	Use:   "preemption-risk",
+	Short: "List the pods that pending higher priority pods would preempt",
+	Long: `List the pending pods that do not fit on any node by their CPU and memory requests
+but could preempt lower priority pods, with the node the scheduler would most likely pick
+and the pods it would evict there. Like the scheduler, the estimate evicts as few and as
+low priority pods as possible, and only considers the nodes a pending pod may run on by
+its nominated node, node constraints and taints. Pods whose PriorityClass never preempts
+are left out.
+
+Examples:
+  kubegraph-cli preemption-risk
+  kubegraph-cli preemption-risk --cluster-name production`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handlePreemptionRisk()
+	},
+}
+
+// missingProbesCmd represents the missing-probes command
+var missingProbesCmd = &cobra.Command{
+	Use:   "missing-probes",
+	Short: "List workloads whose containers have no readiness or liveness probe",
+	Long: `List the Deployments, StatefulSets and DaemonSets with containers lacking a readiness
+or a liveness probe. Without a readiness probe a pod receives traffic as soon as it starts,
+and without a liveness probe a hung container is never restarted. Init containers are not
+considered. Workloads in kube-* namespaces are hidden unless --include-system is given.
+
+Examples:
+  kubegraph-cli missing-probes
+  kubegraph-cli missing-probes --cluster-name production -q --fail-threshold 0`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleMissingProbes()
+	},
+}
+
+// topCmd represents the top command
+var topCmd = &cobra.Command{
+	Use:   "top <nodes|pods> [limit]",
+	Short: "Show the nodes or pods using the most CPU or memory",
+	Long: `Show the nodes or pods using the most CPU or memory, as last sampled from metrics-server.
+Usage is only available when k8s-graph runs with --usage-metrics. Node percentages are
+relative to the allocatable resources of the node. The sampled column tells how old a
+sample is; a node or pod updated since the last poll has no usage until the next one.
+
+Examples:
+  kubegraph-cli top nodes                           # Nodes by CPU usage
+  kubegraph-cli top pods 50 --sort-by memory        # The 50 pods using the most memory
+  kubegraph-cli top pods --cluster-name production  # Pods of a single cluster`,
+	Args:      cobra.RangeArgs(1, 2),
+	ValidArgs: []string{"nodes", "pods"},
+	Run: func(cmd *cobra.Command, args []string) {
+		handleTop(args)
+	},
+}
+
+// efficiencyCmd represents the efficiency command
+var efficiencyCmd = &cobra.Command{
+	Use:   "efficiency",
+	Short: "Find over-provisioned Deployments and idle namespaces",
+	Long: `Compare what the pods of each Deployment request to what they use, as last sampled
+from metrics-server, and list the Deployments using less than --max-utilization percent of
+their requested CPU or memory, with the CPU and memory they could give back. Namespaces
+whose pods together use less than --idle-cpu millicores are listed as idle.
+
+Usage is only available when k8s-graph runs with --usage-metrics; pods without a sample are
+left out. Use --output json to feed the report to other tools.
+
+Examples:
+  kubegraph-cli efficiency                                  # Deployments using less than 30% of their requests
+  kubegraph-cli efficiency --max-utilization 50             # ... or less than half
+  kubegraph-cli efficiency --output json > efficiency.json  # Report for a cost pipeline`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleEfficiency()
+	},
+}
+
+// syncStatusCmd represents the sync-status command
+var syncStatusCmd = &cobra.Command{
+	Use:   "sync-status",
+	Short: "Show the progress of the initial sync of each cluster",
+	Long: `Show how far the initial list and write of each kind has progressed since the
+sync instance of a cluster started: the objects listed from the API server, the objects
+written to the graph and the objects remaining. The progress is written every few seconds
+until every kind is complete.
+
+Examples:
+  kubegraph-cli sync-status                              # All clusters
+  kubegraph-cli sync-status --cluster-name production    # A single cluster`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		handleSyncStatus()
+	},
+}
+
+// canConnectCmd represents the can-connect command
+var canConnectCmd = &cobra.Command{
+	Use:   "can-connect <pod-a> <pod-b> [port]",
+	Short: "Check whether NetworkPolicies allow traffic from one pod to another",
+	Long: `Check whether the NetworkPolicies in the graph allow pod-a to open a connection to pod-b,
+optionally on a port given as "8080", "8080/UDP" or a named container port of pod-b.
+Traffic must be allowed as egress from pod-a and as ingress to pod-b. For each side the
+command prints the policies allowing it, or why it is allowed by default.
+
+Pods are given as namespace/name, or as a name when it is unique. The command exits
+with code 2 when the traffic is denied.
+
+Examples:
+  kubegraph-cli can-connect shop/web-0 shop/api-0                  # On any port
+  kubegraph-cli can-connect shop/web-0 payments/ledger-0 5432      # On a TCP port
+  kubegraph-cli can-connect shop/web-0 kube-system/coredns-0 53/UDP
+  kubegraph-cli can-connect web-0 api-0 http -q                    # Prints only allowed or denied`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleCanConnect(args)
+	},
+}
+
+// diagnoseCmd represents the diagnose command
+var diagnoseCmd = &cobra.Command{
+	Use:   "diagnose",
+	Short: "Explain why a resource is not healthy",
+}
+
+// diagnosePodCmd represents the diagnose pod command
+var diagnosePodCmd = &cobra.Command{
+	Use:   "pod <pod>",
+	Short: "Explain why a pod is not running",
+	Long: `Combine what the graph knows about a pod into a single report: its phase and
+conditions, its containers, the node it is scheduled on, the binding of its PVCs, its
+owner chain and its recent events, followed by the likely reasons it is not Running
+and Ready.
+
+The pod is given as namespace/name, or as a name when it is unique. The command exits
+with code 2 when no problem is found.
+
+Examples:
+  kubegraph-cli diagnose pod shop/web-0
+  kubegraph-cli diagnose pod web-0 --cluster-name production
+  kubegraph-cli diagnose pod shop/web-0 -q                      # Prints only the likely causes`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: withClient(completeArgs(completePods)),
+	Run: func(cmd *cobra.Command, args []string) {
+		handleDiagnosePod(args[0])
+	},
+}
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate the shell completion script",
+	Long: `Generate the completion script of kubegraph-cli for the given shell. Besides
+commands and flags, node labels, relationship types, namespaces, cluster names and
+resource names are completed by querying Neo4j with the configured connection, so
+kubegraph-cli resource Po<TAB> completes the label and kubegraph-cli resource Pod <TAB>
+the pod names.
+
+Examples:
+  source <(kubegraph-cli completion bash)                                  # Bash, current shell
+  kubegraph-cli completion bash > /etc/bash_completion.d/kubegraph-cli     # Bash, permanently
+  kubegraph-cli completion zsh > "${fpath[1]}/_kubegraph-cli"              # Zsh
+  kubegraph-cli completion fish > ~/.config/fish/completions/kubegraph-cli.fish
+  kubegraph-cli completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeCompletion(cmd.Root(), args[0], os.Stdout)
+	},
+}
+
+func init() {
+	cobra.OnInitialize(initConfig)
+
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubegraph-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", ".env file (default is $HOME/.kubegraph-cli.env, or set KUBEGRAPH_ENV_FILE env var)")
+	rootCmd.PersistentFlags().String("uri", "", "Neo4j database URI (default: from NEO4J_URI env var)")
+	rootCmd.PersistentFlags().String("user", "", "Neo4j username (default: from NEO4J_USERNAME env var)")
+	rootCmd.PersistentFlags().String("pass", "", "Neo4j password (default: from NEO4J_PASSWORD env var)")
+	rootCmd.PersistentFlags().String("db", "", "Neo4j database (default: from NEO4J_DATABASE env var, or the server's default database)")
+	rootCmd.PersistentFlags().StringVar(&clusterName, "cluster-name", "", "Kubernetes cluster name to filter by")
+	rootCmd.PersistentFlags().StringVar(&team, "team", "", "Team to filter by, as stamped on the nodes by the agent")
+	rootCmd.PersistentFlags().String("promoted-labels", "", "Comma-separated label keys the agent promotes to node properties, matched by --selector without a text search (default: from PROMOTED_LABELS env var, or the agent's default)")
+	rootCmd.PersistentFlags().String("log-level", "INFO", "Log level (DEBUG, INFO, WARN, ERROR)")
+	rootCmd.PersistentFlags().String("output", "table", "Output format: table, json, csv")
+	rootCmd.PersistentFlags().BoolVar(&showQuery, "show-query", false, "Show the executed Cypher query")
+	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode to show configuration details")
+	rootCmd.PersistentFlags().BoolVar(&showEmojis, "show-emojis", true, "Show emojis in output")
+	rootCmd.PersistentFlags().BoolVar(&showRelated, "related", false, "Show related resources when displaying resource details")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only results, without titles, counts and informational logs")
+	rootCmd.PersistentFlags().IntVar(&failThreshold, "fail-threshold", -1, "Exit with code 3 when a command returns more results than this (-1 disables)")
+
+	// Bind flags to viper
+	viper.BindPFlag("neo4j.uri", rootCmd.PersistentFlags().Lookup("uri"))
+	viper.BindPFlag("neo4j.user", rootCmd.PersistentFlags().Lookup("user"))
+	viper.BindPFlag("neo4j.pass", rootCmd.PersistentFlags().Lookup("pass"))
+	viper.BindPFlag("neo4j.database", rootCmd.PersistentFlags().Lookup("db"))
+	viper.BindPFlag("kubernetes.cluster", rootCmd.PersistentFlags().Lookup("cluster-name"))
+	viper.BindPFlag("promoted.labels", rootCmd.PersistentFlags().Lookup("promoted-labels"))
+	viper.BindPFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
+	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+	viper.BindPFlag("show-emojis", rootCmd.PersistentFlags().Lookup("show-emojis"))
+	viper.BindPFlag("show-related", rootCmd.PersistentFlags().Lookup("related"))
+
+	// Add subcommands
+	rootCmd.AddCommand(nodesCmd)
+	rootCmd.AddCommand(relationshipsCmd)
+	rootCmd.AddCommand(resourcesCmd)
+	rootCmd.AddCommand(podsCmd)
+	rootCmd.AddCommand(servicesCmd)
+	rootCmd.AddCommand(deploymentsCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(dbEventsCmd)
+	rootCmd.AddCommand(dbResourcesCmd)
+	rootCmd.AddCommand(clustersCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(healthCmd)
+	rootCmd.AddCommand(k8sNodesCmd)
+	rootCmd.AddCommand(neo4jDatabasesCmd)
+	rootCmd.AddCommand(rootPodsCmd)
+	rootCmd.AddCommand(privilegedPodsCmd)
+	rootCmd.AddCommand(securityRisksCmd)
+	rootCmd.AddCommand(resourcePressureCmd)
+	rootCmd.AddCommand(resourcePressureSummaryCmd)
+	rootCmd.AddCommand(debugDiskCmd)
+	rootCmd.AddCommand(resourceCmd)
+	rootCmd.AddCommand(anomaliesCmd)
+	rootCmd.AddCommand(incidentsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(rolloutsCmd)
+	rootCmd.AddCommand(changesCmd)
+	rootCmd.AddCommand(portMismatchesCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(loadCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(pendingRebootsCmd)
+	rootCmd.AddCommand(drainImpactCmd)
+	vulnsCmd.AddCommand(vulnsImportCmd)
+	rootCmd.AddCommand(vulnsCmd)
+	rootCmd.AddCommand(impactCmd)
+	rootCmd.AddCommand(treeCmd)
+	rootCmd.AddCommand(registriesCmd)
+	rootCmd.AddCommand(kindsCmd)
+	rootCmd.AddCommand(pathCmd)
+	rootCmd.AddCommand(storageByWorkloadCmd)
+	rootCmd.AddCommand(exportSiteCmd)
+	rootCmd.AddCommand(riskyRolesCmd)
+	rootCmd.AddCommand(attackPathsCmd)
+	rootCmd.AddCommand(saExposureCmd)
+	rootCmd.AddCommand(webhookAuditCmd)
+	rootCmd.AddCommand(driftCmd)
+	rootCmd.AddCommand(spreadCmd)
+	rootCmd.AddCommand(healthReportCmd)
+	rootCmd.AddCommand(canConnectCmd)
+	rootCmd.AddCommand(unprotectedWorkloadsCmd)
+	rootCmd.AddCommand(missingProbesCmd)
+	rootCmd.AddCommand(taintBlockedCmd)
+	rootCmd.AddCommand(preemptionRiskCmd)
+	rootCmd.AddCommand(topCmd)
+	rootCmd.AddCommand(efficiencyCmd)
+	rootCmd.AddCommand(syncStatusCmd)
+	rootCmd.AddCommand(completionCmd)
+	diagnoseCmd.AddCommand(diagnosePodCmd)
+	rootCmd.AddCommand(diagnoseCmd)
+	queryCmd.AddCommand(querySaveCmd)
+	queryCmd.AddCommand(queryRunCmd)
+	queryCmd.AddCommand(queryListCmd)
+
+	// Complete arguments and flags from the graph
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.RegisterFlagCompletionFunc("cluster-name", withClient(completeClusters))
+	nodesCmd.ValidArgsFunction = withClient(completeArgs(completeLabels))
+	relationshipsCmd.ValidArgsFunction = withClient(completeArgs(completeRelationshipTypes))
+	podsCmd.ValidArgsFunction = withClient(completeArgs(completeNamespaces))
+	servicesCmd.ValidArgsFunction = withClient(completeArgs(completeNamespaces))
+	deploymentsCmd.ValidArgsFunction = withClient(completeArgs(completeNamespaces))
+	resourceCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0)))
+	historyCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0), completeNamespaces))
+	rolloutsCmd.ValidArgsFunction = withClient(completeArgs(completeRolloutWorkloads, completeNamespaces))
+	driftCmd.ValidArgsFunction = withClient(completeArgs(completeDriftWorkloads, completeNamespaces))
+	spreadCmd.ValidArgsFunction = withClient(completeArgs(completeSpreadWorkloads, completeNamespaces))
+	graphCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0), completeNamespaces))
+	impactCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0), completeNamespaces))
+	treeCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0), completeNamespaces))
+	pathCmd.ValidArgsFunction = withClient(completeArgs(completeLabels, completeNamesOf(0), completeLabels, completeNamesOf(2)))
+	canConnectCmd.ValidArgsFunction = withClient(completeArgs(completePods, completePods))
+
+	importCmd.Flags().StringVar(&importDatabase, "database", "", "Target Neo4j database (default: the database of --db)")
+	importCmd.Flags().IntVar(&importBatchSize, "batch-size", 1000, "Number of nodes or relationships written per transaction")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Import even if the target database is not empty")
+
+	graphCmd.Flags().IntVar(&graphDepth, "depth", 1, "Number of hops to include around the resource (1-5)")
+	graphCmd.Flags().StringVar(&graphFormat, "format", "mermaid", "Diagram format: mermaid, dot")
+	graphCmd.Flags().StringVarP(&graphOutput, "output-file", "o", "", "Write the diagram to a file instead of stdout")
+
+	impactCmd.Flags().IntVar(&impactDepth, "depth", 3, "Maximum number of hops to follow from the resource (1-6)")
+
+	pathCmd.Flags().StringVar(&pathFromNamespace, "from-namespace", "", "Namespace of the first resource")
+	pathCmd.Flags().StringVar(&pathToNamespace, "to-namespace", "", "Namespace of the second resource")
+	pathCmd.Flags().IntVar(&pathMaxHops, "max-hops", 10, "Maximum number of relationships in the path (1-15)")
+
+	exportSiteCmd.Flags().StringVar(&siteNamespace, "namespace", "", "Namespace to export")
+	exportSiteCmd.Flags().StringVar(&siteOut, "out", "./site", "Directory to write the site to")
+	exportSiteCmd.Flags().BoolVar(&siteIncludeEvents, "include-events", false, "Include Kubernetes Events")
+	exportSiteCmd.MarkFlagRequired("namespace")
+	exportSiteCmd.RegisterFlagCompletionFunc("namespace", withClient(completeNamespaces))
+	pathCmd.RegisterFlagCompletionFunc("from-namespace", withClient(completeNamespaces))
+	pathCmd.RegisterFlagCompletionFunc("to-namespace", withClient(completeNamespaces))
+
+	riskyRolesCmd.Flags().BoolVar(&riskyRolesIncludeSystem, "include-system", false, "Include built-in roles whose name starts with system:")
+	attackPathsCmd.Flags().BoolVar(&attackPathsIncludeSystem, "include-system", false, "Include paths through kube-* namespaces and built-in system: roles")
+	webhookAuditCmd.Flags().IntVar(&webhookExpiryDays, "expiry-days", 30, "Report caBundles expiring within this many days")
+	saExposureCmd.Flags().BoolVar(&saExposureIncludeSystem, "include-system", false, "Include pods in kube-* namespaces and built-in system: roles")
+	saExposureCmd.Flags().BoolVar(&saExposureMountedOnly, "mounted-only", false, "Only list pods mounting the ServiceAccount token")
+	incidentsCmd.Flags().BoolVar(&incidentsOpenOnly, "open", false, "Only show incidents whose workload still receives warnings")
+	rolloutsCmd.Flags().StringVar(&rolloutsKind, "kind", "Deployment", "Kind of the workload: Deployment, StatefulSet, DaemonSet")
+	driftCmd.Flags().StringVar(&driftKind, "kind", "Deployment", "Kind of the workload: Deployment, StatefulSet, DaemonSet")
+	spreadCmd.Flags().StringVar(&spreadKind, "kind", "Deployment", "Kind of the workload: Deployment, StatefulSet")
+	unprotectedWorkloadsCmd.Flags().BoolVar(&unprotectedIncludeSystem, "include-system", false, "Include workloads in kube-* namespaces")
+	missingProbesCmd.Flags().BoolVar(&missingProbesIncludeSystem, "include-system", false, "Include workloads in kube-* namespaces")
+	eventsCmd.Flags().StringVar(&eventsType, "type", "", "Only show events of this type: Normal, Warning")
+	eventsCmd.Flags().StringVar(&eventsReason, "reason", "", "Only show events with this reason, e.g. BackOff")
+	eventsCmd.Flags().StringVarP(&eventsNamespace, "namespace", "n", "", "Only show events in this namespace")
+	eventsCmd.Flags().StringVar(&eventsInvolves, "involves", "", "Only show events about a resource, as <kind>/<name>, e.g. Pod/web-0")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", "", "Only show events seen since a duration ago or an RFC3339 timestamp, e.g. 30m")
+	eventsCmd.RegisterFlagCompletionFunc("type", cobra.FixedCompletions([]string{"Normal", "Warning"}, cobra.ShellCompDirectiveNoFileComp))
+	eventsCmd.RegisterFlagCompletionFunc("namespace", withClient(completeNamespaces))
+	addListFlags(nodesCmd, relationshipsCmd, resourcesCmd, podsCmd, servicesCmd, deploymentsCmd, eventsCmd, anomaliesCmd, incidentsCmd, k8sNodesCmd, vulnsCmd)
+	addSelectorFlag(nodesCmd, resourcesCmd, podsCmd, servicesCmd, deploymentsCmd)
+	securityRisksCmd.Flags().StringVar(&securityProfile, "profile", "baseline", "Pod Security Standards profile to evaluate: baseline, restricted")
+	securityRisksCmd.RegisterFlagCompletionFunc("profile", cobra.FixedCompletions([]string{"baseline", "restricted"}, cobra.ShellCompDirectiveNoFileComp))
+	topCmd.Flags().StringVar(&topSortBy, "sort-by", "cpu", "Resource to rank by: cpu, memory")
+	efficiencyCmd.Flags().Float64Var(&efficiencyMaxUtilization, "max-utilization", 30, "Percentage of its requests below which a Deployment is over-provisioned")
+	queryCmd.PersistentFlags().StringVar(&queriesFile, "queries-file", "", "Query library file (default: KUBEGRAPH_QUERIES_FILE env var, or ~/.kubegraph-cli/queries.yaml)")
+	querySaveCmd.Flags().StringVar(&queryDescription, "description", "", "Description shown by query list")
+	queryRunCmd.Flags().StringArrayVar(&queryParams, "param", nil, "Query parameter as name=value (repeatable)")
+	efficiencyCmd.Flags().Int64Var(&efficiencyIdleCPU, "idle-cpu", 10, "CPU usage in millicores below which a namespace is idle")
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// initConfig reads in config file and ENV variables if set
+func initConfig() {
+	// Load .env file first if specified or if default exists
+	loadEnvFile()
+
+	if cfgFile != "" {
+		// Use config file from the flag
+		viper.SetConfigFile(cfgFile)
+	} else {
+		// Find home directory
+		home, err := os.UserHomeDir()
+		cobra.CheckErr(err)
+
+		// Search config in home directory with name ".kubegraph-cli" (without extension)
+		viper.AddConfigPath(home)
+		viper.SetConfigType("yaml")
+		viper.SetConfigName(".kubegraph-cli")
+	}
+
+	// Read environment variables
+	viper.AutomaticEnv()
+
+	// Set defaults
+	viper.SetDefault("neo4j.uri", "neo4j://localhost:7687")
+	viper.SetDefault("neo4j.user", "neo4j")
+	viper.SetDefault("neo4j.pass", "password")
+	viper.SetDefault("log.level", "INFO")
+	viper.SetDefault("output", "table")
+
+	// If a config file is found, read it in
+	if err := viper.ReadInConfig(); err == nil {
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+
+	// Override with environment variables if they exist
+	if uri := os.Getenv("NEO4J_URI"); uri != "" {
+		viper.Set("neo4j.uri", uri)
+	}
+	if user := os.Getenv("NEO4J_USERNAME"); user != "" {
+		viper.Set("neo4j.user", user)
+	}
+	if pass := os.Getenv("NEO4J_PASSWORD"); pass != "" {
+		viper.Set("neo4j.pass", pass)
+	}
+	if uriFile := os.Getenv("NEO4J_URI_FILE"); uriFile != "" {
+		viper.Set("neo4j.uri-file", uriFile)
+	}
+	if userFile := os.Getenv("NEO4J_USERNAME_FILE"); userFile != "" {
+		viper.Set("neo4j.user-file", userFile)
+	}
+	if passFile := os.Getenv("NEO4J_PASSWORD_FILE"); passFile != "" {
+		viper.Set("neo4j.pass-file", passFile)
+	}
+	if backend := os.Getenv("GRAPH_BACKEND"); backend != "" {
+		viper.Set("neo4j.backend", backend)
+	}
+	if database := os.Getenv("NEO4J_DATABASE"); database != "" {
+		viper.Set("neo4j.database", database)
+	}
+	if caCert := os.Getenv("NEO4J_CA_CERT"); caCert != "" {
+		viper.Set("neo4j.ca-cert", caCert)
+	}
+	if clientCert := os.Getenv("NEO4J_CLIENT_CERT"); clientCert != "" {
+		viper.Set("neo4j.client-cert", clientCert)
+	}
+	if clientKey := os.Getenv("NEO4J_CLIENT_KEY"); clientKey != "" {
+		viper.Set("neo4j.client-key", clientKey)
+	}
+	if skipVerify := os.Getenv("NEO4J_TLS_SKIP_VERIFY"); skipVerify != "" {
+		viper.Set("neo4j.tls-skip-verify", skipVerify)
+	}
+	if labels, ok := os.LookupEnv("PROMOTED_LABELS"); ok {
+		viper.Set("promoted.labels", labels)
+	}
+	if cluster := os.Getenv("KUBEGRAPH_CLUSTER_NAME"); cluster != "" {
+		viper.Set("kubernetes.cluster", cluster)
+	}
+	if logLevel := os.Getenv("KUBEGRAPH_LOG_LEVEL"); logLevel != "" {
+		viper.Set("log.level", logLevel)
+	}
+}
+
+// loadEnvFile loads environment variables from a .env file
+func loadEnvFile() {
+	var envFilePath string
+
+	if envFile != "" {
+		// Use env file from the flag (highest priority)
+		envFilePath = envFile
+	} else if envFileEnv := os.Getenv("KUBEGRAPH_ENV_FILE"); envFileEnv != "" {
+		// Use env file from environment variable (second priority)
+		envFilePath = envFileEnv
+	} else {
+		// Try to find default .env file in current directory
+		if _, err := os.Stat(".env"); err == nil {
+			envFilePath = ".env"
+		} else {
+			// Try to find default .env file in home directory
+			home, err := os.UserHomeDir()
+			if err == nil {
+				defaultEnvFile := home + "/.kubegraph-cli.env"
+				if _, err := os.Stat(defaultEnvFile); err == nil {
+					envFilePath = defaultEnvFile
+				}
+			}
+		}
+	}
+
+	if envFilePath == "" {
+		return // No .env file found
+	}
+
+	file, err := os.Open(envFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Could not open .env file %s: %v\n", envFilePath, err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Parse KEY=VALUE format
+		if strings.Contains(line, "=") {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				key := strings.TrimSpace(parts[0])
+				value := strings.TrimSpace(parts[1])
+
+				// Remove quotes if present
+				if (strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"")) ||
+					(strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'")) {
+					value = value[1 : len(value)-1]
+				}
+
+				// Set environment variable if not already set
+				if os.Getenv(key) == "" {
+					os.Setenv(key, value)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: Invalid line %d in .env file %s: %s\n", lineNum, envFilePath, line)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Error reading .env file %s: %v\n", envFilePath, err)
+		return
+	}
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Loaded environment variables from: %s\n", envFilePath)
+	}
+}
+
+func initializeClient() error {
+	// Initialize logger
+	logLevel := viper.GetString("log.level")
+	if quiet {
+		logLevel = "ERROR"
+	}
+	logger.Init(logger.ParseLogLevel(logLevel))
+
+	// Create configuration
 	cfg = config.NewConfig()
 	cfg.Neo4j.URI = viper.GetString("neo4j.uri")
+	if backend := viper.GetString("neo4j.backend"); backend != "" {
+		cfg.Neo4j.Backend = backend
+	}
 	cfg.Neo4j.Username = viper.GetString("neo4j.user")
 	cfg.Neo4j.Password = viper.GetString("neo4j.pass")
+	cfg.Neo4j.URIFile = viper.GetString("neo4j.uri-file")
+	cfg.Neo4j.UsernameFile = viper.GetString("neo4j.user-file")
+	cfg.Neo4j.PasswordFile = viper.GetString("neo4j.pass-file")
+	cfg.Neo4j.Database = viper.GetString("neo4j.database")
+	cfg.Neo4j.CACertPath = viper.GetString("neo4j.ca-cert")
+	cfg.Neo4j.ClientCertPath = viper.GetString("neo4j.client-cert")
+	cfg.Neo4j.ClientKeyPath = viper.GetString("neo4j.client-key")
+	cfg.Neo4j.TLSSkipVerify = viper.GetBool("neo4j.tls-skip-verify")
 	cfg.Kubernetes.ClusterName = viper.GetString("kubernetes.cluster")
+	if viper.IsSet("promoted.labels") {
+		cfg.Promoted.Labels = nil
+		for _, key := range strings.Split(viper.GetString("promoted.labels"), ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.Promoted.Labels = append(cfg.Promoted.Labels, key)
+			}
+		}
+	}
+
+	// Debug: Print the configuration being used
+	if viper.GetBool("debug") {
+		fmt.Fprintf(os.Stderr, "Debug: Using Neo4j URI: %s\n", cfg.Neo4j.URI)
+		fmt.Fprintf(os.Stderr, "Debug: Using Neo4j Username: %s\n", cfg.Neo4j.Username)
+		fmt.Fprintf(os.Stderr, "Debug: Using Neo4j Database: %s\n", cfg.Neo4j.Database)
+		fmt.Fprintf(os.Stderr, "Debug: Using Cluster Name: %s\n", cfg.Kubernetes.ClusterName)
+	}
+
+	// Initialize context
+	ctx = context.Background()
+
+	// Initialize Neo4j client
+	var err error
+	client, err = neo4j.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create Neo4j client: %w", err)
+	}
+
+	return nil
+}
+
+// Exit codes of the CLI, so it can drive CI gates and cron checks
+const (
+	exitResults           = 0 // the command succeeded and found results, or reports none
+	exitError             = 1 // the command failed
+	exitNoResults         = 2 // the command succeeded but found no results
+	exitThresholdExceeded = 3 // the command found more results than --fail-threshold
+)
+
+// exitCode maps the number of results of a successful command to its exit code
+func exitCode(results, threshold int) int {
+	switch {
+	case results < 0:
+		return exitResults
+	case results == 0:
+		return exitNoResults
+	case threshold >= 0 && results > threshold:
+		return exitThresholdExceeded
+	}
+	return exitResults
+}
+
+func main() {
+	err := rootCmd.Execute()
+	if err != nil {
+		os.Exit(exitError)
+	}
+	os.Exit(exitCode(resultCount, failThreshold))
+}
+
+func handleNodes(args []string) {
+	if len(args) == 0 {
+		// Show all node types
+		query := `
+			MATCH (n)
+			WITH labels(n)[0] as label
+			RETURN label, count(*) as count
+			ORDER BY count DESC`
+		executeQuery(query, "Node Types")
+		return
+	}
+
+	nodeType := args[0]
+	match, params := listMatch(fmt.Sprintf("(n:%s)", nodeType), "n")
+	executeListWithParams(match, params, []string{"n.name as name", "n.namespace as namespace", "n.clusterName as cluster"},
+		"name", positionalLimit(args, 1, 10), fmt.Sprintf("%s Nodes", nodeType))
+}
+
+func handleRelationships(args []string) {
+	if len(args) == 0 {
+		// Show all relationship types
+		query := fmt.Sprintf(`
+			MATCH ()-[r]->()
+			%s
+			WITH type(r) as relationshipType, count(*) as count
+			RETURN relationshipType, count
+			ORDER BY count DESC`,
+			getClusterFilterForRelationships())
+		executeQuery(query, "Relationship Types")
+		return
+	}
+
+	relType := args[0]
+	match := fmt.Sprintf(`
+		MATCH (a)-[r:%s]->(b)
+		%s`, relType, getClusterFilterForRelationships())
+
+	executeList(match, []string{"labels(a)[0] as from_type", "a.name as from_name",
+		"labels(b)[0] as to_type", "b.name as to_name", "a.clusterName as cluster"},
+		"from_name, to_name", positionalLimit(args, 1, 10), fmt.Sprintf("%s Relationships", relType))
+}
+
+func handleResources() {
+	match, params := listMatch("(n)", "n")
+	executeListWithParams(match, params, []string{"labels(n)[0] as type", "n.clusterName as cluster", "count(*) as count"},
+		"type, cluster, count DESC", 0, "Resource Counts")
+}
+
+func handlePods(args []string) {
+	match, params := listMatch("(p:Pod)", "p")
+	executeListWithParams(match, params, []string{"p.name as name", "p.namespace as namespace", "p.status as status", "p.clusterName as cluster"},
+		"namespace, name", 0, "Pods", namespaceFilter(args)...)
+}
+
+func handleServices(args []string) {
+	match, params := listMatch("(s:Service)", "s")
+	executeListWithParams(match, params, []string{"s.name as name", "s.namespace as namespace", "s.type as type", "s.clusterName as cluster"},
+		"namespace, name", 0, "Services", namespaceFilter(args)...)
+}
+
+func handleDeployments(args []string) {
+	match, params := listMatch("(d:Deployment)", "d")
+	executeListWithParams(match, params, []string{"d.name as name", "d.namespace as namespace", "d.replicas as replicas", "d.clusterName as cluster"},
+		"namespace, name", 0, "Deployments", namespaceFilter(args)...)
+}
+
+func handleEvents(args []string) {
+	query, params, err := eventsQuery(args)
+	if err != nil {
+		logger.Error("%v", err)
+		os.Exit(exitError)
+	}
+	executeQueryWithParams(query, params, "Recent Events")
+}
+
+// eventsQuery builds the query of the events command from its flags
+func eventsQuery(args []string) (string, map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	var conditions []string
+	if cluster := getSelectedCluster(); cluster != "" {
+		conditions = append(conditions, "e.clusterName = $cluster")
+		params["cluster"] = cluster
+	}
+	if team != "" {
+		conditions = append(conditions, "e.team = $team")
+		params["team"] = team
+	}
+	if eventsSince != "" {
+		// createdAt is when the event was last written, see the anomaly detector
+		since, err := parseTimeArg(eventsSince)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid --since %s: %w", eventsSince, err)
+		}
+		conditions = append(conditions, "e.createdAt >= $since")
+		params["since"] = since
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	involves := "OPTIONAL MATCH (e)-[:INVOLVES]->(o)"
+	if eventsInvolves != "" {
+		kind, name, ok := strings.Cut(eventsInvolves, "/")
+		if !ok || kind == "" || name == "" {
+			return "", nil, fmt.Errorf("invalid --involves %s, expected <kind>/<name>", eventsInvolves)
+		}
+		involves = fmt.Sprintf("MATCH (e)-[:INVOLVES]->(o:%s {name: $involvedName})", quoteLabel(kind))
+		params["involvedName"] = name
+	}
+
+	var filters []string
+	if eventsType != "" {
+		filters = append(filters, "type="+eventsType)
+	}
+	if eventsReason != "" {
+		filters = append(filters, "reason="+eventsReason)
+	}
+	if eventsNamespace != "" {
+		filters = append(filters, "namespace="+eventsNamespace)
+	}
+
+	match := fmt.Sprintf(`
+		MATCH (e:Event)
+		%s
+		%s`, where, involves)
+	query, filterParams, err := listOpts.query(match, []string{"e.name as name", "e.namespace as namespace", "e.type as type", "e.reason as reason",
+		"labels(o)[0] + '/' + o.name as involves", "e.message as message", "e.lastTimestamp as last_seen", "e.clusterName as cluster"},
+		"last_seen DESC", positionalLimit(args, 0, 20), filters...)
+	if err != nil {
+		return "", nil, err
+	}
+	for name, value := range filterParams {
+		params[name] = value
+	}
+	return query, params, nil
+}
+
+func handleAnomalies(args []string) {
+	match := fmt.Sprintf(`
+		MATCH (a:Anomaly)
+		%s`, getClusterFilterWithVar("a"))
+
+	executeList(match, []string{"a.detectedAt as detected", "a.workloadKind as kind", "a.namespace as namespace", "a.name as name",
+		"a.metric as metric", "a.value as value", "round(a.baseline * 10) / 10 as baseline",
+		"round(a.score * 10) / 10 as score", "a.clusterName as cluster"},
+		"detected DESC", positionalLimit(args, 0, 20), "Workload Anomalies")
+}
+
+func handleIncidents(args []string) {
+	conditions := []string{}
+	if incidentsOpenOnly {
+		conditions = append(conditions, "i.status = 'open'")
+	}
+	if filter := getClusterFilterWithVar("i"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+	match := "MATCH (i:Incident)"
+	if len(conditions) > 0 {
+		match += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+
+	executeList(match, []string{"i.status as status", "i.severity as severity", "toInteger(i.score) as score",
+		"i.workloadKind as kind", "i.namespace as namespace", "i.name as name", "toInteger(i.warnings) as warnings",
+		"i.topReason as reason", "i.startedAt as started", "i.lastSeenAt as lastSeen", "i.clusterName as cluster"},
+		"CASE status WHEN 'open' THEN 0 ELSE 1 END, score DESC, lastSeen DESC", positionalLimit(args, 0, 20), "Incidents")
+}
+
+func handleHistory(args []string) {
+	resourceType := args[0]
+	resourceName := args[1]
+
+	conditions := []string{
+		fmt.Sprintf("v.kind = '%s'", resourceType),
+		fmt.Sprintf("v.name = '%s'", resourceName),
+	}
+	if len(args) > 2 {
+		conditions = append(conditions, fmt.Sprintf("v.namespace = '%s'", args[2]))
+	}
+	if filter := getClusterFilterWithVar("v"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (v:ResourceVersion)
+		WHERE %s
+		RETURN v.validFrom as validFrom,
+		       coalesce(v.validTo, 'current') as validTo,
+		       v.namespace as namespace,
+		       substring(v.contentHash, 0, 12) as version,
+		       v.changedKeys as changed,
+		       v.clusterName as cluster
+		ORDER BY v.validFrom DESC`, strings.Join(conditions, " AND "))
+
+	executeQuery(query, fmt.Sprintf("History of %s %s", resourceType, resourceName))
+}
+
+// revisionLabels are the nodes holding the revisions of each kind of workload
+var revisionLabels = map[string]string{
+	"Deployment":  "ReplicaSet",
+	"StatefulSet": "ControllerRevision",
+	"DaemonSet":   "ControllerRevision",
+}
+
+// rolloutRevision is a revision of a workload's pod template
+type rolloutRevision struct {
+	revision int64
+	name     string
+	created  string
+	replicas string
+	images   map[string]string
+}
+
+func handleRollouts(args []string) {
+	revisionLabel, ok := revisionLabels[rolloutsKind]
+	if !ok {
+		logger.Error("Invalid kind %s: must be one of Deployment, StatefulSet, DaemonSet", rolloutsKind)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"w.name = $name", "r.revision IS NOT NULL", "toInteger(r.revision) > 0"}
+	params := map[string]interface{}{"name": args[0]}
+	if len(args) > 1 {
+		conditions = append(conditions, "w.namespace = $namespace")
+		params["namespace"] = args[1]
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (r:%s)-[:OWNED_BY]->(w:%s)
+		WHERE %s
+		RETURN w.clusterName AS cluster, w.namespace AS namespace, toInteger(r.revision) AS revision, r.name AS name,
+		       r.creationTimestamp AS created, r.replicas AS replicas, r.containerImages AS images
+		ORDER BY cluster, namespace, revision`, revisionLabel, rolloutsKind, strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, params)
+	if len(records) == 0 {
+		printNoResults("No revisions found for %s %s\n", rolloutsKind, args[0])
+		return
+	}
+
+	// Revisions are listed per workload, as the name may exist in several namespaces or clusters
+	histories := make(map[string][]rolloutRevision)
+	workloads := make([]string, 0)
+	for _, record := range records {
+		workload := recordString(record, "cluster") + "\t" + recordString(record, "namespace")
+		if _, ok := histories[workload]; !ok {
+			workloads = append(workloads, workload)
+		}
+		images := make(map[string]string)
+		if data, ok := record.AsMap()["images"].(string); ok {
+			json.Unmarshal([]byte(data), &images)
+		}
+		replicas := recordString(record, "replicas")
+		if replicas == "null" {
+			replicas = ""
+		}
+		histories[workload] = append(histories[workload], rolloutRevision{
+			revision: recordInt64(record, "revision"),
+			name:     recordString(record, "name"),
+			created:  recordString(record, "created"),
+			replicas: replicas,
+			images:   images,
+		})
+	}
+
+	keys := []string{"cluster", "namespace", "revision", "name", "created", "replicas", "images", "changes"}
+	values := make([][]string, 0, len(records))
+	for _, workload := range workloads {
+		cluster, namespace, _ := strings.Cut(workload, "\t")
+		revisions := histories[workload]
+		for i := len(revisions) - 1; i >= 0; i-- {
+			current := revisions[i]
+			revision := strconv.FormatInt(current.revision, 10)
+			if i == len(revisions)-1 {
+				revision += " (current)"
+			}
+			changes := "initial"
+			if i > 0 {
+				changes = strings.Join(imageChanges(revisions[i-1].images, current.images), ", ")
+			}
+			values = append(values, []string{cluster, namespace, revision, current.name, current.created,
+				current.replicas, strings.Join(imageList(current.images), ", "), changes})
+		}
+	}
+	printTable(fmt.Sprintf("Rollout History of %s %s", rolloutsKind, args[0]), keys, values)
+}
+
+// imageList returns the container images of a revision as container=image, by container name
+func imageList(images map[string]string) []string {
+	list := make([]string, 0, len(images))
+	for _, container := range sortedMapKeys(images) {
+		list = append(list, container+"="+images[container])
+	}
+	return list
+}
+
+// imageChanges describes how the container images changed between two
+// revisions. A revision that only changed other parts of the pod template,
+// such as environment variables, has no image changes.
+func imageChanges(previous, current map[string]string) []string {
+	changes := make([]string, 0)
+	for _, container := range sortedMapKeys(current) {
+		before, ok := previous[container]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+%s=%s", container, current[container]))
+		case before != current[container]:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", container, before, current[container]))
+		}
+	}
+	for _, container := range sortedMapKeys(previous) {
+		if _, ok := current[container]; !ok {
+			changes = append(changes, "-"+container)
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "no image changes")
+	}
+	return changes
+}
+
+// sortedMapKeys returns the keys of m in order
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// templateFields maps the pod template properties stored by the workload
+// handlers to the name of the field they are compared as
+var templateFields = map[string]string{
+	"containerImages":    "image",
+	"containerEnv":       "env",
+	"containerResources": "resources",
+	"containerProbes":    "probes",
+	"containerLifecycle": "lifecycle",
+}
+
+// flattenTemplate turns the pod template properties of a workload, JSON maps
+// by container name, into "<field>/<container>" fields
+func flattenTemplate(properties map[string]string) map[string]string {
+	fields := make(map[string]string)
+	for property, field := range templateFields {
+		containers := make(map[string]string)
+		json.Unmarshal([]byte(properties[property]), &containers)
+		for container, value := range containers {
+			fields[field+"/"+container] = value
+		}
+	}
+	if replicas := properties["replicas"]; replicas != "" && replicas != "null" {
+		fields["replicas"] = replicas
+	}
+	return fields
+}
+
+// templateDrift returns, for every field whose value is not the same in all
+// templates, the field followed by its value in each template, "-" when unset
+func templateDrift(templates []map[string]string) [][]string {
+	fields := make(map[string]string)
+	for _, template := range templates {
+		for field := range template {
+			fields[field] = field
+		}
+	}
+	rows := make([][]string, 0)
+	for _, field := range sortedMapKeys(fields) {
+		row := []string{field}
+		drifted := false
+		for i, template := range templates {
+			value, ok := template[field]
+			if !ok {
+				value = "-"
+			}
+			if i > 0 && value != row[1] {
+				drifted = true
+			}
+			row = append(row, value)
+		}
+		if drifted {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func handleDrift(args []string) {
+	if _, ok := revisionLabels[driftKind]; !ok {
+		logger.Error("Invalid kind %s: must be one of Deployment, StatefulSet, DaemonSet", driftKind)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"w.name = $name"}
+	params := map[string]interface{}{"name": args[0]}
+	if len(args) > 1 {
+		conditions = append(conditions, "w.namespace = $namespace")
+		params["namespace"] = args[1]
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (w:%s)
+		WHERE %s
+		RETURN w.clusterName AS cluster, w.namespace AS namespace, w.replicas AS replicas, w.templateHash AS templateHash,
+		       w.containerImages AS containerImages, w.containerEnv AS containerEnv,
+		       w.containerResources AS containerResources, w.containerProbes AS containerProbes,
+		       w.containerLifecycle AS containerLifecycle
+		ORDER BY cluster, namespace`, driftKind, strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, params)
+	if len(records) < 2 {
+		printNoResults("%s %s is not deployed to more than one cluster or namespace\n", driftKind, args[0])
+		return
+	}
+
+	keys := []string{"field"}
+	templates := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		if recordString(record, "templateHash") == "" {
+			logger.Warn("%s %s in %s/%s has no pod template properties yet, it was synced by an older KubeGraph",
+				driftKind, args[0], recordString(record, "cluster"), recordString(record, "namespace"))
+		}
+		keys = append(keys, recordString(record, "cluster")+"/"+recordString(record, "namespace"))
+		properties := map[string]string{"replicas": recordString(record, "replicas")}
+		for property := range templateFields {
+			properties[property] = recordString(record, property)
+		}
+		templates = append(templates, flattenTemplate(properties))
+	}
+
+	rows := templateDrift(templates)
+	if len(rows) == 0 {
+		printNoResults("No drift found for %s %s across %d clusters and namespaces\n", driftKind, args[0], len(records))
+		return
+	}
+	printTable(fmt.Sprintf("Drift of %s %s", driftKind, args[0]), keys, rows)
+}
+
+// nodeZone returns the zone of a node from its labels, empty when not labeled
+func nodeZone(labels map[string]string) string {
+	if zone := labels["topology.kubernetes.io/zone"]; zone != "" {
+		return zone
+	}
+	return labels["failure-domain.beta.kubernetes.io/zone"]
+}
+
+// spreadFindings flags the placements of the scheduled replicas of a workload
+// that do not survive the loss of a zone or node. zones holds the replicas by
+// zone, including the zones of the cluster without replicas, and nodes the
+// replicas by node.
+func spreadFindings(zones, nodes map[string]int) []string {
+	replicas := 0
+	for _, count := range nodes {
+		replicas += count
+	}
+	if replicas < 2 {
+		return nil
+	}
+
+	var findings []string
+	used := make([]string, 0, len(zones))
+	minimum, maximum := replicas, 0
+	for _, zone := range sortedIntMapKeys(zones) {
+		if zones[zone] > 0 {
+			used = append(used, zone)
+		}
+		minimum = min(minimum, zones[zone])
+		maximum = max(maximum, zones[zone])
+	}
+	switch {
+	case len(zones) > 1 && len(used) == 1:
+		findings = append(findings, fmt.Sprintf("all %d replicas in zone %s of %d", replicas, used[0], len(zones)))
+	case len(zones) > 1 && maximum-minimum > 1:
+		findings = append(findings, fmt.Sprintf("zone skew %d (%d to %d replicas per zone)", maximum-minimum, minimum, maximum))
+	}
+	if len(nodes) == 1 {
+		for node := range nodes {
+			findings = append(findings, fmt.Sprintf("all %d replicas on node %s", replicas, node))
+		}
+	}
+	return findings
+}
+
+// sortedIntMapKeys returns the keys of m in order
+func sortedIntMapKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func handleSpread(args []string) {
+	if spreadKind != "Deployment" && spreadKind != "StatefulSet" {
+		logger.Error("Invalid kind %s: must be one of Deployment, StatefulSet", spreadKind)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"w.name = $name", "NOT p.status IN ['Succeeded', 'Failed']"}
+	params := map[string]interface{}{"name": args[0]}
+	if len(args) > 1 {
+		conditions = append(conditions, "w.namespace = $namespace")
+		params["namespace"] = args[1]
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Deployment pods are owned through their ReplicaSet
+	records := collectRecords(fmt.Sprintf(`
+		MATCH (p:Pod)-[:OWNED_BY*1..2]->(w:%s)
+		WHERE %s
+		OPTIONAL MATCH (p)-[:SCHEDULED_ON]->(n:Node)
+		WHERE n.clusterName = p.clusterName
+		RETURN w.clusterName AS cluster, w.namespace AS namespace, n.name AS node, n.labels AS nodeLabels
+		ORDER BY cluster, namespace`, spreadKind, strings.Join(conditions, " AND ")), params)
+	if len(records) == 0 {
+		printNoResults("No running pods found for %s %s\n", spreadKind, args[0])
+		return
+	}
+
+	// Zones of every cluster the workload runs in, to spot zones without replicas
+	clusterZones := make(map[string]map[string]bool)
+	clusters := make([]string, 0)
+	for _, record := range records {
+		if cluster := recordString(record, "cluster"); clusterZones[cluster] == nil {
+			clusterZones[cluster] = make(map[string]bool)
+			clusters = append(clusters, cluster)
+		}
+	}
+	for _, record := range collectRecords(`
+		MATCH (n:Node)
+		WHERE n.clusterName IN $clusters
+		RETURN n.clusterName AS cluster, n.labels AS labels`, map[string]interface{}{"clusters": clusters}) {
+		labels := make(map[string]string)
+		json.Unmarshal([]byte(recordString(record, "labels")), &labels)
+		if zone := nodeZone(labels); zone != "" {
+			clusterZones[recordString(record, "cluster")][zone] = true
+		}
+	}
+
+	type placement struct {
+		cluster, namespace string
+		zones, nodes       map[string]int
+		nodeZones          map[string]string
+		pending            int
+	}
+	placements := make([]*placement, 0)
+	byWorkload := make(map[string]*placement)
+	for _, record := range records {
+		cluster, namespace := recordString(record, "cluster"), recordString(record, "namespace")
+		p, ok := byWorkload[cluster+"/"+namespace]
+		if !ok {
+			p = &placement{cluster: cluster, namespace: namespace, zones: make(map[string]int), nodes: make(map[string]int), nodeZones: make(map[string]string)}
+			for zone := range clusterZones[cluster] {
+				p.zones[zone] = 0
+			}
+			byWorkload[cluster+"/"+namespace] = p
+			placements = append(placements, p)
+		}
+		node := recordString(record, "node")
+		if node == "" {
+			p.pending++
+			continue
+		}
+		labels := make(map[string]string)
+		json.Unmarshal([]byte(recordString(record, "nodeLabels")), &labels)
+		zone := nodeZone(labels)
+		p.nodes[node]++
+		p.nodeZones[node] = zone
+		if zone != "" {
+			p.zones[zone]++
+		}
+	}
+
+	keys := []string{"cluster", "namespace", "zone", "node", "pods"}
+	values := make([][]string, 0)
+	var findings []string
+	for _, p := range placements {
+		// Zones without replicas are listed so the gaps are visible
+		rows := make([][]string, 0, len(p.zones)+len(p.nodes))
+		for _, zone := range sortedIntMapKeys(p.zones) {
+			if p.zones[zone] == 0 {
+				rows = append(rows, []string{p.cluster, p.namespace, zone, "-", "0"})
+			}
+		}
+		for _, node := range sortedIntMapKeys(p.nodes) {
+			zone := p.nodeZones[node]
+			if zone == "" {
+				zone = "-"
+			}
+			rows = append(rows, []string{p.cluster, p.namespace, zone, node, strconv.Itoa(p.nodes[node])})
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i][2] < rows[j][2] })
+		if p.pending > 0 {
+			rows = append(rows, []string{p.cluster, p.namespace, "-", "(unscheduled)", strconv.Itoa(p.pending)})
+		}
+		values = append(values, rows...)
+		for _, finding := range spreadFindings(p.zones, p.nodes) {
+			findings = append(findings, fmt.Sprintf("%s/%s: %s", p.cluster, p.namespace, finding))
+		}
+	}
+
+	printTable(fmt.Sprintf("Spread of %s %s", spreadKind, args[0]), keys, values)
+	if !quiet {
+		for _, finding := range findings {
+			fmt.Printf("Warning: %s\n", finding)
+		}
+		if len(findings) > 0 {
+			fmt.Println()
+		}
+	}
+}
+
+func handleChanges(args []string) {
+	since := "1h"
+	if len(args) > 1 {
+		since = args[1]
+	}
+	sinceTime, err := parseTimeArg(since)
+	if err != nil {
+		logger.Error("Invalid time %s: %v", since, err)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{fmt.Sprintf("c.timestamp >= '%s'", sinceTime)}
+	if len(args) > 0 && args[0] != "all" {
+		conditions = append(conditions, fmt.Sprintf("c.kind = '%s'", args[0]))
+	}
+	if filter := getClusterFilterWithVar("c"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (c:GraphChange)
+		WHERE %s
+		RETURN c.timestamp as timestamp, c.operation as operation, c.kind as kind,
+		       c.namespace as namespace, c.name as name, c.uid as uid, c.instanceHash as instanceHash
+		ORDER BY c.timestamp DESC
+		LIMIT 500`, strings.Join(conditions, " AND "))
+
+	executeQuery(query, fmt.Sprintf("Graph Changes since %s", sinceTime))
+}
+
+// portMismatch describes a Service port whose targetPort is not declared by
+// all of the pods the Service selects
+type portMismatch struct {
+	Port         string
+	TargetPort   string
+	Protocol     string
+	MatchingPods int
+	Pods         int
+	Reason       string
+}
+
+// parsePortInfo parses a "key=value;key=value" port description as stored
+// in the ports property of Services and the containerPorts property of Pods
+func parsePortInfo(info string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(info, ";") {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// defaultProtocol returns protocol, or TCP when it is unset
+func defaultProtocol(protocol string) string {
+	if protocol == "" {
+		return "TCP"
+	}
+	return protocol
+}
+
+// findPortMismatches returns the Service ports whose targetPort doesn't match
+// a container port of every selected pod. podPorts holds the containerPorts
+// of each selected pod.
+func findPortMismatches(servicePorts []string, podPorts [][]string) []portMismatch {
+	mismatches := make([]portMismatch, 0)
+	for _, servicePort := range servicePorts {
+		svcPort := parsePortInfo(servicePort)
+		targetPort := svcPort["targetPort"]
+		if targetPort == "" || targetPort == "0" {
+			targetPort = svcPort["port"]
+		}
+		protocol := defaultProtocol(svcPort["protocol"])
+		_, err := strconv.Atoi(targetPort)
+		named := err != nil
+
+		matching := 0
+		for _, ports := range podPorts {
+			for _, containerPort := range ports {
+				ctrPort := parsePortInfo(containerPort)
+				if defaultProtocol(ctrPort["protocol"]) != protocol {
+					continue
+				}
+				if (named && ctrPort["name"] == targetPort) || (!named && ctrPort["containerPort"] == targetPort) {
+					matching++
+					break
+				}
+			}
+		}
+
+		if matching == len(podPorts) {
+			continue
+		}
+
+		reason := "port not declared by container"
+		if named {
+			reason = "named port not defined by container"
+		}
+		mismatches = append(mismatches, portMismatch{
+			Port:         svcPort["port"],
+			TargetPort:   targetPort,
+			Protocol:     protocol,
+			MatchingPods: matching,
+			Pods:         len(podPorts),
+			Reason:       reason,
+		})
+	}
+	return mismatches
+}
+
+func handlePendingReboots() {
+	conditions := []string{"n.rebootRequired = 'true'"}
+	if filter := getClusterFilterWithVar("n"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Pods owned by a ReplicaSet are reported as their Deployment
+	query := fmt.Sprintf(`
+		MATCH (n:Node)
+		WHERE %s
+		OPTIONAL MATCH (p:Pod)-[:SCHEDULED_ON]->(n)
+		OPTIONAL MATCH (p)-[:OWNED_BY]->(owner)
+		OPTIONAL MATCH (owner)-[:OWNED_BY]->(deployment:Deployment)
+		OPTIONAL MATCH (pdb:PodDisruptionBudget)-[:PROTECTS]->(p)
+		WITH n, p, coalesce(deployment, owner) AS workload, pdb
+		RETURN n.clusterName as cluster, n.name as node, n.rebootSource as source,
+		       n.rebootRequiredAt as since, n.rebootInProgress as inProgress,
+		       CASE WHEN n.osVersion <> '' THEN n.osVersion ELSE n.osVersionID END as osVersion, n.pendingOSVersion as pendingVersion,
+		       count(DISTINCT p) as pods,
+		       collect(DISTINCT labels(workload)[0] + ' ' + workload.namespace + '/' + workload.name) as workloads,
+		       collect(DISTINCT CASE WHEN pdb.disruptionsAllowed = '0' THEN pdb.namespace + '/' + pdb.name END) as blockingPDBs
+		ORDER BY cluster, node`,
+		strings.Join(conditions, " AND "))
+
+	executeQuery(query, "Nodes Pending Reboot")
+}
+
+// drainNote explains how kubectl drain treats a pod with a controller of
+// controllerKind, and whether a PodDisruptionBudget blocks its eviction
+func drainNote(status, controllerKind string, blocked bool) string {
+	switch {
+	case status == "Succeeded" || status == "Failed":
+		return "completed, deleted"
+	case controllerKind == "DaemonSet":
+		return "DaemonSet, not evicted"
+	case controllerKind == "":
+		return "no controller, needs --force and is not recreated"
+	case blocked:
+		return "eviction blocked by PDB"
+	}
+	return "evicted"
+}
+
+func handleDrainImpact(node string) {
+	conditions := []string{"n.name = $node"}
+	if filter := getClusterFilterWithVar("n"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Pods owned by a ReplicaSet are reported as their Deployment. A Service
+	// loses its endpoints when no pod it selects runs elsewhere.
+	records := collectRecords(fmt.Sprintf(`
+		MATCH (n:Node)
+		WHERE %s
+		MATCH (p:Pod)-[:SCHEDULED_ON]->(n)
+		WHERE p.clusterName = n.clusterName
+		OPTIONAL MATCH (p)-[:OWNED_BY]->(owner)
+		OPTIONAL MATCH (owner)-[:OWNED_BY]->(deployment:Deployment)
+		WITH n, p, coalesce(deployment, owner) AS controller
+		OPTIONAL MATCH (pdb:PodDisruptionBudget)-[:PROTECTS]->(p)
+		WITH n, p, controller, collect(DISTINCT pdb) AS pdbs
+		OPTIONAL MATCH (s:Service)-[:SELECTS]->(p)
+		OPTIONAL MATCH (s)-[:SELECTS]->(other:Pod)
+		WHERE other.status = 'Running' AND NOT (other)-[:SCHEDULED_ON]->(n)
+		WITH n, p, controller, pdbs, s, count(other) AS elsewhere
+		WITH n, p, controller, pdbs,
+		     collect(DISTINCT s.namespace + '/' + s.name) AS services,
+		     collect(DISTINCT CASE WHEN elsewhere = 0 THEN s.namespace + '/' + s.name END) AS losesEndpoints
+		RETURN n.clusterName AS cluster, p.namespace AS namespace, p.name AS pod, p.status AS status,
+		       labels(controller)[0] AS controllerKind, controller.name AS controllerName,
+		       [pdb IN pdbs | pdb.namespace + '/' + pdb.name + ' (' + toString(pdb.disruptionsAllowed) + ' allowed)'] AS pdbs,
+		       any(pdb IN pdbs WHERE toString(pdb.disruptionsAllowed) = '0') AS blocked,
+		       services, losesEndpoints
+		ORDER BY cluster, namespace, pod`,
+		strings.Join(conditions, " AND ")), map[string]interface{}{"node": node})
+	if len(records) == 0 {
+		printNoResults("No pods found on node %s\n", node)
+		return
+	}
+
+	keys := []string{"cluster", "namespace", "pod", "status", "controller", "pdbs", "drain", "services", "losesEndpoints"}
+	values := make([][]string, 0, len(records))
+	blocked, losing := 0, make(map[string]bool)
+	for _, record := range records {
+		controller := ""
+		if kind := recordString(record, "controllerKind"); kind != "" {
+			controller = kind + "/" + recordString(record, "controllerName")
+		}
+		isBlocked := recordString(record, "blocked") == "true"
+		note := drainNote(recordString(record, "status"), recordString(record, "controllerKind"), isBlocked)
+		if note == "eviction blocked by PDB" {
+			blocked++
+		}
+		lists := make([]string, 0, 3)
+		for _, key := range []string{"pdbs", "services", "losesEndpoints"} {
+			value, _ := record.Get(key)
+			items := make([]string, 0)
+			for _, item := range value.([]interface{}) {
+				items = append(items, fmt.Sprintf("%v", item))
+				if key == "losesEndpoints" {
+					losing[fmt.Sprintf("%v", item)] = true
+				}
+			}
+			lists = append(lists, strings.Join(items, ","))
+		}
+		values = append(values, []string{recordString(record, "cluster"), recordString(record, "namespace"), recordString(record, "pod"),
+			recordString(record, "status"), controller, lists[0], note, lists[1], lists[2]})
+	}
+
+	printTable(fmt.Sprintf("Impact of Draining %s", node), keys, values)
+	if !quiet {
+		fmt.Printf("%d pods, %d blocked by a PodDisruptionBudget, %d services losing all endpoints\n\n", len(values), blocked, len(losing))
+	}
+}
+
+func handleVulns(args []string) {
+	conditions := []string{"i.scannedAt IS NOT NULL"}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Images run by several pods of a workload are only counted once
+	match := fmt.Sprintf(`
+		MATCH (p:Pod)-[:RUNS_IMAGE]->(i:Image)
+		WHERE %s
+		OPTIONAL MATCH (p)-[:OWNED_BY]->(o)
+		OPTIONAL MATCH (o)-[:OWNED_BY]->(top)
+		WHERE (o:ReplicaSet AND top:Deployment) OR (o:Job AND top:CronJob)
+		WITH coalesce(top, o, p) AS w, collect(DISTINCT i) AS images
+		WITH w, images,
+		     reduce(n = 0, x IN images | n + coalesce(toInteger(x.criticalCount), 0)) AS critical,
+		     reduce(n = 0, x IN images | n + coalesce(toInteger(x.highCount), 0)) AS high,
+		     reduce(n = 0, x IN images | n + coalesce(toInteger(x.mediumCount), 0)) AS medium,
+		     reduce(n = 0, x IN images | n + coalesce(toInteger(x.lowCount), 0)) AS low,
+		     reduce(n = 0, x IN images | n + coalesce(toInteger(x.fixableCount), 0)) AS fixable`,
+		strings.Join(conditions, " AND "))
+
+	executeList(match, []string{"w.clusterName as cluster", "labels(w)[0] as kind", "w.namespace as namespace", "w.name as name",
+		"size(images) as images", "critical", "high", "medium", "low", "fixable"},
+		"critical DESC, high DESC, medium DESC, cluster, namespace, name", positionalLimit(args, 0, 20), "Workloads by Vulnerability Exposure")
+}
+
+func handleVulnsImport(files []string) {
+	cluster := getSelectedCluster()
+	keys := []string{"file", "image", "scanner", "critical", "high", "medium", "low", "fixable"}
+	values := make([][]string, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.Error("Failed to read report: %v", err)
+			os.Exit(exitError)
+		}
+		reports, err := vulns.ParseReports(data)
+		if err != nil {
+			logger.Error("Failed to parse %s: %v", file, err)
+			os.Exit(exitError)
+		}
+		for _, report := range reports {
+			if err := vulns.Write(ctx, client, cluster, report); err != nil {
+				logger.Error("%v", err)
+				os.Exit(exitError)
+			}
+			counts := report.SeverityCounts()
+			values = append(values, []string{file, vulns.NormalizeImage(report.Image), report.Scanner,
+				strconv.Itoa(counts["CRITICAL"]), strconv.Itoa(counts["HIGH"]), strconv.Itoa(counts["MEDIUM"]),
+				strconv.Itoa(counts["LOW"]), strconv.Itoa(report.FixableCount())})
+		}
+	}
+	printTable(fmt.Sprintf("Imported Vulnerability Reports (cluster %s)", cluster), keys, values)
+}
+
+func handleSyncStatus() {
+	query := fmt.Sprintf(`
+		MATCH (n:SyncStatus)
+		%s
+		RETURN n.clusterName as cluster, n.kind as kind, n.state as state,
+		       n.listed as listed, n.written as written, n.remaining as remaining,
+		       n.startedAt as startedAt, n.completedAt as completedAt, n.updatedAt as updatedAt
+		ORDER BY cluster, CASE n.state WHEN 'complete' THEN 1 ELSE 0 END, kind`,
+		getClusterFilterWithVar("n"))
+
+	executeQuery(query, "Initial Sync Status")
+}
+
+func handleExport(args []string) {
+	out := os.Stdout
+	if args[0] != "-" {
+		file, err := os.Create(args[0])
+		if err != nil {
+			logger.Error("Failed to create export file: %v", err)
+			os.Exit(exitError)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := bufio.NewWriter(out)
+	stats, err := client.ExportGraph(ctx, writer, getSelectedCluster(), team)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		logger.Error("Failed to export graph: %v", err)
+		os.Exit(exitError)
+	}
+
+	if args[0] != "-" {
+		fmt.Printf("Exported %d nodes and %d relationships to %s\n", stats.Nodes, stats.Relationships, args[0])
+	}
+}
+
+func handleImport(args []string) {
+	in := os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			logger.Error("Failed to open import file: %v", err)
+			os.Exit(exitError)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	stats, err := client.ImportGraph(ctx, in, importDatabase, importBatchSize, importForce)
+	if err != nil {
+		logger.Error("Failed to import graph: %v", err)
+		os.Exit(exitError)
+	}
+
+	target := importDatabase
+	if target == "" {
+		target = cfg.Neo4j.Database
+	}
+	if target == "" {
+		target = "the default database"
+	}
+	fmt.Printf("Imported %d nodes and %d relationships into %s\n", stats.Nodes, stats.Relationships, target)
+}
+
+func handleLoad(args []string) {
+	in := os.Stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			logger.Error("Failed to open mutation file: %v", err)
+			os.Exit(exitError)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	stats, err := graph.Load(ctx, in, client)
+	if err != nil {
+		logger.Error("Failed to load mutations: %v", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Loaded %d node upserts, %d relationship upserts, %d deletes and %d queries\n",
+		stats[graph.OpUpsertNode], stats[graph.OpUpsertRelationship], stats[graph.OpDelete], stats[graph.OpQuery])
+}
+
+// siteExcludedLabels are left out of exported sites: the history and audit
+// nodes of resources, and Events unless --include-events is given
+var siteExcludedLabels = []string{"ResourceVersion", "GraphChange"}
+
+// newSiteNode converts a graph node for an exported site
+func newSiteNode(node driverneo4j.Node) site.Node {
+	return site.NewNode(node.ElementId, node.Labels, node.Props)
+}
+
+func handleExportSite() {
+	excluded := siteExcludedLabels
+	if !siteIncludeEvents {
+		excluded = append(excluded, "Event")
+	}
+
+	conditions := []string{"n.namespace = $namespace", "NOT any(l IN labels(n) WHERE l IN $excluded)"}
+	neighborConditions := []string{"coalesce(b.namespace, '') = ''", "NOT any(l IN labels(b) WHERE l IN $excluded)"}
+	if filter := getClusterFilterWithVar("n"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+		neighborConditions = append(neighborConditions, strings.TrimPrefix(getClusterFilterWithVar("b"), "WHERE "))
+	}
+
+	// Relationships between resources of the namespace are matched from both
+	// ends and deduplicated below
+	query := fmt.Sprintf(`
+		MATCH (n)
+		WHERE %s
+		WITH collect(n) AS inside
+		UNWIND inside AS a
+		OPTIONAL MATCH (a)-[r]-(b)
+		WHERE b IN inside OR (%s)
+		RETURN a, r, b`,
+		strings.Join(conditions, " AND "), strings.Join(neighborConditions, " AND "))
+
+	records := collectRecords(query, map[string]interface{}{"namespace": siteNamespace, "excluded": excluded})
+	if len(records) == 0 {
+		printNoResults("No resources found in namespace %s\n", siteNamespace)
+		return
+	}
+
+	graph := site.Graph{
+		Title:       "KubeGraph: " + siteNamespace,
+		Cluster:     getSelectedCluster(),
+		Namespace:   siteNamespace,
+		GeneratedAt: time.Now().UTC(),
+		Nodes:       make([]site.Node, 0),
+		Edges:       make([]site.Edge, 0),
+	}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		for _, key := range []string{"a", "b"} {
+			value, _ := record.Get(key)
+			node, ok := value.(driverneo4j.Node)
+			if !ok || seen[node.ElementId] {
+				continue
+			}
+			seen[node.ElementId] = true
+			graph.Nodes = append(graph.Nodes, newSiteNode(node))
+		}
+		value, _ := record.Get("r")
+		relationship, ok := value.(driverneo4j.Relationship)
+		if !ok || seen[relationship.ElementId] {
+			continue
+		}
+		seen[relationship.ElementId] = true
+		graph.Edges = append(graph.Edges, site.Edge{
+			Source: relationship.StartElementId,
+			Target: relationship.EndElementId,
+			Type:   relationship.Type,
+		})
+	}
+
+	if err := site.Write(siteOut, graph); err != nil {
+		logger.Error("Failed to write site: %v", err)
+		os.Exit(exitError)
+	}
+	if !quiet {
+		fmt.Printf("Wrote site with %d resources and %d relationships to %s\n", len(graph.Nodes), len(graph.Edges), filepath.Join(siteOut, "index.html"))
+	}
+}
+
+// maxGraphEdges limits the size of rendered diagrams
+const maxGraphEdges = 500
+
+// diagramNode is a resource in a rendered neighborhood
+type diagramNode struct {
+	ID        string
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// diagramEdge is a relationship between two diagram nodes
+type diagramEdge struct {
+	From string
+	To   string
+	Type string
+}
+
+// diagram is the neighborhood of a root resource. Nodes keep the order in
+// which they were added, starting with the root.
+type diagram struct {
+	Nodes []diagramNode
+	Edges []diagramEdge
+	index map[string]int
+}
+
+func newDiagram() *diagram {
+	return &diagram{index: make(map[string]int)}
+}
+
+// addNode adds a node unless a node with the same ID exists
+func (d *diagram) addNode(node diagramNode) {
+	if _, ok := d.index[node.ID]; ok {
+		return
+	}
+	d.index[node.ID] = len(d.Nodes)
+	d.Nodes = append(d.Nodes, node)
+}
+
+// nodeID returns the identifier used for a node in diagram definitions
+func (d *diagram) nodeID(id string) string {
+	return fmt.Sprintf("n%d", d.index[id])
+}
+
+// label returns the display label of a node
+func (n diagramNode) label() string {
+	return n.Kind + "\n" + n.qualifiedName()
+}
+
+// qualifiedName returns the name of the node prefixed with its namespace, if any
+func (n diagramNode) qualifiedName() string {
+	if n.Namespace == "" {
+		return n.Name
+	}
+	return n.Namespace + "/" + n.Name
+}
+
+// renderMermaid renders a diagram as a Mermaid flowchart, highlighting the root
+func renderMermaid(d *diagram) string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, node := range d.Nodes {
+		label := strings.ReplaceAll(node.label(), "\"", "#quot;")
+		label = strings.ReplaceAll(label, "\n", "<br/>")
+		fmt.Fprintf(&b, "  n%d[\"%s\"]\n", i, label)
+	}
+	for _, edge := range d.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", d.nodeID(edge.From), edge.Type, d.nodeID(edge.To))
+	}
+	if len(d.Nodes) > 0 {
+		b.WriteString("  classDef root stroke-width:3px\n")
+		b.WriteString("  class n0 root\n")
+	}
+	return b.String()
+}
+
+// renderDOT renders a diagram as a Graphviz digraph, highlighting the root
+func renderDOT(d *diagram) string {
+	escape := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+	var b strings.Builder
+	b.WriteString("digraph kubegraph {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for i, node := range d.Nodes {
+		attributes := ""
+		if i == 0 {
+			attributes = ", penwidth=3"
+		}
+		fmt.Fprintf(&b, "  n%d [label=\"%s\"%s];\n", i, escape.Replace(node.label()), attributes)
+	}
+	for _, edge := range d.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=\"%s\"];\n", d.nodeID(edge.From), d.nodeID(edge.To), escape.Replace(edge.Type))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// recordString returns the string value of a record column, or "" if it is null
+func recordString(record *driverneo4j.Record, key string) string {
+	value, _ := record.Get(key)
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func handleGraph(args []string) {
+	if graphDepth < 1 || graphDepth > 5 {
+		logger.Error("Invalid depth %d: must be between 1 and 5", graphDepth)
+		os.Exit(exitError)
+	}
+	render := renderMermaid
+	switch graphFormat {
+	case "mermaid":
+	case "dot":
+		render = renderDOT
+	default:
+		logger.Error("Invalid format %s: must be mermaid or dot", graphFormat)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"root.name = $name"}
+	params := map[string]interface{}{"name": args[1]}
+	if len(args) > 2 {
+		conditions = append(conditions, "root.namespace = $namespace")
+		params["namespace"] = args[2]
+	}
+	if filter := getClusterFilterWithVar("root"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (root:%s)
+		WHERE %s
+		WITH root LIMIT 1
+		OPTIONAL MATCH p = (root)-[*1..%d]-()
+		UNWIND (CASE WHEN p IS NULL THEN [null] ELSE relationships(p) END) AS r
+		WITH DISTINCT root, r
+		LIMIT %d
+		WITH root, r, startNode(r) AS a, endNode(r) AS b
+		RETURN %s AS rootId, labels(root)[0] AS rootKind, root.name AS rootName, root.namespace AS rootNamespace,
+		       %s AS fromId, labels(a)[0] AS fromKind, a.name AS fromName, a.namespace AS fromNamespace,
+		       type(r) AS type,
+		       %s AS toId, labels(b)[0] AS toKind, b.name AS toName, b.namespace AS toNamespace`,
+		args[0], strings.Join(conditions, " AND "), graphDepth, maxGraphEdges,
+		client.ElementID("root"), client.ElementID("a"), client.ElementID("b"))
+
+	records := collectRecords(query, params)
+	if len(records) == 0 {
+		logger.Error("%s %s not found", args[0], args[1])
+		os.Exit(exitError)
+	}
+
+	d := newDiagram()
+	for _, record := range records {
+		d.addNode(diagramNode{
+			ID:        recordString(record, "rootId"),
+			Kind:      recordString(record, "rootKind"),
+			Name:      recordString(record, "rootName"),
+			Namespace: recordString(record, "rootNamespace"),
+		})
+		if recordString(record, "type") == "" {
+			continue
+		}
+		from := diagramNode{
+			ID:        recordString(record, "fromId"),
+			Kind:      recordString(record, "fromKind"),
+			Name:      recordString(record, "fromName"),
+			Namespace: recordString(record, "fromNamespace"),
+		}
+		to := diagramNode{
+			ID:        recordString(record, "toId"),
+			Kind:      recordString(record, "toKind"),
+			Name:      recordString(record, "toName"),
+			Namespace: recordString(record, "toNamespace"),
+		}
+		d.addNode(from)
+		d.addNode(to)
+		d.Edges = append(d.Edges, diagramEdge{From: from.ID, To: to.ID, Type: recordString(record, "type")})
+	}
+	if len(d.Edges) >= maxGraphEdges {
+		logger.Warn("Diagram truncated to %d relationships; reduce --depth for a complete diagram", maxGraphEdges)
+	}
+
+	output := render(d)
+	if graphOutput == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(graphOutput, []byte(output), 0644); err != nil {
+		logger.Error("Failed to write diagram: %v", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %s diagram with %d nodes and %d relationships to %s\n", graphFormat, len(d.Nodes), len(d.Edges), graphOutput)
+}
+
+// impactIncoming are the relationships pointing from a dependent to the
+// resource it depends on, e.g. a Pod SCHEDULED_ON its Node
+var impactIncoming = []string{"OWNED_BY", "USES", "SELECTS", "ROUTES_TO", "SCHEDULED_ON", "MANAGED_BY"}
+
+// impactOutgoing are the relationships pointing from a resource to its
+// dependents, e.g. a ReplicaSet that MANAGES its Pods or a PVC USED_BY a StatefulSet
+var impactOutgoing = []string{"MANAGES", "OWNS", "USED_BY"}
+
+// impactQuery returns the query of the dependents of the root matched by
+// conditions. The relationships are traversed in either direction and each hop
+// must point the way of its type, away from the root for impactOutgoing.
+func impactQuery(kind string, conditions []string, depth int) string {
+	// Relationships matched by name can cross clusters, so dependents are
+	// restricted to the cluster of the root
+	return fmt.Sprintf(`
+		MATCH (root:%s)
+		WHERE %s
+		WITH root LIMIT 1
+		OPTIONAL MATCH p = (root)-[:%s*1..%d]-(affected)
+		WHERE affected <> root AND affected.clusterName = root.clusterName
+		  AND all(i IN range(0, length(p) - 1) WHERE
+		      CASE WHEN type(relationships(p)[i]) IN $outgoing
+		           THEN startNode(relationships(p)[i]) = nodes(p)[i]
+		           ELSE endNode(relationships(p)[i]) = nodes(p)[i] END)
+		WITH root, affected, p
+		ORDER BY length(p)
+		WITH root, affected, collect(p)[0] AS p
+		RETURN labels(affected)[0] AS kind, affected.namespace AS namespace, affected.name AS name,
+		       length(p) AS depth, [n IN nodes(p) | labels(n)[0]] AS kinds,
+		       [i IN range(0, coalesce(length(p), 0) - 1) |
+		        CASE WHEN startNode(relationships(p)[i]) = nodes(p)[i]
+		             THEN '-' + type(relationships(p)[i]) + '->'
+		             ELSE '<-' + type(relationships(p)[i]) + '-' END] AS relationships
+		ORDER BY depth, kind, namespace, name`,
+		kind, strings.Join(conditions, " AND "), strings.Join(append(append([]string{}, impactIncoming...), impactOutgoing...), "|"), depth)
+}
+
+// formatImpactPath describes how an affected resource depends on the root from
+// the kinds along the path and the arrows of its relationships, e.g.
+// "Node <-SCHEDULED_ON- Pod <-SELECTS- Service"
+func formatImpactPath(kinds, relationships []string) string {
+	if len(kinds) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(kinds[0])
+	for i, relationship := range relationships {
+		if i+1 >= len(kinds) {
+			break
+		}
+		fmt.Fprintf(&b, " %s %s", relationship, kinds[i+1])
+	}
+	return b.String()
+}
+
+// toStringList converts a list returned by the driver to strings
+func toStringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		result = append(result, fmt.Sprintf("%v", item))
+	}
+	return result
+}
+
+func handleImpact(args []string) {
+	if impactDepth < 1 || impactDepth > 6 {
+		logger.Error("Invalid depth %d: must be between 1 and 6", impactDepth)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"root.name = $name"}
+	params := map[string]interface{}{"name": args[1]}
+	if len(args) > 2 {
+		conditions = append(conditions, "root.namespace = $namespace")
+		params["namespace"] = args[2]
+	}
+	if filter := getClusterFilterWithVar("root"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	params["outgoing"] = impactOutgoing
+	query := impactQuery(args[0], conditions, impactDepth)
+
+	records := collectRecords(query, params)
+	if len(records) == 0 {
+		logger.Error("%s %s not found", args[0], args[1])
+		os.Exit(exitError)
+	}
+
+	keys := []string{"depth", "kind", "namespace", "name", "path"}
+	values := make([][]string, 0, len(records))
+	counts := make(map[string]int)
+	kinds := make([]string, 0)
+	for _, record := range records {
+		kind := recordString(record, "kind")
+		if kind == "" {
+			continue
+		}
+		if counts[kind] == 0 {
+			kinds = append(kinds, kind)
+		}
+		counts[kind]++
+
+		pathKinds, _ := record.Get("kinds")
+		pathRelationships, _ := record.Get("relationships")
+		values = append(values, []string{
+			recordString(record, "depth"),
+			kind,
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			formatImpactPath(toStringList(pathKinds), toStringList(pathRelationships)),
+		})
+	}
+
+	title := fmt.Sprintf("Impact of %s %s", args[0], args[1])
+	if len(values) == 0 {
+		printNoResults("No resources depend on %s %s within %d hops\n", args[0], args[1], impactDepth)
+		return
+	}
+	printTable(title, keys, values)
+
+	summary := make([]string, 0, len(kinds))
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		summary = append(summary, fmt.Sprintf("%d %s", counts[kind], kind))
+	}
+	if !quiet {
+		fmt.Printf("Affected: %s\n", strings.Join(summary, ", "))
+	}
+}
+
+// maxTreeDepth bounds the OWNED_BY hops followed by tree, enough for
+// operators owning workloads, e.g. Neo4jCluster -> StatefulSet -> Pod
+const maxTreeDepth = 5
+
+// ownerTreeNode is a resource in the ownership tree of the tree command
+type ownerTreeNode struct {
+	ID        string           `json:"-"`
+	Kind      string           `json:"kind"`
+	Name      string           `json:"name"`
+	Namespace string           `json:"namespace,omitempty"`
+	Cluster   string           `json:"cluster"`
+	Status    string           `json:"status,omitempty"`
+	Children  []*ownerTreeNode `json:"children,omitempty"`
+}
+
+// ownerTreeRow is a resource of an ownership tree as returned by the query
+// of the tree command: the root it descends from and the owners it has in
+// the tree
+type ownerTreeRow struct {
+	RootID     string
+	ID         string
+	Kind       string
+	Owners     []string
+	Properties map[string]interface{}
+}
+
+func handleTree(args []string) {
+	conditions := append([]string{"root.name = $name"}, scopeConditions("root")...)
+	params := map[string]interface{}{"name": args[1]}
+	if len(args) > 2 {
+		conditions = append(conditions, "root.namespace = $namespace")
+		params["namespace"] = args[2]
+	}
+
+	// Owners are matched by uid, but descendants are still restricted to the
+	// cluster of their root like the other traversals
+	query := fmt.Sprintf(`
+		MATCH (root:%s)
+		WHERE %s
+		OPTIONAL MATCH (root)<-[:OWNED_BY*1..%d]-(descendant)
+		WHERE descendant.clusterName = root.clusterName
+		WITH root, collect(DISTINCT descendant) AS descendants
+		UNWIND [root] + descendants AS n
+		OPTIONAL MATCH (n)-[:OWNED_BY]->(owner)
+		WHERE owner = root OR owner IN descendants
+		WITH root, n, collect(owner) AS owners
+		RETURN %s AS rootId, %s AS id, labels(n)[0] AS kind, properties(n) AS properties,
+		       [owner IN owners | %s] AS owners`,
+		quoteLabel(args[0]), strings.Join(conditions, " AND "), maxTreeDepth,
+		client.ElementID("root"), client.ElementID("n"), client.ElementID("owner"))
+
+	records := collectRecords(query, params)
+	rows := make([]ownerTreeRow, 0, len(records))
+	for _, record := range records {
+		properties, _ := record.Get("properties")
+		owners, _ := record.Get("owners")
+		row := ownerTreeRow{
+			RootID: recordString(record, "rootId"),
+			ID:     recordString(record, "id"),
+			Kind:   recordString(record, "kind"),
+			Owners: toStringList(owners),
+		}
+		row.Properties, _ = properties.(map[string]interface{})
+		rows = append(rows, row)
+	}
+	roots := buildOwnerTrees(rows)
+	if len(roots) == 0 {
+		logger.Error("%s %s not found", args[0], args[1])
+		os.Exit(exitError)
+	}
+
+	if viper.GetString("output") != "table" {
+		keys := []string{"depth", "cluster", "namespace", "kind", "name", "owner", "status"}
+		values := make([][]string, 0)
+		var flatten func(node *ownerTreeNode, depth int, owner string)
+		flatten = func(node *ownerTreeNode, depth int, owner string) {
+			values = append(values, []string{strconv.Itoa(depth), node.Cluster, node.Namespace, node.Kind, node.Name, owner, node.Status})
+			for _, child := range node.Children {
+				flatten(child, depth+1, node.Kind+"/"+node.Name)
+			}
+		}
+		for _, root := range roots {
+			flatten(root, 0, "")
+		}
+		printTable(fmt.Sprintf("Ownership tree of %s %s", args[0], args[1]), keys, values)
+		return
+	}
+
+	resultCount = 0
+	for _, root := range roots {
+		resultCount += root.size()
+		if !quiet {
+			location := root.Cluster
+			if root.Namespace != "" {
+				location += "/" + root.Namespace
+			}
+			fmt.Printf("\n=== Ownership tree of %s %s (%s) ===\n\n", root.Kind, root.Name, location)
+		}
+		root.render(os.Stdout, "", "")
+	}
+	if !quiet {
+		fmt.Println()
+	}
+}
+
+// buildOwnerTrees assembles the rows of the tree query into one tree per
+// root, adding the containers of the pods as their children. Siblings are
+// sorted by kind and name.
+func buildOwnerTrees(rows []ownerTreeRow) []*ownerTreeNode {
+	nodes := make(map[string]*ownerTreeNode, len(rows))
+	for _, row := range rows {
+		node := &ownerTreeNode{
+			ID:        row.ID,
+			Kind:      row.Kind,
+			Name:      propertyString(row.Properties, "name"),
+			Namespace: propertyString(row.Properties, "namespace"),
+			Cluster:   propertyString(row.Properties, "clusterName"),
+			Status:    ownerTreeStatus(row.Kind, row.Properties),
+		}
+		if row.Kind == "Pod" {
+			for _, info := range decodeStringList(row.Properties["containers"]) {
+				container := parsePortInfo(info)
+				node.Children = append(node.Children, &ownerTreeNode{
+					Kind:      "Container",
+					Name:      container["name"],
+					Namespace: node.Namespace,
+					Cluster:   node.Cluster,
+					Status:    containerTreeStatus(container),
+				})
+			}
+		}
+		nodes[row.ID] = node
+	}
+
+	var roots []*ownerTreeNode
+	attached := make(map[string]bool)
+	for _, row := range rows {
+		node := nodes[row.ID]
+		if row.ID == row.RootID {
+			roots = append(roots, node)
+			continue
+		}
+		// A resource with several owners in the tree is shown under the first
+		for _, owner := range slices.Sorted(slices.Values(row.Owners)) {
+			if parent := nodes[owner]; parent != nil && !attached[row.ID] {
+				parent.Children = append(parent.Children, node)
+				attached[row.ID] = true
+			}
+		}
+	}
+
+	byKindAndName := func(a, b *ownerTreeNode) int {
+		if c := strings.Compare(a.Kind, b.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	}
+	for _, node := range nodes {
+		if node.Kind == "Pod" {
+			continue // containers keep the order of the pod spec
+		}
+		slices.SortFunc(node.Children, byKindAndName)
+	}
+	slices.SortFunc(roots, func(a, b *ownerTreeNode) int {
+		if c := strings.Compare(a.Cluster, b.Cluster); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Namespace, b.Namespace)
+	})
+	return roots
+}
+
+// ownerTreeStatus summarizes the status of a resource of an ownership tree
+// from the properties its handler writes
+func ownerTreeStatus(kind string, properties map[string]interface{}) string {
+	switch kind {
+	case "Pod":
+		return fmt.Sprintf("%s, %s restarts", propertyString(properties, "status"), propertyString(properties, "restartCount"))
+	case "ReplicaSet":
+		status := fmt.Sprintf("%s/%s ready", propertyString(properties, "readyReplicas"), propertyString(properties, "replicas"))
+		if revision := propertyString(properties, "revision"); revision != "" {
+			status += ", revision " + revision
+		}
+		return status
+	case "Deployment", "StatefulSet":
+		return propertyString(properties, "replicas") + " replicas"
+	case "Job":
+		return fmt.Sprintf("%s active, %s succeeded, %s failed", propertyString(properties, "active"),
+			propertyString(properties, "succeeded"), propertyString(properties, "failed"))
+	case "CronJob":
+		status := "schedule " + propertyString(properties, "schedule")
+		if propertyString(properties, "suspend") == "true" {
+			status += ", suspended"
+		}
+		return status
+	}
+	return propertyString(properties, "status")
+}
+
+// containerTreeStatus summarizes the status of a container of a pod
+func containerTreeStatus(container map[string]string) string {
+	status := container["state"]
+	if status == "" {
+		return "" // not started yet
+	}
+	if container["reason"] != "" {
+		status += " (" + container["reason"] + ")"
+	}
+	if container["ready"] == "true" {
+		status += ", ready"
+	} else {
+		status += ", not ready"
+	}
+	return status
+}
+
+// propertyString returns a property of a node as text, empty when it is not
+// set
+func propertyString(properties map[string]interface{}, key string) string {
+	value, ok := properties[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// size returns the number of resources in the tree of node
+func (node *ownerTreeNode) size() int {
+	size := 1
+	for _, child := range node.Children {
+		size += child.size()
+	}
+	return size
+}
+
+// render writes the tree of node, prefix leading the line of node and indent
+// the lines of its descendants
+func (node *ownerTreeNode) render(w io.Writer, prefix, indent string) {
+	line := prefix + node.Kind + "/" + node.Name
+	if node.Status != "" {
+		line += "  " + node.Status
+	}
+	fmt.Fprintln(w, line)
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			child.render(w, indent+"└── ", indent+"    ")
+		} else {
+			child.render(w, indent+"├── ", indent+"│   ")
+		}
+	}
+}
+
+// pathStep is a relationship on a path, with the direction it is traversed in
+type pathStep struct {
+	Type    string
+	Forward bool // the relationship points from the previous node to the next one
+}
+
+// formatPath renders a path as one node per line joined by its relationships, e.g.
+//
+//	Pod default/web-0
+//	  -[USES]-> Secret default/db-credentials
+func formatPath(nodes []diagramNode, steps []pathStep) string {
+	if len(nodes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", nodes[0].Kind, nodes[0].qualifiedName())
+	for i, step := range steps {
+		if i+1 >= len(nodes) {
+			break
+		}
+		arrow := fmt.Sprintf("<-[%s]-", step.Type)
+		if step.Forward {
+			arrow = fmt.Sprintf("-[%s]->", step.Type)
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", arrow, nodes[i+1].Kind, nodes[i+1].qualifiedName())
+	}
+	return b.String()
+}
+
+func handlePath(args []string) {
+	if pathMaxHops < 1 || pathMaxHops > 15 {
+		logger.Error("Invalid max hops %d: must be between 1 and 15", pathMaxHops)
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"a.name = $nameA", "b.name = $nameB", "a <> b"}
+	params := map[string]interface{}{"nameA": args[1], "nameB": args[3]}
+	if pathFromNamespace != "" {
+		conditions = append(conditions, "a.namespace = $namespaceA")
+		params["namespaceA"] = pathFromNamespace
+	}
+	if pathToNamespace != "" {
+		conditions = append(conditions, "b.namespace = $namespaceB")
+		params["namespaceB"] = pathToNamespace
+	}
+	if filter := getClusterFilterWithVar("a"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "), "b.clusterName = a.clusterName")
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (a:%s), (b:%s)
+		WHERE %s
+		MATCH p = shortestPath((a)-[*..%d]-(b))
+		WITH p ORDER BY length(p) LIMIT 1
+		RETURN [n IN nodes(p) | {id: %s, kind: labels(n)[0], name: n.name, namespace: n.namespace}] AS nodes,
+		       [r IN relationships(p) | {type: type(r), start: %s}] AS relationships`,
+		args[0], args[2], strings.Join(conditions, " AND "), pathMaxHops,
+		client.ElementID("n"), client.ElementID("startNode(r)"))
+
+	records := collectRecords(query, params)
+	if len(records) == 0 {
+		printNoResults("No path within %d hops between %s %s and %s %s\n", pathMaxHops, args[0], args[1], args[2], args[3])
+		return
+	}
+
+	nodesValue, _ := records[0].Get("nodes")
+	relationshipsValue, _ := records[0].Get("relationships")
+	nodeList, _ := nodesValue.([]interface{})
+	relationshipList, _ := relationshipsValue.([]interface{})
+
+	nodes := make([]diagramNode, 0, len(nodeList))
+	for _, value := range nodeList {
+		node, _ := value.(map[string]interface{})
+		nodes = append(nodes, diagramNode{
+			ID:        mapString(node, "id"),
+			Kind:      mapString(node, "kind"),
+			Name:      mapString(node, "name"),
+			Namespace: mapString(node, "namespace"),
+		})
+	}
+	steps := make([]pathStep, 0, len(relationshipList))
+	for i, value := range relationshipList {
+		relationship, _ := value.(map[string]interface{})
+		steps = append(steps, pathStep{
+			Type:    mapString(relationship, "type"),
+			Forward: i < len(nodes) && mapString(relationship, "start") == nodes[i].ID,
+		})
+	}
+
+	resultCount = 1
+	if !quiet {
+		fmt.Printf("\n=== Path from %s %s to %s %s (%d hops) ===\n\n", args[0], args[1], args[2], args[3], len(steps))
+	}
+	fmt.Print(formatPath(nodes, steps))
+}
+
+// mapString returns the string value of a map entry, or "" if it is missing or null
+func mapString(m map[string]interface{}, key string) string {
+	if m[key] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", m[key])
+}
+
+// formatBytes renders a byte count with binary units, e.g. "1.5Gi"
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d", bytes)
+	}
+	value := float64(bytes)
+	suffixes := []string{"Ki", "Mi", "Gi", "Ti", "Pi"}
+	i := 0
+	for value /= unit; value >= unit && i < len(suffixes)-1; value /= unit {
+		i++
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0") + suffixes[i]
+}
+
+func handleStorageByWorkload(args []string) {
+	limit := 20
+	if len(args) > 0 {
+		if l, err := strconv.Atoi(args[0]); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	conditions := []string{
+		`(w:Deployment OR w:StatefulSet OR w:DaemonSet OR w:CronJob OR
+		  (w:ReplicaSet AND NOT (w)-[:OWNED_BY]->(:Deployment)) OR
+		  (w:Job AND NOT (w)-[:OWNED_BY]->(:CronJob)))`,
+		"w.totalStorageBytes > 0",
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// totalStorageBytes is maintained at ingest by rolling up the PVCs of the pods
+	query := fmt.Sprintf(`
+		MATCH (w)
+		WHERE %s
+		RETURN w.clusterName AS cluster, labels(w)[0] AS kind, w.namespace AS namespace, w.name AS name,
+		       w.totalStorageBytes AS bytes
+		ORDER BY bytes DESC, cluster, namespace, name
+		LIMIT %d`,
+		strings.Join(conditions, " AND "), limit)
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("No workloads with provisioned storage found\n")
+		return
+	}
+
+	keys := []string{"cluster", "kind", "namespace", "name", "storage"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		bytes, _ := record.Get("bytes")
+		total, _ := bytes.(int64)
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "kind"),
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			formatBytes(total),
+		})
+	}
+	printTable("Storage by Workload", keys, values)
+}
+
+func handleRegistries() {
+	query := fmt.Sprintf(`
+		MATCH (r:Registry)
+		%s
+		OPTIONAL MATCH (w)-[:PULLS_FROM]->(r)
+		WITH r, count(DISTINCT w) AS workloads, collect(DISTINCT w.namespace) AS namespaces
+		OPTIONAL MATCH (s:Secret)-[:AUTHENTICATES_TO]->(r)
+		WITH r, workloads, namespaces, collect(DISTINCT s.namespace) AS credentialNamespaces
+		RETURN r.clusterName AS cluster, r.name AS registry, workloads, namespaces, credentialNamespaces,
+		       [ns IN namespaces WHERE NOT ns IN credentialNamespaces] AS namespacesWithoutCredentials
+		ORDER BY cluster, workloads DESC, registry`,
+		getClusterFilterWithVar("r"))
+
+	executeQuery(query, "Image Registries")
+}
+
+// mergeSubjects merges the subjects of the bindings granting a role, which
+// are stored as JSON lists, into a sorted list without duplicates
+func mergeSubjects(lists []interface{}) []string {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, subject := range decodeStringList(list) {
+			seen[subject] = true
+		}
+	}
+	subjects := make([]string, 0, len(seen))
+	for subject := range seen {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
+
+func handleRiskyRoles() {
+	conditions := []string{"(r:ClusterRole OR r:Role)", "r.riskLevel IN ['critical', 'high']"}
+	if !riskyRolesIncludeSystem {
+		conditions = append(conditions, "NOT r.name STARTS WITH 'system:'")
+	}
+	if filter := getClusterFilterWithVar("r"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (r)
+		WHERE %s
+		OPTIONAL MATCH (b)-[:GRANTS]->(r)
+		WITH r, collect(b.subjects) AS subjectLists, count(b) AS bindings
+		RETURN r.clusterName AS cluster, labels(r)[0] AS kind, r.namespace AS namespace, r.name AS name,
+		       r.riskLevel AS risk, r.riskReasons AS reasons, bindings, subjectLists
+		ORDER BY CASE r.riskLevel WHEN 'critical' THEN 0 ELSE 1 END, bindings DESC, cluster, namespace, name`,
+		strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("No risky roles found\n")
+		return
+	}
+
+	keys := []string{"cluster", "kind", "namespace", "name", "risk", "reasons", "bindings", "subjects"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		reasons, _ := record.Get("reasons")
+		lists, _ := record.Get("subjectLists")
+		subjectLists, _ := lists.([]interface{})
+		subjects := strings.Join(mergeSubjects(subjectLists), ", ")
+		if subjects == "" {
+			subjects = "-"
+		}
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "kind"),
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			recordString(record, "risk"),
+			strings.Join(decodeStringList(reasons), ", "),
+			recordString(record, "bindings"),
+			subjects,
+		})
+	}
+	printTable("Risky Roles", keys, values)
+}
+
+// tokenMountedCondition holds for a pod p that mounts the token of its
+// ServiceAccount sa: the pod setting overrides the ServiceAccount setting, and
+// the token is mounted when neither is set
+const tokenMountedCondition = "coalesce(p.automountServiceAccountToken, sa.automountToken, 'true') <> 'false'"
+
+// saExposureQuery returns the query of the pods running as a ServiceAccount
+// bound to a risky role, restricted by the sa-exposure flags
+func saExposureQuery() string {
+	conditions := []string{"role.riskLevel IN ['critical', 'high']"}
+	if !saExposureIncludeSystem {
+		conditions = append(conditions, "NOT p.namespace STARTS WITH 'kube-'", "NOT role.name STARTS WITH 'system:'")
+	}
+	if saExposureMountedOnly {
+		conditions = append(conditions, tokenMountedCondition)
+	}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	return fmt.Sprintf(`
+		MATCH (p:Pod)-[:USES_SERVICE_ACCOUNT]->(sa:ServiceAccount)<-[:BINDS]-(b)-[:GRANTS]->(role)
+		WHERE %s
+		WITH p, sa, %s AS mounted,
+		     collect(DISTINCT labels(b)[0] + '/' + b.name + ' -> ' + labels(role)[0] + '/' + role.name) AS grants,
+		     collect(role.riskReasons) AS reasonLists,
+		     max(CASE role.riskLevel WHEN 'critical' THEN 2 ELSE 1 END) AS riskRank
+		RETURN p.clusterName AS cluster, p.namespace AS namespace, p.name AS pod, sa.name AS serviceAccount, mounted,
+		       CASE riskRank WHEN 2 THEN 'critical' ELSE 'high' END AS risk, grants, reasonLists
+		ORDER BY riskRank DESC, mounted DESC, cluster, namespace, pod`,
+		strings.Join(conditions, " AND "), tokenMountedCondition)
+}
+
+func handleSAExposure() {
+	records := collectRecords(saExposureQuery(), nil)
+	if len(records) == 0 {
+		printNoResults("No pods run as a ServiceAccount bound to a risky role\n")
+		return
+	}
+
+	keys := []string{"cluster", "namespace", "pod", "serviceAccount", "tokenMounted", "risk", "grants", "reasons"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		grants, _ := record.Get("grants")
+		grantList := make([]string, 0)
+		for _, grant := range grants.([]interface{}) {
+			grantList = append(grantList, fmt.Sprintf("%v", grant))
+		}
+		sort.Strings(grantList)
+		reasonLists, _ := record.Get("reasonLists")
+		lists, _ := reasonLists.([]interface{})
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "namespace"),
+			recordString(record, "pod"),
+			recordString(record, "serviceAccount"),
+			recordString(record, "mounted"),
+			recordString(record, "risk"),
+			strings.Join(grantList, ", "),
+			strings.Join(mergeSubjects(lists), ", "),
+		})
+	}
+	printTable("Pods Exposed Through Their ServiceAccount", keys, values)
+}
+
+// Severities of attack paths, ranked by severityRank
+const (
+	severityCritical = "critical"
+	severityHigh     = "high"
+	severityMedium   = "medium"
+)
+
+func severityRank(severity string) int {
+	switch severity {
+	case severityCritical:
+		return 0
+	case severityHigh:
+		return 1
+	}
+	return 2
+}
+
+// attackPath is a chain of resources from an entry point to a target
+type attackPath struct {
+	severity string
+	cluster  string
+	steps    []string
+	reason   string
+}
+
+// sortAttackPaths orders paths by severity, then shorter paths first
+func sortAttackPaths(paths []attackPath) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		if a, b := severityRank(paths[i].severity), severityRank(paths[j].severity); a != b {
+			return a < b
+		}
+		if len(paths[i].steps) != len(paths[j].steps) {
+			return len(paths[i].steps) < len(paths[j].steps)
+		}
+		if paths[i].cluster != paths[j].cluster {
+			return paths[i].cluster < paths[j].cluster
+		}
+		return strings.Join(paths[i].steps, " ") < strings.Join(paths[j].steps, " ")
+	})
+}
+
+// exposedPodSeverity rates an exposed pod by how easily a compromise of it
+// reaches its node: a privileged container owns the node, host namespaces and
+// hostPath volumes expose it, and root eases a container escape
+func exposedPodSeverity(privileged, hostAccess, root bool) (string, string) {
+	switch {
+	case privileged:
+		return severityCritical, "exposed privileged container can take over the node"
+	case hostAccess:
+		return severityHigh, "exposed pod shares host namespaces or mounts host paths"
+	case root:
+		return severityMedium, "exposed pod runs as root"
+	}
+	return "", ""
+}
+
+// secretAccessSeverity rates a ServiceAccount by the reach of its binding: a
+// ClusterRoleBinding grants the role in every namespace, a RoleBinding only in
+// its own
+func secretAccessSeverity(bindingKind, riskLevel string) string {
+	switch {
+	case bindingKind == "ClusterRoleBinding" && riskLevel == "critical":
+		return severityCritical
+	case bindingKind == "ClusterRoleBinding" || riskLevel == "critical":
+		return severityHigh
+	}
+	return severityMedium
+}
+
+// exposureMatch matches the pods p selected by a Service s that is reachable
+// from outside the cluster, with the routes r to it in entries
+const exposureMatch = `
+		MATCH (s:Service)-[:SELECTS]->(p:Pod)
+		WHERE %s
+		OPTIONAL MATCH (r)-[:ROUTES_TO]->(s)
+		WHERE (r:Ingress OR r:HTTPRoute OR r:VirtualService) AND r.clusterName = s.clusterName
+		WITH s, p, [x IN collect(DISTINCT r) | labels(x)[0] + '/' + x.name] AS entries
+		WHERE size(entries) > 0 OR s.type IN ['LoadBalancer', 'NodePort']`
+
+// exposureSteps returns the first steps of a path through an exposed Service
+func exposureSteps(record *driverneo4j.Record) []string {
+	value, _ := record.Get("entries")
+	entries := make([]string, 0)
+	for _, entry := range value.([]interface{}) {
+		entries = append(entries, fmt.Sprintf("%v", entry))
+	}
+	service := fmt.Sprintf("Service/%s/%s", recordString(record, "namespace"), recordString(record, "service"))
+	if len(entries) == 0 {
+		return []string{"internet (" + recordString(record, "serviceType") + ")", service}
+	}
+	return []string{"internet", strings.Join(entries, ","), service}
+}
+
+// auditedWebhook is a webhook as stored by the admission webhook handlers,
+// "name=...;failurePolicy=...;timeoutSeconds=...;service=<namespace>/<name>:<port><path>;caExpiry=..."
+type auditedWebhook struct {
+	name          string
+	failurePolicy string
+	service       string // namespace/name of the backing Service, empty for webhooks called by URL
+	url           string
+	caExpiry      time.Time
+}
+
+func parseAuditedWebhook(formatted string) auditedWebhook {
+	var webhook auditedWebhook
+	for _, part := range strings.Split(formatted, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "name":
+			webhook.name = value
+		case "failurePolicy":
+			webhook.failurePolicy = value
+		case "service":
+			webhook.service, _, _ = strings.Cut(value, ":")
+		case "url":
+			webhook.url = value
+		case "caExpiry":
+			webhook.caExpiry, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+	return webhook
+}
+
+// webhookFindings returns the severity and problems of a webhook, or no
+// findings for a webhook that ignores failures and is healthy. runningPods
+// holds the running pods behind each synced Service called by the
+// configuration.
+func webhookFindings(webhook auditedWebhook, runningPods map[string]int64, now time.Time, expiryDays int) (string, []string) {
+	var findings []string
+	failClosed := webhook.failurePolicy == "Fail"
+	if failClosed {
+		findings = append(findings, "fails closed")
+	}
+
+	broken := false
+	if webhook.service != "" {
+		running, ok := runningPods[webhook.service]
+		switch {
+		case !ok:
+			findings = append(findings, fmt.Sprintf("service %s not found", webhook.service))
+			broken = true
+		case running == 0:
+			findings = append(findings, fmt.Sprintf("no running pods behind %s", webhook.service))
+			broken = true
+		}
+	}
+
+	expiring := false
+	if !webhook.caExpiry.IsZero() {
+		date := webhook.caExpiry.Format("2006-01-02")
+		switch {
+		case !webhook.caExpiry.After(now):
+			findings = append(findings, "caBundle expired on "+date)
+			broken = true
+		case webhook.caExpiry.Before(now.AddDate(0, 0, expiryDays)):
+			findings = append(findings, fmt.Sprintf("caBundle expires on %s (in %d days)", date, int(webhook.caExpiry.Sub(now).Hours()/24)))
+			expiring = true
+		}
+	}
+
+	switch {
+	case len(findings) == 0:
+		return "", nil
+	case failClosed && broken:
+		return severityCritical, findings
+	case broken, failClosed && expiring:
+		return severityHigh, findings
+	}
+	return severityMedium, findings
+}
+
+func handleWebhookAudit() {
+	query := fmt.Sprintf(`
+		MATCH (w)
+		WHERE (w:ValidatingWebhookConfiguration OR w:MutatingWebhookConfiguration) %s
+		OPTIONAL MATCH (w)-[:CALLS]->(s:Service)
+		OPTIONAL MATCH (s)-[:SELECTS]->(p:Pod)
+		WITH w, s, count(CASE WHEN p.status = 'Running' THEN 1 END) AS running
+		RETURN w.clusterName AS cluster, labels(w)[0] AS kind, w.name AS name, w.webhooks AS webhooks,
+		       collect(CASE WHEN s IS NULL THEN NULL ELSE {service: s.namespace + '/' + s.name, running: running} END) AS backends
+		ORDER BY cluster, kind, name`, strings.Replace(getClusterFilterWithVar("w"), "WHERE", "AND", 1))
+
+	records := collectRecords(query, nil)
+	now := time.Now().UTC()
+	type row struct {
+		severity string
+		values   []string
+	}
+	rows := make([]row, 0)
+	for _, record := range records {
+		runningPods := make(map[string]int64)
+		backends, _ := record.Get("backends")
+		for _, backend := range backends.([]interface{}) {
+			entry, _ := backend.(map[string]interface{})
+			service, _ := entry["service"].(string)
+			running, _ := entry["running"].(int64)
+			runningPods[service] = running
+		}
+
+		webhooks, _ := record.Get("webhooks")
+		for _, formatted := range decodeStringList(webhooks) {
+			webhook := parseAuditedWebhook(formatted)
+			severity, findings := webhookFindings(webhook, runningPods, now, webhookExpiryDays)
+			if len(findings) == 0 {
+				continue
+			}
+			backend := webhook.service
+			if backend == "" {
+				backend = webhook.url
+			}
+			caExpiry := ""
+			if !webhook.caExpiry.IsZero() {
+				caExpiry = webhook.caExpiry.Format(time.RFC3339)
+			}
+			rows = append(rows, row{severity, []string{
+				severity,
+				recordString(record, "cluster"),
+				recordString(record, "kind"),
+				recordString(record, "name"),
+				webhook.name,
+				webhook.failurePolicy,
+				backend,
+				caExpiry,
+				strings.Join(findings, ", "),
+			}})
+		}
+	}
+	if len(rows) == 0 {
+		printNoResults("No risky admission webhooks found\n")
+		return
+	}
+
+	// Records are ordered by configuration, so a stable sort keeps them ordered within a severity
+	sort.SliceStable(rows, func(i, j int) bool { return severityRank(rows[i].severity) < severityRank(rows[j].severity) })
+	keys := []string{"severity", "cluster", "kind", "configuration", "webhook", "failurePolicy", "backend", "caExpiry", "findings"}
+	values := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		values = append(values, r.values)
+	}
+	printTable("Admission Webhook Audit", keys, values)
+}
+
+// apiServiceFindings returns the severity and problems of an APIService. service
+// is the namespace/name of the backing Service, empty for APIServices served by
+// the API server itself, and runningPods is -1 when the Service is not synced.
+func apiServiceFindings(available, reason, message, service string, runningPods int64, insecure bool) (string, []string) {
+	var findings []string
+	severity := severityMedium
+	if available != "True" {
+		finding := "not available"
+		if reason != "" {
+			finding += " (" + reason + ")"
+		}
+		if message != "" {
+			finding += ": " + message
+		}
+		findings = append(findings, finding)
+		severity = severityCritical
+	}
+	if service != "" {
+		switch {
+		case runningPods < 0:
+			findings = append(findings, fmt.Sprintf("service %s not found", service))
+		case runningPods == 0:
+			findings = append(findings, fmt.Sprintf("no running pods behind %s", service))
+		}
+		if runningPods <= 0 && severity != severityCritical {
+			severity = severityHigh
+		}
+	}
+	if insecure {
+		findings = append(findings, "skips TLS verification")
+	}
+	if len(findings) == 0 {
+		return "", nil
+	}
+	return severity, findings
+}
+
+// nodeFindings returns the severity and problems of a node from its conditions
+func nodeFindings(conditions map[string]string, unschedulable bool) (string, []string) {
+	var findings []string
+	severity := severityMedium
+	if status := conditions["Ready"]; status != "True" {
+		if status == "" {
+			status = "Unknown"
+		}
+		findings = append(findings, "Ready="+status)
+		severity = severityCritical
+	}
+	for _, condition := range []string{"MemoryPressure", "DiskPressure", "PIDPressure", "NetworkUnavailable"} {
+		if conditions[condition] == "True" {
+			findings = append(findings, condition)
+			if severity != severityCritical {
+				severity = severityHigh
+			}
+		}
+	}
+	if unschedulable {
+		findings = append(findings, "cordoned")
+	}
+	if len(findings) == 0 {
+		return "", nil
+	}
+	return severity, findings
+}
+
+func handleHealthReport() {
+	type row struct {
+		severity string
+		values   []string
+	}
+	rows := make([]row, 0)
+
+	apiServices := collectRecords(fmt.Sprintf(`
+		MATCH (a:APIService) %s
+		OPTIONAL MATCH (a)-[:CALLS]->(s:Service)
+		OPTIONAL MATCH (s)-[:SELECTS]->(p:Pod)
+		WITH a, s, count(CASE WHEN p.status = 'Running' THEN 1 END) AS running
+		RETURN a.clusterName AS cluster, a.name AS name, a.services AS services, a.available AS available,
+		       a.availableReason AS reason, a.availableMessage AS message, a.insecureSkipTLSVerify AS insecure,
+		       CASE WHEN s IS NULL THEN -1 ELSE running END AS running
+		ORDER BY cluster, name`, getClusterFilterWithVar("a")), nil)
+	for _, record := range apiServices {
+		service := ""
+		services, _ := record.Get("services")
+		if list := decodeStringList(services); len(list) > 0 {
+			service = list[0]
+		}
+		severity, findings := apiServiceFindings(recordString(record, "available"), recordString(record, "reason"),
+			recordString(record, "message"), service, recordInt64(record, "running"), recordString(record, "insecure") == "true")
+		if len(findings) == 0 {
+			continue
+		}
+		rows = append(rows, row{severity, []string{
+			severity, recordString(record, "cluster"), "APIService", recordString(record, "name"), strings.Join(findings, ", "),
+		}})
+	}
+
+	nodes := collectRecords(fmt.Sprintf(`
+		MATCH (n:Node) %s
+		RETURN n.clusterName AS cluster, n.name AS name, n.conditions AS conditions, n.unschedulable AS unschedulable
+		ORDER BY cluster, name`, getClusterFilterWithVar("n")), nil)
+	for _, record := range nodes {
+		conditions := make(map[string]string)
+		json.Unmarshal([]byte(recordString(record, "conditions")), &conditions)
+		severity, findings := nodeFindings(conditions, recordString(record, "unschedulable") == "true")
+		if len(findings) == 0 {
+			continue
+		}
+		rows = append(rows, row{severity, []string{
+			severity, recordString(record, "cluster"), "Node", recordString(record, "name"), strings.Join(findings, ", "),
+		}})
+	}
+
+	if len(rows) == 0 {
+		printNoResults("No unavailable APIServices or unhealthy nodes found\n")
+		return
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return severityRank(rows[i].severity) < severityRank(rows[j].severity) })
+	keys := []string{"severity", "cluster", "kind", "name", "findings"}
+	values := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		values = append(values, r.values)
+	}
+	printTable("Cluster Health Report", keys, values)
+}
+
+func handleAttackPaths() {
+	var podConditions []string
+	roleConditions := []string{"role.riskLevel IN ['critical', 'high']"}
+	if !attackPathsIncludeSystem {
+		podConditions = append(podConditions, "NOT p.namespace STARTS WITH 'kube-'")
+		roleConditions = append(roleConditions, "NOT role.name STARTS WITH 'system:'")
+	}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		podConditions = append(podConditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+	// Services select pods by labels only, so both must be in the same cluster
+	exposedConditions := strings.Join(append([]string{"p.clusterName = s.clusterName"}, podConditions...), " AND ")
+	podFilter := ""
+	if len(podConditions) > 0 {
+		podFilter = "WHERE " + strings.Join(podConditions, " AND ")
+	}
+	// Pods that opt out of the token cannot use the permissions of their ServiceAccount
+	serviceAccountMatch := `
+		MATCH (p)-[:USES_SERVICE_ACCOUNT]->(sa:ServiceAccount)<-[:BINDS]-(b)-[:GRANTS]->(role)
+		WHERE ` + strings.Join(append(roleConditions, tokenMountedCondition), " AND ")
+
+	var paths []attackPath
+
+	// Exposed pods that can reach their node
+	query := fmt.Sprintf(exposureMatch+`
+		  AND (p.privileged = 'true' OR p.pssLevel = 'privileged' OR p.runsAsRoot = 'true')
+		OPTIONAL MATCH (p)-[:SCHEDULED_ON]->(n:Node)
+		RETURN p.clusterName AS cluster, p.namespace AS namespace, s.name AS service, s.type AS serviceType, entries,
+		       p.name AS pod, p.privileged AS privileged, p.pssLevel AS level, p.runsAsRoot AS root, n.name AS node`,
+		exposedConditions)
+	for _, record := range collectRecords(query, nil) {
+		severity, reason := exposedPodSeverity(recordString(record, "privileged") == "true",
+			recordString(record, "level") == "privileged", recordString(record, "root") == "true")
+		steps := append(exposureSteps(record), fmt.Sprintf("Pod/%s/%s", recordString(record, "namespace"), recordString(record, "pod")))
+		if node := recordString(record, "node"); node != "" {
+			steps = append(steps, "Node/"+node)
+		}
+		paths = append(paths, attackPath{severity, recordString(record, "cluster"), steps, reason})
+	}
+
+	// Exposed pods whose ServiceAccount holds dangerous permissions
+	query = fmt.Sprintf(exposureMatch+serviceAccountMatch+`
+		RETURN DISTINCT p.clusterName AS cluster, p.namespace AS namespace, s.name AS service, s.type AS serviceType, entries,
+		       p.name AS pod, sa.name AS serviceAccount, labels(b)[0] AS bindingKind, b.name AS binding,
+		       labels(role)[0] AS roleKind, role.name AS role, role.riskLevel AS risk, role.riskReasons AS reasons`,
+		exposedConditions)
+	for _, record := range collectRecords(query, nil) {
+		reasons, _ := record.Get("reasons")
+		severity := severityHigh
+		if recordString(record, "risk") == "critical" {
+			severity = severityCritical
+		}
+		steps := append(exposureSteps(record),
+			fmt.Sprintf("Pod/%s/%s", recordString(record, "namespace"), recordString(record, "pod")),
+			fmt.Sprintf("ServiceAccount/%s/%s", recordString(record, "namespace"), recordString(record, "serviceAccount")),
+			recordString(record, "bindingKind")+"/"+recordString(record, "binding"),
+			recordString(record, "roleKind")+"/"+recordString(record, "role"))
+		paths = append(paths, attackPath{severity, recordString(record, "cluster"), steps,
+			"exposed pod token " + strings.Join(decodeStringList(reasons), ", ")})
+	}
+
+	// ServiceAccounts in use that can read Secrets
+	query = fmt.Sprintf(`
+		MATCH (p:Pod)
+		%s`+serviceAccountMatch+`
+		  AND (role.riskLevel = 'critical' OR role.riskReasons CONTAINS '"reads secrets"')
+		RETURN p.clusterName AS cluster, p.namespace AS namespace, sa.name AS serviceAccount, count(DISTINCT p) AS pods,
+		       labels(b)[0] AS bindingKind, b.name AS binding, labels(role)[0] AS roleKind, role.name AS role,
+		       role.riskLevel AS risk`,
+		podFilter)
+	for _, record := range collectRecords(query, nil) {
+		bindingKind := recordString(record, "bindingKind")
+		target := "Secrets in " + recordString(record, "namespace")
+		if bindingKind == "ClusterRoleBinding" {
+			target = "Secrets in all namespaces"
+		}
+		steps := []string{
+			fmt.Sprintf("ServiceAccount/%s/%s (%s pods)", recordString(record, "namespace"), recordString(record, "serviceAccount"), recordString(record, "pods")),
+			bindingKind + "/" + recordString(record, "binding"),
+			recordString(record, "roleKind") + "/" + recordString(record, "role"),
+			target,
+		}
+		reason := "pods can read secrets"
+		if recordString(record, "risk") == "critical" {
+			reason = "pods have full control"
+		}
+		paths = append(paths, attackPath{secretAccessSeverity(bindingKind, recordString(record, "risk")), recordString(record, "cluster"), steps, reason})
+	}
+
+	if len(paths) == 0 {
+		printNoResults("No attack paths found\n")
+		return
+	}
+	sortAttackPaths(paths)
+
+	keys := []string{"severity", "cluster", "path", "reason"}
+	values := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		values = append(values, []string{path.severity, path.cluster, strings.Join(path.steps, " -> "), path.reason})
+	}
+	printTable("Attack Paths", keys, values)
+}
+
+func handleUnprotectedWorkloads() {
+	conditions := []string{"(w:Deployment OR w:StatefulSet OR w:DaemonSet OR w:CronJob)"}
+	if !unprotectedIncludeSystem {
+		conditions = append(conditions, "NOT w.namespace STARTS WITH 'kube-'")
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Schedules and backups protect whole namespaces, or the workloads matching their filters
+	query := fmt.Sprintf(`
+		MATCH (w)
+		WHERE %s
+		OPTIONAL MATCH (ns:Namespace {name: w.namespace, clusterName: w.clusterName})
+		WITH w, ns
+		WHERE NOT (w)<-[:PROTECTS]-(:Schedule {paused: 'false'})
+		  AND (ns IS NULL OR NOT (ns)<-[:PROTECTS]-(:Schedule {paused: 'false'}))
+		OPTIONAL MATCH (b:Backup)-[:PROTECTS]->(target)
+		WHERE target = w OR target = ns
+		RETURN w.clusterName AS cluster, labels(w)[0] AS kind, w.namespace AS namespace, w.name AS name,
+		       count(DISTINCT b) AS backups
+		ORDER BY cluster, namespace, kind, name`,
+		strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("All workloads are covered by a backup schedule\n")
+		return
+	}
+
+	keys := []string{"cluster", "kind", "namespace", "name", "backups"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "kind"),
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			recordString(record, "backups"),
+		})
+	}
+	printTable("Workloads Without a Backup Schedule", keys, values)
+}
+
+// blockingTaints returns the distinct NoSchedule and NoExecute taints of
+// nodes, given as the JSON lists of taints stored on them
+func blockingTaints(nodeTaints []string) []string {
+	seen := make(map[string]bool)
+	taints := make([]string, 0)
+	for _, encoded := range nodeTaints {
+		var formatted []string
+		json.Unmarshal([]byte(encoded), &formatted)
+		for _, taint := range formatted {
+			if !strings.HasSuffix(taint, ":NoSchedule") && !strings.HasSuffix(taint, ":NoExecute") || seen[taint] {
+				continue
+			}
+			seen[taint] = true
+			taints = append(taints, taint)
+		}
+	}
+	sort.Strings(taints)
+	return taints
+}
+
+func handleTaintBlocked() {
+	conditions := []string{"NOT coalesce(p.status, '') IN ['Succeeded', 'Failed']"}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Nodes with NoSchedule or NoExecute taints are linked from the pods
+	// tolerating all of them, see docs/scheduling_constraints.md
+	query := fmt.Sprintf(`
+		MATCH (p:Pod)
+		WHERE %s
+		MATCH (n:Node {clusterName: p.clusterName})
+		WHERE p.nodeConstraints IS NULL OR (p)-[:CONSTRAINED_TO]->(n)
+		WITH p, collect(n) AS candidates
+		WHERE all(n IN candidates WHERE (n.taints CONTAINS ':NoSchedule"' OR n.taints CONTAINS ':NoExecute"')
+		                                AND NOT (p)-[:TOLERATES]->(n))
+		RETURN p.clusterName AS cluster, p.namespace AS namespace, p.name AS name, p.status AS status,
+		       size(candidates) AS nodes, [n IN candidates | n.taints] AS taints, p.tolerations AS tolerations
+		ORDER BY cluster, namespace, name`, strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("No pods blocked by node taints found\n")
+		return
+	}
+
+	keys := []string{"cluster", "namespace", "pod", "status", "nodes", "taints", "tolerations"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		nodeTaints := make([]string, 0)
+		if list, ok := record.AsMap()["taints"].([]interface{}); ok {
+			for _, taints := range list {
+				nodeTaints = append(nodeTaints, fmt.Sprint(taints))
+			}
+		}
+		tolerations, _ := record.Get("tolerations")
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			recordString(record, "status"),
+			recordString(record, "nodes"),
+			strings.Join(blockingTaints(nodeTaints), ", "),
+			strings.Join(decodeStringList(tolerations), ", "),
+		})
+	}
+	printTable("Pods Blocked by Node Taints", keys, values)
+}
+
+// preemptionPod is a pod running on a node, a possible preemption victim
+type preemptionPod struct {
+	ref      string // namespace/name
+	priority int64
+	cpu      int64 // Requested millicores
+	memory   int64 // Requested bytes
+}
+
+// preemptionVictims estimates the pods the scheduler evicts from a node for a
+// pod with the given requests and priority. free is what the node has left
+// before preemption. Like the scheduler, all lower priority pods are removed,
+// then pods are added back from the highest priority down as long as the pod
+// still fits. ok is false when the pod does not fit even without them; no
+// victims and ok mean the pod fits without preemption.
+func preemptionVictims(priority, cpu, memory, freeCPU, freeMemory int64, pods []preemptionPod) ([]preemptionPod, bool) {
+	if cpu <= freeCPU && memory <= freeMemory {
+		return nil, true
+	}
+	lower := make([]preemptionPod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.priority < priority {
+			lower = append(lower, pod)
+			freeCPU += pod.cpu
+			freeMemory += pod.memory
+		}
+	}
+	if cpu > freeCPU || memory > freeMemory {
+		return nil, false
+	}
+
+	sort.SliceStable(lower, func(i, j int) bool { return lower[i].priority > lower[j].priority })
+	victims := make([]preemptionPod, 0)
+	for _, pod := range lower {
+		if cpu <= freeCPU-pod.cpu && memory <= freeMemory-pod.memory {
+			freeCPU -= pod.cpu
+			freeMemory -= pod.memory
+			continue
+		}
+		victims = append(victims, pod)
+	}
+	return victims, true
+}
+
+// lessDisruptive reports whether evicting a is preferred over evicting b: the
+// highest victim priority is lower, then the sum of priorities, then fewer pods
+func lessDisruptive(a, b []preemptionPod) bool {
+	highest := func(pods []preemptionPod) (int64, int64) {
+		var top, sum int64
+		for i, pod := range pods {
+			if i == 0 || pod.priority > top {
+				top = pod.priority
+			}
+			sum += pod.priority
+		}
+		return top, sum
+	}
+	topA, sumA := highest(a)
+	topB, sumB := highest(b)
+	if topA != topB {
+		return topA < topB
+	}
+	if sumA != sumB {
+		return sumA < sumB
+	}
+	return len(a) < len(b)
+}
+
+func handlePreemptionRisk() {
+	conditions := []string{"coalesce(p.preemptionPolicy, 'PreemptLowerPriority') <> 'Never'", "toInteger(p.priority) IS NOT NULL"}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// Candidate nodes follow the same rules as taint-blocked, see docs/scheduling_constraints.md
+	query := fmt.Sprintf(`
+		MATCH (p:Pod {status: 'Pending'})
+		WHERE %s
+		MATCH (n:Node {clusterName: p.clusterName})
+		WHERE (p.nominatedNodeName IS NULL OR n.name = p.nominatedNodeName)
+		  AND coalesce(n.unschedulable, 'false') <> 'true'
+		  AND (p.nodeConstraints IS NULL OR (p)-[:CONSTRAINED_TO]->(n))
+		  AND (NOT (n.taints CONTAINS ':NoSchedule"' OR n.taints CONTAINS ':NoExecute"') OR (p)-[:TOLERATES]->(n))
+		OPTIONAL MATCH (v:Pod)-[:SCHEDULED_ON]->(n)
+		WHERE v.clusterName = n.clusterName AND NOT v.status IN ['Succeeded', 'Failed']
+		WITH p, n, collect({ref: v.namespace + '/' + v.name, priority: coalesce(toInteger(v.priority), 0),
+		                    cpu: coalesce(toInteger(v.requestedCPU), 0), memory: coalesce(toInteger(v.requestedMemory), 0)}) AS pods
+		RETURN p.clusterName AS cluster, p.namespace + '/' + p.name AS pod, toInteger(p.priority) AS priority,
+		       p.priorityClassName AS priorityClass, coalesce(toInteger(p.requestedCPU), 0) AS cpu,
+		       coalesce(toInteger(p.requestedMemory), 0) AS memory,
+		       n.name AS node, n.allocatableCPU AS allocatableCPU, n.allocatableMemory AS allocatableMemory, pods
+		ORDER BY cluster, pod, node`, strings.Join(conditions, " AND "))
+
+	type estimate struct {
+		cluster, pod, priorityClass, node string
+		priority                          int64
+		victims                           []preemptionPod
+	}
+	estimates := make(map[string]*estimate)
+	fits := make(map[string]bool)
+	order := make([]string, 0)
+	for _, record := range collectRecords(query, nil) {
+		key := recordString(record, "cluster") + "/" + recordString(record, "pod")
+		allocatableCPU, errCPU := resource.ParseQuantity(recordString(record, "allocatableCPU"))
+		allocatableMemory, errMemory := resource.ParseQuantity(recordString(record, "allocatableMemory"))
+		if errCPU != nil || errMemory != nil || fits[key] {
+			continue
+		}
+
+		freeCPU, freeMemory := allocatableCPU.MilliValue(), allocatableMemory.Value()
+		pods := make([]preemptionPod, 0)
+		if list, ok := record.AsMap()["pods"].([]interface{}); ok {
+			for _, item := range list {
+				entry, _ := item.(map[string]interface{})
+				ref, _ := entry["ref"].(string)
+				if ref == "" {
+					continue
+				}
+				pod := preemptionPod{ref: ref}
+				pod.priority, _ = entry["priority"].(int64)
+				pod.cpu, _ = entry["cpu"].(int64)
+				pod.memory, _ = entry["memory"].(int64)
+				freeCPU -= pod.cpu
+				freeMemory -= pod.memory
+				pods = append(pods, pod)
+			}
+		}
+
+		priority := recordInt64(record, "priority")
+		victims, ok := preemptionVictims(priority, recordInt64(record, "cpu"), recordInt64(record, "memory"), freeCPU, freeMemory, pods)
+		switch {
+		case !ok:
+			continue
+		case len(victims) == 0:
+			// The pod fits somewhere, it is pending for another reason
+			fits[key] = true
+			delete(estimates, key)
+			continue
+		}
+		current, seen := estimates[key]
+		if !seen {
+			order = append(order, key)
+		}
+		if !seen || lessDisruptive(victims, current.victims) {
+			estimates[key] = &estimate{
+				cluster:       recordString(record, "cluster"),
+				pod:           recordString(record, "pod"),
+				priorityClass: recordString(record, "priorityClass"),
+				node:          recordString(record, "node"),
+				priority:      priority,
+				victims:       victims,
+			}
+		}
+	}
+
+	keys := []string{"cluster", "pendingPod", "priority", "priorityClass", "node", "evicted", "victims"}
+	values := make([][]string, 0, len(estimates))
+	for _, key := range order {
+		e, ok := estimates[key]
+		if !ok {
+			continue
+		}
+		victims := make([]string, 0, len(e.victims))
+		for _, victim := range e.victims {
+			victims = append(victims, fmt.Sprintf("%s (%d)", victim.ref, victim.priority))
+		}
+		values = append(values, []string{e.cluster, e.pod, strconv.FormatInt(e.priority, 10), e.priorityClass, e.node,
+			strconv.Itoa(len(e.victims)), strings.Join(victims, ", ")})
+	}
+	if len(values) == 0 {
+		printNoResults("No pending pods would preempt lower priority pods\n")
+		return
+	}
+	printTable("Preemption Risk", keys, values)
+}
+
+func handleMissingProbes() {
+	conditions := []string{"(w:Deployment OR w:StatefulSet OR w:DaemonSet)", "w.missingProbes IS NOT NULL", "w.missingProbes <> '[]'"}
+	if !missingProbesIncludeSystem {
+		conditions = append(conditions, "NOT w.namespace STARTS WITH 'kube-'")
+	}
+	if filter := getClusterFilterWithVar("w"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (w)
+		WHERE %s
+		RETURN w.clusterName AS cluster, labels(w)[0] AS kind, w.namespace AS namespace, w.name AS name,
+		       w.missingProbes AS missing
+		ORDER BY cluster, namespace, kind, name`,
+		strings.Join(conditions, " AND "))
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("All workloads have readiness and liveness probes\n")
+		return
+	}
+
+	keys := []string{"cluster", "kind", "namespace", "name", "missing"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		missing, _ := record.Get("missing")
+		values = append(values, []string{
+			recordString(record, "cluster"),
+			recordString(record, "kind"),
+			recordString(record, "namespace"),
+			recordString(record, "name"),
+			strings.Join(decodeStringList(missing), ", "),
+		})
+	}
+	printTable("Workloads Missing Probes", keys, values)
+}
+
+func handleTop(args []string) {
+	limit := 20
+	if len(args) > 1 {
+		if l, err := strconv.Atoi(args[1]); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	order := "usageCPU"
+	switch topSortBy {
+	case "cpu":
+	case "memory":
+		order = "usageMemory"
+	default:
+		logger.Error("Invalid --sort-by %q: expected cpu or memory", topSortBy)
+		os.Exit(exitError)
+	}
+
+	var label string
+	switch args[0] {
+	case "nodes", "node":
+		label = "Node"
+	case "pods", "pod":
+		label = "Pod"
+	default:
+		logger.Error("Invalid resource %q: expected nodes or pods", args[0])
+		os.Exit(exitError)
+	}
+
+	conditions := []string{"n.usageCPU IS NOT NULL"}
+	if filter := getClusterFilterWithVar("n"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+
+	// usageCPU (millicores), usageMemory (bytes) and sampledAt are written by the usage poller
+	query := fmt.Sprintf(`
+		MATCH (n:%s)
+		WHERE %s
+		RETURN n.clusterName AS cluster, n.namespace AS namespace, n.name AS name, n.nodeName AS node,
+		       n.usageCPU AS cpu, n.usageMemory AS memory,
+		       n.allocatableCPU AS allocatableCPU, n.allocatableMemory AS allocatableMemory,
+		       n.sampledAt AS sampledAt
+		ORDER BY n.%s DESC, cluster, namespace, name
+		LIMIT %d`,
+		label, strings.Join(conditions, " AND "), order, limit)
+
+	records := collectRecords(query, nil)
+	if len(records) == 0 {
+		printNoResults("No usage found for %s; is k8s-graph running with --usage-metrics?\n", args[0])
+		return
+	}
+
+	var keys []string
+	if label == "Node" {
+		keys = []string{"cluster", "name", "cpu", "cpu%", "memory", "memory%", "sampled"}
+	} else {
+		keys = []string{"cluster", "namespace", "name", "node", "cpu", "memory", "sampled"}
+	}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		cpuValue, _ := record.Get("cpu")
+		memoryValue, _ := record.Get("memory")
+		cpu, _ := cpuValue.(int64)
+		memory, _ := memoryValue.(int64)
+		if label == "Node" {
+			values = append(values, []string{
+				recordString(record, "cluster"),
+				recordString(record, "name"),
+				fmt.Sprintf("%dm", cpu),
+				usagePercent(cpu, recordString(record, "allocatableCPU"), true),
+				formatBytes(memory),
+				usagePercent(memory, recordString(record, "allocatableMemory"), false),
+				sampleAge(recordString(record, "sampledAt")),
+			})
+		} else {
+			values = append(values, []string{
+				recordString(record, "cluster"),
+				recordString(record, "namespace"),
+				recordString(record, "name"),
+				recordString(record, "node"),
+				fmt.Sprintf("%dm", cpu),
+				formatBytes(memory),
+				sampleAge(recordString(record, "sampledAt")),
+			})
+		}
+	}
+	printTable(fmt.Sprintf("Top %ss by %s", label, topSortBy), keys, values)
+}
+
+// deploymentEfficiency compares the requests of the sampled pods of a
+// Deployment to their usage. CPU is in millicores and memory in bytes.
+type deploymentEfficiency struct {
+	Cluster           string  `json:"cluster"`
+	Namespace         string  `json:"namespace"`
+	Name              string  `json:"name"`
+	Pods              int64   `json:"pods"`
+	RequestedCPU      int64   `json:"requestedCPU"`
+	UsedCPU           int64   `json:"usedCPU"`
+	LimitCPU          int64   `json:"limitCPU"`
+	CPUUtilization    float64 `json:"cpuUtilization"`
+	RequestedMemory   int64   `json:"requestedMemory"`
+	UsedMemory        int64   `json:"usedMemory"`
+	LimitMemory       int64   `json:"limitMemory"`
+	MemoryUtilization float64 `json:"memoryUtilization"`
+	ReclaimableCPU    int64   `json:"reclaimableCPU"`
+	ReclaimableMemory int64   `json:"reclaimableMemory"`
+}
+
+// idleNamespace is a namespace whose sampled pods barely use any CPU
+type idleNamespace struct {
+	Cluster         string `json:"cluster"`
+	Namespace       string `json:"namespace"`
+	Pods            int64  `json:"pods"`
+	RequestedCPU    int64  `json:"requestedCPU"`
+	UsedCPU         int64  `json:"usedCPU"`
+	RequestedMemory int64  `json:"requestedMemory"`
+	UsedMemory      int64  `json:"usedMemory"`
+}
+
+// utilization returns used as a percentage of requested, or -1 without requests
+func utilization(used, requested int64) float64 {
+	if requested <= 0 {
+		return -1
+	}
+	return float64(used) / float64(requested) * 100
+}
+
+// overProvisioned reports whether a Deployment uses less than maxUtilization
+// percent of its requested CPU or memory
+func overProvisioned(d deploymentEfficiency, maxUtilization float64) bool {
+	return (d.CPUUtilization >= 0 && d.CPUUtilization < maxUtilization) ||
+		(d.MemoryUtilization >= 0 && d.MemoryUtilization < maxUtilization)
+}
+
+// formatUtilization renders a utilization percentage, "-" without requests
+func formatUtilization(percent float64) string {
+	if percent < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", percent)
+}
+
+func recordInt64(record *driverneo4j.Record, key string) int64 {
+	value, _ := record.Get(key)
+	number, _ := value.(int64)
+	return number
+}
+
+func handleEfficiency() {
+	// Pods are only compared while running and once sampled; usageCPU and
+	// requestedCPU are in millicores, usageMemory and requestedMemory in bytes
+	conditions := []string{"p.usageCPU IS NOT NULL", "NOT p.status IN ['Succeeded', 'Failed']"}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	deploymentRecords := collectRecords(fmt.Sprintf(`
+		MATCH (d:Deployment)<-[:OWNED_BY]-(:ReplicaSet)<-[:OWNED_BY]-(p:Pod)
+		WHERE %s
+		RETURN d.clusterName AS cluster, d.namespace AS namespace, d.name AS name, count(p) AS pods,
+		       sum(coalesce(toInteger(p.requestedCPU), 0)) AS requestedCPU, sum(p.usageCPU) AS usedCPU,
+		       sum(coalesce(toInteger(p.limitCPU), 0)) AS limitCPU,
+		       sum(coalesce(toInteger(p.requestedMemory), 0)) AS requestedMemory, sum(p.usageMemory) AS usedMemory,
+		       sum(coalesce(toInteger(p.limitMemory), 0)) AS limitMemory`, where), nil)
+
+	deployments := make([]deploymentEfficiency, 0)
+	for _, record := range deploymentRecords {
+		d := deploymentEfficiency{
+			Cluster:         recordString(record, "cluster"),
+			Namespace:       recordString(record, "namespace"),
+			Name:            recordString(record, "name"),
+			Pods:            recordInt64(record, "pods"),
+			RequestedCPU:    recordInt64(record, "requestedCPU"),
+			UsedCPU:         recordInt64(record, "usedCPU"),
+			LimitCPU:        recordInt64(record, "limitCPU"),
+			RequestedMemory: recordInt64(record, "requestedMemory"),
+			UsedMemory:      recordInt64(record, "usedMemory"),
+			LimitMemory:     recordInt64(record, "limitMemory"),
+		}
+		d.CPUUtilization = utilization(d.UsedCPU, d.RequestedCPU)
+		d.MemoryUtilization = utilization(d.UsedMemory, d.RequestedMemory)
+		if !overProvisioned(d, efficiencyMaxUtilization) {
+			continue
+		}
+		d.ReclaimableCPU = max(d.RequestedCPU-d.UsedCPU, 0)
+		d.ReclaimableMemory = max(d.RequestedMemory-d.UsedMemory, 0)
+		deployments = append(deployments, d)
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		if deployments[i].ReclaimableCPU != deployments[j].ReclaimableCPU {
+			return deployments[i].ReclaimableCPU > deployments[j].ReclaimableCPU
+		}
+		return deployments[i].ReclaimableMemory > deployments[j].ReclaimableMemory
+	})
+
+	namespaceRecords := collectRecords(fmt.Sprintf(`
+		MATCH (p:Pod)
+		WHERE %s
+		WITH p.clusterName AS cluster, p.namespace AS namespace, count(p) AS pods,
+		     sum(coalesce(toInteger(p.requestedCPU), 0)) AS requestedCPU, sum(p.usageCPU) AS usedCPU,
+		     sum(coalesce(toInteger(p.requestedMemory), 0)) AS requestedMemory, sum(p.usageMemory) AS usedMemory
+		WHERE usedCPU < $idleCPU
+		RETURN cluster, namespace, pods, requestedCPU, usedCPU, requestedMemory, usedMemory
+		ORDER BY requestedCPU DESC, cluster, namespace`, where),
+		map[string]interface{}{"idleCPU": efficiencyIdleCPU})
+
+	namespaces := make([]idleNamespace, 0, len(namespaceRecords))
+	for _, record := range namespaceRecords {
+		namespaces = append(namespaces, idleNamespace{
+			Cluster:         recordString(record, "cluster"),
+			Namespace:       recordString(record, "namespace"),
+			Pods:            recordInt64(record, "pods"),
+			RequestedCPU:    recordInt64(record, "requestedCPU"),
+			UsedCPU:         recordInt64(record, "usedCPU"),
+			RequestedMemory: recordInt64(record, "requestedMemory"),
+			UsedMemory:      recordInt64(record, "usedMemory"),
+		})
+	}
+
+	if viper.GetString("output") == "json" {
+		report := map[string]interface{}{
+			"maxUtilization":             efficiencyMaxUtilization,
+			"idleCPU":                    efficiencyIdleCPU,
+			"overProvisionedDeployments": deployments,
+			"idleNamespaces":             namespaces,
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			logger.Error("Failed to write report: %v", err)
+			os.Exit(exitError)
+		}
+		resultCount = len(deployments) + len(namespaces)
+		return
+	}
+
+	if len(deploymentRecords) == 0 && len(namespaceRecords) == 0 {
+		printNoResults("No pod usage found; is k8s-graph running with --usage-metrics?\n")
+		return
+	}
+
+	if len(deployments) > 0 {
+		keys := []string{"cluster", "namespace", "name", "pods", "cpu requested", "cpu used", "cpu%", "cpu limit", "memory requested", "memory used", "memory%", "memory limit", "reclaimable"}
+		values := make([][]string, 0, len(deployments))
+		for _, d := range deployments {
+			values = append(values, []string{
+				d.Cluster,
+				d.Namespace,
+				d.Name,
+				strconv.FormatInt(d.Pods, 10),
+				fmt.Sprintf("%dm", d.RequestedCPU),
+				fmt.Sprintf("%dm", d.UsedCPU),
+				formatUtilization(d.CPUUtilization),
+				fmt.Sprintf("%dm", d.LimitCPU),
+				formatBytes(d.RequestedMemory),
+				formatBytes(d.UsedMemory),
+				formatUtilization(d.MemoryUtilization),
+				formatBytes(d.LimitMemory),
+				fmt.Sprintf("%dm, %s", d.ReclaimableCPU, formatBytes(d.ReclaimableMemory)),
+			})
+		}
+		printTable(fmt.Sprintf("Deployments Using Less Than %.0f%% of Their Requests", efficiencyMaxUtilization), keys, values)
+	} else {
+		printNoResults("No over-provisioned Deployments found\n")
+	}
+
+	if len(namespaces) > 0 {
+		keys := []string{"cluster", "namespace", "pods", "cpu requested", "cpu used", "memory requested", "memory used"}
+		values := make([][]string, 0, len(namespaces))
+		for _, ns := range namespaces {
+			values = append(values, []string{
+				ns.Cluster,
+				ns.Namespace,
+				strconv.FormatInt(ns.Pods, 10),
+				fmt.Sprintf("%dm", ns.RequestedCPU),
+				fmt.Sprintf("%dm", ns.UsedCPU),
+				formatBytes(ns.RequestedMemory),
+				formatBytes(ns.UsedMemory),
+			})
+		}
+		printTable(fmt.Sprintf("Idle Namespaces (CPU < %dm)", efficiencyIdleCPU), keys, values)
+	} else {
+		printNoResults("No idle namespaces found\n")
+	}
+	resultCount = len(deployments) + len(namespaces)
+}
+
+// usagePercent renders usage as a percentage of an allocatable quantity,
+// comparing millicores for CPU and bytes otherwise
+func usagePercent(used int64, allocatable string, milli bool) string {
+	quantity, err := resource.ParseQuantity(allocatable)
+	if err != nil {
+		return "-"
+	}
+	total := quantity.Value()
+	if milli {
+		total = quantity.MilliValue()
+	}
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(used)/float64(total)*100)
+}
+
+// sampleAge renders how long ago a usage sample was taken
+func sampleAge(sampledAt string) string {
+	sampled, err := time.Parse(time.RFC3339, sampledAt)
+	if err != nil {
+		return sampledAt
+	}
+	return time.Since(sampled).Round(time.Second).String() + " ago"
+}
+
+// storedPolicyRule is an ingress or egress rule of a NetworkPolicy as stored in
+// its ingress and egress properties
+type storedPolicyRule struct {
+	Ports []struct {
+		Protocol string `json:"protocol"`
+		Port     string `json:"port"`
+		EndPort  int32  `json:"endPort"`
+	} `json:"ports"`
+	From []storedPolicyPeer `json:"from"`
+	To   []storedPolicyPeer `json:"to"`
+}
+
+type storedPolicyPeer struct {
+	IPBlock *struct {
+		CIDR   string   `json:"cidr"`
+		Except []string `json:"except"`
+	} `json:"ipBlock"`
+}
+
+// policyRule is a rule of a NetworkPolicy that is not materialized as ALLOWS
+// relationships: it either has no peers and allows any pod, or has IP blocks
+type policyRule struct {
+	Ports    []string // "protocol/port" specs as on ALLOWS relationships; empty allows all ports
+	AnyPeer  bool
+	IPBlocks []ipBlock
+}
+
+type ipBlock struct {
+	CIDR   string
+	Except []string
+}
+
+// networkPolicyInfo is a NetworkPolicy selecting a pod, with the rules of the
+// direction being checked
+type networkPolicyInfo struct {
+	UID   string
+	Name  string
+	Rules []policyRule
+}
+
+// allowEdge is an ALLOWS_EGRESS_TO or ALLOWS_INGRESS_FROM relationship
+type allowEdge struct {
+	PolicyUID string
+	Policy    string
+	Ports     []string
+}
+
+// connectionVerdict is whether traffic is allowed in one direction, and why
+type connectionVerdict struct {
+	Allowed bool
+	Reason  string
+}
+
+// policyRules converts the stored rules of one direction
+func policyRules(rules []storedPolicyRule, direction string) []policyRule {
+	result := make([]policyRule, 0, len(rules))
+	for _, stored := range rules {
+		rule := policyRule{Ports: make([]string, 0, len(stored.Ports))}
+		for _, port := range stored.Ports {
+			spec := defaultProtocol(port.Protocol)
+			if port.Port != "" {
+				spec += "/" + port.Port
+				if port.EndPort != 0 {
+					spec += fmt.Sprintf("-%d", port.EndPort)
+				}
+			}
+			rule.Ports = append(rule.Ports, spec)
+		}
+		peers := stored.From
+		if direction == "Egress" {
+			peers = stored.To
+		}
+		rule.AnyPeer = len(peers) == 0
+		for _, peer := range peers {
+			if peer.IPBlock != nil {
+				rule.IPBlocks = append(rule.IPBlocks, ipBlock{CIDR: peer.IPBlock.CIDR, Except: peer.IPBlock.Except})
+			}
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// enforcesDirection reports whether a policy with the given stored policy
+// types applies to a direction. Without policy types a policy applies to
+// ingress, and to egress when it has egress rules.
+func enforcesDirection(policyTypes []string, direction string, hasEgressRules bool) bool {
+	if len(policyTypes) == 0 {
+		return direction == "Ingress" || hasEgressRules
+	}
+	for _, policyType := range policyTypes {
+		if policyType == direction {
+			return true
+		}
+	}
+	return false
+}
+
+// portAllowed reports whether port specs allow a port. namedPorts maps
+// "protocol/name" to the port number of the destination pod's named ports. An
+// empty port matches any spec, as does an empty spec list.
+func portAllowed(specs []string, port, protocol string, namedPorts map[string]string) bool {
+	if len(specs) == 0 || port == "" {
+		return true
+	}
+	number, _ := strconv.Atoi(port)
+	for _, spec := range specs {
+		specProtocol, specPort, _ := strings.Cut(spec, "/")
+		if specProtocol != protocol {
+			continue
+		}
+		if specPort == "" || specPort == port || namedPorts[protocol+"/"+specPort] == port {
+			return true
+		}
+		if start, end, isRange := strings.Cut(specPort, "-"); isRange {
+			first, firstErr := strconv.Atoi(start)
+			last, lastErr := strconv.Atoi(end)
+			if firstErr == nil && lastErr == nil && number >= first && number <= last {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ipBlockMatches reports whether an IP lies in the block and outside its exceptions
+func ipBlockMatches(block ipBlock, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	if _, network, err := net.ParseCIDR(block.CIDR); err != nil || !network.Contains(addr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, network, err := net.ParseCIDR(except); err == nil && network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatPortSpecs describes the ports allowed by a rule or relationship
+func formatPortSpecs(specs []string) string {
+	if len(specs) == 0 {
+		return "all ports"
+	}
+	return strings.Join(specs, ", ")
+}
+
+// evaluateDirection decides whether the policies selecting a pod allow
+// traffic in one direction. edges are the ALLOWS relationships between the
+// two pods for that direction and peerIP is the IP of the other pod.
+func evaluateDirection(direction, pod string, selecting []networkPolicyInfo, edges []allowEdge, peerIP, port, protocol string, namedPorts map[string]string) connectionVerdict {
+	if len(selecting) == 0 {
+		return connectionVerdict{Allowed: true, Reason: fmt.Sprintf("no NetworkPolicy selects %s for %s, allowed by default", pod, strings.ToLower(direction))}
+	}
+
+	names := make([]string, 0, len(selecting))
+	selected := make(map[string]bool, len(selecting))
+	allowedBy := make([]string, 0)
+	for _, policy := range selecting {
+		names = append(names, policy.Name)
+		selected[policy.UID] = true
+		for _, rule := range policy.Rules {
+			if !portAllowed(rule.Ports, port, protocol, namedPorts) {
+				continue
+			}
+			if rule.AnyPeer {
+				allowedBy = append(allowedBy, fmt.Sprintf("%s (any peer, %s)", policy.Name, formatPortSpecs(rule.Ports)))
+				continue
+			}
+			for _, block := range rule.IPBlocks {
+				if ipBlockMatches(block, peerIP) {
+					allowedBy = append(allowedBy, fmt.Sprintf("%s (ipBlock %s, %s)", policy.Name, block.CIDR, formatPortSpecs(rule.Ports)))
+				}
+			}
+		}
+	}
+	for _, edge := range edges {
+		if selected[edge.PolicyUID] && portAllowed(edge.Ports, port, protocol, namedPorts) {
+			allowedBy = append(allowedBy, fmt.Sprintf("%s (%s)", edge.Policy, formatPortSpecs(edge.Ports)))
+		}
+	}
 
-	// Debug: Print the configuration being used
-	if viper.GetBool("debug") {
-		fmt.Fprintf(os.Stderr, "Debug: Using Neo4j URI: %s\n", cfg.Neo4j.URI)
-		fmt.Fprintf(os.Stderr, "Debug: Using Neo4j Username: %s\n", cfg.Neo4j.Username)
-		fmt.Fprintf(os.Stderr, "Debug: Using Cluster Name: %s\n", cfg.Kubernetes.ClusterName)
+	if len(allowedBy) > 0 {
+		sort.Strings(allowedBy)
+		return connectionVerdict{Allowed: true, Reason: "allowed by " + strings.Join(allowedBy, "; ")}
 	}
+	sort.Strings(names)
+	return connectionVerdict{Allowed: false, Reason: fmt.Sprintf("%s is selected by %s for %s and no rule allows it", pod, strings.Join(names, ", "), strings.ToLower(direction))}
+}
 
-	// Initialize context
-	ctx = context.Background()
+// parseConnectPort parses a port argument given as "8080", "8080/UDP" or a port name
+func parseConnectPort(arg string) (string, string, error) {
+	port, protocol, _ := strings.Cut(arg, "/")
+	protocol = strings.ToUpper(defaultProtocol(protocol))
+	if protocol != "TCP" && protocol != "UDP" && protocol != "SCTP" {
+		return "", "", fmt.Errorf("invalid protocol %q: must be TCP, UDP or SCTP", protocol)
+	}
+	if number, err := strconv.Atoi(port); err == nil && (number < 1 || number > 65535) {
+		return "", "", fmt.Errorf("invalid port %d: must be between 1 and 65535", number)
+	}
+	if port == "" {
+		return "", "", fmt.Errorf("invalid port %q", arg)
+	}
+	return port, protocol, nil
+}
 
-	// Initialize Neo4j client
-	var err error
-	client, err = neo4j.NewClient(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create Neo4j client: %w", err)
+// connectPod is a pod given to can-connect
+type connectPod struct {
+	UID        string
+	Ref        string
+	Namespace  string
+	Cluster    string
+	IP         string
+	Labels     map[string]string
+	NamedPorts map[string]string
+}
+
+// findConnectPod looks up a pod given as namespace/name or as a unique name
+func findConnectPod(ref string) connectPod {
+	record := findPod(ref, "p.uid AS uid, p.podIP AS ip, p.labels AS labels, p.containerPorts AS containerPorts")
+	pod := connectPod{
+		UID:        recordString(record, "uid"),
+		Ref:        recordString(record, "namespace") + "/" + recordString(record, "name"),
+		Namespace:  recordString(record, "namespace"),
+		Cluster:    recordString(record, "cluster"),
+		IP:         recordString(record, "ip"),
+		NamedPorts: make(map[string]string),
+	}
+	json.Unmarshal([]byte(recordString(record, "labels")), &pod.Labels)
+	ports, _ := record.Get("containerPorts")
+	for _, containerPort := range decodeStringList(ports) {
+		info := parsePortInfo(containerPort)
+		if info["name"] != "" {
+			pod.NamedPorts[defaultProtocol(info["protocol"])+"/"+info["name"]] = info["containerPort"]
+		}
+	}
+	return pod
+}
+
+// findPod looks up a pod given as namespace/name or as a unique name and
+// returns the columns of returns, which refer to the pod as p. It exits when
+// no pod or several pods match.
+func findPod(ref, returns string) *driverneo4j.Record {
+	conditions := []string{"p.name = $name"}
+	params := map[string]interface{}{"name": ref}
+	if namespace, name, ok := strings.Cut(ref, "/"); ok {
+		conditions = append(conditions, "p.namespace = $namespace")
+		params["namespace"] = namespace
+		params["name"] = name
+	}
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
 	}
 
-	return nil
+	records := collectRecords(fmt.Sprintf(`
+		MATCH (p:Pod)
+		WHERE %s
+		RETURN p.name AS name, p.namespace AS namespace, p.clusterName AS cluster, %s`,
+		strings.Join(conditions, " AND "), returns), params)
+	if len(records) == 0 {
+		logger.Error("Pod %s not found", ref)
+		os.Exit(exitError)
+	}
+	if len(records) > 1 {
+		matches := make([]string, 0, len(records))
+		for _, record := range records {
+			matches = append(matches, recordString(record, "cluster")+":"+recordString(record, "namespace")+"/"+recordString(record, "name"))
+		}
+		logger.Error("Pod %s is ambiguous, matching %s: give it as namespace/name or set --cluster-name", ref, strings.Join(matches, ", "))
+		os.Exit(exitError)
+	}
+	return records[0]
 }
 
-func main() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+// selectingPolicies returns the NetworkPolicies selecting a pod for a direction
+func selectingPolicies(pod connectPod, direction string) []networkPolicyInfo {
+	records := collectRecords(`
+		MATCH (np:NetworkPolicy {namespace: $namespace, clusterName: $cluster})
+		RETURN np.uid AS uid, np.name AS name, np.podSelector AS podSelector,
+		       np.policyTypes AS policyTypes, np.ingress AS ingress, np.egress AS egress`,
+		map[string]interface{}{"namespace": pod.Namespace, "cluster": pod.Cluster})
+
+	policies := make([]networkPolicyInfo, 0)
+	for _, record := range records {
+		selectorValue, _ := record.Get("podSelector")
+		if selectorValue == nil {
+			logger.Warn("NetworkPolicy %s/%s has no pod selector in the graph yet, ignoring it", pod.Namespace, recordString(record, "name"))
+			continue
+		}
+		selector, err := labels.Parse(fmt.Sprintf("%v", selectorValue))
+		if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		var ingress, egress []storedPolicyRule
+		json.Unmarshal([]byte(recordString(record, "ingress")), &ingress)
+		json.Unmarshal([]byte(recordString(record, "egress")), &egress)
+		policyTypes, _ := record.Get("policyTypes")
+		if !enforcesDirection(decodeStringList(policyTypes), direction, len(egress) > 0) {
+			continue
+		}
+		rules := ingress
+		if direction == "Egress" {
+			rules = egress
+		}
+		policies = append(policies, networkPolicyInfo{
+			UID:   recordString(record, "uid"),
+			Name:  recordString(record, "name"),
+			Rules: policyRules(rules, direction),
+		})
 	}
+	return policies
 }
 
-func handleNodes(args []string) {
-	if len(args) == 0 {
-		// Show all node types
-		query := `
-			MATCH (n)
-			WITH labels(n)[0] as label
-			RETURN label, count(*) as count
-			ORDER BY count DESC`
-		executeQuery(query, "Node Types")
+// allowEdges returns the relationships of a type from one pod to another
+func allowEdges(relType, fromUID, toUID string) []allowEdge {
+	records := collectRecords(fmt.Sprintf(`
+		MATCH (:Pod {uid: $from})-[r:%s]->(:Pod {uid: $to})
+		RETURN r.policyUid AS policyUid, r.policy AS policy, r.ports AS ports`, relType),
+		map[string]interface{}{"from": fromUID, "to": toUID})
+
+	edges := make([]allowEdge, 0, len(records))
+	for _, record := range records {
+		ports := make([]string, 0)
+		if value, _ := record.Get("ports"); value != nil {
+			list, _ := value.([]interface{})
+			for _, port := range list {
+				ports = append(ports, fmt.Sprintf("%v", port))
+			}
+		}
+		edges = append(edges, allowEdge{
+			PolicyUID: recordString(record, "policyUid"),
+			Policy:    recordString(record, "policy"),
+			Ports:     ports,
+		})
+	}
+	return edges
+}
+
+func handleCanConnect(args []string) {
+	port, protocol := "", ""
+	if len(args) == 3 {
+		var err error
+		if port, protocol, err = parseConnectPort(args[2]); err != nil {
+			logger.Error("%v", err)
+			os.Exit(exitError)
+		}
+	}
+
+	source := findConnectPod(args[0])
+	destination := findConnectPod(args[1])
+	if source.Cluster != destination.Cluster {
+		logger.Error("Pods %s and %s are in different clusters (%s and %s)", source.Ref, destination.Ref, source.Cluster, destination.Cluster)
+		os.Exit(exitError)
+	}
+
+	// Named ports are resolved against the destination pod
+	if _, err := strconv.Atoi(port); port != "" && err != nil {
+		number, ok := destination.NamedPorts[protocol+"/"+port]
+		if !ok {
+			logger.Error("Pod %s has no %s port named %s", destination.Ref, protocol, port)
+			os.Exit(exitError)
+		}
+		port = number
+	}
+
+	egress := evaluateDirection("Egress", source.Ref, selectingPolicies(source, "Egress"),
+		allowEdges("ALLOWS_EGRESS_TO", source.UID, destination.UID), destination.IP, port, protocol, destination.NamedPorts)
+	ingress := evaluateDirection("Ingress", destination.Ref, selectingPolicies(destination, "Ingress"),
+		allowEdges("ALLOWS_INGRESS_FROM", destination.UID, source.UID), source.IP, port, protocol, destination.NamedPorts)
+
+	allowed := egress.Allowed && ingress.Allowed
+	verdict := "denied"
+	resultCount = 0
+	if allowed {
+		verdict = "allowed"
+		resultCount = 1
+	}
+	if quiet {
+		fmt.Println(verdict)
 		return
 	}
 
-	nodeType := args[0]
-	limit := 10
-	if len(args) > 1 {
-		if l, err := fmt.Sscanf(args[1], "%d", &limit); err != nil || l == 0 {
-			limit = 10
+	target := "any port"
+	if port != "" {
+		target = protocol + "/" + port
+	}
+	fmt.Printf("\n=== Can %s connect to %s on %s? ===\n\n", source.Ref, destination.Ref, target)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, side := range []struct {
+		name    string
+		verdict connectionVerdict
+	}{{"Egress from " + source.Ref, egress}, {"Ingress to " + destination.Ref, ingress}} {
+		status := "denied"
+		if side.verdict.Allowed {
+			status = "allowed"
 		}
+		fmt.Fprintf(w, "%s:\t%s\t%s\n", side.name, status, side.verdict.Reason)
 	}
+	w.Flush()
+	fmt.Printf("\nResult: %s\n\n", strings.ToUpper(verdict))
+}
 
-	query := fmt.Sprintf(`
-		MATCH (n:%s)
-		%s
-		RETURN n.name as name, n.namespace as namespace, n.clusterName as cluster
-		ORDER BY n.name
-		LIMIT %d`, nodeType, getClusterFilterWithVar("n"), limit)
+// podDiagnosis is what the graph knows about a pod that is not running
+type podDiagnosis struct {
+	Ref          string
+	Status       string
+	NodeName     string
+	RestartCount int64
+	Conditions   map[string]string
+	Containers   []map[string]string // container info fields, see the pod handler
+	Node         *diagnosisNode      // nil if the pod is not scheduled or the node is unknown
+	Volumes      []diagnosisVolume
+	OwnerChain   []string
+	Events       []diagnosisEvent
+}
 
-	executeQuery(query, fmt.Sprintf("%s Nodes", nodeType))
+// diagnosisNode is the node a diagnosed pod is scheduled on
+type diagnosisNode struct {
+	Name          string
+	Conditions    map[string]string
+	Unschedulable bool
+	Taints        []string
 }
 
-func handleRelationships(args []string) {
-	if len(args) == 0 {
-		// Show all relationship types
-		query := fmt.Sprintf(`
-			MATCH ()-[r]->()
-			%s
-			WITH type(r) as relationshipType, count(*) as count
-			RETURN relationshipType, count
-			ORDER BY count DESC`,
-			getClusterFilterForRelationships())
-		executeQuery(query, "Relationship Types")
-		return
+// diagnosisVolume is a PVC used by a diagnosed pod
+type diagnosisVolume struct {
+	Name         string
+	Status       string
+	StorageClass string
+}
+
+// diagnosisEvent is a recent event of a diagnosed pod
+type diagnosisEvent struct {
+	Type     string
+	Reason   string
+	Message  string
+	Count    string
+	LastSeen string
+}
+
+// nodePressureConditions are the node conditions that are a problem when True
+var nodePressureConditions = []string{"MemoryPressure", "DiskPressure", "PIDPressure", "NetworkUnavailable"}
+
+// causes returns the likely reasons the pod is not running and ready, most
+// specific first
+func (d podDiagnosis) causes() []string {
+	var causes []string
+	if d.Status == "Succeeded" {
+		return []string{"Pod completed successfully; it is not expected to be running"}
 	}
 
-	relType := args[0]
-	limit := 10
-	if len(args) > 1 {
-		if l, err := fmt.Sscanf(args[1], "%d", &limit); err != nil || l == 0 {
-			limit = 10
+	if d.NodeName == "" {
+		cause := "Pod is not scheduled on any node"
+		if message := d.eventMessage("FailedScheduling"); message != "" {
+			cause += ": " + message
 		}
+		causes = append(causes, cause)
 	}
 
-	query := fmt.Sprintf(`
-		MATCH (a)-[r:%s]->(b)
-		%s
-		RETURN labels(a)[0] as from_type, a.name as from_name, 
-		       labels(b)[0] as to_type, b.name as to_name,
-		       a.clusterName as cluster
-		ORDER BY a.name, b.name
-		LIMIT %d`, relType, getClusterFilterForRelationships(), limit)
+	if d.Node != nil {
+		if ready := d.Node.Conditions["Ready"]; ready != "" && ready != "True" {
+			causes = append(causes, fmt.Sprintf("Node %s is not Ready (Ready=%s)", d.Node.Name, ready))
+		}
+		for _, condition := range nodePressureConditions {
+			if d.Node.Conditions[condition] == "True" {
+				causes = append(causes, fmt.Sprintf("Node %s reports %s", d.Node.Name, condition))
+			}
+		}
+		if d.Node.Unschedulable {
+			causes = append(causes, fmt.Sprintf("Node %s is cordoned", d.Node.Name))
+		}
+	}
+
+	for _, volume := range d.Volumes {
+		if volume.Status != "Bound" {
+			cause := fmt.Sprintf("PVC %s is %s, not Bound", volume.Name, volume.Status)
+			if volume.StorageClass != "" {
+				cause += fmt.Sprintf(" (storage class %s)", volume.StorageClass)
+			}
+			causes = append(causes, cause)
+		}
+	}
+
+	for _, container := range d.Containers {
+		name, reason, message := container["name"], container["reason"], container["message"]
+		switch {
+		case reason == "ErrImagePull" || reason == "ImagePullBackOff" || reason == "InvalidImageName":
+			cause := fmt.Sprintf("Container %s cannot pull image %s (%s)", name, container["image"], reason)
+			if message == "" {
+				message = d.eventMessage("Failed")
+			}
+			if message != "" {
+				cause += ": " + message
+			}
+			causes = append(causes, cause)
+		case reason == "CreateContainerConfigError" || reason == "CreateContainerError":
+			causes = append(causes, fmt.Sprintf("Container %s cannot be created, e.g. a referenced ConfigMap or Secret is missing (%s): %s", name, reason, message))
+		case reason == "CrashLoopBackOff":
+			causes = append(causes, fmt.Sprintf("Container %s keeps crashing (CrashLoopBackOff, %s restarts)", name, container["restartCount"]))
+		case reason == "OOMKilled":
+			causes = append(causes, fmt.Sprintf("Container %s was killed for exceeding its memory limit (OOMKilled)", name))
+		case container["state"] == "Terminated" && container["exitCode"] != "0" && d.Status != "Succeeded":
+			causes = append(causes, fmt.Sprintf("Container %s exited with code %s (%s)", name, container["exitCode"], reason))
+		case container["state"] == "Waiting" && reason == "ContainerCreating" && len(d.Volumes) > 0:
+			causes = append(causes, fmt.Sprintf("Container %s is still being created, e.g. waiting for its volumes to be attached", name))
+		}
+	}
+
+	if d.Status == "Running" && d.Conditions["Ready"] == "False" && len(causes) == 0 {
+		cause := "Pod is running but not Ready, e.g. a readiness probe is failing"
+		if message := d.eventMessage("Unhealthy"); message != "" {
+			cause += ": " + message
+		}
+		causes = append(causes, cause)
+	}
 
-	executeQuery(query, fmt.Sprintf("%s Relationships", relType))
+	if len(causes) == 0 && d.Status != "Running" {
+		cause := fmt.Sprintf("Pod is %s", d.Status)
+		if event := d.lastWarning(); event != nil {
+			cause += fmt.Sprintf("; last warning: %s: %s", event.Reason, event.Message)
+		}
+		causes = append(causes, cause)
+	}
+	return causes
 }
 
-func handleResources() {
-	query := fmt.Sprintf(`
-		MATCH (n)
-		%s
-		WITH labels(n)[0] as type, n.clusterName as cluster, count(*) as count
-		RETURN type, cluster, count
-		ORDER BY type, cluster, count DESC`, getClusterFilterWithVar("n"))
+// eventMessage returns the message of the most recent event with reason
+func (d podDiagnosis) eventMessage(reason string) string {
+	for _, event := range d.Events {
+		if event.Reason == reason {
+			return event.Message
+		}
+	}
+	return ""
+}
 
-	executeQuery(query, "Resource Counts")
+// lastWarning returns the most recent Warning event
+func (d podDiagnosis) lastWarning() *diagnosisEvent {
+	for i := range d.Events {
+		if d.Events[i].Type == "Warning" {
+			return &d.Events[i]
+		}
+	}
+	return nil
 }
 
-func handlePods(args []string) {
-	namespace := ""
-	if len(args) > 0 {
-		namespace = args[0]
+// diagnosePod reads what the graph knows about the pod given as ref
+func diagnosePod(ref string) podDiagnosis {
+	record := findPod(ref, `p.uid AS uid, p.status AS status, p.nodeName AS nodeName, p.restartCount AS restartCount,
+		       p.conditions AS conditions, p.containers AS containers`)
+	uid := recordString(record, "uid")
+	d := podDiagnosis{
+		Ref:        recordString(record, "namespace") + "/" + recordString(record, "name"),
+		Status:     recordString(record, "status"),
+		NodeName:   recordString(record, "nodeName"),
+		Conditions: make(map[string]string),
+	}
+	d.RestartCount, _ = strconv.ParseInt(recordString(record, "restartCount"), 10, 64)
+	json.Unmarshal([]byte(recordString(record, "conditions")), &d.Conditions)
+	containers, _ := record.Get("containers")
+	for _, container := range decodeStringList(containers) {
+		d.Containers = append(d.Containers, parsePortInfo(container))
+	}
+	params := map[string]interface{}{"uid": uid}
+
+	for _, record := range collectRecords(`
+		MATCH (p:Pod {uid: $uid})-[:SCHEDULED_ON]->(n:Node)
+		WHERE n.clusterName = p.clusterName
+		RETURN n.name AS name, n.conditions AS conditions, n.unschedulable AS unschedulable, n.taints AS taints`, params) {
+		node := &diagnosisNode{Name: recordString(record, "name"), Conditions: make(map[string]string)}
+		json.Unmarshal([]byte(recordString(record, "conditions")), &node.Conditions)
+		node.Unschedulable = recordString(record, "unschedulable") == "true"
+		taints, _ := record.Get("taints")
+		node.Taints = decodeStringList(taints)
+		d.Node = node
 	}
 
-	query := fmt.Sprintf(`
-		MATCH (p:Pod)
-		%s
-		%s
-		RETURN p.name as name, p.namespace as namespace, p.status as status, p.clusterName as cluster
-		ORDER BY p.namespace, p.name`,
-		getClusterFilterWithVar("p"),
-		getNamespaceFilter(namespace, "p"))
+	for _, record := range collectRecords(`
+		MATCH (p:Pod {uid: $uid})-[:USES]->(c:PersistentVolumeClaim)
+		WHERE c.namespace = p.namespace AND c.clusterName = p.clusterName
+		RETURN c.name AS name, c.status AS status, c.storageClass AS storageClass
+		ORDER BY name`, params) {
+		d.Volumes = append(d.Volumes, diagnosisVolume{
+			Name:         recordString(record, "name"),
+			Status:       recordString(record, "status"),
+			StorageClass: strings.Trim(recordString(record, "storageClass"), `"`),
+		})
+	}
+
+	for _, record := range collectRecords(`
+		MATCH path = (p:Pod {uid: $uid})-[:OWNED_BY*1..4]->(owner)
+		RETURN [n IN tail(nodes(path)) | labels(n)[0] + '/' + n.name] AS chain
+		ORDER BY length(path) DESC
+		LIMIT 1`, params) {
+		chain, _ := record.Get("chain")
+		for _, owner := range chain.([]interface{}) {
+			d.OwnerChain = append(d.OwnerChain, fmt.Sprintf("%v", owner))
+		}
+	}
 
-	executeQuery(query, "Pods")
+	for _, record := range collectRecords(`
+		MATCH (e:Event)-[:INVOLVES]->(p:Pod {uid: $uid})
+		RETURN e.type AS type, e.reason AS reason, e.message AS message, e.count AS count, e.lastTimestamp AS lastSeen
+		ORDER BY e.createdAt DESC
+		LIMIT 10`, params) {
+		d.Events = append(d.Events, diagnosisEvent{
+			Type:     recordString(record, "type"),
+			Reason:   recordString(record, "reason"),
+			Message:  recordString(record, "message"),
+			Count:    recordString(record, "count"),
+			LastSeen: recordString(record, "lastSeen"),
+		})
+	}
+	return d
 }
 
-func handleServices(args []string) {
-	namespace := ""
-	if len(args) > 0 {
-		namespace = args[0]
+func handleDiagnosePod(ref string) {
+	d := diagnosePod(ref)
+	causes := d.causes()
+	resultCount = len(causes)
+	if quiet {
+		for _, cause := range causes {
+			fmt.Println(cause)
+		}
+		return
 	}
 
-	query := fmt.Sprintf(`
-		MATCH (s:Service)
-		%s
-		%s
-		RETURN s.name as name, s.namespace as namespace, s.type as type, s.clusterName as cluster
-		ORDER BY s.namespace, s.name`,
-		getClusterFilterWithVar("s"),
-		getNamespaceFilter(namespace, "s"))
+	fmt.Printf("\n=== Diagnosis of pod %s ===\n\n", d.Ref)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Phase:\t%s\n", d.Status)
+	conditions := make([]string, 0, len(d.Conditions))
+	for condition, status := range d.Conditions {
+		conditions = append(conditions, condition+"="+status)
+	}
+	sort.Strings(conditions)
+	fmt.Fprintf(w, "Conditions:\t%s\n", strings.Join(conditions, ", "))
+	fmt.Fprintf(w, "Restarts:\t%d\n", d.RestartCount)
+	if len(d.OwnerChain) > 0 {
+		fmt.Fprintf(w, "Owners:\t%s\n", strings.Join(d.OwnerChain, " -> "))
+	}
+	switch {
+	case d.Node != nil:
+		nodeConditions := make([]string, 0, len(d.Node.Conditions))
+		for condition, status := range d.Node.Conditions {
+			nodeConditions = append(nodeConditions, condition+"="+status)
+		}
+		sort.Strings(nodeConditions)
+		fmt.Fprintf(w, "Node:\t%s (%s)\n", d.Node.Name, strings.Join(nodeConditions, ", "))
+		if len(d.Node.Taints) > 0 {
+			fmt.Fprintf(w, "Node taints:\t%s\n", strings.Join(d.Node.Taints, ", "))
+		}
+	case d.NodeName != "":
+		fmt.Fprintf(w, "Node:\t%s (not in the graph)\n", d.NodeName)
+	default:
+		fmt.Fprintf(w, "Node:\tnot scheduled\n")
+	}
+	for _, volume := range d.Volumes {
+		fmt.Fprintf(w, "PVC %s:\t%s\n", volume.Name, volume.Status)
+	}
+	w.Flush()
+
+	if len(d.Containers) > 0 {
+		fmt.Println("\nContainers:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  name\timage\tstate\treason\tready\trestarts")
+		for _, container := range d.Containers {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", container["name"], container["image"], container["state"],
+				container["reason"], container["ready"], container["restartCount"])
+		}
+		w.Flush()
+	}
+
+	if len(d.Events) > 0 {
+		fmt.Println("\nRecent events:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, event := range d.Events {
+			fmt.Fprintf(w, "  %s\t%s\tx%s\t%s\n", event.Type, event.Reason, event.Count, event.Message)
+		}
+		w.Flush()
+	}
 
-	executeQuery(query, "Services")
+	fmt.Println("\nLikely causes:")
+	if len(causes) == 0 {
+		fmt.Println("  None found, the pod is running and ready")
+	}
+	for _, cause := range causes {
+		fmt.Printf("  - %s\n", cause)
+	}
+	fmt.Println()
 }
 
-func handleDeployments(args []string) {
-	namespace := ""
-	if len(args) > 0 {
-		namespace = args[0]
+// decodeStringList decodes a list property stored as a JSON string
+func decodeStringList(value interface{}) []string {
+	list := make([]string, 0)
+	if data, ok := value.(string); ok {
+		json.Unmarshal([]byte(data), &list)
 	}
+	return list
+}
 
+func handlePortMismatches() {
 	query := fmt.Sprintf(`
-		MATCH (d:Deployment)
+		MATCH (s:Service)-[:SELECTS]->(p:Pod)
 		%s
-		%s
-		RETURN d.name as name, d.namespace as namespace, d.replicas as replicas, d.clusterName as cluster
-		ORDER BY d.namespace, d.name`,
-		getClusterFilterWithVar("d"),
-		getNamespaceFilter(namespace, "d"))
+		RETURN s.clusterName as cluster, s.namespace as namespace, s.name as name, s.ports as ports,
+		       collect(p.containerPorts) as podPorts
+		ORDER BY cluster, namespace, name`,
+		getClusterFilterWithVar("s"))
+
+	records := collectRecords(query, nil)
+
+	keys := []string{"cluster", "namespace", "service", "port", "targetPort", "protocol", "matchingPods", "reason"}
+	values := make([][]string, 0)
+	for _, record := range records {
+		podPortsValue, _ := record.Get("podPorts")
+		podPortsList, _ := podPortsValue.([]interface{})
+		podPorts := make([][]string, 0, len(podPortsList))
+		for _, ports := range podPortsList {
+			podPorts = append(podPorts, decodeStringList(ports))
+		}
+		if len(podPorts) == 0 {
+			continue
+		}
+
+		portsValue, _ := record.Get("ports")
+		for _, mismatch := range findPortMismatches(decodeStringList(portsValue), podPorts) {
+			values = append(values, []string{
+				fmt.Sprintf("%v", record.Values[0]),
+				fmt.Sprintf("%v", record.Values[1]),
+				fmt.Sprintf("%v", record.Values[2]),
+				mismatch.Port,
+				mismatch.TargetPort,
+				mismatch.Protocol,
+				fmt.Sprintf("%d/%d", mismatch.MatchingPods, mismatch.Pods),
+				mismatch.Reason,
+			})
+		}
+	}
+
+	if len(values) == 0 {
+		printNoResults("No Service targetPort mismatches found\n")
+		return
+	}
 
-	executeQuery(query, "Deployments")
+	printTable("Service targetPort Mismatches", keys, values)
 }
 
-func handleEvents(args []string) {
-	limit := 20
-	if len(args) > 0 {
-		if l, err := fmt.Sscanf(args[0], "%d", &limit); err != nil || l == 0 {
-			limit = 20
-		}
+func handleSnapshotDiff(args []string) {
+	t1, err := parseTimeArg(args[0])
+	if err != nil {
+		logger.Error("Invalid time %s: %v", args[0], err)
+		os.Exit(exitError)
+	}
+	t2, err := parseTimeArg(args[1])
+	if err != nil {
+		logger.Error("Invalid time %s: %v", args[1], err)
+		os.Exit(exitError)
+	}
+	if t2 < t1 {
+		t1, t2 = t2, t1
+	}
+
+	// Versions overlapping [t1, t2]; a resource is present at t if a version is valid at t
+	conditions := []string{
+		fmt.Sprintf("v.validFrom <= '%s'", t2),
+		fmt.Sprintf("(v.validTo IS NULL OR v.validTo > '%s')", t1),
+	}
+	if filter := getClusterFilterWithVar("v"); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
 	}
 
 	query := fmt.Sprintf(`
-		MATCH (e:Event)
-		%s
-		RETURN e.name as name, e.namespace as namespace, e.type as type, e.reason as reason, e.message as message, e.clusterName as cluster
-		ORDER BY e.lastTimestamp DESC
-		LIMIT %d`,
-		getClusterFilterWithVar("e"), limit)
+		MATCH (v:ResourceVersion)
+		WHERE %s
+		WITH v.kind as kind, v.namespace as namespace, v.name as name, v.resourceUid as uid, collect(v) as versions
+		WITH kind, namespace, name,
+		     [x IN versions WHERE x.validFrom <= '%s' AND (x.validTo IS NULL OR x.validTo > '%s')] as before,
+		     [x IN versions WHERE x.validFrom <= '%s' AND (x.validTo IS NULL OR x.validTo > '%s')] as after
+		WITH kind, namespace, name, before, after,
+		     CASE
+		       WHEN size(before) = 0 AND size(after) > 0 THEN 'added'
+		       WHEN size(before) > 0 AND size(after) = 0 THEN 'removed'
+		       WHEN before[0].contentHash <> after[0].contentHash THEN 'changed'
+		       ELSE null
+		     END as change
+		WHERE change IS NOT NULL
+		RETURN change, kind, namespace, name
+		ORDER BY change, kind, namespace, name`,
+		strings.Join(conditions, " AND "), t1, t1, t2, t2)
+
+	executeQuery(query, fmt.Sprintf("Changes between %s and %s", t1, t2))
+}
 
-	executeQuery(query, "Recent Events")
+// parseTimeArg converts an RFC3339 timestamp, a duration before now or "now"
+// to the UTC RFC3339 format used by resource versions
+func parseTimeArg(arg string) (string, error) {
+	if arg == "now" {
+		return time.Now().UTC().Format(time.RFC3339), nil
+	}
+	if d, err := time.ParseDuration(arg); err == nil {
+		return time.Now().UTC().Add(-d).Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.RFC3339, arg)
+	if err != nil {
+		return "", fmt.Errorf("expected RFC3339 timestamp, duration or \"now\"")
+	}
+	return t.UTC().Format(time.RFC3339), nil
 }
 
 func handleDbEvents(args []string) {
@@ -854,11 +5720,247 @@ func handleDbResources(databaseID string) {
 		ORDER BY resource_type, name`,
 		databaseID, getClusterFilterWithVar("db"), databaseID, getClusterFilterWithVar("db"))
 
-	executeQuery(query, fmt.Sprintf("Resources for Database ID %s", databaseID))
+	executeQuery(query, fmt.Sprintf("Resources for Database ID %s", databaseID))
+}
+
+func handleCustomQuery(query string) {
+	executeQuery(query, "Custom Query")
+}
+
+// savedQuery is a named query of the query library
+type savedQuery struct {
+	Description string `yaml:"description,omitempty"`
+	Query       string `yaml:"query"`
+}
+
+// queryLibrary is the file the named queries are stored in
+type queryLibrary struct {
+	Queries map[string]savedQuery `yaml:"queries"`
+}
+
+// queryParameterPattern matches the $parameters of a Cypher query
+var queryParameterPattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// queryLibraryPath returns the file of the query library
+func queryLibraryPath() (string, error) {
+	if queriesFile != "" {
+		return queriesFile, nil
+	}
+	if path := os.Getenv("KUBEGRAPH_QUERIES_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kubegraph-cli", "queries.yaml"), nil
+}
+
+// loadQueryLibrary reads the query library at path. A missing file is an
+// empty library.
+func loadQueryLibrary(path string) (*queryLibrary, error) {
+	library := &queryLibrary{Queries: make(map[string]savedQuery)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return library, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, library); err != nil {
+		return nil, fmt.Errorf("invalid query library %s: %w", path, err)
+	}
+	if library.Queries == nil {
+		library.Queries = make(map[string]savedQuery)
+	}
+	return library, nil
+}
+
+// saveQueryLibrary writes the query library to path
+func saveQueryLibrary(path string, library *queryLibrary) error {
+	data, err := yaml.Marshal(library)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// queryParameters returns the sorted $parameters a query uses
+func queryParameters(query string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, match := range queryParameterPattern.FindAllStringSubmatch(query, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseQueryParams parses name=value parameters, passing integers, floats
+// and booleans as such
+func parseQueryParams(params []string) (map[string]interface{}, error) {
+	parsed := make(map[string]interface{}, len(params))
+	for _, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected name=value", param)
+		}
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsed[name] = i
+		} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+			parsed[name] = f
+		} else if b, err := strconv.ParseBool(value); err == nil {
+			parsed[name] = b
+		} else {
+			parsed[name] = value
+		}
+	}
+	return parsed, nil
+}
+
+func handleQuerySave(name, query string) {
+	path, err := queryLibraryPath()
+	if err != nil {
+		logger.Error("Failed to locate the query library: %v", err)
+		os.Exit(exitError)
+	}
+	library, err := loadQueryLibrary(path)
+	if err != nil {
+		logger.Error("Failed to read the query library: %v", err)
+		os.Exit(exitError)
+	}
+
+	_, replaced := library.Queries[name]
+	library.Queries[name] = savedQuery{Description: queryDescription, Query: strings.TrimSpace(query)}
+	if err := saveQueryLibrary(path, library); err != nil {
+		logger.Error("Failed to write the query library: %v", err)
+		os.Exit(exitError)
+	}
+
+	if !quiet {
+		action := "Saved"
+		if replaced {
+			action = "Replaced"
+		}
+		fmt.Printf("%s query %s in %s\n", action, name, path)
+	}
+}
+
+func handleQueryRun(name string) {
+	path, err := queryLibraryPath()
+	if err != nil {
+		logger.Error("Failed to locate the query library: %v", err)
+		os.Exit(exitError)
+	}
+	library, err := loadQueryLibrary(path)
+	if err != nil {
+		logger.Error("Failed to read the query library: %v", err)
+		os.Exit(exitError)
+	}
+	saved, ok := library.Queries[name]
+	if !ok {
+		logger.Error("No query named %s in %s", name, path)
+		os.Exit(exitError)
+	}
+
+	params, err := parseQueryParams(queryParams)
+	if err != nil {
+		logger.Error("%v", err)
+		os.Exit(exitError)
+	}
+	if _, ok := params["clusterName"]; !ok {
+		params["clusterName"] = getSelectedCluster()
+	}
+	if _, ok := params["team"]; !ok {
+		params["team"] = team
+	}
+	var missing []string
+	for _, param := range queryParameters(saved.Query) {
+		if _, ok := params[param]; !ok {
+			missing = append(missing, param)
+		}
+	}
+	if len(missing) > 0 {
+		logger.Error("Query %s needs --param for: %s", name, strings.Join(missing, ", "))
+		os.Exit(exitError)
+	}
+
+	executeQueryWithParams(saved.Query, params, name)
 }
 
-func handleCustomQuery(query string) {
-	executeQuery(query, "Custom Query")
+func handleQueryList() {
+	path, err := queryLibraryPath()
+	if err != nil {
+		logger.Error("Failed to locate the query library: %v", err)
+		os.Exit(exitError)
+	}
+	library, err := loadQueryLibrary(path)
+	if err != nil {
+		logger.Error("Failed to read the query library: %v", err)
+		os.Exit(exitError)
+	}
+	if len(library.Queries) == 0 {
+		printNoResults("No saved queries in %s\n", path)
+		return
+	}
+
+	names := make([]string, 0, len(library.Queries))
+	for name := range library.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([][]string, 0, len(names))
+	for _, name := range names {
+		saved := library.Queries[name]
+		values = append(values, []string{name, strings.Join(queryParameters(saved.Query), ","), saved.Description})
+	}
+	printTable("Saved Queries", []string{"name", "parameters", "description"}, values)
+}
+
+// handleKinds lists the registered handlers
+func handleKinds() {
+	registrations := registry.All()
+	values := make([][]string, 0, len(registrations))
+	for _, registration := range registrations {
+		gvr := registration.GVR
+		resource := gvr.Resource
+		if gvr.Group != "" {
+			resource += "." + gvr.Group
+		}
+		scope := "Cluster"
+		if registration.Namespaced {
+			scope = "Namespaced"
+		}
+		values = append(values, []string{registration.Kind, resource, gvr.Version, scope, strconv.FormatBool(registration.RequiresClientset)})
+	}
+	printTable("Kinds", []string{"kind", "resource", "version", "scope", "requires clientset"}, values)
+}
+
+// completeSavedQueries completes the names of the saved queries
+func completeSavedQueries(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	path, err := queryLibraryPath()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	library, err := loadQueryLibrary(path)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(library.Queries))
+	for name, saved := range library.Queries {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name+"\t"+saved.Description)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
 }
 
 func handleStats() {
@@ -870,6 +5972,62 @@ func handleStats() {
 		ORDER BY type, cluster, count DESC`, getClusterFilterWithVar("n"))
 
 	executeQuery(query, "Database Statistics")
+	nodes := resultCount
+
+	// The same statistics are served by the agent on /api/v1/stats
+	stats, err := client.Statistics(ctx, getSelectedCluster(), team)
+	if err != nil {
+		logger.Error("Failed to compute graph statistics: %v", err)
+		os.Exit(exitError)
+	}
+	if len(stats.Relationships) > 0 {
+		printTable("Relationships", []string{"type", "count"}, countRows(stats.Relationships))
+	}
+	if len(stats.Orphans) > 0 {
+		printTable("Nodes Without Relationships", []string{"type", "count"}, countRows(stats.Orphans))
+	}
+	if len(stats.LastWrites) > 0 {
+		values := make([][]string, 0, len(stats.LastWrites))
+		for _, kind := range sortedMapKeys(stats.LastWrites) {
+			values = append(values, []string{kind, stats.LastWrites[kind]})
+		}
+		printTable("Last Writes", []string{"kind", "timestamp"}, values)
+	}
+	schema := make([][]string, 0, len(stats.Schema.Indexes)+len(stats.Schema.Constraints))
+	for _, group := range []struct {
+		kind    string
+		entries []neo4j.SchemaEntry
+	}{{"index", stats.Schema.Indexes}, {"constraint", stats.Schema.Constraints}} {
+		for _, entry := range group.entries {
+			schema = append(schema, []string{group.kind, entry.Name, entry.Type,
+				strings.Join(entry.Labels, ","), strings.Join(entry.Properties, ",")})
+		}
+	}
+	if len(schema) > 0 {
+		printTable("Schema", []string{"kind", "name", "type", "labels", "properties"}, schema)
+	}
+	printTable("Schema Version", []string{"graph", "release"},
+		[][]string{{strconv.Itoa(stats.Schema.Version), strconv.Itoa(neo4j.SchemaVersion)}})
+	resultCount = nodes
+}
+
+// countRows returns the rows of a count table, largest count first
+func countRows(counts map[string]int64) [][]string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	values := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, []string{key, strconv.FormatInt(counts[key], 10)})
+	}
+	return values
 }
 
 func handleHealth() {
@@ -900,18 +6058,16 @@ func handleClusters() {
 }
 
 func handleK8sNodes() {
-	query := fmt.Sprintf(`
+	match := fmt.Sprintf(`
 		MATCH (n:Node)
-		%s
-		RETURN n.name as name, n.phase as status, n.clusterName as cluster, 
-		       n.architecture as architecture, n.operatingSystem as os, 
-		       n.kernelVersion as kernel, n.kubeletVersion as kubelet,
-		       n.capacityCPU as cpu, n.capacityMemory as memory, n.capacityPods as max_pods,
-		       n.unschedulable as unschedulable, n.creationTimestamp as created
-		ORDER BY n.name`,
-		getClusterFilterWithVar("n"))
-
-	executeQuery(query, "Kubernetes Nodes")
+		%s`, getClusterFilterWithVar("n"))
+
+	executeList(match, []string{"n.name as name", "n.phase as status", "n.clusterName as cluster",
+		"n.architecture as architecture", "n.operatingSystem as os",
+		"n.kernelVersion as kernel", "n.kubeletVersion as kubelet",
+		"n.capacityCPU as cpu", "n.capacityMemory as memory", "n.capacityPods as max_pods",
+		"n.unschedulable as unschedulable", "n.creationTimestamp as created"},
+		"name", 0, "Kubernetes Nodes")
 }
 
 func handleNeo4jDatabases() {
@@ -939,41 +6095,16 @@ func handleNeo4jDatabases() {
 func handleRootPods() {
 	query := fmt.Sprintf(`
 		MATCH (p:Pod)
-		%s
-		WHERE (
-			// Check pod-level security context for runAsUser: 0
-			(p.podSecurityContext IS NOT NULL AND p.podSecurityContext CONTAINS '"runAsUser":0') OR
-			
-			// Check container-level security contexts for runAsUser: 0
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"runAsUser":0')) OR
-			
-			// Check for privileged containers
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"privileged":true')) OR
-			
-			// Check for specific security annotations
-			(p.annotations IS NOT NULL AND (
-				p.annotations CONTAINS '"runAsUser":"0"' OR 
-				p.annotations CONTAINS '"runAsUser":0' OR
-				p.annotations CONTAINS '"security.alpha.kubernetes.io/runAsUser":"0"'
-			)) OR
-			
-			// Check for security-related labels
-			(p.labels IS NOT NULL AND (
-				p.labels CONTAINS 'runAsUser=0' OR
-				p.labels CONTAINS 'security-context=root' OR
-				p.labels CONTAINS 'privileged=true'
-			))
-		)
-		RETURN p.name as pod_name, 
-		       p.namespace as namespace, 
+		WHERE %s
+		RETURN p.name as pod_name,
+		       p.namespace as namespace,
 		       p.status as status,
-		       p.podSecurityContext as pod_security_context,
-		       p.containerSecurityContexts as container_security_contexts,
-		       p.annotations as annotations,
-		       p.labels as labels,
+		       coalesce(p.runAsUser, '') as pod_run_as_user,
+		       p.privileged as privileged,
+		       p.pssLevel as pss_level,
 		       p.clusterName as cluster
 		ORDER BY p.namespace, p.name`,
-		getClusterFilterWithVar("p"))
+		securityConditions("p", "p.runsAsRoot = 'true'"))
 
 	executeQuery(query, "Pods Running as Root")
 }
@@ -981,75 +6112,76 @@ func handleRootPods() {
 func handlePrivilegedPods() {
 	query := fmt.Sprintf(`
 		MATCH (p:Pod)
-		%s
-		WHERE (
-			// Check for privileged containers
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"privileged":true')) OR
-			
-			// Check for specific security annotations
-			(p.annotations IS NOT NULL AND (
-				p.annotations CONTAINS '"privileged":"true"' OR 
-				p.annotations CONTAINS '"privileged":true' OR
-				p.annotations CONTAINS '"security.alpha.kubernetes.io/runAsUser":"0"'
-			)) OR
-			
-			// Check for security-related labels
-			(p.labels IS NOT NULL AND (
-				p.labels CONTAINS 'privileged=true' OR
-				p.labels CONTAINS 'security-context=privileged' OR
-				p.labels CONTAINS 'privileged=true'
-			))
-		)
-		RETURN p.name as pod_name, 
-		       p.namespace as namespace, 
+		WHERE %s
+		RETURN p.name as pod_name,
+		       p.namespace as namespace,
 		       p.status as status,
-		       p.podSecurityContext as pod_security_context,
-		       p.containerSecurityContexts as container_security_contexts,
-		       p.annotations as annotations,
-		       p.labels as labels,
+		       p.runsAsRoot as runs_as_root,
+		       p.addedCapabilities as added_capabilities,
 		       p.clusterName as cluster
 		ORDER BY p.namespace, p.name`,
-		getClusterFilterWithVar("p"))
+		securityConditions("p", "p.privileged = 'true'"))
 
 	executeQuery(query, "Pods Running in Privileged Mode")
 }
 
+// securityConditions combines a condition on the security properties of
+// pods with the cluster filter
+func securityConditions(variable, condition string) string {
+	conditions := []string{condition}
+	if filter := getClusterFilterWithVar(variable); filter != "" {
+		conditions = append(conditions, strings.TrimPrefix(filter, "WHERE "))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// failingLevels returns the levels of the pods violating a Pod Security
+// Standards profile
+func failingLevels(profile string) ([]string, error) {
+	switch profile {
+	case "baseline":
+		return []string{"privileged"}, nil
+	case "restricted":
+		return []string{"privileged", "baseline"}, nil
+	}
+	return nil, fmt.Errorf("invalid profile %q, expected baseline or restricted", profile)
+}
+
 func handleSecurityRisks() {
-	query := fmt.Sprintf(`
+	levels, err := failingLevels(securityProfile)
+	if err != nil {
+		logger.Error("%v", err)
+		os.Exit(exitError)
+	}
+
+	// The level and violations are evaluated by the pod handler at sync time
+	records := collectRecords(fmt.Sprintf(`
 		MATCH (p:Pod)
-		%s
-		WHERE (
-			// Check for running as root (runAsUser: 0)
-			(p.podSecurityContext IS NOT NULL AND p.podSecurityContext CONTAINS '"runAsUser":0') OR
-			
-			// Check for privileged containers
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"privileged":true')) OR
-			
-			// Check for containers without read-only root filesystem
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"security.alpha.kubernetes.io/runAsUser":"0"')) OR
-			
-			// Check for containers with privilege escalation allowed
-			(p.containerSecurityContexts IS NOT NULL AND ANY(ctx IN p.containerSecurityContexts WHERE ctx CONTAINS '"security.alpha.kubernetes.io/runAsUser":"0"')) OR
-			
-			// Check for security-related labels
-			(p.labels IS NOT NULL AND (
-				p.labels CONTAINS 'runAsUser=0' OR
-				p.labels CONTAINS 'security-context=root' OR
-				p.labels CONTAINS 'privileged=true'
-			))
-		)
-		RETURN p.name as pod_name, 
-		       p.namespace as namespace, 
-		       p.status as status,
-		       p.podSecurityContext as pod_security_context,
-		       p.containerSecurityContexts as container_security_contexts,
-		       p.annotations as annotations,
-		       p.labels as labels,
-		       p.clusterName as cluster
-		ORDER BY p.namespace, p.name`,
-		getClusterFilterWithVar("p"))
+		WHERE %s
+		RETURN p.clusterName as cluster, p.namespace as namespace, p.name as pod, p.status as status,
+		       p.pssLevel as level, p.pssBaselineViolations as baseline, p.pssRestrictedViolations as restricted
+		ORDER BY cluster, namespace, pod`,
+		securityConditions("p", "p.pssLevel IN $levels")),
+		map[string]interface{}{"levels": levels})
+
+	keys := []string{"cluster", "namespace", "pod", "status", "level", "violations"}
+	values := make([][]string, 0, len(records))
+	for _, record := range records {
+		baseline, _ := record.Get("baseline")
+		violations := decodeStringList(baseline)
+		if securityProfile == "restricted" {
+			restricted, _ := record.Get("restricted")
+			violations = append(violations, decodeStringList(restricted)...)
+		}
+		values = append(values, []string{recordString(record, "cluster"), recordString(record, "namespace"), recordString(record, "pod"),
+			recordString(record, "status"), recordString(record, "level"), strings.Join(violations, "; ")})
+	}
 
-	executeQuery(query, "Pods with Security Risks")
+	if len(values) == 0 {
+		printNoResults("No pods violate the %s profile\n", securityProfile)
+		return
+	}
+	printTable(fmt.Sprintf("Pods Violating the %s Pod Security Standard", securityProfile), keys, values)
 }
 
 func handleResourcePressure(args []string) {
@@ -1134,15 +6266,23 @@ func handleResourcePressure(args []string) {
 		     END as disk_allocatable_mb
 		WHERE cpu_capacity > 0 AND memory_capacity_mb > 0
 		WITH n, cpu_capacity, memory_capacity_mb, cpu_allocatable, memory_allocatable_mb, disk_capacity_mb, disk_allocatable_mb,
-		     // Calculate usage percentages - fixed disk calculation
+		     // CPU and memory percentages of the allocatable resources requested by the pods
+		     // scheduled on the node (requestedCPU in millicores, requestedMemory in bytes)
+		     // and sampled from metrics-server (usageCPU, usageMemory) when available
 		     CASE 
-		       WHEN cpu_allocatable IS NOT NULL AND cpu_capacity > 0 THEN ((cpu_capacity - cpu_allocatable) / cpu_capacity) * 100
+		       WHEN cpu_allocatable > 0 THEN (coalesce(n.requestedCPU, 0) / 1000.0 / cpu_allocatable) * 100
 		       ELSE 0 
-		     END as cpu_usage_percent,
+		     END as cpu_requested_percent,
 		     CASE 
-		       WHEN memory_allocatable_mb IS NOT NULL AND memory_capacity_mb > 0 THEN ((memory_capacity_mb - memory_allocatable_mb) / memory_capacity_mb) * 100
+		       WHEN n.usageCPU IS NOT NULL AND cpu_allocatable > 0 THEN (n.usageCPU / 1000.0 / cpu_allocatable) * 100
+		     END as cpu_sampled_percent,
+		     CASE 
+		       WHEN memory_allocatable_mb > 0 THEN (coalesce(n.requestedMemory, 0) / 1048576.0 / memory_allocatable_mb) * 100
 		       ELSE 0 
-		     END as memory_usage_percent,
+		     END as memory_requested_percent,
+		     CASE 
+		       WHEN n.usageMemory IS NOT NULL AND memory_allocatable_mb > 0 THEN (n.usageMemory / 1048576.0 / memory_allocatable_mb) * 100
+		     END as memory_sampled_percent,
 		     CASE 
 		       WHEN disk_allocatable_mb > 0 AND disk_capacity_mb > 0 THEN 
 		         CASE 
@@ -1151,6 +6291,16 @@ func handleResourcePressure(args []string) {
 		         END
 		       ELSE 0 
 		     END as disk_usage_percent
+		WITH n, cpu_requested_percent, cpu_sampled_percent, memory_requested_percent, memory_sampled_percent, disk_usage_percent,
+		     // The pressure is the higher of what is requested and what is used
+		     CASE 
+		       WHEN coalesce(cpu_sampled_percent, 0) > cpu_requested_percent THEN cpu_sampled_percent
+		       ELSE cpu_requested_percent
+		     END as cpu_usage_percent,
+		     CASE 
+		       WHEN coalesce(memory_sampled_percent, 0) > memory_requested_percent THEN memory_sampled_percent
+		       ELSE memory_requested_percent
+		     END as memory_usage_percent
 		WHERE cpu_usage_percent >= %f OR memory_usage_percent >= %f OR disk_usage_percent >= %f
 		RETURN n.name as node_name,
 		       n.phase as status,
@@ -1159,13 +6309,18 @@ func handleResourcePressure(args []string) {
 		       n.capacityCPU as cpu_capacity,
 		       n.allocatableCPU as cpu_allocatable,
 		       %s + ROUND(cpu_usage_percent, 1) + '%%' as cpu_usage,
+		       ROUND(cpu_requested_percent, 1) + '%%' as cpu_requested,
+		       coalesce(ROUND(cpu_sampled_percent, 1) + '%%', '-') as cpu_sampled,
 		       n.capacityMemory as memory_capacity,
 		       n.allocatableMemory as memory_allocatable,
 		       %s + ROUND(memory_usage_percent, 1) + '%%' as memory_usage,
+		       ROUND(memory_requested_percent, 1) + '%%' as memory_requested,
+		       coalesce(ROUND(memory_sampled_percent, 1) + '%%', '-') as memory_sampled,
 		       n.capacityEphemeralStorage as disk_capacity,
 		       n.allocatableEphemeralStorage as disk_allocatable,
 		       %s + ROUND(disk_usage_percent, 1) + '%%' as disk_usage,
 		       n.unschedulable as unschedulable,
+		       coalesce(n.sampledAt, 'not sampled') as usage_sampled_at,
 		       n.clusterName as cluster
 		ORDER BY (cpu_usage_percent + memory_usage_percent + disk_usage_percent) DESC, n.name`,
 		getClusterFilterWithVar("n"), cpuThreshold, memoryThreshold, diskThreshold, emojiPrefix, emojiPrefix, emojiPrefix)
@@ -1259,15 +6414,23 @@ func handleResourcePressureSummary(args []string) {
 		     END as disk_allocatable_mb
 		WHERE cpu_capacity > 0 AND memory_capacity_mb > 0
 		WITH n, cpu_capacity, memory_capacity_mb, cpu_allocatable, memory_allocatable_mb, disk_capacity_mb, disk_allocatable_mb,
-		     // Calculate usage percentages - fixed disk calculation
+		     // CPU and memory percentages of the allocatable resources requested by the pods
+		     // scheduled on the node (requestedCPU in millicores, requestedMemory in bytes)
+		     // and sampled from metrics-server (usageCPU, usageMemory) when available
 		     CASE 
-		       WHEN cpu_allocatable IS NOT NULL AND cpu_capacity > 0 THEN ((cpu_capacity - cpu_allocatable) / cpu_capacity) * 100
+		       WHEN cpu_allocatable > 0 THEN (coalesce(n.requestedCPU, 0) / 1000.0 / cpu_allocatable) * 100
 		       ELSE 0 
-		     END as cpu_usage_percent,
+		     END as cpu_requested_percent,
 		     CASE 
-		       WHEN memory_allocatable_mb IS NOT NULL AND memory_capacity_mb > 0 THEN ((memory_capacity_mb - memory_allocatable_mb) / memory_capacity_mb) * 100
+		       WHEN n.usageCPU IS NOT NULL AND cpu_allocatable > 0 THEN (n.usageCPU / 1000.0 / cpu_allocatable) * 100
+		     END as cpu_sampled_percent,
+		     CASE 
+		       WHEN memory_allocatable_mb > 0 THEN (coalesce(n.requestedMemory, 0) / 1048576.0 / memory_allocatable_mb) * 100
 		       ELSE 0 
-		     END as memory_usage_percent,
+		     END as memory_requested_percent,
+		     CASE 
+		       WHEN n.usageMemory IS NOT NULL AND memory_allocatable_mb > 0 THEN (n.usageMemory / 1048576.0 / memory_allocatable_mb) * 100
+		     END as memory_sampled_percent,
 		     CASE 
 		       WHEN disk_allocatable_mb > 0 AND disk_capacity_mb > 0 THEN 
 		         CASE 
@@ -1276,6 +6439,16 @@ func handleResourcePressureSummary(args []string) {
 		         END
 		       ELSE 0 
 		     END as disk_usage_percent
+		WITH n, cpu_requested_percent, cpu_sampled_percent, memory_requested_percent, memory_sampled_percent, disk_usage_percent,
+		     // The pressure is the higher of what is requested and what is used
+		     CASE 
+		       WHEN coalesce(cpu_sampled_percent, 0) > cpu_requested_percent THEN cpu_sampled_percent
+		       ELSE cpu_requested_percent
+		     END as cpu_usage_percent,
+		     CASE 
+		       WHEN coalesce(memory_sampled_percent, 0) > memory_requested_percent THEN memory_sampled_percent
+		       ELSE memory_requested_percent
+		     END as memory_usage_percent
 		WHERE cpu_usage_percent >= %f OR memory_usage_percent >= %f OR disk_usage_percent >= %f
 		WITH n, cpu_usage_percent, memory_usage_percent, disk_usage_percent,
 		     // Calculate overall pressure score
@@ -1290,7 +6463,8 @@ func handleResourcePressureSummary(args []string) {
 		       ROUND(disk_usage_percent, 1) + '%%' as disk_usage,
 		       %s as disk_status,
 		       ROUND(overall_pressure, 1) + '%%' as overall_pressure,
-		       n.unschedulable as unschedulable
+		       n.unschedulable as unschedulable,
+		       coalesce(n.sampledAt, 'not sampled') as usage_sampled_at
 		ORDER BY overall_pressure DESC, n.name`,
 		getClusterFilterWithVar("n"), cpuThreshold, memoryThreshold, diskThreshold, cpuEmojiPrefix, memoryEmojiPrefix, diskEmojiPrefix)
 
@@ -1427,29 +6601,329 @@ func handleResource(args []string) {
 	}
 }
 
+// collectRecords runs a read query and returns all records, exiting on failure
+func collectRecords(query string, params map[string]interface{}) []*driverneo4j.Record {
+	if showQuery {
+		fmt.Printf("\n=== Cypher Query ===\n%s\n", query)
+	}
+
+	session := client.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, params)
+	records, err := driverneo4j.CollectWithContext(ctx, result, err)
+	if err != nil {
+		logger.Error("Failed to execute query: %v", err)
+		os.Exit(exitError)
+	}
+	return records
+}
+
 func executeQuery(query, title string) {
+	executeQueryWithParams(query, nil, title)
+}
+
+// listOptions holds the pagination, sorting and filtering flags shared by
+// the list commands
+type listOptions struct {
+	limit   int
+	offset  int
+	page    int
+	sortBy  string
+	filters []string
+}
+
+var listOpts listOptions
+
+// defaultPageSize is the size of a --page when neither --limit nor the
+// command limits the results
+const defaultPageSize = 50
+
+// addListFlags registers the list flags on cmds
+func addListFlags(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().IntVar(&listOpts.limit, "limit", 0, "Maximum number of results (default: the limit of the command, if any)")
+		cmd.Flags().IntVar(&listOpts.offset, "offset", 0, "Number of results to skip")
+		cmd.Flags().IntVar(&listOpts.page, "page", 0, "Page of --limit results to show, starting at 1")
+		cmd.Flags().StringVar(&listOpts.sortBy, "sort-by", "", "Column to sort by, prefixed with - for descending order")
+		cmd.Flags().StringArrayVar(&listOpts.filters, "filter", nil, "Filter on a column as column=value, column!=value or column~text (repeatable)")
+		cmd.MarkFlagsMutuallyExclusive("offset", "page")
+	}
+}
+
+// positionalLimit returns the limit given as argument i, or def if it is
+// missing or not a positive number
+func positionalLimit(args []string, i, def int) int {
+	if len(args) <= i {
+		return def
+	}
+	if limit, err := strconv.Atoi(args[i]); err == nil && limit > 0 {
+		return limit
+	}
+	return def
+}
+
+// namespaceFilter returns the filter of the optional namespace argument
+func namespaceFilter(args []string) []string {
+	if len(args) == 0 || args[0] == "" {
+		return nil
+	}
+	return []string{"namespace=" + args[0]}
+}
+
+// columnName returns the name a projection such as "p.name as name" is
+// returned as
+func columnName(projection string) string {
+	if i := strings.LastIndex(strings.ToLower(projection), " as "); i >= 0 {
+		return strings.TrimSpace(projection[i+4:])
+	}
+	return strings.TrimSpace(projection)
+}
+
+// query completes match with the projection of columns, filtered, sorted and
+// paged by the options. defaultOrder sorts the results when --sort-by is not
+// given, and defaultLimit bounds them when --limit is not, 0 for no bound.
+// extraFilters are applied like --filter. It returns the parameters of the
+// filters with the query.
+func (o listOptions) query(match string, columns []string, defaultOrder string, defaultLimit int, extraFilters ...string) (string, map[string]interface{}, error) {
+	names := make([]string, len(columns))
+	known := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		names[i] = columnName(column)
+		known[names[i]] = true
+	}
+
+	params := make(map[string]interface{})
+	var conditions []string
+	for i, filter := range append(extraFilters, o.filters...) {
+		column, operator, value, ok := parseFilter(filter)
+		if !ok {
+			return "", nil, fmt.Errorf("invalid filter %q, expected column=value, column!=value or column~text", filter)
+		}
+		if !known[column] {
+			return "", nil, fmt.Errorf("unknown column %q in filter, expected one of: %s", column, strings.Join(names, ", "))
+		}
+		param := fmt.Sprintf("filter%d", i)
+		params[param] = value
+		switch operator {
+		case "=":
+			conditions = append(conditions, fmt.Sprintf("toString(%s) = $%s", column, param))
+		case "!=":
+			conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR toString(%s) <> $%s)", column, column, param))
+		case "~":
+			conditions = append(conditions, fmt.Sprintf("toLower(toString(%s)) CONTAINS toLower($%s)", column, param))
+		}
+	}
+
+	order := defaultOrder
+	if o.sortBy != "" {
+		column, direction := strings.TrimPrefix(o.sortBy, "-"), ""
+		if strings.HasPrefix(o.sortBy, "-") {
+			direction = " DESC"
+		}
+		if !known[column] {
+			return "", nil, fmt.Errorf("unknown column %q in --sort-by, expected one of: %s", column, strings.Join(names, ", "))
+		}
+		order = column + direction
+	}
+
+	limit := defaultLimit
+	if o.limit > 0 {
+		limit = o.limit
+	}
+	offset := o.offset
+	if o.page > 0 {
+		if limit <= 0 {
+			limit = defaultPageSize
+		}
+		offset = (o.page - 1) * limit
+	}
+
+	var query strings.Builder
+	query.WriteString(match)
+	fmt.Fprintf(&query, "\n\t\tWITH %s", strings.Join(columns, ", "))
+	if len(conditions) > 0 {
+		fmt.Fprintf(&query, "\n\t\tWHERE %s", strings.Join(conditions, " AND "))
+	}
+	fmt.Fprintf(&query, "\n\t\tRETURN %s", strings.Join(names, ", "))
+	if order != "" {
+		fmt.Fprintf(&query, "\n\t\tORDER BY %s", order)
+	}
+	if offset > 0 {
+		fmt.Fprintf(&query, "\n\t\tSKIP %d", offset)
+	}
+	if limit > 0 {
+		fmt.Fprintf(&query, "\n\t\tLIMIT %d", limit)
+	}
+	return query.String(), params, nil
+}
+
+// parseFilter splits a filter into its column, operator and value
+func parseFilter(filter string) (column, operator, value string, ok bool) {
+	i := strings.IndexAny(filter, "!~=")
+	if i <= 0 {
+		return "", "", "", false
+	}
+	switch {
+	case strings.HasPrefix(filter[i:], "!="):
+		operator = "!="
+	case filter[i] == '~' || filter[i] == '=':
+		operator = filter[i : i+1]
+	default:
+		return "", "", "", false
+	}
+	return strings.TrimSpace(filter[:i]), operator, filter[i+len(operator):], true
+}
+
+// addSelectorFlag registers --selector on list commands over nodes
+func addSelectorFlag(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter on, e.g. app=api,env!=dev,tier,!canary")
+	}
+}
+
+// listMatch returns the MATCH clause of a list command over the nodes varName
+// of pattern, restricted to the selected cluster and team and to the nodes
+// matching --selector, with the parameters of the selector
+func listMatch(pattern, varName string) (string, map[string]interface{}) {
+	conditions := scopeConditions(varName)
+	selected, params, err := selectorConditions(varName, selector, cfg.Promoted.Labels)
+	if err != nil {
+		logger.Error("%v", err)
+		os.Exit(exitError)
+	}
+	match := "\n\t\tMATCH " + pattern
+	if conditions = append(conditions, selected...); len(conditions) > 0 {
+		match += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	return match, params
+}
+
+// selectorConditions returns the conditions matching the node varName against
+// a comma-separated label selector, and their parameters. Requirements are
+// key=value (or key==value), key!=value, key for a label that is set and
+// !key for one that is not. The keys in promoted are matched on the indexed
+// properties the agent promotes them to, the others by searching the labels
+// JSON of the node.
+func selectorConditions(varName, selector string, promoted []string) ([]string, map[string]interface{}, error) {
+	var conditions []string
+	params := make(map[string]interface{})
+	for i, requirement := range strings.Split(selector, ",") {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+		key, value, operator := requirement, "", ""
+		switch {
+		case strings.Contains(requirement, "!="):
+			key, value, _ = strings.Cut(requirement, "!=")
+			operator = "!="
+		case strings.Contains(requirement, "=="):
+			key, value, _ = strings.Cut(requirement, "==")
+			operator = "="
+		case strings.Contains(requirement, "="):
+			key, value, _ = strings.Cut(requirement, "=")
+			operator = "="
+		case strings.HasPrefix(requirement, "!"):
+			key = strings.TrimPrefix(requirement, "!")
+			operator = "!"
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == "" || strings.ContainsAny(key, "!= ") {
+			return nil, nil, fmt.Errorf("invalid selector requirement %q, expected key=value, key!=value, key or !key", requirement)
+		}
+
+		param := fmt.Sprintf("selector%d", i)
+		if slices.Contains(promoted, key) {
+			property := fmt.Sprintf("%s.%s", varName, neo4j.LabelProperty(key))
+			switch operator {
+			case "=":
+				conditions = append(conditions, fmt.Sprintf("%s = $%s", property, param))
+			case "!=":
+				conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s <> $%s)", property, property, param))
+			case "!":
+				conditions = append(conditions, property+" IS NULL")
+			default:
+				conditions = append(conditions, property+" IS NOT NULL")
+			}
+			if operator == "=" || operator == "!=" {
+				params[param] = value
+			}
+			continue
+		}
+
+		// The labels are stored as the JSON of a map, whose entries are
+		// followed by a comma or the closing brace
+		labels := varName + ".labels"
+		set := fmt.Sprintf("coalesce(%s CONTAINS $%s, false)", labels, param)
+		equal := fmt.Sprintf("coalesce(%s CONTAINS $%s + ',' OR %s ENDS WITH $%s + '}', false)", labels, param, labels, param)
+		switch operator {
+		case "=":
+			conditions = append(conditions, equal)
+		case "!=":
+			conditions = append(conditions, "NOT "+equal)
+		case "!":
+			conditions = append(conditions, "NOT "+set)
+		default:
+			conditions = append(conditions, set)
+		}
+		params[param] = jsonString(key) + ":"
+		if operator == "=" || operator == "!=" {
+			params[param] = jsonString(key) + ":" + jsonString(value)
+		}
+	}
+	return conditions, params, nil
+}
+
+// jsonString returns a string as encoded in the JSON properties of the nodes
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// executeList runs a list query built by listOptions.query and prints its
+// results
+func executeList(match string, columns []string, defaultOrder string, defaultLimit int, title string, extraFilters ...string) {
+	executeListWithParams(match, nil, columns, defaultOrder, defaultLimit, title, extraFilters...)
+}
+
+// executeListWithParams is executeList for a match taking parameters
+func executeListWithParams(match string, matchParams map[string]interface{}, columns []string, defaultOrder string, defaultLimit int, title string, extraFilters ...string) {
+	query, params, err := listOpts.query(match, columns, defaultOrder, defaultLimit, extraFilters...)
+	if err != nil {
+		logger.Error("%v", err)
+		os.Exit(exitError)
+	}
+	for name, value := range matchParams {
+		params[name] = value
+	}
+	executeQueryWithParams(query, params, title)
+}
+
+// executeQueryWithParams runs a query with parameters and prints its results
+func executeQueryWithParams(query string, params map[string]interface{}, title string) {
 	// Show the query if the flag is enabled
 	if showQuery {
 		fmt.Printf("\n=== Cypher Query ===\n%s\n", query)
 	}
 
-	session := client.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeRead})
+	session := client.NewSession(ctx, driverneo4j.AccessModeRead)
 	defer session.Close(ctx)
 
-	result, err := session.Run(ctx, query, nil)
+	result, err := session.Run(ctx, query, params)
 	if err != nil {
 		logger.Error("Failed to execute query: %v", err)
-		return
+		os.Exit(exitError)
 	}
 
 	records, err := driverneo4j.CollectWithContext(ctx, result, err)
 	if err != nil {
 		logger.Error("Failed to collect results: %v", err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
 	if len(records) == 0 {
-		fmt.Printf("No results found for: %s\n", title)
+		printNoResults("No results found for: %s\n", title)
 		return
 	}
 
@@ -1471,9 +6945,16 @@ func executeQuery(query, title string) {
 		values[i] = row
 	}
 
-	// Print results
-	fmt.Printf("\n=== %s ===\n", title)
-	fmt.Printf("Found %d results\n\n", len(records))
+	printTable(title, keys, values)
+}
+
+// printTable prints rows as an aligned table under a title
+func printTable(title string, keys []string, values [][]string) {
+	resultCount = len(values)
+	if !quiet {
+		fmt.Printf("\n=== %s ===\n", title)
+		fmt.Printf("Found %d results\n\n", len(values))
+	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
@@ -1491,42 +6972,238 @@ func executeQuery(query, title string) {
 	}
 
 	w.Flush()
-	fmt.Println()
+	if !quiet {
+		fmt.Println()
+	}
+}
+
+// printNoResults reports that the command found nothing, printing the
+// message unless in quiet mode
+func printNoResults(format string, args ...interface{}) {
+	resultCount = 0
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// offline reports whether cmd runs without Neo4j. Completion connects only
+// when completing from the graph, see withClient.
+func offline(cmd *cobra.Command) bool {
+	switch cmd {
+	case completionCmd, querySaveCmd, queryListCmd, kindsCmd:
+		return true
+	}
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// writeCompletion writes the completion script of root for shell
+func writeCompletion(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell %q", shell)
+}
+
+// completeArgs completes each positional argument with the function at its
+// position. Arguments past the last function are not completed.
+func completeArgs(fns ...cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(fns) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fns[len(args)](cmd, args, toComplete)
+	}
+}
+
+// withClient connects to Neo4j before completing from the graph. Completion
+// skips the PersistentPreRunE of the root command, so that the script and
+// static completions work without Neo4j. Errors only disable the completion
+// since anything printed would be taken as a candidate.
+func withClient(fn cobra.CompletionFunc) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if client == nil {
+			quiet = true
+			if err := initializeClient(); err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+		}
+		return fn(cmd, args, toComplete)
+	}
+}
+
+// completeLabels completes the node labels in the graph
+func completeLabels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completionValues(fmt.Sprintf(`
+		MATCH (n)
+		%s
+		UNWIND labels(n) AS value
+		RETURN DISTINCT value
+		ORDER BY value`, getClusterFilterWithVar("n")), nil, toComplete)
+}
+
+// completeRelationshipTypes completes the relationship types in the graph
+func completeRelationshipTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completionValues(fmt.Sprintf(`
+		MATCH (a)-[r]->(b)
+		%s
+		RETURN DISTINCT type(r) AS value
+		ORDER BY value`, getClusterFilterForRelationships()), nil, toComplete)
+}
+
+// completeNamespaces completes the namespace names
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames("Namespace", toComplete)
+}
+
+// completeNodes completes the Kubernetes node names
+func completeNodes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames("Node", toComplete)
+}
+
+// completeClusters completes the cluster names, whatever --cluster-name is set to
+func completeClusters(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completionValues(`
+		MATCH (n:Namespace)
+		WHERE n.clusterName STARTS WITH $prefix
+		RETURN DISTINCT n.clusterName AS value
+		ORDER BY value`, map[string]interface{}{"prefix": toComplete}, toComplete)
+}
+
+// completePods completes pods as namespace/name, the form can-connect takes
+func completePods(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	clusterFilter := ""
+	if cluster := getSelectedCluster(); cluster != "" {
+		clusterFilter = "AND n.clusterName = $cluster"
+	}
+	if team != "" {
+		clusterFilter += " AND n.team = $team"
+	}
+	return completionValues(fmt.Sprintf(`
+		MATCH (n:Pod)
+		WHERE n.namespace + '/' + n.name STARTS WITH $prefix %s
+		RETURN DISTINCT n.namespace + '/' + n.name AS value
+		ORDER BY value
+		LIMIT %d`, clusterFilter, completionLimit),
+		map[string]interface{}{"prefix": toComplete, "cluster": getSelectedCluster(), "team": team}, toComplete)
+}
+
+// completeNamesOf completes the names of the nodes whose label is the
+// argument at position labelArg
+func completeNamesOf(labelArg int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeNames(args[labelArg], toComplete)
+	}
+}
+
+// completionLimit bounds the number of resource names offered, so that a
+// <TAB> on a large cluster stays fast
+const completionLimit = 200
+
+// completeNames completes the names of the nodes with label
+func completeNames(label, toComplete string) ([]string, cobra.ShellCompDirective) {
+	clusterFilter := ""
+	if cluster := getSelectedCluster(); cluster != "" {
+		clusterFilter = "AND n.clusterName = $cluster"
+	}
+	if team != "" {
+		clusterFilter += " AND n.team = $team"
+	}
+	return completionValues(fmt.Sprintf(`
+		MATCH (n:%s)
+		WHERE n.name STARTS WITH $prefix %s
+		RETURN DISTINCT n.name AS value
+		ORDER BY value
+		LIMIT %d`, quoteLabel(label), clusterFilter, completionLimit),
+		map[string]interface{}{"prefix": toComplete, "cluster": getSelectedCluster(), "team": team}, toComplete)
+}
+
+// completeRolloutWorkloads completes the names of the workloads of the kind selected with --kind
+func completeRolloutWorkloads(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(rolloutsKind, toComplete)
+}
+
+// completeDriftWorkloads completes the names of the workloads of the kind selected with --kind
+func completeDriftWorkloads(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(driftKind, toComplete)
+}
+
+// completeSpreadWorkloads completes the names of the workloads of the kind selected with --kind
+func completeSpreadWorkloads(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeNames(spreadKind, toComplete)
+}
+
+// quoteLabel quotes a label typed by the user so it can be used in a query
+func quoteLabel(label string) string {
+	return "`" + strings.ReplaceAll(label, "`", "``") + "`"
+}
+
+// completionValues runs a query returning a value column and offers the
+// values starting with toComplete
+func completionValues(query string, params map[string]interface{}, toComplete string) ([]string, cobra.ShellCompDirective) {
+	session := client.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, params)
+	records, err := driverneo4j.CollectWithContext(ctx, result, err)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		if value, ok := record.Values[0].(string); ok && strings.HasPrefix(value, toComplete) {
+			values = append(values, value)
+		}
+	}
+	return values, cobra.ShellCompDirectiveNoFileComp
 }
 
 func getClusterFilter() string {
 	return getClusterFilterWithVar("n")
 }
 
+// getClusterFilterWithVar returns the WHERE clause restricting the node
+// varName to the selected cluster and team, empty when neither is selected
 func getClusterFilterWithVar(varName string) string {
-	// Priority: 1. --cluster-name flag, 2. config cluster name
-	cluster := clusterName
-	if cluster == "" {
-		cluster = cfg.Kubernetes.ClusterName
-	}
-
-	if cluster == "" {
+	conditions := scopeConditions(varName)
+	if len(conditions) == 0 {
 		return ""
 	}
-	return fmt.Sprintf("WHERE %s.clusterName = '%s'", varName, cluster)
+	return "WHERE " + strings.Join(conditions, " AND ")
 }
 
-func getClusterFilterForRelationships() string {
-	// Priority: 1. --cluster-name flag, 2. config cluster name
-	cluster := clusterName
-	if cluster == "" {
-		cluster = cfg.Kubernetes.ClusterName
+// scopeConditions returns the conditions restricting the node varName to the
+// selected cluster and team
+func scopeConditions(varName string) []string {
+	var conditions []string
+	if cluster := getSelectedCluster(); cluster != "" {
+		conditions = append(conditions, fmt.Sprintf("%s.clusterName = '%s'", varName, cluster))
+	}
+	if team != "" {
+		conditions = append(conditions, fmt.Sprintf("%s.team = '%s'", varName, team))
 	}
+	return conditions
+}
 
-	if cluster == "" {
-		return ""
+func getSelectedCluster() string {
+	// Priority: 1. --cluster-name flag, 2. config cluster name
+	if clusterName != "" {
+		return clusterName
 	}
-	return fmt.Sprintf("WHERE a.clusterName = '%s' AND b.clusterName = '%s'", cluster, cluster)
+	return cfg.Kubernetes.ClusterName
 }
 
-func getNamespaceFilter(namespace string, varName string) string {
-	if namespace == "" {
+func getClusterFilterForRelationships() string {
+	conditions := append(scopeConditions("a"), scopeConditions("b")...)
+	if len(conditions) == 0 {
 		return ""
 	}
-	return fmt.Sprintf("WHERE %s.namespace = '%s'", varName, namespace)
+	return "WHERE " + strings.Join(conditions, " AND ")
 }
@@ -0,0 +1,1081 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+
+	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/spf13/cobra"
+)
+
+func TestFindPortMismatches(t *testing.T) {
+	podPorts := [][]string{
+		{"container=app;name=http;containerPort=8080;protocol=TCP"},
+		{"container=app;name=http;containerPort=8080;protocol=TCP"},
+	}
+
+	tests := []struct {
+		name        string
+		servicePort string
+		podPorts    [][]string
+		expected    string
+	}{
+		{"numeric target port matches", "name=web;protocol=TCP;port=80;targetPort=8080", podPorts, ""},
+		{"named target port matches", "name=web;protocol=TCP;port=80;targetPort=http", podPorts, ""},
+		{"numeric target port missing", "name=web;protocol=TCP;port=80;targetPort=9090", podPorts, "port not declared by container"},
+		{"named target port missing", "name=web;protocol=TCP;port=80;targetPort=metrics", podPorts, "named port not defined by container"},
+		{"protocol mismatch", "name=dns;protocol=UDP;port=53;targetPort=8080", podPorts, "port not declared by container"},
+		{"pod without ports", "name=web;protocol=TCP;port=80;targetPort=8080", append(podPorts, []string{}), "port not declared by container"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mismatches := findPortMismatches([]string{test.servicePort}, test.podPorts)
+			if test.expected == "" {
+				if len(mismatches) != 0 {
+					t.Errorf("Expected no mismatches, got %+v", mismatches)
+				}
+				return
+			}
+			if len(mismatches) != 1 {
+				t.Fatalf("Expected 1 mismatch, got %d", len(mismatches))
+			}
+			if mismatches[0].Reason != test.expected {
+				t.Errorf("Expected reason %q, got %q", test.expected, mismatches[0].Reason)
+			}
+		})
+	}
+}
+
+func testDiagram() *diagram {
+	d := newDiagram()
+	d.addNode(diagramNode{ID: "1", Kind: "Pod", Name: "web-0", Namespace: "default"})
+	d.addNode(diagramNode{ID: "2", Kind: "Node", Name: "worker-\"a\""})
+	d.addNode(diagramNode{ID: "1", Kind: "Pod", Name: "duplicate"})
+	d.Edges = append(d.Edges, diagramEdge{From: "1", To: "2", Type: "SCHEDULED_ON"})
+	return d
+}
+
+func TestRenderMermaid(t *testing.T) {
+	expected := `graph LR
+  n0["Pod<br/>default/web-0"]
+  n1["Node<br/>worker-#quot;a#quot;"]
+  n0 -->|SCHEDULED_ON| n1
+  classDef root stroke-width:3px
+  class n0 root
+`
+	if got := renderMermaid(testDiagram()); got != expected {
+		t.Errorf("Unexpected Mermaid output:\n%s", got)
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	expected := `digraph kubegraph {
+  rankdir=LR;
+  node [shape=box];
+  n0 [label="Pod\ndefault/web-0", penwidth=3];
+  n1 [label="Node\nworker-\"a\""];
+  n0 -> n1 [label="SCHEDULED_ON"];
+}
+`
+	if got := renderDOT(testDiagram()); got != expected {
+		t.Errorf("Unexpected DOT output:\n%s", got)
+	}
+}
+
+func TestBuildOwnerTrees(t *testing.T) {
+	node := func(name string, properties map[string]interface{}) map[string]interface{} {
+		properties["name"], properties["namespace"], properties["clusterName"] = name, "shop", "production"
+		return properties
+	}
+	rows := []ownerTreeRow{
+		{RootID: "d", ID: "d", Kind: "Deployment", Properties: node("api", map[string]interface{}{"replicas": int64(2)})},
+		{RootID: "d", ID: "rs2", Kind: "ReplicaSet", Owners: []string{"d"},
+			Properties: node("api-b", map[string]interface{}{"replicas": int64(2), "readyReplicas": int64(1), "revision": "2"})},
+		{RootID: "d", ID: "rs1", Kind: "ReplicaSet", Owners: []string{"d"},
+			Properties: node("api-a", map[string]interface{}{"replicas": int64(0), "readyReplicas": int64(0), "revision": "1"})},
+		{RootID: "d", ID: "p1", Kind: "Pod", Owners: []string{"rs2"}, Properties: node("api-b-1", map[string]interface{}{
+			"status": "Running", "restartCount": int64(0),
+			"containers": `["name=api;image=api:2;ready=true;state=Running","name=proxy;image=envoy;ready=false;state=Waiting;reason=CrashLoopBackOff"]`,
+		})},
+		{RootID: "d", ID: "p2", Kind: "Pod", Owners: []string{"rs2"}, Properties: node("api-b-2", map[string]interface{}{
+			"status": "Pending", "restartCount": int64(0), "containers": `["name=api;image=api:2"]`,
+		})},
+	}
+
+	roots := buildOwnerTrees(rows)
+	if len(roots) != 1 {
+		t.Fatalf("Expected one tree, got %d", len(roots))
+	}
+	if size := roots[0].size(); size != 8 {
+		t.Errorf("Expected 8 resources in the tree, got %d", size)
+	}
+
+	var out strings.Builder
+	roots[0].render(&out, "", "")
+	expected := `Deployment/api  2 replicas
+├── ReplicaSet/api-a  0/0 ready, revision 1
+└── ReplicaSet/api-b  1/2 ready, revision 2
+    ├── Pod/api-b-1  Running, 0 restarts
+    │   ├── Container/api  Running, ready
+    │   └── Container/proxy  Waiting (CrashLoopBackOff), not ready
+    └── Pod/api-b-2  Pending, 0 restarts
+        └── Container/api
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected tree:\n%s", out.String())
+	}
+}
+
+func TestOwnerTreeStatus(t *testing.T) {
+	tests := []struct {
+		kind       string
+		properties map[string]interface{}
+		status     string
+	}{
+		{"Job", map[string]interface{}{"active": int64(0), "succeeded": int64(1), "failed": int64(2)}, "0 active, 1 succeeded, 2 failed"},
+		{"CronJob", map[string]interface{}{"schedule": "0 3 * * *", "suspend": true}, "schedule 0 3 * * *, suspended"},
+		{"CronJob", map[string]interface{}{"schedule": "@hourly", "suspend": false}, "schedule @hourly"},
+		{"StatefulSet", map[string]interface{}{"replicas": int64(3)}, "3 replicas"},
+		{"Neo4jCluster", map[string]interface{}{"status": "Ready"}, "Ready"},
+		{"ConfigMap", map[string]interface{}{}, ""},
+	}
+	for _, test := range tests {
+		if status := ownerTreeStatus(test.kind, test.properties); status != test.status {
+			t.Errorf("%s: expected %q, got %q", test.kind, test.status, status)
+		}
+	}
+}
+
+func TestFormatImpactPath(t *testing.T) {
+	tests := []struct {
+		kinds         []string
+		relationships []string
+		expected      string
+	}{
+		{[]string{"Node"}, nil, "Node"},
+		{[]string{"Node", "Pod", "Service", "Ingress"}, []string{"<-SCHEDULED_ON-", "<-SELECTS-", "<-ROUTES_TO-"}, "Node <-SCHEDULED_ON- Pod <-SELECTS- Service <-ROUTES_TO- Ingress"},
+		{[]string{"PersistentVolumeClaim", "StatefulSet", "Pod"}, []string{"-USED_BY->", "-MANAGES->"}, "PersistentVolumeClaim -USED_BY-> StatefulSet -MANAGES-> Pod"},
+		{nil, nil, ""},
+	}
+
+	for _, test := range tests {
+		if got := formatImpactPath(test.kinds, test.relationships); got != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, got)
+		}
+	}
+}
+
+func TestImpactQuery(t *testing.T) {
+	query := impactQuery("PersistentVolumeClaim", []string{"root.name = $name"}, 3)
+	for _, clause := range []string{"MATCH (root:PersistentVolumeClaim)", "WHERE root.name = $name",
+		"(root)-[:OWNED_BY|USES|SELECTS|ROUTES_TO|SCHEDULED_ON|MANAGED_BY|MANAGES|OWNS|USED_BY*1..3]-(affected)",
+		"THEN startNode(relationships(p)[i]) = nodes(p)[i]", "ELSE endNode(relationships(p)[i]) = nodes(p)[i]"} {
+		if !strings.Contains(query, clause) {
+			t.Errorf("Expected %q in %q", clause, query)
+		}
+	}
+	for _, relationship := range impactOutgoing {
+		if slices.Contains(impactIncoming, relationship) {
+			t.Errorf("%s is both incoming and outgoing", relationship)
+		}
+	}
+}
+
+func TestFormatPath(t *testing.T) {
+	nodes := []diagramNode{
+		{Kind: "Pod", Name: "web-0", Namespace: "default"},
+		{Kind: "Node", Name: "worker-1"},
+		{Kind: "Pod", Name: "db-0", Namespace: "default"},
+	}
+	steps := []pathStep{{Type: "SCHEDULED_ON", Forward: true}, {Type: "SCHEDULED_ON", Forward: false}}
+
+	expected := `Pod default/web-0
+  -[SCHEDULED_ON]-> Node worker-1
+  <-[SCHEDULED_ON]- Pod default/db-0
+`
+	if got := formatPath(nodes, steps); got != expected {
+		t.Errorf("Unexpected path output:\n%s", got)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   int
+		threshold int
+		expected  int
+	}{
+		{"command without results", -1, -1, exitResults},
+		{"results found", 3, -1, exitResults},
+		{"no results", 0, -1, exitNoResults},
+		{"no results with threshold", 0, 0, exitNoResults},
+		{"within threshold", 3, 3, exitResults},
+		{"threshold exceeded", 4, 3, exitThresholdExceeded},
+		{"any result exceeds zero threshold", 1, 0, exitThresholdExceeded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := exitCode(test.results, test.threshold); got != test.expected {
+				t.Errorf("Expected exit code %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{0, "0"},
+		{512, "512"},
+		{1024, "1Ki"},
+		{1536 * 1024 * 1024, "1.5Gi"},
+		{100 * 1024 * 1024 * 1024, "100Gi"},
+		{3 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024, "3072Pi"},
+	}
+
+	for _, test := range tests {
+		if got := formatBytes(test.bytes); got != test.expected {
+			t.Errorf("formatBytes(%d): expected %q, got %q", test.bytes, test.expected, got)
+		}
+	}
+}
+
+func TestNewSiteNodeOmitsConfigurationData(t *testing.T) {
+	node := newSiteNode(driverneo4j.Node{
+		ElementId: "4:abc:1",
+		Labels:    []string{"ConfigMap"},
+		Props: map[string]interface{}{
+			"name":       "settings",
+			"namespace":  "payments",
+			"data":       `{"password":"hunter2"}`,
+			"binaryData": "{}",
+			"uid":        "123",
+		},
+	})
+
+	if node.ID != "4:abc:1" || node.Kind != "ConfigMap" || node.Name != "settings" || node.Namespace != "payments" {
+		t.Errorf("Unexpected node %+v", node)
+	}
+	if _, ok := node.Properties["data"]; ok {
+		t.Error("Expected data to be omitted")
+	}
+	if _, ok := node.Properties["binaryData"]; ok {
+		t.Error("Expected binaryData to be omitted")
+	}
+	if node.Properties["uid"] != "123" {
+		t.Errorf("Expected uid to be kept, got %v", node.Properties["uid"])
+	}
+}
+
+func TestMergeSubjects(t *testing.T) {
+	subjects := mergeSubjects([]interface{}{
+		`["User:alice","ServiceAccount:shop/deployer"]`,
+		`["ServiceAccount:shop/deployer","Group:ops"]`,
+		nil,
+	})
+
+	expected := []string{"Group:ops", "ServiceAccount:shop/deployer", "User:alice"}
+	if strings.Join(subjects, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, subjects)
+	}
+}
+
+func TestPortAllowed(t *testing.T) {
+	namedPorts := map[string]string{"TCP/http": "8080"}
+	tests := []struct {
+		specs    []string
+		port     string
+		protocol string
+		expected bool
+	}{
+		{[]string{}, "5432", "TCP", true},
+		{[]string{"TCP/8080"}, "", "", true},
+		{[]string{"TCP/8080"}, "8080", "TCP", true},
+		{[]string{"TCP/8080"}, "8080", "UDP", false},
+		{[]string{"TCP/8000-9000"}, "8443", "TCP", true},
+		{[]string{"TCP/8000-9000"}, "9001", "TCP", false},
+		{[]string{"UDP"}, "53", "UDP", true},
+		{[]string{"TCP/http"}, "8080", "TCP", true},
+		{[]string{"TCP/http"}, "9090", "TCP", false},
+	}
+
+	for _, test := range tests {
+		if result := portAllowed(test.specs, test.port, test.protocol, namedPorts); result != test.expected {
+			t.Errorf("portAllowed(%v, %q, %q): expected %t, got %t", test.specs, test.port, test.protocol, test.expected, result)
+		}
+	}
+}
+
+func TestEvaluateDirection(t *testing.T) {
+	api := networkPolicyInfo{UID: "np-1", Name: "api", Rules: []policyRule{{Ports: []string{"TCP/8080"}, IPBlocks: []ipBlock{{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}}}}}
+	open := networkPolicyInfo{UID: "np-2", Name: "metrics", Rules: []policyRule{{Ports: []string{"TCP/9090"}, AnyPeer: true}}}
+	edges := []allowEdge{{PolicyUID: "np-1", Policy: "api", Ports: []string{"TCP/8080"}}}
+
+	tests := []struct {
+		name      string
+		selecting []networkPolicyInfo
+		edges     []allowEdge
+		peerIP    string
+		port      string
+		allowed   bool
+		reason    string
+	}{
+		{"not isolated", nil, nil, "", "8080", true, "no NetworkPolicy selects shop/api-0 for ingress, allowed by default"},
+		{"allowed by relationship", []networkPolicyInfo{api}, edges, "", "8080", true, "allowed by api (TCP/8080)"},
+		{"wrong port", []networkPolicyInfo{api, open}, edges, "", "5432", false, "shop/api-0 is selected by api, metrics for ingress and no rule allows it"},
+		{"allowed from any peer", []networkPolicyInfo{api, open}, nil, "", "9090", true, "allowed by metrics (any peer, TCP/9090)"},
+		{"allowed by ip block", []networkPolicyInfo{api}, nil, "10.2.3.4", "8080", true, "allowed by api (ipBlock 10.0.0.0/8, TCP/8080)"},
+		{"ip block exception", []networkPolicyInfo{api}, nil, "10.1.3.4", "8080", false, "shop/api-0 is selected by api for ingress and no rule allows it"},
+		{"relationship of another policy", []networkPolicyInfo{open}, edges, "", "8080", false, "shop/api-0 is selected by metrics for ingress and no rule allows it"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verdict := evaluateDirection("Ingress", "shop/api-0", test.selecting, test.edges, test.peerIP, test.port, "TCP", nil)
+			if verdict.Allowed != test.allowed || verdict.Reason != test.reason {
+				t.Errorf("Expected %t %q, got %t %q", test.allowed, test.reason, verdict.Allowed, verdict.Reason)
+			}
+		})
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	var stored []storedPolicyRule
+	data := `[{"ports":[{"protocol":"TCP","port":"8000","endPort":9000},{"protocol":"UDP"}]},{"to":[{"podSelector":{"app":"db"}},{"ipBlock":{"cidr":"0.0.0.0/0","except":["169.254.0.0/16"]}}]}]`
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		t.Fatalf("Failed to decode rules: %v", err)
+	}
+
+	rules := policyRules(stored, "Egress")
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if !rules[0].AnyPeer || strings.Join(rules[0].Ports, ",") != "TCP/8000-9000,UDP" {
+		t.Errorf("Unexpected first rule %+v", rules[0])
+	}
+	if rules[1].AnyPeer || len(rules[1].Ports) != 0 || len(rules[1].IPBlocks) != 1 || rules[1].IPBlocks[0].CIDR != "0.0.0.0/0" {
+		t.Errorf("Unexpected second rule %+v", rules[1])
+	}
+
+	if !enforcesDirection(nil, "Ingress", false) || enforcesDirection(nil, "Egress", false) || !enforcesDirection(nil, "Egress", true) {
+		t.Error("Unexpected default policy types")
+	}
+	if enforcesDirection([]string{"Egress"}, "Ingress", true) {
+		t.Error("Expected an egress-only policy not to apply to ingress")
+	}
+}
+
+func TestParseConnectPort(t *testing.T) {
+	tests := []struct {
+		arg      string
+		port     string
+		protocol string
+		valid    bool
+	}{
+		{"8080", "8080", "TCP", true},
+		{"53/udp", "53", "UDP", true},
+		{"http", "http", "TCP", true},
+		{"70000", "", "", false},
+		{"80/ICMP", "", "", false},
+		{"/TCP", "", "", false},
+	}
+
+	for _, test := range tests {
+		port, protocol, err := parseConnectPort(test.arg)
+		if (err == nil) != test.valid || port != test.port || protocol != test.protocol {
+			t.Errorf("parseConnectPort(%q): expected %q %q valid=%t, got %q %q %v", test.arg, test.port, test.protocol, test.valid, port, protocol, err)
+		}
+	}
+}
+
+func TestOverProvisioned(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpu      [2]int64 // used, requested
+		memory   [2]int64
+		expected bool
+	}{
+		{"well sized", [2]int64{400, 500}, [2]int64{900, 1000}, false},
+		{"idle cpu", [2]int64{50, 500}, [2]int64{900, 1000}, true},
+		{"idle memory", [2]int64{400, 500}, [2]int64{100, 1000}, true},
+		{"no requests", [2]int64{5, 0}, [2]int64{100, 0}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := deploymentEfficiency{
+				CPUUtilization:    utilization(test.cpu[0], test.cpu[1]),
+				MemoryUtilization: utilization(test.memory[0], test.memory[1]),
+			}
+			if got := overProvisioned(d, 30); got != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompleteArgs(t *testing.T) {
+	complete := func(value string) cobra.CompletionFunc {
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{value}, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	fn := completeArgs(complete("label"), complete("name"))
+
+	if values, _ := fn(nil, nil, ""); len(values) != 1 || values[0] != "label" {
+		t.Errorf("Expected the first argument to complete labels, got %v", values)
+	}
+	if values, _ := fn(nil, []string{"Pod"}, ""); len(values) != 1 || values[0] != "name" {
+		t.Errorf("Expected the second argument to complete names, got %v", values)
+	}
+	if values, directive := fn(nil, []string{"Pod", "web"}, ""); len(values) != 0 || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("Expected no completion past the last argument, got %v", values)
+	}
+}
+
+func TestQuoteLabel(t *testing.T) {
+	if quoted := quoteLabel("Pod`) DETACH DELETE (n"); quoted != "`Pod``) DETACH DELETE (n`" {
+		t.Errorf("Expected backticks to be escaped, got %s", quoted)
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		var buf bytes.Buffer
+		if err := writeCompletion(rootCmd, shell, &buf); err != nil {
+			t.Fatalf("Failed to generate %s completion: %v", shell, err)
+		}
+		if !strings.Contains(buf.String(), "kubegraph-cli") {
+			t.Errorf("Expected the %s completion to reference kubegraph-cli", shell)
+		}
+	}
+	if err := writeCompletion(rootCmd, "tcsh", &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	params := queryParameters("MATCH (p:Pod {namespace: $namespace}) WHERE p.restartCount > $min AND p.namespace = $namespace RETURN p LIMIT $limit")
+	if strings.Join(params, ",") != "limit,min,namespace" {
+		t.Errorf("Expected limit,min,namespace, got %v", params)
+	}
+}
+
+func TestParseQueryParams(t *testing.T) {
+	params, err := parseQueryParams([]string{"node=worker-1", "min=5", "ratio=0.5", "ready=true", "selector=app=web"})
+	if err != nil {
+		t.Fatalf("Failed to parse parameters: %v", err)
+	}
+	if params["node"] != "worker-1" || params["min"] != int64(5) || params["ratio"] != 0.5 || params["ready"] != true || params["selector"] != "app=web" {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+	if _, err := parseQueryParams([]string{"node"}); err == nil {
+		t.Error("Expected an error for a parameter without a value")
+	}
+}
+
+func TestQueryLibrary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubegraph-cli", "queries.yaml")
+	library, err := loadQueryLibrary(path)
+	if err != nil || len(library.Queries) != 0 {
+		t.Fatalf("Expected a missing file to be an empty library, got %v, %v", library, err)
+	}
+
+	library.Queries["pods-on-node"] = savedQuery{Description: "Pods on a node", Query: "MATCH (p:Pod)-[:SCHEDULED_ON]->(n:Node {name: $node}) RETURN p.name"}
+	if err := saveQueryLibrary(path, library); err != nil {
+		t.Fatalf("Failed to save the library: %v", err)
+	}
+	loaded, err := loadQueryLibrary(path)
+	if err != nil {
+		t.Fatalf("Failed to load the library: %v", err)
+	}
+	if loaded.Queries["pods-on-node"] != library.Queries["pods-on-node"] {
+		t.Errorf("Expected the saved query, got %+v", loaded.Queries)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter, column, operator, value string
+		ok                              bool
+	}{
+		{"namespace=default", "namespace", "=", "default", true},
+		{"status!=Running", "status", "!=", "Running", true},
+		{"message~back-off", "message", "~", "back-off", true},
+		{"name=a~b", "name", "=", "a~b", true},
+		{"=default", "", "", "", false},
+		{"namespace", "", "", "", false},
+		{"name!x", "", "", "", false},
+	}
+	for _, test := range tests {
+		column, operator, value, ok := parseFilter(test.filter)
+		if column != test.column || operator != test.operator || value != test.value || ok != test.ok {
+			t.Errorf("parseFilter(%q) = %q, %q, %q, %v", test.filter, column, operator, value, ok)
+		}
+	}
+}
+
+func TestSelectorConditions(t *testing.T) {
+	promoted := []string{"app.kubernetes.io/name", "env", "tier", "canary", "legacy"}
+	conditions, params, err := selectorConditions("n", "app.kubernetes.io/name=api, env!=dev,tier==web,canary,!legacy", promoted)
+	if err != nil {
+		t.Fatalf("Failed to parse selector: %v", err)
+	}
+	expected := []string{
+		"n.label_app_kubernetes_io_name = $selector0",
+		"(n.label_env IS NULL OR n.label_env <> $selector1)",
+		"n.label_tier = $selector2",
+		"n.label_canary IS NOT NULL",
+		"n.label_legacy IS NULL",
+	}
+	if !slices.Equal(conditions, expected) {
+		t.Errorf("Unexpected conditions %v", conditions)
+	}
+	if params["selector0"] != "api" || params["selector1"] != "dev" || params["selector2"] != "web" {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+
+	// Keys that are not promoted are searched in the labels JSON
+	conditions, params, err = selectorConditions("p", "app=api,team,env!=dev", nil)
+	if err != nil {
+		t.Fatalf("Failed to parse selector: %v", err)
+	}
+	expected = []string{
+		"coalesce(p.labels CONTAINS $selector0 + ',' OR p.labels ENDS WITH $selector0 + '}', false)",
+		"coalesce(p.labels CONTAINS $selector1, false)",
+		"NOT coalesce(p.labels CONTAINS $selector2 + ',' OR p.labels ENDS WITH $selector2 + '}', false)",
+	}
+	if !slices.Equal(conditions, expected) {
+		t.Errorf("Unexpected conditions %v", conditions)
+	}
+	if params["selector0"] != `"app":"api"` || params["selector1"] != `"team":` || params["selector2"] != `"env":"dev"` {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+
+	if conditions, _, err := selectorConditions("n", "", nil); err != nil || len(conditions) != 0 {
+		t.Errorf("Expected no condition for an empty selector, got %v, %v", conditions, err)
+	}
+	for _, selector := range []string{"=api", "!", "app!"} {
+		if _, _, err := selectorConditions("n", selector, nil); err == nil {
+			t.Errorf("Expected an error for selector %q", selector)
+		}
+	}
+}
+
+func TestListMatch(t *testing.T) {
+	cfg = config.NewConfig()
+	cfg.Kubernetes.ClusterName = "production"
+	selector = "app=api,env=dev"
+	defer func() { selector = "" }()
+
+	match, params := listMatch("(p:Pod)", "p")
+	expected := "\n\t\tMATCH (p:Pod)\n\t\tWHERE p.clusterName = 'production' AND p.label_app = $selector0 AND " +
+		"coalesce(p.labels CONTAINS $selector1 + ',' OR p.labels ENDS WITH $selector1 + '}', false)"
+	if match != expected {
+		t.Errorf("Unexpected match %q", match)
+	}
+	if params["selector0"] != "api" || params["selector1"] != `"env":"dev"` {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+}
+
+func TestListOptionsQuery(t *testing.T) {
+	columns := []string{"p.name as name", "p.namespace as namespace", "p.status as status"}
+
+	query, params, err := listOptions{}.query("MATCH (p:Pod)", columns, "namespace, name", 0)
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	expected := "MATCH (p:Pod)\n\t\tWITH p.name as name, p.namespace as namespace, p.status as status\n\t\tRETURN name, namespace, status\n\t\tORDER BY namespace, name"
+	if query != expected || len(params) != 0 {
+		t.Errorf("Unexpected default query %q, %v", query, params)
+	}
+
+	opts := listOptions{limit: 10, page: 3, sortBy: "-status", filters: []string{"status!=Running"}}
+	query, params, err = opts.query("MATCH (p:Pod)", columns, "namespace, name", 0, "namespace=default")
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	for _, clause := range []string{"WHERE toString(namespace) = $filter0 AND (status IS NULL OR toString(status) <> $filter1)", "ORDER BY status DESC", "SKIP 20", "LIMIT 10"} {
+		if !strings.Contains(query, clause) {
+			t.Errorf("Expected %q in %q", clause, query)
+		}
+	}
+	if params["filter0"] != "default" || params["filter1"] != "Running" {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+
+	if _, _, err := (listOptions{page: 2}).query("MATCH (p:Pod)", columns, "", 0); err != nil {
+		t.Errorf("Expected a page without limit to use the default page size, got %v", err)
+	}
+	if _, _, err := (listOptions{sortBy: "p.name"}).query("MATCH (p:Pod)", columns, "", 0); err == nil {
+		t.Error("Expected an error when sorting by an unknown column")
+	}
+	if _, _, err := (listOptions{filters: []string{"uid=1"}}).query("MATCH (p:Pod)", columns, "", 0); err == nil {
+		t.Error("Expected an error when filtering on an unknown column")
+	}
+}
+
+func TestPositionalLimit(t *testing.T) {
+	if limit := positionalLimit([]string{"Pod", "25"}, 1, 10); limit != 25 {
+		t.Errorf("Expected 25, got %d", limit)
+	}
+	if limit := positionalLimit([]string{"Pod", "many"}, 1, 10); limit != 10 {
+		t.Errorf("Expected the default for an invalid limit, got %d", limit)
+	}
+	if limit := positionalLimit([]string{"Pod"}, 1, 10); limit != 10 {
+		t.Errorf("Expected the default for a missing limit, got %d", limit)
+	}
+}
+
+func TestClusterFilter(t *testing.T) {
+	cfg = config.NewConfig()
+	cfg.Kubernetes.ClusterName = ""
+	defer func() { clusterName, team = "", "" }()
+
+	if filter := getClusterFilterWithVar("p"); filter != "" {
+		t.Errorf("Expected no filter without a cluster or team, got %q", filter)
+	}
+	team = "payments"
+	if filter := getClusterFilterWithVar("p"); filter != "WHERE p.team = 'payments'" {
+		t.Errorf("Unexpected filter %q", filter)
+	}
+	clusterName = "production"
+	if filter := getClusterFilterWithVar("p"); filter != "WHERE p.clusterName = 'production' AND p.team = 'payments'" {
+		t.Errorf("Unexpected filter %q", filter)
+	}
+	expected := "WHERE a.clusterName = 'production' AND a.team = 'payments' AND b.clusterName = 'production' AND b.team = 'payments'"
+	if filter := getClusterFilterForRelationships(); filter != expected {
+		t.Errorf("Unexpected relationship filter %q", filter)
+	}
+}
+
+func TestEventsQuery(t *testing.T) {
+	cfg = config.NewConfig()
+	eventsType, eventsNamespace, eventsInvolves, eventsSince = "Warning", "shop", "Pod/web-0", "30m"
+	defer func() { eventsType, eventsNamespace, eventsInvolves, eventsSince = "", "", "", "" }()
+
+	query, params, err := eventsQuery([]string{"5"})
+	if err != nil {
+		t.Fatalf("Failed to build query: %v", err)
+	}
+	for _, clause := range []string{"e.createdAt >= $since", "MATCH (e)-[:INVOLVES]->(o:`Pod` {name: $involvedName})",
+		"WHERE toString(type) = $filter0 AND toString(namespace) = $filter1", "ORDER BY last_seen DESC", "LIMIT 5"} {
+		if !strings.Contains(query, clause) {
+			t.Errorf("Expected %q in %q", clause, query)
+		}
+	}
+	if params["involvedName"] != "web-0" || params["filter0"] != "Warning" || params["filter1"] != "shop" || params["since"] == nil {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+
+	eventsInvolves = "web-0"
+	if _, _, err := eventsQuery(nil); err == nil {
+		t.Error("Expected an error for --involves without a kind")
+	}
+}
+
+func TestPodDiagnosisCauses(t *testing.T) {
+	tests := []struct {
+		name      string
+		diagnosis podDiagnosis
+		expected  []string
+	}{
+		{
+			"running and ready",
+			podDiagnosis{Status: "Running", NodeName: "worker-1", Conditions: map[string]string{"Ready": "True"}},
+			nil,
+		},
+		{
+			"unschedulable",
+			podDiagnosis{Status: "Pending", Events: []diagnosisEvent{{Type: "Warning", Reason: "FailedScheduling", Message: "0/3 nodes are available: 3 Insufficient cpu."}}},
+			[]string{"Pod is not scheduled on any node: 0/3 nodes are available: 3 Insufficient cpu."},
+		},
+		{
+			"image pull",
+			podDiagnosis{Status: "Pending", NodeName: "worker-1", Containers: []map[string]string{{"name": "app", "image": "shop/web:v2", "state": "Waiting", "reason": "ImagePullBackOff", "message": "Back-off pulling image"}}},
+			[]string{"Container app cannot pull image shop/web:v2 (ImagePullBackOff): Back-off pulling image"},
+		},
+		{
+			"unbound PVC on a node under pressure",
+			podDiagnosis{Status: "Pending", NodeName: "worker-1",
+				Node:    &diagnosisNode{Name: "worker-1", Conditions: map[string]string{"Ready": "True", "DiskPressure": "True"}},
+				Volumes: []diagnosisVolume{{Name: "data", Status: "Pending", StorageClass: "fast"}}},
+			[]string{"Node worker-1 reports DiskPressure", "PVC data is Pending, not Bound (storage class fast)"},
+		},
+		{
+			"crash loop",
+			podDiagnosis{Status: "Running", NodeName: "worker-1", Conditions: map[string]string{"Ready": "False"},
+				Containers: []map[string]string{{"name": "app", "state": "Waiting", "reason": "CrashLoopBackOff", "restartCount": "12"}}},
+			[]string{"Container app keeps crashing (CrashLoopBackOff, 12 restarts)"},
+		},
+		{
+			"readiness probe",
+			podDiagnosis{Status: "Running", NodeName: "worker-1", Conditions: map[string]string{"Ready": "False"},
+				Events: []diagnosisEvent{{Type: "Warning", Reason: "Unhealthy", Message: "Readiness probe failed: HTTP probe failed with statuscode: 503"}}},
+			[]string{"Pod is running but not Ready, e.g. a readiness probe is failing: Readiness probe failed: HTTP probe failed with statuscode: 503"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			causes := test.diagnosis.causes()
+			if strings.Join(causes, "\n") != strings.Join(test.expected, "\n") {
+				t.Errorf("Expected %q, got %q", test.expected, causes)
+			}
+		})
+	}
+}
+
+func TestDrainNote(t *testing.T) {
+	tests := []struct {
+		status, controllerKind string
+		blocked                bool
+		expected               string
+	}{
+		{"Running", "Deployment", false, "evicted"},
+		{"Running", "StatefulSet", true, "eviction blocked by PDB"},
+		{"Running", "DaemonSet", true, "DaemonSet, not evicted"},
+		{"Running", "", false, "no controller, needs --force and is not recreated"},
+		{"Succeeded", "Job", false, "completed, deleted"},
+	}
+	for _, test := range tests {
+		if note := drainNote(test.status, test.controllerKind, test.blocked); note != test.expected {
+			t.Errorf("drainNote(%s, %s, %v) = %q, expected %q", test.status, test.controllerKind, test.blocked, note, test.expected)
+		}
+	}
+}
+
+func TestFailingLevels(t *testing.T) {
+	levels, err := failingLevels("baseline")
+	if err != nil || !reflect.DeepEqual(levels, []string{"privileged"}) {
+		t.Errorf("Unexpected baseline levels %v, %v", levels, err)
+	}
+	levels, err = failingLevels("restricted")
+	if err != nil || !reflect.DeepEqual(levels, []string{"privileged", "baseline"}) {
+		t.Errorf("Unexpected restricted levels %v, %v", levels, err)
+	}
+	if _, err := failingLevels("privileged"); err == nil {
+		t.Error("Expected an error for the privileged profile")
+	}
+}
+
+func TestExposedPodSeverity(t *testing.T) {
+	tests := []struct {
+		privileged, hostAccess, root bool
+		expected                     string
+	}{
+		{true, true, true, "critical"},
+		{false, true, true, "high"},
+		{false, false, true, "medium"},
+		{false, false, false, ""},
+	}
+	for _, test := range tests {
+		if severity, _ := exposedPodSeverity(test.privileged, test.hostAccess, test.root); severity != test.expected {
+			t.Errorf("exposedPodSeverity(%v, %v, %v) = %q, expected %q", test.privileged, test.hostAccess, test.root, severity, test.expected)
+		}
+	}
+}
+
+func TestSAExposureQuery(t *testing.T) {
+	cfg = config.NewConfig()
+	cfg.Kubernetes.ClusterName = ""
+	defer func() { saExposureIncludeSystem, saExposureMountedOnly, clusterName = false, false, "" }()
+
+	query := saExposureQuery()
+	for _, clause := range []string{"(p:Pod)-[:USES_SERVICE_ACCOUNT]->(sa:ServiceAccount)<-[:BINDS]-(b)-[:GRANTS]->(role)",
+		"WHERE role.riskLevel IN ['critical', 'high'] AND NOT p.namespace STARTS WITH 'kube-' AND NOT role.name STARTS WITH 'system:'\n",
+		"WITH p, sa, " + tokenMountedCondition + " AS mounted"} {
+		if !strings.Contains(query, clause) {
+			t.Errorf("Expected %q in %q", clause, query)
+		}
+	}
+
+	saExposureIncludeSystem, saExposureMountedOnly, clusterName = true, true, "production"
+	query = saExposureQuery()
+	expected := "WHERE role.riskLevel IN ['critical', 'high'] AND " + tokenMountedCondition + " AND p.clusterName = 'production'\n"
+	if !strings.Contains(query, expected) {
+		t.Errorf("Expected %q in %q", expected, query)
+	}
+}
+
+func TestSecretAccessSeverity(t *testing.T) {
+	tests := []struct {
+		bindingKind, riskLevel, expected string
+	}{
+		{"ClusterRoleBinding", "critical", "critical"},
+		{"ClusterRoleBinding", "high", "high"},
+		{"RoleBinding", "critical", "high"},
+		{"RoleBinding", "high", "medium"},
+	}
+	for _, test := range tests {
+		if severity := secretAccessSeverity(test.bindingKind, test.riskLevel); severity != test.expected {
+			t.Errorf("secretAccessSeverity(%s, %s) = %q, expected %q", test.bindingKind, test.riskLevel, severity, test.expected)
+		}
+	}
+}
+
+func TestSortAttackPaths(t *testing.T) {
+	paths := []attackPath{
+		{severity: "medium", steps: []string{"a", "b"}},
+		{severity: "critical", steps: []string{"a", "b", "c", "d"}},
+		{severity: "high", steps: []string{"a", "b", "c"}},
+		{severity: "critical", steps: []string{"a", "b", "c"}},
+	}
+	sortAttackPaths(paths)
+	var got []string
+	for _, path := range paths {
+		got = append(got, fmt.Sprintf("%s/%d", path.severity, len(path.steps)))
+	}
+	expected := []string{"critical/3", "critical/4", "high/3", "medium/2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestImageChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous map[string]string
+		current  map[string]string
+		expected []string
+	}{
+		{"unchanged", map[string]string{"app": "api:1"}, map[string]string{"app": "api:1"}, []string{"no image changes"}},
+		{"updated", map[string]string{"app": "api:1", "proxy": "envoy:1"}, map[string]string{"app": "api:2", "proxy": "envoy:1"},
+			[]string{"app: api:1 -> api:2"}},
+		{"added and removed", map[string]string{"app": "api:1", "log": "fluent:1"}, map[string]string{"app": "api:1", "proxy": "envoy:1"},
+			[]string{"+proxy=envoy:1", "-log"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := imageChanges(test.previous, test.current); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebhookFindings(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	running := map[string]int64{"policy/webhook": 2, "policy/idle": 0}
+	tests := []struct {
+		name     string
+		webhook  string
+		severity string
+		findings []string
+	}{
+		{"healthy and ignoring failures", "name=a;failurePolicy=Ignore;service=policy/webhook:443", "", nil},
+		{"fails closed", "name=a;failurePolicy=Fail;service=policy/webhook:443/validate", severityMedium, []string{"fails closed"}},
+		{"fails closed without pods", "name=a;failurePolicy=Fail;service=policy/idle:443", severityCritical,
+			[]string{"fails closed", "no running pods behind policy/idle"}},
+		{"missing service ignored", "name=a;failurePolicy=Ignore;service=policy/gone:443", severityHigh,
+			[]string{"service policy/gone not found"}},
+		{"expired CA", "name=a;failurePolicy=Fail;url=https://x;caExpiry=2024-04-01T00:00:00Z", severityCritical,
+			[]string{"fails closed", "caBundle expired on 2024-04-01"}},
+		{"expiring CA", "name=a;failurePolicy=Ignore;url=https://x;caExpiry=2024-05-11T00:00:00Z", severityMedium,
+			[]string{"caBundle expires on 2024-05-11 (in 10 days)"}},
+		{"expiring CA failing closed", "name=a;failurePolicy=Fail;url=https://x;caExpiry=2024-05-11T00:00:00Z", severityHigh,
+			[]string{"fails closed", "caBundle expires on 2024-05-11 (in 10 days)"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			severity, findings := webhookFindings(parseAuditedWebhook(test.webhook), running, now, 30)
+			if severity != test.severity || !reflect.DeepEqual(findings, test.findings) {
+				t.Errorf("Expected %q %v, got %q %v", test.severity, test.findings, severity, findings)
+			}
+		})
+	}
+}
+
+func TestAPIServiceFindings(t *testing.T) {
+	tests := []struct {
+		name      string
+		available string
+		service   string
+		running   int64
+		insecure  bool
+		severity  string
+		findings  []string
+	}{
+		{"local and available", "True", "", 0, false, "", nil},
+		{"aggregated and available", "True", "kube-system/metrics-server", 1, false, "", nil},
+		{"unavailable", "False", "kube-system/metrics-server", 1, false, severityCritical,
+			[]string{"not available (FailedDiscoveryCheck): timeout"}},
+		{"unavailable without pods", "False", "kube-system/metrics-server", 0, false, severityCritical,
+			[]string{"not available (FailedDiscoveryCheck): timeout", "no running pods behind kube-system/metrics-server"}},
+		{"missing service", "True", "kube-system/metrics-server", -1, false, severityHigh,
+			[]string{"service kube-system/metrics-server not found"}},
+		{"insecure", "True", "kube-system/metrics-server", 1, true, severityMedium, []string{"skips TLS verification"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			severity, findings := apiServiceFindings(test.available, "FailedDiscoveryCheck", "timeout", test.service, test.running, test.insecure)
+			if severity != test.severity || !reflect.DeepEqual(findings, test.findings) {
+				t.Errorf("Expected %q %v, got %q %v", test.severity, test.findings, severity, findings)
+			}
+		})
+	}
+}
+
+func TestNodeFindings(t *testing.T) {
+	tests := []struct {
+		name          string
+		conditions    map[string]string
+		unschedulable bool
+		severity      string
+		findings      []string
+	}{
+		{"ready", map[string]string{"Ready": "True", "DiskPressure": "False"}, false, "", nil},
+		{"not ready", map[string]string{"Ready": "Unknown"}, false, severityCritical, []string{"Ready=Unknown"}},
+		{"no conditions", map[string]string{}, false, severityCritical, []string{"Ready=Unknown"}},
+		{"pressure", map[string]string{"Ready": "True", "MemoryPressure": "True"}, false, severityHigh, []string{"MemoryPressure"}},
+		{"cordoned", map[string]string{"Ready": "True"}, true, severityMedium, []string{"cordoned"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			severity, findings := nodeFindings(test.conditions, test.unschedulable)
+			if severity != test.severity || !reflect.DeepEqual(findings, test.findings) {
+				t.Errorf("Expected %q %v, got %q %v", test.severity, test.findings, severity, findings)
+			}
+		})
+	}
+}
+
+func TestTemplateDrift(t *testing.T) {
+	staging := flattenTemplate(map[string]string{
+		"replicas":           "2",
+		"containerImages":    `{"api":"api:1.3","sidecar":"proxy:2"}`,
+		"containerEnv":       `{"api":"DB_URL,FEATURE_X"}`,
+		"containerResources": `{"api":"requests.cpu=100m"}`,
+	})
+	production := flattenTemplate(map[string]string{
+		"replicas":           "6",
+		"containerImages":    `{"api":"api:1.2","sidecar":"proxy:2"}`,
+		"containerEnv":       `{"api":"DB_URL"}`,
+		"containerResources": `{"api":"requests.cpu=100m"}`,
+		"containerProbes":    `{"api":"readiness=tcpSocket:http"}`,
+	})
+
+	expected := [][]string{
+		{"env/api", "DB_URL,FEATURE_X", "DB_URL"},
+		{"image/api", "api:1.3", "api:1.2"},
+		{"probes/api", "-", "readiness=tcpSocket:http"},
+		{"replicas", "2", "6"},
+	}
+	if rows := templateDrift([]map[string]string{staging, production}); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %v, got %v", expected, rows)
+	}
+	if rows := templateDrift([]map[string]string{staging, staging}); len(rows) != 0 {
+		t.Errorf("Expected no drift between identical templates, got %v", rows)
+	}
+}
+
+func TestNodeZone(t *testing.T) {
+	if zone := nodeZone(map[string]string{"topology.kubernetes.io/zone": "eu-west-1a", "failure-domain.beta.kubernetes.io/zone": "old"}); zone != "eu-west-1a" {
+		t.Errorf("Expected eu-west-1a, got %s", zone)
+	}
+	if zone := nodeZone(map[string]string{"failure-domain.beta.kubernetes.io/zone": "eu-west-1b"}); zone != "eu-west-1b" {
+		t.Errorf("Expected the deprecated label to be used, got %s", zone)
+	}
+	if zone := nodeZone(nil); zone != "" {
+		t.Errorf("Expected no zone, got %s", zone)
+	}
+}
+
+func TestSpreadFindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		zones    map[string]int
+		nodes    map[string]int
+		findings []string
+	}{
+		{"single replica", map[string]int{"a": 1, "b": 0}, map[string]int{"n1": 1}, nil},
+		{"balanced", map[string]int{"a": 2, "b": 1, "c": 1}, map[string]int{"n1": 2, "n2": 1, "n3": 1}, nil},
+		{"single zone", map[string]int{"a": 3, "b": 0}, map[string]int{"n1": 2, "n2": 1},
+			[]string{"all 3 replicas in zone a of 2"}},
+		{"skewed", map[string]int{"a": 3, "b": 1, "c": 0}, map[string]int{"n1": 3, "n2": 1},
+			[]string{"zone skew 3 (0 to 3 replicas per zone)"}},
+		{"single node", map[string]int{"a": 2, "b": 0}, map[string]int{"n1": 2},
+			[]string{"all 2 replicas in zone a of 2", "all 2 replicas on node n1"}},
+		{"no zones", map[string]int{}, map[string]int{"n1": 3}, []string{"all 3 replicas on node n1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if findings := spreadFindings(test.zones, test.nodes); !reflect.DeepEqual(findings, test.findings) {
+				t.Errorf("Expected %v, got %v", test.findings, findings)
+			}
+		})
+	}
+}
+
+func TestBlockingTaints(t *testing.T) {
+	taints := blockingTaints([]string{
+		`["dedicated=gpu:NoSchedule","spot=true:PreferNoSchedule"]`,
+		`["dedicated=gpu:NoSchedule","node.kubernetes.io/unreachable=:NoExecute"]`,
+		`[]`,
+	})
+	expected := []string{"dedicated=gpu:NoSchedule", "node.kubernetes.io/unreachable=:NoExecute"}
+	if !reflect.DeepEqual(taints, expected) {
+		t.Errorf("Expected %v, got %v", expected, taints)
+	}
+}
+
+func TestPreemptionVictims(t *testing.T) {
+	pods := []preemptionPod{
+		{ref: "batch/a", priority: 0, cpu: 500, memory: 512},
+		{ref: "batch/b", priority: 100, cpu: 1000, memory: 1024},
+		{ref: "web/c", priority: 1000, cpu: 500, memory: 512},
+		{ref: "system/d", priority: 2000, cpu: 2000, memory: 2048},
+	}
+	refs := func(victims []preemptionPod) []string {
+		result := make([]string, 0, len(victims))
+		for _, victim := range victims {
+			result = append(result, victim.ref)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		cpu      int64
+		freeCPU  int64
+		ok       bool
+		expected []string
+	}{
+		{"fits", 500, 500, true, []string{}},
+		{"lowest priority first", 400, 0, true, []string{"batch/a"}},
+		{"reprieve higher priority", 1000, 0, true, []string{"batch/b"}},
+		{"several victims", 1400, 0, true, []string{"batch/b", "batch/a"}},
+		{"not enough lower priority pods", 2500, 0, false, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			victims, ok := preemptionVictims(1000, test.cpu, 0, test.freeCPU, 0, pods)
+			if ok != test.ok || !reflect.DeepEqual(refs(victims), test.expected) {
+				t.Errorf("Expected %v %v, got %v %v", test.ok, test.expected, ok, refs(victims))
+			}
+		})
+	}
+
+	low := []preemptionPod{{priority: 0}, {priority: 0}}
+	high := []preemptionPod{{priority: 100}}
+	if !lessDisruptive(low, high) || lessDisruptive(high, low) {
+		t.Error("Expected evicting lower priority pods to be less disruptive")
+	}
+	if !lessDisruptive([]preemptionPod{{priority: 0}}, low) {
+		t.Error("Expected fewer victims to be less disruptive")
+	}
+}
@@ -1,49 +1,250 @@
 package config
 
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Config struct {
 	Neo4j struct {
+		Backend                        string // Graph database served at URI: neo4j or memgraph
 		URI                            string
 		Username                       string
 		Password                       string
+		URIFile                        string // File the URI is read from, e.g. a mounted Secret
+		UsernameFile                   string // File the username is read from, reloaded when it changes
+		PasswordFile                   string // File the password is read from, reloaded when it changes
+		CredentialReloadSeconds        int    // How often the credential files are checked for changes
+		Database                       string // Database written to and queried (empty uses the server's default database)
 		MaxConnectionPoolSize          int
-		ConnectionAcquisitionTimeout   int // in seconds
-		ConnectionLivenessCheckTimeout int // in seconds
-		MaxConnectionLifetime          int // in hours
-		MaxTransactionRetryTime        int // in seconds
+		ReadURI                        string // URI of the read pool, such as read replicas (empty uses URI)
+		ReadMaxConnectionPoolSize      int    // Size of the read pool (0 uses MaxConnectionPoolSize)
+		ConnectionAcquisitionTimeout   int    // in seconds
+		ConnectionLivenessCheckTimeout int    // in seconds
+		MaxConnectionLifetime          int    // in hours
+		MaxTransactionRetryTime        int    // in seconds
+		BreakerThreshold               int    // Consecutive connectivity failures that open the circuit breaker (0 disables)
+		BreakerProbeIntervalSeconds    int    // How often Neo4j is probed while the circuit breaker is open
+		CACertPath                     string // CA certificate trusted in addition to the system CAs
+		ClientCertPath                 string // Client certificate for mutual TLS
+		ClientKeyPath                  string // Key of the client certificate
+		TLSSkipVerify                  bool   // Accept any server certificate (neo4j+ssc)
+		Batch                          Neo4jBatch
 	}
-	Kubernetes struct {
+	// Where the graph is written: neo4j, or file:<path> to append its
+	// mutations to a file instead of connecting to a database
+	GraphOutput string
+	Kubernetes  struct {
 		ConfigPath  string
 		ClusterName string // Name to identify the cluster in Neo4j
 	}
 	HTTP struct {
 		Enabled bool
 		Port    int
+		UI      bool     // Serve the graph explorer on /ui/
+		Debug   bool     // Serve the pprof and expvar endpoints under /debug/
+		Auth    HTTPAuth // Authentication of the /api endpoints (disabled when no method is configured)
+		Queries HTTPQueries
+		// Certificate and key files served over HTTPS, reloaded when they change (empty serves plain HTTP)
+		TLSCertFile  string
+		TLSKeyFile   string
+		RedirectPort int // Port redirecting plain HTTP requests to HTTPS (0 disables)
+	}
+	Handlers struct {
+		Disabled []string // Kinds whose handlers are not registered
+	}
+	Filters struct {
+		Namespaces        []string // Namespaces whose resources are synchronized (empty synchronizes all)
+		ExcludeNamespaces []string // Namespaces whose resources are ignored
+	}
+	Ingest struct {
+		Sources []IngestSource // External agents allowed to push resources (empty disables ingest)
+	}
+	Teams struct {
+		// Label whose value on a resource, or else on its namespace, is the team
+		// stamped onto the resource's node (empty disables team labels)
+		Label      string
+		Namespaces []TeamRule // Teams of the namespaces without a team label, first match wins
+	}
+	Promoted struct {
+		Labels      []string // Label keys copied into indexed node properties of their own
+		Annotations []string // Annotation keys copied into indexed node properties of their own
+	}
+	Sync struct {
+		CoalesceWindowMs int      // Window for coalescing rapid updates to the same object, in milliseconds (0 disables)
+		ChangeCacheSize  int      // Number of objects tracked for change detection (0 disables)
+		WriteWorkers     int      // Workers serializing writes to hot nodes (0 disables)
+		SerializedLabels []string // Labels whose node writes are serialized by the write workers
+		OrderedStartup   bool     // Start informers in dependency order, e.g. Namespaces and Nodes before Pods
+		InitialAddRate   int      // Add events per second and kind during the initial sync (0 disables throttling)
+		// Relationships whose nodes do not both exist yet, kept until the missing node is written (0 disables)
+		PendingRelationships          int
+		PendingRelationshipTTLSeconds int // How long a relationship waits for its missing node
+	}
+	DeadLetter struct {
+		Capacity             int    // Failed writes kept for retry (0 disables)
+		Path                 string // File the failed writes are saved to (empty keeps them in memory)
+		RetryIntervalSeconds int    // How often failed writes are retried while Neo4j is reachable
+		MaxAttempts          int    // Retries before a failed write is only kept for inspection
+	}
+	Anomaly struct {
+		Enabled         bool
+		IntervalSeconds int     // How often workload baselines are sampled
+		Threshold       float64 // Standard deviations above the baseline that count as an anomaly
+		MinSamples      int     // Samples required before a baseline is trusted
+		RetentionDays   int     // How long Anomaly nodes are kept
+	}
+	EventCorrelation struct {
+		Enabled         bool
+		IntervalSeconds int // How often recent events are correlated
+		WindowMinutes   int // How far back events are attributed to a workload
+		RetentionDays   int // How long resolved Incident nodes are kept
+	}
+	Usage struct {
+		Enabled         bool // Poll metrics-server for Node and Pod usage
+		IntervalSeconds int  // How often usage is polled
+	}
+	History struct {
+		Enabled       bool // Record every change as a ResourceVersion node
+		RetentionDays int  // How long superseded versions are kept (0 keeps them forever)
+	}
+	Audit struct {
+		Enabled bool // Record every upsert and delete as a GraphChange node
+		TTLDays int  // How long GraphChange nodes are kept
+	}
+	DryRun struct {
+		Enabled bool   // Log write statements instead of executing them
+		Output  string // File the skipped statements are appended to as JSON lines (empty only logs them)
+	}
+	Replay struct {
+		RecordPath string  // File the watch events are appended to as JSON lines (empty disables recording)
+		Path       string  // Recording replayed through the handlers instead of watching the cluster
+		Speed      float64 // Replay speed relative to the recording (0 replays as fast as possible)
+	}
+	Enrichment struct {
+		Enrichers []string // Names of the enrichers run on every node write, in order
+		Plugins   []string // Go plugins loaded to register additional enrichers
+	}
+	Reload struct {
+		IntervalSeconds int // How often the config file is checked for changes (0 reloads on SIGHUP only)
+	}
+	Retention struct {
+		Rules []RetentionRule // Per-kind retention applied by the background cleanup
+	}
+	Cleanup struct {
+		Interval           time.Duration // Interval of the background cleanup, which also runs at startup
+		EventPruneInterval time.Duration // Interval between prunes of expired events
 	}
 	InstanceHash string // Unique hash for this program instance
 	EventTTLDays int    // TTL for events in days (0 disables event handling)
 }
 
+// Neo4jBatch configures the batching of node writes, which groups the writes
+// of concurrent handlers into shared transactions. The batch size adapts
+// between MinSize and MaxSize: it grows while writes queue up and shrinks
+// under low load.
+type Neo4jBatch struct {
+	MaxSize         int // Largest number of writes per transaction (0 disables batching)
+	MinSize         int // Smallest batch size the adaptive sizing shrinks to
+	FlushIntervalMs int // Time a batch waits to fill before it is flushed, in milliseconds
+}
+
+// Roles of the clients of the HTTP server
+const (
+	HTTPRoleRead  = "read"  // Reads the graph, statistics and change feed
+	HTTPRoleAdmin = "admin" // Also pauses handlers and inspects dead letters
+)
+
+// HTTPAuth configures how clients of the HTTP server authenticate
+type HTTPAuth struct {
+	Tokens      []HTTPCredential // Bearer tokens
+	Users       []HTTPCredential // Basic authentication users
+	TokenReview bool             // Authenticate other bearer tokens with the Kubernetes TokenReview API
+	AdminGroups []string         // Users and groups authenticated by TokenReview that get the admin role
+	// ReaderGroups are the users and groups authenticated by TokenReview that
+	// get the read role. Other reviewed users are denied.
+	ReaderGroups []string
+}
+
+// Enabled reports whether any authentication method is configured
+func (a HTTPAuth) Enabled() bool {
+	return len(a.Tokens) > 0 || len(a.Users) > 0 || a.TokenReview
+}
+
+// HTTPQueries limits the endpoints of the HTTP server that query the graph,
+// so that they cannot starve the synchronization of Neo4j connections
+type HTTPQueries struct {
+	TimeoutSeconds int // Time a query may run before it is canceled
+	RateLimit      int // Requests per minute per client (0 disables)
+	RateBurst      int // Requests a client may make at once before being limited
+	MaxResults     int // Largest ?limit= accepted by the graph endpoints
+	MaxConcurrent  int // Queries run at once; further requests wait for their turn
+}
+
+// HTTPCredential is a bearer token or basic authentication user of the HTTP server
+type HTTPCredential struct {
+	Name   string // Identifier used in logs, and the username for basic authentication
+	Secret string // Token or password
+	Role   string // HTTPRoleRead or HTTPRoleAdmin
+}
+
+// IngestSource describes an external agent allowed to push resources through the ingest endpoint
+type IngestSource struct {
+	Name        string // Identifier used in logs and responses
+	Token       string // Bearer token the agent authenticates with
+	ClusterName string // Cluster name attributed to everything the agent pushes
+}
+
 func NewConfig() *Config {
 	return &Config{
 		Neo4j: struct {
+			Backend                        string
 			URI                            string
 			Username                       string
 			Password                       string
+			URIFile                        string
+			UsernameFile                   string
+			PasswordFile                   string
+			CredentialReloadSeconds        int
+			Database                       string
 			MaxConnectionPoolSize          int
+			ReadURI                        string
+			ReadMaxConnectionPoolSize      int
 			ConnectionAcquisitionTimeout   int
 			ConnectionLivenessCheckTimeout int
 			MaxConnectionLifetime          int
 			MaxTransactionRetryTime        int
+			BreakerThreshold               int
+			BreakerProbeIntervalSeconds    int
+			CACertPath                     string
+			ClientCertPath                 string
+			ClientKeyPath                  string
+			TLSSkipVerify                  bool
+			Batch                          Neo4jBatch
 		}{
+			Backend:                        "neo4j",
 			URI:                            "neo4j://localhost:7687",
 			Username:                       "neo4j",
 			Password:                       "password",
+			CredentialReloadSeconds:        10,
+			Database:                       "",
 			MaxConnectionPoolSize:          50,
 			ConnectionAcquisitionTimeout:   30,
 			ConnectionLivenessCheckTimeout: 30,
 			MaxConnectionLifetime:          1,
 			MaxTransactionRetryTime:        15,
+			BreakerThreshold:               5,
+			BreakerProbeIntervalSeconds:    5,
+			Batch: Neo4jBatch{
+				MaxSize:         0, // Every write runs in its own transaction by default
+				MinSize:         16,
+				FlushIntervalMs: 20,
+			},
 		},
+		GraphOutput: "neo4j",
 		Kubernetes: struct {
 			ConfigPath  string
 			ClusterName string
@@ -52,13 +253,332 @@ func NewConfig() *Config {
 			ClusterName: "default", // Default cluster name if not specified
 		},
 		HTTP: struct {
-			Enabled bool
-			Port    int
+			Enabled      bool
+			Port         int
+			UI           bool
+			Debug        bool
+			Auth         HTTPAuth
+			Queries      HTTPQueries
+			TLSCertFile  string
+			TLSKeyFile   string
+			RedirectPort int
 		}{
 			Enabled: true,
 			Port:    8080,
+			Queries: HTTPQueries{
+				TimeoutSeconds: 60,
+				RateLimit:      600,
+				RateBurst:      20,
+				MaxResults:     1000,
+				MaxConcurrent:  4,
+			},
+		},
+		Handlers: struct {
+			Disabled []string
+		}{
+			Disabled: nil, // All handlers are registered
+		},
+		Filters: struct {
+			Namespaces        []string
+			ExcludeNamespaces []string
+		}{
+			Namespaces:        nil, // Resources of all namespaces are synchronized
+			ExcludeNamespaces: nil,
+		},
+		Ingest: struct {
+			Sources []IngestSource
+		}{
+			Sources: nil, // Ingest endpoint is disabled unless sources are configured
+		},
+		Teams: struct {
+			Label      string
+			Namespaces []TeamRule
+		}{
+			Label:      "", // Nodes are not stamped with a team unless configured
+			Namespaces: nil,
+		},
+		Promoted: struct {
+			Labels      []string
+			Annotations []string
+		}{
+			Labels:      []string{"app.kubernetes.io/name", "app.kubernetes.io/instance", "app"},
+			Annotations: nil,
+		},
+		Sync: struct {
+			CoalesceWindowMs              int
+			ChangeCacheSize               int
+			WriteWorkers                  int
+			SerializedLabels              []string
+			OrderedStartup                bool
+			InitialAddRate                int
+			PendingRelationships          int
+			PendingRelationshipTTLSeconds int
+		}{
+			CoalesceWindowMs:              500,
+			ChangeCacheSize:               100000,
+			WriteWorkers:                  8,
+			SerializedLabels:              []string{"Node", "Namespace"},
+			OrderedStartup:                true,
+			InitialAddRate:                0, // Initial sync is not throttled by default
+			PendingRelationships:          10000,
+			PendingRelationshipTTLSeconds: 600,
+		},
+		DeadLetter: struct {
+			Capacity             int
+			Path                 string
+			RetryIntervalSeconds int
+			MaxAttempts          int
+		}{
+			Capacity:             1000,
+			Path:                 "",
+			RetryIntervalSeconds: 30,
+			MaxAttempts:          10,
+		},
+		Anomaly: struct {
+			Enabled         bool
+			IntervalSeconds int
+			Threshold       float64
+			MinSamples      int
+			RetentionDays   int
+		}{
+			Enabled:         false,
+			IntervalSeconds: 300,
+			Threshold:       3.0,
+			MinSamples:      6,
+			RetentionDays:   7,
+		},
+		EventCorrelation: struct {
+			Enabled         bool
+			IntervalSeconds int
+			WindowMinutes   int
+			RetentionDays   int
+		}{
+			Enabled:         false,
+			IntervalSeconds: 120,
+			WindowMinutes:   60,
+			RetentionDays:   7,
+		},
+		Usage: struct {
+			Enabled         bool
+			IntervalSeconds int
+		}{
+			Enabled:         false,
+			IntervalSeconds: 60,
+		},
+		History: struct {
+			Enabled       bool
+			RetentionDays int
+		}{
+			Enabled:       false,
+			RetentionDays: 30,
+		},
+		Audit: struct {
+			Enabled bool
+			TTLDays int
+		}{
+			Enabled: false,
+			TTLDays: 3,
+		},
+		DryRun: struct {
+			Enabled bool
+			Output  string
+		}{
+			Enabled: false,
+		},
+		Replay: struct {
+			RecordPath string
+			Path       string
+			Speed      float64
+		}{
+			Speed: 1,
+		},
+		Enrichment: struct {
+			Enrichers []string
+			Plugins   []string
+		}{
+			Enrichers: nil, // No enrichment unless enrichers are selected
+			Plugins:   nil,
+		},
+		Reload: struct {
+			IntervalSeconds int
+		}{
+			IntervalSeconds: 10,
+		},
+		Retention: struct {
+			Rules []RetentionRule
+		}{
+			Rules: nil, // Only the global TTLs apply unless rules are configured
+		},
+		Cleanup: struct {
+			Interval           time.Duration
+			EventPruneInterval time.Duration
+		}{
+			Interval:           5 * time.Minute,
+			EventPruneInterval: 5 * time.Minute,
 		},
 		InstanceHash: "",
 		EventTTLDays: 7,
 	}
 }
+
+// RetentionRule configures how long the nodes of one kind are retained
+type RetentionRule struct {
+	Kind            string // Node label, such as Pod or ReplicaSet
+	HistoryDays     *int   // Days to keep ended versions of the kind in history mode, overriding History.RetentionDays (0 keeps them forever)
+	KeepGenerations int    // Nodes kept per owner, newest first; older ones scaled to zero are deleted (0 keeps all)
+}
+
+// ParseRetentionRules parses a comma-separated list of kind:setting=value
+// entries, such as ReplicaSet:keepGenerations=3,Pod:historyDays=7. Entries of
+// the same kind are merged into one rule.
+func ParseRetentionRules(spec string) ([]RetentionRule, error) {
+	rules := make([]RetentionRule, 0)
+	index := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kind, setting, ok := strings.Cut(entry, ":")
+		name, value, hasValue := strings.Cut(setting, "=")
+		kind, name = strings.TrimSpace(kind), strings.TrimSpace(name)
+		if !ok || !hasValue || kind == "" {
+			return nil, fmt.Errorf("invalid retention rule %q: expected kind:setting=value", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid retention rule %q: %s must be a number of 0 or more", entry, name)
+		}
+
+		i, seen := index[kind]
+		if !seen {
+			i = len(rules)
+			index[kind] = i
+			rules = append(rules, RetentionRule{Kind: kind})
+		}
+		switch name {
+		case "historyDays":
+			rules[i].HistoryDays = &n
+		case "keepGenerations":
+			if n == 0 {
+				return nil, fmt.Errorf("invalid retention rule %q: keepGenerations must be 1 or more", entry)
+			}
+			rules[i].KeepGenerations = n
+		default:
+			return nil, fmt.Errorf("invalid retention rule %q: unknown setting %s, expected historyDays or keepGenerations", entry, name)
+		}
+	}
+	return rules, nil
+}
+
+// FormatRetentionRules formats rules as accepted by ParseRetentionRules
+func FormatRetentionRules(rules []RetentionRule) string {
+	entries := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule.HistoryDays != nil {
+			entries = append(entries, fmt.Sprintf("%s:historyDays=%d", rule.Kind, *rule.HistoryDays))
+		}
+		if rule.KeepGenerations > 0 {
+			entries = append(entries, fmt.Sprintf("%s:keepGenerations=%d", rule.Kind, rule.KeepGenerations))
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// TeamRule assigns the namespaces matching a pattern to a team
+type TeamRule struct {
+	Namespace string // Namespace name, or a path.Match pattern such as payments-*
+	Team      string
+}
+
+// Matches reports whether the rule applies to a namespace
+func (r TeamRule) Matches(namespace string) bool {
+	matched, _ := path.Match(r.Namespace, namespace)
+	return matched
+}
+
+// ParseTeamRules parses a comma-separated list of namespace=team entries, such
+// as payments-*=billing,kube-*=platform
+func ParseTeamRules(spec string) ([]TeamRule, error) {
+	rules := make([]TeamRule, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		namespace, team, ok := strings.Cut(entry, "=")
+		namespace, team = strings.TrimSpace(namespace), strings.TrimSpace(team)
+		if !ok || namespace == "" || team == "" {
+			return nil, fmt.Errorf("invalid team rule %q: expected namespace=team", entry)
+		}
+		if _, err := path.Match(namespace, ""); err != nil {
+			return nil, fmt.Errorf("invalid team rule %q: %w", entry, err)
+		}
+		rules = append(rules, TeamRule{Namespace: namespace, Team: team})
+	}
+	return rules, nil
+}
+
+// FormatTeamRules formats rules as accepted by ParseTeamRules
+func FormatTeamRules(rules []TeamRule) string {
+	entries := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		entries = append(entries, rule.Namespace+"="+rule.Team)
+	}
+	return strings.Join(entries, ",")
+}
+
+// ParseHTTPCredentials parses a comma-separated list of name:secret:role
+// entries. The secret may contain colons.
+func ParseHTTPCredentials(spec string) ([]HTTPCredential, error) {
+	credentials := make([]HTTPCredential, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rest, found := strings.Cut(entry, ":")
+		i := strings.LastIndex(rest, ":")
+		if !found || i < 0 {
+			return nil, fmt.Errorf("invalid credential %q: expected name:secret:role", name)
+		}
+		credential := HTTPCredential{
+			Name:   strings.TrimSpace(name),
+			Secret: rest[:i],
+			Role:   strings.TrimSpace(rest[i+1:]),
+		}
+		if credential.Name == "" || credential.Secret == "" {
+			return nil, fmt.Errorf("invalid credential %q: name and secret are required", credential.Name)
+		}
+		if credential.Role != HTTPRoleRead && credential.Role != HTTPRoleAdmin {
+			return nil, fmt.Errorf("invalid credential %q: role must be %s or %s, got %q", credential.Name, HTTPRoleRead, HTTPRoleAdmin, credential.Role)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+// ParseIngestSources parses a comma-separated list of name:token:clusterName entries
+func ParseIngestSources(spec string) ([]IngestSource, error) {
+	sources := make([]IngestSource, 0)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid ingest source %q: expected name:token:clusterName", entry)
+		}
+		source := IngestSource{
+			Name:        strings.TrimSpace(parts[0]),
+			Token:       strings.TrimSpace(parts[1]),
+			ClusterName: strings.TrimSpace(parts[2]),
+		}
+		if source.Name == "" || source.Token == "" || source.ClusterName == "" {
+			return nil, fmt.Errorf("invalid ingest source %q: name, token and clusterName are required", entry)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
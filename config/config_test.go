@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -56,6 +57,52 @@ func TestNewConfig(t *testing.T) {
 	if cfg.EventTTLDays != 7 {
 		t.Errorf("Expected EventTTLDays to be 7, got %d", cfg.EventTTLDays)
 	}
+
+	// Test sync configuration
+	if cfg.Sync.CoalesceWindowMs != 500 {
+		t.Errorf("Expected CoalesceWindowMs to be 500, got %d", cfg.Sync.CoalesceWindowMs)
+	}
+	if cfg.Sync.ChangeCacheSize != 100000 {
+		t.Errorf("Expected ChangeCacheSize to be 100000, got %d", cfg.Sync.ChangeCacheSize)
+	}
+	if cfg.Sync.WriteWorkers != 8 {
+		t.Errorf("Expected WriteWorkers to be 8, got %d", cfg.Sync.WriteWorkers)
+	}
+	if len(cfg.Sync.SerializedLabels) != 2 || cfg.Sync.SerializedLabels[0] != "Node" || cfg.Sync.SerializedLabels[1] != "Namespace" {
+		t.Errorf("Expected SerializedLabels to be [Node Namespace], got %v", cfg.Sync.SerializedLabels)
+	}
+
+	// Test anomaly detection configuration
+	if cfg.Anomaly.Enabled {
+		t.Error("Expected anomaly detection to be disabled by default")
+	}
+	if cfg.Anomaly.Threshold != 3.0 {
+		t.Errorf("Expected anomaly Threshold to be 3.0, got %f", cfg.Anomaly.Threshold)
+	}
+
+	// Test event correlation configuration
+	if cfg.EventCorrelation.Enabled {
+		t.Error("Expected event correlation to be disabled by default")
+	}
+	if cfg.EventCorrelation.WindowMinutes != 60 {
+		t.Errorf("Expected event correlation WindowMinutes to be 60, got %d", cfg.EventCorrelation.WindowMinutes)
+	}
+
+	// Test history configuration
+	if cfg.History.Enabled {
+		t.Error("Expected history mode to be disabled by default")
+	}
+	if cfg.History.RetentionDays != 30 {
+		t.Errorf("Expected history RetentionDays to be 30, got %d", cfg.History.RetentionDays)
+	}
+
+	// Test audit trail configuration
+	if cfg.Audit.Enabled {
+		t.Error("Expected audit trail to be disabled by default")
+	}
+	if cfg.Audit.TTLDays != 3 {
+		t.Errorf("Expected audit TTLDays to be 3, got %d", cfg.Audit.TTLDays)
+	}
 }
 
 func TestConfigStructFields(t *testing.T) {
@@ -97,3 +144,108 @@ func TestConfigStructFields(t *testing.T) {
 		t.Errorf("Failed to set EventTTLDays")
 	}
 }
+
+func TestParseIngestSources(t *testing.T) {
+	sources, err := ParseIngestSources("edge-a:secret-a:edge-cluster-a, edge-b:secret-b:edge-cluster-b")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, got %d", len(sources))
+	}
+	if sources[1].Name != "edge-b" || sources[1].Token != "secret-b" || sources[1].ClusterName != "edge-cluster-b" {
+		t.Errorf("Unexpected second source: %+v", sources[1])
+	}
+
+	sources, err = ParseIngestSources("")
+	if err != nil || len(sources) != 0 {
+		t.Errorf("Expected empty spec to yield no sources, got %v (err %v)", sources, err)
+	}
+
+	invalid := []string{"edge-a", "edge-a:secret", "edge-a::cluster", ":secret:cluster"}
+	for _, spec := range invalid {
+		if _, err := ParseIngestSources(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestParseHTTPCredentials(t *testing.T) {
+	credentials, err := ParseHTTPCredentials("dashboard:s3cr:et:read, ops:admin-token:admin")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []HTTPCredential{
+		{Name: "dashboard", Secret: "s3cr:et", Role: HTTPRoleRead},
+		{Name: "ops", Secret: "admin-token", Role: HTTPRoleAdmin},
+	}
+	if !reflect.DeepEqual(credentials, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, credentials)
+	}
+
+	credentials, err = ParseHTTPCredentials("")
+	if err != nil || len(credentials) != 0 {
+		t.Errorf("Expected empty spec to yield no credentials, got %v (err %v)", credentials, err)
+	}
+
+	invalid := []string{"dashboard", "dashboard:secret", "dashboard::read", ":secret:read", "dashboard:secret:write"}
+	for _, spec := range invalid {
+		if _, err := ParseHTTPCredentials(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestParseTeamRules(t *testing.T) {
+	rules, err := ParseTeamRules("payments-*=billing, checkout=billing,kube-*=platform")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []TeamRule{{"payments-*", "billing"}, {"checkout", "billing"}, {"kube-*", "platform"}}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected rules %v, got %v", expected, rules)
+	}
+	if !rules[0].Matches("payments-eu") || rules[0].Matches("payments") || !rules[1].Matches("checkout") {
+		t.Error("Unexpected namespace matches")
+	}
+	if spec := FormatTeamRules(rules); spec != "payments-*=billing,checkout=billing,kube-*=platform" {
+		t.Errorf("Unexpected formatted rules %q", spec)
+	}
+
+	for _, spec := range []string{"payments", "=billing", "payments=", "payments-[=billing"} {
+		if _, err := ParseTeamRules(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
+
+func TestParseRetentionRules(t *testing.T) {
+	rules, err := ParseRetentionRules("ReplicaSet:keepGenerations=3, Pod:historyDays=7, Pod:keepGenerations=1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Kind != "ReplicaSet" || rules[0].KeepGenerations != 3 || rules[0].HistoryDays != nil {
+		t.Errorf("Unexpected first rule: %+v", rules[0])
+	}
+	if rules[1].Kind != "Pod" || rules[1].HistoryDays == nil || *rules[1].HistoryDays != 7 || rules[1].KeepGenerations != 1 {
+		t.Errorf("Unexpected second rule: %+v", rules[1])
+	}
+	if spec := FormatRetentionRules(rules); spec != "ReplicaSet:keepGenerations=3,Pod:historyDays=7,Pod:keepGenerations=1" {
+		t.Errorf("Unexpected formatted rules %q", spec)
+	}
+
+	rules, err = ParseRetentionRules("")
+	if err != nil || len(rules) != 0 {
+		t.Errorf("Expected empty spec to yield no rules, got %v (err %v)", rules, err)
+	}
+
+	invalid := []string{"Pod", "Pod:historyDays", ":historyDays=7", "Pod:historyDays=-1", "Pod:historyDays=x", "Pod:maxAge=7", "ReplicaSet:keepGenerations=0"}
+	for _, spec := range invalid {
+		if _, err := ParseRetentionRules(spec); err == nil {
+			t.Errorf("Expected error for spec %q", spec)
+		}
+	}
+}
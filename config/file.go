@@ -0,0 +1,462 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the schema of the configuration file of the sync binary. Every
+// setting maps onto a command-line flag; settings left out keep the flag's
+// default (see docs/configuration.md).
+type File struct {
+	LogLevel   *string         `yaml:"logLevel"`
+	Neo4j      FileNeo4j       `yaml:"neo4j"`
+	Kubernetes FileKubernetes  `yaml:"kubernetes"`
+	Handlers   FileHandlers    `yaml:"handlers"`
+	Filters    FileFilters     `yaml:"filters"`
+	Teams      FileTeams       `yaml:"teams"`
+	Promoted   FilePromoted    `yaml:"promoted"`
+	TTL        FileTTL         `yaml:"ttl"`
+	Retention  []FileRetention `yaml:"retention"`
+	Cleanup    FileCleanup     `yaml:"cleanup"`
+	HTTP       FileHTTP        `yaml:"http"`
+}
+
+// FileNeo4j configures the connection to the graph database
+type FileNeo4j struct {
+	URI              *string        `yaml:"uri"`
+	Username         *string        `yaml:"username"`
+	Password         *string        `yaml:"password"`
+	URIFile          *string        `yaml:"uriFile"`
+	UsernameFile     *string        `yaml:"usernameFile"`
+	PasswordFile     *string        `yaml:"passwordFile"`
+	Database         *string        `yaml:"database"`
+	Backend          *string        `yaml:"backend"`
+	BreakerThreshold *int           `yaml:"breakerThreshold"`
+	PoolSize         *int           `yaml:"maxConnectionPoolSize"`
+	ReadURI          *string        `yaml:"readURI"`
+	ReadPoolSize     *int           `yaml:"readMaxConnectionPoolSize"`
+	TLS              FileNeo4jTLS   `yaml:"tls"`
+	Batch            FileNeo4jBatch `yaml:"batch"`
+}
+
+// FileNeo4jTLS configures custom CAs and client certificates
+type FileNeo4jTLS struct {
+	CACert     *string `yaml:"caCert"`
+	ClientCert *string `yaml:"clientCert"`
+	ClientKey  *string `yaml:"clientKey"`
+	SkipVerify *bool   `yaml:"skipVerify"`
+}
+
+// FileNeo4jBatch configures the batching of node writes
+type FileNeo4jBatch struct {
+	MaxSize         *int `yaml:"maxSize"`
+	MinSize         *int `yaml:"minSize"`
+	FlushIntervalMs *int `yaml:"flushIntervalMs"`
+}
+
+// FileKubernetes configures the watched cluster
+type FileKubernetes struct {
+	ConfigPath  *string `yaml:"configPath"`
+	ClusterName *string `yaml:"clusterName"`
+}
+
+// FileHandlers selects the resource handlers
+type FileHandlers struct {
+	Disabled []string `yaml:"disabled"`
+}
+
+// FileFilters selects the namespaces whose resources are synchronized
+type FileFilters struct {
+	Namespaces        []string `yaml:"namespaces"`
+	ExcludeNamespaces []string `yaml:"excludeNamespaces"`
+}
+
+// FileTeams configures the team stamped onto the nodes
+type FileTeams struct {
+	Label      *string        `yaml:"label"`
+	Namespaces []FileTeamRule `yaml:"namespaces"`
+}
+
+// FileTeamRule assigns the namespaces matching a pattern to a team
+type FileTeamRule struct {
+	Namespace string `yaml:"namespace"`
+	Team      string `yaml:"team"`
+}
+
+// FilePromoted selects the label and annotation keys promoted to node
+// properties
+type FilePromoted struct {
+	Labels      []string `yaml:"labels"`
+	Annotations []string `yaml:"annotations"`
+}
+
+// FileTTL configures how long records are retained
+type FileTTL struct {
+	EventDays   *int `yaml:"eventDays"`
+	HistoryDays *int `yaml:"historyDays"`
+	AuditDays   *int `yaml:"auditDays"`
+}
+
+// FileRetention configures how long the nodes of one kind are retained
+type FileRetention struct {
+	Kind            string `yaml:"kind"`
+	HistoryDays     *int   `yaml:"historyDays"`
+	KeepGenerations *int   `yaml:"keepGenerations"`
+}
+
+// FileCleanup schedules the background cleanup
+type FileCleanup struct {
+	Interval           *string `yaml:"interval"`
+	EventPruneInterval *string `yaml:"eventPruneInterval"`
+}
+
+// FileHTTP configures the status server
+type FileHTTP struct {
+	Enabled *bool           `yaml:"enabled"`
+	Port    *int            `yaml:"port"`
+	UI      *bool           `yaml:"ui"`
+	Debug   *bool           `yaml:"debug"`
+	Auth    FileHTTPAuth    `yaml:"auth"`
+	Queries FileHTTPQueries `yaml:"queries"`
+	TLS     FileHTTPTLS     `yaml:"tls"`
+}
+
+// FileHTTPTLS configures HTTPS
+type FileHTTPTLS struct {
+	Cert         *string `yaml:"cert"`
+	Key          *string `yaml:"key"`
+	RedirectPort *int    `yaml:"redirectPort"`
+}
+
+// FileHTTPQueries limits the endpoints that query the graph
+type FileHTTPQueries struct {
+	TimeoutSeconds *int `yaml:"timeoutSeconds"`
+	RateLimit      *int `yaml:"rateLimit"`
+	RateBurst      *int `yaml:"rateBurst"`
+	MaxResults     *int `yaml:"maxResults"`
+	MaxConcurrent  *int `yaml:"maxConcurrent"`
+}
+
+// FileHTTPAuth configures Kubernetes authentication of the HTTP server.
+// Tokens and users are secrets and only accepted from flags or the environment.
+type FileHTTPAuth struct {
+	TokenReview  *bool    `yaml:"tokenReview"`
+	AdminGroups  []string `yaml:"adminGroups"`
+	ReaderGroups []string `yaml:"readerGroups"`
+}
+
+// logLevels are the levels accepted by logLevel
+var logLevels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// neo4jSchemes are the URI schemes the driver accepts
+var neo4jSchemes = []string{"neo4j", "neo4j+s", "neo4j+ssc", "bolt", "bolt+s", "bolt+ssc"}
+
+// LoadFile reads and validates a configuration file. Unknown keys are
+// rejected so that typos do not go unnoticed.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file := &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Validate checks the values of the file, reporting every invalid setting by
+// its path in the file
+func (f *File) Validate() error {
+	var errs []error
+	invalid := func(path, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if f.LogLevel != nil && !contains(logLevels, strings.ToUpper(*f.LogLevel)) {
+		invalid("logLevel", "%q is not one of %s", *f.LogLevel, strings.Join(logLevels, ", "))
+	}
+
+	if f.Neo4j.URI != nil {
+		scheme, _, ok := strings.Cut(*f.Neo4j.URI, "://")
+		if !ok || !contains(neo4jSchemes, scheme) {
+			invalid("neo4j.uri", "%q must start with one of %s://", *f.Neo4j.URI, strings.Join(neo4jSchemes, "://, "))
+		}
+	}
+	if f.Neo4j.Username != nil && *f.Neo4j.Username == "" {
+		invalid("neo4j.username", "must not be empty")
+	}
+	if f.Neo4j.BreakerThreshold != nil && *f.Neo4j.BreakerThreshold < 0 {
+		invalid("neo4j.breakerThreshold", "must be 0 (disabled) or more, got %d", *f.Neo4j.BreakerThreshold)
+	}
+	if f.Neo4j.PoolSize != nil && *f.Neo4j.PoolSize < 1 {
+		invalid("neo4j.maxConnectionPoolSize", "must be 1 or more, got %d", *f.Neo4j.PoolSize)
+	}
+	if f.Neo4j.ReadPoolSize != nil && *f.Neo4j.ReadPoolSize < 0 {
+		invalid("neo4j.readMaxConnectionPoolSize", "must be 0 (shared pool) or more, got %d", *f.Neo4j.ReadPoolSize)
+	}
+	if f.Neo4j.Batch.MaxSize != nil && *f.Neo4j.Batch.MaxSize < 0 {
+		invalid("neo4j.batch.maxSize", "must be 0 (disabled) or more, got %d", *f.Neo4j.Batch.MaxSize)
+	}
+	if f.Neo4j.Batch.MinSize != nil && *f.Neo4j.Batch.MinSize < 1 {
+		invalid("neo4j.batch.minSize", "must be 1 or more, got %d", *f.Neo4j.Batch.MinSize)
+	}
+	if f.Neo4j.Batch.MinSize != nil && f.Neo4j.Batch.MaxSize != nil && *f.Neo4j.Batch.MaxSize > 0 && *f.Neo4j.Batch.MinSize > *f.Neo4j.Batch.MaxSize {
+		invalid("neo4j.batch.minSize", "must not exceed maxSize %d, got %d", *f.Neo4j.Batch.MaxSize, *f.Neo4j.Batch.MinSize)
+	}
+	if f.Neo4j.Batch.FlushIntervalMs != nil && *f.Neo4j.Batch.FlushIntervalMs < 1 {
+		invalid("neo4j.batch.flushIntervalMs", "must be 1 or more, got %d", *f.Neo4j.Batch.FlushIntervalMs)
+	}
+	if (f.Neo4j.TLS.ClientCert == nil) != (f.Neo4j.TLS.ClientKey == nil) {
+		invalid("neo4j.tls", "clientCert and clientKey must be set together")
+	}
+
+	if f.Kubernetes.ClusterName != nil && *f.Kubernetes.ClusterName == "" {
+		invalid("kubernetes.clusterName", "must not be empty")
+	}
+
+	for i, kind := range f.Handlers.Disabled {
+		if strings.TrimSpace(kind) == "" {
+			invalid(fmt.Sprintf("handlers.disabled[%d]", i), "must not be empty")
+		}
+	}
+	for i, namespace := range f.Filters.Namespaces {
+		if strings.TrimSpace(namespace) == "" {
+			invalid(fmt.Sprintf("filters.namespaces[%d]", i), "must not be empty")
+		}
+		if contains(f.Filters.ExcludeNamespaces, namespace) {
+			invalid(fmt.Sprintf("filters.namespaces[%d]", i), "%q is also excluded", namespace)
+		}
+	}
+	for i, namespace := range f.Filters.ExcludeNamespaces {
+		if strings.TrimSpace(namespace) == "" {
+			invalid(fmt.Sprintf("filters.excludeNamespaces[%d]", i), "must not be empty")
+		}
+	}
+
+	if f.Teams.Label != nil && strings.TrimSpace(*f.Teams.Label) == "" {
+		invalid("teams.label", "must not be empty")
+	}
+	for i, rule := range f.Teams.Namespaces {
+		p := fmt.Sprintf("teams.namespaces[%d]", i)
+		if strings.TrimSpace(rule.Namespace) == "" || strings.ContainsAny(rule.Namespace, ",=") {
+			invalid(p+".namespace", "%q is not a namespace pattern", rule.Namespace)
+		} else if _, err := path.Match(rule.Namespace, ""); err != nil {
+			invalid(p+".namespace", "%q is not a namespace pattern: %v", rule.Namespace, err)
+		}
+		if strings.TrimSpace(rule.Team) == "" || strings.ContainsAny(rule.Team, ",=") {
+			invalid(p+".team", "%q is not a team name", rule.Team)
+		}
+	}
+	for i, key := range f.Promoted.Labels {
+		if strings.TrimSpace(key) == "" || strings.Contains(key, ",") {
+			invalid(fmt.Sprintf("promoted.labels[%d]", i), "%q is not a label key", key)
+		}
+	}
+	for i, key := range f.Promoted.Annotations {
+		if strings.TrimSpace(key) == "" || strings.Contains(key, ",") {
+			invalid(fmt.Sprintf("promoted.annotations[%d]", i), "%q is not an annotation key", key)
+		}
+	}
+
+	ttls := []struct {
+		path string
+		days *int
+	}{
+		{"ttl.eventDays", f.TTL.EventDays},
+		{"ttl.historyDays", f.TTL.HistoryDays},
+		{"ttl.auditDays", f.TTL.AuditDays},
+	}
+	for _, ttl := range ttls {
+		if ttl.days != nil && *ttl.days < 0 {
+			invalid(ttl.path, "must be 0 or more, got %d", *ttl.days)
+		}
+	}
+
+	kinds := make(map[string]bool)
+	for i, rule := range f.Retention {
+		path := fmt.Sprintf("retention[%d]", i)
+		switch {
+		case strings.TrimSpace(rule.Kind) == "":
+			invalid(path+".kind", "must not be empty")
+		case strings.ContainsAny(rule.Kind, ",:="):
+			invalid(path+".kind", "%q is not a kind", rule.Kind)
+		case kinds[rule.Kind]:
+			invalid(path+".kind", "%s already has a rule", rule.Kind)
+		}
+		kinds[rule.Kind] = true
+		if rule.HistoryDays == nil && rule.KeepGenerations == nil {
+			invalid(path, "must set historyDays or keepGenerations")
+		}
+		if rule.HistoryDays != nil && *rule.HistoryDays < 0 {
+			invalid(path+".historyDays", "must be 0 or more, got %d", *rule.HistoryDays)
+		}
+		if rule.KeepGenerations != nil && *rule.KeepGenerations < 1 {
+			invalid(path+".keepGenerations", "must be 1 or more, got %d", *rule.KeepGenerations)
+		}
+	}
+
+	intervals := []struct {
+		path     string
+		interval *string
+	}{
+		{"cleanup.interval", f.Cleanup.Interval},
+		{"cleanup.eventPruneInterval", f.Cleanup.EventPruneInterval},
+	}
+	for _, interval := range intervals {
+		if interval.interval == nil {
+			continue
+		}
+		if d, err := time.ParseDuration(*interval.interval); err != nil || d <= 0 {
+			invalid(interval.path, "%q is not a positive duration such as 5m", *interval.interval)
+		}
+	}
+
+	if f.HTTP.Port != nil && (*f.HTTP.Port < 1 || *f.HTTP.Port > 65535) {
+		invalid("http.port", "must be between 1 and 65535, got %d", *f.HTTP.Port)
+	}
+	if f.HTTP.TLS.RedirectPort != nil && (*f.HTTP.TLS.RedirectPort < 0 || *f.HTTP.TLS.RedirectPort > 65535) {
+		invalid("http.tls.redirectPort", "must be between 0 and 65535, got %d", *f.HTTP.TLS.RedirectPort)
+	}
+	if (f.HTTP.TLS.Cert == nil) != (f.HTTP.TLS.Key == nil) {
+		invalid("http.tls", "cert and key must be set together")
+	}
+
+	limits := []struct {
+		path  string
+		value *int
+		min   int
+	}{
+		{"http.queries.timeoutSeconds", f.HTTP.Queries.TimeoutSeconds, 1},
+		{"http.queries.rateLimit", f.HTTP.Queries.RateLimit, 0},
+		{"http.queries.rateBurst", f.HTTP.Queries.RateBurst, 1},
+		{"http.queries.maxResults", f.HTTP.Queries.MaxResults, 1},
+		{"http.queries.maxConcurrent", f.HTTP.Queries.MaxConcurrent, 1},
+	}
+	for _, limit := range limits {
+		if limit.value != nil && *limit.value < limit.min {
+			invalid(limit.path, "must be %d or more, got %d", limit.min, *limit.value)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Flags returns the command-line flags set by the file, by flag name
+func (f *File) Flags() map[string]string {
+	flags := make(map[string]string)
+	setString := func(name string, value *string) {
+		if value != nil {
+			flags[name] = *value
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			flags[name] = strconv.Itoa(*value)
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			flags[name] = strconv.FormatBool(*value)
+		}
+	}
+	setList := func(name string, values []string) {
+		if values != nil {
+			flags[name] = strings.Join(values, ",")
+		}
+	}
+
+	setString("log-level", f.LogLevel)
+	setString("neo4j-uri", f.Neo4j.URI)
+	setString("neo4j-username", f.Neo4j.Username)
+	setString("neo4j-password", f.Neo4j.Password)
+	setString("neo4j-uri-file", f.Neo4j.URIFile)
+	setString("neo4j-username-file", f.Neo4j.UsernameFile)
+	setString("neo4j-password-file", f.Neo4j.PasswordFile)
+	setString("neo4j-database", f.Neo4j.Database)
+	setString("graph-backend", f.Neo4j.Backend)
+	setInt("neo4j-breaker-threshold", f.Neo4j.BreakerThreshold)
+	setInt("neo4j-max-connection-pool-size", f.Neo4j.PoolSize)
+	setString("neo4j-read-uri", f.Neo4j.ReadURI)
+	setInt("neo4j-read-max-connection-pool-size", f.Neo4j.ReadPoolSize)
+	setString("neo4j-ca-cert", f.Neo4j.TLS.CACert)
+	setString("neo4j-client-cert", f.Neo4j.TLS.ClientCert)
+	setString("neo4j-client-key", f.Neo4j.TLS.ClientKey)
+	setBool("neo4j-tls-skip-verify", f.Neo4j.TLS.SkipVerify)
+	setInt("neo4j-batch-max-size", f.Neo4j.Batch.MaxSize)
+	setInt("neo4j-batch-min-size", f.Neo4j.Batch.MinSize)
+	setInt("neo4j-batch-flush-interval-ms", f.Neo4j.Batch.FlushIntervalMs)
+	setString("kubeconfig", f.Kubernetes.ConfigPath)
+	setString("cluster-name", f.Kubernetes.ClusterName)
+	setList("disabled-handlers", f.Handlers.Disabled)
+	setList("namespaces", f.Filters.Namespaces)
+	setList("exclude-namespaces", f.Filters.ExcludeNamespaces)
+	setString("team-label", f.Teams.Label)
+	if f.Teams.Namespaces != nil {
+		rules := make([]TeamRule, 0, len(f.Teams.Namespaces))
+		for _, rule := range f.Teams.Namespaces {
+			rules = append(rules, TeamRule{Namespace: rule.Namespace, Team: rule.Team})
+		}
+		flags["team-namespaces"] = FormatTeamRules(rules)
+	}
+	setList("promoted-labels", f.Promoted.Labels)
+	setList("promoted-annotations", f.Promoted.Annotations)
+	setInt("event-ttl-days", f.TTL.EventDays)
+	setInt("history-retention-days", f.TTL.HistoryDays)
+	setInt("audit-ttl-days", f.TTL.AuditDays)
+	if f.Retention != nil {
+		rules := make([]RetentionRule, 0, len(f.Retention))
+		for _, rule := range f.Retention {
+			r := RetentionRule{Kind: rule.Kind, HistoryDays: rule.HistoryDays}
+			if rule.KeepGenerations != nil {
+				r.KeepGenerations = *rule.KeepGenerations
+			}
+			rules = append(rules, r)
+		}
+		flags["retention"] = FormatRetentionRules(rules)
+	}
+	setString("cleanup-interval", f.Cleanup.Interval)
+	setString("event-prune-interval", f.Cleanup.EventPruneInterval)
+	setBool("http-enabled", f.HTTP.Enabled)
+	setInt("http-port", f.HTTP.Port)
+	setBool("web-ui", f.HTTP.UI)
+	setBool("http-debug", f.HTTP.Debug)
+	setBool("http-token-review", f.HTTP.Auth.TokenReview)
+	setList("http-admin-groups", f.HTTP.Auth.AdminGroups)
+	setList("http-reader-groups", f.HTTP.Auth.ReaderGroups)
+	setString("http-tls-cert", f.HTTP.TLS.Cert)
+	setString("http-tls-key", f.HTTP.TLS.Key)
+	setInt("http-redirect-port", f.HTTP.TLS.RedirectPort)
+	setInt("http-query-timeout-seconds", f.HTTP.Queries.TimeoutSeconds)
+	setInt("http-rate-limit", f.HTTP.Queries.RateLimit)
+	setInt("http-rate-burst", f.HTTP.Queries.RateBurst)
+	setInt("http-max-results", f.HTTP.Queries.MaxResults)
+	setInt("http-max-concurrent-queries", f.HTTP.Queries.MaxConcurrent)
+	return flags
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeConfigFile(t, `
+logLevel: debug
+neo4j:
+  uri: neo4j+s://graph.example.com:7687
+  database: staging
+  tls:
+    skipVerify: true
+  batch:
+    maxSize: 256
+    flushIntervalMs: 10
+kubernetes:
+  clusterName: production
+handlers:
+  disabled: [Event, Secret]
+filters:
+  excludeNamespaces: [kube-system]
+teams:
+  label: team
+  namespaces:
+    - namespace: payments-*
+      team: billing
+    - namespace: kube-*
+      team: platform
+promoted:
+  labels: [app.kubernetes.io/name, env]
+ttl:
+  eventDays: 0
+retention:
+  - kind: ReplicaSet
+    keepGenerations: 3
+  - kind: Pod
+    historyDays: 7
+cleanup:
+  interval: 10m
+http:
+  port: 9090
+  ui: true
+  debug: true
+  auth:
+    tokenReview: true
+    adminGroups: [platform-admins, system:masters]
+    readerGroups: [system:serviceaccounts:monitoring]
+  tls:
+    cert: /etc/kubegraph/tls/tls.crt
+    key: /etc/kubegraph/tls/tls.key
+    redirectPort: 8081
+  queries:
+    rateLimit: 120
+    maxConcurrent: 2
+`)
+
+	file, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load config file: %v", err)
+	}
+
+	expected := map[string]string{
+		"log-level":                     "debug",
+		"neo4j-uri":                     "neo4j+s://graph.example.com:7687",
+		"neo4j-database":                "staging",
+		"neo4j-tls-skip-verify":         "true",
+		"neo4j-batch-max-size":          "256",
+		"neo4j-batch-flush-interval-ms": "10",
+		"cluster-name":                  "production",
+		"disabled-handlers":             "Event,Secret",
+		"exclude-namespaces":            "kube-system",
+		"team-label":                    "team",
+		"team-namespaces":               "payments-*=billing,kube-*=platform",
+		"promoted-labels":               "app.kubernetes.io/name,env",
+		"event-ttl-days":                "0",
+		"retention":                     "ReplicaSet:keepGenerations=3,Pod:historyDays=7",
+		"cleanup-interval":              "10m",
+		"http-port":                     "9090",
+		"web-ui":                        "true",
+		"http-debug":                    "true",
+		"http-token-review":             "true",
+		"http-admin-groups":             "platform-admins,system:masters",
+		"http-reader-groups":            "system:serviceaccounts:monitoring",
+		"http-tls-cert":                 "/etc/kubegraph/tls/tls.crt",
+		"http-tls-key":                  "/etc/kubegraph/tls/tls.key",
+		"http-redirect-port":            "8081",
+		"http-rate-limit":               "120",
+		"http-max-concurrent-queries":   "2",
+	}
+	if flags := file.Flags(); !reflect.DeepEqual(flags, expected) {
+		t.Errorf("Expected flags %v, got %v", expected, flags)
+	}
+}
+
+func TestLoadFileEmpty(t *testing.T) {
+	file, err := LoadFile(writeConfigFile(t, ""))
+	if err != nil {
+		t.Fatalf("Failed to load empty config file: %v", err)
+	}
+	if flags := file.Flags(); len(flags) != 0 {
+		t.Errorf("Expected no flags, got %v", flags)
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	_, err := LoadFile(writeConfigFile(t, "neo4j:\n  url: neo4j://localhost:7687\n"))
+	if err == nil || !strings.Contains(err.Error(), "field url not found") {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+}
+
+func TestLoadFileValidation(t *testing.T) {
+	_, err := LoadFile(writeConfigFile(t, `
+logLevel: verbose
+neo4j:
+  uri: http://localhost:7474
+  tls:
+    clientCert: /certs/tls.crt
+  batch:
+    maxSize: 8
+    minSize: 16
+filters:
+  namespaces: [payments]
+  excludeNamespaces: [payments]
+teams:
+  namespaces:
+    - namespace: payments
+promoted:
+  annotations: [""]
+ttl:
+  auditDays: -1
+retention:
+  - kind: Pod
+  - kind: ReplicaSet
+    keepGenerations: 0
+cleanup:
+  eventPruneInterval: 0s
+http:
+  port: 70000
+`))
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, path := range []string{"logLevel", "neo4j.uri", "neo4j.tls", "neo4j.batch.minSize", "filters.namespaces[0]", "teams.namespaces[0].team", "promoted.annotations[0]", "ttl.auditDays", "retention[0]", "retention[1].keepGenerations", "cleanup.eventPruneInterval", "http.port"} {
+		if !strings.Contains(err.Error(), path+":") {
+			t.Errorf("Expected an error for %s, got %v", path, err)
+		}
+	}
+}
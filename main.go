@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -12,14 +13,25 @@ import (
 	"time"
 
 	"k8s-graph/config"
+	"k8s-graph/pkg/anomaly"
+	"k8s-graph/pkg/enrich"
+	_ "k8s-graph/pkg/enrich/neo4jclaims" // Opt-in with --enrichers=neo4j-statefulset-claims
+	"k8s-graph/pkg/graph"
 	"k8s-graph/pkg/httpserver"
+	"k8s-graph/pkg/incidents"
 	"k8s-graph/pkg/kubernetes"
 	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/kubernetes/registry"
 	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/manifests"
 	"k8s-graph/pkg/neo4j"
+	"k8s-graph/pkg/reload"
+	"k8s-graph/pkg/replay"
+	"k8s-graph/pkg/tracing"
+	"k8s-graph/pkg/usage"
+	"k8s-graph/pkg/version"
 
 	"github.com/google/uuid"
-	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
 // getEnvBool gets a boolean value from environment variable
@@ -47,29 +59,309 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvDuration gets a duration such as 5m from environment variable
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if duration, err := time.ParseDuration(strings.TrimSpace(val)); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// manifestExcludedFlags are not passed on to the Deployment printed by
+// --print-manifests: the modes of this run, the credentials, which go into a
+// Secret, and the paths of local files that do not exist in the pod
+var manifestExcludedFlags = map[string]bool{
+	"config": true, "kubeconfig": true, "check-rbac": true, "print-cluster-role": true,
+	"print-manifests": true, "manifests-namespace": true, "manifests-image": true,
+	"replay": true, "replay-speed": true, "record-events": true, "dry-run-output": true, "graph-output": true,
+	"neo4j-username": true, "neo4j-password": true, "http-auth-tokens": true, "http-auth-users": true,
+	"neo4j-uri-file": true, "neo4j-username-file": true, "neo4j-password-file": true,
+	"neo4j-ca-cert": true, "neo4j-client-cert": true, "neo4j-client-key": true,
+	"http-tls-cert": true, "http-tls-key": true, "dead-letter-path": true, "enricher-plugins": true,
+}
+
+// manifestArgs returns the flags of the settings that differ from their
+// defaults, whether they were given as flags, environment variables or in the
+// config file
+func manifestArgs() []string {
+	var args []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if manifestExcludedFlags[f.Name] || f.Value.String() == f.DefValue {
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
+
+// runRBACCheck logs the permissions the enabled handlers are missing and
+// reports whether none are
+func runRBACCheck(ctx context.Context, cfg *config.Config) bool {
+	kubernetesClient, err := kubernetes.NewClient(cfg)
+	if err != nil {
+		logger.Error("Failed to create Kubernetes client: %v", err)
+		return false
+	}
+	checks, err := kubernetesClient.CheckRBAC(ctx, registry.Enabled(cfg))
+	if err != nil {
+		logger.Error("Failed to check RBAC permissions: %v", err)
+		return false
+	}
+	missing := 0
+	for _, check := range checks {
+		switch {
+		case !check.Served:
+			logger.Info("%s (%s) is not served by the cluster, skipped", check.Kind, check.GVR.String())
+		case !check.Allowed:
+			missing++
+			logger.Error("Missing permission: %s %s (%s) %s", check.Verb, check.GVR.Resource, check.Kind, check.Reason)
+		}
+	}
+	if missing > 0 {
+		logger.Error("%d permissions missing, see --print-cluster-role for a ClusterRole granting them", missing)
+		return false
+	}
+	logger.Info("All %d permissions of the enabled handlers are granted", len(checks))
+	return true
+}
+
+// runFileOutput watches the cluster and appends the mutations of the handlers
+// to the file store at path until the process is stopped. Nothing is read
+// back, so the periodic resolvers, pruning, anomaly detection and the HTTP
+// server are not run.
+func runFileOutput(ctx context.Context, cfg *config.Config, path string) bool {
+	store, err := graph.NewFileStore(path)
+	if err != nil {
+		logger.Error("Failed to open the file store: %v", err)
+		return false
+	}
+	defer func() {
+		if err := store.Close(context.Background()); err != nil {
+			logger.Error("Failed to close the file store: %v", err)
+		}
+	}()
+	logger.Info("Writing the graph to %s instead of Neo4j", path)
+
+	kubernetesClient, err := kubernetes.NewClient(cfg)
+	if err != nil {
+		logger.Error("Failed to create Kubernetes client: %v", err)
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigChan:
+			logger.Info("Shutting down...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := kubernetesClient.StartWatching(ctx, store); err != nil {
+		logger.Error("Failed to start watching resources: %v", err)
+		return false
+	}
+	return true
+}
+
 func main() {
 	cfg := config.NewConfig()
 
 	// Command line flags
+	var configFile string
 	var kubeconfig string
 	var clusterName string
 	var neo4jURI string
 	var neo4jUsername string
 	var neo4jPassword string
+	var neo4jURIFile string
+	var neo4jUsernameFile string
+	var neo4jPasswordFile string
 	var httpEnabled bool
 	var httpPort int
+	var webUI bool
+	var httpDebug bool
+	var httpAuthTokens string
+	var httpAuthUsers string
+	var httpTokenReview bool
+	var httpAdminGroups string
+	var httpReaderGroups string
+	var httpTLSCert string
+	var httpTLSKey string
+	var httpRedirectPort int
+	var httpQueryTimeoutSeconds int
+	var httpRateLimit int
+	var httpRateBurst int
+	var httpMaxResults int
+	var httpMaxConcurrentQueries int
 	var logLevel string
 	var eventTTLDays int
+	var ingestSources string
+	var retentionRules string
+	var disabledHandlers string
+	var namespaces string
+	var excludeNamespaces string
+	var teamLabel string
+	var teamNamespaces string
+	var promotedLabels string
+	var promotedAnnotations string
+	var coalesceWindowMs int
+	var changeCacheSize int
+	var writeWorkers int
+	var orderedStartup bool
+	var initialAddRate int
+	var pendingRelationships int
+	var pendingRelationshipTTLSeconds int
+	var serializedLabels string
+	var graphBackend string
+	var graphOutput string
+	var neo4jDatabase string
+	var neo4jBreakerThreshold int
+	var neo4jPoolSize int
+	var neo4jReadURI string
+	var neo4jReadPoolSize int
+	var neo4jCACert string
+	var neo4jClientCert string
+	var neo4jClientKey string
+	var neo4jTLSSkipVerify bool
+	var neo4jBatchMaxSize int
+	var neo4jBatchMinSize int
+	var neo4jBatchFlushIntervalMs int
+	var deadLetterCapacity int
+	var deadLetterPath string
+	var deadLetterRetrySeconds int
+	var anomalyDetection bool
+	var anomalyIntervalSeconds int
+	var anomalyThreshold float64
+	var eventCorrelation bool
+	var eventCorrelationIntervalSeconds int
+	var eventCorrelationWindowMinutes int
+	var usageMetrics bool
+	var usageIntervalSeconds int
+	var cleanupInterval time.Duration
+	var eventPruneInterval time.Duration
+	var historyMode bool
+	var historyRetentionDays int
+	var auditTrail bool
+	var dryRun bool
+	var dryRunOutput string
+	var recordEvents string
+	var replayFile string
+	var replaySpeed float64
+	var auditTTLDays int
+	var enrichers string
+	var enricherPlugins string
+	var checkRBAC bool
+	var printClusterRole bool
+	var printManifests bool
+	var manifestsNamespace string
+	var manifestsImage string
 
+	flag.StringVar(&configFile, "config", "", "Path of a YAML configuration file; flags and environment variables take precedence over it")
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file (uses in-cluster config if empty)")
 	flag.StringVar(&clusterName, "cluster-name", "default", "Name of the Kubernetes cluster")
 	flag.StringVar(&neo4jURI, "neo4j-uri", "neo4j://localhost:7687", "Neo4j database URI")
 	flag.StringVar(&neo4jUsername, "neo4j-username", "neo4j", "Neo4j username")
 	flag.StringVar(&neo4jPassword, "neo4j-password", "password", "Neo4j password")
+	flag.StringVar(&neo4jURIFile, "neo4j-uri-file", "", "File the Neo4j URI is read from, e.g. a mounted Secret (overrides --neo4j-uri)")
+	flag.StringVar(&neo4jUsernameFile, "neo4j-username-file", "", "File the Neo4j username is read from and reloaded when it changes (overrides --neo4j-username)")
+	flag.StringVar(&neo4jPasswordFile, "neo4j-password-file", "", "File the Neo4j password is read from and reloaded when it changes (overrides --neo4j-password)")
+	flag.StringVar(&graphBackend, "graph-backend", "neo4j", "Graph database served at --neo4j-uri: neo4j or memgraph")
+	flag.StringVar(&graphOutput, "graph-output", "neo4j", "Where the graph is written: neo4j, or file:<path> to append its mutations to a file without connecting to a database")
+	flag.StringVar(&neo4jDatabase, "neo4j-database", "", "Neo4j database to write to (empty uses the server's default database)")
+	flag.StringVar(&neo4jCACert, "neo4j-ca-cert", "", "Path of a CA certificate trusted for the Neo4j connection in addition to the system CAs")
+	flag.StringVar(&neo4jClientCert, "neo4j-client-cert", "", "Path of the client certificate for mutual TLS with Neo4j")
+	flag.StringVar(&neo4jClientKey, "neo4j-client-key", "", "Path of the key of the Neo4j client certificate")
+	flag.BoolVar(&neo4jTLSSkipVerify, "neo4j-tls-skip-verify", false, "Accept any Neo4j server certificate (insecure, for self-signed test deployments)")
+	flag.IntVar(&neo4jBreakerThreshold, "neo4j-breaker-threshold", 5, "Consecutive Neo4j connectivity failures that pause writes until Neo4j is reachable again (0 disables)")
+	flag.IntVar(&neo4jPoolSize, "neo4j-max-connection-pool-size", 50, "Size of the Neo4j connection pool used for writes, and reads unless a read pool is configured")
+	flag.StringVar(&neo4jReadURI, "neo4j-read-uri", "", "Neo4j URI of a separate read pool, e.g. read replicas (empty uses --neo4j-uri)")
+	flag.IntVar(&neo4jReadPoolSize, "neo4j-read-max-connection-pool-size", 0, "Size of a separate Neo4j read pool (0 shares the pool unless --neo4j-read-uri is set)")
+	flag.IntVar(&neo4jBatchMaxSize, "neo4j-batch-max-size", 0, "Largest number of node writes grouped into one Neo4j transaction (0 disables batching)")
+	flag.IntVar(&neo4jBatchMinSize, "neo4j-batch-min-size", 16, "Smallest batch size the adaptive batching shrinks to under low load")
+	flag.IntVar(&neo4jBatchFlushIntervalMs, "neo4j-batch-flush-interval-ms", 20, "Time in milliseconds a batch of node writes waits to fill before it is written")
 	flag.BoolVar(&httpEnabled, "http-enabled", true, "Enable HTTP server for status")
 	flag.IntVar(&httpPort, "http-port", 8080, "HTTP server port")
+	flag.BoolVar(&webUI, "web-ui", false, "Serve the graph explorer web UI on /ui/ of the HTTP server")
+	flag.BoolVar(&httpDebug, "http-debug", false, "Serve the pprof and expvar endpoints on /debug/ of the HTTP server")
+	flag.StringVar(&httpAuthTokens, "http-auth-tokens", "", "Comma-separated name:token:role bearer tokens of the HTTP server API, with role read or admin")
+	flag.StringVar(&httpAuthUsers, "http-auth-users", "", "Comma-separated username:password:role basic authentication users of the HTTP server API, with role read or admin")
+	flag.BoolVar(&httpTokenReview, "http-token-review", false, "Authenticate bearer tokens of the HTTP server API with the Kubernetes TokenReview API")
+	flag.StringVar(&httpAdminGroups, "http-admin-groups", "", "Comma-separated users and groups authenticated by TokenReview that get the admin role")
+	flag.StringVar(&httpReaderGroups, "http-reader-groups", "", "Comma-separated users and groups authenticated by TokenReview that get the read role; other reviewed users are denied")
+	flag.StringVar(&httpTLSCert, "http-tls-cert", "", "Path of the certificate served by the HTTP server over HTTPS, reloaded when it changes")
+	flag.StringVar(&httpTLSKey, "http-tls-key", "", "Path of the private key of --http-tls-cert")
+	flag.IntVar(&httpRedirectPort, "http-redirect-port", 0, "Port redirecting plain HTTP requests to HTTPS when TLS is enabled (0 disables)")
+	flag.IntVar(&httpQueryTimeoutSeconds, "http-query-timeout-seconds", 60, "Time in seconds a graph query of the HTTP API may run before it is canceled")
+	flag.IntVar(&httpRateLimit, "http-rate-limit", 600, "Graph query requests per minute per client of the HTTP API (0 disables)")
+	flag.IntVar(&httpRateBurst, "http-rate-burst", 20, "Graph query requests a client of the HTTP API may make at once")
+	flag.IntVar(&httpMaxResults, "http-max-results", 1000, "Largest number of results a graph query of the HTTP API may request")
+	flag.IntVar(&httpMaxConcurrentQueries, "http-max-concurrent-queries", 4, "Graph queries of the HTTP API run at once")
 	flag.StringVar(&logLevel, "log-level", "INFO", "Log level: DEBUG, INFO, WARN, ERROR")
 	flag.IntVar(&eventTTLDays, "event-ttl-days", 7, "Number of days to retain Kubernetes events (0 disables event handling)")
+	flag.IntVar(&coalesceWindowMs, "coalesce-window-ms", 500, "Window in milliseconds for coalescing rapid updates to the same object (0 disables)")
+	flag.IntVar(&changeCacheSize, "change-cache-size", 100000, "Number of objects tracked to skip unchanged updates (0 disables)")
+	flag.IntVar(&writeWorkers, "write-workers", 8, "Number of workers serializing writes to nodes of the serialized labels (0 disables)")
+	flag.BoolVar(&orderedStartup, "ordered-startup", true, "Start informers in dependency order, e.g. Namespaces and Nodes before Pods")
+	flag.IntVar(&initialAddRate, "initial-add-rate", 0, "Add events handled per second and kind during the initial sync (0 disables throttling)")
+	flag.IntVar(&pendingRelationships, "pending-relationships", 10000, "Relationships kept until their missing node is written (0 disables)")
+	flag.IntVar(&pendingRelationshipTTLSeconds, "pending-relationship-ttl-seconds", 600, "Seconds a relationship waits for its missing node")
+	flag.StringVar(&serializedLabels, "serialized-labels", "Node,Namespace", "Comma-separated node labels whose writes are serialized per node to avoid lock contention")
+	flag.IntVar(&deadLetterCapacity, "dead-letter-capacity", 1000, "Number of failed Neo4j writes kept for retry and inspection (0 disables)")
+	flag.StringVar(&deadLetterPath, "dead-letter-path", "", "File failed Neo4j writes are saved to so they survive restarts (empty keeps them in memory)")
+	flag.IntVar(&deadLetterRetrySeconds, "dead-letter-retry-seconds", 30, "Interval in seconds between retries of failed Neo4j writes")
+	flag.BoolVar(&anomalyDetection, "anomaly-detection", false, "Detect restart and warning event spikes per workload")
+	flag.IntVar(&anomalyIntervalSeconds, "anomaly-interval-seconds", 300, "Interval in seconds between anomaly detection runs")
+	flag.Float64Var(&anomalyThreshold, "anomaly-threshold", 3.0, "Standard deviations above a workload's baseline that count as an anomaly")
+	flag.BoolVar(&eventCorrelation, "event-correlation", false, "Correlate events with their workloads, score them and group warnings into incidents")
+	flag.IntVar(&eventCorrelationIntervalSeconds, "event-correlation-interval-seconds", 120, "Interval in seconds between event correlation runs")
+	flag.IntVar(&eventCorrelationWindowMinutes, "event-correlation-window-minutes", 60, "Minutes of events attributed to a workload by each correlation run")
+	flag.BoolVar(&usageMetrics, "usage-metrics", false, "Poll metrics-server for the actual CPU and memory usage of nodes and pods")
+	flag.IntVar(&usageIntervalSeconds, "usage-interval-seconds", 60, "Interval in seconds between metrics-server polls")
+	flag.DurationVar(&cleanupInterval, "cleanup-interval", 5*time.Minute, "Interval of the background cleanup pruning history and audit records and resolving relationships")
+	flag.DurationVar(&eventPruneInterval, "event-prune-interval", 5*time.Minute, "Interval between prunes of expired events")
+	flag.BoolVar(&historyMode, "history-mode", false, "Record every change as a versioned ResourceVersion node for time-travel queries")
+	flag.IntVar(&historyRetentionDays, "history-retention-days", 30, "Number of days to keep superseded resource versions (0 keeps them forever)")
+	flag.BoolVar(&auditTrail, "audit-trail", false, "Record every upsert and delete performed by the sync process as a GraphChange node")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log the write statements instead of executing them; Neo4j is still read")
+	flag.StringVar(&dryRunOutput, "dry-run-output", "", "File the statements skipped by --dry-run are appended to as JSON lines")
+	flag.StringVar(&recordEvents, "record-events", "", "File the watch events received from the cluster are appended to as JSON lines")
+	flag.StringVar(&replayFile, "replay", "", "Replay a recording of --record-events through the handlers instead of watching the cluster, then exit")
+	flag.Float64Var(&replaySpeed, "replay-speed", 1, "Replay speed relative to the recording (0 replays as fast as possible)")
+	flag.IntVar(&auditTTLDays, "audit-ttl-days", 3, "Number of days to retain GraphChange audit records")
+	flag.StringVar(&enrichers, "enrichers", "", "Comma-separated names of the enrichers run on every node write, in order")
+	flag.StringVar(&enricherPlugins, "enricher-plugins", "", "Comma-separated paths of Go plugins registering enrichers")
+	flag.StringVar(&disabledHandlers, "disabled-handlers", "", "Comma-separated kinds whose handlers are not registered, e.g. Event,Secret")
+	flag.StringVar(&namespaces, "namespaces", "", "Comma-separated namespaces whose resources are synchronized (empty synchronizes all)")
+	flag.StringVar(&excludeNamespaces, "exclude-namespaces", "", "Comma-separated namespaces whose resources are ignored")
+	flag.StringVar(&teamLabel, "team-label", "", "Label whose value on a resource, or else on its namespace, is stamped onto its node as the team property")
+	flag.StringVar(&teamNamespaces, "team-namespaces", "", "Comma-separated namespace=team entries assigning namespaces without a team label to teams, e.g. payments-*=billing")
+	flag.StringVar(&promotedLabels, "promoted-labels", "app.kubernetes.io/name,app.kubernetes.io/instance,app", "Comma-separated label keys copied into indexed node properties of their own, queryable with kubegraph-cli --selector")
+	flag.StringVar(&promotedAnnotations, "promoted-annotations", "", "Comma-separated annotation keys copied into indexed node properties of their own")
+	flag.StringVar(&retentionRules, "retention", "", "Comma-separated kind:setting=value retention rules, e.g. ReplicaSet:keepGenerations=3,Pod:historyDays=7")
+	flag.BoolVar(&checkRBAC, "check-rbac", false, "Check that kubegraph may list and watch the resources of every enabled handler, report what is missing, then exit")
+	flag.BoolVar(&printClusterRole, "print-cluster-role", false, "Print a minimal ClusterRole covering exactly the enabled handlers as YAML, then exit")
+	flag.BoolVar(&printManifests, "print-manifests", false, "Print the manifests deploying k8s-graph with the current settings as YAML, then exit")
+	flag.StringVar(&manifestsNamespace, "manifests-namespace", "kubegraph", "Namespace of the manifests printed by --print-manifests")
+	flag.StringVar(&manifestsImage, "manifests-image", "dcarias/kubegraph:"+version.Version, "Image of the Deployment printed by --print-manifests")
+	flag.StringVar(&ingestSources, "ingest-sources", "", "Comma-separated name:token:clusterName entries allowed to push resources to /api/v1/ingest")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "k8s-graph - Kubernetes Resource Graph Database\n\n")
@@ -85,15 +377,97 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s --neo4j-uri=neo4j://remote:7687 --neo4j-username=user --neo4j-password=pass\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Disable event monitoring for performance\n")
 		fmt.Fprintf(os.Stderr, "  %s --event-ttl-days=0\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Load settings from a configuration file\n")
+		fmt.Fprintf(os.Stderr, "  %s --config=/etc/kubegraph/config.yaml\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Check the RBAC permissions, or generate the ClusterRole they need\n")
+		fmt.Fprintf(os.Stderr, "  %s --check-rbac\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --print-cluster-role --disabled-handlers=Secret > clusterrole.yaml\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Generate the manifests deploying k8s-graph with these settings\n")
+		fmt.Fprintf(os.Stderr, "  %s --config=config.yaml --print-manifests | kubectl apply -f -\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Environment Variables:\n")
+		fmt.Fprintf(os.Stderr, "  CONFIG_FILE      - Path of a YAML configuration file\n")
 		fmt.Fprintf(os.Stderr, "  KUBECONFIG       - Path to kubeconfig file\n")
 		fmt.Fprintf(os.Stderr, "  CLUSTER_NAME     - Kubernetes cluster name\n")
 		fmt.Fprintf(os.Stderr, "  NEO4J_URI        - Neo4j database URI\n")
 		fmt.Fprintf(os.Stderr, "  NEO4J_USERNAME   - Neo4j username\n")
 		fmt.Fprintf(os.Stderr, "  NEO4J_PASSWORD   - Neo4j password\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_URI_FILE   - File the Neo4j URI is read from\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_USERNAME_FILE - File the Neo4j username is read from\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_PASSWORD_FILE - File the Neo4j password is read from\n")
+		fmt.Fprintf(os.Stderr, "  GRAPH_BACKEND    - Graph database served at the Neo4j URI (neo4j/memgraph)\n")
+		fmt.Fprintf(os.Stderr, "  GRAPH_OUTPUT     - Where the graph is written (neo4j/file:<path>)\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_DATABASE   - Neo4j database to write to\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_CA_CERT    - CA certificate trusted for the Neo4j connection\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_CLIENT_CERT - Client certificate for mutual TLS with Neo4j\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_CLIENT_KEY - Key of the Neo4j client certificate\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_TLS_SKIP_VERIFY - Accept any Neo4j server certificate (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_BREAKER_THRESHOLD - Consecutive connectivity failures that open the circuit breaker\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_MAX_CONNECTION_POOL_SIZE - Size of the Neo4j connection pool\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_READ_URI   - Neo4j URI of a separate read pool\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_READ_MAX_CONNECTION_POOL_SIZE - Size of a separate Neo4j read pool\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_BATCH_MAX_SIZE - Largest number of node writes per transaction (0 disables batching)\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_BATCH_MIN_SIZE - Smallest adaptive batch size\n")
+		fmt.Fprintf(os.Stderr, "  NEO4J_BATCH_FLUSH_INTERVAL_MS - Time a batch waits to fill, in milliseconds\n")
 		fmt.Fprintf(os.Stderr, "  LOG_LEVEL        - Log level\n")
 		fmt.Fprintf(os.Stderr, "  HTTP_ENABLED     - Enable HTTP server (true/false)\n")
-		fmt.Fprintf(os.Stderr, "  HTTP_PORT        - HTTP server port\n\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_PORT        - HTTP server port\n")
+		fmt.Fprintf(os.Stderr, "  WEB_UI           - Serve the graph explorer web UI (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_DEBUG       - Serve the pprof and expvar endpoints (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_AUTH_TOKENS - Bearer tokens of the HTTP server API (name:token:role)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_AUTH_USERS  - Basic authentication users of the HTTP server API (username:password:role)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_TOKEN_REVIEW - Authenticate bearer tokens with TokenReview (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_ADMIN_GROUPS - Users and groups authenticated by TokenReview that get the admin role\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_READER_GROUPS - Users and groups authenticated by TokenReview that get the read role\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_TLS_CERT    - Certificate served by the HTTP server over HTTPS\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_TLS_KEY     - Private key of the HTTP server certificate\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_REDIRECT_PORT - Port redirecting plain HTTP requests to HTTPS\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_QUERY_TIMEOUT_SECONDS - Time a graph query of the HTTP API may run\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_RATE_LIMIT  - Graph query requests per minute per client (0 disables)\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_RATE_BURST  - Graph query requests a client may make at once\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_MAX_RESULTS - Largest number of results a graph query may request\n")
+		fmt.Fprintf(os.Stderr, "  HTTP_MAX_CONCURRENT_QUERIES - Graph queries of the HTTP API run at once\n")
+		fmt.Fprintf(os.Stderr, "  COALESCE_WINDOW_MS - Update coalescing window in milliseconds\n")
+		fmt.Fprintf(os.Stderr, "  CHANGE_CACHE_SIZE - Number of objects tracked for change detection\n")
+		fmt.Fprintf(os.Stderr, "  WRITE_WORKERS    - Number of workers serializing writes to hot nodes\n")
+		fmt.Fprintf(os.Stderr, "  ORDERED_STARTUP  - Start informers in dependency order (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  INITIAL_ADD_RATE - Add events per second and kind during the initial sync\n")
+		fmt.Fprintf(os.Stderr, "  PENDING_RELATIONSHIPS - Relationships kept until their missing node is written\n")
+		fmt.Fprintf(os.Stderr, "  PENDING_RELATIONSHIP_TTL_SECONDS - Seconds a relationship waits for its missing node\n")
+		fmt.Fprintf(os.Stderr, "  SERIALIZED_LABELS - Node labels whose writes are serialized\n")
+		fmt.Fprintf(os.Stderr, "  DEAD_LETTER_CAPACITY - Number of failed Neo4j writes kept for retry\n")
+		fmt.Fprintf(os.Stderr, "  DEAD_LETTER_PATH - File failed Neo4j writes are saved to\n")
+		fmt.Fprintf(os.Stderr, "  DEAD_LETTER_RETRY_SECONDS - Interval between retries of failed Neo4j writes\n")
+		fmt.Fprintf(os.Stderr, "  ANOMALY_DETECTION - Enable anomaly detection (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  ANOMALY_INTERVAL_SECONDS - Interval between anomaly detection runs\n")
+		fmt.Fprintf(os.Stderr, "  EVENT_CORRELATION - Enable event correlation (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  EVENT_CORRELATION_INTERVAL_SECONDS - Interval between event correlation runs\n")
+		fmt.Fprintf(os.Stderr, "  EVENT_CORRELATION_WINDOW_MINUTES - Minutes of events correlated by each run\n")
+		fmt.Fprintf(os.Stderr, "  USAGE_METRICS    - Poll metrics-server for node and pod usage (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  USAGE_INTERVAL_SECONDS - Interval between metrics-server polls\n")
+		fmt.Fprintf(os.Stderr, "  CLEANUP_INTERVAL - Interval of the background cleanup (e.g. 5m)\n")
+		fmt.Fprintf(os.Stderr, "  EVENT_PRUNE_INTERVAL - Interval between prunes of expired events (e.g. 5m)\n")
+		fmt.Fprintf(os.Stderr, "  HISTORY_MODE     - Enable history mode (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  HISTORY_RETENTION_DAYS - Days to keep superseded resource versions\n")
+		fmt.Fprintf(os.Stderr, "  AUDIT_TRAIL      - Enable the audit trail of graph mutations (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  DRY_RUN          - Log write statements instead of executing them (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  DRY_RUN_OUTPUT   - File the statements skipped by the dry run are appended to\n")
+		fmt.Fprintf(os.Stderr, "  RECORD_EVENTS    - File the watch events are recorded to\n")
+		fmt.Fprintf(os.Stderr, "  REPLAY           - Recording replayed instead of watching the cluster\n")
+		fmt.Fprintf(os.Stderr, "  REPLAY_SPEED     - Replay speed relative to the recording (0 replays as fast as possible)\n")
+		fmt.Fprintf(os.Stderr, "  AUDIT_TTL_DAYS   - Days to retain audit records\n")
+		fmt.Fprintf(os.Stderr, "  ENRICHERS        - Enrichers run on every node write\n")
+		fmt.Fprintf(os.Stderr, "  ENRICHER_PLUGINS - Go plugins registering enrichers\n")
+		fmt.Fprintf(os.Stderr, "  DISABLED_HANDLERS - Kinds whose handlers are not registered\n")
+		fmt.Fprintf(os.Stderr, "  NAMESPACES       - Namespaces whose resources are synchronized\n")
+		fmt.Fprintf(os.Stderr, "  EXCLUDE_NAMESPACES - Namespaces whose resources are ignored\n")
+		fmt.Fprintf(os.Stderr, "  TEAM_LABEL       - Label holding the team of resources and namespaces\n")
+		fmt.Fprintf(os.Stderr, "  TEAM_NAMESPACES  - Teams of namespaces (namespace=team,...)\n")
+		fmt.Fprintf(os.Stderr, "  PROMOTED_LABELS  - Label keys promoted to indexed node properties\n")
+		fmt.Fprintf(os.Stderr, "  PROMOTED_ANNOTATIONS - Annotation keys promoted to indexed node properties\n")
+		fmt.Fprintf(os.Stderr, "  INGEST_SOURCES   - Ingest sources (name:token:clusterName,...)\n")
+		fmt.Fprintf(os.Stderr, "  RETENTION_RULES  - Per-kind retention rules (kind:setting=value,...)\n")
+		fmt.Fprintf(os.Stderr, "  CHECK_RBAC       - Check the RBAC permissions of the enabled handlers, then exit (true/false)\n")
+		fmt.Fprintf(os.Stderr, "  OTEL_EXPORTER_OTLP_ENDPOINT - OTLP/HTTP endpoint traces are exported to (tracing is disabled when unset)\n\n")
 		fmt.Fprintf(os.Stderr, "Supported Resources:\n")
 		fmt.Fprintf(os.Stderr, "  • Pods: Pod lifecycle and relationships\n")
 		fmt.Fprintf(os.Stderr, "  • Deployments: Deployment configurations\n")
@@ -106,6 +480,31 @@ func main() {
 
 	flag.Parse()
 
+	// Settings of the configuration file apply to the flags that were not given
+	if envConfigFile := os.Getenv("CONFIG_FILE"); envConfigFile != "" && configFile == "" {
+		configFile = envConfigFile
+	}
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	if configFile != "" {
+		file, err := config.LoadFile(configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		for name, value := range file.Flags() {
+			if given[name] {
+				continue
+			}
+			if err := flag.Set(name, value); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid value for %s in config file %s: %v\n", name, configFile, err)
+				os.Exit(1)
+			}
+		}
+	}
+
 	// Override with environment variables
 	if envKubeconfig := os.Getenv("KUBECONFIG"); envKubeconfig != "" {
 		kubeconfig = envKubeconfig
@@ -122,12 +521,167 @@ func main() {
 	if envNeo4jPassword := os.Getenv("NEO4J_PASSWORD"); envNeo4jPassword != "" {
 		neo4jPassword = envNeo4jPassword
 	}
+	if envNeo4jURIFile := os.Getenv("NEO4J_URI_FILE"); envNeo4jURIFile != "" {
+		neo4jURIFile = envNeo4jURIFile
+	}
+	if envNeo4jUsernameFile := os.Getenv("NEO4J_USERNAME_FILE"); envNeo4jUsernameFile != "" {
+		neo4jUsernameFile = envNeo4jUsernameFile
+	}
+	if envNeo4jPasswordFile := os.Getenv("NEO4J_PASSWORD_FILE"); envNeo4jPasswordFile != "" {
+		neo4jPasswordFile = envNeo4jPasswordFile
+	}
+	if envGraphBackend := os.Getenv("GRAPH_BACKEND"); envGraphBackend != "" {
+		graphBackend = envGraphBackend
+	}
+	if envGraphOutput := os.Getenv("GRAPH_OUTPUT"); envGraphOutput != "" {
+		graphOutput = envGraphOutput
+	}
+	if envNeo4jDatabase := os.Getenv("NEO4J_DATABASE"); envNeo4jDatabase != "" {
+		neo4jDatabase = envNeo4jDatabase
+	}
+	if envNeo4jReadURI := os.Getenv("NEO4J_READ_URI"); envNeo4jReadURI != "" {
+		neo4jReadURI = envNeo4jReadURI
+	}
+	if envNeo4jCACert := os.Getenv("NEO4J_CA_CERT"); envNeo4jCACert != "" {
+		neo4jCACert = envNeo4jCACert
+	}
+	if envNeo4jClientCert := os.Getenv("NEO4J_CLIENT_CERT"); envNeo4jClientCert != "" {
+		neo4jClientCert = envNeo4jClientCert
+	}
+	if envNeo4jClientKey := os.Getenv("NEO4J_CLIENT_KEY"); envNeo4jClientKey != "" {
+		neo4jClientKey = envNeo4jClientKey
+	}
 	if envLogLevel := os.Getenv("LOG_LEVEL"); envLogLevel != "" {
 		logLevel = envLogLevel
 	}
+	if envIngestSources := os.Getenv("INGEST_SOURCES"); envIngestSources != "" {
+		ingestSources = envIngestSources
+	}
+	if envHTTPAuthTokens := os.Getenv("HTTP_AUTH_TOKENS"); envHTTPAuthTokens != "" {
+		httpAuthTokens = envHTTPAuthTokens
+	}
+	if envHTTPAuthUsers := os.Getenv("HTTP_AUTH_USERS"); envHTTPAuthUsers != "" {
+		httpAuthUsers = envHTTPAuthUsers
+	}
+	if envHTTPAdminGroups := os.Getenv("HTTP_ADMIN_GROUPS"); envHTTPAdminGroups != "" {
+		httpAdminGroups = envHTTPAdminGroups
+	}
+	if envHTTPReaderGroups := os.Getenv("HTTP_READER_GROUPS"); envHTTPReaderGroups != "" {
+		httpReaderGroups = envHTTPReaderGroups
+	}
+	if envHTTPTLSCert := os.Getenv("HTTP_TLS_CERT"); envHTTPTLSCert != "" {
+		httpTLSCert = envHTTPTLSCert
+	}
+	if envHTTPTLSKey := os.Getenv("HTTP_TLS_KEY"); envHTTPTLSKey != "" {
+		httpTLSKey = envHTTPTLSKey
+	}
+	if envRetentionRules := os.Getenv("RETENTION_RULES"); envRetentionRules != "" {
+		retentionRules = envRetentionRules
+	}
+	if envSerializedLabels := os.Getenv("SERIALIZED_LABELS"); envSerializedLabels != "" {
+		serializedLabels = envSerializedLabels
+	}
+	if envDeadLetterPath := os.Getenv("DEAD_LETTER_PATH"); envDeadLetterPath != "" {
+		deadLetterPath = envDeadLetterPath
+	}
+	if envEnrichers := os.Getenv("ENRICHERS"); envEnrichers != "" {
+		enrichers = envEnrichers
+	}
+	if envEnricherPlugins := os.Getenv("ENRICHER_PLUGINS"); envEnricherPlugins != "" {
+		enricherPlugins = envEnricherPlugins
+	}
+	if envDisabledHandlers := os.Getenv("DISABLED_HANDLERS"); envDisabledHandlers != "" {
+		disabledHandlers = envDisabledHandlers
+	}
+	if envNamespaces := os.Getenv("NAMESPACES"); envNamespaces != "" {
+		namespaces = envNamespaces
+	}
+	if envExcludeNamespaces := os.Getenv("EXCLUDE_NAMESPACES"); envExcludeNamespaces != "" {
+		excludeNamespaces = envExcludeNamespaces
+	}
+	if envTeamLabel := os.Getenv("TEAM_LABEL"); envTeamLabel != "" {
+		teamLabel = envTeamLabel
+	}
+	if envTeamNamespaces := os.Getenv("TEAM_NAMESPACES"); envTeamNamespaces != "" {
+		teamNamespaces = envTeamNamespaces
+	}
+	if envPromotedLabels := os.Getenv("PROMOTED_LABELS"); envPromotedLabels != "" {
+		promotedLabels = envPromotedLabels
+	}
+	if envPromotedAnnotations := os.Getenv("PROMOTED_ANNOTATIONS"); envPromotedAnnotations != "" {
+		promotedAnnotations = envPromotedAnnotations
+	}
 
+	neo4jTLSSkipVerify = getEnvBool("NEO4J_TLS_SKIP_VERIFY", neo4jTLSSkipVerify)
+	neo4jBreakerThreshold = getEnvInt("NEO4J_BREAKER_THRESHOLD", neo4jBreakerThreshold)
+	neo4jPoolSize = getEnvInt("NEO4J_MAX_CONNECTION_POOL_SIZE", neo4jPoolSize)
+	neo4jReadPoolSize = getEnvInt("NEO4J_READ_MAX_CONNECTION_POOL_SIZE", neo4jReadPoolSize)
+	neo4jBatchMaxSize = getEnvInt("NEO4J_BATCH_MAX_SIZE", neo4jBatchMaxSize)
+	neo4jBatchMinSize = getEnvInt("NEO4J_BATCH_MIN_SIZE", neo4jBatchMinSize)
+	neo4jBatchFlushIntervalMs = getEnvInt("NEO4J_BATCH_FLUSH_INTERVAL_MS", neo4jBatchFlushIntervalMs)
 	httpEnabled = getEnvBool("HTTP_ENABLED", httpEnabled)
 	httpPort = getEnvInt("HTTP_PORT", httpPort)
+	webUI = getEnvBool("WEB_UI", webUI)
+	httpDebug = getEnvBool("HTTP_DEBUG", httpDebug)
+	httpTokenReview = getEnvBool("HTTP_TOKEN_REVIEW", httpTokenReview)
+	httpRedirectPort = getEnvInt("HTTP_REDIRECT_PORT", httpRedirectPort)
+	httpQueryTimeoutSeconds = getEnvInt("HTTP_QUERY_TIMEOUT_SECONDS", httpQueryTimeoutSeconds)
+	httpRateLimit = getEnvInt("HTTP_RATE_LIMIT", httpRateLimit)
+	httpRateBurst = getEnvInt("HTTP_RATE_BURST", httpRateBurst)
+	httpMaxResults = getEnvInt("HTTP_MAX_RESULTS", httpMaxResults)
+	httpMaxConcurrentQueries = getEnvInt("HTTP_MAX_CONCURRENT_QUERIES", httpMaxConcurrentQueries)
+	coalesceWindowMs = getEnvInt("COALESCE_WINDOW_MS", coalesceWindowMs)
+	changeCacheSize = getEnvInt("CHANGE_CACHE_SIZE", changeCacheSize)
+	writeWorkers = getEnvInt("WRITE_WORKERS", writeWorkers)
+	orderedStartup = getEnvBool("ORDERED_STARTUP", orderedStartup)
+	initialAddRate = getEnvInt("INITIAL_ADD_RATE", initialAddRate)
+	pendingRelationships = getEnvInt("PENDING_RELATIONSHIPS", pendingRelationships)
+	pendingRelationshipTTLSeconds = getEnvInt("PENDING_RELATIONSHIP_TTL_SECONDS", pendingRelationshipTTLSeconds)
+	deadLetterCapacity = getEnvInt("DEAD_LETTER_CAPACITY", deadLetterCapacity)
+	deadLetterRetrySeconds = getEnvInt("DEAD_LETTER_RETRY_SECONDS", deadLetterRetrySeconds)
+	anomalyDetection = getEnvBool("ANOMALY_DETECTION", anomalyDetection)
+	anomalyIntervalSeconds = getEnvInt("ANOMALY_INTERVAL_SECONDS", anomalyIntervalSeconds)
+	eventCorrelation = getEnvBool("EVENT_CORRELATION", eventCorrelation)
+	eventCorrelationIntervalSeconds = getEnvInt("EVENT_CORRELATION_INTERVAL_SECONDS", eventCorrelationIntervalSeconds)
+	eventCorrelationWindowMinutes = getEnvInt("EVENT_CORRELATION_WINDOW_MINUTES", eventCorrelationWindowMinutes)
+	usageMetrics = getEnvBool("USAGE_METRICS", usageMetrics)
+	usageIntervalSeconds = getEnvInt("USAGE_INTERVAL_SECONDS", usageIntervalSeconds)
+	cleanupInterval = getEnvDuration("CLEANUP_INTERVAL", cleanupInterval)
+	eventPruneInterval = getEnvDuration("EVENT_PRUNE_INTERVAL", eventPruneInterval)
+	historyMode = getEnvBool("HISTORY_MODE", historyMode)
+	historyRetentionDays = getEnvInt("HISTORY_RETENTION_DAYS", historyRetentionDays)
+	auditTrail = getEnvBool("AUDIT_TRAIL", auditTrail)
+	dryRun = getEnvBool("DRY_RUN", dryRun)
+	if envDryRunOutput := os.Getenv("DRY_RUN_OUTPUT"); envDryRunOutput != "" {
+		dryRunOutput = envDryRunOutput
+	}
+	if envRecordEvents := os.Getenv("RECORD_EVENTS"); envRecordEvents != "" {
+		recordEvents = envRecordEvents
+	}
+	if envReplay := os.Getenv("REPLAY"); envReplay != "" {
+		replayFile = envReplay
+	}
+	if envReplaySpeed := os.Getenv("REPLAY_SPEED"); envReplaySpeed != "" {
+		if speed, err := strconv.ParseFloat(envReplaySpeed, 64); err == nil {
+			replaySpeed = speed
+		}
+	}
+	auditTTLDays = getEnvInt("AUDIT_TTL_DAYS", auditTTLDays)
+	checkRBAC = getEnvBool("CHECK_RBAC", checkRBAC)
+
+	// Settings given by environment variables are not reloaded from the config file either
+	for name, env := range map[string]string{
+		"log-level":              "LOG_LEVEL",
+		"disabled-handlers":      "DISABLED_HANDLERS",
+		"namespaces":             "NAMESPACES",
+		"exclude-namespaces":     "EXCLUDE_NAMESPACES",
+		"history-retention-days": "HISTORY_RETENTION_DAYS",
+		"audit-ttl-days":         "AUDIT_TTL_DAYS",
+	} {
+		if os.Getenv(env) != "" {
+			given[name] = true
+		}
+	}
 
 	// Update config
 	cfg.Kubernetes.ConfigPath = kubeconfig
@@ -135,21 +689,219 @@ func main() {
 	cfg.Neo4j.URI = neo4jURI
 	cfg.Neo4j.Username = neo4jUsername
 	cfg.Neo4j.Password = neo4jPassword
+	cfg.Neo4j.URIFile = neo4jURIFile
+	cfg.Neo4j.UsernameFile = neo4jUsernameFile
+	cfg.Neo4j.PasswordFile = neo4jPasswordFile
+	cfg.Neo4j.Backend = graphBackend
+	cfg.GraphOutput = graphOutput
+	cfg.Neo4j.Database = neo4jDatabase
+	cfg.Neo4j.BreakerThreshold = neo4jBreakerThreshold
+	cfg.Neo4j.MaxConnectionPoolSize = neo4jPoolSize
+	cfg.Neo4j.ReadURI = neo4jReadURI
+	cfg.Neo4j.ReadMaxConnectionPoolSize = neo4jReadPoolSize
+	cfg.Neo4j.CACertPath = neo4jCACert
+	cfg.Neo4j.ClientCertPath = neo4jClientCert
+	cfg.Neo4j.ClientKeyPath = neo4jClientKey
+	cfg.Neo4j.TLSSkipVerify = neo4jTLSSkipVerify
+	cfg.Neo4j.Batch = config.Neo4jBatch{
+		MaxSize:         neo4jBatchMaxSize,
+		MinSize:         neo4jBatchMinSize,
+		FlushIntervalMs: neo4jBatchFlushIntervalMs,
+	}
 	cfg.HTTP.Enabled = httpEnabled
 	cfg.HTTP.Port = httpPort
+	cfg.HTTP.UI = webUI
+	cfg.HTTP.Debug = httpDebug
 	cfg.EventTTLDays = eventTTLDays
+	cfg.Sync.CoalesceWindowMs = coalesceWindowMs
+	cfg.Sync.ChangeCacheSize = changeCacheSize
+	cfg.Sync.WriteWorkers = writeWorkers
+	cfg.Sync.OrderedStartup = orderedStartup
+	cfg.Sync.InitialAddRate = initialAddRate
+	cfg.Sync.PendingRelationships = pendingRelationships
+	cfg.Sync.PendingRelationshipTTLSeconds = pendingRelationshipTTLSeconds
+	cfg.Sync.SerializedLabels = splitList(serializedLabels)
+	cfg.DeadLetter.Capacity = deadLetterCapacity
+	cfg.DeadLetter.Path = deadLetterPath
+	cfg.DeadLetter.RetryIntervalSeconds = deadLetterRetrySeconds
+	cfg.Anomaly.Enabled = anomalyDetection
+	cfg.Anomaly.IntervalSeconds = anomalyIntervalSeconds
+	cfg.Anomaly.Threshold = anomalyThreshold
+	cfg.EventCorrelation.Enabled = eventCorrelation
+	cfg.EventCorrelation.IntervalSeconds = eventCorrelationIntervalSeconds
+	cfg.EventCorrelation.WindowMinutes = eventCorrelationWindowMinutes
+	cfg.Usage.Enabled = usageMetrics
+	cfg.Usage.IntervalSeconds = usageIntervalSeconds
+	cfg.Cleanup.Interval = cleanupInterval
+	cfg.Cleanup.EventPruneInterval = eventPruneInterval
+	cfg.History.Enabled = historyMode
+	cfg.History.RetentionDays = historyRetentionDays
+	cfg.Audit.Enabled = auditTrail
+	cfg.DryRun.Enabled = dryRun
+	cfg.DryRun.Output = dryRunOutput
+	cfg.Replay.RecordPath = recordEvents
+	cfg.Replay.Path = replayFile
+	cfg.Replay.Speed = replaySpeed
+	cfg.Audit.TTLDays = auditTTLDays
+	cfg.Enrichment.Enrichers = splitList(enrichers)
+	cfg.Enrichment.Plugins = splitList(enricherPlugins)
+	cfg.Handlers.Disabled = splitList(disabledHandlers)
+	cfg.Filters.Namespaces = splitList(namespaces)
+	cfg.Filters.ExcludeNamespaces = splitList(excludeNamespaces)
 	cfg.InstanceHash = uuid.New().String()
 
+	sources, err := config.ParseIngestSources(ingestSources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid ingest sources: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Ingest.Sources = sources
+
+	if cfg.HTTP.Auth.Tokens, err = config.ParseHTTPCredentials(httpAuthTokens); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid HTTP auth tokens: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.HTTP.Auth.Users, err = config.ParseHTTPCredentials(httpAuthUsers); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid HTTP auth users: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.HTTP.Auth.TokenReview = httpTokenReview
+	cfg.HTTP.Auth.AdminGroups = splitList(httpAdminGroups)
+	cfg.HTTP.Auth.ReaderGroups = splitList(httpReaderGroups)
+
+	if (httpTLSCert == "") != (httpTLSKey == "") {
+		fmt.Fprintf(os.Stderr, "Invalid HTTP TLS settings: --http-tls-cert and --http-tls-key must be set together\n")
+		os.Exit(1)
+	}
+	cfg.HTTP.TLSCertFile = httpTLSCert
+	cfg.HTTP.TLSKeyFile = httpTLSKey
+	cfg.HTTP.RedirectPort = httpRedirectPort
+	cfg.HTTP.Queries.TimeoutSeconds = httpQueryTimeoutSeconds
+	cfg.HTTP.Queries.RateLimit = httpRateLimit
+	cfg.HTTP.Queries.RateBurst = httpRateBurst
+	cfg.HTTP.Queries.MaxResults = httpMaxResults
+	cfg.HTTP.Queries.MaxConcurrent = httpMaxConcurrentQueries
+
+	if cfg.Cleanup.Interval <= 0 || cfg.Cleanup.EventPruneInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid cleanup intervals: must be positive, got %s and %s\n", cfg.Cleanup.Interval, cfg.Cleanup.EventPruneInterval)
+		os.Exit(1)
+	}
+
+	outputPath, err := graph.ParseOutput(cfg.GraphOutput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid graph output: %v\n", err)
+		os.Exit(1)
+	}
+
+	rules, err := config.ParseRetentionRules(retentionRules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid retention rules: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Retention.Rules = rules
+
+	teamRules, err := config.ParseTeamRules(teamNamespaces)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid team namespaces: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Teams.Label = teamLabel
+	cfg.Teams.Namespaces = teamRules
+	cfg.Promoted.Labels = splitList(promotedLabels)
+	cfg.Promoted.Annotations = splitList(promotedAnnotations)
+
+	// The ClusterRole is printed before anything is logged to stdout
+	if printClusterRole {
+		if err := kubernetes.WriteClusterRole(os.Stdout, "kubegraph", registry.Enabled(cfg), cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print the ClusterRole: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if printManifests {
+		err := manifests.Write(os.Stdout, manifests.Options{
+			Name:          "kubegraph",
+			Namespace:     manifestsNamespace,
+			Image:         manifestsImage,
+			Args:          manifestArgs(),
+			Neo4jUsername: neo4jUsername,
+			Neo4jPassword: neo4jPassword,
+			HTTPPort:      map[bool]int{true: httpPort}[httpEnabled],
+			Registrations: registry.Enabled(cfg),
+			Config:        cfg,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize logger
-	logger.SetLevel(logLevel)
+	logger.Init(logger.ParseLogLevel(logLevel))
 	logger.Info("Starting k8s-graph...")
 	logger.Info("Cluster: %s", clusterName)
-	logger.Info("Neo4j URI: %s", neo4jURI)
+	if neo4jURIFile != "" {
+		logger.Info("Neo4j URI file: %s", neo4jURIFile)
+	} else {
+		logger.Info("Neo4j URI: %s", neo4jURI)
+	}
+	if graphBackend != graph.BackendNeo4j {
+		logger.Info("Graph backend: %s", graphBackend)
+	}
+	if neo4jDatabase != "" {
+		logger.Info("Neo4j database: %s", neo4jDatabase)
+	}
+	if dryRun {
+		logger.Warn("Dry run: write statements are logged instead of executed")
+	}
+	if neo4jReadURI != "" || neo4jReadPoolSize > 0 {
+		logger.Info("Neo4j read pool: %s (size %d)", neo4jReadURI, neo4jReadPoolSize)
+	}
+	if neo4jBatchMaxSize > 0 {
+		logger.Info("Neo4j write batching: %d to %d writes, flushed after %dms", max(1, min(neo4jBatchMinSize, neo4jBatchMaxSize)), neo4jBatchMaxSize, neo4jBatchFlushIntervalMs)
+	}
+	if configFile != "" {
+		logger.Info("Config file: %s", configFile)
+	}
+	if len(cfg.Filters.Namespaces) > 0 {
+		logger.Info("Namespaces: %v", cfg.Filters.Namespaces)
+	}
+	if len(cfg.Filters.ExcludeNamespaces) > 0 {
+		logger.Info("Excluded namespaces: %v", cfg.Filters.ExcludeNamespaces)
+	}
 	logger.Info("Instance Hash: %s", cfg.InstanceHash)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Check the RBAC permissions instead of watching the cluster
+	if checkRBAC {
+		if !runRBACCheck(ctx, cfg) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Export traces when configured through the OTEL_ environment variables
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		logger.Error("Failed to initialize tracing: %v", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+	if tracing.Enabled() {
+		logger.Info("Tracing enabled, exporting spans over OTLP")
+	}
+
+	// Append the mutations to a file instead of connecting to Neo4j
+	if outputPath != "" {
+		if !runFileOutput(ctx, cfg, outputPath) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create Neo4j client
 	neo4jClient, err := neo4j.NewClient(cfg)
 	if err != nil {
@@ -160,6 +912,55 @@ func main() {
 
 	logger.Info("Connected to Neo4j database")
 
+	// Upgrade graphs written by earlier releases before any handler writes
+	if _, err := neo4jClient.Migrate(ctx); err != nil {
+		logger.Error("Failed to migrate the graph: %v", err)
+		neo4jClient.Close(ctx)
+		os.Exit(1)
+	}
+	var kinds []string
+	for _, registration := range registry.Enabled(cfg) {
+		kinds = append(kinds, registration.Kind)
+	}
+	if err := neo4jClient.CreatePromotedIndexes(ctx, kinds); err != nil {
+		logger.Warn("Failed to index the promoted labels and annotations: %v", err)
+	}
+
+	// Load enricher plugins and enable the selected enrichers
+	if err := enrich.LoadPlugins(cfg.Enrichment.Plugins); err != nil {
+		logger.Error("Failed to load enricher plugins: %v", err)
+		os.Exit(1)
+	}
+	if len(cfg.Enrichment.Enrichers) > 0 {
+		chain, err := enrich.New(cfg.Enrichment.Enrichers)
+		if err != nil {
+			logger.Error("Failed to create enrichers: %v", err)
+			os.Exit(1)
+		}
+		neo4jClient.SetEnrichers(chain)
+		logger.Info("Enrichers enabled: %v", cfg.Enrichment.Enrichers)
+	}
+
+	// Replay a recording through the handlers instead of watching the cluster
+	if cfg.Replay.Path != "" {
+		logger.Info("Replaying %s at speed %g", cfg.Replay.Path, cfg.Replay.Speed)
+		summary, err := replay.NewReplayer(cfg, neo4jClient).ReplayFile(ctx, cfg.Replay.Path)
+		if err != nil {
+			logger.Error("Replay failed: %v", err)
+		}
+		rate := 0.0
+		if seconds := summary.Duration.Seconds(); seconds > 0 {
+			rate = float64(summary.Events) / seconds
+		}
+		logger.Info("Replayed %d events in %v (%.1f events/s): %d applied, %d failed, %d skipped",
+			summary.Events, summary.Duration.Round(time.Millisecond), rate, summary.Applied, summary.Failed, summary.Skipped)
+		if err != nil {
+			neo4jClient.Close(ctx)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create Kubernetes client
 	kubernetesClient, err := kubernetes.NewClient(cfg)
 	if err != nil {
@@ -167,94 +968,176 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Create resource handlers for standard Kubernetes resources
-	var resourceHandlers []handlers.ResourceHandler
-	
-	// Core workload resources
-	resourceHandlers = append(resourceHandlers, handlers.NewPodHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewDeploymentHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewReplicaSetHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewDaemonSetHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewStatefulSetHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewJobHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewCronJobHandler(cfg))
-
-	// Services and networking
-	resourceHandlers = append(resourceHandlers, handlers.NewServiceHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewEndpointsHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewIngressHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewNetworkPolicyHandler(cfg))
-
-	// Configuration and storage
-	resourceHandlers = append(resourceHandlers, handlers.NewConfigMapHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewSecretHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewPVHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewPVCHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewStorageClassHandler(cfg))
-
-	// RBAC and policies
-	resourceHandlers = append(resourceHandlers, handlers.NewServiceAccountHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewLimitRangeHandler(cfg))
-
-	// Cluster resources
-	resourceHandlers = append(resourceHandlers, handlers.NewNodeHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewNamespaceHandler(cfg))
-
-	// Autoscaling
-	resourceHandlers = append(resourceHandlers, handlers.NewHPAHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewVPAHandler(cfg))
-	resourceHandlers = append(resourceHandlers, handlers.NewPDBHandler(cfg))
-
-	// Events (if enabled)
+	// Reload the config file on SIGHUP and when it changes
+	reloader := reload.NewReloader(configFile, reload.Settings{
+		LogLevel:          logger.GetLevel().String(),
+		DisabledHandlers:  cfg.Handlers.Disabled,
+		Namespaces:        cfg.Filters.Namespaces,
+		ExcludeNamespaces: cfg.Filters.ExcludeNamespaces,
+		EventTTLDays:      cfg.EventTTLDays,
+		HistoryDays:       cfg.History.RetentionDays,
+		AuditDays:         cfg.Audit.TTLDays,
+	}, given, func(ctx context.Context, previous, next reload.Settings) error {
+		var errs []error
+		logger.SetLevel(logger.ParseLogLevel(next.LogLevel))
+		if fmt.Sprint(previous.DisabledHandlers) != fmt.Sprint(next.DisabledHandlers) {
+			errs = append(errs, kubernetesClient.SetDisabledHandlers(ctx, next.DisabledHandlers))
+		}
+		if fmt.Sprint(previous.Namespaces, previous.ExcludeNamespaces) != fmt.Sprint(next.Namespaces, next.ExcludeNamespaces) {
+			kubernetesClient.SetNamespaceFilter(next.Namespaces, next.ExcludeNamespaces)
+		}
+		// The cleanup loop reads the TTLs from the reloader
+		return errors.Join(errs...)
+	})
+	go reloader.Run(ctx, time.Duration(cfg.Reload.IntervalSeconds)*time.Second)
+
+	// The Kubernetes client registers the resource handlers
 	if cfg.EventTTLDays > 0 {
-		resourceHandlers = append(resourceHandlers, handlers.NewEventHandler(cfg))
 		logger.Info("Event monitoring enabled (TTL: %d days)", cfg.EventTTLDays)
 	} else {
 		logger.Info("Event monitoring disabled")
 	}
 
-	logger.Info("Registered %d resource handlers", len(resourceHandlers))
-
-	// Start background cleanup process
+	// Start background cleanup process. The prunes run once at startup instead
+	// of waiting for the first tick, while the rest of the cleanup needs the
+	// graph written by the initial sync.
+	prune := func() {
+		settings := reloader.Settings()
+		// Prune superseded resource versions if history mode is enabled
+		if cfg.History.Enabled {
+			if err := neo4jClient.PruneHistory(ctx, settings.HistoryDays, cfg.Retention.Rules); err != nil {
+				logger.Error("[HISTORY PRUNE] Failed to prune resource versions: %v", err)
+			} else {
+				logger.Debug("[HISTORY PRUNE] Resource versions pruned (retention=%d days)", settings.HistoryDays)
+			}
+		}
+		// Prune expired audit records if the audit trail is enabled
+		if cfg.Audit.Enabled {
+			if err := neo4jClient.PruneGraphChanges(ctx, settings.AuditDays); err != nil {
+				logger.Error("[AUDIT PRUNE] Failed to prune graph changes: %v", err)
+			} else {
+				logger.Debug("[AUDIT PRUNE] Expired graph changes pruned (TTL=%d days)", settings.AuditDays)
+			}
+		}
+		// Prune old generations of the kinds with retention rules
+		if err := neo4jClient.PruneGenerations(ctx, cfg.Retention.Rules); err != nil {
+			logger.Error("[RETENTION] Failed to prune old generations: %v", err)
+		}
+	}
+	cleanup := func() {
+		// Clean up duplicate clusters with same name but different hashes. Every
+		// start has a new instance hash, so the nodes written before the restart
+		// are only stale once the initial sync has written them again.
+		if pending := kubernetesClient.InitialSyncPending(); len(pending) > 0 {
+			logger.Debug("[CLEANUP] Initial sync of %v not complete, skipping duplicate cluster cleanup", pending)
+		} else if err := neo4jClient.CleanupDuplicateClusters(ctx, cfg.Kubernetes.ClusterName, cfg.InstanceHash); err != nil {
+			logger.Error("[CLEANUP] Failed to cleanup duplicate clusters: %v", err)
+		} else {
+			logger.Debug("[CLEANUP] Duplicate cluster cleanup completed")
+		}
+		// Resolve NetworkPolicy relationships again so they follow pod and namespace changes
+		if err := handlers.ResolveNetworkPolicies(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[NETWORK POLICY] Failed to resolve network policies: %v", err)
+		} else {
+			logger.Debug("[NETWORK POLICY] Network policy relationships resolved")
+		}
+		// Resolve pod node constraints again so they follow node additions and label changes
+		if err := handlers.ResolveNodeConstraints(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[SCHEDULING] Failed to resolve node constraints: %v", err)
+		} else {
+			logger.Debug("[SCHEDULING] Node constraint relationships resolved")
+		}
+		// Resolve pod tolerations again so they follow nodes being tainted and untainted
+		if err := handlers.ResolveTolerations(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[SCHEDULING] Failed to resolve tolerations: %v", err)
+		} else {
+			logger.Debug("[SCHEDULING] Toleration relationships resolved")
+		}
+		// Link GitOps applications to the resources created since they last changed
+		if err := handlers.ResolveGitOpsApplications(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[GITOPS] Failed to resolve GitOps applications: %v", err)
+		} else {
+			logger.Debug("[GITOPS] GitOps application relationships resolved")
+		}
+		// Link monitors to the Services and Pods created or relabeled since they last changed
+		if err := handlers.ResolveMonitors(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[MONITORS] Failed to resolve monitors: %v", err)
+		} else {
+			logger.Debug("[MONITORS] Monitor relationships resolved")
+		}
+		// Link backups to the namespaces and workloads created or relabeled since they last changed
+		if err := handlers.ResolveBackups(ctx, neo4jClient, cfg.Kubernetes.ClusterName); err != nil {
+			logger.Error("[BACKUPS] Failed to resolve backups: %v", err)
+		} else {
+			logger.Debug("[BACKUPS] Backup relationships resolved")
+		}
+		prune()
+	}
+	pruneEvents := func() {
+		settings := reloader.Settings()
+		if settings.EventTTLDays > 0 {
+			err := handlers.PruneExpiredEvents(ctx, neo4jClient, settings.EventTTLDays)
+			if err != nil {
+				logger.Error("[EVENT PRUNE] Failed to prune expired events: %v", err)
+			} else {
+				logger.Debug("[EVENT PRUNE] Expired events pruned (TTL=%d days)", settings.EventTTLDays)
+			}
+		}
+	}
 	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
+		cleanupTicker := time.NewTicker(cfg.Cleanup.Interval)
+		defer cleanupTicker.Stop()
+		eventPruneTicker := time.NewTicker(cfg.Cleanup.EventPruneInterval)
+		defer eventPruneTicker.Stop()
+
+		prune()
+		pruneEvents()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				// Clean up duplicate clusters with same name but different hashes
-				if err := neo4jClient.CleanupDuplicateClusters(ctx, cfg.Kubernetes.ClusterName, cfg.InstanceHash); err != nil {
-					logger.Error("[CLEANUP] Failed to cleanup duplicate clusters: %v", err)
-				} else {
-					logger.Debug("[CLEANUP] Duplicate cluster cleanup completed")
-				}
-				// Prune expired events if enabled
-				if cfg.EventTTLDays > 0 {
-					err := handlers.PruneExpiredEvents(ctx, neo4jClient, cfg.EventTTLDays)
-					if err != nil {
-						logger.Error("[EVENT PRUNE] Failed to prune expired events: %v", err)
-					} else {
-						logger.Debug("[EVENT PRUNE] Expired events pruned (TTL=%d days)", cfg.EventTTLDays)
-					}
-				}
+			case <-cleanupTicker.C:
+				cleanup()
+			case <-eventPruneTicker.C:
+				pruneEvents()
 			}
 		}
 	}()
 
-	// Start watching resources
-	err = kubernetesClient.StartWatching(ctx, resourceHandlers, neo4jClient)
-	if err != nil {
-		logger.Error("Failed to start watching resources: %v", err)
-		os.Exit(1)
+	// Start watching resources. Watching runs until shutdown, so the HTTP
+	// server and the background loops below are started meanwhile.
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- kubernetesClient.StartWatching(ctx, neo4jClient)
+	}()
+
+	// Start anomaly detection if enabled
+	if cfg.Anomaly.Enabled {
+		go anomaly.NewDetector(cfg, neo4jClient).Start(ctx)
+		logger.Info("Anomaly detection enabled (interval: %ds, threshold: %.1f)", cfg.Anomaly.IntervalSeconds, cfg.Anomaly.Threshold)
+	}
+
+	// Start event correlation if enabled; it needs the events stored by the Event handler
+	if cfg.EventCorrelation.Enabled {
+		if cfg.EventTTLDays <= 0 {
+			logger.Warn("Event correlation enabled but event handling is disabled (--event-ttl-days 0), no incidents will be found")
+		}
+		go incidents.NewCorrelator(cfg, neo4jClient).Start(ctx)
+		logger.Info("Event correlation enabled (interval: %ds, window: %dm)", cfg.EventCorrelation.IntervalSeconds, cfg.EventCorrelation.WindowMinutes)
+	}
+
+	// Start metrics-server usage polling if enabled
+	if cfg.Usage.Enabled {
+		go usage.NewPoller(cfg, kubernetesClient.DynamicClient(), neo4jClient).Start(ctx)
+		logger.Info("Usage polling enabled (interval: %ds)", cfg.Usage.IntervalSeconds)
 	}
 
 	// Start HTTP server if enabled
 	if cfg.HTTP.Enabled {
-		server := httpserver.NewServer(cfg)
+		server := httpserver.NewServer(cfg, kubernetesClient, neo4jClient)
+		server.SetReloader(reloader)
 		go func() {
-			if err := server.Start(); err != nil {
+			if err := server.Start(ctx); err != nil {
 				logger.Error("HTTP server error: %v", err)
 			}
 		}()
@@ -267,11 +1150,19 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	<-sigChan
+	select {
+	case <-sigChan:
+	case err := <-watchErr:
+		if err != nil {
+			logger.Error("Failed to start watching resources: %v", err)
+			cancel()
+			os.Exit(1)
+		}
+	}
 	logger.Info("Shutting down...")
 	cancel()
 
 	// Give some time for cleanup
 	time.Sleep(2 * time.Second)
 	logger.Info("Shutdown complete")
-}
\ No newline at end of file
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -162,3 +163,20 @@ func TestGetEnvIntEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", nil},
+		{"cmdb", []string{"cmdb"}},
+		{" cmdb , billing,, ", []string{"cmdb", "billing"}},
+	}
+
+	for _, test := range tests {
+		if result := splitList(test.value); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Expected splitList(%q) to return %v, got %v", test.value, test.expected, result)
+		}
+	}
+}
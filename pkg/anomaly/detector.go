@@ -0,0 +1,370 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+
+	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// MetricRestarts is the number of container restarts across a workload's pods per interval
+	MetricRestarts = "restarts"
+	// MetricWarningEvents is the number of Warning events involving a workload per interval
+	MetricWarningEvents = "warningEvents"
+
+	// smoothing is the weight of the newest sample in the rolling baseline
+	smoothing = 0.3
+	// minStdDev keeps perfectly flat baselines from flagging every small blip
+	minStdDev = 1.0
+)
+
+var anomaliesDetectedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_anomalies_detected_total",
+		Help: "Total number of workload anomalies detected, by metric",
+	},
+	[]string{"metric"},
+)
+
+// Baseline is an exponentially weighted rolling mean and variance of a workload metric
+type Baseline struct {
+	Mean     float64
+	Variance float64
+	Samples  int
+	Last     float64 // Last raw value, used to turn cumulative counters into per-interval deltas
+}
+
+// Update folds a new per-interval value into the baseline
+func (b *Baseline) Update(value float64) {
+	if b.Samples == 0 {
+		b.Mean = value
+		b.Variance = 0
+		b.Samples = 1
+		return
+	}
+	diff := value - b.Mean
+	increment := smoothing * diff
+	b.Mean += increment
+	b.Variance = (1 - smoothing) * (b.Variance + diff*increment)
+	b.Samples++
+}
+
+// StdDev returns the baseline's standard deviation, never lower than minStdDev
+func (b Baseline) StdDev() float64 {
+	return math.Max(math.Sqrt(b.Variance), minStdDev)
+}
+
+// Score returns how many standard deviations value lies above the baseline
+func (b Baseline) Score(value float64) float64 {
+	return (value - b.Mean) / b.StdDev()
+}
+
+// Sample is a single observation of a metric for a workload
+type Sample struct {
+	Kind      string
+	UID       string
+	Namespace string
+	Name      string
+	Value     float64
+}
+
+// Detector periodically samples per-workload restart counts and warning event
+// rates, keeps rolling baselines for them in the graph and records deviations
+// as Anomaly nodes linked to the workload
+type Detector struct {
+	config      *config.Config
+	neo4jClient *neo4j.Client
+}
+
+// NewDetector creates a new anomaly detector
+func NewDetector(cfg *config.Config, neo4jClient *neo4j.Client) *Detector {
+	return &Detector{
+		config:      cfg,
+		neo4jClient: neo4jClient,
+	}
+}
+
+// Start runs the detector every configured interval until ctx is done
+func (d *Detector) Start(ctx context.Context) {
+	interval := time.Duration(d.config.Anomaly.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		logger.Warn("[ANOMALY] Invalid interval %v, anomaly detection not started", interval)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Run(ctx); err != nil {
+				logger.Error("[ANOMALY] Detection run failed: %v", err)
+			}
+		}
+	}
+}
+
+// Run samples all workloads once, updates their baselines and records anomalies
+func (d *Detector) Run(ctx context.Context) error {
+	baselines, err := d.loadBaselines(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load baselines: %w", err)
+	}
+
+	now := time.Now().UTC()
+	found := 0
+	for _, metric := range []string{MetricRestarts, MetricWarningEvents} {
+		samples, err := d.collect(ctx, metric, now)
+		if err != nil {
+			return fmt.Errorf("failed to collect %s samples: %w", metric, err)
+		}
+
+		for _, sample := range samples {
+			key := fmt.Sprintf("%s/%s/%s", d.config.Kubernetes.ClusterName, metric, sample.UID)
+			baseline, seen := baselines[key]
+			if !seen {
+				baseline = &Baseline{}
+			}
+
+			value, ok := intervalValue(metric, baseline, seen, sample.Value)
+			if ok {
+				if baseline.Samples >= d.config.Anomaly.MinSamples && isAnomalous(*baseline, value, d.config.Anomaly.Threshold) {
+					if err := d.recordAnomaly(ctx, metric, sample, *baseline, value, now); err != nil {
+						logger.Error("[ANOMALY] Failed to record anomaly for %s %s/%s: %v", sample.Kind, sample.Namespace, sample.Name, err)
+					} else {
+						found++
+					}
+				}
+				baseline.Update(value)
+			}
+			baseline.Last = sample.Value
+
+			if err := d.saveBaseline(ctx, key, metric, sample, *baseline, now); err != nil {
+				logger.Error("[ANOMALY] Failed to save baseline %s: %v", key, err)
+			}
+		}
+	}
+
+	if err := d.prune(ctx, now); err != nil {
+		logger.Error("[ANOMALY] Failed to prune old anomalies: %v", err)
+	}
+
+	logger.Debug("[ANOMALY] Detection run completed, %d anomalies found", found)
+	return nil
+}
+
+// intervalValue turns a raw sample into the per-interval value the baseline
+// tracks. Restart counts are cumulative, so the first observation only primes
+// the baseline and a drop (pods replaced) restarts the count from the new total.
+func intervalValue(metric string, baseline *Baseline, seen bool, raw float64) (float64, bool) {
+	if metric != MetricRestarts {
+		return raw, true
+	}
+	if !seen {
+		return 0, false
+	}
+	if raw < baseline.Last {
+		return raw, true
+	}
+	return raw - baseline.Last, true
+}
+
+// isAnomalous reports whether value deviates from the baseline by more than threshold standard deviations
+func isAnomalous(baseline Baseline, value, threshold float64) bool {
+	return value > 0 && baseline.Score(value) > threshold
+}
+
+// collect returns the current value of metric for every workload in the cluster.
+// Pods owned by a ReplicaSet are attributed to its Deployment.
+func (d *Detector) collect(ctx context.Context, metric string, now time.Time) ([]Sample, error) {
+	var query string
+	params := map[string]interface{}{
+		"clusterName":  d.config.Kubernetes.ClusterName,
+		"instanceHash": d.config.InstanceHash,
+	}
+
+	switch metric {
+	case MetricRestarts:
+		query = `
+			MATCH (p:Pod {clusterName: $clusterName, instanceHash: $instanceHash})-[:OWNED_BY]->(o)
+			OPTIONAL MATCH (o)-[:OWNED_BY]->(d:Deployment)
+			WITH coalesce(d, o) AS w, sum(coalesce(p.restartCount, 0)) AS value
+			RETURN labels(w)[0] AS kind, w.uid AS uid, w.namespace AS namespace, w.name AS name, value`
+	case MetricWarningEvents:
+		query = `
+			MATCH (e:Event {clusterName: $clusterName, type: 'Warning'})-[:INVOLVES]->(x)
+			WHERE e.createdAt >= $since
+			OPTIONAL MATCH (x)-[:OWNED_BY]->(o)
+			OPTIONAL MATCH (o)-[:OWNED_BY]->(d:Deployment)
+			WITH coalesce(d, o, x) AS w, count(e) AS value
+			WHERE NOT w:Node AND NOT w:Namespace
+			RETURN labels(w)[0] AS kind, w.uid AS uid, w.namespace AS namespace, w.name AS name, value`
+		since := now.Add(-time.Duration(d.config.Anomaly.IntervalSeconds) * time.Second)
+		params["since"] = since.Format(time.RFC3339)
+	default:
+		return nil, fmt.Errorf("unknown metric %s", metric)
+	}
+
+	session := d.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]Sample, 0)
+	for result.Next(ctx) {
+		record := result.Record()
+		uid, _ := record.Values[1].(string)
+		if uid == "" {
+			continue
+		}
+		kind, _ := record.Values[0].(string)
+		namespace, _ := record.Values[2].(string)
+		name, _ := record.Values[3].(string)
+		value, _ := record.Values[4].(int64)
+		samples = append(samples, Sample{
+			Kind:      kind,
+			UID:       uid,
+			Namespace: namespace,
+			Name:      name,
+			Value:     float64(value),
+		})
+	}
+	return samples, result.Err()
+}
+
+// loadBaselines reads the stored baselines for this cluster, keyed by baseline key
+func (d *Detector) loadBaselines(ctx context.Context) (map[string]*Baseline, error) {
+	query := `
+		MATCH (b:AnomalyBaseline {clusterName: $clusterName})
+		RETURN b.key AS key, b.mean AS mean, b.variance AS variance, b.samples AS samples, b.last AS last`
+
+	session := d.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, map[string]interface{}{
+		"clusterName": d.config.Kubernetes.ClusterName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baselines := make(map[string]*Baseline)
+	for result.Next(ctx) {
+		record := result.Record()
+		key, _ := record.Values[0].(string)
+		mean, _ := record.Values[1].(float64)
+		variance, _ := record.Values[2].(float64)
+		samples, _ := record.Values[3].(int64)
+		last, _ := record.Values[4].(float64)
+		baselines[key] = &Baseline{
+			Mean:     mean,
+			Variance: variance,
+			Samples:  int(samples),
+			Last:     last,
+		}
+	}
+	return baselines, result.Err()
+}
+
+// saveBaseline stores a workload's baseline so it survives restarts
+func (d *Detector) saveBaseline(ctx context.Context, key, metric string, sample Sample, baseline Baseline, now time.Time) error {
+	properties := map[string]interface{}{
+		"key":          key,
+		"metric":       metric,
+		"workloadKind": sample.Kind,
+		"workloadUid":  sample.UID,
+		"namespace":    sample.Namespace,
+		"name":         sample.Name,
+		"mean":         baseline.Mean,
+		"variance":     baseline.Variance,
+		"samples":      baseline.Samples,
+		"last":         baseline.Last,
+		"updatedAt":    now.Format(time.RFC3339),
+		"clusterName":  d.config.Kubernetes.ClusterName,
+		// Baselines should not have instanceHash as they should persist across restarts
+	}
+	return d.neo4jClient.UpsertNode(ctx, []string{"AnomalyBaseline"}, properties, "key")
+}
+
+// recordAnomaly creates an Anomaly node linked to the workload and raises an alert
+func (d *Detector) recordAnomaly(ctx context.Context, metric string, sample Sample, baseline Baseline, value float64, now time.Time) error {
+	detectedAt := now.Format(time.RFC3339)
+	uid := fmt.Sprintf("%s/%s/%s/%s", d.config.Kubernetes.ClusterName, metric, sample.UID, detectedAt)
+	score := baseline.Score(value)
+
+	properties := map[string]interface{}{
+		"uid":          uid,
+		"metric":       metric,
+		"value":        value,
+		"baseline":     baseline.Mean,
+		"stdDev":       baseline.StdDev(),
+		"score":        score,
+		"workloadKind": sample.Kind,
+		"workloadUid":  sample.UID,
+		"namespace":    sample.Namespace,
+		"name":         sample.Name,
+		"detectedAt":   detectedAt,
+		"clusterName":  d.config.Kubernetes.ClusterName,
+		// Anomalies should not have instanceHash as they should persist across restarts
+	}
+	if err := d.neo4jClient.UpsertNode(ctx, []string{"Anomaly"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert anomaly: %w", err)
+	}
+
+	if sample.Kind != "" {
+		err := d.neo4jClient.CreateRelationship(
+			ctx,
+			"Anomaly", "uid", uid,
+			"DETECTED_ON",
+			sample.Kind, "uid", sample.UID,
+		)
+		if err != nil {
+			logger.Warn("[ANOMALY] Failed to create DETECTED_ON relationship for Anomaly %s: %v", uid, err)
+		}
+	}
+
+	anomaliesDetectedTotal.WithLabelValues(metric).Inc()
+	logger.Warn("[ANOMALY] %s %s/%s: %s is %.0f per interval (baseline %.1f, %.1f standard deviations above)",
+		sample.Kind, sample.Namespace, sample.Name, metric, value, baseline.Mean, score)
+	return nil
+}
+
+// prune deletes anomalies and baselines that are older than the retention period
+func (d *Detector) prune(ctx context.Context, now time.Time) error {
+	if d.config.Anomaly.RetentionDays <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-time.Duration(d.config.Anomaly.RetentionDays) * 24 * time.Hour).Format(time.RFC3339)
+	queries := []string{
+		`MATCH (a:Anomaly {clusterName: $clusterName}) WHERE a.detectedAt < $cutoff DETACH DELETE a`,
+		`MATCH (b:AnomalyBaseline {clusterName: $clusterName}) WHERE b.updatedAt < $cutoff DETACH DELETE b`,
+	}
+	params := map[string]interface{}{
+		"clusterName": d.config.Kubernetes.ClusterName,
+		"cutoff":      cutoff,
+	}
+
+	session := d.neo4jClient.NewSession(ctx, driverneo4j.AccessModeWrite)
+	defer session.Close(ctx)
+
+	for _, query := range queries {
+		if _, err := session.Run(ctx, query, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBaselineUpdate(t *testing.T) {
+	b := &Baseline{}
+	for i := 0; i < 20; i++ {
+		b.Update(2)
+	}
+
+	if b.Samples != 20 {
+		t.Errorf("Expected 20 samples, got %d", b.Samples)
+	}
+	if math.Abs(b.Mean-2) > 0.001 {
+		t.Errorf("Expected mean 2, got %f", b.Mean)
+	}
+	if b.StdDev() != minStdDev {
+		t.Errorf("Expected flat baseline to use minimum stddev %f, got %f", minStdDev, b.StdDev())
+	}
+}
+
+func TestIsAnomalous(t *testing.T) {
+	b := Baseline{Mean: 2, Variance: 1, Samples: 10}
+
+	tests := []struct {
+		name     string
+		value    float64
+		expected bool
+	}{
+		{"at baseline", 2, false},
+		{"within threshold", 4, false},
+		{"spike", 10, true},
+		{"zero", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isAnomalous(b, test.value, 3.0); got != test.expected {
+				t.Errorf("Expected isAnomalous(%v) to be %v, got %v", test.value, test.expected, got)
+			}
+		})
+	}
+}
+
+func TestIntervalValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		metric   string
+		last     float64
+		seen     bool
+		raw      float64
+		expected float64
+		ok       bool
+	}{
+		{"warning events are used as is", MetricWarningEvents, 0, false, 5, 5, true},
+		{"first restart sample primes baseline", MetricRestarts, 0, false, 7, 0, false},
+		{"restart delta", MetricRestarts, 7, true, 10, 3, true},
+		{"restart counter reset", MetricRestarts, 7, true, 2, 2, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, ok := intervalValue(test.metric, &Baseline{Last: test.last}, test.seen, test.raw)
+			if ok != test.ok {
+				t.Fatalf("Expected ok %v, got %v", test.ok, ok)
+			}
+			if ok && value != test.expected {
+				t.Errorf("Expected value %v, got %v", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var enrichmentErrorsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_enrichment_errors_total",
+		Help: "Total number of enrichments that failed, by enricher",
+	},
+	[]string{"enricher"},
+)
+
+// identifier matches the labels and relationship types that can be used in a query
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Resource is a node a handler is about to write. Properties hold the values
+// extracted by the handler before they are converted for storage, so labels
+// and annotations are still maps.
+type Resource struct {
+	Labels     []string
+	Properties map[string]interface{}
+}
+
+// Kind returns the primary label of the node
+func (r *Resource) Kind() string {
+	if len(r.Labels) == 0 {
+		return ""
+	}
+	return r.Labels[0]
+}
+
+// Relationship is a relationship from an enriched node to another node. The
+// target is matched by TargetKey, and created with TargetProperties when they
+// are set.
+type Relationship struct {
+	Type             string
+	TargetLabel      string
+	TargetKey        string
+	TargetValue      interface{}
+	TargetProperties map[string]interface{}
+}
+
+// Enricher adds or modifies the properties of nodes written by the handlers
+// and links them to other nodes. Enrich is called for every node write, so it
+// should answer from memory rather than call out to external systems.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, resource *Resource) ([]Relationship, error)
+}
+
+// Factory creates an enricher. Enrichers read their own settings, typically
+// from environment variables.
+type Factory func() (Enricher, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes an enricher available under a name. It is meant to be called
+// from the init function of the package providing the enricher, whether it is
+// compiled in or loaded as a Go plugin.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("enrich: enricher %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of the registered enrichers
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Result is what one enricher returned for a node
+type Result struct {
+	Enricher      string
+	Relationships []Relationship
+}
+
+// Chain runs enrichers in order
+type Chain struct {
+	enrichers []Enricher
+}
+
+// New creates the enrichers with the given names, in that order
+func New(names []string) (*Chain, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	chain := &Chain{}
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown enricher %q (registered: %v)", name, Registered())
+		}
+		enricher, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create enricher %q: %w", name, err)
+		}
+		chain.enrichers = append(chain.enrichers, enricher)
+	}
+	return chain, nil
+}
+
+// NewChain creates a chain of already created enrichers
+func NewChain(enrichers ...Enricher) *Chain {
+	return &Chain{enrichers: enrichers}
+}
+
+// Len returns the number of enrichers in the chain
+func (c *Chain) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.enrichers)
+}
+
+// Apply runs every enricher on the resource. Each enricher works on a copy of
+// the properties, which replaces the properties of the resource only when it
+// succeeds, so a failing enricher leaves no partial changes behind.
+func (c *Chain) Apply(ctx context.Context, resource *Resource) []Result {
+	results := make([]Result, 0, c.Len())
+	if c == nil {
+		return results
+	}
+	for _, enricher := range c.enrichers {
+		candidate := &Resource{Labels: resource.Labels, Properties: copyProperties(resource.Properties)}
+		relationships, err := runEnricher(ctx, enricher, candidate)
+		if err != nil {
+			enrichmentErrorsTotal.WithLabelValues(enricher.Name()).Inc()
+			logger.Warn("Enricher %s failed for %s %v: %v", enricher.Name(), resource.Kind(), resource.Properties["name"], err)
+			continue
+		}
+		resource.Properties = candidate.Properties
+		results = append(results, Result{Enricher: enricher.Name(), Relationships: validRelationships(enricher.Name(), relationships)})
+	}
+	return results
+}
+
+// runEnricher calls an enricher and turns a panic into an error, so a faulty
+// plugin cannot take down the sync
+func runEnricher(ctx context.Context, enricher Enricher, resource *Resource) (relationships []Relationship, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return enricher.Enrich(ctx, resource)
+}
+
+// validRelationships drops the relationships whose type or labels cannot be
+// used in a query
+func validRelationships(enricher string, relationships []Relationship) []Relationship {
+	valid := make([]Relationship, 0, len(relationships))
+	for _, relationship := range relationships {
+		if !identifier.MatchString(relationship.Type) || !identifier.MatchString(relationship.TargetLabel) || !identifier.MatchString(relationship.TargetKey) {
+			logger.Warn("Enricher %s returned an invalid relationship %s to %s {%s}", enricher, relationship.Type, relationship.TargetLabel, relationship.TargetKey)
+			continue
+		}
+		valid = append(valid, relationship)
+	}
+	return valid
+}
+
+func copyProperties(properties map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		copied[key] = value
+	}
+	return copied
+}
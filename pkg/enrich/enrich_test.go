@@ -0,0 +1,109 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/logger"
+)
+
+type costCenterEnricher struct{}
+
+func (costCenterEnricher) Name() string { return "cost-center" }
+
+func (costCenterEnricher) Enrich(ctx context.Context, resource *Resource) ([]Relationship, error) {
+	labels, _ := resource.Properties["labels"].(map[string]string)
+	team := labels["team"]
+	if team == "" {
+		return nil, nil
+	}
+	resource.Properties["costCenter"] = "cc-" + team
+	return []Relationship{{
+		Type:             "BILLED_TO",
+		TargetLabel:      "CostCenter",
+		TargetKey:        "name",
+		TargetValue:      "cc-" + team,
+		TargetProperties: map[string]interface{}{"team": team},
+	}}, nil
+}
+
+type failingEnricher struct{ panics bool }
+
+func (failingEnricher) Name() string { return "failing" }
+
+func (e failingEnricher) Enrich(ctx context.Context, resource *Resource) ([]Relationship, error) {
+	resource.Properties["partial"] = true
+	if e.panics {
+		panic("boom")
+	}
+	return nil, errors.New("lookup failed")
+}
+
+type invalidEnricher struct{}
+
+func (invalidEnricher) Name() string { return "invalid" }
+
+func (invalidEnricher) Enrich(ctx context.Context, resource *Resource) ([]Relationship, error) {
+	return []Relationship{
+		{Type: "OWNED_BY", TargetLabel: "Team", TargetKey: "name", TargetValue: "payments"},
+		{Type: "X]->() DETACH DELETE (n", TargetLabel: "Team", TargetKey: "name", TargetValue: "payments"},
+	}, nil
+}
+
+func testResource() *Resource {
+	logger.Init(logger.ERROR)
+
+	return &Resource{
+		Labels:     []string{"Deployment"},
+		Properties: map[string]interface{}{"name": "api", "labels": map[string]string{"team": "payments"}},
+	}
+}
+
+func TestChainApply(t *testing.T) {
+	resource := testResource()
+	results := NewChain(failingEnricher{}, costCenterEnricher{}, failingEnricher{panics: true}).Apply(context.Background(), resource)
+
+	if len(results) != 1 || results[0].Enricher != "cost-center" || len(results[0].Relationships) != 1 {
+		t.Fatalf("Expected one result from the cost-center enricher, got %+v", results)
+	}
+	if resource.Properties["costCenter"] != "cc-payments" {
+		t.Errorf("Expected costCenter to be set, got %v", resource.Properties["costCenter"])
+	}
+	if _, ok := resource.Properties["partial"]; ok {
+		t.Error("Expected changes of failing enrichers to be discarded")
+	}
+}
+
+func TestChainApplyDropsInvalidRelationships(t *testing.T) {
+	results := NewChain(invalidEnricher{}).Apply(context.Background(), testResource())
+	expected := []Relationship{{Type: "OWNED_BY", TargetLabel: "Team", TargetKey: "name", TargetValue: "payments"}}
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Relationships, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, results)
+	}
+}
+
+func TestNewUsesRegistry(t *testing.T) {
+	Register("test-cost-center", func() (Enricher, error) { return costCenterEnricher{}, nil })
+	Register("test-broken", func() (Enricher, error) { return nil, errors.New("missing settings") })
+
+	chain, err := New([]string{"test-cost-center"})
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	if chain.Len() != 1 {
+		t.Errorf("Expected 1 enricher, got %d", chain.Len())
+	}
+	if _, err := New([]string{"test-missing"}); err == nil {
+		t.Error("Expected an error for an unknown enricher")
+	}
+	if _, err := New([]string{"test-broken"}); err == nil {
+		t.Error("Expected an error for an enricher failing to start")
+	}
+
+	var empty *Chain
+	if empty.Len() != 0 || len(empty.Apply(context.Background(), testResource())) != 0 {
+		t.Error("Expected a nil chain to do nothing")
+	}
+}
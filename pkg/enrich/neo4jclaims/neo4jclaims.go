@@ -0,0 +1,64 @@
+// Package neo4jclaims provides the neo4j-statefulset-claims enricher, which
+// links the PVCs of the Neo4j operator to their StatefulSets by name. The
+// operator creates a StatefulSet per server, named p-<id>-<server>, whose
+// claim is named data-p-<id>-<server>-<volume>, where <id> may itself contain
+// a dash.
+package neo4jclaims
+
+import (
+	"context"
+	"strings"
+
+	"k8s-graph/pkg/enrich"
+)
+
+// Name is the name the enricher is registered under
+const Name = "neo4j-statefulset-claims"
+
+func init() {
+	enrich.Register(Name, func() (enrich.Enricher, error) {
+		return &enricher{}, nil
+	})
+}
+
+type enricher struct{}
+
+func (e *enricher) Name() string { return Name }
+
+// Enrich links a PVC named data-p-e1229598-0001-0 to the StatefulSet
+// p-e1229598-0001, and data-p-e1229598-12f1-0001-0 to p-e1229598-12f1-0001.
+// StatefulSets are matched by name only, in any namespace.
+func (e *enricher) Enrich(ctx context.Context, resource *enrich.Resource) ([]enrich.Relationship, error) {
+	if resource.Kind() != "PersistentVolumeClaim" {
+		return nil, nil
+	}
+	name, _ := resource.Properties["name"].(string)
+	statefulSet := StatefulSetName(name)
+	if statefulSet == "" {
+		return nil, nil
+	}
+	return []enrich.Relationship{{
+		Type:        "USED_BY",
+		TargetLabel: "StatefulSet",
+		TargetKey:   "name",
+		TargetValue: statefulSet,
+	}}, nil
+}
+
+// StatefulSetName returns the name of the StatefulSet of a claim of the Neo4j
+// operator, or "" when the claim does not follow its naming
+func StatefulSetName(claim string) string {
+	if !strings.HasPrefix(claim, "data-p-") {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(claim, "data-"), "-")
+	switch {
+	case len(parts) == 4:
+		// p-<id>-<server>-<volume>
+		return strings.Join(parts[:3], "-")
+	case len(parts) >= 5:
+		// p-<id>-<id suffix>-<server>-<volume>
+		return strings.Join(parts[:4], "-")
+	}
+	return ""
+}
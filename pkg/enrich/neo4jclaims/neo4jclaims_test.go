@@ -0,0 +1,40 @@
+package neo4jclaims
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/pkg/enrich"
+)
+
+func TestStatefulSetName(t *testing.T) {
+	tests := map[string]string{
+		"data-p-e1229598-0001-0":      "p-e1229598-0001",
+		"data-p-e1229598-12f1-0001-0": "p-e1229598-12f1-0001",
+		"data-p-e1229598-0":           "",
+		"data-web-0":                  "",
+	}
+	for claim, expected := range tests {
+		if name := StatefulSetName(claim); name != expected {
+			t.Errorf("StatefulSetName(%q) = %q, expected %q", claim, name, expected)
+		}
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	chain, err := enrich.New([]string{Name})
+	if err != nil {
+		t.Fatalf("Failed to create the enricher: %v", err)
+	}
+
+	claim := &enrich.Resource{Labels: []string{"PersistentVolumeClaim"}, Properties: map[string]interface{}{"name": "data-p-e1229598-0001-0"}}
+	results := chain.Apply(context.Background(), claim)
+	if len(results) != 1 || len(results[0].Relationships) != 1 || results[0].Relationships[0].TargetValue != "p-e1229598-0001" {
+		t.Errorf("Expected the claim to be linked to its StatefulSet, got %+v", results)
+	}
+
+	pod := &enrich.Resource{Labels: []string{"Pod"}, Properties: map[string]interface{}{"name": "data-p-e1229598-0001-0"}}
+	if results := chain.Apply(context.Background(), pod); len(results[0].Relationships) != 0 {
+		t.Errorf("Expected only claims to be linked, got %+v", results)
+	}
+}
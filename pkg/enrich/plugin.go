@@ -0,0 +1,21 @@
+package enrich
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugins opens Go plugins providing enrichers. A plugin registers its
+// enrichers from its init function, which runs when the plugin is opened, so
+// they can be selected by name like compiled-in enrichers afterwards.
+//
+// Plugins must be built with the same Go version and the same versions of the
+// packages they share with k8s-graph, including this one.
+func LoadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("failed to load enricher plugin %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package failure
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Category is the cause of a failure to process a resource
+type Category string
+
+const (
+	// ConversionError means the object could not be converted to its typed form
+	ConversionError Category = "ConversionError"
+	// TargetMissing means a resource the handler depends on does not exist
+	TargetMissing Category = "TargetMissing"
+	// Neo4jTransient means Neo4j failed in a way that may succeed on retry
+	Neo4jTransient Category = "Neo4jTransient"
+	// Neo4jFatal means Neo4j rejected the operation
+	Neo4jFatal Category = "Neo4jFatal"
+	// RBACDenied means the Kubernetes API denied a request of the handler
+	RBACDenied Category = "RBACDenied"
+	// Unknown is used for failures that fit no other category
+	Unknown Category = "Unknown"
+)
+
+var categories = []Category{ConversionError, TargetMissing, Neo4jTransient, Neo4jFatal, RBACDenied, Unknown}
+
+var failuresTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_handler_failures_total",
+		Help: "Total number of failures to process a resource, by kind and cause",
+	},
+	[]string{"kind", "category"},
+)
+
+var (
+	mu     sync.Mutex
+	counts = make(map[Category]int64)
+)
+
+// Error attaches a category to an error
+type Error struct {
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns err tagged with category, or nil if err is nil
+func New(category Category, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// Classify returns the category of err. Errors tagged with New keep their
+// category; Kubernetes API and Neo4j driver errors are recognized anywhere in
+// the chain.
+func Classify(err error) Category {
+	var tagged *Error
+	if errors.As(err, &tagged) {
+		return tagged.Category
+	}
+
+	switch {
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return RBACDenied
+	case apierrors.IsNotFound(err):
+		return TargetMissing
+	}
+
+	var connectivityErr *neo4j.ConnectivityError
+	var limitErr *neo4j.TransactionExecutionLimit
+	if errors.As(err, &connectivityErr) || errors.As(err, &limitErr) {
+		return Neo4jTransient
+	}
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) {
+		if neo4jErr.IsRetriable() {
+			return Neo4jTransient
+		}
+		return Neo4jFatal
+	}
+	var usageErr *neo4j.UsageError
+	if errors.As(err, &usageErr) {
+		return Neo4jFatal
+	}
+
+	return Unknown
+}
+
+// Record counts a failure to process a resource of kind and returns its category
+func Record(kind string, err error) Category {
+	category := Classify(err)
+	failuresTotal.WithLabelValues(kind, string(category)).Inc()
+
+	mu.Lock()
+	counts[category]++
+	mu.Unlock()
+
+	return category
+}
+
+// Counts returns the number of failures recorded per category, including
+// categories without failures
+func Counts() map[string]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make(map[string]int64, len(categories))
+	for _, category := range categories {
+		result[string(category)] = counts[category]
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package failure
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassify(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected Category
+	}{
+		{"tagged", New(ConversionError, fmt.Errorf("bad object")), ConversionError},
+		{"wrapped tag", fmt.Errorf("failed to convert pod: %w", New(ConversionError, fmt.Errorf("bad object"))), ConversionError},
+		{"forbidden", fmt.Errorf("failed to list pods: %w", apierrors.NewForbidden(pods, "", fmt.Errorf("denied"))), RBACDenied},
+		{"not found", apierrors.NewNotFound(pods, "web"), TargetMissing},
+		{"connectivity", fmt.Errorf("failed to upsert pod: %w", &neo4j.ConnectivityError{Inner: fmt.Errorf("reset")}), Neo4jTransient},
+		{"transient database error", &neo4j.Neo4jError{Code: "Neo.TransientError.Transaction.DeadlockDetected"}, Neo4jTransient},
+		{"client database error", fmt.Errorf("failed to upsert pod: %w", &neo4j.Neo4jError{Code: "Neo.ClientError.Statement.SyntaxError"}), Neo4jFatal},
+		{"other", fmt.Errorf("something else"), Unknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Classify(test.err); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	before := Counts()[string(Neo4jFatal)]
+
+	category := Record("Pod", &neo4j.Neo4jError{Code: "Neo.ClientError.Schema.ConstraintValidationFailed"})
+	if category != Neo4jFatal {
+		t.Errorf("Expected Neo4jFatal, got %s", category)
+	}
+
+	counts := Counts()
+	if counts[string(Neo4jFatal)] != before+1 {
+		t.Errorf("Expected Neo4jFatal count %d, got %d", before+1, counts[string(Neo4jFatal)])
+	}
+	if _, ok := counts[string(RBACDenied)]; !ok {
+		t.Error("Expected counts to include categories without failures")
+	}
+	if New(Unknown, nil) != nil {
+		t.Error("Expected New to return nil for a nil error")
+	}
+}
@@ -0,0 +1,62 @@
+package graph
+
+import "fmt"
+
+// ChangeColumn is the column in which a statement run by Store.Write returns
+// the relationships it created or deleted, as maps with the keys operation,
+// type, from and to. The Neo4j client publishes them on the change feed.
+// Statements build it with the clauses below instead of returning it.
+const ChangeColumn = "change"
+
+// Operations of a change
+const (
+	ChangeUpsert = "upsert"
+	ChangeDelete = "delete"
+)
+
+// changeMap returns the map literal of a change of the relationship of type
+// relationshipType between the nodes bound to from and to. The nodes are
+// identified as Label/value of their fromKey and toKey properties, like the
+// ends of a Relationship.
+func changeMap(operation, relationshipType, from, fromKey, to, toKey string) string {
+	return fmt.Sprintf("{operation: '%s', type: %s, from: labels(%s)[0] + '/' + %s.%s, to: labels(%s)[0] + '/' + %s.%s}",
+		operation, relationshipType, from, from, fromKey, to, to, toKey)
+}
+
+// MergeRelationship returns the clauses merging a relationship of
+// relationshipType from the node bound to from to the node bound to to, and
+// returning it in ChangeColumn unless it existed. It ends the statement.
+func MergeRelationship(from, fromKey, relationshipType, to, toKey string) string {
+	return fmt.Sprintf(`
+		OPTIONAL MATCH (%[1]s)-[existing:%[2]s]->(%[3]s)
+		WITH %[1]s, %[3]s, existing IS NULL AS created
+		MERGE (%[1]s)-[:%[2]s]->(%[3]s)
+		WITH %[1]s, %[3]s, created WHERE created
+		RETURN DISTINCT %[4]s AS change`,
+		from, relationshipType, to, changeMap(ChangeUpsert, "'"+relationshipType+"'", from, fromKey, to, toKey))
+}
+
+// CreateRelationship returns the clauses creating a relationship of
+// relationshipType with properties, a Cypher map or "", from the node bound to
+// from to the node bound to to, and returning it in ChangeColumn. It ends the
+// statement.
+func CreateRelationship(from, fromKey, relationshipType, properties, to, toKey string) string {
+	if properties != "" {
+		properties = " " + properties
+	}
+	return fmt.Sprintf(`
+		CREATE (%s)-[:%s%s]->(%s)
+		RETURN %s AS change`,
+		from, relationshipType, properties, to, changeMap(ChangeUpsert, "'"+relationshipType+"'", from, fromKey, to, toKey))
+}
+
+// DeleteRelationship returns the clauses deleting the relationship bound to
+// relationship between the nodes bound to from and to, and returning it in
+// ChangeColumn. It ends the statement.
+func DeleteRelationship(relationship, from, fromKey, to, toKey string) string {
+	return fmt.Sprintf(`
+		WITH %s, %s AS change
+		DELETE %s
+		RETURN change`,
+		relationship, changeMap(ChangeDelete, "type("+relationship+")", from, fromKey, to, toKey), relationship)
+}
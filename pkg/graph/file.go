@@ -0,0 +1,260 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Operations of a mutation
+const (
+	OpUpsertNode         = "upsertNode"
+	OpUpsertRelationship = "upsertRelationship"
+	OpDelete             = "delete"
+	OpQuery              = "query"
+)
+
+// maxMutationLine limits the size of a single line of a mutation file
+const maxMutationLine = 64 * 1024 * 1024
+
+// ErrStoreClosed is returned by writes to a closed FileStore
+var ErrStoreClosed = errors.New("file store is closed")
+
+// Mutation is one line of a mutation file. Only the fields of its operation
+// are set.
+type Mutation struct {
+	Op   string    `json:"op"`
+	Time time.Time `json:"time"`
+
+	// upsertNode
+	Labels     []string               `json:"labels,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	UniqueKey  string                 `json:"uniqueKey,omitempty"`
+
+	// upsertRelationship
+	Relationship *Relationship `json:"relationship,omitempty"`
+
+	// delete
+	Label string      `json:"label,omitempty"`
+	Key   string      `json:"key,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	// query
+	Query  string                 `json:"query,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// FileStore appends the mutations of the graph as JSON lines to a file
+// instead of writing them to a database, e.g. in clusters without access to
+// Neo4j. The file is loaded into Neo4j later with Load.
+type FileStore struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore opens path for appending, creating it if needed
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mutation file: %w", err)
+	}
+	return &FileStore{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// UpsertNode records a node upsert
+func (s *FileStore) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	return s.append(Mutation{Op: OpUpsertNode, Labels: labels, Properties: properties, UniqueKey: uniqueKey})
+}
+
+// UpsertNodeWithRelationships records a node upsert followed by the upserts of
+// its relationships
+func (s *FileStore) UpsertNodeWithRelationships(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string, relationships []Relationship) error {
+	if err := s.UpsertNode(ctx, labels, properties, uniqueKey); err != nil {
+		return err
+	}
+	for _, relationship := range relationships {
+		if err := s.UpsertRelationship(ctx, relationship); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertRelationship records a relationship upsert
+func (s *FileStore) UpsertRelationship(ctx context.Context, relationship Relationship) error {
+	return s.append(Mutation{Op: OpUpsertRelationship, Relationship: &relationship})
+}
+
+// Delete records the deletion of a node
+func (s *FileStore) Delete(ctx context.Context, label, key string, value interface{}) error {
+	return s.append(Mutation{Op: OpDelete, Label: label, Key: key, Value: value})
+}
+
+// Query records a statement to run when the file is loaded. There is no
+// database to read from, so it returns no records.
+func (s *FileStore) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return nil, s.append(Mutation{Op: OpQuery, Query: query, Params: params})
+}
+
+// Read returns no records, as there is no database to read from
+func (s *FileStore) Read(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+
+// Write records the statements to run when the file is loaded
+func (s *FileStore) Write(ctx context.Context, statements ...Statement) error {
+	for _, statement := range statements {
+		if err := s.append(Mutation{Op: OpQuery, Query: statement.Query, Params: statement.Params}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForgetNode does nothing, the file store remembers no nodes
+func (s *FileStore) ForgetNode(label string, value interface{}) {}
+
+// RecordChange does nothing, the file store keeps no audit trail
+func (s *FileStore) RecordChange(ctx context.Context, kind, uid, operation string) {}
+
+// CloseVersion does nothing, the file store keeps no history
+func (s *FileStore) CloseVersion(ctx context.Context, label, uid string) error {
+	return nil
+}
+
+// Close syncs and closes the file
+func (s *FileStore) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Sync()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	s.file = nil
+	return err
+}
+
+// append writes a mutation as one line
+func (s *FileStore) append(mutation Mutation) error {
+	mutation.Time = time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	if err := s.encoder.Encode(mutation); err != nil {
+		return fmt.Errorf("failed to write mutation: %w", err)
+	}
+	return nil
+}
+
+// LoadStats counts the mutations replayed by Load, by operation
+type LoadStats map[string]int
+
+// Load replays the mutations read from r into store, in the order they were
+// recorded. It stops at the first mutation that fails, reporting its line.
+func Load(ctx context.Context, r io.Reader, store Store) (LoadStats, error) {
+	stats := LoadStats{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMutationLine)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		mutation, err := decodeMutation(scanner.Bytes())
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := apply(ctx, store, mutation); err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+		stats[mutation.Op]++
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read mutation file: %w", err)
+	}
+	return stats, nil
+}
+
+// apply runs a mutation against store
+func apply(ctx context.Context, store Store, mutation Mutation) error {
+	switch mutation.Op {
+	case OpUpsertNode:
+		return store.UpsertNode(ctx, mutation.Labels, mutation.Properties, mutation.UniqueKey)
+	case OpUpsertRelationship:
+		if mutation.Relationship == nil {
+			return fmt.Errorf("relationship missing")
+		}
+		return store.UpsertRelationship(ctx, *mutation.Relationship)
+	case OpDelete:
+		return store.Delete(ctx, mutation.Label, mutation.Key, mutation.Value)
+	case OpQuery:
+		_, err := store.Query(ctx, mutation.Query, mutation.Params)
+		return err
+	default:
+		return fmt.Errorf("unknown operation %q", mutation.Op)
+	}
+}
+
+// decodeMutation parses one line of a mutation file, keeping integers as
+// int64 so they are not loaded as floats
+func decodeMutation(data []byte) (Mutation, error) {
+	var mutation Mutation
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&mutation); err != nil {
+		return mutation, fmt.Errorf("invalid mutation: %w", err)
+	}
+	for k, v := range mutation.Properties {
+		mutation.Properties[k] = normalizeNumber(v)
+	}
+	for k, v := range mutation.Params {
+		mutation.Params[k] = normalizeNumber(v)
+	}
+	mutation.Value = normalizeNumber(mutation.Value)
+	return mutation, nil
+}
+
+// normalizeNumber converts JSON numbers, also within lists and maps, to int64
+// or float64
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeNumber(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = normalizeNumber(v[k])
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingStore keeps the mutations applied to it. Load only calls the
+// methods it overrides.
+type recordingStore struct {
+	Store
+	mutations []Mutation
+}
+
+func (s *recordingStore) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	s.mutations = append(s.mutations, Mutation{Op: OpUpsertNode, Labels: labels, Properties: properties, UniqueKey: uniqueKey})
+	return nil
+}
+
+func (s *recordingStore) UpsertRelationship(ctx context.Context, relationship Relationship) error {
+	s.mutations = append(s.mutations, Mutation{Op: OpUpsertRelationship, Relationship: &relationship})
+	return nil
+}
+
+func (s *recordingStore) Delete(ctx context.Context, label, key string, value interface{}) error {
+	s.mutations = append(s.mutations, Mutation{Op: OpDelete, Label: label, Key: key, Value: value})
+	return nil
+}
+
+func (s *recordingStore) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	s.mutations = append(s.mutations, Mutation{Op: OpQuery, Query: query, Params: params})
+	return nil, nil
+}
+
+func (s *recordingStore) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "graph.jsonl")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	relationship := Relationship{FromLabel: "Pod", FromKey: "uid", FromValue: "p1", Type: "SCHEDULED_ON", ToLabel: "Node", ToKey: "name", ToValue: "n1"}
+	writes := []func() error{
+		func() error {
+			return store.UpsertNode(ctx, []string{"Pod"}, map[string]interface{}{"uid": "p1", "restarts": 3, "cpu": 0.5, "ports": []int{80, 443}}, "uid")
+		},
+		func() error { return store.UpsertRelationship(ctx, relationship) },
+		func() error {
+			_, err := store.Query(ctx, "MATCH (n:Pod {uid: $uid}) SET n.ready = true", map[string]interface{}{"uid": "p1"})
+			return err
+		},
+		func() error {
+			return store.Write(ctx, Statement{Query: "MATCH (n:Pod {uid: $uid}) SET n.ready = false", Params: map[string]interface{}{"uid": "p1"}})
+		},
+		func() error {
+			return store.UpsertNodeWithRelationships(ctx, []string{"Node"}, map[string]interface{}{"name": "n1"}, "name", []Relationship{relationship})
+		},
+		func() error { return store.Delete(ctx, "Pod", "uid", "p1") },
+	}
+	for _, write := range writes {
+		if err := write(); err != nil {
+			t.Fatalf("Failed to write mutation: %v", err)
+		}
+	}
+	if err := store.Close(ctx); err != nil {
+		t.Fatalf("Failed to close store: %v", err)
+	}
+	if err := store.Delete(ctx, "Pod", "uid", "p1"); err != ErrStoreClosed {
+		t.Errorf("Expected ErrStoreClosed after Close, got %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open mutation file: %v", err)
+	}
+	defer file.Close()
+	target := &recordingStore{}
+	stats, err := Load(ctx, file, target)
+	if err != nil {
+		t.Fatalf("Failed to load mutations: %v", err)
+	}
+
+	expected := LoadStats{OpUpsertNode: 2, OpUpsertRelationship: 2, OpQuery: 2, OpDelete: 1}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected stats %v, got %v", expected, stats)
+	}
+	if len(target.mutations) != 7 {
+		t.Fatalf("Expected 7 mutations, got %d", len(target.mutations))
+	}
+
+	node := target.mutations[0]
+	if node.Properties["restarts"] != int64(3) || node.Properties["cpu"] != 0.5 {
+		t.Errorf("Expected numbers to keep their type, got %#v", node.Properties)
+	}
+	if !reflect.DeepEqual(node.Properties["ports"], []interface{}{int64(80), int64(443)}) {
+		t.Errorf("Expected integer list, got %#v", node.Properties["ports"])
+	}
+	if *target.mutations[1].Relationship != relationship {
+		t.Errorf("Expected relationship %+v, got %+v", relationship, *target.mutations[1].Relationship)
+	}
+	if target.mutations[2].Params["uid"] != "p1" {
+		t.Errorf("Expected query params to be replayed, got %v", target.mutations[2].Params)
+	}
+	if statement := target.mutations[3]; statement.Op != OpQuery || statement.Params["uid"] != "p1" {
+		t.Errorf("Expected written statement to be replayed as a query, got %+v", statement)
+	}
+	if node, related := target.mutations[4], target.mutations[5]; node.Op != OpUpsertNode || related.Op != OpUpsertRelationship || *related.Relationship != relationship {
+		t.Errorf("Expected node upsert followed by its relationship, got %+v and %+v", node, related)
+	}
+	if deleted := target.mutations[6]; deleted.Label != "Pod" || deleted.Key != "uid" || deleted.Value != "p1" {
+		t.Errorf("Expected delete of Pod p1, got %+v", deleted)
+	}
+}
+
+func TestLoadReportsLine(t *testing.T) {
+	input := `{"op":"delete","label":"Pod","key":"uid","value":"p1"}
+
+{"op":"rename"}
+`
+	_, err := Load(context.Background(), strings.NewReader(input), &recordingStore{})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected an error on line 3, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+// Package graphtest provides a graph.Store that records the writes of the
+// resource handlers in memory, so handlers can be tested without a database.
+package graphtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"k8s-graph/pkg/graph"
+)
+
+var _ graph.Store = (*Store)(nil)
+
+// Node is a node written to the store
+type Node struct {
+	Labels     []string
+	Properties map[string]interface{}
+	UniqueKey  string
+}
+
+// Statement is a Cypher statement run against the store
+type Statement struct {
+	Query  string
+	Params map[string]interface{}
+	Write  bool
+}
+
+// Change is a change recorded for the audit trail
+type Change struct {
+	Kind      string
+	UID       string
+	Operation string
+}
+
+// response is the records returned to statements containing match
+type response struct {
+	match   string
+	records []map[string]interface{}
+}
+
+// Store records nodes, relationships and statements in the order they are
+// written. Reads return no records unless a response is registered with
+// Respond.
+type Store struct {
+	mu            sync.Mutex
+	Nodes         []Node
+	Relationships []graph.Relationship
+	Statements    []Statement
+	Changes       []Change
+	// Deleted and Forgotten hold the deleted and forgotten nodes as label/value
+	Deleted   []string
+	Forgotten []string
+	responses []response
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Respond makes statements containing match return records, keyed by column.
+// The response registered last wins when several match.
+func (s *Store) Respond(match string, records ...map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, response{match: match, records: records})
+}
+
+// Node returns the last write of the node with the label and unique key value
+func (s *Store) Node(label string, value interface{}) (Node, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.Nodes) - 1; i >= 0; i-- {
+		node := s.Nodes[i]
+		if node.Labels[0] == label && reflect.DeepEqual(node.Properties[node.UniqueKey], value) {
+			return node, true
+		}
+	}
+	return Node{}, false
+}
+
+// RelationshipsOf returns the relationships of the type written so far
+func (s *Store) RelationshipsOf(relationshipType string) []graph.Relationship {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var relationships []graph.Relationship
+	for _, r := range s.Relationships {
+		if r.Type == relationshipType {
+			relationships = append(relationships, r)
+		}
+	}
+	return relationships
+}
+
+// HasRelationship reports whether the relationship was written
+func (s *Store) HasRelationship(relationship graph.Relationship) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.Relationships {
+		if r == relationship {
+			return true
+		}
+	}
+	return false
+}
+
+// StatementsMatching returns the statements containing match
+func (s *Store) StatementsMatching(match string) []Statement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var statements []Statement
+	for _, statement := range s.Statements {
+		if strings.Contains(statement.Query, match) {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// UpsertNode records the node
+func (s *Store) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Nodes = append(s.Nodes, Node{Labels: labels, Properties: properties, UniqueKey: uniqueKey})
+	return nil
+}
+
+// UpsertNodeWithRelationships records the node and its relationships
+func (s *Store) UpsertNodeWithRelationships(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string, relationships []graph.Relationship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Nodes = append(s.Nodes, Node{Labels: labels, Properties: properties, UniqueKey: uniqueKey})
+	s.Relationships = append(s.Relationships, relationships...)
+	return nil
+}
+
+// UpsertRelationship records the relationship
+func (s *Store) UpsertRelationship(ctx context.Context, relationship graph.Relationship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Relationships = append(s.Relationships, relationship)
+	return nil
+}
+
+// Delete records the node as deleted, as label/value
+func (s *Store) Delete(ctx context.Context, label, key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Deleted = append(s.Deleted, fmt.Sprintf("%s/%v", label, value))
+	return nil
+}
+
+// Read records the statement and returns the records of its response
+func (s *Store) Read(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return s.run(query, params, false), nil
+}
+
+// Write records the statements as writes
+func (s *Store) Write(ctx context.Context, statements ...graph.Statement) error {
+	for _, statement := range statements {
+		s.run(statement.Query, statement.Params, true)
+	}
+	return nil
+}
+
+// Query records the statement as a write and returns the records of its
+// response
+func (s *Store) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return s.run(query, params, true), nil
+}
+
+// ForgetNode records the node as forgotten, as label/value
+func (s *Store) ForgetNode(label string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Forgotten = append(s.Forgotten, fmt.Sprintf("%s/%v", label, value))
+}
+
+// RecordChange records the change
+func (s *Store) RecordChange(ctx context.Context, kind, uid, operation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Changes = append(s.Changes, Change{Kind: kind, UID: uid, Operation: operation})
+}
+
+// CloseVersion does nothing, the store keeps no history
+func (s *Store) CloseVersion(ctx context.Context, label, uid string) error {
+	return nil
+}
+
+// Close does nothing
+func (s *Store) Close(ctx context.Context) error {
+	return nil
+}
+
+// run records a statement and returns the records of its response
+func (s *Store) run(query string, params map[string]interface{}, write bool) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Statements = append(s.Statements, Statement{Query: query, Params: params, Write: write})
+	for i := len(s.responses) - 1; i >= 0; i-- {
+		if strings.Contains(query, s.responses[i].match) {
+			return s.responses[i].records
+		}
+	}
+	return nil
+}
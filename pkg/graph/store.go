@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Backends the graph can be stored in. Both speak Bolt and Cypher and are
+// served by the client of pkg/neo4j, which adapts the few statements whose
+// syntax differs.
+const (
+	BackendNeo4j    = "neo4j"
+	BackendMemgraph = "memgraph"
+)
+
+// Backends lists the supported backends
+var Backends = []string{BackendNeo4j, BackendMemgraph}
+
+// ValidateBackend returns an error for an unsupported backend
+func ValidateBackend(backend string) error {
+	for _, supported := range Backends {
+		if backend == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported graph backend %q, expected one of %v", backend, Backends)
+}
+
+// Outputs the agent writes the graph to: the backend at the Neo4j URI, or
+// the file store at the path following OutputFilePrefix
+const (
+	OutputNeo4j      = "neo4j"
+	OutputFilePrefix = "file:"
+)
+
+// ParseOutput returns the path of the file store of an output, or an empty
+// path when the graph is written to the backend
+func ParseOutput(output string) (string, error) {
+	if output == OutputNeo4j {
+		return "", nil
+	}
+	path, ok := strings.CutPrefix(output, OutputFilePrefix)
+	if !ok || path == "" {
+		return "", fmt.Errorf("unsupported graph output %q, expected %s or %s<path>", output, OutputNeo4j, OutputFilePrefix)
+	}
+	return path, nil
+}
+
+// Relationship identifies a relationship by its type and the unique keys of
+// the nodes it connects
+type Relationship struct {
+	FromLabel string `json:"fromLabel"`
+	FromKey   string `json:"fromKey"`
+	FromValue string `json:"fromValue"`
+	Type      string `json:"type"`
+	ToLabel   string `json:"toLabel"`
+	ToKey     string `json:"toKey"`
+	ToValue   string `json:"toValue"`
+}
+
+// Statement is a Cypher statement and its parameters
+type Statement struct {
+	Query  string
+	Params map[string]interface{}
+}
+
+// Store is the storage the graph is written to and queried from. The
+// resource handlers only depend on this interface, so they run against
+// Neo4j, the file store or the recording store of graphtest alike.
+type Store interface {
+	// UpsertNode creates the node with properties[uniqueKey] or replaces the
+	// properties of the existing one
+	UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error
+	// UpsertNodeWithRelationships upserts a node and its relationships in one
+	// transaction
+	UpsertNodeWithRelationships(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string, relationships []Relationship) error
+	// UpsertRelationship creates a relationship unless it exists. Nothing is
+	// written when either node is missing.
+	UpsertRelationship(ctx context.Context, relationship Relationship) error
+	// Delete removes the node with the given key and its relationships
+	Delete(ctx context.Context, label, key string, value interface{}) error
+	// Read runs a read-only Cypher statement that sees the latest writes and
+	// returns its records as maps keyed by the returned columns
+	Read(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error)
+	// Write runs Cypher statements in order in one transaction
+	Write(ctx context.Context, statements ...Statement) error
+	// Query runs a Cypher statement and returns its records as maps keyed by
+	// the returned columns
+	Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error)
+	// ForgetNode drops the remembered properties of a deleted node
+	ForgetNode(label string, value interface{})
+	// RecordChange publishes a change of a resource and records it in the
+	// audit trail
+	RecordChange(ctx context.Context, kind, uid, operation string)
+	// CloseVersion ends the current version of a deleted resource in the
+	// history
+	CloseVersion(ctx context.Context, label, uid string) error
+	// Close releases the connections of the store
+	Close(ctx context.Context) error
+}
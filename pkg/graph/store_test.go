@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		path   string
+		valid  bool
+	}{
+		{"neo4j", "", true},
+		{"file:/var/lib/kubegraph/graph.jsonl", "/var/lib/kubegraph/graph.jsonl", true},
+		{"file:", "", false},
+		{"", "", false},
+		{"memgraph", "", false},
+	}
+	for _, test := range tests {
+		path, err := ParseOutput(test.output)
+		if (err == nil) != test.valid || path != test.path {
+			t.Errorf("ParseOutput(%q) = %q, %v; expected %q, valid %v", test.output, path, err, test.path, test.valid)
+		}
+	}
+}
+
+func TestValidateBackend(t *testing.T) {
+	for _, backend := range []string{BackendNeo4j, BackendMemgraph} {
+		if err := ValidateBackend(backend); err != nil {
+			t.Errorf("Expected %s to be supported, got %v", backend, err)
+		}
+	}
+	for _, backend := range []string{"", "neptune", "Neo4j"} {
+		if err := ValidateBackend(backend); err == nil {
+			t.Errorf("Expected %q to be rejected", backend)
+		}
+	}
+}
+
+func TestRelationshipChanges(t *testing.T) {
+	merge := MergeRelationship("p", "uid", "USES", "s", "uid")
+	for _, clause := range []string{
+		"OPTIONAL MATCH (p)-[existing:USES]->(s)",
+		"MERGE (p)-[:USES]->(s)",
+		"WHERE created",
+		"RETURN DISTINCT {operation: 'upsert', type: 'USES', from: labels(p)[0] + '/' + p.uid, to: labels(s)[0] + '/' + s.uid} AS change",
+	} {
+		if !strings.Contains(merge, clause) {
+			t.Errorf("Expected %q in %s", clause, merge)
+		}
+	}
+	if create := CreateRelationship("p", "uid", "TOLERATES", "", "n", "uid"); !strings.Contains(create, "CREATE (p)-[:TOLERATES]->(n)") {
+		t.Errorf("Unexpected creation %s", create)
+	}
+	if create := CreateRelationship("a", "uid", "ALLOWS", "{policy: $name}", "b", "uid"); !strings.Contains(create, "CREATE (a)-[:ALLOWS {policy: $name}]->(b)") {
+		t.Errorf("Unexpected creation with properties %s", create)
+	}
+	remove := DeleteRelationship("old", "r", "uid", "i", "key")
+	for _, clause := range []string{
+		"{operation: 'delete', type: type(old), from: labels(r)[0] + '/' + r.uid, to: labels(i)[0] + '/' + i.key} AS change",
+		"DELETE old",
+		"RETURN change",
+	} {
+		if !strings.Contains(remove, clause) {
+			t.Errorf("Expected %q in %s", clause, remove)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes"
+)
+
+// drainTimeout bounds how long a pause request waits for in-flight events
+const drainTimeout = 30 * time.Second
+
+// HandlerStatesResponse represents the response for the admin handlers endpoint
+type HandlerStatesResponse struct {
+	Handlers []string                  `json:"handlers"`
+	Paused   []kubernetes.HandlerState `json:"paused"`
+}
+
+// DeadLettersResponse represents the response for the dead-letter endpoint
+type DeadLettersResponse struct {
+	Enabled bool                    `json:"enabled"`
+	Count   int                     `json:"count"`
+	Letters []kubernetes.DeadLetter `json:"letters"`
+}
+
+// registerAdminRoutes registers the runtime handler toggles and the
+// dead-letter inspection endpoint
+func (s *Server) registerAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /deadletter", s.require(config.HTTPRoleAdmin, s.handleDeadLetters))
+	mux.HandleFunc("GET /api/v1/admin/handlers", s.require(config.HTTPRoleAdmin, s.handleListHandlers))
+	mux.HandleFunc("POST /api/v1/admin/handlers/{kind}/pause", s.require(config.HTTPRoleAdmin, s.handlePauseHandler))
+	mux.HandleFunc("POST /api/v1/admin/handlers/{kind}/resume", s.require(config.HTTPRoleAdmin, s.handleResumeHandler))
+}
+
+// handleListHandlers handles GET /api/v1/admin/handlers
+func (s *Server) handleListHandlers(w http.ResponseWriter, r *http.Request) {
+	kinds := make([]string, 0)
+	for kind := range s.k8sClient.GetHandlers() {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HandlerStatesResponse{
+		Handlers: kinds,
+		Paused:   s.k8sClient.PausedHandlers(),
+	})
+}
+
+// handlePauseHandler handles POST /api/v1/admin/handlers/{kind}/pause
+func (s *Server) handlePauseHandler(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if _, ok := s.k8sClient.GetHandlers()[kind]; !ok {
+		http.Error(w, "Unknown handler "+kind, http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), drainTimeout)
+	defer cancel()
+	if err := s.k8sClient.PauseHandler(ctx, kind); err != nil {
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+		return
+	}
+	s.writeHandlerState(w, kind)
+}
+
+// handleResumeHandler handles POST /api/v1/admin/handlers/{kind}/resume
+func (s *Server) handleResumeHandler(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if err := s.k8sClient.ResumeHandler(kind); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.writeHandlerState(w, kind)
+}
+
+// writeHandlerState writes the current state of a single handler
+func (s *Server) writeHandlerState(w http.ResponseWriter, kind string) {
+	state := kubernetes.HandlerState{Kind: kind}
+	for _, paused := range s.k8sClient.PausedHandlers() {
+		if paused.Kind == kind {
+			state = paused
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleDeadLetters handles GET /deadletter, optionally filtered by ?kind=
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	store := s.k8sClient.DeadLetters()
+	kind := r.URL.Query().Get("kind")
+	letters := make([]kubernetes.DeadLetter, 0)
+	for _, letter := range store.List() {
+		if kind == "" || letter.Kind == kind {
+			letters = append(letters, letter)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DeadLettersResponse{
+		Enabled: store.Enabled(),
+		Count:   len(letters),
+		Letters: letters,
+	})
+}
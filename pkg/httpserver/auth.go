@@ -0,0 +1,201 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+)
+
+const (
+	// tokenReviewTTL is how long the result of a TokenReview is reused, so a
+	// client polling the API does not cause a review per request
+	tokenReviewTTL = time.Minute
+	// maxReviewedTokens bounds the cache of TokenReview results
+	maxReviewedTokens = 1000
+)
+
+// tokenReviewer authenticates a bearer token and returns its user and groups
+type tokenReviewer func(ctx context.Context, token string) (string, []string, bool, error)
+
+// identity is an authenticated client of the HTTP server
+type identity struct {
+	name string
+	role string
+}
+
+// identityKey is the context key of the identity of an authenticated request
+type identityKey struct{}
+
+// reviewedToken is a cached TokenReview result
+type reviewedToken struct {
+	identity      identity
+	authenticated bool
+	expires       time.Time
+}
+
+// authenticator authenticates the clients of the HTTP server with the
+// configured bearer tokens, basic authentication users and TokenReview
+type authenticator struct {
+	tokens       []config.HTTPCredential
+	users        []config.HTTPCredential
+	review       tokenReviewer // nil unless TokenReview is enabled
+	adminGroups  map[string]bool
+	readerGroups map[string]bool
+
+	mu       sync.Mutex
+	reviewed map[[sha256.Size]byte]reviewedToken
+	now      func() time.Time
+}
+
+// newAuthenticator creates an authenticator for the configured methods.
+// review is only used when TokenReview is enabled.
+func newAuthenticator(auth config.HTTPAuth, review tokenReviewer) *authenticator {
+	a := &authenticator{
+		tokens:       auth.Tokens,
+		users:        auth.Users,
+		adminGroups:  make(map[string]bool),
+		readerGroups: make(map[string]bool),
+		reviewed:     make(map[[sha256.Size]byte]reviewedToken),
+		now:          time.Now,
+	}
+	if auth.TokenReview {
+		a.review = review
+	}
+	for _, group := range auth.AdminGroups {
+		a.adminGroups[group] = true
+	}
+	for _, group := range auth.ReaderGroups {
+		a.readerGroups[group] = true
+	}
+	return a
+}
+
+// authenticate returns the identity of the client of a request. The error is
+// set when the client could not be authenticated because TokenReview failed.
+func (a *authenticator) authenticate(r *http.Request) (identity, bool, error) {
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, user := range a.users {
+			if equal(username, user.Name) && equal(password, user.Secret) {
+				return identity{name: user.Name, role: user.Role}, true, nil
+			}
+		}
+		return identity{}, false, nil
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return identity{}, false, nil
+	}
+	for _, credential := range a.tokens {
+		if equal(token, credential.Secret) {
+			return identity{name: credential.Name, role: credential.Role}, true, nil
+		}
+	}
+	if a.review == nil {
+		return identity{}, false, nil
+	}
+	return a.reviewToken(r.Context(), token)
+}
+
+// reviewToken authenticates a token with TokenReview, reusing recent results
+func (a *authenticator) reviewToken(ctx context.Context, token string) (identity, bool, error) {
+	key := sha256.Sum256([]byte(token))
+	a.mu.Lock()
+	cached, ok := a.reviewed[key]
+	a.mu.Unlock()
+	if ok && a.now().Before(cached.expires) {
+		return cached.identity, cached.authenticated, nil
+	}
+
+	user, groups, authenticated, err := a.review(ctx, token)
+	if err != nil {
+		return identity{}, false, err
+	}
+	result := reviewedToken{authenticated: authenticated, expires: a.now().Add(tokenReviewTTL)}
+	if authenticated {
+		result.identity = identity{name: user, role: a.roleOf(user, groups)}
+	}
+
+	a.mu.Lock()
+	if len(a.reviewed) >= maxReviewedTokens {
+		a.reviewed = make(map[[sha256.Size]byte]reviewedToken)
+	}
+	a.reviewed[key] = result
+	a.mu.Unlock()
+	return result.identity, result.authenticated, nil
+}
+
+// roleOf returns the role of a user authenticated by TokenReview, empty when
+// neither the user nor one of their groups is granted a role, which denies
+// every route
+func (a *authenticator) roleOf(user string, groups []string) string {
+	names := append([]string{user}, groups...)
+	for _, name := range names {
+		if a.adminGroups[name] {
+			return config.HTTPRoleAdmin
+		}
+	}
+	for _, name := range names {
+		if a.readerGroups[name] {
+			return config.HTTPRoleRead
+		}
+	}
+	return ""
+}
+
+// challenges returns the WWW-Authenticate values of the enabled methods
+func (a *authenticator) challenges() []string {
+	challenges := make([]string, 0, 2)
+	if len(a.users) > 0 {
+		challenges = append(challenges, `Basic realm="kubegraph"`)
+	}
+	if len(a.tokens) > 0 || a.review != nil {
+		challenges = append(challenges, `Bearer realm="kubegraph"`)
+	}
+	return challenges
+}
+
+// equal compares secrets in constant time
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// allowed reports whether a role may call routes requiring another role
+func allowed(role, required string) bool {
+	return role == required || role == config.HTTPRoleAdmin
+}
+
+// require wraps a route so that it is only served to clients with the given
+// role. Routes are open when no authentication method is configured.
+func (s *Server) require(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next(w, r)
+			return
+		}
+
+		client, ok, err := s.auth.authenticate(r)
+		switch {
+		case err != nil:
+			logger.Error("Failed to authenticate %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, "Authentication unavailable", http.StatusServiceUnavailable)
+		case !ok:
+			for _, challenge := range s.auth.challenges() {
+				w.Header().Add("WWW-Authenticate", challenge)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case !allowed(client.role, role):
+			logger.Warn("Denied %s %s to %s: requires the %s role", r.Method, r.URL.Path, client.name, role)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		default:
+			next(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, client)))
+		}
+	}
+}
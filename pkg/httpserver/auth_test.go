@@ -0,0 +1,136 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+)
+
+func testAuthenticator(reviews *int) *authenticator {
+	auth := config.HTTPAuth{
+		Tokens:       []config.HTTPCredential{{Name: "dashboard", Secret: "read-token", Role: config.HTTPRoleRead}},
+		Users:        []config.HTTPCredential{{Name: "ops", Secret: "password", Role: config.HTTPRoleAdmin}},
+		TokenReview:  true,
+		AdminGroups:  []string{"platform-admins"},
+		ReaderGroups: []string{"system:serviceaccounts:monitoring"},
+	}
+	return newAuthenticator(auth, func(ctx context.Context, token string) (string, []string, bool, error) {
+		*reviews++
+		switch token {
+		case "sa-token":
+			return "system:serviceaccount:monitoring:grafana", []string{"system:serviceaccounts", "system:serviceaccounts:monitoring"}, true, nil
+		case "other-sa-token":
+			return "system:serviceaccount:default:default", []string{"system:serviceaccounts", "system:serviceaccounts:default"}, true, nil
+		case "admin-token":
+			return "alice", []string{"platform-admins"}, true, nil
+		case "broken":
+			return "", nil, false, errors.New("connection refused")
+		}
+		return "", nil, false, nil
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	var reviews int
+	a := testAuthenticator(&reviews)
+
+	tests := []struct {
+		name          string
+		authorization func(r *http.Request)
+		expected      identity
+		authenticated bool
+		err           bool
+	}{
+		{"no credentials", func(r *http.Request) {}, identity{}, false, false},
+		{"static token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") },
+			identity{name: "dashboard", role: config.HTTPRoleRead}, true, false},
+		{"basic user", func(r *http.Request) { r.SetBasicAuth("ops", "password") },
+			identity{name: "ops", role: config.HTTPRoleAdmin}, true, false},
+		{"wrong password", func(r *http.Request) { r.SetBasicAuth("ops", "read-token") }, identity{}, false, false},
+		{"reviewed token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer sa-token") },
+			identity{name: "system:serviceaccount:monitoring:grafana", role: config.HTTPRoleRead}, true, false},
+		{"reviewed admin", func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			identity{name: "alice", role: config.HTTPRoleAdmin}, true, false},
+		{"reviewed without role", func(r *http.Request) { r.Header.Set("Authorization", "Bearer other-sa-token") },
+			identity{name: "system:serviceaccount:default:default"}, true, false},
+		{"rejected token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer unknown") }, identity{}, false, false},
+		{"review failure", func(r *http.Request) { r.Header.Set("Authorization", "Bearer broken") }, identity{}, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+			test.authorization(r)
+			client, ok, err := a.authenticate(r)
+			if client != test.expected || ok != test.authenticated || (err != nil) != test.err {
+				t.Errorf("Expected %+v %v (error %v), got %+v %v (%v)", test.expected, test.authenticated, test.err, client, ok, err)
+			}
+		})
+	}
+}
+
+func TestReviewTokenCache(t *testing.T) {
+	var reviews int
+	a := testAuthenticator(&reviews)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		a.reviewToken(context.Background(), "sa-token")
+		a.reviewToken(context.Background(), "unknown")
+	}
+	if reviews != 2 {
+		t.Errorf("Expected results to be reused, got %d reviews", reviews)
+	}
+
+	now = now.Add(tokenReviewTTL)
+	a.reviewToken(context.Background(), "sa-token")
+	if reviews != 3 {
+		t.Errorf("Expected an expired result to be reviewed again, got %d reviews", reviews)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	logger.Init(logger.ERROR)
+	var reviews int
+	served := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+
+	tests := []struct {
+		name     string
+		auth     *authenticator
+		role     string
+		token    string
+		expected int
+	}{
+		{"open", nil, config.HTTPRoleAdmin, "", http.StatusNoContent},
+		{"anonymous", testAuthenticator(&reviews), config.HTTPRoleRead, "", http.StatusUnauthorized},
+		{"reader", testAuthenticator(&reviews), config.HTTPRoleRead, "read-token", http.StatusNoContent},
+		{"reader on admin route", testAuthenticator(&reviews), config.HTTPRoleAdmin, "read-token", http.StatusForbidden},
+		{"admin on read route", testAuthenticator(&reviews), config.HTTPRoleRead, "admin-token", http.StatusNoContent},
+		{"reviewed reader", testAuthenticator(&reviews), config.HTTPRoleRead, "sa-token", http.StatusNoContent},
+		{"reviewed without role", testAuthenticator(&reviews), config.HTTPRoleRead, "other-sa-token", http.StatusForbidden},
+		{"review failure", testAuthenticator(&reviews), config.HTTPRoleRead, "broken", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &Server{auth: test.auth}
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			s.require(test.role, served)(w, r)
+			if w.Code != test.expected {
+				t.Errorf("Expected status %d, got %d", test.expected, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && len(w.Header().Values("WWW-Authenticate")) != 2 {
+				t.Errorf("Expected Basic and Bearer challenges, got %v", w.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
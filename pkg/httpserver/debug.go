@@ -0,0 +1,32 @@
+package httpserver
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+
+	"k8s-graph/config"
+)
+
+// publishRuntime publishes the runtime variables that expvar does not, once
+// per process since expvar panics on duplicate names
+var publishRuntime sync.Once
+
+// registerDebugRoutes registers the pprof profiles under /debug/pprof/ and
+// the expvar variables on /debug/vars
+func (s *Server) registerDebugRoutes(mux *http.ServeMux) {
+	publishRuntime.Do(func() {
+		expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	})
+
+	// pprof.Index serves the named profiles, such as heap and goroutine
+	mux.HandleFunc("GET /debug/pprof/", s.require(config.HTTPRoleAdmin, pprof.Index))
+	mux.HandleFunc("GET /debug/pprof/cmdline", s.require(config.HTTPRoleAdmin, pprof.Cmdline))
+	mux.HandleFunc("GET /debug/pprof/profile", s.require(config.HTTPRoleAdmin, pprof.Profile))
+	mux.HandleFunc("GET /debug/pprof/symbol", s.require(config.HTTPRoleAdmin, pprof.Symbol))
+	mux.HandleFunc("POST /debug/pprof/symbol", s.require(config.HTTPRoleAdmin, pprof.Symbol))
+	mux.HandleFunc("GET /debug/pprof/trace", s.require(config.HTTPRoleAdmin, pprof.Trace))
+	mux.HandleFunc("GET /debug/vars", s.require(config.HTTPRoleAdmin, expvar.Handler().ServeHTTP))
+}
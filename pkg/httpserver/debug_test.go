@@ -0,0 +1,44 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s-graph/pkg/logger"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	logger.Init(logger.ERROR)
+	var reviews int
+	s := &Server{auth: testAuthenticator(&reviews)}
+	mux := http.NewServeMux()
+	s.registerDebugRoutes(mux)
+
+	tests := []struct {
+		path     string
+		auth     func(r *http.Request)
+		code     int
+		contains string
+	}{
+		{"/debug/vars", func(r *http.Request) {}, http.StatusUnauthorized, ""},
+		{"/debug/vars", func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") }, http.StatusForbidden, ""},
+		{"/debug/vars", func(r *http.Request) { r.SetBasicAuth("ops", "password") }, http.StatusOK, `"goroutines"`},
+		{"/debug/pprof/", func(r *http.Request) { r.SetBasicAuth("ops", "password") }, http.StatusOK, "heap"},
+		{"/debug/pprof/goroutine?debug=1", func(r *http.Request) { r.SetBasicAuth("ops", "password") }, http.StatusOK, "goroutine profile"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		test.auth(r)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s: expected %d, got %d", test.path, test.code, w.Code)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), test.contains) {
+			t.Errorf("%s: expected body to contain %q", test.path, test.contains)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// idleClientTTL is how long the rate of a client is tracked after its last request
+const idleClientTTL = 10 * time.Minute
+
+var queryRejectionsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_query_rejections_total",
+		Help: "Total number of graph query requests of the HTTP API rejected, by reason",
+	},
+	[]string{"reason"},
+)
+
+// queryLimiter limits the requests that query the graph per client, and the
+// number of queries run at once so that they leave Neo4j connections to the
+// synchronization
+type queryLimiter struct {
+	limits    config.HTTPQueries
+	slots     chan struct{}
+	mu        sync.Mutex
+	clients   map[string]*clientRate
+	lastSweep time.Time
+}
+
+// clientRate is the token bucket of a client
+type clientRate struct {
+	limiter  flowcontrol.RateLimiter
+	lastSeen time.Time
+}
+
+// newQueryLimiter creates a limiter enforcing limits
+func newQueryLimiter(limits config.HTTPQueries) *queryLimiter {
+	return &queryLimiter{
+		limits:    limits,
+		slots:     make(chan struct{}, max(limits.MaxConcurrent, 1)),
+		clients:   make(map[string]*clientRate),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether client may make a request now
+func (l *queryLimiter) allow(client string, now time.Time) bool {
+	if l.limits.RateLimit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > idleClientTTL {
+		for name, c := range l.clients {
+			if now.Sub(c.lastSeen) > idleClientTTL {
+				delete(l.clients, name)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientRate{limiter: flowcontrol.NewTokenBucketRateLimiter(float32(l.limits.RateLimit)/60, max(l.limits.RateBurst, 1))}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+	return c.limiter.TryAccept()
+}
+
+// acquire waits for a query slot until ctx is done, returning the function
+// that releases it
+func (l *queryLimiter) acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limit returns the ?limit= of r, defaulting to def and capped to the
+// configured maximum
+func (l *queryLimiter) limit(r *http.Request, def int) (int, error) {
+	limit := min(def, l.limits.MaxResults)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return 0, strconv.ErrSyntax
+		}
+		limit = min(n, l.limits.MaxResults)
+	}
+	return max(limit, 1), nil
+}
+
+// queryStatus is the status of a request whose query failed: 504 when it ran
+// out of time, 503 otherwise
+func queryStatus(ctx context.Context) int {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		queryRejectionsTotal.WithLabelValues("timeout").Inc()
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusServiceUnavailable
+}
+
+// clientKey identifies the client of r for rate limiting: its authenticated
+// name, or its address when authentication is disabled
+func clientKey(r *http.Request) string {
+	if client, ok := r.Context().Value(identityKey{}).(identity); ok {
+		return "user:" + client.name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "address:" + host
+}
+
+// query wraps an endpoint that queries the graph with the rate limit of its
+// client, a query slot and the query timeout
+func (s *Server) query(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := clientKey(r)
+		if !s.limiter.allow(client, time.Now()) {
+			queryRejectionsTotal.WithLabelValues("rate_limited").Inc()
+			logger.Debug("Rate limited %s %s from %s", r.Method, r.URL.Path, client)
+			w.Header().Set("Retry-After", strconv.Itoa(max(60/max(s.limiter.limits.RateLimit, 1), 1)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		ctx := r.Context()
+		if s.limiter.limits.TimeoutSeconds > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(s.limiter.limits.TimeoutSeconds)*time.Second)
+			defer cancel()
+		}
+
+		release, err := s.limiter.acquire(ctx)
+		if err != nil {
+			queryRejectionsTotal.WithLabelValues("busy").Inc()
+			http.Error(w, "Too many concurrent queries", http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		next(w, r.WithContext(ctx))
+	}
+}
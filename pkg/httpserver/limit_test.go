@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+)
+
+func TestQueryLimiterAllow(t *testing.T) {
+	l := newQueryLimiter(config.HTTPQueries{RateLimit: 60, RateBurst: 2, MaxConcurrent: 1, MaxResults: 10})
+	now := time.Now()
+
+	if !l.allow("a", now) || !l.allow("a", now) {
+		t.Error("Expected the burst to be allowed")
+	}
+	if l.allow("a", now) {
+		t.Error("Expected a request beyond the burst to be limited")
+	}
+	if !l.allow("b", now) {
+		t.Error("Expected clients to be limited independently")
+	}
+
+	// Idle clients are forgotten
+	l.allow("c", now.Add(2*idleClientTTL))
+	if _, ok := l.clients["a"]; ok {
+		t.Error("Expected the idle client to be forgotten")
+	}
+
+	unlimited := newQueryLimiter(config.HTTPQueries{RateLimit: 0, MaxConcurrent: 1})
+	for i := 0; i < 100; i++ {
+		if !unlimited.allow("a", now) {
+			t.Fatal("Expected no limit with a rate limit of 0")
+		}
+	}
+}
+
+func TestQueryLimiterAcquire(t *testing.T) {
+	l := newQueryLimiter(config.HTTPQueries{MaxConcurrent: 1})
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire a slot: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); err == nil {
+		t.Error("Expected to wait for the busy slot until the context is done")
+	}
+
+	release()
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Errorf("Expected the released slot to be acquired: %v", err)
+	}
+}
+
+func TestQueryLimiterLimit(t *testing.T) {
+	l := newQueryLimiter(config.HTTPQueries{MaxResults: 100})
+	tests := []struct {
+		query    string
+		def      int
+		expected int
+		err      bool
+	}{
+		{"", 50, 50, false},
+		{"", 200, 100, false},
+		{"?limit=20", 50, 20, false},
+		{"?limit=5000", 50, 100, false},
+		{"?limit=0", 50, 0, true},
+		{"?limit=many", 50, 0, true},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/graph/search"+test.query, nil)
+		limit, err := l.limit(r, test.def)
+		if (err != nil) != test.err || limit != test.expected {
+			t.Errorf("%q: expected %d (error %t), got %d (%v)", test.query, test.expected, test.err, limit, err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	logger.Init(logger.ERROR)
+	var reviews int
+	s := &Server{
+		auth:    testAuthenticator(&reviews),
+		limiter: newQueryLimiter(config.HTTPQueries{TimeoutSeconds: 5, RateLimit: 60, RateBurst: 1, MaxConcurrent: 1}),
+	}
+	var deadline bool
+	handler := s.require(config.HTTPRoleRead, s.query(func(w http.ResponseWriter, r *http.Request) {
+		_, deadline = r.Context().Deadline()
+	}))
+
+	request := func(username string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+		r.SetBasicAuth(username, "password")
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+	s.auth.users = append(s.auth.users, config.HTTPCredential{Name: "dev", Secret: "password", Role: config.HTTPRoleRead})
+
+	if code := request("ops"); code != http.StatusOK || !deadline {
+		t.Errorf("Expected the query to run with a deadline, got %d (deadline %t)", code, deadline)
+	}
+	if code := request("ops"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the second request to be rate limited, got %d", code)
+	}
+	if code := request("dev"); code != http.StatusOK {
+		t.Errorf("Expected another user to have its own limit, got %d", code)
+	}
+}
@@ -2,17 +2,23 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
 	"time"
 
-	"kubegraph/config"
-	"kubegraph/pkg/kubernetes"
-	"kubegraph/pkg/logger"
-	"kubegraph/pkg/neo4j"
-	"kubegraph/pkg/version"
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/ingest"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+	"k8s-graph/pkg/reload"
+	"k8s-graph/pkg/tracing"
+	"k8s-graph/pkg/version"
 
 	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,33 +30,57 @@ type Server struct {
 	config      *config.Config
 	k8sClient   *kubernetes.Client
 	neo4jClient *neo4j.Client
+	reloader    *reload.Reloader
 	server      *http.Server
 	startTime   time.Time
+	shutdown    chan struct{}  // closed when the server shuts down, ending open streams
+	auth        *authenticator // nil when no authentication method is configured
+	redirect    *http.Server   // redirects plain HTTP to HTTPS, nil when disabled
+	limiter     *queryLimiter  // limits the endpoints that query the graph
 }
 
 // InfoResponse represents the response for the /info endpoint
 type InfoResponse struct {
-	Application   string                 `json:"application"`
-	Version       string                 `json:"version"`
-	GitCommit     string                 `json:"gitCommit"`
-	GitBranch     string                 `json:"gitBranch"`
-	StartTime     time.Time              `json:"startTime"`
-	Uptime        string                 `json:"uptime"`
-	ClusterName   string                 `json:"clusterName"`
-	InstanceHash  string                 `json:"instanceHash"`
-	EventTTLDays  int                    `json:"eventTTLDays"`
-	ActiveCRDs    []string               `json:"activeCRDs"`
-	ResourceCount map[string]int         `json:"resourceCount"`
-	SystemInfo    map[string]interface{} `json:"systemInfo"`
+	Application      string                                  `json:"application"`
+	Version          string                                  `json:"version"`
+	GitCommit        string                                  `json:"gitCommit"`
+	GitBranch        string                                  `json:"gitBranch"`
+	StartTime        time.Time                               `json:"startTime"`
+	Uptime           string                                  `json:"uptime"`
+	ClusterName      string                                  `json:"clusterName"`
+	InstanceHash     string                                  `json:"instanceHash"`
+	EventTTLDays     int                                     `json:"eventTTLDays"`
+	ActiveCRDs       []string                                `json:"activeCRDs"`
+	Handlers         []HandlerInfo                           `json:"handlers"`
+	PausedHandlers   []kubernetes.HandlerState               `json:"pausedHandlers"`
+	HandlerFailures  map[string]int64                        `json:"handlerFailures"`
+	Neo4jBreaker     *neo4j.BreakerState                     `json:"neo4jBreaker,omitempty"`
+	Reload           *reload.Status                          `json:"reload,omitempty"`
+	ResourceCount    map[string]int                          `json:"resourceCount"`
+	SyncCompleteness map[string]kubernetes.SyncStatus        `json:"syncCompleteness"`
+	InitialSync      map[string]kubernetes.InitialSyncStatus `json:"initialSync"`
+	SystemInfo       map[string]interface{}                  `json:"systemInfo"`
+}
+
+// HandlerInfo describes a registered handler in the /info response
+type HandlerInfo struct {
+	Kind              string `json:"kind"`
+	Group             string `json:"group"`
+	Version           string `json:"version"`
+	Resource          string `json:"resource"`
+	Namespaced        bool   `json:"namespaced"`
+	RequiresClientset bool   `json:"requiresClientset"`
+	// Enabled is false for handlers disabled by the configuration
+	Enabled bool `json:"enabled"`
 }
 
 // Metrics represents the Prometheus metrics
 type Metrics struct {
-	resourceEventsTotal *prometheus.CounterVec
-	resourceCount       *prometheus.GaugeVec
-	uptimeSeconds       prometheus.Gauge
-	neo4jConnections    prometheus.Gauge
-	registry            *prometheus.Registry
+	resourceCount    *prometheus.GaugeVec
+	syncCompleteness *prometheus.GaugeVec
+	uptimeSeconds    prometheus.Gauge
+	neo4jConnections prometheus.Gauge
+	registry         *prometheus.Registry
 }
 
 // NewServer creates a new HTTP server
@@ -60,9 +90,16 @@ func NewServer(cfg *config.Config, k8sClient *kubernetes.Client, neo4jClient *ne
 		k8sClient:   k8sClient,
 		neo4jClient: neo4jClient,
 		startTime:   time.Now(),
+		shutdown:    make(chan struct{}),
 	}
 }
 
+// SetReloader sets the reloader of the configuration file whose last reload is
+// reported by /info
+func (s *Server) SetReloader(reloader *reload.Reloader) {
+	s.reloader = reloader
+}
+
 // Start starts the HTTP server
 func (s *Server) Start(ctx context.Context) error {
 	if !s.config.HTTP.Enabled {
@@ -73,27 +110,67 @@ func (s *Server) Start(ctx context.Context) error {
 	// Initialize metrics
 	metrics := s.initMetrics()
 
+	if s.config.HTTP.Auth.Enabled() {
+		var review tokenReviewer
+		if s.k8sClient != nil {
+			review = s.k8sClient.ReviewToken
+		} else if s.config.HTTP.Auth.TokenReview {
+			logger.Warn("TokenReview authentication requires a Kubernetes client and is disabled")
+		}
+		s.auth = newAuthenticator(s.config.HTTP.Auth, review)
+		logger.Info("HTTP API authentication enabled: %d tokens, %d users, TokenReview %t",
+			len(s.config.HTTP.Auth.Tokens), len(s.config.HTTP.Auth.Users), s.auth.review != nil)
+	}
+
+	s.limiter = newQueryLimiter(s.config.HTTP.Queries)
+
 	// Create mux
 	mux := http.NewServeMux()
 
 	// Register routes
 	mux.HandleFunc("/info", s.handleInfo)
-	mux.Handle("/metrics", promhttp.HandlerFor(metrics.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	// The metrics of the other packages are registered on the default registry
+	mux.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{metrics.registry, prometheus.DefaultGatherer}, promhttp.HandlerOpts{}))
+	if s.k8sClient != nil {
+		s.registerAdminRoutes(mux)
+	}
+	if s.neo4jClient != nil {
+		mux.HandleFunc("GET /api/v1/stats", s.require(config.HTTPRoleRead, s.query(s.handleStats)))
+		mux.HandleFunc("GET /api/v1/stream", s.require(config.HTTPRoleRead, s.handleStream))
+	}
+	if s.config.HTTP.UI && s.neo4jClient != nil {
+		s.registerUIRoutes(mux)
+	}
+	if s.config.HTTP.Debug {
+		s.registerDebugRoutes(mux)
+		logger.Info("Debug endpoints enabled on /debug/pprof/ and /debug/vars")
+	}
+	if len(s.config.Ingest.Sources) > 0 {
+		mux.Handle("/api/v1/ingest", ingest.NewReceiver(s.config, s.k8sClient, s.neo4jClient))
+		logger.Info("Ingest endpoint enabled for %d sources", len(s.config.Ingest.Sources))
+	}
 
 	// Create server
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.config.HTTP.Port),
-		Handler: mux,
+		Handler: tracing.Handler(mux),
 	}
+	// Shutdown waits for open requests, which streams never end by themselves
+	s.server.RegisterOnShutdown(func() { close(s.shutdown) })
 
-	logger.Info("Starting HTTP server on port %d", s.config.HTTP.Port)
+	if s.config.HTTP.TLSCertFile == "" {
+		logger.Info("Starting HTTP server on port %d", s.config.HTTP.Port)
 
-	// Start server in goroutine
-	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTP server error: %v", err)
-		}
-	}()
+		// Start server in goroutine
+		go func() {
+			if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP server error: %v", err)
+			}
+		}()
+	} else if err := s.startTLS(ctx); err != nil {
+		return err
+	}
 
 	// Start metrics collection goroutine
 	go s.collectMetrics(ctx, metrics)
@@ -103,6 +180,9 @@ func (s *Server) Start(ctx context.Context) error {
 
 // Stop stops the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
+	if s.redirect != nil {
+		s.redirect.Shutdown(ctx)
+	}
 	if s.server != nil {
 		logger.Info("Stopping HTTP server...")
 		return s.server.Shutdown(ctx)
@@ -110,6 +190,42 @@ func (s *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
+// startTLS serves HTTPS with the configured certificate, which is reloaded
+// when its files change, and the optional redirect from plain HTTP
+func (s *Server) startTLS(ctx context.Context) error {
+	certificates, err := newCertificateReloader(s.config.HTTP.TLSCertFile, s.config.HTTP.TLSKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load HTTP server certificate: %w", err)
+	}
+	go certificates.watch(ctx, certificateReloadInterval)
+	s.server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.GetCertificate,
+	}
+
+	logger.Info("Starting HTTPS server on port %d", s.config.HTTP.Port)
+	go func() {
+		if err := s.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			logger.Error("HTTPS server error: %v", err)
+		}
+	}()
+
+	if s.config.HTTP.RedirectPort > 0 {
+		s.redirect = &http.Server{
+			Addr:              fmt.Sprintf(":%d", s.config.HTTP.RedirectPort),
+			Handler:           httpsRedirect(s.config.HTTP.Port),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		logger.Info("Redirecting HTTP requests on port %d to HTTPS", s.config.HTTP.RedirectPort)
+		go func() {
+			if err := s.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP redirect server error: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
 // handleInfo handles the /info endpoint
 func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -130,24 +246,67 @@ func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	versionInfo := version.GetVersionInfo()
 
 	response := InfoResponse{
-		Application:   "kubegraph",
-		Version:       versionInfo["full"],
-		GitCommit:     versionInfo["commit"],
-		GitBranch:     versionInfo["branch"],
-		StartTime:     s.startTime,
-		Uptime:        time.Since(s.startTime).String(),
-		ClusterName:   s.config.Kubernetes.ClusterName,
-		InstanceHash:  s.config.InstanceHash,
-		EventTTLDays:  s.config.EventTTLDays,
-		ActiveCRDs:    activeCRDs,
-		ResourceCount: resourceCount,
-		SystemInfo:    systemInfo,
+		Application:      "kubegraph",
+		Version:          versionInfo["full"],
+		GitCommit:        versionInfo["commit"],
+		GitBranch:        versionInfo["branch"],
+		StartTime:        s.startTime,
+		Uptime:           time.Since(s.startTime).String(),
+		ClusterName:      s.config.Kubernetes.ClusterName,
+		InstanceHash:     s.config.InstanceHash,
+		EventTTLDays:     s.config.EventTTLDays,
+		ActiveCRDs:       activeCRDs,
+		Handlers:         s.getHandlers(),
+		PausedHandlers:   s.getPausedHandlers(),
+		HandlerFailures:  failure.Counts(),
+		ResourceCount:    resourceCount,
+		SyncCompleteness: s.getSyncCompleteness(resourceCount),
+		InitialSync:      s.getInitialSync(),
+		SystemInfo:       systemInfo,
+	}
+	if s.neo4jClient != nil {
+		breaker := s.neo4jClient.BreakerState()
+		response.Neo4jBreaker = &breaker
+	}
+	if s.reloader != nil {
+		response.EventTTLDays = s.reloader.Settings().EventTTLDays
+		response.Reload = s.reloader.Status()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// ReadyResponse represents the response for the /readyz endpoint
+type ReadyResponse struct {
+	Ready   bool     `json:"ready"`
+	Pending []string `json:"pending,omitempty"` // Kinds whose initial sync is not complete
+}
+
+// handleReadyz handles the /readyz endpoint, which reports ready once the
+// initial sync of every watched kind is complete
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	response := ReadyResponse{Ready: true}
+	if s.k8sClient != nil {
+		response.Pending = s.k8sClient.InitialSyncPending()
+		response.Ready = len(response.Pending) == 0
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !response.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// getInitialSync returns the initial sync progress of every watched kind
+func (s *Server) getInitialSync() map[string]kubernetes.InitialSyncStatus {
+	if s.k8sClient == nil {
+		return map[string]kubernetes.InitialSyncStatus{}
+	}
+	return s.k8sClient.InitialSync()
+}
+
 // getActiveCRDs returns a list of active CRDs
 func (s *Server) getActiveCRDs() []string {
 	if s.k8sClient == nil {
@@ -167,6 +326,37 @@ func (s *Server) getActiveCRDs() []string {
 	return activeCRDs
 }
 
+// getHandlers returns every registered handler and whether it is enabled
+func (s *Server) getHandlers() []HandlerInfo {
+	var enabled map[string]registry.Handler
+	if s.k8sClient != nil {
+		enabled = s.k8sClient.GetHandlers()
+	}
+	registrations := registry.All()
+	infos := make([]HandlerInfo, 0, len(registrations))
+	for _, registration := range registrations {
+		_, ok := enabled[registration.Kind]
+		infos = append(infos, HandlerInfo{
+			Kind:              registration.Kind,
+			Group:             registration.GVR.Group,
+			Version:           registration.GVR.Version,
+			Resource:          registration.GVR.Resource,
+			Namespaced:        registration.Namespaced,
+			RequiresClientset: registration.RequiresClientset,
+			Enabled:           ok,
+		})
+	}
+	return infos
+}
+
+// getPausedHandlers returns the handlers paused through the admin API
+func (s *Server) getPausedHandlers() []kubernetes.HandlerState {
+	if s.k8sClient == nil {
+		return []kubernetes.HandlerState{}
+	}
+	return s.k8sClient.PausedHandlers()
+}
+
 // getResourceCounts returns the count of resources in Neo4j
 func (s *Server) getResourceCounts() map[string]int {
 	if s.neo4jClient == nil || s.k8sClient == nil {
@@ -199,7 +389,7 @@ func (s *Server) getResourceCounts() map[string]int {
 func (s *Server) getResourceCount(ctx context.Context, resourceType string) (int, error) {
 	query := fmt.Sprintf("MATCH (n:%s) WHERE n.clusterName = $clusterName AND n.instanceHash = $instanceHash RETURN count(n) as count", resourceType)
 
-	session := s.neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeRead})
+	session := s.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
 	defer session.Close(ctx)
 
 	result, err := session.Run(ctx, query, map[string]interface{}{
@@ -223,6 +413,25 @@ func (s *Server) getResourceCount(ctx context.Context, resourceType string) (int
 	return int(count), nil
 }
 
+// getSyncCompleteness compares the informer cache of every watched kind with
+// the nodes this instance has written for it
+func (s *Server) getSyncCompleteness(resourceCount map[string]int) map[string]kubernetes.SyncStatus {
+	completeness := make(map[string]kubernetes.SyncStatus)
+	if s.k8sClient == nil {
+		return completeness
+	}
+
+	for kind, cached := range s.k8sClient.CachedCounts() {
+		synced, ok := resourceCount[kind]
+		if !ok {
+			// The count query failed, so completeness is unknown
+			continue
+		}
+		completeness[kind] = kubernetes.NewSyncStatus(cached, synced)
+	}
+	return completeness
+}
+
 // getSystemInfo returns system information
 func (s *Server) getSystemInfo() map[string]interface{} {
 	var m runtime.MemStats
@@ -248,13 +457,6 @@ func (s *Server) initMetrics() *Metrics {
 	registry := prometheus.NewRegistry()
 
 	metrics := &Metrics{
-		resourceEventsTotal: prometheus.NewCounterVec(
-			prometheus.CounterOpts{
-				Name: "kubegraph_resource_events_total",
-				Help: "Total number of Kubernetes resource events processed",
-			},
-			[]string{"resource_type", "event_type", "cluster_name"},
-		),
 		resourceCount: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "kubegraph_resource_count",
@@ -262,6 +464,13 @@ func (s *Server) initMetrics() *Metrics {
 			},
 			[]string{"resource_type", "cluster_name"},
 		),
+		syncCompleteness: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "kubegraph_sync_completeness_percent",
+				Help: "Percentage of the objects in the informer cache that are present in Neo4j",
+			},
+			[]string{"resource_type", "cluster_name"},
+		),
 		uptimeSeconds: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "kubegraph_uptime_seconds",
@@ -278,8 +487,8 @@ func (s *Server) initMetrics() *Metrics {
 	}
 
 	// Register metrics
-	registry.MustRegister(metrics.resourceEventsTotal)
 	registry.MustRegister(metrics.resourceCount)
+	registry.MustRegister(metrics.syncCompleteness)
 	registry.MustRegister(metrics.uptimeSeconds)
 	registry.MustRegister(metrics.neo4jConnections)
 
@@ -304,11 +513,14 @@ func (s *Server) collectMetrics(ctx context.Context, metrics *Metrics) {
 			for resourceType, count := range resourceCount {
 				metrics.resourceCount.WithLabelValues(resourceType, s.config.Kubernetes.ClusterName).Set(float64(count))
 			}
+			for resourceType, status := range s.getSyncCompleteness(resourceCount) {
+				metrics.syncCompleteness.WithLabelValues(resourceType, s.config.Kubernetes.ClusterName).Set(status.Completeness)
+			}
 
 			// Update Neo4j connection status
 			if s.neo4jClient != nil {
 				// Simple connection check
-				session := s.neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeRead})
+				session := s.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
 				_, err := session.Run(ctx, "RETURN 1", nil)
 				session.Close(ctx)
 
@@ -321,9 +533,3 @@ func (s *Server) collectMetrics(ctx context.Context, metrics *Metrics) {
 		}
 	}
 }
-
-// IncrementEventCounter increments the event counter for metrics
-func (s *Server) IncrementEventCounter(resourceType, eventType string) {
-	// This would be called from the Kubernetes handlers
-	// For now, we'll implement this when we integrate with the handlers
-}
@@ -0,0 +1,22 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s-graph/pkg/logger"
+)
+
+// handleStats handles GET /api/v1/stats, optionally limited to one cluster
+// with ?cluster= and to one team with ?team=
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.neo4jClient.Statistics(r.Context(), r.URL.Query().Get("cluster"), r.URL.Query().Get("team"))
+	if err != nil {
+		logger.Error("Failed to compute graph statistics: %v", err)
+		http.Error(w, err.Error(), queryStatus(r.Context()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
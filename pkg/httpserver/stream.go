@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s-graph/pkg/neo4j"
+)
+
+const (
+	// streamBuffer is the number of mutations kept for a slow stream client
+	// before further mutations are dropped for it
+	streamBuffer = 256
+	// streamKeepAlive is the interval of the comments keeping idle streams
+	// open through proxies
+	streamKeepAlive = 30 * time.Second
+)
+
+// mutationFilter selects the mutations sent to a stream client. Empty sets
+// match everything.
+type mutationFilter struct {
+	entities   map[string]bool
+	kinds      map[string]bool
+	operations map[string]bool
+}
+
+// newMutationFilter reads the comma-separated ?entity=, ?kind= and
+// ?operation= parameters
+func newMutationFilter(r *http.Request) mutationFilter {
+	set := func(name string) map[string]bool {
+		values := make(map[string]bool)
+		for _, value := range strings.Split(r.URL.Query().Get(name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values[value] = true
+			}
+		}
+		return values
+	}
+	return mutationFilter{entities: set("entity"), kinds: set("kind"), operations: set("operation")}
+}
+
+// matches reports whether a mutation is selected by the filter
+func (f mutationFilter) matches(mutation neo4j.Mutation) bool {
+	return (len(f.entities) == 0 || f.entities[mutation.Entity]) &&
+		(len(f.kinds) == 0 || f.kinds[mutation.Kind]) &&
+		(len(f.operations) == 0 || f.operations[mutation.Operation])
+}
+
+// handleStream handles GET /api/v1/stream, a Server-Sent Events stream of the
+// mutations written to the graph from now on
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	filter := newMutationFilter(r)
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	mutations, unsubscribe := s.neo4jClient.Subscribe(streamBuffer)
+	defer unsubscribe()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case mutation := <-mutations:
+			if !filter.matches(mutation) {
+				continue
+			}
+			data, err := json.Marshal(mutation)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: mutation\ndata: %s\n\n", data)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
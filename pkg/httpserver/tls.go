@@ -0,0 +1,113 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/logger"
+)
+
+// certificateReloadInterval is how often the certificate files are checked
+// for changes, such as the renewal of a cert-manager Secret
+const certificateReloadInterval = 30 * time.Second
+
+// certificateReloader serves a certificate from files and picks up new
+// versions of them. The files are polled, which also follows the symlink
+// swaps of Secret volumes.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newCertificateReloader loads the certificate of the given files
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload reads the files again and reports whether the certificate changed.
+// On error, the previous certificate is kept.
+func (r *certificateReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	r.mu.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	// The certificate and key are updated one after the other, so they may
+	// briefly not match
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("failed to load key pair: %w", err)
+	}
+	r.mu.Lock()
+	r.cert, r.certPEM, r.keyPEM = &cert, certPEM, keyPEM
+	r.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the certificate until ctx is done
+func (r *certificateReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				logger.Warn("Failed to reload HTTP server certificate %s: %v", r.certFile, err)
+			} else if changed {
+				logger.Info("Reloaded HTTP server certificate %s", r.certFile)
+			}
+		}
+	}
+}
+
+// httpsRedirect redirects requests to the same host and path on the HTTPS port
+func httpsRedirect(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
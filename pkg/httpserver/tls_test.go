@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate for name and its key to dir
+func writeKeyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, r *certificateReloader) string {
+	t.Helper()
+	cert, _ := r.GetCertificate(nil)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "kubegraph.example.com")
+
+	r, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	if name := commonName(t, r); name != "kubegraph.example.com" {
+		t.Errorf("Expected kubegraph.example.com, got %s", name)
+	}
+	if changed, err := r.reload(); changed || err != nil {
+		t.Errorf("Expected no change, got %v (%v)", changed, err)
+	}
+
+	// A certificate without its new key is rejected and the previous one kept
+	renewed := t.TempDir()
+	newCert, newKey := writeKeyPair(t, renewed, "kubegraph.internal")
+	data, _ := os.ReadFile(newCert)
+	os.WriteFile(certFile, data, 0600)
+	if _, err := r.reload(); err == nil {
+		t.Error("Expected mismatched certificate and key to fail")
+	}
+	if name := commonName(t, r); name != "kubegraph.example.com" {
+		t.Errorf("Expected the previous certificate to be kept, got %s", name)
+	}
+
+	data, _ = os.ReadFile(newKey)
+	os.WriteFile(keyFile, data, 0600)
+	if changed, err := r.reload(); !changed || err != nil {
+		t.Errorf("Expected the renewed certificate to be loaded, got %v (%v)", changed, err)
+	}
+	if name := commonName(t, r); name != "kubegraph.internal" {
+		t.Errorf("Expected kubegraph.internal, got %s", name)
+	}
+
+	if _, err := newCertificateReloader(filepath.Join(dir, "missing.crt"), keyFile); err == nil {
+		t.Error("Expected a missing certificate to fail")
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		port     int
+		host     string
+		expected string
+	}{
+		{8443, "kubegraph:8080", "https://kubegraph:8443/api/v1/stats?cluster=prod"},
+		{443, "kubegraph.example.com", "https://kubegraph.example.com/api/v1/stats?cluster=prod"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://"+test.host+"/api/v1/stats?cluster=prod", nil)
+		w := httptest.NewRecorder()
+		httpsRedirect(test.port).ServeHTTP(w, r)
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != test.expected {
+			t.Errorf("Expected redirect to %s, got %d %s", test.expected, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+	"k8s-graph/pkg/site"
+
+	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+const (
+	// defaultSearchResults limits the resources listed by a search without ?limit=
+	defaultSearchResults = 50
+	// defaultNeighborhood limits the relationships loaded when a resource is
+	// expanded without ?limit=
+	defaultNeighborhood = 200
+)
+
+// uiExcludedLabels are the history and audit nodes of resources, which are
+// not shown in the explorer
+var uiExcludedLabels = []string{"ResourceVersion", "GraphChange", neo4j.MetaLabel}
+
+// GraphSearchResponse represents the response for the graph search endpoint
+type GraphSearchResponse struct {
+	Nodes     []site.Node `json:"nodes"`
+	Truncated bool        `json:"truncated"` // More resources matched than listed
+}
+
+// NeighborhoodResponse represents the response for the neighborhood endpoint
+type NeighborhoodResponse struct {
+	Nodes     []site.Node `json:"nodes"`
+	Edges     []site.Edge `json:"edges"`
+	Truncated bool        `json:"truncated"` // The resource has more relationships than loaded
+}
+
+// registerUIRoutes registers the graph explorer and the read-only endpoints
+// it loads resources from
+func (s *Server) registerUIRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently))
+	mux.HandleFunc("GET /ui/{$}", s.require(config.HTTPRoleRead, s.handleUI))
+	mux.HandleFunc("GET /api/v1/graph/search", s.require(config.HTTPRoleRead, s.query(s.handleGraphSearch)))
+	mux.HandleFunc("GET /api/v1/graph/neighborhood", s.require(config.HTTPRoleRead, s.query(s.handleNeighborhood)))
+	logger.Info("Graph explorer enabled on /ui/")
+}
+
+// handleUI handles GET /ui/
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	page, err := site.Explorer()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// handleGraphSearch handles GET /api/v1/graph/search, matching resources whose
+// name contains ?name= and optionally filtered by ?namespace=, ?kind= and ?cluster=
+func (s *Server) handleGraphSearch(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.limiter.limit(r, defaultSearchResults)
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	params := map[string]interface{}{
+		"name":      strings.ToLower(query.Get("name")),
+		"namespace": query.Get("namespace"),
+		"kind":      query.Get("kind"),
+		"cluster":   query.Get("cluster"),
+		"excluded":  uiExcludedLabels,
+		"limit":     limit + 1,
+	}
+
+	records, err := s.readGraph(r.Context(), `
+		MATCH (n)
+		WHERE toLower(coalesce(n.name, '')) CONTAINS $name
+		  AND ($namespace = '' OR n.namespace = $namespace)
+		  AND ($kind = '' OR $kind IN labels(n))
+		  AND ($cluster = '' OR n.clusterName = $cluster)
+		  AND NOT any(l IN labels(n) WHERE l IN $excluded)
+		RETURN n
+		ORDER BY labels(n)[0], n.namespace, n.name
+		LIMIT $limit`, params)
+	if err != nil {
+		logger.Error("Failed to search the graph: %v", err)
+		http.Error(w, err.Error(), queryStatus(r.Context()))
+		return
+	}
+
+	response := GraphSearchResponse{Nodes: make([]site.Node, 0, len(records))}
+	for _, record := range records {
+		if len(response.Nodes) == limit {
+			response.Truncated = true
+			break
+		}
+		if node, ok := record.Values[0].(driverneo4j.Node); ok {
+			response.Nodes = append(response.Nodes, site.NewNode(node.ElementId, node.Labels, node.Props))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleNeighborhood handles GET /api/v1/graph/neighborhood, returning the
+// resource with the ID ?id= and its direct neighbors
+func (s *Server) handleNeighborhood(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Missing id", http.StatusBadRequest)
+		return
+	}
+	limit, err := s.limiter.limit(r, defaultNeighborhood)
+	if err != nil {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	records, err := s.readGraph(r.Context(), fmt.Sprintf(`
+		MATCH (n)
+		WHERE %s = $id
+		OPTIONAL MATCH (n)-[r]-(b)
+		WHERE NOT any(l IN labels(b) WHERE l IN $excluded)
+		RETURN n, r, b
+		LIMIT $limit`, s.neo4jClient.ElementID("n")),
+		map[string]interface{}{"id": id, "excluded": uiExcludedLabels, "limit": limit + 1})
+	if err != nil {
+		logger.Error("Failed to load the neighborhood of %s: %v", id, err)
+		http.Error(w, err.Error(), queryStatus(r.Context()))
+		return
+	}
+	if len(records) == 0 {
+		http.Error(w, "Unknown resource "+id, http.StatusNotFound)
+		return
+	}
+
+	response := NeighborhoodResponse{Nodes: make([]site.Node, 0), Edges: make([]site.Edge, 0)}
+	seen := make(map[string]bool)
+	for i, record := range records {
+		if i == limit {
+			response.Truncated = true
+			break
+		}
+		for _, value := range []interface{}{record.Values[0], record.Values[2]} {
+			node, ok := value.(driverneo4j.Node)
+			if !ok || seen[node.ElementId] {
+				continue
+			}
+			seen[node.ElementId] = true
+			response.Nodes = append(response.Nodes, site.NewNode(node.ElementId, node.Labels, node.Props))
+		}
+		if relationship, ok := record.Values[1].(driverneo4j.Relationship); ok {
+			response.Edges = append(response.Edges, site.Edge{
+				Source: relationship.StartElementId,
+				Target: relationship.EndElementId,
+				Type:   relationship.Type,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// readGraph runs a read query for the explorer
+func (s *Server) readGraph(ctx context.Context, query string, params map[string]interface{}) ([]*driverneo4j.Record, error) {
+	result, err := s.neo4jClient.ExecuteQueryRead(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		records, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+		return records.Collect(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]*driverneo4j.Record), nil
+}
@@ -0,0 +1,364 @@
+package incidents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+
+	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var incidentsOpenedTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_incidents_opened_total",
+		Help: "Total number of incidents opened from correlated events, by severity",
+	},
+	[]string{"severity"},
+)
+
+// Workload is a workload and the recent events attributed to it
+type Workload struct {
+	Kind      string
+	UID       string
+	Namespace string
+	Name      string
+	Events    []Event
+}
+
+// summarized reports whether the eventSummary of the workload is stored on its node
+func (w Workload) summarized() bool {
+	return w.Kind == "Deployment" || w.Kind == "StatefulSet"
+}
+
+// Correlator periodically attributes recent Events to the workloads owning
+// the objects they involve, stores a scored eventSummary on Deployments and
+// StatefulSets and groups the warnings of a workload into Incident nodes
+type Correlator struct {
+	config      *config.Config
+	neo4jClient *neo4j.Client
+}
+
+// NewCorrelator creates a new event correlator
+func NewCorrelator(cfg *config.Config, neo4jClient *neo4j.Client) *Correlator {
+	return &Correlator{
+		config:      cfg,
+		neo4jClient: neo4jClient,
+	}
+}
+
+// Start runs the correlator every configured interval until ctx is done
+func (c *Correlator) Start(ctx context.Context) {
+	interval := time.Duration(c.config.EventCorrelation.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		logger.Warn("[INCIDENTS] Invalid interval %v, event correlation not started", interval)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Run(ctx); err != nil {
+				logger.Error("[INCIDENTS] Correlation run failed: %v", err)
+			}
+		}
+	}
+}
+
+// Run correlates the events of the window once, updates the workload
+// summaries and opens, updates or resolves incidents
+func (c *Correlator) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	workloads, err := c.collect(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to collect events: %w", err)
+	}
+	open, err := c.openIncidents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load open incidents: %w", err)
+	}
+
+	opened := 0
+	for _, workload := range workloads {
+		summary := Summarize(workload.Events)
+		if workload.summarized() {
+			if err := c.saveSummary(ctx, workload, summary, now); err != nil {
+				logger.Error("[INCIDENTS] Failed to save event summary of %s %s/%s: %v", workload.Kind, workload.Namespace, workload.Name, err)
+			}
+		}
+		if summary.Warnings == 0 {
+			continue
+		}
+
+		uid, ok := open[workload.UID]
+		if !ok {
+			uid = fmt.Sprintf("%s/%s/%s", c.config.Kubernetes.ClusterName, workload.UID, now.Format(time.RFC3339))
+		}
+		delete(open, workload.UID)
+		if err := c.saveIncident(ctx, uid, workload, summary, now); err != nil {
+			logger.Error("[INCIDENTS] Failed to save incident for %s %s/%s: %v", workload.Kind, workload.Namespace, workload.Name, err)
+			continue
+		}
+		if !ok {
+			opened++
+			incidentsOpenedTotal.WithLabelValues(summary.Severity).Inc()
+			logger.Warn("[INCIDENTS] %s incident on %s %s/%s: %d warnings, mostly %s (score %d)",
+				summary.Severity, workload.Kind, workload.Namespace, workload.Name, summary.Warnings, summary.TopReason(), summary.Score)
+		}
+	}
+
+	// Incidents without warnings in the window are over
+	for _, uid := range open {
+		if err := c.resolveIncident(ctx, uid, now); err != nil {
+			logger.Error("[INCIDENTS] Failed to resolve incident %s: %v", uid, err)
+		}
+	}
+
+	if err := c.clearSummaries(ctx, now); err != nil {
+		logger.Error("[INCIDENTS] Failed to clear stale event summaries: %v", err)
+	}
+	if err := c.prune(ctx, now); err != nil {
+		logger.Error("[INCIDENTS] Failed to prune resolved incidents: %v", err)
+	}
+
+	logger.Debug("[INCIDENTS] Correlation run completed, %d workloads with events, %d incidents opened, %d resolved",
+		len(workloads), opened, len(open))
+	return nil
+}
+
+// collect returns the workloads with events in the window. Events involving
+// a pod or ReplicaSet are attributed to the Deployment, StatefulSet,
+// DaemonSet, Job or CronJob at the top of its owner chain; events on
+// standalone pods and cluster-scoped objects are ignored.
+func (c *Correlator) collect(ctx context.Context, now time.Time) ([]Workload, error) {
+	query := `
+		MATCH (e:Event {clusterName: $clusterName})-[:INVOLVES]->(x)
+		WHERE e.createdAt >= $since
+		OPTIONAL MATCH (x)-[:OWNED_BY]->(o)
+		OPTIONAL MATCH (o)-[:OWNED_BY]->(top)
+		WHERE (o:ReplicaSet AND top:Deployment) OR (o:Job AND top:CronJob)
+		WITH e, x, coalesce(top, o, x) AS w
+		WHERE w:Deployment OR w:StatefulSet OR w:DaemonSet OR w:Job OR w:CronJob
+		RETURN labels(w)[0] AS kind, w.uid AS uid, w.namespace AS namespace, w.name AS name,
+		       e.uid AS eventUid, e.type AS type, e.reason AS reason, toInteger(e.count) AS count, x.uid AS objectUid`
+	since := now.Add(-time.Duration(c.config.EventCorrelation.WindowMinutes) * time.Minute)
+
+	session := c.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, query, map[string]interface{}{
+		"clusterName": c.config.Kubernetes.ClusterName,
+		"since":       since.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := make(map[string]*Workload)
+	for result.Next(ctx) {
+		record := result.Record()
+		uid, _ := record.Values[1].(string)
+		if uid == "" {
+			continue
+		}
+		workload, ok := workloads[uid]
+		if !ok {
+			kind, _ := record.Values[0].(string)
+			namespace, _ := record.Values[2].(string)
+			name, _ := record.Values[3].(string)
+			workload = &Workload{Kind: kind, UID: uid, Namespace: namespace, Name: name}
+			workloads[uid] = workload
+		}
+		eventUID, _ := record.Values[4].(string)
+		eventType, _ := record.Values[5].(string)
+		reason, _ := record.Values[6].(string)
+		count, _ := record.Values[7].(int64)
+		objectUID, _ := record.Values[8].(string)
+		workload.Events = append(workload.Events, Event{
+			UID:       eventUID,
+			Type:      eventType,
+			Reason:    reason,
+			Count:     int(count),
+			ObjectUID: objectUID,
+		})
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Workload, 0, len(workloads))
+	for _, workload := range workloads {
+		sorted = append(sorted, *workload)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UID < sorted[j].UID })
+	return sorted, nil
+}
+
+// openIncidents returns the uids of the open incidents of this cluster, keyed by workload uid
+func (c *Correlator) openIncidents(ctx context.Context) (map[string]string, error) {
+	session := c.neo4jClient.NewSession(ctx, driverneo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	result, err := session.Run(ctx, `
+		MATCH (i:Incident {clusterName: $clusterName, status: 'open'})
+		RETURN i.workloadUid AS workloadUid, i.uid AS uid`,
+		map[string]interface{}{"clusterName": c.config.Kubernetes.ClusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	open := make(map[string]string)
+	for result.Next(ctx) {
+		record := result.Record()
+		workloadUID, _ := record.Values[0].(string)
+		uid, _ := record.Values[1].(string)
+		open[workloadUID] = uid
+	}
+	return open, result.Err()
+}
+
+// saveSummary stores the rolled-up events on the workload node without
+// touching the properties written by its handler
+func (c *Correlator) saveSummary(ctx context.Context, workload Workload, summary Summary, now time.Time) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = c.neo4jClient.ExecuteWrite(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, fmt.Sprintf(`
+			MATCH (w:%s {uid: $uid})
+			SET w.eventSummary = $summary, w.eventSeverity = $severity, w.eventSeverityScore = $score,
+			    w.eventSummaryUpdatedAt = $now`, workload.Kind),
+			map[string]interface{}{
+				"uid":      workload.UID,
+				"summary":  string(data),
+				"severity": summary.Severity,
+				"score":    summary.Score,
+				"now":      now.Format(time.RFC3339),
+			})
+		return nil, err
+	})
+	return err
+}
+
+// saveIncident creates or updates an incident, links it to the workload it
+// affects and to the events it correlates
+func (c *Correlator) saveIncident(ctx context.Context, uid string, workload Workload, summary Summary, now time.Time) error {
+	reasons, err := json.Marshal(summary.Reasons)
+	if err != nil {
+		return err
+	}
+	events := make([]string, 0, len(workload.Events))
+	for _, event := range workload.Events {
+		if event.Type == "Warning" && event.UID != "" {
+			events = append(events, event.UID)
+		}
+	}
+
+	properties := map[string]interface{}{
+		"status":       "open",
+		"severity":     summary.Severity,
+		"score":        summary.Score,
+		"warnings":     summary.Warnings,
+		"objects":      summary.Objects,
+		"reasons":      string(reasons),
+		"topReason":    summary.TopReason(),
+		"workloadKind": workload.Kind,
+		"workloadUid":  workload.UID,
+		"namespace":    workload.Namespace,
+		"name":         workload.Name,
+		"lastSeenAt":   now.Format(time.RFC3339),
+		"clusterName":  c.config.Kubernetes.ClusterName,
+		// Incidents should not have instanceHash as they should persist across restarts
+	}
+
+	_, err = c.neo4jClient.ExecuteWrite(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		params := map[string]interface{}{
+			"uid":         uid,
+			"workloadUid": workload.UID,
+			"properties":  properties,
+			"now":         now.Format(time.RFC3339),
+			"events":      events,
+		}
+		queries := []string{
+			`MERGE (i:Incident {uid: $uid})
+			 ON CREATE SET i.startedAt = $now
+			 SET i += $properties`,
+			fmt.Sprintf(`
+			MATCH (i:Incident {uid: $uid}), (w:%s {uid: $workloadUid})
+			MERGE (i)-[:AFFECTS]->(w)`, workload.Kind),
+			`MATCH (i:Incident {uid: $uid})
+			 UNWIND $events AS eventUid
+			 MATCH (e:Event {uid: eventUid})
+			 MERGE (i)-[:CORRELATES]->(e)`,
+		}
+		for _, query := range queries {
+			if _, err := tx.Run(ctx, query, params); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// resolveIncident closes an incident whose workload had no warnings in the window
+func (c *Correlator) resolveIncident(ctx context.Context, uid string, now time.Time) error {
+	_, err := c.neo4jClient.ExecuteWrite(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			MATCH (i:Incident {uid: $uid})
+			SET i.status = 'resolved', i.resolvedAt = $now`,
+			map[string]interface{}{"uid": uid, "now": now.Format(time.RFC3339)})
+		return nil, err
+	})
+	return err
+}
+
+// clearSummaries removes the event summaries that were not refreshed by this
+// run, as the workload had no events in the window
+func (c *Correlator) clearSummaries(ctx context.Context, now time.Time) error {
+	_, err := c.neo4jClient.ExecuteWrite(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			MATCH (w {clusterName: $clusterName})
+			WHERE (w:Deployment OR w:StatefulSet) AND w.eventSummaryUpdatedAt < $now
+			REMOVE w.eventSummary, w.eventSeverity, w.eventSeverityScore, w.eventSummaryUpdatedAt`,
+			map[string]interface{}{
+				"clusterName": c.config.Kubernetes.ClusterName,
+				"now":         now.Format(time.RFC3339),
+			})
+		return nil, err
+	})
+	return err
+}
+
+// prune deletes resolved incidents that are older than the retention period
+func (c *Correlator) prune(ctx context.Context, now time.Time) error {
+	if c.config.EventCorrelation.RetentionDays <= 0 {
+		return nil
+	}
+	cutoff := now.Add(-time.Duration(c.config.EventCorrelation.RetentionDays) * 24 * time.Hour).Format(time.RFC3339)
+
+	session := c.neo4jClient.NewSession(ctx, driverneo4j.AccessModeWrite)
+	defer session.Close(ctx)
+
+	_, err := session.Run(ctx, `
+		MATCH (i:Incident {clusterName: $clusterName, status: 'resolved'})
+		WHERE i.resolvedAt < $cutoff
+		DETACH DELETE i`,
+		map[string]interface{}{
+			"clusterName": c.config.Kubernetes.ClusterName,
+			"cutoff":      cutoff,
+		})
+	return err
+}
@@ -0,0 +1,151 @@
+package incidents
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// SeverityCritical and the other levels bucket a severity score
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityNone     = "none"
+
+	// defaultReasonWeight is the weight of Warning reasons not listed in reasonWeights
+	defaultReasonWeight = 2.0
+	// maxScore caps the severity score
+	maxScore = 100
+)
+
+// reasonWeights rates how disruptive a Warning event reason is
+var reasonWeights = map[string]float64{
+	"OOMKilling":             10,
+	"Evicted":                8,
+	"BackOff":                8,
+	"NodeNotReady":           7,
+	"FailedScheduling":       6,
+	"FailedCreate":           6,
+	"Failed":                 6,
+	"ErrImagePull":           6,
+	"FailedMount":            5,
+	"FailedAttachVolume":     5,
+	"FailedPostStartHook":    5,
+	"InspectFailed":          5,
+	"FailedKillPod":          4,
+	"Unhealthy":              3,
+	"FailedPreStopHook":      3,
+	"FailedCreatePodSandBox": 3,
+	"ProbeWarning":           1,
+}
+
+// Event is an Event attributed to a workload
+type Event struct {
+	UID       string
+	Type      string
+	Reason    string
+	Count     int
+	ObjectUID string // Object the event involves, e.g. one of the workload's pods
+}
+
+// Summary is the rolled-up view of the recent events of a workload
+type Summary struct {
+	Events   int            `json:"events"`   // Occurrences of all events
+	Warnings int            `json:"warnings"` // Occurrences of Warning events
+	Objects  int            `json:"objects"`  // Distinct objects with Warning events
+	Reasons  map[string]int `json:"reasons"`  // Warning occurrences by reason
+	Score    int            `json:"score"`
+	Severity string         `json:"severity"`
+}
+
+// TopReason returns the Warning reason with the most occurrences
+func (s Summary) TopReason() string {
+	reasons := make([]string, 0, len(s.Reasons))
+	for reason := range s.Reasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if s.Reasons[reasons[i]] != s.Reasons[reasons[j]] {
+			return s.Reasons[reasons[i]] > s.Reasons[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	if len(reasons) == 0 {
+		return ""
+	}
+	return reasons[0]
+}
+
+// Summarize rolls up the events of a workload and scores them
+func Summarize(events []Event) Summary {
+	summary := Summary{Reasons: make(map[string]int)}
+	objects := make(map[string]bool)
+	for _, event := range events {
+		occurrences := occurrences(event)
+		summary.Events += occurrences
+		if event.Type != "Warning" {
+			continue
+		}
+		summary.Warnings += occurrences
+		summary.Reasons[event.Reason] += occurrences
+		if event.ObjectUID != "" {
+			objects[event.ObjectUID] = true
+		}
+	}
+	summary.Objects = len(objects)
+	summary.Score = Score(events)
+	summary.Severity = SeverityLevel(summary.Score)
+	return summary
+}
+
+// Score rates the events of a workload from 0 to 100. Every Warning event
+// contributes the weight of its reason, growing logarithmically with how often
+// it repeated, and the total is raised by up to 2x when the warnings spread
+// over several objects, e.g. several pods of a Deployment. Normal events do
+// not contribute.
+func Score(events []Event) int {
+	total := 0.0
+	objects := make(map[string]bool)
+	for _, event := range events {
+		if event.Type != "Warning" {
+			continue
+		}
+		weight, ok := reasonWeights[event.Reason]
+		if !ok {
+			weight = defaultReasonWeight
+		}
+		total += weight * (1 + math.Log2(float64(occurrences(event))))
+		if event.ObjectUID != "" {
+			objects[event.ObjectUID] = true
+		}
+	}
+	if len(objects) > 1 {
+		total *= math.Min(1+0.25*float64(len(objects)-1), 2)
+	}
+	return int(math.Min(math.Round(total), maxScore))
+}
+
+// SeverityLevel buckets a severity score
+func SeverityLevel(score int) string {
+	switch {
+	case score >= 60:
+		return SeverityCritical
+	case score >= 30:
+		return SeverityHigh
+	case score >= 10:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityNone
+	}
+}
+
+// occurrences returns how often an event happened, at least once
+func occurrences(event Event) int {
+	if event.Count < 1 {
+		return 1
+	}
+	return event.Count
+}
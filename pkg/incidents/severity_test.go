@@ -0,0 +1,68 @@
+package incidents
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		events   []Event
+		expected int
+	}{
+		{"no events", nil, 0},
+		{"normal events", []Event{{Type: "Normal", Reason: "Pulled", Count: 5, ObjectUID: "p1"}}, 0},
+		{"single warning", []Event{{Type: "Warning", Reason: "Unhealthy", Count: 1, ObjectUID: "p1"}}, 3},
+		{"unknown reason", []Event{{Type: "Warning", Reason: "Custom", ObjectUID: "p1"}}, 2},
+		{"repeated warning", []Event{{Type: "Warning", Reason: "BackOff", Count: 8, ObjectUID: "p1"}}, 32},
+		{"spread over pods", []Event{
+			{Type: "Warning", Reason: "BackOff", Count: 8, ObjectUID: "p1"},
+			{Type: "Warning", Reason: "BackOff", Count: 8, ObjectUID: "p2"},
+		}, 80},
+		{"capped", []Event{
+			{Type: "Warning", Reason: "OOMKilling", Count: 1024, ObjectUID: "p1"},
+			{Type: "Warning", Reason: "OOMKilling", Count: 1024, ObjectUID: "p2"},
+		}, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Score(test.events); got != test.expected {
+				t.Errorf("Expected score %d, got %d", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestSeverityLevel(t *testing.T) {
+	tests := map[int]string{0: SeverityNone, 3: SeverityLow, 10: SeverityMedium, 45: SeverityHigh, 60: SeverityCritical}
+	for score, expected := range tests {
+		if got := SeverityLevel(score); got != expected {
+			t.Errorf("Expected score %d to be %s, got %s", score, expected, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]Event{
+		{Type: "Warning", Reason: "Unhealthy", Count: 3, ObjectUID: "p1"},
+		{Type: "Warning", Reason: "BackOff", Count: 5, ObjectUID: "p1"},
+		{Type: "Warning", Reason: "Unhealthy", Count: 2, ObjectUID: "p2"},
+		{Type: "Normal", Reason: "Pulled", Count: 4, ObjectUID: "p3"},
+	})
+
+	if summary.Events != 14 || summary.Warnings != 10 || summary.Objects != 2 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if expected := map[string]int{"Unhealthy": 5, "BackOff": 5}; !reflect.DeepEqual(summary.Reasons, expected) {
+		t.Errorf("Expected reasons %v, got %v", expected, summary.Reasons)
+	}
+	// Ties are broken alphabetically
+	if got := summary.TopReason(); got != "BackOff" {
+		t.Errorf("Expected top reason BackOff, got %s", got)
+	}
+	if summary.Severity != SeverityLevel(summary.Score) {
+		t.Errorf("Severity %s does not match score %d", summary.Severity, summary.Score)
+	}
+}
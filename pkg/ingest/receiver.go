@@ -0,0 +1,198 @@
+package ingest
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/logger"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxRequestBytes limits the size of a single ingest request body
+const maxRequestBytes = 10 << 20
+
+// Event is a single resource change pushed by an external agent
+type Event struct {
+	// Type is ADDED, MODIFIED or DELETED (empty is treated as ADDED)
+	Type string `json:"type"`
+	// Object is the full Kubernetes manifest of the resource
+	Object map[string]interface{} `json:"object"`
+}
+
+// Response summarizes the outcome of an ingest request
+type Response struct {
+	Source   string   `json:"source"`
+	Cluster  string   `json:"cluster"`
+	Accepted int      `json:"accepted"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Ingester writes the objects of other clusters through the regular resource
+// handlers, as the Kubernetes client does
+type Ingester interface {
+	Ingest(ctx context.Context, store graph.Store, clusterName string, obj *unstructured.Unstructured, deleted bool) error
+}
+
+// Receiver accepts resource manifests and events from external agents and
+// routes them through the regular resource handlers
+type Receiver struct {
+	ingester    Ingester
+	neo4jClient graph.Store
+	sources     []config.IngestSource
+}
+
+// NewReceiver creates a new ingest receiver for the configured sources
+func NewReceiver(cfg *config.Config, ingester Ingester, neo4jClient graph.Store) *Receiver {
+	return &Receiver{
+		ingester:    ingester,
+		neo4jClient: neo4jClient,
+		sources:     cfg.Ingest.Sources,
+	}
+}
+
+// ServeHTTP handles POST /api/v1/ingest
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source, ok := r.authenticate(req)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	events, err := ParseEvents(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid ingest payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	response := Response{
+		Source:  source.Name,
+		Cluster: source.ClusterName,
+	}
+
+	for _, event := range events {
+		if err := r.dispatch(req, source.ClusterName, event); err != nil {
+			response.Failed++
+			response.Errors = append(response.Errors, err.Error())
+			continue
+		}
+		response.Accepted++
+	}
+
+	if response.Failed > 0 {
+		logger.Warn("[INGEST] Source %s (cluster %s): accepted %d, failed %d", source.Name, source.ClusterName, response.Accepted, response.Failed)
+	} else {
+		logger.Debug("[INGEST] Source %s (cluster %s): accepted %d", source.Name, source.ClusterName, response.Accepted)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// authenticate matches the request's bearer token against the configured sources
+func (r *Receiver) authenticate(req *http.Request) (config.IngestSource, bool) {
+	header := req.Header.Get("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		return config.IngestSource{}, false
+	}
+
+	for _, source := range r.sources {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(source.Token)) == 1 {
+			return source, true
+		}
+	}
+	return config.IngestSource{}, false
+}
+
+// dispatch routes a single event of the given cluster to the ingester
+func (r *Receiver) dispatch(req *http.Request, clusterName string, event Event) error {
+	obj := &unstructured.Unstructured{Object: event.Object}
+
+	var deleted bool
+	switch strings.ToUpper(event.Type) {
+	case "", "ADDED", "MODIFIED":
+	case "DELETED":
+		deleted = true
+	default:
+		return fmt.Errorf("%s %s/%s: unsupported event type %q", obj.GetKind(), obj.GetNamespace(), obj.GetName(), event.Type)
+	}
+	err := r.ingester.Ingest(req.Context(), r.neo4jClient, clusterName, obj, deleted)
+	if errors.Is(err, kubernetes.ErrUnsupportedKind) {
+		return fmt.Errorf("%s %s/%s: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s/%s (%s): %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), failure.Classify(err), err)
+	}
+	return nil
+}
+
+// ParseEvents decodes an ingest payload. Accepted shapes are a batch
+// ({"events": [...]}), a single watch-style event ({"type": ..., "object": ...}),
+// a Kubernetes List ({"kind": "PodList", "items": [...]}) or a bare manifest.
+func ParseEvents(body []byte) ([]Event, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	if _, ok := payload["events"]; ok {
+		var batch struct {
+			Events []Event `json:"events"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, fmt.Errorf("failed to decode events: %w", err)
+		}
+		for i, event := range batch.Events {
+			if event.Object == nil {
+				return nil, fmt.Errorf("event %d has no object", i)
+			}
+		}
+		return batch.Events, nil
+	}
+
+	if object, ok := payload["object"].(map[string]interface{}); ok {
+		eventType, _ := payload["type"].(string)
+		return []Event{{Type: eventType, Object: object}}, nil
+	}
+
+	if items, ok := payload["items"].([]interface{}); ok {
+		events := make([]Event, 0, len(items))
+		for i, item := range items {
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("item %d is not an object", i)
+			}
+			events = append(events, Event{Type: "ADDED", Object: object})
+		}
+		return events, nil
+	}
+
+	if _, ok := payload["kind"]; ok {
+		return []Event{{Type: "ADDED", Object: payload}}, nil
+	}
+
+	return nil, fmt.Errorf("payload is neither a manifest, a list, nor an event batch")
+}
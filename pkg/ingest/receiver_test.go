@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/logger"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		expected  int
+		firstType string
+		expectErr bool
+	}{
+		{
+			name:      "bare manifest",
+			body:      `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p1"}}`,
+			expected:  1,
+			firstType: "ADDED",
+		},
+		{
+			name:      "watch event",
+			body:      `{"type":"DELETED","object":{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p1"}}}`,
+			expected:  1,
+			firstType: "DELETED",
+		},
+		{
+			name:      "list",
+			body:      `{"kind":"PodList","items":[{"kind":"Pod","metadata":{"name":"p1"}},{"kind":"Pod","metadata":{"name":"p2"}}]}`,
+			expected:  2,
+			firstType: "ADDED",
+		},
+		{
+			name:      "event batch",
+			body:      `{"events":[{"type":"MODIFIED","object":{"kind":"Service"}},{"type":"ADDED","object":{"kind":"Pod"}}]}`,
+			expected:  2,
+			firstType: "MODIFIED",
+		},
+		{
+			name:      "batch event without object",
+			body:      `{"events":[{"type":"ADDED"}]}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid JSON",
+			body:      `{not json`,
+			expectErr: true,
+		},
+		{
+			name:      "unrecognized payload",
+			body:      `{"foo":"bar"}`,
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := ParseEvents([]byte(test.body))
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got %d events", len(events))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(events) != test.expected {
+				t.Fatalf("Expected %d events, got %d", test.expected, len(events))
+			}
+			if events[0].Type != test.firstType {
+				t.Errorf("Expected first event type %s, got %s", test.firstType, events[0].Type)
+			}
+		})
+	}
+}
+
+// fakeIngester records the ingested objects and rejects the kinds without a
+// handler like the Kubernetes client
+type fakeIngester struct {
+	ingested []string
+}
+
+func (f *fakeIngester) Ingest(ctx context.Context, store graph.Store, clusterName string, obj *unstructured.Unstructured, deleted bool) error {
+	if obj.GetKind() == "Widget" {
+		return kubernetes.ErrUnsupportedKind
+	}
+	f.ingested = append(f.ingested, fmt.Sprintf("%s %s %s/%s deleted=%v", clusterName, obj.GetKind(), obj.GetNamespace(), obj.GetName(), deleted))
+	return nil
+}
+
+func newTestReceiver() (*Receiver, *fakeIngester) {
+	logger.Init(logger.ERROR)
+
+	cfg := config.NewConfig()
+	cfg.EventTTLDays = 0
+	cfg.Ingest.Sources = []config.IngestSource{
+		{Name: "edge-a", Token: "secret-a", ClusterName: "edge-cluster-a"},
+	}
+	ingester := &fakeIngester{}
+	return NewReceiver(cfg, ingester, nil), ingester
+}
+
+func TestReceiverRejectsInvalidRequests(t *testing.T) {
+	receiver, _ := newTestReceiver()
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		expected int
+	}{
+		{"wrong method", http.MethodGet, "secret-a", http.StatusMethodNotAllowed},
+		{"missing token", http.MethodPost, "", http.StatusUnauthorized},
+		{"unknown token", http.MethodPost, "secret-b", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/api/v1/ingest", strings.NewReader(`{"kind":"Pod"}`))
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rec := httptest.NewRecorder()
+			receiver.ServeHTTP(rec, req)
+			if rec.Code != test.expected {
+				t.Errorf("Expected status %d, got %d", test.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestReceiverReportsUnsupportedKinds(t *testing.T) {
+	receiver, _ := newTestReceiver()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest", strings.NewReader(`{"kind":"Widget","metadata":{"name":"w1","namespace":"default"}}`))
+	req.Header.Set("Authorization", "Bearer secret-a")
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"failed":1`) || !strings.Contains(body, "unsupported kind") {
+		t.Errorf("Expected unsupported kind failure in response, got %s", body)
+	}
+	if !strings.Contains(body, `"cluster":"edge-cluster-a"`) {
+		t.Errorf("Expected response to be attributed to edge-cluster-a, got %s", body)
+	}
+}
+
+func TestReceiverDispatchesToIngester(t *testing.T) {
+	receiver, ingester := newTestReceiver()
+
+	body := `{"events":[{"type":"MODIFIED","object":{"kind":"Pod","metadata":{"name":"p1","namespace":"shop"}}},{"type":"DELETED","object":{"kind":"Pod","metadata":{"name":"p2","namespace":"shop"}}},{"type":"BOOKMARK","object":{"kind":"Pod"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret-a")
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+
+	expected := []string{"edge-cluster-a Pod shop/p1 deleted=false", "edge-cluster-a Pod shop/p2 deleted=true"}
+	if fmt.Sprint(ingester.ingested) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v to be ingested, got %v", expected, ingester.ingested)
+	}
+	if response := rec.Body.String(); !strings.Contains(response, `"accepted":2`) || !strings.Contains(response, "unsupported event type") {
+		t.Errorf("Expected 2 accepted events and the bookmark to fail, got %s", response)
+	}
+}
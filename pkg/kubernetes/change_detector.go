@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"k8s-graph/pkg/lru"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+var unchangedObjectsSkippedTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "kubegraph_unchanged_objects_skipped_total",
+		Help: "Total number of informer events skipped because the object's resourceVersion was already processed",
+	},
+)
+
+// ChangeDetector remembers the last processed resourceVersion of each object so
+// that informer resyncs, which redeliver unchanged objects, do not hit Neo4j
+type ChangeDetector struct {
+	versions *lru.Cache[string, string]
+}
+
+// NewChangeDetector creates a change detector tracking up to size objects (0 disables it)
+func NewChangeDetector(size int) *ChangeDetector {
+	return &ChangeDetector{
+		versions: lru.New[string, string](size),
+	}
+}
+
+// Changed reports whether obj differs from the last processed version of the same object
+func (d *ChangeDetector) Changed(obj interface{}) bool {
+	uid, version := objectVersion(obj)
+	if uid == "" || version == "" {
+		return true
+	}
+	if last, ok := d.versions.Get(uid); ok && last == version {
+		unchangedObjectsSkippedTotal.Inc()
+		return false
+	}
+	return true
+}
+
+// Record marks obj as processed
+func (d *ChangeDetector) Record(obj interface{}) {
+	uid, version := objectVersion(obj)
+	if uid == "" || version == "" {
+		return
+	}
+	d.versions.Add(uid, version)
+}
+
+// Forget drops the tracked version of obj, e.g. after it was deleted
+func (d *ChangeDetector) Forget(obj interface{}) {
+	if uid := objectUID(obj); uid != "" {
+		d.versions.Remove(uid)
+	}
+}
+
+// objectVersion returns the UID and resourceVersion of a watched object
+func objectVersion(obj interface{}) (string, string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", ""
+	}
+	return string(accessor.GetUID()), accessor.GetResourceVersion()
+}
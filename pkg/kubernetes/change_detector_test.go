@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestObject(uid, resourceVersion string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind("Pod")
+	obj.SetName("pod-" + uid)
+	obj.SetUID(types.UID("uid-" + uid))
+	obj.SetResourceVersion(resourceVersion)
+	return obj
+}
+
+func TestChangeDetector(t *testing.T) {
+	d := NewChangeDetector(10)
+
+	if !d.Changed(newTestObject("1", "100")) {
+		t.Error("Expected unseen object to be changed")
+	}
+
+	d.Record(newTestObject("1", "100"))
+	if d.Changed(newTestObject("1", "100")) {
+		t.Error("Expected resync with same resourceVersion to be unchanged")
+	}
+	if !d.Changed(newTestObject("1", "101")) {
+		t.Error("Expected new resourceVersion to be changed")
+	}
+
+	d.Forget(newTestObject("1", "100"))
+	if !d.Changed(newTestObject("1", "100")) {
+		t.Error("Expected forgotten object to be changed")
+	}
+}
+
+func TestChangeDetectorDisabled(t *testing.T) {
+	d := NewChangeDetector(0)
+	d.Record(newTestObject("1", "100"))
+	if !d.Changed(newTestObject("1", "100")) {
+		t.Error("Expected disabled change detector to report every object as changed")
+	}
+}
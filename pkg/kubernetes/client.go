@@ -6,14 +6,24 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"kubegraph/config"
-	"kubegraph/pkg/kubernetes/handlers"
-	"kubegraph/pkg/logger"
-	"kubegraph/pkg/neo4j"
-
-	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+	"k8s-graph/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -27,18 +37,6 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
-// ResourceHandler defines the interface for handling Kubernetes resources
-type ResourceHandler interface {
-	// GetGVR returns the GroupVersionResource for this handler
-	GetGVR() schema.GroupVersionResource
-	// GetKind returns the kind of resource this handler manages
-	GetKind() string
-	// HandleCreate handles creation/update events
-	HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error
-	// HandleDelete handles deletion events
-	HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error
-}
-
 // BaseHandler provides common functionality for resource handlers
 type BaseHandler struct {
 	gvr  schema.GroupVersionResource
@@ -58,8 +56,24 @@ type Client struct {
 	clientset       *kubernetes.Clientset
 	dynamicClient   dynamic.Interface
 	informerFactory dynamicinformer.DynamicSharedInformerFactory
-	handlers        map[string]handlers.ResourceHandler
+	discovery       *ResourceDiscovery                  // nil in tests, which fall back to the registrations
+	handlers        map[string]handlers.ResourceHandler // all handlers, including the disabled ones
+	handlersMu      sync.RWMutex
+	disabled        map[string]bool // kinds whose handlers are disabled
 	config          *config.Config
+	coalescer       *Coalescer
+	changes         *ChangeDetector
+	gate            *HandlerGate
+	namespaces      atomic.Pointer[NamespaceFilter]
+	deadLetters     *DeadLetterStore
+	watchCtx        context.Context
+	neo4jClient     graph.Store
+	informersMu     sync.RWMutex
+	informers       map[string]cache.SharedInformer // informers of the watched kinds
+	initialSync     *InitialSyncTracker
+	recorder        *EventRecorder // records the watch events, nil when disabled
+	ingestMu        sync.Mutex
+	ingested        map[string]map[string]handlers.ResourceHandler // clusterName -> kind -> handler of ingested objects
 }
 
 // NewClient creates a new Kubernetes client
@@ -97,6 +111,9 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	config.QPS = 50    // Increase QPS from default 5
 	config.Burst = 100 // Increase Burst from default 10
 	config.Timeout = 30 * time.Second
+	if tracing.Enabled() {
+		config.Wrap(tracing.Transport)
+	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -111,13 +128,32 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	// Create informer factory with longer resync period
 	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 5*time.Minute)
 
+	deadLetters, err := NewDeadLetterStore(cfg.DeadLetter.Capacity, cfg.DeadLetter.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder, err := NewEventRecorder(cfg.Replay.RecordPath)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &Client{
 		clientset:       clientset,
 		dynamicClient:   dynamicClient,
 		informerFactory: informerFactory,
+		discovery:       NewResourceDiscovery(clientset.Discovery()),
 		handlers:        make(map[string]handlers.ResourceHandler),
 		config:          cfg,
+		coalescer:       NewCoalescer(time.Duration(cfg.Sync.CoalesceWindowMs) * time.Millisecond),
+		changes:         NewChangeDetector(cfg.Sync.ChangeCacheSize),
+		gate:            NewHandlerGate(),
+		deadLetters:     deadLetters,
+		informers:       make(map[string]cache.SharedInformer),
+		initialSync:     NewInitialSyncTracker(),
+		recorder:        recorder,
 	}
+	client.namespaces.Store(NewNamespaceFilter(cfg.Filters.Namespaces, cfg.Filters.ExcludeNamespaces))
 
 	// Register resource handlers
 	client.registerHandlers()
@@ -125,133 +161,69 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	return client, nil
 }
 
-// registerHandlers registers all resource handlers
+// registerHandlers registers all resource handlers and marks the disabled ones,
+// which are not watched until they are enabled by a configuration reload
 func (c *Client) registerHandlers() {
-	resourceHandlers := []handlers.ResourceHandler{
-		handlers.NewNodeHandler(c.config),
-		handlers.NewPodHandler(c.clientset, c.config),
-		handlers.NewServiceHandler(c.clientset, c.config),
-		handlers.NewConfigMapHandler(c.config),
-		handlers.NewSecretHandler(c.config),
-		handlers.NewDeploymentHandler(c.clientset, c.config),
-		handlers.NewReplicaSetHandler(c.clientset, c.config),
-		handlers.NewStatefulSetHandler(c.clientset, c.config),
-		handlers.NewDaemonSetHandler(c.clientset, c.config),
-		handlers.NewJobHandler(c.clientset, c.config),
-		handlers.NewCronJobHandler(c.clientset, c.config),
-		handlers.NewPVHandler(c.config),
-		handlers.NewPVCHandler(c.config),
-		handlers.NewStorageClassHandler(c.config),
-		handlers.NewNeo4jDatabaseHandler(c.config),
-		handlers.NewNeo4jClusterHandler(c.config),
-		handlers.NewNeo4jSingleInstanceHandler(c.config),
-		handlers.NewNeo4jRoleHandler(c.config),
-		handlers.NewIPAccessControlHandler(c.config),
-		handlers.NewCustomEndpointHandler(c.config),
-		handlers.NewBackupScheduleHandler(c.config),
-		handlers.NewDomainNameHandler(c.config),
-		handlers.NewNamespaceHandler(c.config),
-		handlers.NewServiceAccountHandler(c.config),
-		handlers.NewHorizontalPodAutoscalerHandler(c.config),
-		handlers.NewVerticalPodAutoscalerHandler(c.config),
-		handlers.NewPodDisruptionBudgetHandler(c.config),
-		handlers.NewLimitRangeHandler(c.config),
-		handlers.NewIngressHandler(c.config),
-		handlers.NewEndpointsHandler(c.config),
-		handlers.NewNetworkPolicyHandler(c.config),
-	}
-	if c.config.EventTTLDays > 0 {
-		resourceHandlers = append(resourceHandlers, handlers.NewEventHandler(c.config))
-	}
-
-	for _, handler := range resourceHandlers {
+	for _, handler := range NewResourceHandlers(c.clientset, c.config) {
 		c.handlers[handler.GetKind()] = handler
 	}
+	c.disabled = c.knownKinds(c.config.Handlers.Disabled)
+	for kind := range c.disabled {
+		logger.Info("Handler for %s is disabled", kind)
+	}
 }
 
-// StartWatching starts watching Kubernetes resources
-func (c *Client) StartWatching(ctx context.Context, neo4jClient *neo4j.Client) error {
-	logger.Info("Starting to watch Kubernetes resources...")
+// NewResourceHandlers creates the resource handlers for the given configuration.
+// The clientset may be nil when objects do not come from the local cluster, in
+// which case handlers skip lookups that would query the API server.
+func NewResourceHandlers(clientset *kubernetes.Clientset, cfg *config.Config) []handlers.ResourceHandler {
+	return registry.Handlers(clientset, cfg)
+}
 
-	// Set up informers for each handler
-	informers := make([]cache.SharedInformer, 0, len(c.handlers))
-	skippedHandlers := make([]string, 0)
+// StartWatching starts watching Kubernetes resources, writing them to store
+func (c *Client) StartWatching(ctx context.Context, neo4jClient graph.Store) error {
+	logger.Info("Starting to watch Kubernetes resources...")
+	c.watchCtx = ctx
+	c.neo4jClient = neo4jClient
 
+	enabled := make([]handlers.ResourceHandler, 0, len(c.handlers))
 	for _, handler := range c.handlers {
-		// Create a new variable in this scope to avoid closure issues
-		h := handler
-		logger.Info("Setting up informer for resource type: %s", h.GetKind())
-
-		// Check if the resource exists before setting up the informer
-		gvr := h.GetGVR()
-		var err error
-
-		// For namespaced resources, try listing in default namespace
-		// For cluster-scoped resources, list without namespace
-		if c.isNamespacedResource(gvr) {
-			_, err = c.dynamicClient.Resource(gvr).Namespace("default").List(ctx, metav1.ListOptions{Limit: 1})
-		} else {
-			_, err = c.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{Limit: 1})
+		if c.isDisabled(handler.GetKind()) {
+			continue
 		}
+		enabled = append(enabled, handler)
+		// Later tiers are reported as pending until they start
+		c.initialSync.Expect(handler.GetKind())
+	}
+	go c.reportInitialSync(ctx, 5*time.Second)
 
-		if err != nil {
-			errorMsg := err.Error()
-			logger.Debug("Resource check failed for %s (%s): %v", h.GetKind(), gvr.String(), err)
-			if strings.Contains(errorMsg, "the server could not find the requested resource") ||
-				strings.Contains(errorMsg, "not found") ||
-				strings.Contains(errorMsg, "does not exist") ||
-				strings.Contains(errorMsg, "forbidden") ||
-				strings.Contains(errorMsg, "is forbidden") {
-				// Check if this is a custom resource (non-core Kubernetes resource)
-				if gvr.Group != "" && gvr.Group != "core" && gvr.Group != "v1" {
-					logger.Info("Custom resource %s (%s) not available in cluster - this is normal if the corresponding CRD is not installed or RBAC permissions are missing", h.GetKind(), gvr.String())
-				} else {
-					logger.Warn("Resource %s (%s) not found in cluster or access forbidden, skipping informer setup", h.GetKind(), gvr.String())
-				}
-				skippedHandlers = append(skippedHandlers, h.GetKind())
+	// Set up informers tier by tier, so that the kinds other kinds link to
+	// are written first
+	informers := make([]cache.SharedInformer, 0, len(enabled))
+	skippedHandlers := make([]string, 0)
+	tiers := orderHandlers(enabled, c.config.Sync.OrderedStartup)
+	for i, tier := range tiers {
+		kinds := make([]string, 0, len(tier))
+		for _, handler := range tier {
+			informer := c.watchHandler(ctx, handler)
+			if informer == nil {
+				c.initialSync.Untrack(handler.GetKind())
+				skippedHandlers = append(skippedHandlers, handler.GetKind())
 				continue
-			} else {
-				logger.Warn("Failed to check if resource %s exists: %v", h.GetKind(), err)
-				// Continue anyway, the informer might still work
 			}
+			informers = append(informers, informer)
+			kinds = append(kinds, handler.GetKind())
 		}
 
-		informer := c.informerFactory.ForResource(gvr).Informer()
-
-		// Add backoff retry for event handlers
-		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				logger.Debug("Received Add event for %s", h.GetKind())
-				if err := h.HandleCreate(ctx, obj, neo4jClient); err != nil {
-					if !isContextCanceled(err) {
-						logger.Error("Error handling create event for %s: %v", h.GetKind(), err)
-					}
-				} else {
-					logger.Debug("Successfully processed Add event for %s", h.GetKind())
-				}
-			},
-			UpdateFunc: func(old, new interface{}) {
-				logger.Debug("Received Update event for %s", h.GetKind())
-				if err := h.HandleCreate(ctx, new, neo4jClient); err != nil {
-					if !isContextCanceled(err) {
-						logger.Error("Error handling update event for %s: %v", h.GetKind(), err)
-					}
-				} else {
-					logger.Debug("Successfully processed Update event for %s", h.GetKind())
-				}
-			},
-			DeleteFunc: func(obj interface{}) {
-				logger.Debug("Received Delete event for %s", h.GetKind())
-				if err := h.HandleDelete(ctx, obj, neo4jClient); err != nil {
-					if !isContextCanceled(err) {
-						logger.Error("Error handling delete event for %s: %v", h.GetKind(), err)
-					}
-				} else {
-					logger.Debug("Successfully processed Delete event for %s", h.GetKind())
-				}
-			},
-		})
-		informers = append(informers, informer)
+		// Start only starts the informers that are not running yet
+		logger.Info("Starting informers of %d kinds (tier %d of %d)...", len(kinds), i+1, len(tiers))
+		c.informerFactory.Start(ctx.Done())
+		if i == len(tiers)-1 {
+			break
+		}
+		if pending := c.waitInitialSync(ctx, kinds, startupTierTimeout); len(pending) > 0 {
+			logger.Warn("Initial sync of %v not complete after %v, starting the next tier", pending, startupTierTimeout)
+		}
 	}
 
 	// Log summary of handler setup
@@ -260,10 +232,6 @@ func (c *Client) StartWatching(ctx context.Context, neo4jClient *neo4j.Client) e
 	}
 	logger.Info("Successfully set up %d informers", len(informers))
 
-	// Start informers
-	logger.Info("Starting informer factory...")
-	c.informerFactory.Start(ctx.Done())
-
 	// Wait for caches to sync with timeout
 	logger.Info("Waiting for caches to sync...")
 	syncCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
@@ -276,6 +244,8 @@ func (c *Client) StartWatching(ctx context.Context, neo4jClient *neo4j.Client) e
 	}
 	logger.Info("All caches synced successfully")
 
+	go c.retryDeadLetters(ctx)
+
 	// Create a ticker to periodically check connections
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -289,28 +259,338 @@ func (c *Client) StartWatching(ctx context.Context, neo4jClient *neo4j.Client) e
 				return
 			case <-ticker.C:
 				// Check Neo4j connection with a simple query
-				session := neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeWrite})
-				_, err := session.Run(ctx, "RETURN 1", nil)
-				if err != nil {
+				if _, err := neo4jClient.Read(ctx, "RETURN 1", nil); err != nil {
 					logger.Warn("Neo4j connectivity check failed: %v", err)
 				}
-				session.Close(ctx)
 
 				// Log informer status
-				for _, handler := range c.handlers {
-					informer := c.informerFactory.ForResource(handler.GetGVR()).Informer()
-					logger.Debug("Informer status for %s - HasSynced: %v", handler.GetKind(), informer.HasSynced())
+				c.informersMu.RLock()
+				for kind, informer := range c.informers {
+					logger.Debug("Informer status for %s - HasSynced: %v", kind, informer.HasSynced())
 				}
+				c.informersMu.RUnlock()
 			}
 		}
 	}()
 
 	logger.Info("Watching for Kubernetes events...")
 	<-ctx.Done()
+	c.coalescer.Stop()
+	if err := c.recorder.Close(); err != nil {
+		logger.Warn("Failed to close event recording: %v", err)
+	}
 	logger.Info("Stopping watch due to context cancellation")
 	return nil
 }
 
+// watchHandler sets up the informer of h and returns it, or nil if its resource
+// is not available in the cluster
+func (c *Client) watchHandler(ctx context.Context, h handlers.ResourceHandler) cache.SharedInformer {
+	logger.Info("Setting up informer for resource type: %s", h.GetKind())
+
+	// Check if the resource is served before setting up the informer
+	gvr := h.GetGVR()
+	if c.discovery != nil {
+		_, found, err := c.discovery.Lookup(gvr)
+		if err != nil {
+			logger.Warn("Failed to check if resource %s exists: %v", h.GetKind(), err)
+			// Continue anyway, the informer might still work
+		} else if !found {
+			if gvr.Group != "" {
+				logger.Info("Custom resource %s (%s) not available in cluster - this is normal if the corresponding CRD is not installed", h.GetKind(), gvr.String())
+			} else {
+				logger.Warn("Resource %s (%s) not found in cluster, skipping informer setup", h.GetKind(), gvr.String())
+			}
+			return nil
+		}
+	}
+
+	// The resource is served, check that it may be listed. For namespaced
+	// resources, try listing in the default namespace
+	var err error
+	if c.isNamespacedResource(gvr) {
+		_, err = c.dynamicClient.Resource(gvr).Namespace("default").List(ctx, metav1.ListOptions{Limit: 1})
+	} else {
+		_, err = c.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{Limit: 1})
+	}
+	if err != nil {
+		logger.Debug("Resource check failed for %s (%s): %v", h.GetKind(), gvr.String(), err)
+		if apierrors.IsForbidden(err) {
+			logger.Warn("Access to resource %s (%s) forbidden, skipping informer setup - check the RBAC permissions of kubegraph", h.GetKind(), gvr.String())
+			return nil
+		}
+		if apierrors.IsNotFound(err) {
+			logger.Warn("Resource %s (%s) not found in cluster, skipping informer setup", h.GetKind(), gvr.String())
+			return nil
+		}
+		logger.Warn("Failed to check if resource %s can be listed: %v", h.GetKind(), err)
+		// Continue anyway, the informer might still work
+	}
+
+	informer := c.informerFactory.ForResource(gvr).Informer()
+	limiter := c.initialAddLimiter()
+
+	// Add backoff retry for event handlers
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			logger.Debug("Received Add event for %s", h.GetKind())
+			c.recorder.Record(EventAdded, h.GetKind(), obj)
+			// Filtered and unchanged objects count as written for the initial sync
+			defer c.initialSync.Written(h.GetKind())
+			// The burst of Add events of the initial list is throttled
+			if limiter != nil && !c.initialSync.Complete(h.GetKind()) {
+				if err := limiter.Wait(ctx); err != nil {
+					return
+				}
+			}
+			if !c.namespaces.Load().Allows(obj) || !c.changes.Changed(obj) {
+				return
+			}
+			c.processCreate(ctx, h, obj, c.neo4jClient, "", "Add")
+		},
+		UpdateFunc: func(old, new interface{}) {
+			logger.Debug("Received Update event for %s", h.GetKind())
+			c.recorder.Record(EventModified, h.GetKind(), new)
+			if !c.namespaces.Load().Allows(new) {
+				return
+			}
+			// Periodic resyncs redeliver objects whose resourceVersion has not moved
+			if !c.changes.Changed(new) {
+				return
+			}
+			// Rapid updates to the same object are coalesced so only the latest state is written
+			c.coalescer.Submit(h.GetKind(), objectUID(new), func() {
+				c.processCreate(ctx, h, new, c.neo4jClient, "", "Update")
+			})
+		},
+		DeleteFunc: func(obj interface{}) {
+			logger.Debug("Received Delete event for %s", h.GetKind())
+			c.recorder.Record(EventDeleted, h.GetKind(), obj)
+			if !c.namespaces.Load().Allows(obj) {
+				return
+			}
+			c.coalescer.Cancel(objectUID(obj))
+			c.changes.Forget(obj)
+			c.processDelete(ctx, h, obj, c.neo4jClient, "")
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to add event handler for %s: %v", h.GetKind(), err)
+		return nil
+	}
+	c.initialSync.Track(h.GetKind(), func() (int, bool) {
+		if !informer.HasSynced() {
+			return 0, false
+		}
+		return len(informer.GetStore().ListKeys()), true
+	}, registration.HasSynced)
+	c.informersMu.Lock()
+	c.informers[h.GetKind()] = informer
+	c.informersMu.Unlock()
+	return informer
+}
+
+// processCreate runs the create handler for an added or updated object unless
+// its handler is paused. clusterName is the cluster of objects pushed to the
+// ingest endpoint, and empty for the watched cluster.
+func (c *Client) processCreate(ctx context.Context, h handlers.ResourceHandler, obj interface{}, neo4jClient graph.Store, clusterName, event string) error {
+	// During a Neo4j outage events wait for the circuit breaker to close,
+	// buffered in the queues of the informers
+	if err := waitAvailable(ctx, neo4jClient); err != nil {
+		return err
+	}
+	if !c.gate.Enter(h.GetKind()) {
+		return fmt.Errorf("handler %s is paused", h.GetKind())
+	}
+	defer c.gate.Leave(h.GetKind())
+
+	ctx, span := startHandlerSpan(ctx, "create", h.GetKind(), obj)
+	start := time.Now()
+	err := h.HandleCreate(ctx, obj, neo4jClient)
+	defer tracing.End(span, err)
+	c.recordEvent(h.GetKind(), strings.ToLower(event), clusterName, start, err)
+	if err == nil && event != "Resync" && clusterName == "" {
+		c.recordLag(h.GetKind(), obj, time.Now())
+	}
+	if err != nil {
+		if !isContextCanceled(err) {
+			category := failure.Record(h.GetKind(), err)
+			logger.Error("Error handling %s event for %s (%s): %v", strings.ToLower(event), h.GetKind(), category, err)
+			if shouldDeadLetter(err) {
+				c.deadLetters.AddFrom(clusterName, h.GetKind(), "create", obj, err)
+			}
+		}
+		return err
+	}
+	// The change detector only skips the redelivered objects of the informers
+	if clusterName == "" {
+		c.changes.Record(obj)
+	}
+	c.deadLetters.Remove(objectUID(obj))
+	if err := tagVirtualCluster(ctx, h.GetKind(), obj, neo4jClient); err != nil {
+		logger.Warn("Failed to tag %s with its virtual cluster: %v", h.GetKind(), err)
+	}
+	logger.Debug("Successfully processed %s event for %s", event, h.GetKind())
+	return nil
+}
+
+// processDelete runs the delete handler for a deleted object unless its
+// handler is paused. clusterName is as for processCreate.
+func (c *Client) processDelete(ctx context.Context, h handlers.ResourceHandler, obj interface{}, neo4jClient graph.Store, clusterName string) error {
+	if err := waitAvailable(ctx, neo4jClient); err != nil {
+		return err
+	}
+	if !c.gate.Enter(h.GetKind()) {
+		return fmt.Errorf("handler %s is paused", h.GetKind())
+	}
+	defer c.gate.Leave(h.GetKind())
+
+	ctx, span := startHandlerSpan(ctx, "delete", h.GetKind(), obj)
+	start := time.Now()
+	err := h.HandleDelete(ctx, obj, neo4jClient)
+	tracing.End(span, err)
+	c.recordEvent(h.GetKind(), "delete", clusterName, start, err)
+	if err != nil {
+		if !isContextCanceled(err) {
+			category := failure.Record(h.GetKind(), err)
+			logger.Error("Error handling delete event for %s (%s): %v", h.GetKind(), category, err)
+			if shouldDeadLetter(err) {
+				c.deadLetters.AddFrom(clusterName, h.GetKind(), "delete", obj, err)
+			}
+		}
+		return err
+	}
+	c.deadLetters.Remove(objectUID(obj))
+	logger.Debug("Successfully processed Delete event for %s", h.GetKind())
+	return nil
+}
+
+// PauseHandler stops processing events for kind and waits for in-flight events
+// to finish. Events received while paused are dropped and reconciled on resume.
+func (c *Client) PauseHandler(ctx context.Context, kind string) error {
+	if _, err := c.handler(kind); err != nil {
+		return err
+	}
+	paused, err := c.gate.Pause(ctx, kind)
+	if err != nil {
+		return fmt.Errorf("handler %s paused but in-flight events did not drain: %w", kind, err)
+	}
+	if paused {
+		logger.Warn("[ADMIN] Handler %s paused", kind)
+	}
+	return nil
+}
+
+// ResumeHandler resumes processing events for kind and reconciles the graph
+// with the informer cache in the background to catch up on dropped events
+func (c *Client) ResumeHandler(kind string) error {
+	h, err := c.handler(kind)
+	if err != nil {
+		return err
+	}
+	if !c.gate.Resume(kind) {
+		return nil
+	}
+	logger.Warn("[ADMIN] Handler %s resumed", kind)
+
+	if c.neo4jClient != nil {
+		go c.reconcile(c.watchCtx, h)
+	}
+	return nil
+}
+
+// PausedHandlers returns the state of all paused handlers
+func (c *Client) PausedHandlers() []HandlerState {
+	return c.gate.Paused()
+}
+
+// reconcile replays the informer cache for h and removes nodes of its kind
+// whose objects are no longer in the cache
+func (c *Client) reconcile(ctx context.Context, h handlers.ResourceHandler) {
+	informer := c.informerFactory.ForResource(h.GetGVR()).Informer()
+	if !informer.HasSynced() {
+		logger.Debug("[ADMIN] Informer for %s not synced, skipping reconcile", h.GetKind())
+		return
+	}
+
+	objects := informer.GetStore().List()
+	uids := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		if ctx.Err() != nil {
+			return
+		}
+		// Nodes of filtered namespaces are removed as stale, and written again
+		// if their namespace is included later
+		if !c.namespaces.Load().Allows(obj) {
+			c.changes.Forget(obj)
+			continue
+		}
+		if uid := objectUID(obj); uid != "" {
+			uids = append(uids, uid)
+		}
+		if c.changes.Changed(obj) {
+			c.processCreate(ctx, h, obj, c.neo4jClient, "", "Resync")
+		}
+	}
+
+	// Nodes without instanceHash (e.g. Events) are kept, matching regular cleanup
+	query := fmt.Sprintf(`
+		MATCH (n:%s {clusterName: $clusterName, instanceHash: $instanceHash})
+		WHERE NOT n.uid IN $uids
+		WITH n, n.uid AS uid
+		DETACH DELETE n
+		RETURN uid`, h.GetKind())
+
+	rows, err := c.neo4jClient.Query(ctx, query, map[string]interface{}{
+		"clusterName":  c.config.Kubernetes.ClusterName,
+		"instanceHash": c.config.InstanceHash,
+		"uids":         uids,
+	})
+	if err != nil {
+		logger.Error("[ADMIN] Failed to remove stale %s nodes: %v", h.GetKind(), err)
+		return
+	}
+	deleted := 0
+	for _, row := range rows {
+		if uid, ok := row["uid"].(string); ok {
+			c.neo4jClient.ForgetNode(h.GetKind(), uid)
+			c.neo4jClient.RecordChange(ctx, h.GetKind(), uid, neo4j.OperationDelete)
+			if err := c.neo4jClient.CloseVersion(ctx, h.GetKind(), uid); err != nil {
+				logger.Warn("[ADMIN] Failed to close version of %s %s: %v", h.GetKind(), uid, err)
+			}
+		}
+		deleted++
+	}
+	logger.Info("[ADMIN] Reconciled %s: %d objects in cache, %d stale nodes removed", h.GetKind(), len(uids), deleted)
+}
+
+// objectUID returns the UID of a watched object, or an empty string if it has none
+func objectUID(obj interface{}) string {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return string(accessor.GetUID())
+}
+
+// startHandlerSpan starts the span of a handler processing an event for obj
+func startHandlerSpan(ctx context.Context, operation, kind string, obj interface{}) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{attribute.String("k8s.kind", kind)}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		attributes = append(attributes,
+			attribute.String("k8s.namespace", accessor.GetNamespace()),
+			attribute.String("k8s.name", accessor.GetName()),
+			attribute.String("k8s.uid", string(accessor.GetUID())))
+	}
+	return tracing.Start(ctx, "handler."+operation+" "+kind, attributes...)
+}
+
 // isContextCanceled checks if the error is due to context cancellation
 func isContextCanceled(err error) bool {
 	if err == nil {
@@ -319,45 +599,20 @@ func isContextCanceled(err error) bool {
 	return err == context.Canceled || err == context.DeadlineExceeded || strings.Contains(err.Error(), "context canceled")
 }
 
-// isNamespacedResource determines if a resource is namespaced based on its GVR
+// isNamespacedResource determines if a resource is namespaced from the
+// discovery API, falling back to the registration of its handler when the
+// resource cannot be discovered. Other resources are assumed to be
+// namespaced, as most custom resources are.
 func (c *Client) isNamespacedResource(gvr schema.GroupVersionResource) bool {
-	// Core Kubernetes namespaced resources
-	namespacedResources := map[string]bool{
-		"pods":                     true,
-		"services":                 true,
-		"configmaps":               true,
-		"secrets":                  true,
-		"deployments":              true,
-		"replicasets":              true,
-		"statefulsets":             true,
-		"daemonsets":               true,
-		"jobs":                     true,
-		"cronjobs":                 true,
-		"persistentvolumeclaims":   true,
-		"events":                   true,
-		"namespaces":               false, // Namespaces are cluster-scoped
-		"serviceaccounts":          true,
-		"horizontalpodautoscalers": true,
-		"verticalpodautoscalers":   true,
-		"poddisruptionbudgets":     true,
-		"limitranges":              true,
-		"nodes":                    false, // Nodes are cluster-scoped
-		"persistentvolumes":        false, // PVs are cluster-scoped
-		"storageclasses":           false, // StorageClasses are cluster-scoped
-		"ipaccesscontrols":         true,  // IPAccessControl is namespaced
-		"customendpoints":          false, // CustomEndpoint is cluster-scoped
-		"ingresses":                true,  // Ingresses are namespaced
-		"endpoints":                true,  // Endpoints are namespaced
-		"networkpolicies":          true,  // NetworkPolicies are namespaced
-	}
-
-	// Check if it's a known core resource
-	if isNamespaced, exists := namespacedResources[gvr.Resource]; exists {
-		return isNamespaced
-	}
-
-	// For custom resources, assume they are namespaced unless we know otherwise
-	// Most custom resources are namespaced
+	if c.discovery != nil {
+		resource, found, err := c.discovery.Lookup(gvr)
+		if err == nil && found {
+			return resource.Namespaced
+		}
+	}
+	if registration, ok := registry.ForGVR(gvr); ok {
+		return registration.Namespaced
+	}
 	return true
 }
 
@@ -380,122 +635,155 @@ func convertToTyped[T any](obj interface{}) (T, error) {
 }
 
 // handleResourceDelete is a helper function for deleting resources from Neo4j
-func handleResourceDelete(ctx context.Context, resourceType, uid string, neo4jClient *neo4j.Client) error {
-	query := fmt.Sprintf("MATCH (r:%s {uid: $uid}) DETACH DELETE r", resourceType)
-	session := neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeWrite})
-	defer session.Close(ctx)
+func handleResourceDelete(ctx context.Context, resourceType, uid string, neo4jClient graph.Store) error {
+	return neo4jClient.Delete(ctx, resourceType, "uid", uid)
+}
 
-	_, err := session.Run(ctx, query, map[string]interface{}{"uid": uid})
-	return err
+// waitAvailable waits for the database of a store to be available. Stores
+// without a database, like the file store, always are.
+func waitAvailable(ctx context.Context, store graph.Store) error {
+	if waiter, ok := store.(interface{ WaitAvailable(context.Context) error }); ok {
+		return waiter.WaitAvailable(ctx)
+	}
+	return nil
 }
 
 // handleNodeChange handles node changes
-func (c *Client) handleNodeChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleNodeChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewNodeHandler(c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
 // handleNodeDelete handles node deletions
-func (c *Client) handleNodeDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleNodeDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewNodeHandler(c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
 // handlePodChange handles pod changes
-func (c *Client) handlePodChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePodChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPodHandler(c.clientset, c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
 // handlePodDelete handles pod deletions
-func (c *Client) handlePodDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePodDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPodHandler(c.clientset, c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
 // handleServiceChange handles service changes
-func (c *Client) handleServiceChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleServiceChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewServiceHandler(c.clientset, c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
 // handleServiceDelete handles service deletions
-func (c *Client) handleServiceDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleServiceDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewServiceHandler(c.clientset, c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleConfigMapChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleConfigMapChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewConfigMapHandler(c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleConfigMapDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleConfigMapDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewConfigMapHandler(c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleSecretChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleSecretChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewSecretHandler(c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleSecretDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleSecretDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewSecretHandler(c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleDeploymentChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleDeploymentChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewDeploymentHandler(c.clientset, c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleDeploymentDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleDeploymentDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewDeploymentHandler(c.clientset, c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleStatefulSetChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleStatefulSetChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewStatefulSetHandler(c.clientset, c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleStatefulSetDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleStatefulSetDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewStatefulSetHandler(c.clientset, c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleDaemonSetChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleDaemonSetChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewDaemonSetHandler(c.clientset, c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handleDaemonSetDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handleDaemonSetDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewDaemonSetHandler(c.clientset, c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handlePVChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePVChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPVHandler(c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handlePVDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePVDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPVHandler(c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handlePVCChange(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePVCChange(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPVCHandler(c.config)
 	return handler.HandleCreate(ctx, obj, neo4jClient)
 }
 
-func (c *Client) handlePVCDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (c *Client) handlePVCDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	handler := handlers.NewPVCHandler(c.config)
 	return handler.HandleDelete(ctx, obj, neo4jClient)
 }
 
-// GetHandlers returns all registered resource handlers
+// GetHandlers returns the enabled resource handlers
 func (c *Client) GetHandlers() map[string]handlers.ResourceHandler {
-	return c.handlers
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+
+	enabled := make(map[string]handlers.ResourceHandler, len(c.handlers))
+	for kind, handler := range c.handlers {
+		if !c.disabled[kind] {
+			enabled[kind] = handler
+		}
+	}
+	return enabled
+}
+
+// DynamicClient returns the dynamic client used to watch resources
+func (c *Client) DynamicClient() dynamic.Interface {
+	return c.dynamicClient
+}
+
+// ReviewToken authenticates a bearer token with the TokenReview API and
+// returns the user it belongs to
+func (c *Client) ReviewToken(ctx context.Context, token string) (string, []string, bool, error) {
+	review, err := c.clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", nil, false, fmt.Errorf("failed to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		return "", nil, false, nil
+	}
+	return review.Status.User.Username, review.Status.User.Groups, true, nil
 }
@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	coalescerUpdatesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_coalescer_updates_total",
+			Help: "Total number of updates submitted to the coalescer",
+		},
+		[]string{"kind"},
+	)
+	coalescedUpdatesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_coalesced_updates_total",
+			Help: "Total number of updates superseded by a newer update within the coalescing window",
+		},
+		[]string{"kind"},
+	)
+	coalescerPendingUpdates = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubegraph_coalescer_pending_updates",
+			Help: "Number of updates waiting for their coalescing window to close",
+		},
+	)
+)
+
+// Coalescer collapses rapid updates to the same object so that only the latest
+// state seen within the window is written to Neo4j
+type Coalescer struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[string]*pendingUpdate
+}
+
+type pendingUpdate struct {
+	timer *time.Timer
+	fn    func()
+}
+
+// NewCoalescer creates a coalescer with the given window (0 disables coalescing)
+func NewCoalescer(window time.Duration) *Coalescer {
+	return &Coalescer{
+		window:  window,
+		pending: make(map[string]*pendingUpdate),
+	}
+}
+
+// Submit schedules fn to run at the end of the window opened by the first
+// update for key. Later updates within the window replace fn, so an object
+// updated continuously is written at most once per window. kind labels the
+// coalescer metrics.
+func (c *Coalescer) Submit(kind, key string, fn func()) {
+	coalescerUpdatesTotal.WithLabelValues(kind).Inc()
+	if c.window <= 0 || key == "" {
+		fn()
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pending[key]; ok {
+		p.fn = fn
+		coalescedUpdatesTotal.WithLabelValues(kind).Inc()
+		return
+	}
+
+	p := &pendingUpdate{fn: fn}
+	p.timer = time.AfterFunc(c.window, func() {
+		c.fire(key, p)
+	})
+	c.pending[key] = p
+	coalescerPendingUpdates.Inc()
+}
+
+// Cancel drops any pending update for key, e.g. because the object was deleted
+func (c *Coalescer) Cancel(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pending[key]; ok {
+		p.timer.Stop()
+		delete(c.pending, key)
+		coalescerPendingUpdates.Dec()
+	}
+}
+
+// Stop drops all pending updates
+func (c *Coalescer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, p := range c.pending {
+		p.timer.Stop()
+		delete(c.pending, key)
+		coalescerPendingUpdates.Dec()
+	}
+}
+
+// Pending returns the number of updates waiting for their window to close
+func (c *Coalescer) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+func (c *Coalescer) fire(key string, p *pendingUpdate) {
+	c.mu.Lock()
+	if c.pending[key] != p {
+		// Cancelled or replaced after the timer fired
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, key)
+	coalescerPendingUpdates.Dec()
+	fn := p.fn
+	c.mu.Unlock()
+
+	fn()
+}
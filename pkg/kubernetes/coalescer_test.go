@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCoalescerRunsLatestUpdateOnce(t *testing.T) {
+	c := NewCoalescer(50 * time.Millisecond)
+
+	var calls int32
+	var last int32
+	for i := int32(1); i <= 5; i++ {
+		value := i
+		c.Submit("Pod", "uid-1", func() {
+			atomic.AddInt32(&calls, 1)
+			atomic.StoreInt32(&last, value)
+		})
+	}
+
+	if c.Pending() != 1 {
+		t.Errorf("Expected 1 pending update, got %d", c.Pending())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	if atomic.LoadInt32(&last) != 5 {
+		t.Errorf("Expected latest update (5) to win, got %d", last)
+	}
+	if c.Pending() != 0 {
+		t.Errorf("Expected no pending updates, got %d", c.Pending())
+	}
+}
+
+func TestCoalescerCancel(t *testing.T) {
+	c := NewCoalescer(50 * time.Millisecond)
+
+	var calls int32
+	c.Submit("Pod", "uid-1", func() { atomic.AddInt32(&calls, 1) })
+	c.Cancel("uid-1")
+
+	time.Sleep(100 * time.Millisecond)
+
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Errorf("Expected cancelled update not to run, got %d calls", calls)
+	}
+}
+
+func TestCoalescerDisabled(t *testing.T) {
+	c := NewCoalescer(0)
+
+	var calls int32
+	c.Submit("Pod", "uid-1", func() { atomic.AddInt32(&calls, 1) })
+	c.Submit("Pod", "uid-1", func() { atomic.AddInt32(&calls, 1) })
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected updates to run synchronously when disabled, got %d calls", calls)
+	}
+}
+
+func TestCoalescerCountsUpdatesByKind(t *testing.T) {
+	c := NewCoalescer(time.Minute)
+	defer c.Stop()
+
+	submitted := testutil.ToFloat64(coalescerUpdatesTotal.WithLabelValues("Deployment"))
+	coalesced := testutil.ToFloat64(coalescedUpdatesTotal.WithLabelValues("Deployment"))
+	pending := testutil.ToFloat64(coalescerPendingUpdates)
+
+	for i := 0; i < 4; i++ {
+		c.Submit("Deployment", "uid-1", func() {})
+	}
+	c.Submit("Deployment", "uid-2", func() {})
+
+	if got := testutil.ToFloat64(coalescerUpdatesTotal.WithLabelValues("Deployment")) - submitted; got != 5 {
+		t.Errorf("Expected 5 submitted updates, got %v", got)
+	}
+	if got := testutil.ToFloat64(coalescedUpdatesTotal.WithLabelValues("Deployment")) - coalesced; got != 3 {
+		t.Errorf("Expected 3 coalesced updates, got %v", got)
+	}
+	if got := testutil.ToFloat64(coalescerPendingUpdates) - pending; got != 2 {
+		t.Errorf("Expected 2 pending updates, got %v", got)
+	}
+
+	c.Cancel("uid-1")
+	if got := testutil.ToFloat64(coalescerPendingUpdates) - pending; got != 1 {
+		t.Errorf("Expected 1 pending update after cancel, got %v", got)
+	}
+}
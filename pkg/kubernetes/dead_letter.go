@@ -0,0 +1,390 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	deadLettersTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_dead_letters_total",
+			Help: "Total number of events whose write to Neo4j failed and was kept for retry",
+		},
+		[]string{"kind"},
+	)
+	deadLetterRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_dead_letter_retries_total",
+			Help: "Total number of dead-letter retries, by result",
+		},
+		[]string{"result"},
+	)
+	deadLettersEvictedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "kubegraph_dead_letters_evicted_total",
+			Help: "Total number of dead letters dropped because the store was full",
+		},
+	)
+	deadLettersPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "kubegraph_dead_letters_pending",
+			Help: "Number of dead letters in the store",
+		},
+	)
+)
+
+// DeadLetter is an event whose write to Neo4j failed
+type DeadLetter struct {
+	// ClusterName is the cluster of an object pushed to the ingest endpoint,
+	// empty for the watched cluster
+	ClusterName string          `json:"clusterName,omitempty"`
+	Kind        string          `json:"kind"`
+	Event       string          `json:"event"` // create or delete
+	Namespace   string          `json:"namespace,omitempty"`
+	Name        string          `json:"name"`
+	UID         string          `json:"uid"`
+	Category    string          `json:"category"`
+	Error       string          `json:"error"`
+	FailedAt    time.Time       `json:"failedAt"`
+	Attempts    int             `json:"attempts"` // Retries so far
+	Object      json.RawMessage `json:"object,omitempty"`
+}
+
+// DeadLetterStore keeps the latest failed event of each object in a bounded
+// ring, optionally mirrored to a file so that they survive restarts. A newer
+// failure of the same object replaces the previous one, and the oldest dead
+// letter is dropped when the store is full.
+type DeadLetterStore struct {
+	mu       sync.Mutex
+	capacity int
+	path     string
+	letters  []DeadLetter // Oldest first
+}
+
+// NewDeadLetterStore creates a store holding up to capacity dead letters
+// (0 disables it). When path is set, dead letters are loaded from and saved
+// to that file.
+func NewDeadLetterStore(capacity int, path string) (*DeadLetterStore, error) {
+	s := &DeadLetterStore{capacity: capacity, path: path}
+	if capacity <= 0 || path == "" {
+		return s, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return nil, fmt.Errorf("failed to parse dead-letter file %s: %w", path, err)
+		}
+		s.letters = append(s.letters, letter)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter file %s: %w", path, err)
+	}
+	if len(s.letters) > capacity {
+		s.letters = s.letters[len(s.letters)-capacity:]
+	}
+	deadLettersPending.Set(float64(len(s.letters)))
+	return s, nil
+}
+
+// Enabled reports whether failed events are kept
+func (s *DeadLetterStore) Enabled() bool {
+	return s != nil && s.capacity > 0
+}
+
+// Add keeps a failed event for obj
+func (s *DeadLetterStore) Add(kind, event string, obj interface{}, err error) {
+	s.AddFrom("", kind, event, obj, err)
+}
+
+// AddFrom keeps a failed event for obj of the given cluster, empty for the
+// watched cluster
+func (s *DeadLetterStore) AddFrom(clusterName, kind, event string, obj interface{}, err error) {
+	if !s.Enabled() {
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, accessErr := meta.Accessor(obj)
+	if accessErr != nil {
+		return
+	}
+	letter := DeadLetter{
+		ClusterName: clusterName,
+		Kind:        kind,
+		Event:       event,
+		Namespace:   accessor.GetNamespace(),
+		Name:        accessor.GetName(),
+		UID:         string(accessor.GetUID()),
+		Category:    string(failure.Classify(err)),
+		Error:       err.Error(),
+		FailedAt:    time.Now(),
+	}
+	// Only deletes and ingested objects replay the stored object, creates of
+	// the watched cluster replay the informer cache
+	if event == "delete" || clusterName != "" {
+		if data, marshalErr := json.Marshal(obj); marshalErr == nil {
+			letter.Object = data
+		}
+	}
+	deadLettersTotal.WithLabelValues(kind).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(letter.UID)
+	if len(s.letters) >= s.capacity {
+		s.letters = s.letters[1:]
+		deadLettersEvictedTotal.Inc()
+	}
+	s.letters = append(s.letters, letter)
+	s.save()
+}
+
+// Remove drops the dead letter of the object with uid, e.g. because a later
+// event for it was written successfully
+func (s *DeadLetterStore) Remove(uid string) {
+	if !s.Enabled() || uid == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.remove(uid) {
+		s.save()
+	}
+}
+
+// remove drops the dead letter of uid; s.mu must be held
+func (s *DeadLetterStore) remove(uid string) bool {
+	for i, letter := range s.letters {
+		if letter.UID == uid {
+			s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the dead letters, oldest first
+func (s *DeadLetterStore) List() []DeadLetter {
+	if !s.Enabled() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	letters := make([]DeadLetter, len(s.letters))
+	copy(letters, s.letters)
+	return letters
+}
+
+// Retry replays the dead letters that have been retried fewer than
+// maxAttempts times with replay. Dead letters replayed successfully are
+// dropped; the others keep their latest error.
+func (s *DeadLetterStore) Retry(ctx context.Context, maxAttempts int, replay func(context.Context, DeadLetter) error) (succeeded, failed int) {
+	for _, letter := range s.List() {
+		if ctx.Err() != nil {
+			break
+		}
+		if letter.Attempts >= maxAttempts {
+			continue
+		}
+		err := replay(ctx, letter)
+
+		s.mu.Lock()
+		for i := range s.letters {
+			// Skip letters replaced by a newer failure while replaying
+			if s.letters[i].UID != letter.UID || !s.letters[i].FailedAt.Equal(letter.FailedAt) {
+				continue
+			}
+			if err == nil {
+				s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			} else {
+				s.letters[i].Attempts++
+				s.letters[i].Error = err.Error()
+				s.letters[i].Category = string(failure.Classify(err))
+			}
+			break
+		}
+		s.save()
+		s.mu.Unlock()
+
+		if err == nil {
+			deadLetterRetriesTotal.WithLabelValues("success").Inc()
+			succeeded++
+		} else {
+			deadLetterRetriesTotal.WithLabelValues("failure").Inc()
+			failed++
+		}
+	}
+	return succeeded, failed
+}
+
+// save updates the pending gauge and rewrites the file; s.mu must be held
+func (s *DeadLetterStore) save() {
+	deadLettersPending.Set(float64(len(s.letters)))
+	if s.path == "" {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		logger.Warn("[DEADLETTER] Failed to save dead letters: %v", err)
+		return
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, letter := range s.letters {
+		if err = encoder.Encode(letter); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		logger.Warn("[DEADLETTER] Failed to save dead letters: %v", err)
+	}
+}
+
+// shouldDeadLetter reports whether a failed event is kept for retry: only
+// writes that Neo4j failed or rejected are, other failures would fail again
+func shouldDeadLetter(err error) bool {
+	category := failure.Classify(err)
+	return category == failure.Neo4jTransient || category == failure.Neo4jFatal
+}
+
+// DeadLetters returns the store of failed events
+func (c *Client) DeadLetters() *DeadLetterStore {
+	return c.deadLetters
+}
+
+// retryDeadLetters replays the dead letters whenever Neo4j is reachable
+func (c *Client) retryDeadLetters(ctx context.Context) {
+	interval := time.Duration(c.config.DeadLetter.RetryIntervalSeconds) * time.Second
+	if !c.deadLetters.Enabled() || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if len(c.deadLetters.List()) == 0 {
+				continue
+			}
+			if _, err := c.neo4jClient.Read(ctx, "RETURN 1", nil); err != nil {
+				logger.Debug("[DEADLETTER] Neo4j unreachable, postponing retry: %v", err)
+				continue
+			}
+			succeeded, failed := c.deadLetters.Retry(ctx, c.config.DeadLetter.MaxAttempts, c.replayDeadLetter)
+			if succeeded > 0 || failed > 0 {
+				logger.Info("[DEADLETTER] Retried dead letters: %d succeeded, %d failed", succeeded, failed)
+			}
+		}
+	}
+}
+
+// replayDeadLetter runs the handler of a dead letter again. Creates write the
+// current state of the object from the informer cache; an object that is gone
+// has been deleted since and needs no retry.
+func (c *Client) replayDeadLetter(ctx context.Context, letter DeadLetter) error {
+	if letter.ClusterName != "" {
+		return c.replayIngested(ctx, letter)
+	}
+	h, ok := c.handlers[letter.Kind]
+	if !ok {
+		return nil
+	}
+	if !c.gate.Enter(letter.Kind) {
+		return fmt.Errorf("handler %s is paused", letter.Kind)
+	}
+	defer c.gate.Leave(letter.Kind)
+
+	if letter.Event == "delete" {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(letter.Object); err != nil {
+			return nil
+		}
+		return h.HandleDelete(ctx, obj, c.neo4jClient)
+	}
+
+	c.informersMu.RLock()
+	informer, ok := c.informers[letter.Kind]
+	c.informersMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	key := letter.Name
+	if letter.Namespace != "" {
+		key = letter.Namespace + "/" + letter.Name
+	}
+	obj, exists, err := informer.GetStore().GetByKey(key)
+	if err != nil || !exists || objectUID(obj) != letter.UID {
+		return nil
+	}
+	if err := h.HandleCreate(ctx, obj, c.neo4jClient); err != nil {
+		return err
+	}
+	c.changes.Record(obj)
+	return nil
+}
+
+// replayIngested runs the handler of a dead letter of an ingested object
+// again. There is no informer cache of its cluster, so creates write the
+// stored object; pushing the object again replaces its dead letter.
+func (c *Client) replayIngested(ctx context.Context, letter DeadLetter) error {
+	h, ok := c.ingestHandlers(letter.ClusterName)[letter.Kind]
+	if !ok {
+		return nil
+	}
+	if !c.gate.Enter(letter.Kind) {
+		return fmt.Errorf("handler %s is paused", letter.Kind)
+	}
+	defer c.gate.Leave(letter.Kind)
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(letter.Object); err != nil {
+		return nil
+	}
+	if letter.Event == "delete" {
+		return h.HandleDelete(ctx, obj, c.neo4jClient)
+	}
+	return h.HandleCreate(ctx, obj, c.neo4jClient)
+}
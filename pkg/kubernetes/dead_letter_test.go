@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func deadLetterPod(uid string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-" + uid, UID: types.UID(uid)}}
+}
+
+func TestDeadLetterStoreReplacesAndEvicts(t *testing.T) {
+	store, err := NewDeadLetterStore(2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := &neo4j.ConnectivityError{Inner: errors.New("reset")}
+
+	store.Add("Pod", "create", deadLetterPod("a"), failed)
+	store.Add("Pod", "create", deadLetterPod("b"), failed)
+	store.Add("Pod", "delete", deadLetterPod("a"), failed)
+
+	letters := store.List()
+	if len(letters) != 2 || letters[0].UID != "b" || letters[1].UID != "a" || letters[1].Event != "delete" {
+		t.Fatalf("Expected b then the delete of a, got %+v", letters)
+	}
+	if letters[1].Category != "Neo4jTransient" || letters[1].Object == nil || letters[0].Object != nil {
+		t.Errorf("Expected a Neo4jTransient delete carrying its object, got %+v", letters[1])
+	}
+
+	store.Add("Pod", "create", deadLetterPod("c"), failed)
+	letters = store.List()
+	if len(letters) != 2 || letters[0].UID != "a" || letters[1].UID != "c" {
+		t.Errorf("Expected the oldest letter to be evicted, got %+v", letters)
+	}
+
+	store.Remove("a")
+	if letters := store.List(); len(letters) != 1 || letters[0].UID != "c" {
+		t.Errorf("Expected only c after removing a, got %+v", letters)
+	}
+}
+
+func TestDeadLetterStoreTombstone(t *testing.T) {
+	store, _ := NewDeadLetterStore(10, "")
+	store.Add("Pod", "delete", cache.DeletedFinalStateUnknown{Key: "default/pod-a", Obj: deadLetterPod("a")}, errors.New("boom"))
+	if letters := store.List(); len(letters) != 1 || letters[0].UID != "a" {
+		t.Errorf("Expected the tombstoned object to be kept, got %+v", letters)
+	}
+}
+
+func TestDeadLetterStoreDisabled(t *testing.T) {
+	store, _ := NewDeadLetterStore(0, "")
+	store.Add("Pod", "create", deadLetterPod("a"), errors.New("boom"))
+	if store.Enabled() || len(store.List()) != 0 {
+		t.Error("Expected a disabled store to keep nothing")
+	}
+
+	var missing *DeadLetterStore
+	missing.Add("Pod", "create", deadLetterPod("a"), errors.New("boom"))
+	missing.Remove("a")
+	if missing.Enabled() || missing.List() != nil {
+		t.Error("Expected a nil store to be disabled")
+	}
+}
+
+func TestDeadLetterStoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadletters.jsonl")
+	store, err := NewDeadLetterStore(10, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add("Pod", "create", deadLetterPod("a"), errors.New("boom"))
+	store.Add("Pod", "delete", deadLetterPod("b"), errors.New("boom"))
+
+	reloaded, err := NewDeadLetterStore(1, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if letters := reloaded.List(); len(letters) != 1 || letters[0].UID != "b" || letters[0].Error != "boom" {
+		t.Errorf("Expected the newest letter to be reloaded within capacity, got %+v", letters)
+	}
+}
+
+func TestDeadLetterStoreRetry(t *testing.T) {
+	store, _ := NewDeadLetterStore(10, "")
+	store.Add("Pod", "create", deadLetterPod("a"), errors.New("boom"))
+	store.Add("Pod", "create", deadLetterPod("b"), errors.New("boom"))
+
+	replay := func(ctx context.Context, letter DeadLetter) error {
+		if letter.UID == "b" {
+			return errors.New("still failing")
+		}
+		return nil
+	}
+
+	succeeded, failed := store.Retry(context.Background(), 2, replay)
+	if succeeded != 1 || failed != 1 {
+		t.Fatalf("Expected 1 success and 1 failure, got %d and %d", succeeded, failed)
+	}
+	letters := store.List()
+	if len(letters) != 1 || letters[0].UID != "b" || letters[0].Attempts != 1 || letters[0].Error != "still failing" {
+		t.Fatalf("Expected b to remain with its latest error, got %+v", letters)
+	}
+
+	store.Retry(context.Background(), 2, replay)
+	if succeeded, failed := store.Retry(context.Background(), 2, replay); succeeded != 0 || failed != 0 {
+		t.Errorf("Expected letters past the maximum attempts not to be retried, got %d and %d", succeeded, failed)
+	}
+	if letters := store.List(); len(letters) != 1 || letters[0].Attempts != 2 {
+		t.Errorf("Expected b to be kept for inspection, got %+v", letters)
+	}
+}
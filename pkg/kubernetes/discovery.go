@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// ResourceDiscovery looks up the resources served by the API server through
+// the discovery API. The resource list of a group version is cached once it is
+// served, so looking up the resources of a group costs one request. Group
+// versions that are not served are not cached, so a CRD installed later is
+// found when its handler is enabled again.
+type ResourceDiscovery struct {
+	client discovery.DiscoveryInterface
+	mu     sync.Mutex
+	lists  map[string]*metav1.APIResourceList // by group version
+}
+
+// NewResourceDiscovery creates a ResourceDiscovery using the discovery client
+func NewResourceDiscovery(client discovery.DiscoveryInterface) *ResourceDiscovery {
+	return &ResourceDiscovery{
+		client: client,
+		lists:  make(map[string]*metav1.APIResourceList),
+	}
+}
+
+// Lookup returns the API resource of gvr, and false when the API server does
+// not serve it. Subresources such as pods/status are never returned.
+func (d *ResourceDiscovery) Lookup(gvr schema.GroupVersionResource) (metav1.APIResource, bool, error) {
+	list, err := d.resourceList(gvr.GroupVersion().String())
+	if err != nil || list == nil {
+		return metav1.APIResource{}, false, err
+	}
+	for _, resource := range list.APIResources {
+		if resource.Name == gvr.Resource {
+			return resource, true, nil
+		}
+	}
+	return metav1.APIResource{}, false, nil
+}
+
+// resourceList returns the resource list of a group version, nil when it is
+// not served
+func (d *ResourceDiscovery) resourceList(groupVersion string) (*metav1.APIResourceList, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if list, ok := d.lists[groupVersion]; ok {
+		return list, nil
+	}
+	list, err := d.client.ServerResourcesForGroupVersion(groupVersion)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the resources of %s: %w", groupVersion, err)
+	}
+	d.lists[groupVersion] = list
+	return list, nil
+}
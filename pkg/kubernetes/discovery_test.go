@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newFakeDiscovery() *fakediscovery.FakeDiscovery {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "pods", Namespaced: true, Kind: "Pod"},
+			{Name: "pods/status", Namespaced: true, Kind: "Pod"},
+			{Name: "nodes", Namespaced: false, Kind: "Node"},
+			{Name: "services", Namespaced: true, Kind: "Service"},
+		}},
+		{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+			{Name: "clusterwidgets", Namespaced: false, Kind: "ClusterWidget"},
+		}},
+	}
+	return discovery
+}
+
+func TestResourceDiscoveryLookup(t *testing.T) {
+	fake := newFakeDiscovery()
+	discovery := NewResourceDiscovery(fake)
+
+	for _, test := range []struct {
+		gvr        schema.GroupVersionResource
+		found      bool
+		namespaced bool
+	}{
+		{schema.GroupVersionResource{Version: "v1", Resource: "pods"}, true, true},
+		{schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, true, false},
+		{schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "clusterwidgets"}, true, false},
+		{schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, false, false},
+		{schema.GroupVersionResource{Group: "missing.io", Version: "v1", Resource: "things"}, false, false},
+	} {
+		resource, found, err := discovery.Lookup(test.gvr)
+		if err != nil {
+			t.Fatalf("Lookup(%v): %v", test.gvr, err)
+		}
+		if found != test.found || resource.Namespaced != test.namespaced {
+			t.Errorf("Lookup(%v) = namespaced %t, found %t, want %t, %t", test.gvr, resource.Namespaced, found, test.namespaced, test.found)
+		}
+	}
+
+	// The served group versions are cached, the missing one is looked up again
+	requests := len(fake.Actions())
+	discovery.Lookup(schema.GroupVersionResource{Version: "v1", Resource: "pods"})
+	discovery.Lookup(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "clusterwidgets"})
+	if len(fake.Actions()) != requests {
+		t.Errorf("expected served group versions to be cached, got %d more requests", len(fake.Actions())-requests)
+	}
+	discovery.Lookup(schema.GroupVersionResource{Group: "missing.io", Version: "v1", Resource: "things"})
+	if len(fake.Actions()) != requests+1 {
+		t.Error("expected missing group versions to be looked up again")
+	}
+}
+
+func TestResourceDiscoveryError(t *testing.T) {
+	fake := newFakeDiscovery()
+	fake.PrependReactor("get", "resource", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	discovery := NewResourceDiscovery(fake)
+
+	if _, _, err := discovery.Lookup(schema.GroupVersionResource{Version: "v1", Resource: "pods"}); err == nil {
+		t.Error("expected the discovery error to be returned")
+	}
+}
+
+func TestIsNamespacedResourceFromDiscovery(t *testing.T) {
+	c := &Client{discovery: NewResourceDiscovery(newFakeDiscovery())}
+
+	if c.isNamespacedResource(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "clusterwidgets"}) {
+		t.Error("expected the discovered cluster-scoped custom resource not to be namespaced")
+	}
+	if !c.isNamespacedResource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}) {
+		t.Error("expected pods to be namespaced")
+	}
+	// Resources that are not served fall back to the handler registrations
+	if c.isNamespacedResource(schema.GroupVersionResource{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"}) {
+		t.Error("expected storageclasses to be cluster-scoped from its registration")
+	}
+}
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHandlerGatePauseAndResume(t *testing.T) {
+	g := NewHandlerGate()
+
+	if !g.Enter("Pod") {
+		t.Fatal("Expected running handler to admit events")
+	}
+	g.Leave("Pod")
+
+	paused, err := g.Pause(context.Background(), "Pod")
+	if err != nil || !paused {
+		t.Fatalf("Expected pause to succeed, got paused=%v err=%v", paused, err)
+	}
+	if g.Enter("Pod") {
+		t.Error("Expected paused handler to drop events")
+	}
+	if !g.Enter("Service") {
+		t.Error("Expected other handlers to keep running")
+	}
+	g.Leave("Service")
+
+	states := g.Paused()
+	if len(states) != 1 || states[0].Kind != "Pod" || states[0].Dropped != 1 {
+		t.Errorf("Expected Pod paused with 1 dropped event, got %+v", states)
+	}
+
+	if !g.Resume("Pod") {
+		t.Error("Expected resume of paused handler to succeed")
+	}
+	if g.Resume("Pod") {
+		t.Error("Expected resume of running handler to report not paused")
+	}
+	if !g.Enter("Pod") {
+		t.Error("Expected resumed handler to admit events")
+	}
+	g.Leave("Pod")
+}
+
+func TestHandlerGatePauseDrainsInFlight(t *testing.T) {
+	g := NewHandlerGate()
+	g.Enter("Pod")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		g.Leave("Pod")
+	}()
+
+	start := time.Now()
+	if _, err := g.Pause(context.Background(), "Pod"); err != nil {
+		t.Fatalf("Expected drain to succeed, got %v", err)
+	}
+	if time.Since(start) < 40*time.Millisecond {
+		t.Error("Expected pause to wait for the in-flight event")
+	}
+}
+
+func TestHandlerGatePauseDrainTimeout(t *testing.T) {
+	g := NewHandlerGate()
+	g.Enter("Pod")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.Pause(ctx, "Pod"); err == nil {
+		t.Error("Expected drain to time out while an event is in flight")
+	}
+	if !g.IsPaused("Pod") {
+		t.Error("Expected handler to stay paused after drain timeout")
+	}
+}
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	resourceEventsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_resource_events_total",
+			Help: "Total number of Kubernetes resource events processed",
+		},
+		[]string{"resource_type", "event_type", "cluster_name"},
+	)
+	resourceEventErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kubegraph_resource_event_errors_total",
+			Help: "Total number of Kubernetes resource events whose handler failed",
+		},
+		[]string{"resource_type", "event_type", "cluster_name"},
+	)
+	resourceEventDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kubegraph_resource_event_duration_seconds",
+			Help:    "Time handlers take to process a Kubernetes resource event",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 12), // 5ms to ~10s
+		},
+		[]string{"resource_type", "event_type", "cluster_name"},
+	)
+	resourceLastProcessed = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubegraph_resource_last_processed_timestamp_seconds",
+			Help: "Unix time of the last Kubernetes resource event processed successfully",
+		},
+		[]string{"resource_type", "cluster_name"},
+	)
+	informerSyncLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kubegraph_informer_sync_lag_seconds",
+			Help: "Time between the last change of an object in the API server and its processing, for the last object processed",
+		},
+		[]string{"resource_type", "cluster_name"},
+	)
+)
+
+// recordEvent records the outcome of a handler processing an event of type
+// add, update, resync, ingest or delete of the given cluster, empty for the
+// watched cluster
+func (c *Client) recordEvent(kind, event, clusterName string, start time.Time, err error) {
+	cluster := clusterName
+	if cluster == "" {
+		cluster = c.config.Kubernetes.ClusterName
+	}
+	if isContextCanceled(err) {
+		return
+	}
+	resourceEventsTotal.WithLabelValues(kind, event, cluster).Inc()
+	resourceEventDuration.WithLabelValues(kind, event, cluster).Observe(time.Since(start).Seconds())
+	if err != nil {
+		resourceEventErrorsTotal.WithLabelValues(kind, event, cluster).Inc()
+		return
+	}
+	resourceLastProcessed.WithLabelValues(kind, cluster).Set(float64(time.Now().Unix()))
+}
+
+// recordLag records how long after its last change an added or updated object
+// was processed. Objects delivered by the initial list of an informer may have
+// changed long ago and are not counted.
+func (c *Client) recordLag(kind string, obj interface{}, processed time.Time) {
+	c.informersMu.RLock()
+	informer, ok := c.informers[kind]
+	c.informersMu.RUnlock()
+	if !ok || !informer.HasSynced() {
+		return
+	}
+	if changed, ok := lastChanged(obj); ok {
+		informerSyncLag.WithLabelValues(kind, c.config.Kubernetes.ClusterName).Set(max(processed.Sub(changed).Seconds(), 0))
+	}
+}
+
+// lastChanged returns when an object was last changed in the API server: the
+// latest of its creation and of the updates recorded in its managed fields
+func lastChanged(obj interface{}) (time.Time, bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return time.Time{}, false
+	}
+	changed := accessor.GetCreationTimestamp().Time
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Time != nil && entry.Time.After(changed) {
+			changed = entry.Time.Time
+		}
+	}
+	return changed, !changed.IsZero()
+}
@@ -0,0 +1,43 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLastChanged(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "api",
+			CreationTimestamp: metav1.NewTime(created),
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Time: &metav1.Time{Time: updated}},
+				{Manager: "kubelet", Time: &metav1.Time{Time: created.Add(time.Minute)}},
+				{Manager: "controller"},
+			},
+		},
+	}
+
+	changed, ok := lastChanged(pod)
+	if !ok || !changed.Equal(updated) {
+		t.Errorf("Expected %v, got %v (%v)", updated, changed, ok)
+	}
+
+	changed, ok = lastChanged(cache.DeletedFinalStateUnknown{Key: "default/api", Obj: pod})
+	if !ok || !changed.Equal(updated) {
+		t.Errorf("Expected %v for a tombstone, got %v (%v)", updated, changed, ok)
+	}
+
+	if _, ok := lastChanged(&corev1.Pod{}); ok {
+		t.Error("Expected no time for an object without timestamps")
+	}
+	if _, ok := lastChanged("not an object"); ok {
+		t.Error("Expected no time for a non-object")
+	}
+}
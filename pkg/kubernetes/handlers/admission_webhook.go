@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// admissionWebhookResource returns the GVR of a webhook configuration resource
+func admissionWebhookResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: admissionregistrationv1.GroupName, Version: "v1", Resource: resource}
+}
+
+// admissionWebhook holds the fields shared by validating and mutating webhooks
+type admissionWebhook struct {
+	Name           string
+	ClientConfig   admissionregistrationv1.WebhookClientConfig
+	FailurePolicy  *admissionregistrationv1.FailurePolicyType
+	SideEffects    *admissionregistrationv1.SideEffectClass
+	TimeoutSeconds *int32
+}
+
+// failurePolicy returns the failure policy of a webhook, which defaults to Fail
+func (w admissionWebhook) failurePolicy() admissionregistrationv1.FailurePolicyType {
+	if w.FailurePolicy == nil {
+		return admissionregistrationv1.Fail
+	}
+	return *w.FailurePolicy
+}
+
+// timeoutSeconds returns the timeout of a webhook, which defaults to 10 seconds
+func (w admissionWebhook) timeoutSeconds() int32 {
+	if w.TimeoutSeconds == nil {
+		return 10
+	}
+	return *w.TimeoutSeconds
+}
+
+// caBundleExpiry returns when the first certificate of the webhook's CA bundle expires
+func (w admissionWebhook) caBundleExpiry() (time.Time, bool) {
+	var expiry time.Time
+	rest := w.ClientConfig.CABundle
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	return expiry, !expiry.IsZero()
+}
+
+// format describes a webhook as "name=...;failurePolicy=...;timeoutSeconds=...;service=<namespace>/<name>:<port><path>"
+// or with "url=..." for webhooks called by URL, followed by ";caExpiry=<RFC3339>"
+// when the webhook has a CA bundle
+func (w admissionWebhook) format() string {
+	parts := []string{
+		"name=" + w.Name,
+		"failurePolicy=" + string(w.failurePolicy()),
+		fmt.Sprintf("timeoutSeconds=%d", w.timeoutSeconds()),
+	}
+	if w.SideEffects != nil {
+		parts = append(parts, "sideEffects="+string(*w.SideEffects))
+	}
+	if service := w.ClientConfig.Service; service != nil {
+		port := int32(443)
+		if service.Port != nil {
+			port = *service.Port
+		}
+		path := ""
+		if service.Path != nil {
+			path = *service.Path
+		}
+		parts = append(parts, fmt.Sprintf("service=%s:%d%s", namespacedKey(service.Namespace, service.Name), port, path))
+	} else if w.ClientConfig.URL != nil {
+		parts = append(parts, "url="+*w.ClientConfig.URL)
+	}
+	if expiry, ok := w.caBundleExpiry(); ok {
+		parts = append(parts, "caExpiry="+expiry.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(parts, ";")
+}
+
+// webhookProperties returns the properties describing the webhooks of a
+// configuration and the namespace/name keys of the Services backing them
+func webhookProperties(webhooks []admissionWebhook) (map[string]interface{}, []string) {
+	formatted := make([]string, 0, len(webhooks))
+	services := make(map[string]bool)
+	failClosed := false
+	var caExpiry time.Time
+	for _, webhook := range webhooks {
+		if expiry, ok := webhook.caBundleExpiry(); ok && (caExpiry.IsZero() || expiry.Before(caExpiry)) {
+			caExpiry = expiry
+		}
+		formatted = append(formatted, webhook.format())
+		if service := webhook.ClientConfig.Service; service != nil {
+			services[namespacedKey(service.Namespace, service.Name)] = true
+		}
+		if webhook.failurePolicy() == admissionregistrationv1.Fail {
+			failClosed = true
+		}
+	}
+	keys := sortedKeys(services)
+	properties := map[string]interface{}{
+		"webhooks":     formatted,
+		"webhookCount": len(webhooks),
+		"failClosed":   failClosed,
+		"services":     keys,
+	}
+	if !caExpiry.IsZero() {
+		properties["caBundleExpiresAt"] = caExpiry.UTC().Format(time.RFC3339)
+	}
+	return properties, keys
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWebhookProperties(t *testing.T) {
+	ignore := admissionregistrationv1.Ignore
+	none := admissionregistrationv1.SideEffectClassNone
+	port := int32(9443)
+	path := "/validate"
+	timeout := int32(5)
+	url := "https://policy.example.com/check"
+
+	properties, services := webhookProperties([]admissionWebhook{
+		{
+			Name: "pods.policy.example.com",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Namespace: "policy", Name: "webhook", Port: &port, Path: &path},
+			},
+			SideEffects: &none,
+		},
+		{
+			Name:           "deployments.policy.example.com",
+			ClientConfig:   admissionregistrationv1.WebhookClientConfig{Service: &admissionregistrationv1.ServiceReference{Namespace: "policy", Name: "webhook"}},
+			FailurePolicy:  &ignore,
+			TimeoutSeconds: &timeout,
+		},
+		{
+			Name:          "external.policy.example.com",
+			ClientConfig:  admissionregistrationv1.WebhookClientConfig{URL: &url},
+			FailurePolicy: &ignore,
+		},
+	})
+
+	expectedWebhooks := []string{
+		"name=pods.policy.example.com;failurePolicy=Fail;timeoutSeconds=10;sideEffects=None;service=policy/webhook:9443/validate",
+		"name=deployments.policy.example.com;failurePolicy=Ignore;timeoutSeconds=5;service=policy/webhook:443",
+		"name=external.policy.example.com;failurePolicy=Ignore;timeoutSeconds=10;url=https://policy.example.com/check",
+	}
+	if !reflect.DeepEqual(properties["webhooks"], expectedWebhooks) {
+		t.Errorf("Expected webhooks %v, got %v", expectedWebhooks, properties["webhooks"])
+	}
+	if !reflect.DeepEqual(services, []string{"policy/webhook"}) {
+		t.Errorf("Expected the backing service once, got %v", services)
+	}
+	if properties["failClosed"] != true {
+		t.Error("Expected a webhook without failure policy to fail closed")
+	}
+	if properties["webhookCount"] != 3 {
+		t.Errorf("Expected 3 webhooks, got %v", properties["webhookCount"])
+	}
+}
+
+// testCertificate returns a PEM encoded self-signed certificate expiring at notAfter
+func testCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: notAfter.Add(-24 * time.Hour), NotAfter: notAfter}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCABundleExpiry(t *testing.T) {
+	early := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	bundle := append(testCertificate(t, late), testCertificate(t, early)...)
+
+	webhook := admissionWebhook{
+		Name:         "pods.policy.example.com",
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{URL: new(string), CABundle: bundle},
+	}
+	if expiry, ok := webhook.caBundleExpiry(); !ok || !expiry.Equal(early) {
+		t.Errorf("Expected the bundle to expire with its first certificate on %v, got %v", early, expiry)
+	}
+	expected := "name=pods.policy.example.com;failurePolicy=Fail;timeoutSeconds=10;url=;caExpiry=2024-05-01T00:00:00Z"
+	if got := webhook.format(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	properties, _ := webhookProperties([]admissionWebhook{webhook})
+	if properties["caBundleExpiresAt"] != "2024-05-01T00:00:00Z" {
+		t.Errorf("Unexpected caBundleExpiresAt %v", properties["caBundleExpiresAt"])
+	}
+
+	if _, ok := (admissionWebhook{}).caBundleExpiry(); ok {
+		t.Error("Expected no expiry without a CA bundle")
+	}
+}
+
+func TestIngressClass(t *testing.T) {
+	className := "nginx"
+	tests := []struct {
+		name     string
+		ingress  *networkingv1.Ingress
+		expected string
+	}{
+		{"spec", &networkingv1.Ingress{Spec: networkingv1.IngressSpec{IngressClassName: &className}}, "nginx"},
+		{"legacy annotation", &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ingressClassAnnotation: "traefik"}}}, "traefik"},
+		{"default class", &networkingv1.Ingress{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := ingressClass(tt.ingress); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The APIService type is part of kube-aggregator, not client-go, so the
+// handler decodes the fields it needs into the struct below
+
+type apiService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Service *struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+			Port      *int32 `json:"port,omitempty"`
+		} `json:"service,omitempty"`
+		Group                 string `json:"group,omitempty"`
+		Version               string `json:"version,omitempty"`
+		InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+		GroupPriorityMinimum  int32  `json:"groupPriorityMinimum"`
+		VersionPriority       int32  `json:"versionPriority"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// availability returns the Available condition of the APIService, which is
+// Unknown until the aggregator has checked it
+func (s *apiService) availability() metav1.Condition {
+	for _, condition := range s.Status.Conditions {
+		if condition.Type == "Available" {
+			return condition
+		}
+	}
+	return metav1.Condition{Type: "Available", Status: metav1.ConditionUnknown}
+}
+
+// APIServiceHandler tracks the API groups served by the API server, linking
+// aggregated APIs such as metrics-server to the Service behind them
+type APIServiceHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var apiServiceGVR = schema.GroupVersionResource{
+	Group:    "apiregistration.k8s.io",
+	Version:  "v1",
+	Resource: "apiservices",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "APIService",
+		GVR:               apiServiceGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewAPIServiceHandler(cfg)
+		},
+	})
+}
+
+func NewAPIServiceHandler(cfg *config.Config) *APIServiceHandler {
+	return &APIServiceHandler{
+		BaseHandler:  NewBaseHandler(apiServiceGVR, "APIService", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *APIServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	service, err := ConvertToTyped[*apiService](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert apiservice: %w", err)
+	}
+
+	uid := string(service.UID)
+	services := make([]string, 0, 1)
+	if ref := service.Spec.Service; ref != nil {
+		services = append(services, namespacedKey(ref.Namespace, ref.Name))
+	}
+	available := service.availability()
+
+	properties := map[string]interface{}{
+		"name":                  service.Name,
+		"uid":                   uid,
+		"creationTimestamp":     service.CreationTimestamp.String(),
+		"labels":                service.Labels,
+		"annotations":           service.Annotations,
+		"group":                 service.Spec.Group,
+		"version":               service.Spec.Version,
+		"local":                 service.Spec.Service == nil,
+		"services":              services,
+		"insecureSkipTLSVerify": service.Spec.InsecureSkipTLSVerify,
+		"groupPriorityMinimum":  service.Spec.GroupPriorityMinimum,
+		"versionPriority":       service.Spec.VersionPriority,
+		"available":             string(available.Status),
+		"availableReason":       available.Reason,
+		"availableMessage":      available.Message,
+		"clusterName":           h.GetClusterName(),
+		"instanceHash":          h.instanceHash,
+	}
+	if !available.LastTransitionTime.IsZero() {
+		properties["availableSince"] = available.LastTransitionTime.String()
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"APIService"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert apiservice %s: %w", service.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "APIService", uid, "CALLS", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create CALLS relationship for APIService %s: %v\n", service.Name, err)
+	}
+
+	return nil
+}
+
+func (h *APIServiceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	service, err := ConvertToTyped[*apiService](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert apiservice: %w", err)
+	}
+	return HandleResourceDelete(ctx, "APIService", string(service.UID), neo4jClient)
+}
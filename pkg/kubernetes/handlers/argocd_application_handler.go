@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ArgoCDApplicationHandler tracks Argo CD Applications, linking them to the
+// resources they deploy
+type ArgoCDApplicationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var applicationGVR = schema.GroupVersionResource{
+	Group:    "argoproj.io",
+	Version:  "v1alpha1",
+	Resource: "applications",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Application",
+		GVR:               applicationGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewArgoCDApplicationHandler(cfg)
+		},
+	})
+}
+
+func NewArgoCDApplicationHandler(cfg *config.Config) *ArgoCDApplicationHandler {
+	RegisterOwnerKind("Application", "Application")
+	return &ArgoCDApplicationHandler{
+		BaseHandler:  NewBaseHandler(applicationGVR, "Application", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ArgoCDApplicationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	app, err := ConvertToTyped[*argoCDApplication](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert application: %w", err)
+	}
+
+	uid := string(app.UID)
+	remote := !argoCDInCluster(app)
+	properties := map[string]interface{}{
+		"name":                 app.Name,
+		"uid":                  uid,
+		"namespace":            app.Namespace,
+		"creationTimestamp":    app.CreationTimestamp.String(),
+		"labels":               app.Labels,
+		"annotations":          app.Annotations,
+		"project":              app.Spec.Project,
+		"sources":              argoCDSources(app),
+		"destinationServer":    app.Spec.Destination.Server,
+		"destinationName":      app.Spec.Destination.Name,
+		"destinationNamespace": app.Spec.Destination.Namespace,
+		"syncStatus":           app.Status.Sync.Status,
+		"healthStatus":         app.Status.Health.Status,
+		"revision":             app.Status.Sync.Revision,
+		"remoteCluster":        remote,
+		"clusterName":          h.GetClusterName(),
+		"instanceHash":         h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Application"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert application %s: %w", app.Name, err)
+	}
+
+	// Resources deployed to other clusters are not in this graph
+	if !remote {
+		if err := linkDeployedResources(ctx, neo4jClient, "Application", uid, app.Name, app.Namespace, h.GetClusterName()); err != nil {
+			fmt.Printf("Warning: failed to create DEPLOYS relationships for Application %s: %v\n", app.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *ArgoCDApplicationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	app, err := ConvertToTyped[*argoCDApplication](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert application: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Application", string(app.UID), neo4jClient)
+}
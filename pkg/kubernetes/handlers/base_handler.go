@@ -5,9 +5,11 @@ import (
 	"fmt"
 
 	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
 	"k8s-graph/pkg/neo4j"
 
-	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -42,12 +44,14 @@ func (h *BaseHandler) GetClusterName() string {
 }
 
 // HandleResourceDelete is a helper function for deleting resources from Neo4j
-func HandleResourceDelete(ctx context.Context, resourceType, uid string, neo4jClient *neo4j.Client) error {
-	query := fmt.Sprintf("MATCH (r:%s {uid: $uid}) DETACH DELETE r", resourceType)
-	session := neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.Run(ctx, query, map[string]interface{}{"uid": uid})
+func HandleResourceDelete(ctx context.Context, resourceType, uid string, neo4jClient graph.Store) error {
+	err := neo4jClient.Delete(ctx, resourceType, "uid", uid)
+	if err == nil {
+		neo4jClient.RecordChange(ctx, resourceType, uid, neo4j.OperationDelete)
+		if err := neo4jClient.CloseVersion(ctx, resourceType, uid); err != nil {
+			fmt.Printf("Warning: failed to close version of %s %s: %v\n", resourceType, uid, err)
+		}
+	}
 	return err
 }
 
@@ -63,13 +67,13 @@ func ConvertToTyped[T any](obj interface{}) (T, error) {
 	// Handle unstructured objects
 	unstructuredObj, ok := obj.(*unstructured.Unstructured)
 	if !ok {
-		return zero, fmt.Errorf("object is not *unstructured.Unstructured or %T", zero)
+		return zero, failure.New(failure.ConversionError, fmt.Errorf("object is not *unstructured.Unstructured or %T", zero))
 	}
 
 	var typedObj T
 	err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredObj.Object, &typedObj)
 	if err != nil {
-		return zero, fmt.Errorf("failed to convert unstructured to typed: %w", err)
+		return zero, failure.New(failure.ConversionError, fmt.Errorf("failed to convert unstructured to typed: %w", err))
 	}
 
 	return typedObj, nil
@@ -79,4 +83,26 @@ var ownerKindToLabel = make(map[string]string)
 
 func RegisterOwnerKind(kind, label string) {
 	ownerKindToLabel[kind] = label
-}
\ No newline at end of file
+}
+
+// relationship returns the relationship of relationshipType between the nodes
+// matched by label, key and value, in the argument order of CreateRelationship
+func relationship(fromLabel, fromKey, fromValue, relationshipType, toLabel, toKey, toValue string) graph.Relationship {
+	return graph.Relationship{
+		FromLabel: fromLabel, FromKey: fromKey, FromValue: fromValue,
+		Type:    relationshipType,
+		ToLabel: toLabel, ToKey: toKey, ToValue: toValue,
+	}
+}
+
+// ownerRelationships returns the OWNED_BY relationships of the node with label
+// and uid to its owners of the supported kinds
+func ownerRelationships(label, uid string, owners []metav1.OwnerReference) []graph.Relationship {
+	relationships := make([]graph.Relationship, 0, len(owners))
+	for _, ownerRef := range owners {
+		if ownerLabel, ok := ownerKindToLabel[ownerRef.Kind]; ok {
+			relationships = append(relationships, relationship(label, "uid", uid, "OWNED_BY", ownerLabel, "uid", string(ownerRef.UID)))
+		}
+	}
+	return relationships
+}
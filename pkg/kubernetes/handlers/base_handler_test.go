@@ -3,7 +3,7 @@ package handlers
 import (
 	"testing"
 
-	"kubegraph/config"
+	"k8s-graph/config"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
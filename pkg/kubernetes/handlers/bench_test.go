@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+
+	neo4jdriver "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// benchCluster is the cluster name of the synthetic resources, deleted after
+// every benchmark
+const benchCluster = "kubegraph-bench"
+
+// benchClient connects to the Neo4j at NEO4J_URI, skipping the benchmark
+// when it is unset
+func benchClient(b *testing.B) *neo4j.Client {
+	uri := os.Getenv("NEO4J_URI")
+	if uri == "" {
+		b.Skip("NEO4J_URI is not set")
+	}
+	logger.Init(logger.ERROR)
+
+	cfg := config.NewConfig()
+	cfg.Neo4j.URI = uri
+	cfg.Neo4j.Username = os.Getenv("NEO4J_USERNAME")
+	cfg.Neo4j.Password = os.Getenv("NEO4J_PASSWORD")
+	cfg.Neo4j.Database = os.Getenv("NEO4J_DATABASE")
+	cfg.Kubernetes.ClusterName = benchCluster
+	cfg.InstanceHash = "bench"
+
+	client, err := neo4j.NewClient(cfg)
+	if err != nil {
+		b.Fatalf("Failed to connect to Neo4j: %v", err)
+	}
+	b.Cleanup(func() {
+		ctx := context.Background()
+		_, err := client.ExecuteWrite(ctx, func(tx neo4jdriver.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, "MATCH (n {clusterName: $clusterName}) DETACH DELETE n", map[string]interface{}{"clusterName": benchCluster})
+			return nil, err
+		})
+		if err != nil {
+			b.Errorf("Failed to delete the synthetic resources: %v", err)
+		}
+		client.Close(ctx)
+	})
+	return client
+}
+
+// latencies collects the duration of every event handled by a benchmark
+type latencies struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	l.durations = append(l.durations, d)
+	l.mu.Unlock()
+}
+
+// percentile returns the duration below which p percent of the events completed
+func (l *latencies) percentile(p float64) time.Duration {
+	if len(l.durations) == 0 {
+		return 0
+	}
+	sort.Slice(l.durations, func(i, j int) bool { return l.durations[i] < l.durations[j] })
+	index := int(float64(len(l.durations)-1) * p / 100)
+	return l.durations[index]
+}
+
+// report adds the throughput and latency percentiles to the benchmark results
+func (l *latencies) report(b *testing.B, elapsed time.Duration) {
+	b.ReportMetric(float64(len(l.durations))/elapsed.Seconds(), "events/s")
+	for _, p := range []float64{50, 95, 99} {
+		b.ReportMetric(float64(l.percentile(p).Microseconds())/1000, fmt.Sprintf("p%.0f-ms", p))
+	}
+}
+
+// syntheticDeployment returns the i-th Deployment of the synthetic cluster
+func syntheticDeployment(i int) *appsv1.Deployment {
+	replicas := int32(3)
+	labels := map[string]string{"app": fmt.Sprintf("app-%d", i)}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("app-%d", i),
+			Namespace:         fmt.Sprintf("team-%d", i%20),
+			UID:               types.UID(fmt.Sprintf("deployment-%d", i)),
+			Labels:            labels,
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       syntheticPodSpec(i),
+			},
+		},
+	}
+}
+
+// syntheticPod returns the i-th Pod of the synthetic cluster, spread over
+// the Deployments and nodes
+func syntheticPod(i int) *corev1.Pod {
+	deployment := i / 3
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("app-%d-%d", deployment, i%3),
+			Namespace:         fmt.Sprintf("team-%d", deployment%20),
+			UID:               types.UID(fmt.Sprintf("pod-%d", i)),
+			Labels:            map[string]string{"app": fmt.Sprintf("app-%d", deployment)},
+			CreationTimestamp: metav1.Now(),
+			OwnerReferences: []metav1.OwnerReference{{
+				Kind: "ReplicaSet",
+				Name: fmt.Sprintf("app-%d-7d4b9c", deployment),
+				UID:  types.UID(fmt.Sprintf("replicaset-%d", deployment)),
+			}},
+		},
+		Spec: func() corev1.PodSpec {
+			spec := syntheticPodSpec(deployment)
+			spec.NodeName = fmt.Sprintf("node-%d", i%50)
+			return spec
+		}(),
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			PodIP:      fmt.Sprintf("10.%d.%d.%d", i/65536%256, i/256%256, i%256),
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func syntheticPodSpec(i int) corev1.PodSpec {
+	return corev1.PodSpec{
+		ServiceAccountName: "default",
+		Containers: []corev1.Container{{
+			Name:  "app",
+			Image: fmt.Sprintf("registry.example.com/app-%d:1.0", i%100),
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+			Env: []corev1.EnvVar{{Name: "CONFIG", ValueFrom: &corev1.EnvVarSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "app-config"}, Key: "config"},
+			}}},
+		}},
+	}
+}
+
+// syntheticEvent returns the i-th Event of the synthetic cluster, involving a Pod
+func syntheticEvent(i int) *corev1.Event {
+	pod := syntheticPod(i % 3000)
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", pod.Name, i),
+			Namespace: pod.Namespace,
+			UID:       types.UID(fmt.Sprintf("event-%d", i)),
+		},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace, UID: pod.UID},
+		Reason:         "Pulled",
+		Message:        "Successfully pulled image",
+		Type:           corev1.EventTypeNormal,
+		Count:          1,
+		FirstTimestamp: metav1.Now(),
+		LastTimestamp:  metav1.Now(),
+	}
+}
+
+// benchmarkHandler handles b.N synthetic objects concurrently, like the
+// informers of a cluster do, and reports events per second and latency
+// percentiles of the writes
+func benchmarkHandler(b *testing.B, handler ResourceHandler, object func(int) interface{}) {
+	client := benchClient(b)
+	ctx := context.Background()
+	var l latencies
+	var next sync.Mutex
+	i := 0
+
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			next.Lock()
+			obj := object(i)
+			i++
+			next.Unlock()
+
+			began := time.Now()
+			if err := handler.HandleCreate(ctx, obj, client); err != nil {
+				b.Errorf("Failed to handle %s: %v", handler.GetKind(), err)
+				return
+			}
+			l.add(time.Since(began))
+		}
+	})
+	elapsed := time.Since(start)
+	b.StopTimer()
+	l.report(b, elapsed)
+}
+
+func BenchmarkPodHandler(b *testing.B) {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = benchCluster
+	benchmarkHandler(b, NewPodHandler(nil, cfg), func(i int) interface{} { return syntheticPod(i) })
+}
+
+func BenchmarkDeploymentHandler(b *testing.B) {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = benchCluster
+	benchmarkHandler(b, NewDeploymentHandler(nil, cfg), func(i int) interface{} { return syntheticDeployment(i) })
+}
+
+func BenchmarkEventHandler(b *testing.B) {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = benchCluster
+	benchmarkHandler(b, NewEventHandler(cfg), func(i int) interface{} { return syntheticEvent(i) })
+}
+
+// BenchmarkMixedWorkload replays Deployments, their Pods and Events in the
+// proportions of a busy cluster
+func BenchmarkMixedWorkload(b *testing.B) {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = benchCluster
+	deployments := NewDeploymentHandler(nil, cfg)
+	pods := NewPodHandler(nil, cfg)
+	events := NewEventHandler(cfg)
+	benchmarkHandler(b, mixedHandler{deployments, pods, events}, func(i int) interface{} {
+		switch {
+		case i%10 == 0:
+			return syntheticDeployment(i / 10)
+		case i%10 < 4:
+			return syntheticPod(i)
+		default:
+			return syntheticEvent(i)
+		}
+	})
+}
+
+// mixedHandler dispatches synthetic objects to the handler of their type
+type mixedHandler struct {
+	deployments, pods, events ResourceHandler
+}
+
+func (h mixedHandler) handler(obj interface{}) ResourceHandler {
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		return h.deployments
+	case *corev1.Pod:
+		return h.pods
+	default:
+		return h.events
+	}
+}
+
+func (h mixedHandler) GetGVR() schema.GroupVersionResource { return schema.GroupVersionResource{} }
+func (h mixedHandler) GetKind() string                     { return "Mixed" }
+
+func (h mixedHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return h.handler(obj).HandleCreate(ctx, obj, neo4jClient)
+}
+
+func (h mixedHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return h.handler(obj).HandleDelete(ctx, obj, neo4jClient)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterRoleHandler tracks ClusterRoles and flags the ones granting wildcard
+// or otherwise dangerous permissions
+type ClusterRoleHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var clusterRoleGVR = rbacResource("clusterroles")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ClusterRole",
+		GVR:               clusterRoleGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewClusterRoleHandler(cfg)
+		},
+	})
+}
+
+func NewClusterRoleHandler(cfg *config.Config) *ClusterRoleHandler {
+	RegisterOwnerKind("ClusterRole", "ClusterRole")
+	return &ClusterRoleHandler{
+		BaseHandler:  NewBaseHandler(clusterRoleGVR, "ClusterRole", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ClusterRoleHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	role, err := ConvertToTyped[*rbacv1.ClusterRole](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert clusterrole: %w", err)
+	}
+
+	properties := roleProperties(role.Rules)
+	properties["name"] = role.Name
+	properties["uid"] = string(role.UID)
+	properties["creationTimestamp"] = role.CreationTimestamp.String()
+	properties["labels"] = role.Labels
+	properties["annotations"] = role.Annotations
+	properties["aggregated"] = role.AggregationRule != nil
+	properties["aggregationSelectors"] = aggregationSelectors(role.AggregationRule)
+	properties["clusterName"] = h.GetClusterName()
+	properties["instanceHash"] = h.instanceHash
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"ClusterRole"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert clusterrole %s: %w", role.Name, err)
+	}
+
+	if err := linkRoleGrants(ctx, neo4jClient, "ClusterRole", string(role.UID), role.Name, "", h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to link bindings to ClusterRole %s: %v\n", role.Name, err)
+	}
+	if err := linkAggregation(ctx, neo4jClient, role, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to create AGGREGATES relationships for ClusterRole %s: %v\n", role.Name, err)
+	}
+
+	return nil
+}
+
+func (h *ClusterRoleHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	role, err := ConvertToTyped[*rbacv1.ClusterRole](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert clusterrole: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ClusterRole", string(role.UID), neo4jClient)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ClusterRoleBindingHandler tracks ClusterRoleBindings, linking them to the
+// ClusterRole they grant and to the ServiceAccounts they bind
+type ClusterRoleBindingHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var clusterRoleBindingGVR = rbacResource("clusterrolebindings")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ClusterRoleBinding",
+		GVR:               clusterRoleBindingGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewClusterRoleBindingHandler(cfg)
+		},
+	})
+}
+
+func NewClusterRoleBindingHandler(cfg *config.Config) *ClusterRoleBindingHandler {
+	RegisterOwnerKind("ClusterRoleBinding", "ClusterRoleBinding")
+	return &ClusterRoleBindingHandler{
+		BaseHandler:  NewBaseHandler(clusterRoleBindingGVR, "ClusterRoleBinding", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ClusterRoleBindingHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	binding, err := ConvertToTyped[*rbacv1.ClusterRoleBinding](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert clusterrolebinding: %w", err)
+	}
+
+	subjects, serviceAccounts := bindingSubjects(binding.Subjects, "")
+	properties := map[string]interface{}{
+		"name":              binding.Name,
+		"uid":               string(binding.UID),
+		"creationTimestamp": binding.CreationTimestamp.String(),
+		"labels":            binding.Labels,
+		"annotations":       binding.Annotations,
+		"roleRefKind":       binding.RoleRef.Kind,
+		"roleRefName":       binding.RoleRef.Name,
+		"subjects":          subjects,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"ClusterRoleBinding"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert clusterrolebinding %s: %w", binding.Name, err)
+	}
+
+	linkBinding(ctx, neo4jClient, "ClusterRoleBinding", string(binding.UID), "", h.GetClusterName(), binding.RoleRef, serviceAccounts)
+	return nil
+}
+
+func (h *ClusterRoleBindingHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	binding, err := ConvertToTyped[*rbacv1.ClusterRoleBinding](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert clusterrolebinding: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ClusterRoleBinding", string(binding.UID), neo4jClient)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type ConfigMapHandler struct {
@@ -16,20 +18,33 @@ type ConfigMapHandler struct {
 	instanceHash string
 }
 
+var configMapGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "configmaps",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ConfigMap",
+		GVR:               configMapGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewConfigMapHandler(cfg)
+		},
+	})
+}
+
 func NewConfigMapHandler(cfg *config.Config) *ConfigMapHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "configmaps",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("ConfigMap", "ConfigMap")
 	return &ConfigMapHandler{
-		BaseHandler:  NewBaseHandler(gvr, "ConfigMap", cfg),
+		BaseHandler:  NewBaseHandler(configMapGVR, "ConfigMap", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *ConfigMapHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ConfigMapHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	cm, err := ConvertToTyped[*corev1.ConfigMap](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert configmap: %w", err)
@@ -48,31 +63,18 @@ func (h *ConfigMapHandler) HandleCreate(ctx context.Context, obj interface{}, ne
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"ConfigMap"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"ConfigMap"}, properties, "uid", ownerRelationships("ConfigMap", string(cm.UID), cm.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert configmap %s: %w", cm.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if cm.OwnerReferences != nil {
-		for _, ownerRef := range cm.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"ConfigMap", "uid", string(cm.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between ConfigMap %s and %s %s: %v\n", cm.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	if err := linkPodUsers(ctx, neo4jClient, "ConfigMap", string(cm.UID), cm.Name, cm.Namespace, h.GetClusterName(), "configMaps"); err != nil {
+		fmt.Printf("Warning: failed to create relationships to ConfigMap %s: %v\n", cm.Name, err)
 	}
 
 	return nil
 }
 
-func (h *ConfigMapHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ConfigMapHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	cm, err := ConvertToTyped[*corev1.ConfigMap](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert configmap: %w", err)
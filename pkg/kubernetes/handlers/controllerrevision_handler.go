@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ControllerRevisionHandler records the revisions StatefulSets and DaemonSets
+// keep of their pod templates, the rollout history of these workloads
+type ControllerRevisionHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var controllerRevisionGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "controllerrevisions",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ControllerRevision",
+		GVR:               controllerRevisionGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewControllerRevisionHandler(cfg)
+		},
+	})
+}
+
+func NewControllerRevisionHandler(cfg *config.Config) *ControllerRevisionHandler {
+	return &ControllerRevisionHandler{
+		BaseHandler:  NewBaseHandler(controllerRevisionGVR, "ControllerRevision", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ControllerRevisionHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	cr, err := ConvertToTyped[*appsv1.ControllerRevision](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert controllerrevision: %w", err)
+	}
+
+	properties := map[string]interface{}{
+		"name":              cr.Name,
+		"uid":               string(cr.UID),
+		"namespace":         cr.Namespace,
+		"creationTimestamp": cr.CreationTimestamp.String(),
+		"labels":            cr.Labels,
+		"annotations":       cr.Annotations,
+		"revision":          cr.Revision,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	if images := revisionImages(cr.Data.Raw); images != nil {
+		properties["containerImages"] = images
+	}
+
+	relationships := ownerRelationships("ControllerRevision", string(cr.UID), cr.OwnerReferences)
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"ControllerRevision"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert controllerrevision %s: %w", cr.Name, err)
+	}
+	return nil
+}
+
+func (h *ControllerRevisionHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	cr, err := ConvertToTyped[*appsv1.ControllerRevision](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert controllerrevision: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ControllerRevision", string(cr.UID), neo4jClient)
+}
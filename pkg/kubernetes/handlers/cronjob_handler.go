@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,27 +20,43 @@ type CronJobHandler struct {
 	instanceHash string
 }
 
+var cronJobGVR = schema.GroupVersionResource{
+	Group:    "batch",
+	Version:  "v1",
+	Resource: "cronjobs",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "CronJob",
+		GVR:               cronJobGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewCronJobHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewCronJobHandler(clientset *kubernetes.Clientset, cfg *config.Config) *CronJobHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "batch",
-		Version:  "v1",
-		Resource: "cronjobs",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("CronJob", "CronJob")
 	return &CronJobHandler{
-		BaseHandler:  NewBaseHandler(gvr, "CronJob", cfg),
+		BaseHandler:  NewBaseHandler(cronJobGVR, "CronJob", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *CronJobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *CronJobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	cronjob, err := ConvertToTyped[*batchv1.CronJob](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert cronjob: %w", err)
 	}
 
+	podSpec := cronjob.Spec.JobTemplate.Spec.Template.Spec
+	registries := podSpecRegistries(podSpec)
+
 	properties := map[string]interface{}{
 		"name":                       cronjob.Name,
 		"uid":                        string(cronjob.UID),
@@ -55,6 +72,8 @@ func (h *CronJobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		"failedJobsHistoryLimit":     cronjob.Spec.FailedJobsHistoryLimit,
 		"suspend":                    cronjob.Spec.Suspend,
 		"clusterName":                h.GetClusterName(),
+		"registries":                 registries,
+		"imagePullSecrets":           pullSecretNames(podSpec),
 		"instanceHash":               h.instanceHash,
 	}
 
@@ -66,50 +85,41 @@ func (h *CronJobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		properties["lastSuccessfulTime"] = cronjob.Status.LastSuccessfulTime.String()
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"CronJob"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert cronjob %s: %w", cronjob.Name, err)
-	}
+	relationships := ownerRelationships("CronJob", string(cronjob.UID), cronjob.OwnerReferences)
 
-	// Create relationships based on owner references for all supported types
-	if cronjob.OwnerReferences != nil {
-		for _, ownerRef := range cronjob.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"CronJob", "uid", string(cronjob.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between CronJob %s and %s %s: %v\n", cronjob.Name, label, ownerRef.Name, err)
+	// Create relationships with jobs created by this cronjob. Objects pushed
+	// from outside the cluster have no clientset to look up jobs with.
+	if h.clientset != nil {
+		// Use a more robust approach to find jobs owned by this cronjob
+		jobs, err := h.clientset.BatchV1().Jobs(cronjob.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list jobs for cronjob %s: %w", cronjob.Name, err)
+		}
+
+		for _, job := range jobs.Items {
+			// Check if this job is owned by this cronjob
+			for _, ownerRef := range job.OwnerReferences {
+				if ownerRef.Kind == "CronJob" && ownerRef.UID == cronjob.UID {
+					relationships = append(relationships, relationship("CronJob", "uid", string(cronjob.UID), "CREATES", "Job", "uid", string(job.UID)))
+					break
 				}
 			}
 		}
 	}
 
-	// Create relationships with jobs created by this cronjob
-	// Use a more robust approach to find jobs owned by this cronjob
-	jobs, err := h.clientset.BatchV1().Jobs(cronjob.Namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to list jobs for cronjob %s: %w", cronjob.Name, err)
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"CronJob"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert cronjob %s: %w", cronjob.Name, err)
 	}
 
-	for _, job := range jobs.Items {
-		// Check if this job is owned by this cronjob
-		for _, ownerRef := range job.OwnerReferences {
-			if ownerRef.Kind == "CronJob" && ownerRef.UID == cronjob.UID {
-				if err := neo4jClient.CreateRelationship(ctx, "CronJob", "uid", string(cronjob.UID), "CREATES", "Job", "uid", string(job.UID)); err != nil {
-					return fmt.Errorf("failed to create relationship between cronjob %s and job %s: %w", cronjob.Name, job.Name, err)
-				}
-				break
-			}
-		}
-	}
+	rollupStorageForWorkload(ctx, neo4jClient, "CronJob", string(cronjob.UID), cronjob.Name)
+
+	// Link the registries the pod template pulls images from
+	linkRegistries(ctx, neo4jClient, "CronJob", string(cronjob.UID), "PULLS_FROM", h.GetClusterName(), h.instanceHash, registries)
 
 	return nil
 }
 
-func (h *CronJobHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *CronJobHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	cronjob, err := ConvertToTyped[*batchv1.CronJob](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert cronjob: %w", err)
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CSIDriverHandler tracks the CSI drivers installed in the cluster and how
+// Kubernetes interacts with them
+type CSIDriverHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var csiDriverGVR = schema.GroupVersionResource{
+	Group:    "storage.k8s.io",
+	Version:  "v1",
+	Resource: "csidrivers",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "CSIDriver",
+		GVR:               csiDriverGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewCSIDriverHandler(cfg)
+		},
+	})
+}
+
+func NewCSIDriverHandler(cfg *config.Config) *CSIDriverHandler {
+	return &CSIDriverHandler{
+		BaseHandler:  NewBaseHandler(csiDriverGVR, "CSIDriver", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *CSIDriverHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	driver, err := ConvertToTyped[*storagev1.CSIDriver](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert csidriver: %w", err)
+	}
+
+	modes := make([]string, 0, len(driver.Spec.VolumeLifecycleModes))
+	for _, mode := range driver.Spec.VolumeLifecycleModes {
+		modes = append(modes, string(mode))
+	}
+	properties := map[string]interface{}{
+		"name":                 driver.Name,
+		"uid":                  string(driver.UID),
+		"creationTimestamp":    driver.CreationTimestamp.String(),
+		"labels":               driver.Labels,
+		"annotations":          driver.Annotations,
+		"attachRequired":       driver.Spec.AttachRequired == nil || *driver.Spec.AttachRequired,
+		"podInfoOnMount":       driver.Spec.PodInfoOnMount != nil && *driver.Spec.PodInfoOnMount,
+		"storageCapacity":      driver.Spec.StorageCapacity != nil && *driver.Spec.StorageCapacity,
+		"requiresRepublish":    driver.Spec.RequiresRepublish != nil && *driver.Spec.RequiresRepublish,
+		"volumeLifecycleModes": modes,
+		"clusterName":          h.GetClusterName(),
+		"instanceHash":         h.instanceHash,
+	}
+	if driver.Spec.FSGroupPolicy != nil {
+		properties["fsGroupPolicy"] = string(*driver.Spec.FSGroupPolicy)
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"CSIDriver"}, properties, "uid", ownerRelationships("CSIDriver", string(driver.UID), driver.OwnerReferences)); err != nil {
+		return fmt.Errorf("failed to upsert csidriver %s: %w", driver.Name, err)
+	}
+	return nil
+}
+
+func (h *CSIDriverHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	driver, err := ConvertToTyped[*storagev1.CSIDriver](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert csidriver: %w", err)
+	}
+	return HandleResourceDelete(ctx, "CSIDriver", string(driver.UID), neo4jClient)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CSINodeHandler tracks the CSI drivers registered on each Node. A CSINode has
+// the name of its Node, and the kubelet makes the Node its owner.
+type CSINodeHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var csiNodeGVR = schema.GroupVersionResource{
+	Group:    "storage.k8s.io",
+	Version:  "v1",
+	Resource: "csinodes",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "CSINode",
+		GVR:               csiNodeGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewCSINodeHandler(cfg)
+		},
+	})
+}
+
+func NewCSINodeHandler(cfg *config.Config) *CSINodeHandler {
+	return &CSINodeHandler{
+		BaseHandler:  NewBaseHandler(csiNodeGVR, "CSINode", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *CSINodeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	csiNode, err := ConvertToTyped[*storagev1.CSINode](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert csinode: %w", err)
+	}
+
+	uid := string(csiNode.UID)
+	drivers := make([]string, 0, len(csiNode.Spec.Drivers))
+	allocatable := make(map[string]interface{})
+	for _, driver := range csiNode.Spec.Drivers {
+		drivers = append(drivers, driver.Name)
+		if driver.Allocatable != nil && driver.Allocatable.Count != nil {
+			allocatable[driver.Name] = *driver.Allocatable.Count
+		}
+	}
+	sort.Strings(drivers)
+
+	properties := map[string]interface{}{
+		"name":              csiNode.Name,
+		"uid":               uid,
+		"creationTimestamp": csiNode.CreationTimestamp.String(),
+		"labels":            csiNode.Labels,
+		"annotations":       csiNode.Annotations,
+		"drivers":           drivers,
+		"allocatable":       allocatable,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	relationships := ownerRelationships("CSINode", uid, csiNode.OwnerReferences)
+	for _, driver := range drivers {
+		relationships = append(relationships, relationship("CSINode", "uid", uid, "USES_DRIVER", "CSIDriver", "name", driver))
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"CSINode"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert csinode %s: %w", csiNode.Name, err)
+	}
+	return nil
+}
+
+func (h *CSINodeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	csiNode, err := ConvertToTyped[*storagev1.CSINode](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert csinode: %w", err)
+	}
+	return HandleResourceDelete(ctx, "CSINode", string(csiNode.UID), neo4jClient)
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,27 +20,43 @@ type DaemonSetHandler struct {
 	instanceHash string
 }
 
+var daemonSetGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "daemonsets",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "DaemonSet",
+		GVR:               daemonSetGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewDaemonSetHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewDaemonSetHandler(clientset *kubernetes.Clientset, cfg *config.Config) *DaemonSetHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "apps",
-		Version:  "v1",
-		Resource: "daemonsets",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("DaemonSet", "DaemonSet")
 	return &DaemonSetHandler{
-		BaseHandler:  NewBaseHandler(gvr, "DaemonSet", cfg),
+		BaseHandler:  NewBaseHandler(daemonSetGVR, "DaemonSet", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *DaemonSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *DaemonSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ds, err := ConvertToTyped[*appsv1.DaemonSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert daemonset: %w", err)
 	}
 
+	podSpec := ds.Spec.Template.Spec
+	registries := podSpecRegistries(podSpec)
+
 	properties := map[string]interface{}{
 		"name":              ds.Name,
 		"uid":               string(ds.UID),
@@ -50,32 +67,18 @@ func (h *DaemonSetHandler) HandleCreate(ctx context.Context, obj interface{}, ne
 		"selector":          ds.Spec.Selector.MatchLabels,
 		"updateStrategy":    string(ds.Spec.UpdateStrategy.Type),
 		"clusterName":       h.GetClusterName(),
+		"registries":        registries,
+		"imagePullSecrets":  pullSecretNames(podSpec),
 		"instanceHash":      h.instanceHash,
 	}
-
-	if err := neo4jClient.UpsertNode(ctx, []string{"DaemonSet"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert daemonset %s: %w", ds.Name, err)
+	for key, value := range templateProperties(podSpec) {
+		properties[key] = value
 	}
 
-	// Create relationships based on owner references for all supported types
-	if ds.OwnerReferences != nil {
-		for _, ownerRef := range ds.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"DaemonSet", "uid", string(ds.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between DaemonSet %s and %s %s: %v\n", ds.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("DaemonSet", string(ds.UID), ds.OwnerReferences)
 
 	// Create relationships with pods
-	if ds.Spec.Selector != nil {
+	if ds.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(ds.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(ds.Spec.Selector),
 		})
@@ -84,16 +87,23 @@ func (h *DaemonSetHandler) HandleCreate(ctx context.Context, obj interface{}, ne
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "DaemonSet", "uid", string(ds.UID), "MANAGES", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between daemonset %s and pod %s: %w", ds.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("DaemonSet", "uid", string(ds.UID), "MANAGES", "Pod", "uid", string(pod.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"DaemonSet"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert daemonset %s: %w", ds.Name, err)
+	}
+
+	rollupStorageForWorkload(ctx, neo4jClient, "DaemonSet", string(ds.UID), ds.Name)
+
+	// Link the registries the pod template pulls images from
+	linkRegistries(ctx, neo4jClient, "DaemonSet", string(ds.UID), "PULLS_FROM", h.GetClusterName(), h.instanceHash, registries)
+
 	return nil
 }
 
-func (h *DaemonSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *DaemonSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ds, err := ConvertToTyped[*appsv1.DaemonSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert daemonset: %w", err)
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,27 +20,43 @@ type DeploymentHandler struct {
 	instanceHash string
 }
 
+var deploymentGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "deployments",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Deployment",
+		GVR:               deploymentGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewDeploymentHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewDeploymentHandler(clientset *kubernetes.Clientset, cfg *config.Config) *DeploymentHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "apps",
-		Version:  "v1",
-		Resource: "deployments",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Deployment", "Deployment")
 	return &DeploymentHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Deployment", cfg),
+		BaseHandler:  NewBaseHandler(deploymentGVR, "Deployment", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *DeploymentHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *DeploymentHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	deployment, err := ConvertToTyped[*appsv1.Deployment](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert deployment: %w", err)
 	}
 
+	podSpec := deployment.Spec.Template.Spec
+	registries := podSpecRegistries(podSpec)
+
 	properties := map[string]interface{}{
 		"name":              deployment.Name,
 		"uid":               string(deployment.UID),
@@ -51,32 +68,18 @@ func (h *DeploymentHandler) HandleCreate(ctx context.Context, obj interface{}, n
 		"strategy":          string(deployment.Spec.Strategy.Type),
 		"selector":          deployment.Spec.Selector.MatchLabels,
 		"clusterName":       h.GetClusterName(),
+		"registries":        registries,
+		"imagePullSecrets":  pullSecretNames(podSpec),
 		"instanceHash":      h.instanceHash,
 	}
-
-	if err := neo4jClient.UpsertNode(ctx, []string{"Deployment"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert deployment %s: %w", deployment.Name, err)
+	for key, value := range templateProperties(podSpec) {
+		properties[key] = value
 	}
 
-	// Create relationships based on owner references for all supported types
-	if deployment.OwnerReferences != nil {
-		for _, ownerRef := range deployment.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Deployment", "uid", string(deployment.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Deployment %s and %s %s: %v\n", deployment.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("Deployment", string(deployment.UID), deployment.OwnerReferences)
 
 	// Create relationships with pods
-	if deployment.Spec.Selector != nil {
+	if deployment.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(deployment.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(deployment.Spec.Selector),
 		})
@@ -85,16 +88,23 @@ func (h *DeploymentHandler) HandleCreate(ctx context.Context, obj interface{}, n
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "Deployment", "uid", string(deployment.UID), "MANAGES", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between deployment %s and pod %s: %w", deployment.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("Deployment", "uid", string(deployment.UID), "MANAGES", "Pod", "uid", string(pod.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Deployment"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert deployment %s: %w", deployment.Name, err)
+	}
+
+	rollupStorageForWorkload(ctx, neo4jClient, "Deployment", string(deployment.UID), deployment.Name)
+
+	// Link the registries the pod template pulls images from
+	linkRegistries(ctx, neo4jClient, "Deployment", string(deployment.UID), "PULLS_FROM", h.GetClusterName(), h.instanceHash, registries)
+
 	return nil
 }
 
-func (h *DeploymentHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *DeploymentHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	deployment, err := ConvertToTyped[*appsv1.Deployment](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert deployment: %w", err)
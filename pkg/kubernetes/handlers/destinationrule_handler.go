@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DestinationRuleHandler tracks Istio DestinationRules, linking them to the
+// Service whose traffic policy they define
+type DestinationRuleHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var destinationRuleGVR = istioResource("networking.istio.io", "destinationrules")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "DestinationRule",
+		GVR:               destinationRuleGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewDestinationRuleHandler(cfg)
+		},
+	})
+}
+
+func NewDestinationRuleHandler(cfg *config.Config) *DestinationRuleHandler {
+	RegisterOwnerKind("DestinationRule", "DestinationRule")
+	return &DestinationRuleHandler{
+		BaseHandler:  NewBaseHandler(destinationRuleGVR, "DestinationRule", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *DestinationRuleHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	rule, err := ConvertToTyped[*destinationRule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert destinationrule: %w", err)
+	}
+
+	var tlsMode, loadBalancer string
+	if policy := rule.Spec.TrafficPolicy; policy != nil {
+		if policy.TLS != nil {
+			tlsMode = policy.TLS.Mode
+		}
+		if policy.LoadBalancer != nil {
+			loadBalancer = policy.LoadBalancer.Simple
+		}
+	}
+	subsets := make([]string, 0, len(rule.Spec.Subsets))
+	for _, subset := range rule.Spec.Subsets {
+		subsets = append(subsets, subset.Name)
+	}
+	// A list so Services synced later can find the rule with linkReferrers
+	services := make([]string, 0, 1)
+	if key, ok := meshServiceKey(rule.Spec.Host, rule.Namespace); ok {
+		services = append(services, key)
+	}
+
+	uid := string(rule.UID)
+	properties := map[string]interface{}{
+		"name":              rule.Name,
+		"uid":               uid,
+		"namespace":         rule.Namespace,
+		"creationTimestamp": rule.CreationTimestamp.String(),
+		"labels":            rule.Labels,
+		"annotations":       rule.Annotations,
+		"host":              rule.Spec.Host,
+		"services":          services,
+		"tlsMode":           tlsMode,
+		"loadBalancer":      loadBalancer,
+		"subsets":           subsets,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"DestinationRule"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert destinationrule %s: %w", rule.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "DestinationRule", uid, "APPLIES_TO", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create APPLIES_TO relationship for DestinationRule %s: %v\n", rule.Name, err)
+	}
+
+	return nil
+}
+
+func (h *DestinationRuleHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	rule, err := ConvertToTyped[*destinationRule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert destinationrule: %w", err)
+	}
+	return HandleResourceDelete(ctx, "DestinationRule", string(rule.UID), neo4jClient)
+}
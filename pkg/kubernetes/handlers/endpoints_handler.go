@@ -4,29 +4,44 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type EndpointsHandler struct {
 	BaseHandler
 }
 
+var endpointsGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "endpoints",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Endpoints",
+		GVR:               endpointsGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewEndpointsHandler(cfg)
+		},
+	})
+}
+
 func NewEndpointsHandler(cfg *config.Config) *EndpointsHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "endpoints",
-	}
 	RegisterOwnerKind("Endpoints", "Endpoints")
 	return &EndpointsHandler{
-		BaseHandler: NewBaseHandler(gvr, "Endpoints", cfg),
+		BaseHandler: NewBaseHandler(endpointsGVR, "Endpoints", cfg),
 	}
 }
 
-func (h *EndpointsHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *EndpointsHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	endpoints, err := ConvertToTyped[*corev1.Endpoints](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert endpoints: %w", err)
@@ -52,25 +67,16 @@ func (h *EndpointsHandler) HandleCreate(ctx context.Context, obj interface{}, ne
 		"clusterName": h.GetClusterName(),
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Endpoints"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert endpoints %s: %w", endpoints.Name, err)
-	}
-
 	// Create relationship to the service with the same name
-	err = neo4jClient.CreateRelationship(
-		ctx,
-		"Endpoints", "name", endpoints.Name,
-		"PROVIDES_ENDPOINTS_FOR",
-		"Service", "name", endpoints.Name,
-	)
-	if err != nil {
-		fmt.Printf("Warning: failed to create PROVIDES_ENDPOINTS_FOR relationship for Endpoints %s: %v\n", endpoints.Name, err)
+	providesEndpoints := relationship("Endpoints", "name", endpoints.Name, "PROVIDES_ENDPOINTS_FOR", "Service", "name", endpoints.Name)
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Endpoints"}, properties, "uid", []graph.Relationship{providesEndpoints}); err != nil {
+		return fmt.Errorf("failed to upsert endpoints %s: %w", endpoints.Name, err)
 	}
 
 	return nil
 }
 
-func (h *EndpointsHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *EndpointsHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	endpoints, err := ConvertToTyped[*corev1.Endpoints](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert endpoints: %w", err)
@@ -6,29 +6,47 @@ import (
 	"time"
 
 	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 	"k8s-graph/pkg/neo4j"
 
-	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type EventHandler struct {
 	BaseHandler
 }
 
+var eventGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "events",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Event",
+		GVR:               eventGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		Enabled: func(cfg *config.Config) bool {
+			return cfg.EventTTLDays > 0
+		},
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewEventHandler(cfg)
+		},
+	})
+}
+
 func NewEventHandler(cfg *config.Config) *EventHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "events",
-	}
 	RegisterOwnerKind("Event", "Event")
 	return &EventHandler{
-		BaseHandler: NewBaseHandler(gvr, "Event", cfg),
+		BaseHandler: NewBaseHandler(eventGVR, "Event", cfg),
 	}
 }
 
-func (h *EventHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *EventHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	event, err := ConvertToTyped[*corev1.Event](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert event: %w", err)
@@ -55,29 +73,23 @@ func (h *EventHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jC
 		// Events should not have instanceHash as they should persist across restarts
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Event"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert event %s: %w", event.Name, err)
-	}
+	var relationships []graph.Relationship
 
 	// Relationship to involved object
 	if event.InvolvedObject.UID != "" && event.InvolvedObject.Kind != "" {
 		if label, ok := ownerKindToLabel[event.InvolvedObject.Kind]; ok {
-			err := neo4jClient.CreateRelationship(
-				ctx,
-				"Event", "uid", string(event.UID),
-				"INVOLVES",
-				label, "uid", string(event.InvolvedObject.UID),
-			)
-			if err != nil {
-				fmt.Printf("Warning: failed to create INVOLVES relationship for Event %s: %v\n", event.Name, err)
-			}
+			relationships = append(relationships, relationship("Event", "uid", string(event.UID), "INVOLVES", label, "uid", string(event.InvolvedObject.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Event"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert event %s: %w", event.Name, err)
+	}
+
 	return nil
 }
 
-func (h *EventHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *EventHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	// Do not delete events from Neo4j when Kubernetes deletes them
 	// Events should persist in Neo4j and only be cleaned up via TTL mechanism
 	// using PruneExpiredEvents function
@@ -85,15 +97,24 @@ func (h *EventHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jC
 }
 
 // PruneExpiredEvents deletes Event nodes older than the given TTL (in days)
-func PruneExpiredEvents(ctx context.Context, neo4jClient *neo4j.Client, ttlDays int) error {
+func PruneExpiredEvents(ctx context.Context, neo4jClient graph.Store, ttlDays int) error {
 	if ttlDays <= 0 {
 		return nil
 	}
 	cutoff := time.Now().UTC().Add(-time.Duration(ttlDays) * 24 * time.Hour).Format(time.RFC3339)
-	query := `MATCH (e:Event) WHERE e.createdAt < $cutoff DETACH DELETE e`
-	params := map[string]interface{}{"cutoff": cutoff}
-	session := neo4jClient.Driver().NewSession(ctx, driverneo4j.SessionConfig{AccessMode: driverneo4j.AccessModeWrite})
-	defer session.Close(ctx)
-	_, err := session.Run(ctx, query, params)
-	return err
-}
\ No newline at end of file
+	query := `MATCH (e:Event) WHERE e.createdAt < $cutoff DETACH DELETE e RETURN count(e) AS pruned`
+	rows, err := neo4jClient.Query(ctx, query, map[string]interface{}{"cutoff": cutoff})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		// A dry run skips the statement and returns no record, so nothing was pruned
+		return nil
+	}
+	if len(rows) != 1 {
+		return fmt.Errorf("expected a single record, got %d", len(rows))
+	}
+	count, _ := rows[0]["pruned"].(int64)
+	neo4j.RecordPruned("Event", "ttl", count)
+	return nil
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+)
+
+func TestPruneExpiredEventsWithoutRecord(t *testing.T) {
+	store := graphtest.NewStore()
+	if err := PruneExpiredEvents(context.Background(), store, 7); err != nil {
+		t.Errorf("Expected a skipped prune to delete nothing, got %v", err)
+	}
+	if len(store.StatementsMatching("DETACH DELETE e")) != 1 {
+		t.Error("Expected the prune statement to run")
+	}
+}
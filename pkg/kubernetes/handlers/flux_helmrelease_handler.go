@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FluxHelmReleaseHandler tracks Flux HelmReleases, linking them to the
+// resources of their Helm release
+type FluxHelmReleaseHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var helmReleaseGVR = schema.GroupVersionResource{
+	Group:    "helm.toolkit.fluxcd.io",
+	Version:  "v2",
+	Resource: "helmreleases",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "HelmRelease",
+		GVR:               helmReleaseGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewFluxHelmReleaseHandler(cfg)
+		},
+	})
+}
+
+func NewFluxHelmReleaseHandler(cfg *config.Config) *FluxHelmReleaseHandler {
+	RegisterOwnerKind("HelmRelease", "HelmRelease")
+	return &FluxHelmReleaseHandler{
+		BaseHandler:  NewBaseHandler(helmReleaseGVR, "HelmRelease", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *FluxHelmReleaseHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	release, err := ConvertToTyped[*fluxHelmRelease](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert helmrelease: %w", err)
+	}
+
+	// The chart comes either from a template or from a reference to a chart object
+	var chart, chartVersion, source string
+	if release.Spec.Chart != nil {
+		chart = release.Spec.Chart.Spec.Chart
+		chartVersion = release.Spec.Chart.Spec.Version
+		source = fluxSourceString(release.Spec.Chart.Spec.SourceRef, release.Namespace)
+	} else if release.Spec.ChartRef != nil {
+		source = fluxSourceString(*release.Spec.ChartRef, release.Namespace)
+	}
+
+	uid := string(release.UID)
+	remote := release.Spec.KubeConfig != nil
+	properties := map[string]interface{}{
+		"name":                  release.Name,
+		"uid":                   uid,
+		"namespace":             release.Namespace,
+		"creationTimestamp":     release.CreationTimestamp.String(),
+		"labels":                release.Labels,
+		"annotations":           release.Annotations,
+		"chart":                 chart,
+		"chartVersion":          chartVersion,
+		"source":                source,
+		"releaseName":           helmReleaseName(release),
+		"interval":              release.Spec.Interval,
+		"suspend":               release.Spec.Suspend,
+		"targetNamespace":       release.Spec.TargetNamespace,
+		"ready":                 meta.IsStatusConditionTrue(release.Status.Conditions, "Ready"),
+		"lastAttemptedRevision": release.Status.LastAttemptedRevision,
+		"remoteCluster":         remote,
+		"clusterName":           h.GetClusterName(),
+		"instanceHash":          h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"HelmRelease"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert helmrelease %s: %w", release.Name, err)
+	}
+
+	// Releases installed through a kubeconfig go to another cluster
+	if !remote {
+		if err := linkDeployedResources(ctx, neo4jClient, "HelmRelease", uid, release.Name, release.Namespace, h.GetClusterName()); err != nil {
+			fmt.Printf("Warning: failed to create DEPLOYS relationships for HelmRelease %s: %v\n", release.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *FluxHelmReleaseHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	release, err := ConvertToTyped[*fluxHelmRelease](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert helmrelease: %w", err)
+	}
+	return HandleResourceDelete(ctx, "HelmRelease", string(release.UID), neo4jClient)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// FluxKustomizationHandler tracks Flux Kustomizations, linking them to the
+// resources they apply
+type FluxKustomizationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var kustomizationGVR = schema.GroupVersionResource{
+	Group:    "kustomize.toolkit.fluxcd.io",
+	Version:  "v1",
+	Resource: "kustomizations",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Kustomization",
+		GVR:               kustomizationGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewFluxKustomizationHandler(cfg)
+		},
+	})
+}
+
+func NewFluxKustomizationHandler(cfg *config.Config) *FluxKustomizationHandler {
+	RegisterOwnerKind("Kustomization", "Kustomization")
+	return &FluxKustomizationHandler{
+		BaseHandler:  NewBaseHandler(kustomizationGVR, "Kustomization", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *FluxKustomizationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	kustomization, err := ConvertToTyped[*fluxKustomization](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert kustomization: %w", err)
+	}
+
+	uid := string(kustomization.UID)
+	remote := kustomization.Spec.KubeConfig != nil
+	properties := map[string]interface{}{
+		"name":                kustomization.Name,
+		"uid":                 uid,
+		"namespace":           kustomization.Namespace,
+		"creationTimestamp":   kustomization.CreationTimestamp.String(),
+		"labels":              kustomization.Labels,
+		"annotations":         kustomization.Annotations,
+		"source":              fluxSourceString(kustomization.Spec.SourceRef, kustomization.Namespace),
+		"path":                kustomization.Spec.Path,
+		"interval":            kustomization.Spec.Interval,
+		"prune":               kustomization.Spec.Prune,
+		"suspend":             kustomization.Spec.Suspend,
+		"targetNamespace":     kustomization.Spec.TargetNamespace,
+		"ready":               meta.IsStatusConditionTrue(kustomization.Status.Conditions, "Ready"),
+		"lastAppliedRevision": kustomization.Status.LastAppliedRevision,
+		"remoteCluster":       remote,
+		"clusterName":         h.GetClusterName(),
+		"instanceHash":        h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Kustomization"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert kustomization %s: %w", kustomization.Name, err)
+	}
+
+	// Resources applied through a kubeconfig go to another cluster
+	if !remote {
+		if err := linkDeployedResources(ctx, neo4jClient, "Kustomization", uid, kustomization.Name, kustomization.Namespace, h.GetClusterName()); err != nil {
+			fmt.Printf("Warning: failed to create DEPLOYS relationships for Kustomization %s: %v\n", kustomization.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *FluxKustomizationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	kustomization, err := ConvertToTyped[*fluxKustomization](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert kustomization: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Kustomization", string(kustomization.UID), neo4jClient)
+}
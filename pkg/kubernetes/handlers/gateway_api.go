@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayAPIGroup is the API group of the Gateway API resources
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// gatewayAPIResource returns the GVR of a Gateway API resource
+func gatewayAPIResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: gatewayAPIGroup, Version: "v1", Resource: resource}
+}
+
+// The Gateway API types are not part of client-go, so the handlers decode the
+// fields they need into the structs below
+
+type gatewayClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		ControllerName string `json:"controllerName"`
+		Description    string `json:"description,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+type gatewayListener struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname,omitempty"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type gateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		GatewayClassName string            `json:"gatewayClassName"`
+		Listeners        []gatewayListener `json:"listeners,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Value string `json:"value"`
+		} `json:"addresses,omitempty"`
+		Conditions []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// gatewayReference is a parentRef or backendRef of a route
+type gatewayReference struct {
+	Group       string `json:"group,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+	Port        int32  `json:"port,omitempty"`
+}
+
+type httpRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		ParentRefs []gatewayReference `json:"parentRefs,omitempty"`
+		Hostnames  []string           `json:"hostnames,omitempty"`
+		Rules      []struct {
+			BackendRefs []gatewayReference `json:"backendRefs,omitempty"`
+		} `json:"rules,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  gatewayReference   `json:"parentRef"`
+			Conditions []metav1.Condition `json:"conditions,omitempty"`
+		} `json:"parents,omitempty"`
+	} `json:"status"`
+}
+
+type referenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		From []struct {
+			Group     string `json:"group"`
+			Kind      string `json:"kind"`
+			Namespace string `json:"namespace"`
+		} `json:"from"`
+		To []struct {
+			Group string `json:"group"`
+			Kind  string `json:"kind"`
+			Name  string `json:"name,omitempty"`
+		} `json:"to"`
+	} `json:"spec"`
+}
+
+// parentGateways returns the namespace/name keys of the Gateways a route
+// attaches to. References without a namespace are in the route's namespace.
+func parentGateways(route *httpRoute) []string {
+	seen := make(map[string]bool)
+	for _, ref := range route.Spec.ParentRefs {
+		if (ref.Group != "" && ref.Group != gatewayAPIGroup) || (ref.Kind != "" && ref.Kind != "Gateway") {
+			continue
+		}
+		seen[namespacedKey(defaultNamespace(ref.Namespace, route.Namespace), ref.Name)] = true
+	}
+	return sortedKeys(seen)
+}
+
+// backendServices returns the namespace/name keys of the Services a route
+// forwards to
+func backendServices(route *httpRoute) []string {
+	seen := make(map[string]bool)
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			if ref.Group != "" || (ref.Kind != "" && ref.Kind != "Service") {
+				continue
+			}
+			seen[namespacedKey(defaultNamespace(ref.Namespace, route.Namespace), ref.Name)] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// routeConditionTrue reports whether every parent that reported status on the
+// route has the condition set to true
+func routeConditionTrue(route *httpRoute, conditionType string) bool {
+	if len(route.Status.Parents) == 0 {
+		return false
+	}
+	for _, parent := range route.Status.Parents {
+		if !meta.IsStatusConditionTrue(parent.Conditions, conditionType) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatListener describes a listener as "name=...;protocol=...;port=...;hostname=..."
+func formatListener(listener gatewayListener) string {
+	parts := []string{
+		"name=" + listener.Name,
+		"protocol=" + listener.Protocol,
+		fmt.Sprintf("port=%d", listener.Port),
+	}
+	if listener.Hostname != "" {
+		parts = append(parts, "hostname="+listener.Hostname)
+	}
+	return strings.Join(parts, ";")
+}
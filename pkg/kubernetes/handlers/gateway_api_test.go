@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testHTTPRoute(t *testing.T) *httpRoute {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata": map[string]interface{}{
+			"name":      "store",
+			"namespace": "shop",
+			"uid":       "route-1",
+		},
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{
+				map[string]interface{}{"name": "public"},
+				map[string]interface{}{"name": "internal", "namespace": "infra", "sectionName": "https"},
+				map[string]interface{}{"name": "internal", "namespace": "infra", "sectionName": "http"},
+				map[string]interface{}{"name": "mesh", "group": "", "kind": "Service"},
+			},
+			"hostnames": []interface{}{"store.example.com"},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": "store-v1", "port": int64(8080)},
+						map[string]interface{}{"name": "store-v2", "port": int64(8080), "weight": int64(10)},
+					},
+				},
+				map[string]interface{}{
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": "search", "namespace": "catalog", "port": int64(80)},
+						map[string]interface{}{"name": "bucket", "group": "storage.example.com", "kind": "Bucket"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"parents": []interface{}{
+				map[string]interface{}{
+					"parentRef":      map[string]interface{}{"name": "public"},
+					"controllerName": "example.com/gateway",
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Accepted", "status": "True", "reason": "Accepted", "message": "", "lastTransitionTime": "2024-05-01T12:00:00Z"},
+						map[string]interface{}{"type": "ResolvedRefs", "status": "False", "reason": "RefNotPermitted", "message": "", "lastTransitionTime": "2024-05-01T12:00:00Z"},
+					},
+				},
+			},
+		},
+	}}
+
+	route, err := ConvertToTyped[*httpRoute](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert HTTPRoute: %v", err)
+	}
+	return route
+}
+
+func TestParentGateways(t *testing.T) {
+	expected := []string{"infra/internal", "shop/public"}
+	if got := parentGateways(testHTTPRoute(t)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestBackendServices(t *testing.T) {
+	expected := []string{"catalog/search", "shop/store-v1", "shop/store-v2"}
+	if got := backendServices(testHTTPRoute(t)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRouteConditionTrue(t *testing.T) {
+	route := testHTTPRoute(t)
+	if !routeConditionTrue(route, "Accepted") {
+		t.Error("Expected the route to be accepted")
+	}
+	if routeConditionTrue(route, "ResolvedRefs") {
+		t.Error("Expected the route refs not to be resolved")
+	}
+
+	route.Status.Parents = nil
+	if routeConditionTrue(route, "Accepted") {
+		t.Error("Expected a route without parent status not to be accepted")
+	}
+}
+
+func TestFormatListener(t *testing.T) {
+	tests := []struct {
+		listener gatewayListener
+		expected string
+	}{
+		{gatewayListener{Name: "http", Protocol: "HTTP", Port: 80}, "name=http;protocol=HTTP;port=80"},
+		{gatewayListener{Name: "https", Protocol: "HTTPS", Port: 443, Hostname: "*.example.com"}, "name=https;protocol=HTTPS;port=443;hostname=*.example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := formatListener(tt.listener); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
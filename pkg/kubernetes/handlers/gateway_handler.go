@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GatewayHandler tracks Gateway API Gateways, the load balancers that
+// HTTPRoutes attach to
+type GatewayHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var gatewayGVR = gatewayAPIResource("gateways")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Gateway",
+		GVR:               gatewayGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewGatewayHandler(cfg)
+		},
+	})
+}
+
+func NewGatewayHandler(cfg *config.Config) *GatewayHandler {
+	RegisterOwnerKind("Gateway", "Gateway")
+	return &GatewayHandler{
+		BaseHandler:  NewBaseHandler(gatewayGVR, "Gateway", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *GatewayHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	gw, err := ConvertToTyped[*gateway](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert gateway: %w", err)
+	}
+
+	listeners := make([]string, 0, len(gw.Spec.Listeners))
+	for _, listener := range gw.Spec.Listeners {
+		listeners = append(listeners, formatListener(listener))
+	}
+	addresses := make([]string, 0, len(gw.Status.Addresses))
+	for _, address := range gw.Status.Addresses {
+		addresses = append(addresses, address.Value)
+	}
+
+	uid := string(gw.UID)
+	properties := map[string]interface{}{
+		"name":              gw.Name,
+		"uid":               uid,
+		"namespace":         gw.Namespace,
+		"creationTimestamp": gw.CreationTimestamp.String(),
+		"labels":            gw.Labels,
+		"annotations":       gw.Annotations,
+		"gatewayClassName":  gw.Spec.GatewayClassName,
+		"listeners":         listeners,
+		"addresses":         addresses,
+		"programmed":        meta.IsStatusConditionTrue(gw.Status.Conditions, "Programmed"),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	relationships := ownerRelationships("Gateway", uid, gw.OwnerReferences)
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Gateway"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert gateway %s: %w", gw.Name, err)
+	}
+
+	err = neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (g:Gateway {uid: $uid})
+			MATCH (c:GatewayClass {name: $className, clusterName: $clusterName})` +
+			graph.MergeRelationship("g", "uid", "USES", "c", "uid"),
+		Params: map[string]interface{}{"uid": uid, "className": gw.Spec.GatewayClassName, "clusterName": h.GetClusterName()},
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to create USES relationship between Gateway %s and GatewayClass %s: %v\n", gw.Name, gw.Spec.GatewayClassName, err)
+	}
+
+	if err := linkReferrers(ctx, neo4jClient, "HTTPRoute", "Gateway", uid, gw.Name, gw.Namespace, h.GetClusterName(), "ATTACHED_TO", "parentGateways"); err != nil {
+		fmt.Printf("Warning: failed to link HTTPRoutes to Gateway %s: %v\n", gw.Name, err)
+	}
+
+	return nil
+}
+
+func (h *GatewayHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	gw, err := ConvertToTyped[*gateway](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert gateway: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Gateway", string(gw.UID), neo4jClient)
+}
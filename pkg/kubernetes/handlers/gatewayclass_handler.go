@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GatewayClassHandler tracks Gateway API GatewayClasses, which name the
+// controller implementing the Gateways of the class
+type GatewayClassHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var gatewayClassGVR = gatewayAPIResource("gatewayclasses")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "GatewayClass",
+		GVR:               gatewayClassGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewGatewayClassHandler(cfg)
+		},
+	})
+}
+
+func NewGatewayClassHandler(cfg *config.Config) *GatewayClassHandler {
+	RegisterOwnerKind("GatewayClass", "GatewayClass")
+	return &GatewayClassHandler{
+		BaseHandler:  NewBaseHandler(gatewayClassGVR, "GatewayClass", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *GatewayClassHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*gatewayClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert gatewayclass: %w", err)
+	}
+
+	properties := map[string]interface{}{
+		"name":              class.Name,
+		"uid":               string(class.UID),
+		"creationTimestamp": class.CreationTimestamp.String(),
+		"labels":            class.Labels,
+		"annotations":       class.Annotations,
+		"controllerName":    class.Spec.ControllerName,
+		"description":       class.Spec.Description,
+		"accepted":          meta.IsStatusConditionTrue(class.Status.Conditions, "Accepted"),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"GatewayClass"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert gatewayclass %s: %w", class.Name, err)
+	}
+
+	// Gateways synced before their class
+	err = neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (c:GatewayClass {uid: $uid})
+			MATCH (g:Gateway {gatewayClassName: $name, clusterName: $clusterName})` +
+			graph.MergeRelationship("g", "uid", "USES", "c", "uid"),
+		Params: map[string]interface{}{"uid": string(class.UID), "name": class.Name, "clusterName": h.GetClusterName()},
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to link Gateways to GatewayClass %s: %v\n", class.Name, err)
+	}
+
+	return nil
+}
+
+func (h *GatewayClassHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*gatewayClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert gatewayclass: %w", err)
+	}
+	return HandleResourceDelete(ctx, "GatewayClass", string(class.UID), neo4jClient)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels and annotations GitOps tools put on the resources they apply
+const (
+	argoCDInstanceLabel             = "app.kubernetes.io/instance"
+	argoCDTrackingAnnotation        = "argocd.argoproj.io/tracking-id"
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmReleaseNameLabel        = "helm.toolkit.fluxcd.io/name"
+	fluxHelmReleaseNamespaceLabel   = "helm.toolkit.fluxcd.io/namespace"
+
+	// argoCDInClusterServer is the destination of Applications deploying to
+	// the cluster Argo CD runs in
+	argoCDInClusterServer = "https://kubernetes.default.svc"
+)
+
+// gitOpsLabels are the node labels of the GitOps applications
+var gitOpsLabels = []string{"Application", "Kustomization", "HelmRelease"}
+
+// The Argo CD and Flux types are not part of client-go, so the handlers decode
+// the fields they need into the structs below
+
+type argoCDSource struct {
+	RepoURL        string `json:"repoURL"`
+	Path           string `json:"path,omitempty"`
+	Chart          string `json:"chart,omitempty"`
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+type argoCDApplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Project     string         `json:"project"`
+		Source      *argoCDSource  `json:"source,omitempty"`
+		Sources     []argoCDSource `json:"sources,omitempty"`
+		Destination struct {
+			Server    string `json:"server,omitempty"`
+			Name      string `json:"name,omitempty"`
+			Namespace string `json:"namespace,omitempty"`
+		} `json:"destination"`
+	} `json:"spec"`
+	Status struct {
+		Sync struct {
+			Status   string `json:"status,omitempty"`
+			Revision string `json:"revision,omitempty"`
+		} `json:"sync"`
+		Health struct {
+			Status string `json:"status,omitempty"`
+		} `json:"health"`
+	} `json:"status"`
+}
+
+// fluxReference is a reference to a Flux source or chart
+type fluxReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type fluxKustomization struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		SourceRef       fluxReference `json:"sourceRef"`
+		Path            string        `json:"path,omitempty"`
+		Interval        string        `json:"interval,omitempty"`
+		Prune           bool          `json:"prune,omitempty"`
+		Suspend         bool          `json:"suspend,omitempty"`
+		TargetNamespace string        `json:"targetNamespace,omitempty"`
+		KubeConfig      *struct{}     `json:"kubeConfig,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LastAppliedRevision string             `json:"lastAppliedRevision,omitempty"`
+		Conditions          []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+type fluxHelmRelease struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Chart *struct {
+			Spec struct {
+				Chart     string        `json:"chart"`
+				Version   string        `json:"version,omitempty"`
+				SourceRef fluxReference `json:"sourceRef"`
+			} `json:"spec"`
+		} `json:"chart,omitempty"`
+		ChartRef        *fluxReference `json:"chartRef,omitempty"`
+		ReleaseName     string         `json:"releaseName,omitempty"`
+		Interval        string         `json:"interval,omitempty"`
+		Suspend         bool           `json:"suspend,omitempty"`
+		TargetNamespace string         `json:"targetNamespace,omitempty"`
+		KubeConfig      *struct{}      `json:"kubeConfig,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LastAttemptedRevision string             `json:"lastAttemptedRevision,omitempty"`
+		Conditions            []metav1.Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// argoCDSources describes the sources of an Application as
+// "repoURL=...;path=...;chart=...;targetRevision=..."
+func argoCDSources(app *argoCDApplication) []string {
+	sources := app.Spec.Sources
+	if app.Spec.Source != nil {
+		sources = append([]argoCDSource{*app.Spec.Source}, sources...)
+	}
+	result := make([]string, 0, len(sources))
+	for _, source := range sources {
+		parts := []string{"repoURL=" + source.RepoURL}
+		if source.Path != "" {
+			parts = append(parts, "path="+source.Path)
+		}
+		if source.Chart != "" {
+			parts = append(parts, "chart="+source.Chart)
+		}
+		if source.TargetRevision != "" {
+			parts = append(parts, "targetRevision="+source.TargetRevision)
+		}
+		result = append(result, strings.Join(parts, ";"))
+	}
+	return result
+}
+
+// argoCDInCluster reports whether an Application deploys to the cluster Argo CD runs in
+func argoCDInCluster(app *argoCDApplication) bool {
+	destination := app.Spec.Destination
+	return destination.Server == argoCDInClusterServer || destination.Name == "in-cluster"
+}
+
+// helmReleaseName returns the Helm release name of a HelmRelease, which
+// defaults to the name prefixed with the target namespace
+func helmReleaseName(release *fluxHelmRelease) string {
+	if release.Spec.ReleaseName != "" {
+		return release.Spec.ReleaseName
+	}
+	if release.Spec.TargetNamespace != "" {
+		return release.Spec.TargetNamespace + "-" + release.Name
+	}
+	return release.Name
+}
+
+// fluxSourceString describes a Flux source or chart reference as
+// "Kind/namespace/name", the namespace defaulting to the one of the referrer
+func fluxSourceString(ref fluxReference, namespace string) string {
+	if ref.Name == "" {
+		return ""
+	}
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	}
+	return ref.Kind + "/" + namespace + "/" + ref.Name
+}
+
+// metadataEntry returns a label or annotation as it appears in the JSON
+// stored in the labels and annotations properties, e.g. `"key":"value"`
+func metadataEntry(key, value string) string {
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, _ := json.Marshal(value)
+	return string(encodedKey) + ":" + string(encodedValue)
+}
+
+// metadataPrefix returns the start of a label or annotation whose value
+// starts with prefix, as it appears in the stored JSON
+func metadataPrefix(key, prefix string) string {
+	return strings.TrimSuffix(metadataEntry(key, prefix), `"`)
+}
+
+// trackingClauses returns how the resources applied by a GitOps application
+// are recognized: a resource is tracked when its labels or annotations
+// contain every entry of one of the clauses.
+func trackingClauses(kind, name, namespace string) [][]string {
+	switch kind {
+	case "Application":
+		// Applications outside of the Argo CD namespace are tracked as namespace_name
+		qualified := namespace + "_" + name
+		return [][]string{
+			{metadataEntry(argoCDInstanceLabel, name)},
+			{metadataEntry(argoCDInstanceLabel, qualified)},
+			{metadataPrefix(argoCDTrackingAnnotation, name+":")},
+			{metadataPrefix(argoCDTrackingAnnotation, qualified+":")},
+		}
+	case "Kustomization":
+		return [][]string{{
+			metadataEntry(fluxKustomizationNameLabel, name),
+			metadataEntry(fluxKustomizationNamespaceLabel, namespace),
+		}}
+	case "HelmRelease":
+		return [][]string{{
+			metadataEntry(fluxHelmReleaseNameLabel, name),
+			metadataEntry(fluxHelmReleaseNamespaceLabel, namespace),
+		}}
+	}
+	return [][]string{}
+}
+
+// linkDeployedResources replaces the DEPLOYS relationships of a GitOps
+// application with relationships to the resources carrying its tracking label
+// or annotation. Resources owned by a controller, like the Pods of a
+// Deployment, inherit labels from their templates but are not applied by the
+// application, so they are left out.
+func linkDeployedResources(ctx context.Context, neo4jClient graph.Store, label, uid, name, namespace, clusterName string) error {
+	params := map[string]interface{}{
+		"uid":         uid,
+		"clusterName": clusterName,
+		"clauses":     trackingClauses(label, name, namespace),
+	}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (app:%s {uid: $uid})-[old:DEPLOYS]->(n)`, label) +
+			graph.DeleteRelationship("old", "app", "uid", "n", "uid"),
+		Params: params,
+	}, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (app:%s {uid: $uid})
+			MATCH (n {clusterName: $clusterName})
+			WHERE n.instanceHash IS NOT NULL AND n <> app AND NOT (n)-[:OWNED_BY]->()
+			WITH app, n, coalesce(n.labels, '') + coalesce(n.annotations, '') AS metadata
+			WHERE any(clause IN $clauses WHERE all(entry IN clause WHERE metadata CONTAINS entry))`, label) +
+			graph.MergeRelationship("app", "uid", "DEPLOYS", "n", "uid"),
+		Params: params,
+	})
+}
+
+// ResolveGitOpsApplications links every GitOps application of a cluster to
+// the resources it deploys. The handlers link an application when it changes;
+// running this periodically also picks up resources created since.
+func ResolveGitOpsApplications(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (app {clusterName: $clusterName})
+		WHERE any(label IN labels(app) WHERE label IN $labels)
+		  AND coalesce(app.remoteCluster, 'false') = 'false'
+		RETURN labels(app)[0] AS label, app.uid AS uid, app.name AS name, app.namespace AS namespace`,
+		map[string]interface{}{"clusterName": clusterName, "labels": gitOpsLabels})
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, record := range records {
+		values := make(map[string]string)
+		for _, key := range []string{"label", "uid", "name", "namespace"} {
+			if value := record[key]; value != nil {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		if err := linkDeployedResources(ctx, neo4jClient, values["label"], values["uid"], values["name"], values["namespace"], clusterName); err != nil {
+			failed = append(failed, values["label"]+" "+values["namespace"]+"/"+values["name"])
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to link GitOps applications %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
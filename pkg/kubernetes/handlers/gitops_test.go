@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestArgoCDApplication(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": "payments", "namespace": "argocd", "uid": "app-1"},
+		"spec": map[string]interface{}{
+			"project": "default",
+			"sources": []interface{}{
+				map[string]interface{}{"repoURL": "https://charts.example.com", "chart": "api", "targetRevision": "1.2.0"},
+				map[string]interface{}{"repoURL": "https://git.example.com/payments.git", "path": "deploy"},
+			},
+			"destination": map[string]interface{}{"server": "https://kubernetes.default.svc", "namespace": "payments"},
+		},
+		"status": map[string]interface{}{
+			"sync":   map[string]interface{}{"status": "Synced", "revision": "abc123"},
+			"health": map[string]interface{}{"status": "Healthy"},
+		},
+	}}
+
+	app, err := ConvertToTyped[*argoCDApplication](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert application: %v", err)
+	}
+	expected := []string{
+		"repoURL=https://charts.example.com;chart=api;targetRevision=1.2.0",
+		"repoURL=https://git.example.com/payments.git;path=deploy",
+	}
+	if sources := argoCDSources(app); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Expected sources %v, got %v", expected, sources)
+	}
+	if !argoCDInCluster(app) {
+		t.Error("Expected the application to deploy in-cluster")
+	}
+	if app.Status.Sync.Status != "Synced" || app.Status.Health.Status != "Healthy" {
+		t.Errorf("Unexpected status %+v", app.Status)
+	}
+
+	app.Spec.Destination.Server = "https://prod.example.com"
+	if argoCDInCluster(app) {
+		t.Error("Expected the application to deploy to a remote cluster")
+	}
+}
+
+func TestFluxRemoteCluster(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   map[string]interface{}{"name": "apps", "namespace": "flux-system", "uid": "ks-1"},
+		"spec": map[string]interface{}{
+			"sourceRef":  map[string]interface{}{"kind": "GitRepository", "name": "fleet"},
+			"kubeConfig": map[string]interface{}{"secretRef": map[string]interface{}{"name": "prod-kubeconfig"}},
+		},
+	}}
+
+	kustomization, err := ConvertToTyped[*fluxKustomization](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert kustomization: %v", err)
+	}
+	if kustomization.Spec.KubeConfig == nil {
+		t.Error("Expected the kustomization to apply to a remote cluster")
+	}
+	if source := fluxSourceString(kustomization.Spec.SourceRef, kustomization.Namespace); source != "GitRepository/flux-system/fleet" {
+		t.Errorf("Expected the source to default to the kustomization namespace, got %s", source)
+	}
+}
+
+func TestHelmReleaseName(t *testing.T) {
+	release := &fluxHelmRelease{}
+	release.Name = "api"
+	if name := helmReleaseName(release); name != "api" {
+		t.Errorf("Expected api, got %s", name)
+	}
+	release.Spec.TargetNamespace = "payments"
+	if name := helmReleaseName(release); name != "payments-api" {
+		t.Errorf("Expected payments-api, got %s", name)
+	}
+	release.Spec.ReleaseName = "payments"
+	if name := helmReleaseName(release); name != "payments" {
+		t.Errorf("Expected payments, got %s", name)
+	}
+}
+
+// matchesClauses evaluates the tracking clauses like linkDeployedResources does
+func matchesClauses(clauses [][]string, labels, annotations map[string]string) bool {
+	encodedLabels, _ := json.Marshal(labels)
+	encodedAnnotations, _ := json.Marshal(annotations)
+	metadata := string(encodedLabels) + string(encodedAnnotations)
+	for _, clause := range clauses {
+		matched := true
+		for _, entry := range clause {
+			matched = matched && strings.Contains(metadata, entry)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTrackingClauses(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    bool
+	}{
+		{"argo instance label", "Application", map[string]string{"app.kubernetes.io/instance": "api"}, nil, true},
+		{"argo other instance", "Application", map[string]string{"app.kubernetes.io/instance": "api-v2"}, nil, false},
+		{"argo qualified instance", "Application", map[string]string{"app.kubernetes.io/instance": "argocd_api"}, nil, true},
+		{"argo tracking annotation", "Application", nil, map[string]string{"argocd.argoproj.io/tracking-id": "api:apps/Deployment:payments/api"}, true},
+		{"argo other tracking annotation", "Application", nil, map[string]string{"argocd.argoproj.io/tracking-id": "api-v2:apps/Deployment:payments/api"}, false},
+		{"flux kustomization", "Kustomization", map[string]string{
+			"kustomize.toolkit.fluxcd.io/name":      "api",
+			"kustomize.toolkit.fluxcd.io/namespace": "argocd",
+		}, nil, true},
+		{"flux kustomization other namespace", "Kustomization", map[string]string{
+			"kustomize.toolkit.fluxcd.io/name":      "api",
+			"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+		}, nil, false},
+		{"flux helmrelease", "HelmRelease", map[string]string{
+			"helm.toolkit.fluxcd.io/name":      "api",
+			"helm.toolkit.fluxcd.io/namespace": "argocd",
+		}, nil, true},
+		{"unknown kind", "Deployment", map[string]string{"app.kubernetes.io/instance": "api"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clauses := trackingClauses(tt.kind, "api", "argocd")
+			if matched := matchesClauses(clauses, tt.labels, tt.annotations); matched != tt.expected {
+				t.Errorf("Expected %v, got %v for clauses %v", tt.expected, matched, clauses)
+			}
+		})
+	}
+}
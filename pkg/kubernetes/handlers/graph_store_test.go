@@ -0,0 +1,643 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/graph/graphtest"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s-graph/pkg/neo4j"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = "test-cluster"
+	cfg.InstanceHash = "test-hash"
+	return cfg
+}
+
+// expectNode returns the node written with the label and uid, failing the
+// test if there is none or a property differs from the expected ones
+func expectNode(t *testing.T, store *graphtest.Store, label, uid string, expected map[string]interface{}) graphtest.Node {
+	t.Helper()
+	node, ok := store.Node(label, uid)
+	if !ok {
+		t.Fatalf("Expected a %s node with uid %s, got %+v", label, uid, store.Nodes)
+	}
+	for key, value := range expected {
+		if !reflect.DeepEqual(node.Properties[key], value) {
+			t.Errorf("Expected %s property %s to be %#v, got %#v", label, key, value, node.Properties[key])
+		}
+	}
+	return node
+}
+
+// expectRelationships fails the test unless exactly the expected
+// relationships were written
+func expectRelationships(t *testing.T, store *graphtest.Store, expected ...graph.Relationship) {
+	t.Helper()
+	for _, r := range expected {
+		if !store.HasRelationship(r) {
+			t.Errorf("Expected relationship %+v", r)
+		}
+	}
+	if len(store.Relationships) != len(expected) {
+		t.Errorf("Expected %d relationships, got %+v", len(expected), store.Relationships)
+	}
+}
+
+// registerOwnerKinds registers the kinds the handler under test links to by
+// owner reference, which their own handlers register when they are created
+func registerOwnerKinds(kinds ...string) {
+	for _, kind := range kinds {
+		RegisterOwnerKind(kind, kind)
+	}
+}
+
+func TestPodHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("ReplicaSet")
+	handler := NewPodHandler(nil, cfg)
+	store := graphtest.NewStore()
+
+	controller := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "api-7d4b9c-x2x8k",
+			Namespace:       "shop",
+			UID:             "pod-1",
+			Labels:          map[string]string{"app": "api"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d4b9c", UID: "rs-1", Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          "worker-1",
+			PriorityClassName: "high",
+			Containers: []corev1.Container{{
+				Name:  "api",
+				Image: "registry.example.com/shop/api:1.2",
+				EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-config"}}},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "data",
+				VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "api-data"}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.12"},
+	}
+
+	if err := handler.HandleCreate(context.Background(), pod, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Pod", "pod-1", map[string]interface{}{
+		"name":           "api-7d4b9c-x2x8k",
+		"namespace":      "shop",
+		"nodeName":       "worker-1",
+		"status":         "Running",
+		"podIP":          "10.0.0.12",
+		"serviceAccount": "",
+		"clusterName":    "test-cluster",
+		"instanceHash":   "test-hash",
+	})
+	expectRelationships(t, store,
+		relationship("Pod", "uid", "pod-1", "OWNED_BY", "ReplicaSet", "uid", "rs-1"),
+		relationship("Pod", "uid", "pod-1", "SCHEDULED_ON", "Node", "name", "worker-1"),
+		relationship("Pod", "uid", "pod-1", "USES_PRIORITY_CLASS", "PriorityClass", "name", "high"),
+		relationship("Pod", "uid", "pod-1", "USES", "PersistentVolumeClaim", "name", "api-data"),
+	)
+
+	if images := store.StatementsMatching("RUNS_IMAGE"); len(images) != 1 || images[0].Params["name"] != "registry.example.com/shop/api:1.2" {
+		t.Errorf("Expected the pod to be linked to its image, got %+v", images)
+	} else if _, ok := images[0].Params["instanceHash"]; ok {
+		t.Error("Expected shared image nodes not to carry the instance hash")
+	}
+	if accounts := store.StatementsMatching("MERGE (from)-[:USES_SERVICE_ACCOUNT]"); len(accounts) != 1 {
+		t.Errorf("Expected the pod to be linked to the default service account, got %+v", accounts)
+	}
+	if registries := store.RelationshipsOf("PULLS_FROM"); len(registries) != 0 {
+		t.Errorf("Expected pods of a controller not to be linked to registries, got %+v", registries)
+	}
+}
+
+func TestDeploymentHandlerWrites(t *testing.T) {
+	handler := NewDeploymentHandler(nil, testConfig())
+	store := graphtest.NewStore()
+
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", UID: "deployment-1"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "api", Image: "registry.example.com/shop/api:1.2"}},
+			}},
+		},
+	}
+
+	if err := handler.HandleCreate(context.Background(), deployment, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Deployment", "deployment-1", map[string]interface{}{
+		"name":        "api",
+		"namespace":   "shop",
+		"replicas":    &replicas,
+		"strategy":    "RollingUpdate",
+		"selector":    map[string]string{"app": "api"},
+		"registries":  []string{"registry.example.com"},
+		"clusterName": "test-cluster",
+	})
+	registries := store.RelationshipsOf("PULLS_FROM")
+	if len(registries) != 1 || registries[0].FromValue != "deployment-1" || registries[0].ToLabel != "Registry" {
+		t.Errorf("Expected the deployment to be linked to its registry, got %+v", registries)
+	}
+}
+
+func TestEventHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("Pod")
+	handler := NewEventHandler(cfg)
+	store := graphtest.NewStore()
+
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "api.17a", Namespace: "shop", UID: "event-1"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "api", Namespace: "shop", UID: "pod-1"},
+		Reason:         "BackOff",
+		Type:           corev1.EventTypeWarning,
+		Count:          4,
+	}
+
+	if err := handler.HandleCreate(context.Background(), event, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	node := expectNode(t, store, "Event", "event-1", map[string]interface{}{
+		"reason":      "BackOff",
+		"type":        "Warning",
+		"count":       int32(4),
+		"clusterName": "test-cluster",
+	})
+	if _, ok := node.Properties["instanceHash"]; ok {
+		t.Error("Expected events not to carry the instance hash")
+	}
+	expectRelationships(t, store, relationship("Event", "uid", "event-1", "INVOLVES", "Pod", "uid", "pod-1"))
+}
+
+func TestPersistentVolumeClaimHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("StatefulSet")
+	handler := NewPVCHandler(cfg)
+	store := graphtest.NewStore()
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "data-p-e1229598-0001-0",
+			Namespace:       "neo4j",
+			UID:             "pvc-1",
+			Labels:          map[string]string{"dbid": "e1229598"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "p-e1229598-0001", UID: "sts-1"}},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "pv-1"},
+	}
+
+	if err := handler.HandleCreate(context.Background(), pvc, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "PersistentVolumeClaim", "pvc-1", map[string]interface{}{"name": "data-p-e1229598-0001-0"})
+	expectRelationships(t, store,
+		relationship("PersistentVolumeClaim", "uid", "pvc-1", "OWNED_BY", "StatefulSet", "uid", "sts-1"),
+		relationship("PersistentVolumeClaim", "uid", "pvc-1", "BOUND_TO", "PersistentVolume", "name", "pv-1"),
+		relationship("PersistentVolumeClaim", "uid", "pvc-1", "USED_BY", "StatefulSet", "uid", "sts-1"),
+		relationship("PersistentVolumeClaim", "uid", "pvc-1", "OWNED_BY", "Neo4jSingleInstance", "dbid", "e1229598"),
+	)
+	if links := store.StatementsMatching("MERGE (pvc)-[:USED_BY]->(sts)"); len(links) != 1 || links[0].Params["base"] != "data-p-e1229598-0001" {
+		t.Errorf("Expected the claim to be matched against volumeClaimTemplates without its ordinal, got %+v", links)
+	}
+}
+
+func TestStatefulSetHandlerLinksClaimTemplates(t *testing.T) {
+	handler := NewStatefulSetHandler(nil, testConfig())
+	store := graphtest.NewStore()
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop", UID: "sts-1"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{ObjectMeta: metav1.ObjectMeta{Name: "data"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "wal"}},
+			},
+		},
+	}
+
+	if err := handler.HandleCreate(context.Background(), sts, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "StatefulSet", "sts-1", map[string]interface{}{"volumeClaimTemplates": []string{"data", "wal"}})
+	links := store.StatementsMatching("MERGE (pvc)-[:USED_BY]->(sts)")
+	if len(links) != 1 || !reflect.DeepEqual(links[0].Params["prefixes"], []string{"data-db-", "wal-db-"}) || !links[0].Write {
+		t.Errorf("Expected the claims of the templates to be linked, got %+v", links)
+	}
+}
+
+func TestIngressHandlerWrites(t *testing.T) {
+	handler := NewIngressHandler(testConfig())
+	store := graphtest.NewStore()
+
+	className := "nginx"
+	pathType := networkingv1.PathTypePrefix
+	backend := func(service string) networkingv1.HTTPIngressPath {
+		return networkingv1.HTTPIngressPath{Path: "/" + service, PathType: &pathType, Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{Name: service, Port: networkingv1.ServiceBackendPort{Number: 80}},
+		}}
+	}
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop", UID: "ingress-1"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &className,
+			Rules: []networkingv1.IngressRule{{
+				Host: "shop.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{backend("api"), backend("web")},
+				}},
+			}},
+		},
+	}
+
+	if err := handler.HandleCreate(context.Background(), ingress, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Ingress", "ingress-1", map[string]interface{}{"ingressClass": "nginx"})
+	expectRelationships(t, store,
+		relationship("Ingress", "uid", "ingress-1", "ROUTES_TO", "Service", "name", "api"),
+		relationship("Ingress", "uid", "ingress-1", "ROUTES_TO", "Service", "name", "web"),
+	)
+	if classes := store.StatementsMatching("IngressClass"); len(classes) != 1 || classes[0].Params["className"] != "nginx" || !classes[0].Write {
+		t.Errorf("Expected the ingress to be linked to its class, got %+v", classes)
+	}
+}
+
+func TestJobHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("CronJob")
+	handler := NewJobHandler(nil, cfg)
+	store := graphtest.NewStore()
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "report-28791",
+			Namespace:       "batch",
+			UID:             "job-1",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "report", UID: "cronjob-1"}},
+		},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "report", Image: "busybox"}},
+		}}},
+	}
+
+	if err := handler.HandleCreate(context.Background(), job, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Job", "job-1", map[string]interface{}{"name": "report-28791", "namespace": "batch"})
+	if !store.HasRelationship(relationship("Job", "uid", "job-1", "OWNED_BY", "CronJob", "uid", "cronjob-1")) {
+		t.Errorf("Expected the job to be owned by its CronJob, got %+v", store.Relationships)
+	}
+}
+
+func TestServiceHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("Application")
+	handler := NewServiceHandler(nil, cfg)
+	store := graphtest.NewStore()
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "api",
+			Namespace:       "shop",
+			UID:             "svc-1",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Application", Name: "shop", UID: "app-1"}},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeClusterIP,
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "api"},
+			Ports:     []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(8080)}},
+		},
+	}
+
+	if err := handler.HandleCreate(context.Background(), svc, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Service", "svc-1", map[string]interface{}{
+		"type":      "ClusterIP",
+		"clusterIP": "10.96.0.10",
+		"selector":  map[string]string{"app": "api"},
+		"ports":     []string{"name=http;protocol=TCP;port=80;targetPort=8080"},
+	})
+	// Without a clientset the pods are not listed, so only the owner is linked
+	expectRelationships(t, store, relationship("Service", "uid", "svc-1", "OWNED_BY", "Application", "uid", "app-1"))
+
+	// Routes, Istio resources, webhooks and APIServices synced before the
+	// service are linked to it by the namespace/name key they store
+	for _, referrer := range []string{"MATCH (r:HTTPRoute", "MATCH (r:VirtualService", "MATCH (r:DestinationRule", "MATCH (r:ValidatingWebhookConfiguration", "MATCH (r:MutatingWebhookConfiguration", "MATCH (r:APIService"} {
+		statements := store.StatementsMatching(referrer)
+		if len(statements) != 1 || statements[0].Params["quotedKey"] != `"shop/api"` || !statements[0].Write {
+			t.Errorf("Expected one statement linking %s to the service, got %+v", referrer, statements)
+		}
+	}
+}
+
+func TestEndpointsHandlerWrites(t *testing.T) {
+	handler := NewEndpointsHandler(testConfig())
+	store := graphtest.NewStore()
+
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", UID: "endpoints-1"},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.12"}},
+			Ports:     []corev1.EndpointPort{{Port: 8080}},
+		}},
+	}
+
+	if err := handler.HandleCreate(context.Background(), endpoints, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Endpoints", "endpoints-1", map[string]interface{}{"name": "api", "namespace": "shop"})
+	expectRelationships(t, store, relationship("Endpoints", "name", "api", "PROVIDES_ENDPOINTS_FOR", "Service", "name", "api"))
+}
+
+func TestHorizontalPodAutoscalerHandlerWrites(t *testing.T) {
+	cfg := testConfig()
+	registerOwnerKinds("Deployment")
+	handler := NewHorizontalPodAutoscalerHandler(cfg)
+	store := graphtest.NewStore()
+
+	minReplicas := int32(2)
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", UID: "hpa-1"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{CurrentReplicas: 3, DesiredReplicas: 4},
+	}
+
+	if err := handler.HandleCreate(context.Background(), hpa, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "HorizontalPodAutoscaler", "hpa-1", map[string]interface{}{
+		"minReplicas":     &minReplicas,
+		"maxReplicas":     int32(10),
+		"currentReplicas": int32(3),
+		"desiredReplicas": int32(4),
+	})
+	expectRelationships(t, store, relationship("HorizontalPodAutoscaler", "uid", "hpa-1", "SCALES", "Deployment", "name", "api"))
+}
+
+func TestPVHandlerWrites(t *testing.T) {
+	handler := NewPVHandler(testConfig())
+	store := graphtest.NewStore()
+
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-1", UID: "pv-uid-1"},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:                      corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("10Gi")},
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+			StorageClassName:              "gp3",
+			ClaimRef:                      &corev1.ObjectReference{Kind: "PersistentVolumeClaim", Name: "data", Namespace: "shop", UID: "pvc-1"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: "ebs.csi.aws.com", VolumeHandle: "vol-0abc"},
+			},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeBound},
+	}
+
+	if err := handler.HandleCreate(context.Background(), pv, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "PersistentVolume", "pv-uid-1", map[string]interface{}{
+		"capacity":     "10Gi",
+		"status":       "Bound",
+		"storageClass": "gp3",
+		"csiDriver":    "ebs.csi.aws.com",
+		"volumeHandle": "vol-0abc",
+	})
+	expectRelationships(t, store,
+		relationship("PersistentVolume", "uid", "pv-uid-1", "USES_DRIVER", "CSIDriver", "name", "ebs.csi.aws.com"),
+		relationship("PersistentVolume", "uid", "pv-uid-1", "BOUND_TO", "PersistentVolumeClaim", "uid", "pvc-1"),
+	)
+}
+
+func TestVolumeAttachmentHandlerWrites(t *testing.T) {
+	handler := NewVolumeAttachmentHandler(testConfig())
+	store := graphtest.NewStore()
+
+	pvName := "pv-1"
+	va := &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{Name: "csi-0abc", UID: "va-1"},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: "ebs.csi.aws.com",
+			NodeName: "worker-1",
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+		Status: storagev1.VolumeAttachmentStatus{
+			Attached:    false,
+			AttachError: &storagev1.VolumeError{Message: "volume is in use"},
+		},
+	}
+
+	if err := handler.HandleCreate(context.Background(), va, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "VolumeAttachment", "va-1", map[string]interface{}{
+		"persistentVolumeName": "pv-1",
+		"attached":             false,
+		"attachError":          "volume is in use",
+	})
+	expectRelationships(t, store,
+		relationship("VolumeAttachment", "uid", "va-1", "ATTACHED_TO", "Node", "name", "worker-1"),
+		relationship("VolumeAttachment", "uid", "va-1", "USES_DRIVER", "CSIDriver", "name", "ebs.csi.aws.com"),
+		relationship("VolumeAttachment", "uid", "va-1", "ATTACHES", "PersistentVolume", "name", "pv-1"),
+	)
+}
+
+func TestConfigMapHandlerWrites(t *testing.T) {
+	handler := NewConfigMapHandler(testConfig())
+	store := graphtest.NewStore()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-config", Namespace: "shop", UID: "cm-1"},
+		Data:       map[string]string{"LOG_LEVEL": "debug"},
+	}
+
+	if err := handler.HandleCreate(context.Background(), cm, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "ConfigMap", "cm-1", map[string]interface{}{"data": map[string]string{"LOG_LEVEL": "debug"}})
+	users := store.StatementsMatching("WHERE p.configMaps CONTAINS $quotedName")
+	if len(users) != 1 || users[0].Params["quotedName"] != `"api-config"` || users[0].Params["namespace"] != "shop" {
+		t.Errorf("Expected the pods using the config map to be linked to it, got %+v", users)
+	}
+}
+
+func TestSecretHandlerWrites(t *testing.T) {
+	handler := NewSecretHandler(testConfig())
+	store := graphtest.NewStore()
+
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ghcr", Namespace: "shop", UID: "secret-1"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"ghcr.io":{"auth":"x"}}}`)},
+	}
+	if err := handler.HandleCreate(context.Background(), pullSecret, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	node := expectNode(t, store, "Secret", "secret-1", map[string]interface{}{
+		"type":       "kubernetes.io/dockerconfigjson",
+		"registries": []string{"ghcr.io"},
+	})
+	if _, ok := node.Properties["data"]; ok {
+		t.Error("Expected the data of the secret not to be stored")
+	}
+	expectNode(t, store, "Registry", "test-cluster/ghcr.io", map[string]interface{}{"name": "ghcr.io"})
+	expectRelationships(t, store, relationship("Secret", "uid", "secret-1", "AUTHENTICATES_TO", "Registry", "key", "test-cluster/ghcr.io"))
+	if users := store.StatementsMatching("WHERE p.secrets CONTAINS $quotedName"); len(users) != 1 {
+		t.Errorf("Expected the pods using the secret to be linked to it, got %+v", users)
+	}
+	if tokens := store.StatementsMatching("MERGE (sa)-[:USES]->(s)"); len(tokens) != 0 {
+		t.Errorf("Expected only token secrets to be linked to a service account, got %+v", tokens)
+	}
+
+	store = graphtest.NewStore()
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api-token",
+			Namespace:   "shop",
+			UID:         "secret-2",
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: "api"},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	if err := handler.HandleCreate(context.Background(), token, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+	if tokens := store.StatementsMatching("MERGE (sa)-[:USES]->(s)"); len(tokens) != 1 || tokens[0].Params["serviceAccount"] != "api" {
+		t.Errorf("Expected the token to be linked to its service account, got %+v", tokens)
+	}
+}
+
+func TestHandleResourceDeleteWrites(t *testing.T) {
+	store := graphtest.NewStore()
+
+	if err := HandleResourceDelete(context.Background(), "Pod", "pod-1", store); err != nil {
+		t.Fatalf("HandleResourceDelete failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(store.Deleted, []string{"Pod/pod-1"}) {
+		t.Errorf("Expected the pod to be deleted, got %v", store.Deleted)
+	}
+	expected := []graphtest.Change{{Kind: "Pod", UID: "pod-1", Operation: neo4j.OperationDelete}}
+	if !reflect.DeepEqual(store.Changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, store.Changes)
+	}
+}
+
+func TestPruneExpiredEventsWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	store.Respond("MATCH (e:Event)", map[string]interface{}{"pruned": int64(3)})
+
+	if err := PruneExpiredEvents(context.Background(), store, 7); err != nil {
+		t.Fatalf("PruneExpiredEvents failed: %v", err)
+	}
+	if prunes := store.StatementsMatching("DETACH DELETE e"); len(prunes) != 1 || prunes[0].Params["cutoff"] == nil {
+		t.Errorf("Expected expired events to be pruned, got %+v", store.Statements)
+	}
+
+	store = graphtest.NewStore()
+	if err := PruneExpiredEvents(context.Background(), store, 0); err != nil || len(store.Statements) != 0 {
+		t.Errorf("Expected nothing to be pruned without a TTL, got %+v (%v)", store.Statements, err)
+	}
+}
+
+// minimalFields holds the fields the API server guarantees for the kinds whose
+// handlers rely on them, beyond the metadata every object has
+var minimalFields = map[string]map[string]interface{}{
+	"DaemonSet":    {"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}}}},
+	"Deployment":   {"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}}}},
+	"ReplicaSet":   {"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}}}},
+	"StatefulSet":  {"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}}}},
+	"StorageClass": {"provisioner": "ebs.csi.aws.com", "reclaimPolicy": "Delete", "volumeBindingMode": "WaitForFirstConsumer"},
+	"VulnerabilityReport": {"report": map[string]interface{}{
+		"registry": map[string]interface{}{"server": "ghcr.io"},
+		"artifact": map[string]interface{}{"repository": "org/api", "tag": "v1"},
+	}},
+}
+
+// TestEveryHandlerWrites runs every registered handler against the fake store
+// with a minimal object, as the agent, the ingest receiver and the replayer
+// create them from the registry. The tests above and the handler tests of the
+// other files check the properties and relationships of the handlers in detail.
+func TestEveryHandlerWrites(t *testing.T) {
+	for _, registration := range registry.All() {
+		t.Run(registration.Kind, func(t *testing.T) {
+			store := graphtest.NewStore()
+			handler := registration.New(nil, testConfig())
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": registration.GVR.GroupVersion().String(),
+				"kind":       registration.Kind,
+				"metadata":   map[string]interface{}{"name": "api", "namespace": "shop", "uid": "uid-1"},
+			}}
+			for key, value := range minimalFields[registration.Kind] {
+				obj.Object[key] = value
+			}
+
+			if err := handler.HandleCreate(context.Background(), obj, store); err != nil {
+				t.Fatalf("HandleCreate failed: %v", err)
+			}
+			expectNode(t, store, registration.Kind, "uid-1", map[string]interface{}{"clusterName": "test-cluster"})
+
+			if err := handler.HandleDelete(context.Background(), obj, store); err != nil {
+				t.Fatalf("HandleDelete failed: %v", err)
+			}
+			// Events outlive their objects until PruneExpiredEvents removes them
+			expected := []string{registration.Kind + "/uid-1"}
+			if registration.Kind == "Event" {
+				expected = nil
+			}
+			if !reflect.DeepEqual(store.Deleted, expected) {
+				t.Errorf("Expected %v to be deleted, got %v", expected, store.Deleted)
+			}
+		})
+	}
+}
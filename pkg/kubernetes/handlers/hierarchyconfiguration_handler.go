@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HierarchyConfigurationHandler tracks the HierarchyConfiguration objects of
+// the Hierarchical Namespace Controller, which declare the parent of a namespace
+type HierarchyConfigurationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var hierarchyConfigurationGVR = schema.GroupVersionResource{
+	Group:    "hnc.x-k8s.io",
+	Version:  "v1alpha2",
+	Resource: "hierarchyconfigurations",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "HierarchyConfiguration",
+		GVR:               hierarchyConfigurationGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewHierarchyConfigurationHandler(cfg)
+		},
+	})
+}
+
+func NewHierarchyConfigurationHandler(cfg *config.Config) *HierarchyConfigurationHandler {
+	// Register this handler's kind for owner references
+	RegisterOwnerKind("HierarchyConfiguration", "HierarchyConfiguration")
+	return &HierarchyConfigurationHandler{
+		BaseHandler:  NewBaseHandler(hierarchyConfigurationGVR, "HierarchyConfiguration", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *HierarchyConfigurationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	// Since HierarchyConfiguration is a custom resource, we'll work with unstructured objects
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return failure.New(failure.ConversionError, fmt.Errorf("object is not *unstructured.Unstructured"))
+	}
+
+	name := unstructuredObj.GetName()
+	uid := string(unstructuredObj.GetUID())
+	namespace := unstructuredObj.GetNamespace()
+	parent, _, _ := unstructured.NestedString(unstructuredObj.Object, "spec", "parent")
+	children, _, _ := unstructured.NestedStringSlice(unstructuredObj.Object, "status", "children")
+
+	properties := map[string]interface{}{
+		"name":              name,
+		"uid":               uid,
+		"namespace":         namespace,
+		"creationTimestamp": unstructuredObj.GetCreationTimestamp().String(),
+		"labels":            unstructuredObj.GetLabels(),
+		"annotations":       unstructuredObj.GetAnnotations(),
+		"parent":            parent,
+		"children":          children,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	configures := relationship("HierarchyConfiguration", "uid", uid, "CONFIGURES", "Namespace", "name", namespace)
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"HierarchyConfiguration"}, properties, "uid", []graph.Relationship{configures}); err != nil {
+		return fmt.Errorf("failed to upsert hierarchyconfiguration %s/%s: %w", namespace, name, err)
+	}
+
+	// The HierarchyConfiguration is the source of truth for the parent, even
+	// if the HNC tree labels on the namespace are missing or outdated
+	if err := linkNamespaceParent(ctx, neo4jClient, h.GetClusterName(), namespace, parent); err != nil {
+		fmt.Printf("Warning: failed to link Namespace %s to parent %s: %v\n", namespace, parent, err)
+	}
+
+	return nil
+}
+
+func (h *HierarchyConfigurationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return failure.New(failure.ConversionError, fmt.Errorf("object is not *unstructured.Unstructured"))
+	}
+	return HandleResourceDelete(ctx, "HierarchyConfiguration", string(unstructuredObj.GetUID()), neo4jClient)
+}
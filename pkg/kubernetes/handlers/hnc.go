@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+)
+
+const (
+	// hncTreeLabelSuffix marks the ancestors of a namespace managed by the
+	// Hierarchical Namespace Controller, e.g. "team-a.tree.hnc.x-k8s.io/depth: 1"
+	hncTreeLabelSuffix = ".tree.hnc.x-k8s.io/depth"
+	// hncSubnamespaceOfAnnotation names the parent of a subnamespace
+	hncSubnamespaceOfAnnotation = "hnc.x-k8s.io/subnamespace-of"
+)
+
+// hncParent returns the name of a namespace's parent in the HNC hierarchy, or
+// an empty string if the namespace is a root or not managed by HNC
+func hncParent(name string, labels, annotations map[string]string) string {
+	if parent := annotations[hncSubnamespaceOfAnnotation]; parent != "" {
+		return parent
+	}
+	for key, depth := range labels {
+		ancestor, ok := strings.CutSuffix(key, hncTreeLabelSuffix)
+		if ok && depth == "1" && ancestor != name {
+			return ancestor
+		}
+	}
+	return ""
+}
+
+// linkNamespaceParent makes parent the only parent of the child namespace by
+// creating a PARENT_OF relationship and removing those from other namespaces.
+// An empty parent only removes existing parents.
+func linkNamespaceParent(ctx context.Context, neo4jClient graph.Store, clusterName, child, parent string) error {
+	params := map[string]interface{}{
+		"child":       child,
+		"parent":      parent,
+		"clusterName": clusterName,
+	}
+	statements := []graph.Statement{{
+		Query: `
+			MATCH (old:Namespace)-[r:PARENT_OF]->(child:Namespace {name: $child, clusterName: $clusterName})
+			WHERE old.name <> $parent` +
+			graph.DeleteRelationship("r", "old", "uid", "child", "uid"),
+		Params: params,
+	}}
+	if parent != "" {
+		statements = append(statements, graph.Statement{
+			Query: `
+			MATCH (child:Namespace {name: $child, clusterName: $clusterName})
+			MATCH (parent:Namespace {name: $parent, clusterName: $clusterName})` +
+				graph.MergeRelationship("parent", "uid", "PARENT_OF", "child", "uid"),
+			Params: params,
+		})
+	}
+	return neo4jClient.Write(ctx, statements...)
+}
+
+// linkNamespaceChildren creates PARENT_OF relationships to the namespaces that
+// were ingested before their parent
+func linkNamespaceChildren(ctx context.Context, neo4jClient graph.Store, clusterName, parent string) error {
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (parent:Namespace {name: $parent, clusterName: $clusterName})
+			MATCH (child:Namespace {hncParent: $parent, clusterName: $clusterName})` +
+			graph.MergeRelationship("parent", "uid", "PARENT_OF", "child", "uid"),
+		Params: map[string]interface{}{
+			"parent":      parent,
+			"clusterName": clusterName,
+		},
+	})
+	return err
+}
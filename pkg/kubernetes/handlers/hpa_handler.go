@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type HorizontalPodAutoscalerHandler struct {
@@ -16,21 +18,34 @@ type HorizontalPodAutoscalerHandler struct {
 	instanceHash string
 }
 
+var horizontalPodAutoscalerGVR = schema.GroupVersionResource{
+	Group:    "autoscaling",
+	Version:  "v2",
+	Resource: "horizontalpodautoscalers",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "HorizontalPodAutoscaler",
+		GVR:               horizontalPodAutoscalerGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewHorizontalPodAutoscalerHandler(cfg)
+		},
+	})
+}
+
 func NewHorizontalPodAutoscalerHandler(cfg *config.Config) *HorizontalPodAutoscalerHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "autoscaling",
-		Version:  "v2",
-		Resource: "horizontalpodautoscalers",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("HorizontalPodAutoscaler", "HorizontalPodAutoscaler")
 	return &HorizontalPodAutoscalerHandler{
-		BaseHandler:  NewBaseHandler(gvr, "HorizontalPodAutoscaler", cfg),
+		BaseHandler:  NewBaseHandler(horizontalPodAutoscalerGVR, "HorizontalPodAutoscaler", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *HorizontalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *HorizontalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	hpa, err := ConvertToTyped[*autoscalingv2.HorizontalPodAutoscaler](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert horizontalpodautoscaler: %w", err)
@@ -57,47 +72,24 @@ func (h *HorizontalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj i
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"HorizontalPodAutoscaler"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert horizontalpodautoscaler %s: %w", hpa.Name, err)
-	}
-
-	// Create relationships based on owner references for all supported types
-	if hpa.OwnerReferences != nil {
-		for _, ownerRef := range hpa.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"HorizontalPodAutoscaler", "uid", string(hpa.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between HorizontalPodAutoscaler %s and %s %s: %v\n", hpa.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("HorizontalPodAutoscaler", string(hpa.UID), hpa.OwnerReferences)
 
 	// Create relationship to the target resource (Deployment, StatefulSet, etc.)
 	if hpa.Spec.ScaleTargetRef.Name != "" {
 		targetKind := hpa.Spec.ScaleTargetRef.Kind
 		if label, ok := ownerKindToLabel[targetKind]; ok {
-			err := neo4jClient.CreateRelationship(
-				ctx,
-				"HorizontalPodAutoscaler", "uid", string(hpa.UID),
-				"SCALES",
-				label, "name", hpa.Spec.ScaleTargetRef.Name,
-			)
-			if err != nil {
-				fmt.Printf("Warning: failed to create SCALES relationship between HorizontalPodAutoscaler %s and %s %s: %v\n", hpa.Name, targetKind, hpa.Spec.ScaleTargetRef.Name, err)
-			}
+			relationships = append(relationships, relationship("HorizontalPodAutoscaler", "uid", string(hpa.UID), "SCALES", label, "name", hpa.Spec.ScaleTargetRef.Name))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"HorizontalPodAutoscaler"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert horizontalpodautoscaler %s: %w", hpa.Name, err)
+	}
+
 	return nil
 }
 
-func (h *HorizontalPodAutoscalerHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *HorizontalPodAutoscalerHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	hpa, err := ConvertToTyped[*autoscalingv2.HorizontalPodAutoscaler](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert horizontalpodautoscaler: %w", err)
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HTTPRouteHandler tracks Gateway API HTTPRoutes, linking them to the
+// Gateways they attach to and the Services they forward to
+type HTTPRouteHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var httpRouteGVR = gatewayAPIResource("httproutes")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "HTTPRoute",
+		GVR:               httpRouteGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewHTTPRouteHandler(cfg)
+		},
+	})
+}
+
+func NewHTTPRouteHandler(cfg *config.Config) *HTTPRouteHandler {
+	RegisterOwnerKind("HTTPRoute", "HTTPRoute")
+	return &HTTPRouteHandler{
+		BaseHandler:  NewBaseHandler(httpRouteGVR, "HTTPRoute", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *HTTPRouteHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	route, err := ConvertToTyped[*httpRoute](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert httproute: %w", err)
+	}
+
+	uid := string(route.UID)
+	gateways := parentGateways(route)
+	services := backendServices(route)
+	properties := map[string]interface{}{
+		"name":              route.Name,
+		"uid":               uid,
+		"namespace":         route.Namespace,
+		"creationTimestamp": route.CreationTimestamp.String(),
+		"labels":            route.Labels,
+		"annotations":       route.Annotations,
+		"hostnames":         route.Spec.Hostnames,
+		"parentGateways":    gateways,
+		"backendServices":   services,
+		"accepted":          routeConditionTrue(route, "Accepted"),
+		"resolvedRefs":      routeConditionTrue(route, "ResolvedRefs"),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"HTTPRoute"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert httproute %s: %w", route.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "HTTPRoute", uid, "ATTACHED_TO", "Gateway", h.GetClusterName(), gateways); err != nil {
+		fmt.Printf("Warning: failed to create ATTACHED_TO relationships for HTTPRoute %s: %v\n", route.Name, err)
+	}
+	if err := linkNamespacedTargets(ctx, neo4jClient, "HTTPRoute", uid, "ROUTES_TO", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create ROUTES_TO relationships for HTTPRoute %s: %v\n", route.Name, err)
+	}
+
+	return nil
+}
+
+func (h *HTTPRouteHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	route, err := ConvertToTyped[*httpRoute](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert httproute: %w", err)
+	}
+	return HandleResourceDelete(ctx, "HTTPRoute", string(route.UID), neo4jClient)
+}
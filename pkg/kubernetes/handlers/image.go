@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/vulns"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podSpecImages returns the sorted normalized images the containers of a pod spec run
+func podSpecImages(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	for _, container := range spec.InitContainers {
+		seen[vulns.NormalizeImage(container.Image)] = true
+	}
+	for _, container := range spec.Containers {
+		seen[vulns.NormalizeImage(container.Image)] = true
+	}
+	for _, container := range spec.EphemeralContainers {
+		seen[vulns.NormalizeImage(container.Image)] = true
+	}
+	return sortedKeys(seen)
+}
+
+// linkImages creates the Image nodes of the normalized references and a
+// RUNS_IMAGE relationship from the resource to each of them. Image nodes are
+// merged rather than replaced, so the vulnerability counts written by the
+// scanner integrations survive. They carry no instance hash, as they are
+// shared by the pods of every run and must survive the cleanup of the
+// nodes of previous runs.
+func linkImages(ctx context.Context, neo4jClient graph.Store, label, uid, clusterName string, images []string) {
+	for _, image := range images {
+		ref := vulns.ParseImage(image)
+		err := neo4jClient.Write(ctx, graph.Statement{
+			Query: fmt.Sprintf(`
+				MERGE (i:Image {key: $key})
+				SET i.name = $name, i.registry = $registry, i.repository = $repository,
+					i.tag = $tag, i.digest = $digest, i.clusterName = $clusterName
+				WITH i
+				MATCH (r:%s {uid: $uid})`, label) +
+				graph.MergeRelationship("r", "uid", "RUNS_IMAGE", "i", "key"),
+			Params: map[string]interface{}{
+				"key":         vulns.ImageKey(clusterName, image),
+				"name":        image,
+				"registry":    ref.Registry,
+				"repository":  ref.Repository,
+				"tag":         ref.Tag,
+				"digest":      ref.Digest,
+				"clusterName": clusterName,
+				"uid":         uid,
+			},
+		})
+		if err != nil {
+			fmt.Printf("Warning: failed to create RUNS_IMAGE relationship between %s %s and Image %s: %v\n", label, uid, image, err)
+		}
+	}
+}
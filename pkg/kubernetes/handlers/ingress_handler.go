@@ -4,30 +4,56 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
+// ingressClassAnnotation names the class of Ingresses created before IngressClasses existed
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+// ingressClass returns the class of an Ingress, or "" if it uses the default class
+func ingressClass(ingress *networkingv1.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	return ingress.Annotations[ingressClassAnnotation]
+}
+
 type IngressHandler struct {
 	BaseHandler
 }
 
+var ingressGVR = schema.GroupVersionResource{
+	Group:    "networking.k8s.io",
+	Version:  "v1",
+	Resource: "ingresses",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Ingress",
+		GVR:               ingressGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewIngressHandler(cfg)
+		},
+	})
+}
+
 func NewIngressHandler(cfg *config.Config) *IngressHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "networking.k8s.io",
-		Version:  "v1",
-		Resource: "ingresses",
-	}
 	RegisterOwnerKind("Ingress", "Ingress")
 	return &IngressHandler{
-		BaseHandler: NewBaseHandler(gvr, "Ingress", cfg),
+		BaseHandler: NewBaseHandler(ingressGVR, "Ingress", cfg),
 	}
 }
 
-func (h *IngressHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *IngressHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ingress, err := ConvertToTyped[*networkingv1.Ingress](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert ingress: %w", err)
@@ -82,6 +108,7 @@ func (h *IngressHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		"uid":                string(ingress.UID),
 		"namespace":          ingress.Namespace,
 		"ingressClassName":   ingress.Spec.IngressClassName,
+		"ingressClass":       ingressClass(ingress),
 		"rules":              rules,
 		"tls":                tls,
 		"loadBalancerStatus": loadBalancerStatus,
@@ -90,33 +117,40 @@ func (h *IngressHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		"clusterName":        h.GetClusterName(),
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Ingress"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert ingress %s: %w", ingress.Name, err)
-	}
+	var relationships []graph.Relationship
 
 	// Create relationships to referenced services
 	for _, rule := range ingress.Spec.Rules {
 		if rule.HTTP != nil {
 			for _, path := range rule.HTTP.Paths {
 				if path.Backend.Service != nil {
-					err := neo4jClient.CreateRelationship(
-						ctx,
-						"Ingress", "uid", string(ingress.UID),
-						"ROUTES_TO",
-						"Service", "name", path.Backend.Service.Name,
-					)
-					if err != nil {
-						fmt.Printf("Warning: failed to create ROUTES_TO relationship for Ingress %s: %v\n", ingress.Name, err)
-					}
+					relationships = append(relationships, relationship("Ingress", "uid", string(ingress.UID), "ROUTES_TO", "Service", "name", path.Backend.Service.Name))
 				}
 			}
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Ingress"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert ingress %s: %w", ingress.Name, err)
+	}
+
+	if class := ingressClass(ingress); class != "" {
+		err := neo4jClient.Write(ctx, graph.Statement{
+			Query: `
+				MATCH (i:Ingress {uid: $uid})
+				MATCH (c:IngressClass {name: $className, clusterName: $clusterName})` +
+				graph.MergeRelationship("i", "uid", "USES", "c", "uid"),
+			Params: map[string]interface{}{"uid": string(ingress.UID), "className": class, "clusterName": h.GetClusterName()},
+		})
+		if err != nil {
+			fmt.Printf("Warning: failed to create USES relationship between Ingress %s and IngressClass %s: %v\n", ingress.Name, class, err)
+		}
+	}
+
 	return nil
 }
 
-func (h *IngressHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *IngressHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ingress, err := ConvertToTyped[*networkingv1.Ingress](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert ingress: %w", err)
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ingressClassDefaultAnnotation marks the IngressClass used by Ingresses that do not name one
+const ingressClassDefaultAnnotation = "ingressclass.kubernetes.io/is-default-class"
+
+// IngressClassHandler tracks IngressClasses, which name the controller
+// implementing the Ingresses of the class
+type IngressClassHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var ingressClassGVR = schema.GroupVersionResource{
+	Group:    "networking.k8s.io",
+	Version:  "v1",
+	Resource: "ingressclasses",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "IngressClass",
+		GVR:               ingressClassGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewIngressClassHandler(cfg)
+		},
+	})
+}
+
+func NewIngressClassHandler(cfg *config.Config) *IngressClassHandler {
+	RegisterOwnerKind("IngressClass", "IngressClass")
+	return &IngressClassHandler{
+		BaseHandler:  NewBaseHandler(ingressClassGVR, "IngressClass", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *IngressClassHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*networkingv1.IngressClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert ingressclass: %w", err)
+	}
+
+	parameters := ""
+	if ref := class.Spec.Parameters; ref != nil {
+		parameters = ref.Kind + "/" + ref.Name
+		if ref.Namespace != nil {
+			parameters = ref.Kind + "/" + namespacedKey(*ref.Namespace, ref.Name)
+		}
+	}
+
+	properties := map[string]interface{}{
+		"name":              class.Name,
+		"uid":               string(class.UID),
+		"creationTimestamp": class.CreationTimestamp.String(),
+		"labels":            class.Labels,
+		"annotations":       class.Annotations,
+		"controller":        class.Spec.Controller,
+		"parameters":        parameters,
+		"isDefault":         class.Annotations[ingressClassDefaultAnnotation] == "true",
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"IngressClass"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert ingressclass %s: %w", class.Name, err)
+	}
+
+	// Ingresses synced before their class
+	err = neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (c:IngressClass {uid: $uid})
+			MATCH (i:Ingress {ingressClass: $name, clusterName: $clusterName})` +
+			graph.MergeRelationship("i", "uid", "USES", "c", "uid"),
+		Params: map[string]interface{}{"uid": string(class.UID), "name": class.Name, "clusterName": h.GetClusterName()},
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to link Ingresses to IngressClass %s: %v\n", class.Name, err)
+	}
+
+	return nil
+}
+
+func (h *IngressClassHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*networkingv1.IngressClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert ingressclass: %w", err)
+	}
+	return HandleResourceDelete(ctx, "IngressClass", string(class.UID), neo4jClient)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// istioResource returns the GVR of an Istio resource
+func istioResource(group, resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: group, Version: "v1beta1", Resource: resource}
+}
+
+// istioMeshGateway is the reserved gateway name of the sidecars of the mesh
+const istioMeshGateway = "mesh"
+
+// The Istio types are not part of client-go, so the handlers decode the
+// fields they need into the structs below
+
+type istioDestination struct {
+	Host   string `json:"host"`
+	Subset string `json:"subset,omitempty"`
+	Port   struct {
+		Number uint32 `json:"number,omitempty"`
+	} `json:"port,omitempty"`
+}
+
+type istioRoute struct {
+	Route []struct {
+		Destination istioDestination `json:"destination"`
+		Weight      int32            `json:"weight,omitempty"`
+	} `json:"route,omitempty"`
+}
+
+type virtualService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Hosts    []string     `json:"hosts,omitempty"`
+		Gateways []string     `json:"gateways,omitempty"`
+		HTTP     []istioRoute `json:"http,omitempty"`
+		TCP      []istioRoute `json:"tcp,omitempty"`
+		TLS      []istioRoute `json:"tls,omitempty"`
+	} `json:"spec"`
+}
+
+type destinationRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Host          string `json:"host"`
+		TrafficPolicy *struct {
+			TLS *struct {
+				Mode string `json:"mode,omitempty"`
+			} `json:"tls,omitempty"`
+			LoadBalancer *struct {
+				Simple string `json:"simple,omitempty"`
+			} `json:"loadBalancer,omitempty"`
+		} `json:"trafficPolicy,omitempty"`
+		Subsets []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels,omitempty"`
+		} `json:"subsets,omitempty"`
+	} `json:"spec"`
+}
+
+type istioServer struct {
+	Port struct {
+		Number   uint32 `json:"number"`
+		Protocol string `json:"protocol"`
+		Name     string `json:"name,omitempty"`
+	} `json:"port"`
+	Hosts []string `json:"hosts,omitempty"`
+	TLS   *struct {
+		Mode string `json:"mode,omitempty"`
+	} `json:"tls,omitempty"`
+}
+
+type istioGateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Selector map[string]string `json:"selector,omitempty"`
+		Servers  []istioServer     `json:"servers,omitempty"`
+	} `json:"spec"`
+}
+
+type peerAuthentication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Selector *struct {
+			MatchLabels map[string]string `json:"matchLabels,omitempty"`
+		} `json:"selector,omitempty"`
+		MTLS *struct {
+			Mode string `json:"mode,omitempty"`
+		} `json:"mtls,omitempty"`
+		PortLevelMTLS map[string]struct {
+			Mode string `json:"mode,omitempty"`
+		} `json:"portLevelMtls,omitempty"`
+	} `json:"spec"`
+}
+
+// meshServiceKey returns the namespace/name key of the Service a mesh host
+// refers to. Short names are in the namespace of the referring resource.
+// Hosts outside of the cluster, wildcards and names that are not Service
+// FQDNs are not Services and ok is false.
+func meshServiceKey(host, namespace string) (string, bool) {
+	if host == "" || strings.Contains(host, "*") {
+		return "", false
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) == 1 {
+		return namespacedKey(namespace, host), true
+	}
+	if len(parts) >= 3 && parts[2] == "svc" {
+		return namespacedKey(parts[1], parts[0]), true
+	}
+	return "", false
+}
+
+// virtualServiceDestinations returns the namespace/name keys of the Services
+// the routes of a VirtualService forward to
+func virtualServiceDestinations(vs *virtualService) []string {
+	seen := make(map[string]bool)
+	for _, routes := range [][]istioRoute{vs.Spec.HTTP, vs.Spec.TCP, vs.Spec.TLS} {
+		for _, route := range routes {
+			for _, destination := range route.Route {
+				if key, ok := meshServiceKey(destination.Destination.Host, vs.Namespace); ok {
+					seen[key] = true
+				}
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// virtualServiceGateways returns the namespace/name keys of the Istio
+// Gateways a VirtualService is bound to. The reserved mesh gateway stands for
+// the sidecars and is left out.
+func virtualServiceGateways(vs *virtualService) []string {
+	seen := make(map[string]bool)
+	for _, name := range vs.Spec.Gateways {
+		if name == istioMeshGateway {
+			continue
+		}
+		if namespace, gatewayName, found := strings.Cut(name, "/"); found {
+			seen[namespacedKey(namespace, gatewayName)] = true
+		} else {
+			seen[namespacedKey(vs.Namespace, name)] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// formatIstioServer describes a Gateway server as "port=...;protocol=...;hosts=...;tls=..."
+func formatIstioServer(server istioServer) string {
+	parts := []string{
+		fmt.Sprintf("port=%d", server.Port.Number),
+		"protocol=" + server.Port.Protocol,
+	}
+	if len(server.Hosts) > 0 {
+		parts = append(parts, "hosts="+strings.Join(server.Hosts, ","))
+	}
+	if server.TLS != nil && server.TLS.Mode != "" {
+		parts = append(parts, "tls="+server.TLS.Mode)
+	}
+	return strings.Join(parts, ";")
+}
+
+// mtlsMode returns the mTLS mode of a PeerAuthentication. UNSET inherits the
+// mode of the parent scope.
+func mtlsMode(pa *peerAuthentication) string {
+	if pa.Spec.MTLS == nil || pa.Spec.MTLS.Mode == "" {
+		return "UNSET"
+	}
+	return pa.Spec.MTLS.Mode
+}
+
+// portLevelMTLS describes the per-port mTLS modes of a PeerAuthentication as "port=mode"
+func portLevelMTLS(pa *peerAuthentication) []string {
+	result := make([]string, 0, len(pa.Spec.PortLevelMTLS))
+	for port, setting := range pa.Spec.PortLevelMTLS {
+		mode := setting.Mode
+		if mode == "" {
+			mode = "UNSET"
+		}
+		result = append(result, port+"="+mode)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMeshServiceKey(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+		ok       bool
+	}{
+		{"reviews", "bookinfo/reviews", true},
+		{"reviews.prod.svc.cluster.local", "prod/reviews", true},
+		{"reviews.prod.svc", "prod/reviews", true},
+		{"api.example.com", "", false},
+		{"*.bookinfo.svc.cluster.local", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			key, ok := meshServiceKey(tt.host, "bookinfo")
+			if key != tt.expected || ok != tt.ok {
+				t.Errorf("Expected %q (%v), got %q (%v)", tt.expected, tt.ok, key, ok)
+			}
+		})
+	}
+}
+
+func TestVirtualServiceReferences(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.istio.io/v1beta1",
+		"kind":       "VirtualService",
+		"metadata":   map[string]interface{}{"name": "reviews", "namespace": "bookinfo", "uid": "vs-1"},
+		"spec": map[string]interface{}{
+			"hosts":    []interface{}{"reviews.example.com"},
+			"gateways": []interface{}{"mesh", "public", "istio-system/ingress"},
+			"http": []interface{}{
+				map[string]interface{}{"route": []interface{}{
+					map[string]interface{}{"destination": map[string]interface{}{"host": "reviews", "subset": "v1"}, "weight": int64(90)},
+					map[string]interface{}{"destination": map[string]interface{}{"host": "reviews", "subset": "v2"}, "weight": int64(10)},
+				}},
+			},
+			"tcp": []interface{}{
+				map[string]interface{}{"route": []interface{}{
+					map[string]interface{}{"destination": map[string]interface{}{"host": "mysql.db.svc.cluster.local", "port": map[string]interface{}{"number": int64(3306)}}},
+				}},
+			},
+			"tls": []interface{}{
+				map[string]interface{}{"route": []interface{}{
+					map[string]interface{}{"destination": map[string]interface{}{"host": "api.example.com"}},
+				}},
+			},
+		},
+	}}
+
+	vs, err := ConvertToTyped[*virtualService](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert virtualservice: %v", err)
+	}
+	if services := virtualServiceDestinations(vs); !reflect.DeepEqual(services, []string{"bookinfo/reviews", "db/mysql"}) {
+		t.Errorf("Unexpected destination services %v", services)
+	}
+	if gateways := virtualServiceGateways(vs); !reflect.DeepEqual(gateways, []string{"bookinfo/public", "istio-system/ingress"}) {
+		t.Errorf("Unexpected gateways %v", gateways)
+	}
+}
+
+func TestPeerAuthenticationModes(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "security.istio.io/v1beta1",
+		"kind":       "PeerAuthentication",
+		"metadata":   map[string]interface{}{"name": "default", "namespace": "bookinfo", "uid": "pa-1"},
+		"spec": map[string]interface{}{
+			"portLevelMtls": map[string]interface{}{
+				"9090": map[string]interface{}{"mode": "DISABLE"},
+				"8080": map[string]interface{}{"mode": "PERMISSIVE"},
+			},
+		},
+	}}
+
+	pa, err := ConvertToTyped[*peerAuthentication](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert peerauthentication: %v", err)
+	}
+	if mode := mtlsMode(pa); mode != "UNSET" {
+		t.Errorf("Expected UNSET, got %s", mode)
+	}
+	if ports := portLevelMTLS(pa); !reflect.DeepEqual(ports, []string{"8080=PERMISSIVE", "9090=DISABLE"}) {
+		t.Errorf("Unexpected port level modes %v", ports)
+	}
+}
+
+func TestFormatIstioServer(t *testing.T) {
+	var server istioServer
+	server.Port.Number = 443
+	server.Port.Protocol = "HTTPS"
+	server.Hosts = []string{"bookinfo/*.example.com", "api.example.com"}
+	if formatted := formatIstioServer(server); formatted != "port=443;protocol=HTTPS;hosts=bookinfo/*.example.com,api.example.com" {
+		t.Errorf("Unexpected server %s", formatted)
+	}
+}
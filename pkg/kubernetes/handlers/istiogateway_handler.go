@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// IstioGatewayHandler tracks Istio Gateways. They are stored as IstioGateway
+// nodes to keep them apart from Gateway API Gateways.
+type IstioGatewayHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var istioGatewayGVR = istioResource("networking.istio.io", "gateways")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "IstioGateway",
+		GVR:               istioGatewayGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewIstioGatewayHandler(cfg)
+		},
+	})
+}
+
+func NewIstioGatewayHandler(cfg *config.Config) *IstioGatewayHandler {
+	return &IstioGatewayHandler{
+		BaseHandler:  NewBaseHandler(istioGatewayGVR, "IstioGateway", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *IstioGatewayHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	gw, err := ConvertToTyped[*istioGateway](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert istio gateway: %w", err)
+	}
+
+	servers := make([]string, 0, len(gw.Spec.Servers))
+	for _, server := range gw.Spec.Servers {
+		servers = append(servers, formatIstioServer(server))
+	}
+
+	uid := string(gw.UID)
+	properties := map[string]interface{}{
+		"name":              gw.Name,
+		"uid":               uid,
+		"namespace":         gw.Namespace,
+		"creationTimestamp": gw.CreationTimestamp.String(),
+		"labels":            gw.Labels,
+		"annotations":       gw.Annotations,
+		"selector":          gw.Spec.Selector,
+		"servers":           servers,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"IstioGateway"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert istio gateway %s: %w", gw.Name, err)
+	}
+
+	if err := linkReferrers(ctx, neo4jClient, "VirtualService", "IstioGateway", uid, gw.Name, gw.Namespace, h.GetClusterName(), "ATTACHED_TO", "istioGateways"); err != nil {
+		fmt.Printf("Warning: failed to link VirtualServices to Istio Gateway %s: %v\n", gw.Name, err)
+	}
+
+	return nil
+}
+
+func (h *IstioGatewayHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	gw, err := ConvertToTyped[*istioGateway](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert istio gateway: %w", err)
+	}
+	return HandleResourceDelete(ctx, "IstioGateway", string(gw.UID), neo4jClient)
+}
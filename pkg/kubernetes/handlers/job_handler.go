@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,27 +20,43 @@ type JobHandler struct {
 	instanceHash string
 }
 
+var jobGVR = schema.GroupVersionResource{
+	Group:    "batch",
+	Version:  "v1",
+	Resource: "jobs",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Job",
+		GVR:               jobGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewJobHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewJobHandler(clientset *kubernetes.Clientset, cfg *config.Config) *JobHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "batch",
-		Version:  "v1",
-		Resource: "jobs",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Job", "Job")
 	return &JobHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Job", cfg),
+		BaseHandler:  NewBaseHandler(jobGVR, "Job", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *JobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *JobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	job, err := ConvertToTyped[*batchv1.Job](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert job: %w", err)
 	}
 
+	podSpec := job.Spec.Template.Spec
+	registries := podSpecRegistries(podSpec)
+
 	properties := map[string]interface{}{
 		"name":                    job.Name,
 		"uid":                     string(job.UID),
@@ -53,6 +70,8 @@ func (h *JobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		"activeDeadlineSeconds":   job.Spec.ActiveDeadlineSeconds,
 		"ttlSecondsAfterFinished": job.Spec.TTLSecondsAfterFinished,
 		"clusterName":             h.GetClusterName(),
+		"registries":              registries,
+		"imagePullSecrets":        pullSecretNames(podSpec),
 		"instanceHash":            h.instanceHash,
 	}
 
@@ -67,29 +86,10 @@ func (h *JobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		properties["completionTime"] = job.Status.CompletionTime.String()
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Job"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert job %s: %w", job.Name, err)
-	}
-
-	// Create relationships based on owner references for all supported types
-	if job.OwnerReferences != nil {
-		for _, ownerRef := range job.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Job", "uid", string(job.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Job %s and %s %s: %v\n", job.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("Job", string(job.UID), job.OwnerReferences)
 
 	// Create relationships with pods
-	if job.Spec.Selector != nil {
+	if job.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(job.Spec.Selector),
 		})
@@ -98,16 +98,23 @@ func (h *JobHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "Job", "uid", string(job.UID), "MANAGES", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between job %s and pod %s: %w", job.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("Job", "uid", string(job.UID), "MANAGES", "Pod", "uid", string(pod.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Job"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert job %s: %w", job.Name, err)
+	}
+
+	rollupStorageForWorkload(ctx, neo4jClient, "Job", string(job.UID), job.Name)
+
+	// Link the registries the pod template pulls images from
+	linkRegistries(ctx, neo4jClient, "Job", string(job.UID), "PULLS_FROM", h.GetClusterName(), h.instanceHash, registries)
+
 	return nil
 }
 
-func (h *JobHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *JobHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	job, err := ConvertToTyped[*batchv1.Job](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert job: %w", err)
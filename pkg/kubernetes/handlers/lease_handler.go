@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeLeaseNamespace holds the kubelet heartbeat Leases, one per Node. Node
+// health is already tracked by the Node handler, so these are not stored.
+const nodeLeaseNamespace = "kube-node-lease"
+
+// LeaseHandler tracks coordination Leases, which controllers renew while they
+// hold leadership. A Lease that is no longer renewed points at a controller
+// that stopped working.
+type LeaseHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var leaseGVR = schema.GroupVersionResource{
+	Group:    "coordination.k8s.io",
+	Version:  "v1",
+	Resource: "leases",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Lease",
+		GVR:               leaseGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewLeaseHandler(cfg)
+		},
+	})
+}
+
+func NewLeaseHandler(cfg *config.Config) *LeaseHandler {
+	return &LeaseHandler{
+		BaseHandler:  NewBaseHandler(leaseGVR, "Lease", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *LeaseHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	lease, err := ConvertToTyped[*coordinationv1.Lease](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert lease: %w", err)
+	}
+	if lease.Namespace == nodeLeaseNamespace {
+		return nil
+	}
+
+	properties := map[string]interface{}{
+		"name":              lease.Name,
+		"uid":               string(lease.UID),
+		"namespace":         lease.Namespace,
+		"creationTimestamp": lease.CreationTimestamp.String(),
+		"labels":            lease.Labels,
+		"annotations":       lease.Annotations,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	for key, value := range leaseSpecProperties(lease.Spec) {
+		properties[key] = value
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Lease"}, properties, "uid", ownerRelationships("Lease", string(lease.UID), lease.OwnerReferences)); err != nil {
+		return fmt.Errorf("failed to upsert lease %s: %w", lease.Name, err)
+	}
+	return nil
+}
+
+func (h *LeaseHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	lease, err := ConvertToTyped[*coordinationv1.Lease](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert lease: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Lease", string(lease.UID), neo4jClient)
+}
+
+// leaseSpecProperties returns the holder and timing of a Lease. Leader
+// election renews Leases every few seconds, so renewTime is truncated to the
+// minute; otherwise every renewal would rewrite the node.
+func leaseSpecProperties(spec coordinationv1.LeaseSpec) map[string]interface{} {
+	properties := make(map[string]interface{})
+	if spec.HolderIdentity != nil {
+		properties["holderIdentity"] = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		properties["leaseDurationSeconds"] = int64(*spec.LeaseDurationSeconds)
+	}
+	if spec.LeaseTransitions != nil {
+		properties["leaseTransitions"] = int64(*spec.LeaseTransitions)
+	}
+	if spec.AcquireTime != nil {
+		properties["acquireTime"] = leaseTime(spec.AcquireTime, time.Second)
+	}
+	if spec.RenewTime != nil {
+		properties["renewTime"] = leaseTime(spec.RenewTime, time.Minute)
+	}
+	return properties
+}
+
+func leaseTime(t *metav1.MicroTime, precision time.Duration) string {
+	return t.UTC().Truncate(precision).Format(time.RFC3339)
+}
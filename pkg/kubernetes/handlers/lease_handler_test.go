@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLeaseSpecProperties(t *testing.T) {
+	holder := "controller-7d9f_0b1c"
+	duration := int32(15)
+	renew := metav1.NewMicroTime(time.Date(2024, 5, 1, 10, 3, 42, 500, time.UTC))
+
+	properties := leaseSpecProperties(coordinationv1.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &duration,
+		RenewTime:            &renew,
+	})
+	expected := map[string]interface{}{
+		"holderIdentity":       holder,
+		"leaseDurationSeconds": int64(15),
+		"renewTime":            "2024-05-01T10:03:00Z",
+	}
+	if len(properties) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, properties)
+	}
+	for key, value := range expected {
+		if properties[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, properties[key])
+		}
+	}
+
+	// Released leases have no holder
+	if properties := leaseSpecProperties(coordinationv1.LeaseSpec{}); len(properties) != 0 {
+		t.Errorf("Expected no properties for an empty lease, got %v", properties)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type LimitRangeHandler struct {
@@ -16,20 +18,33 @@ type LimitRangeHandler struct {
 	instanceHash string
 }
 
+var limitRangeGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "limitranges",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "LimitRange",
+		GVR:               limitRangeGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewLimitRangeHandler(cfg)
+		},
+	})
+}
+
 func NewLimitRangeHandler(cfg *config.Config) *LimitRangeHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "limitranges",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("LimitRange", "LimitRange")
 	return &LimitRangeHandler{
-		BaseHandler:  NewBaseHandler(gvr, "LimitRange", cfg),
+		BaseHandler:  NewBaseHandler(limitRangeGVR, "LimitRange", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *LimitRangeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *LimitRangeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	lr, err := ConvertToTyped[*corev1.LimitRange](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert limitrange: %w", err)
@@ -47,31 +62,14 @@ func (h *LimitRangeHandler) HandleCreate(ctx context.Context, obj interface{}, n
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"LimitRange"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"LimitRange"}, properties, "uid", ownerRelationships("LimitRange", string(lr.UID), lr.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert limitrange %s: %w", lr.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if lr.OwnerReferences != nil {
-		for _, ownerRef := range lr.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"LimitRange", "uid", string(lr.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between LimitRange %s and %s %s: %v\n", lr.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
-
 	return nil
 }
 
-func (h *LimitRangeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *LimitRangeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	lr, err := ConvertToTyped[*corev1.LimitRange](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert limitrange: %w", err)
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MutatingWebhookConfigurationHandler tracks mutating admission webhooks,
+// linking them to the Services backing them
+type MutatingWebhookConfigurationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var mutatingWebhookConfigurationGVR = admissionWebhookResource("mutatingwebhookconfigurations")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "MutatingWebhookConfiguration",
+		GVR:               mutatingWebhookConfigurationGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewMutatingWebhookConfigurationHandler(cfg)
+		},
+	})
+}
+
+func NewMutatingWebhookConfigurationHandler(cfg *config.Config) *MutatingWebhookConfigurationHandler {
+	RegisterOwnerKind("MutatingWebhookConfiguration", "MutatingWebhookConfiguration")
+	return &MutatingWebhookConfigurationHandler{
+		BaseHandler:  NewBaseHandler(mutatingWebhookConfigurationGVR, "MutatingWebhookConfiguration", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *MutatingWebhookConfigurationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	configuration, err := ConvertToTyped[*admissionregistrationv1.MutatingWebhookConfiguration](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert mutatingwebhookconfiguration: %w", err)
+	}
+
+	webhooks := make([]admissionWebhook, 0, len(configuration.Webhooks))
+	for _, webhook := range configuration.Webhooks {
+		webhooks = append(webhooks, admissionWebhook{
+			Name:           webhook.Name,
+			ClientConfig:   webhook.ClientConfig,
+			FailurePolicy:  webhook.FailurePolicy,
+			SideEffects:    webhook.SideEffects,
+			TimeoutSeconds: webhook.TimeoutSeconds,
+		})
+	}
+
+	uid := string(configuration.UID)
+	properties, services := webhookProperties(webhooks)
+	reinvocationPolicies := make([]string, 0, len(configuration.Webhooks))
+	for _, webhook := range configuration.Webhooks {
+		policy := admissionregistrationv1.NeverReinvocationPolicy
+		if webhook.ReinvocationPolicy != nil {
+			policy = *webhook.ReinvocationPolicy
+		}
+		reinvocationPolicies = append(reinvocationPolicies, webhook.Name+"="+string(policy))
+	}
+	properties["reinvocationPolicies"] = reinvocationPolicies
+	properties["name"] = configuration.Name
+	properties["uid"] = uid
+	properties["creationTimestamp"] = configuration.CreationTimestamp.String()
+	properties["labels"] = configuration.Labels
+	properties["annotations"] = configuration.Annotations
+	properties["clusterName"] = h.GetClusterName()
+	properties["instanceHash"] = h.instanceHash
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"MutatingWebhookConfiguration"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert mutatingwebhookconfiguration %s: %w", configuration.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "MutatingWebhookConfiguration", uid, "CALLS", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create CALLS relationships for MutatingWebhookConfiguration %s: %v\n", configuration.Name, err)
+	}
+
+	return nil
+}
+
+func (h *MutatingWebhookConfigurationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	configuration, err := ConvertToTyped[*admissionregistrationv1.MutatingWebhookConfiguration](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert mutatingwebhookconfiguration: %w", err)
+	}
+	return HandleResourceDelete(ctx, "MutatingWebhookConfiguration", string(configuration.UID), neo4jClient)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type NamespaceHandler struct {
@@ -16,25 +18,40 @@ type NamespaceHandler struct {
 	instanceHash string
 }
 
+var namespaceGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "namespaces",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Namespace",
+		GVR:               namespaceGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewNamespaceHandler(cfg)
+		},
+	})
+}
+
 func NewNamespaceHandler(cfg *config.Config) *NamespaceHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "namespaces",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Namespace", "Namespace")
 	return &NamespaceHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Namespace", cfg),
+		BaseHandler:  NewBaseHandler(namespaceGVR, "Namespace", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *NamespaceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NamespaceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ns, err := ConvertToTyped[*corev1.Namespace](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert namespace: %w", err)
 	}
 
+	parent := hncParent(ns.Name, ns.Labels, ns.Annotations)
+
 	properties := map[string]interface{}{
 		"name":              ns.Name,
 		"uid":               string(ns.UID),
@@ -42,35 +59,28 @@ func (h *NamespaceHandler) HandleCreate(ctx context.Context, obj interface{}, ne
 		"labels":            ns.Labels,
 		"annotations":       ns.Annotations,
 		"status":            string(ns.Status.Phase),
+		"hncParent":         parent,
 		"clusterName":       h.GetClusterName(),
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Namespace"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Namespace"}, properties, "uid", ownerRelationships("Namespace", string(ns.UID), ns.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert namespace %s: %w", ns.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if ns.OwnerReferences != nil {
-		for _, ownerRef := range ns.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Namespace", "uid", string(ns.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Namespace %s and %s %s: %v\n", ns.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	// Link the namespace into the HNC hierarchy from both sides, since the
+	// parent may be ingested before or after its children
+	if err := linkNamespaceParent(ctx, neo4jClient, h.GetClusterName(), ns.Name, parent); err != nil {
+		fmt.Printf("Warning: failed to link Namespace %s to parent %s: %v\n", ns.Name, parent, err)
+	}
+	if err := linkNamespaceChildren(ctx, neo4jClient, h.GetClusterName(), ns.Name); err != nil {
+		fmt.Printf("Warning: failed to link child namespaces of Namespace %s: %v\n", ns.Name, err)
 	}
 
 	return nil
 }
 
-func (h *NamespaceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NamespaceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	ns, err := ConvertToTyped[*corev1.Namespace](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert namespace: %w", err)
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"testing"
 
-	"kubegraph/config"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph/graphtest"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -140,3 +144,53 @@ func TestNamespaceHandlerOwnerKindRegistration(t *testing.T) {
 		t.Errorf("Expected instance hash to be 'test-hash', got %s", handler.instanceHash)
 	}
 }
+
+func TestHNCParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    string
+	}{
+		{"not managed by HNC", map[string]string{"team": "a"}, nil, ""},
+		{"root namespace", map[string]string{"team-a.tree.hnc.x-k8s.io/depth": "0"}, nil, ""},
+		{"child namespace", map[string]string{
+			"team-a-dev.tree.hnc.x-k8s.io/depth": "0",
+			"team-a.tree.hnc.x-k8s.io/depth":     "1",
+			"org.tree.hnc.x-k8s.io/depth":        "2",
+		}, nil, "team-a"},
+		{"subnamespace annotation", nil, map[string]string{"hnc.x-k8s.io/subnamespace-of": "team-a"}, "team-a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hncParent("team-a-dev", test.labels, test.annotations); got != test.expected {
+				t.Errorf("Expected parent %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestNamespaceHandlerLinksHierarchy(t *testing.T) {
+	store := graphtest.NewStore()
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-a-dev",
+			UID:         "ns-1",
+			Annotations: map[string]string{"hnc.x-k8s.io/subnamespace-of": "team-a"},
+		},
+		Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+	}
+	if err := NewNamespaceHandler(testConfig()).HandleCreate(context.Background(), ns, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Namespace", "ns-1", map[string]interface{}{"status": "Active", "hncParent": "team-a"})
+	// The namespace is linked to its parent and to the children synced before it
+	if parents := store.StatementsMatching("MERGE (parent)-[:PARENT_OF]->(child)"); len(parents) != 2 {
+		t.Errorf("Expected statements linking the parent and the children, got %+v", parents)
+	}
+	if stale := store.StatementsMatching("WHERE old.name <> $parent"); len(stale) != 1 || stale[0].Params["parent"] != "team-a" {
+		t.Errorf("Expected the links to previous parents to be removed, got %+v", stale)
+	}
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Relationship types materialized from NetworkPolicy rules. Both point from a
+// pod selected by the policy to the peer pod the rule allows.
+const (
+	allowsIngressFrom = "ALLOWS_INGRESS_FROM"
+	allowsEgressTo    = "ALLOWS_EGRESS_TO"
+)
+
+// networkPolicyPeer is a pod peer of a rule with its selectors in the string
+// form accepted by labels.Parse. An empty selector matches everything and a
+// nil namespace selector means the namespace of the policy.
+type networkPolicyPeer struct {
+	PodSelector       string  `json:"podSelector"`
+	NamespaceSelector *string `json:"namespaceSelector,omitempty"`
+}
+
+// networkPolicyRule is an ingress or egress rule reduced to what is needed to
+// resolve it against the pods in the graph. It is stored on the NetworkPolicy
+// node so the rules can be resolved again when pods or namespaces change.
+type networkPolicyRule struct {
+	Direction string              `json:"direction"`
+	Peers     []networkPolicyPeer `json:"peers"`
+	Ports     []string            `json:"ports"`
+}
+
+// policyPod is a pod as read from the graph for policy resolution
+type policyPod struct {
+	UID       string
+	Namespace string
+	Labels    map[string]string
+}
+
+// networkPolicyEdge is an ALLOWS relationship between two pods. No ports means
+// all ports are allowed.
+type networkPolicyEdge struct {
+	Type  string
+	From  string
+	To    string
+	Ports []string
+}
+
+// selectorString converts a label selector to its labels.Parse form. ok is
+// false for selectors that cannot be converted, which then match nothing.
+func selectorString(selector *metav1.LabelSelector) (string, bool) {
+	converted, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", false
+	}
+	return converted.String(), true
+}
+
+// formatPolicyPort describes a port as "protocol/port", "protocol/port-endPort"
+// or just "protocol" when the rule allows every port of the protocol
+func formatPolicyPort(port networkingv1.NetworkPolicyPort) string {
+	protocol := "TCP"
+	if port.Protocol != nil {
+		protocol = string(*port.Protocol)
+	}
+	if port.Port == nil {
+		return protocol
+	}
+	if port.EndPort != nil {
+		return fmt.Sprintf("%s/%s-%d", protocol, port.Port.String(), *port.EndPort)
+	}
+	return protocol + "/" + port.Port.String()
+}
+
+// networkPolicyPeers returns the pod peers of a rule. IP block peers do not
+// select pods and are skipped.
+func networkPolicyPeers(peers []networkingv1.NetworkPolicyPeer) []networkPolicyPeer {
+	result := make([]networkPolicyPeer, 0, len(peers))
+	for _, peer := range peers {
+		if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+			continue
+		}
+		resolved := networkPolicyPeer{}
+		if peer.PodSelector != nil {
+			selector, ok := selectorString(peer.PodSelector)
+			if !ok {
+				continue
+			}
+			resolved.PodSelector = selector
+		}
+		if peer.NamespaceSelector != nil {
+			selector, ok := selectorString(peer.NamespaceSelector)
+			if !ok {
+				continue
+			}
+			resolved.NamespaceSelector = &selector
+		}
+		result = append(result, resolved)
+	}
+	return result
+}
+
+// networkPolicyRules returns the rules of the policy types the policy
+// enforces. Rules without peers allow traffic from or to anywhere and are not
+// materialized, as that would link every pod of the cluster.
+func networkPolicyRules(policy *networkingv1.NetworkPolicy) []networkPolicyRule {
+	rules := make([]networkPolicyRule, 0)
+	newRule := func(direction string, peers []networkingv1.NetworkPolicyPeer, ports []networkingv1.NetworkPolicyPort) {
+		if len(peers) == 0 {
+			return
+		}
+		rule := networkPolicyRule{Direction: direction, Peers: networkPolicyPeers(peers), Ports: make([]string, 0, len(ports))}
+		for _, port := range ports {
+			rule.Ports = append(rule.Ports, formatPolicyPort(port))
+		}
+		if len(rule.Peers) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+
+	ingress, egress := enforcedPolicyTypes(policy)
+	if ingress {
+		for _, rule := range policy.Spec.Ingress {
+			newRule(string(networkingv1.PolicyTypeIngress), rule.From, rule.Ports)
+		}
+	}
+	if egress {
+		for _, rule := range policy.Spec.Egress {
+			newRule(string(networkingv1.PolicyTypeEgress), rule.To, rule.Ports)
+		}
+	}
+	return rules
+}
+
+// enforcedPolicyTypes reports whether the policy applies to ingress and egress
+// traffic. Without explicit policy types a policy always applies to ingress,
+// and to egress when it has egress rules.
+func enforcedPolicyTypes(policy *networkingv1.NetworkPolicy) (bool, bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	var ingress, egress bool
+	for _, policyType := range policy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// matchesSelector reports whether the labels match a selector in labels.Parse form
+func matchesSelector(selector string, podLabels map[string]string) bool {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return false
+	}
+	return parsed.Matches(labels.Set(podLabels))
+}
+
+// networkPolicyEdges resolves the rules of a policy against the pods and
+// namespace labels of the cluster. Edges allowed by several rules are merged
+// and allow the union of their ports.
+func networkPolicyEdges(namespace, podSelector string, rules []networkPolicyRule, pods []policyPod, namespaceLabels map[string]map[string]string) []networkPolicyEdge {
+	targets := make([]policyPod, 0)
+	for _, pod := range pods {
+		if pod.Namespace == namespace && matchesSelector(podSelector, pod.Labels) {
+			targets = append(targets, pod)
+		}
+	}
+	if len(targets) == 0 {
+		return []networkPolicyEdge{}
+	}
+
+	type edgeKey struct{ relType, from, to string }
+	ports := make(map[edgeKey]map[string]bool)
+	for _, rule := range rules {
+		relType := allowsIngressFrom
+		if rule.Direction == string(networkingv1.PolicyTypeEgress) {
+			relType = allowsEgressTo
+		}
+		for _, peer := range networkPolicyPeerPods(namespace, rule.Peers, pods, namespaceLabels) {
+			for _, target := range targets {
+				if target.UID == peer.UID {
+					continue
+				}
+				key := edgeKey{relType, target.UID, peer.UID}
+				existing, found := ports[key]
+				switch {
+				case found && len(existing) == 0:
+					// Already allows all ports
+				case len(rule.Ports) == 0:
+					ports[key] = map[string]bool{}
+				default:
+					if existing == nil {
+						existing = make(map[string]bool)
+						ports[key] = existing
+					}
+					for _, port := range rule.Ports {
+						existing[port] = true
+					}
+				}
+			}
+		}
+	}
+
+	edges := make([]networkPolicyEdge, 0, len(ports))
+	for key, allowed := range ports {
+		edges = append(edges, networkPolicyEdge{Type: key.relType, From: key.from, To: key.to, Ports: sortedKeys(allowed)})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Type != edges[j].Type {
+			return edges[i].Type < edges[j].Type
+		}
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// networkPolicyPeerPods returns the pods selected by any of the peers
+func networkPolicyPeerPods(namespace string, peers []networkPolicyPeer, pods []policyPod, namespaceLabels map[string]map[string]string) []policyPod {
+	selected := make([]policyPod, 0)
+	for _, pod := range pods {
+		for _, peer := range peers {
+			if peer.NamespaceSelector == nil {
+				if pod.Namespace != namespace {
+					continue
+				}
+			} else if !matchesSelector(*peer.NamespaceSelector, namespaceLabels[pod.Namespace]) {
+				continue
+			}
+			if matchesSelector(peer.PodSelector, pod.Labels) {
+				selected = append(selected, pod)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// loadPolicyPods reads the running pods and the namespace labels of a cluster
+func loadPolicyPods(ctx context.Context, neo4jClient graph.Store, clusterName string) ([]policyPod, map[string]map[string]string, error) {
+	params := map[string]interface{}{"clusterName": clusterName}
+	podRecords, err := neo4jClient.Read(ctx, `
+		MATCH (p:Pod {clusterName: $clusterName})
+		WHERE NOT coalesce(p.status, '') IN ['Succeeded', 'Failed']
+		RETURN p.uid AS uid, p.namespace AS namespace, p.labels AS labels`, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespaceRecords, err := neo4jClient.Read(ctx, `
+		MATCH (n:Namespace {clusterName: $clusterName})
+		RETURN n.name AS namespace, n.labels AS labels`, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decodeLabels := func(record map[string]interface{}) map[string]string {
+		var decoded map[string]string
+		if value := record["labels"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &decoded)
+		}
+		return decoded
+	}
+
+	pods := make([]policyPod, 0, len(podRecords))
+	for _, record := range podRecords {
+		pods = append(pods, policyPod{UID: fmt.Sprint(record["uid"]), Namespace: fmt.Sprint(record["namespace"]), Labels: decodeLabels(record)})
+	}
+	namespaceLabels := make(map[string]map[string]string, len(namespaceRecords))
+	for _, record := range namespaceRecords {
+		namespaceLabels[fmt.Sprint(record["namespace"])] = decodeLabels(record)
+	}
+	return pods, namespaceLabels, nil
+}
+
+// writeNetworkPolicyEdges replaces the ALLOWS relationships of a policy. The
+// relationships carry the policy uid so each policy only replaces its own.
+func writeNetworkPolicyEdges(ctx context.Context, neo4jClient graph.Store, uid, name string, edges []networkPolicyEdge) error {
+	byType := map[string][]map[string]interface{}{allowsIngressFrom: {}, allowsEgressTo: {}}
+	for _, edge := range edges {
+		byType[edge.Type] = append(byType[edge.Type], map[string]interface{}{
+			"from":  edge.From,
+			"to":    edge.To,
+			"ports": edge.Ports,
+		})
+	}
+
+	statements := []graph.Statement{{
+		Query: `
+			MATCH (from:Pod)-[old:ALLOWS_INGRESS_FROM|ALLOWS_EGRESS_TO {policyUid: $uid}]->(to:Pod)` +
+			graph.DeleteRelationship("old", "from", "uid", "to", "uid"),
+		Params: map[string]interface{}{"uid": uid},
+	}}
+	for _, relType := range []string{allowsIngressFrom, allowsEgressTo} {
+		if len(byType[relType]) == 0 {
+			continue
+		}
+		statements = append(statements, graph.Statement{
+			Query: `
+				UNWIND $edges AS edge
+				MATCH (from:Pod {uid: edge.from})
+				MATCH (to:Pod {uid: edge.to})` +
+				graph.CreateRelationship("from", "uid", relType, "{policy: $name, policyUid: $uid, ports: edge.ports}", "to", "uid"),
+			Params: map[string]interface{}{"uid": uid, "name": name, "edges": byType[relType]},
+		})
+	}
+	return neo4jClient.Write(ctx, statements...)
+}
+
+// resolveNetworkPolicy materializes the ALLOWS relationships of one policy
+func resolveNetworkPolicy(ctx context.Context, neo4jClient graph.Store, uid, name, namespace, podSelector string, rules []networkPolicyRule, clusterName string) error {
+	pods, namespaceLabels, err := loadPolicyPods(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+	return writeNetworkPolicyEdges(ctx, neo4jClient, uid, name, networkPolicyEdges(namespace, podSelector, rules, pods, namespaceLabels))
+}
+
+// ResolveNetworkPolicies materializes the ALLOWS relationships of every
+// NetworkPolicy of a cluster from the rules stored on the policies. It is run
+// periodically so the relationships follow pod and namespace label changes.
+func ResolveNetworkPolicies(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (np:NetworkPolicy {clusterName: $clusterName})
+		WHERE np.peerRules IS NOT NULL
+		RETURN np.uid AS uid, np.name AS name, np.namespace AS namespace,
+		       np.podSelector AS podSelector, np.peerRules AS peerRules`,
+		map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return err
+	}
+
+	pods, namespaceLabels, err := loadPolicyPods(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, record := range records {
+		values := make(map[string]string)
+		for _, key := range []string{"uid", "name", "namespace", "podSelector", "peerRules"} {
+			if value := record[key]; value != nil {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		var rules []networkPolicyRule
+		if err := json.Unmarshal([]byte(values["peerRules"]), &rules); err != nil {
+			failed = append(failed, values["namespace"]+"/"+values["name"])
+			continue
+		}
+		edges := networkPolicyEdges(values["namespace"], values["podSelector"], rules, pods, namespaceLabels)
+		if err := writeNetworkPolicyEdges(ctx, neo4jClient, values["uid"], values["name"], edges); err != nil {
+			failed = append(failed, values["namespace"]+"/"+values["name"])
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to resolve network policies %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
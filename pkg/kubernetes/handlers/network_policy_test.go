@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestNetworkPolicyRules(t *testing.T) {
+	tcp := corev1.ProtocolTCP
+	udp := corev1.ProtocolUDP
+	port := intstr.FromInt(8080)
+	dns := intstr.FromInt(53)
+	endPort := int32(9000)
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port, EndPort: &endPort}},
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+						{NamespaceSelector: &metav1.LabelSelector{}},
+						{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+					},
+				},
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}}},
+			},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: &dns}}, To: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{}}}},
+			},
+		},
+	}
+
+	everything := ""
+	expected := []networkPolicyRule{
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{PodSelector: "app=web"}, {NamespaceSelector: &everything}}, Ports: []string{"TCP/8080-9000"}},
+		{Direction: "Egress", Peers: []networkPolicyPeer{{NamespaceSelector: &everything}}, Ports: []string{"UDP/53"}},
+	}
+	if got := networkPolicyRules(policy); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	policy.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if got := networkPolicyRules(policy); len(got) != 1 || got[0].Direction != "Ingress" {
+		t.Errorf("Expected only the ingress rule when egress is not enforced, got %+v", got)
+	}
+}
+
+func TestNetworkPolicyEdges(t *testing.T) {
+	pods := []policyPod{
+		{UID: "api-1", Namespace: "shop", Labels: map[string]string{"app": "api"}},
+		{UID: "api-2", Namespace: "shop", Labels: map[string]string{"app": "api"}},
+		{UID: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+		{UID: "web-2", Namespace: "staging", Labels: map[string]string{"app": "web"}},
+		{UID: "prom-1", Namespace: "monitoring", Labels: map[string]string{"app": "prometheus"}},
+	}
+	namespaceLabels := map[string]map[string]string{
+		"shop":       {"kubernetes.io/metadata.name": "shop"},
+		"staging":    {"kubernetes.io/metadata.name": "staging"},
+		"monitoring": {"kubernetes.io/metadata.name": "monitoring", "team": "platform"},
+	}
+	platform := "team=platform"
+	rules := []networkPolicyRule{
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{PodSelector: "app=web"}}, Ports: []string{"TCP/8080"}},
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{PodSelector: "app=web"}}, Ports: []string{"TCP/8443"}},
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{NamespaceSelector: &platform}}, Ports: []string{}},
+		{Direction: "Egress", Peers: []networkPolicyPeer{{PodSelector: "app=api"}}, Ports: []string{"TCP/5432"}},
+	}
+
+	expected := []networkPolicyEdge{
+		{Type: allowsEgressTo, From: "api-1", To: "api-2", Ports: []string{"TCP/5432"}},
+		{Type: allowsEgressTo, From: "api-2", To: "api-1", Ports: []string{"TCP/5432"}},
+		{Type: allowsIngressFrom, From: "api-1", To: "prom-1", Ports: []string{}},
+		{Type: allowsIngressFrom, From: "api-1", To: "web-1", Ports: []string{"TCP/8080", "TCP/8443"}},
+		{Type: allowsIngressFrom, From: "api-2", To: "prom-1", Ports: []string{}},
+		{Type: allowsIngressFrom, From: "api-2", To: "web-1", Ports: []string{"TCP/8080", "TCP/8443"}},
+	}
+	if got := networkPolicyEdges("shop", "app=api", rules, pods, namespaceLabels); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	if got := networkPolicyEdges("shop", "app=missing", rules, pods, namespaceLabels); len(got) != 0 {
+		t.Errorf("Expected no edges for a policy selecting no pods, got %+v", got)
+	}
+}
+
+func TestNetworkPolicyEdgesAllPortsWins(t *testing.T) {
+	pods := []policyPod{
+		{UID: "db-1", Namespace: "shop", Labels: map[string]string{"app": "db"}},
+		{UID: "api-1", Namespace: "shop", Labels: map[string]string{"app": "api"}},
+	}
+	rules := []networkPolicyRule{
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{PodSelector: ""}}, Ports: []string{}},
+		{Direction: "Ingress", Peers: []networkPolicyPeer{{PodSelector: "app=api"}}, Ports: []string{"TCP/5432"}},
+	}
+	expected := []networkPolicyEdge{{Type: allowsIngressFrom, From: "db-1", To: "api-1", Ports: []string{}}}
+	if got := networkPolicyEdges("shop", "app=db", rules, pods, nil); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestNetworkPolicyHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	store.Respond("RETURN p.uid AS uid",
+		map[string]interface{}{"uid": "api-1", "namespace": "shop", "labels": `{"app":"api"}`},
+		map[string]interface{}{"uid": "web-1", "namespace": "shop", "labels": `{"app":"web"}`},
+	)
+	store.Respond("RETURN n.name AS namespace", map[string]interface{}{"namespace": "shop", "labels": `{"kubernetes.io/metadata.name":"shop"}`})
+
+	tcp := corev1.ProtocolTCP
+	port := intstr.FromInt(8080)
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop", UID: "np-1"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+				From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+			}},
+		},
+	}
+	if err := NewNetworkPolicyHandler(testConfig()).HandleCreate(context.Background(), policy, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "NetworkPolicy", "np-1", map[string]interface{}{
+		"podSelector": "app=api",
+		"policyTypes": []string{"Ingress"},
+	})
+	if stale := store.StatementsMatching("[old:ALLOWS_INGRESS_FROM|ALLOWS_EGRESS_TO {policyUid: $uid}]"); len(stale) != 1 {
+		t.Errorf("Expected the previous relationships of the policy to be removed, got %+v", stale)
+	}
+	edges := store.StatementsMatching("CREATE (from)-[:ALLOWS_INGRESS_FROM")
+	expected := []map[string]interface{}{{"from": "api-1", "to": "web-1", "ports": []string{"TCP/8080"}}}
+	if len(edges) != 1 || !reflect.DeepEqual(edges[0].Params["edges"], expected) || edges[0].Params["name"] != "api" {
+		t.Errorf("Expected the ingress from web-1 to api-1 to be allowed, got %+v", edges)
+	}
+	if egress := store.StatementsMatching("CREATE (from)-[:ALLOWS_EGRESS_TO"); len(egress) != 0 {
+		t.Errorf("Expected no egress relationships, got %+v", egress)
+	}
+}
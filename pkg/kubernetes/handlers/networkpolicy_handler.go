@@ -4,30 +4,45 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type NetworkPolicyHandler struct {
 	BaseHandler
 }
 
+var networkPolicyGVR = schema.GroupVersionResource{
+	Group:    "networking.k8s.io",
+	Version:  "v1",
+	Resource: "networkpolicies",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "NetworkPolicy",
+		GVR:               networkPolicyGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewNetworkPolicyHandler(cfg)
+		},
+	})
+}
+
 func NewNetworkPolicyHandler(cfg *config.Config) *NetworkPolicyHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "networking.k8s.io",
-		Version:  "v1",
-		Resource: "networkpolicies",
-	}
 	RegisterOwnerKind("NetworkPolicy", "NetworkPolicy")
 	return &NetworkPolicyHandler{
-		BaseHandler: NewBaseHandler(gvr, "NetworkPolicy", cfg),
+		BaseHandler: NewBaseHandler(networkPolicyGVR, "NetworkPolicy", cfg),
 	}
 }
 
-func (h *NetworkPolicyHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NetworkPolicyHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	networkPolicy, err := ConvertToTyped[*networkingv1.NetworkPolicy](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert networkpolicy: %w", err)
@@ -137,17 +152,37 @@ func (h *NetworkPolicyHandler) HandleCreate(ctx context.Context, obj interface{}
 		"clusterName": h.GetClusterName(),
 	}
 
+	// Keep the selectors in a form the periodic resolution can evaluate again
+	podSelector, resolvable := selectorString(&networkPolicy.Spec.PodSelector)
+	rules := networkPolicyRules(networkPolicy)
+	if resolvable {
+		properties["podSelector"] = podSelector
+		properties["peerRules"] = rules
+	}
+
 	if err := neo4jClient.UpsertNode(ctx, []string{"NetworkPolicy"}, properties, "uid"); err != nil {
 		return fmt.Errorf("failed to upsert networkpolicy %s: %w", networkPolicy.Name, err)
 	}
 
+	// Materialize the traffic the policy allows between pods
+	if resolvable {
+		if err := resolveNetworkPolicy(ctx, neo4jClient, string(networkPolicy.UID), networkPolicy.Name, networkPolicy.Namespace, podSelector, rules, h.GetClusterName()); err != nil {
+			fmt.Printf("Warning: failed to resolve NetworkPolicy %s: %v\n", networkPolicy.Name, err)
+		}
+	} else {
+		fmt.Printf("Warning: NetworkPolicy %s has an invalid pod selector, not resolving it\n", networkPolicy.Name)
+	}
+
 	return nil
 }
 
-func (h *NetworkPolicyHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NetworkPolicyHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	networkPolicy, err := ConvertToTyped[*networkingv1.NetworkPolicy](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert networkpolicy: %w", err)
 	}
+	if err := writeNetworkPolicyEdges(ctx, neo4jClient, string(networkPolicy.UID), networkPolicy.Name, nil); err != nil {
+		fmt.Printf("Warning: failed to remove relationships of NetworkPolicy %s: %v\n", networkPolicy.Name, err)
+	}
 	return HandleResourceDelete(ctx, "NetworkPolicy", string(networkPolicy.UID), neo4jClient)
 }
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"strings"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type NodeHandler struct {
@@ -17,20 +19,33 @@ type NodeHandler struct {
 	instanceHash string
 }
 
+var nodeGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "nodes",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Node",
+		GVR:               nodeGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewNodeHandler(cfg)
+		},
+	})
+}
+
 func NewNodeHandler(cfg *config.Config) *NodeHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "nodes",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Node", "Node")
 	return &NodeHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Node", cfg),
+		BaseHandler:  NewBaseHandler(nodeGVR, "Node", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *NodeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NodeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	node, err := ConvertToTyped[*corev1.Node](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert node: %w", err)
@@ -93,6 +108,11 @@ func (h *NodeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCl
 		"phase":         string(node.Status.Phase),
 	}
 
+	// OS patch level and reboot state
+	for key, value := range nodeMaintenanceProperties(node.Labels, node.Annotations) {
+		properties[key] = value
+	}
+
 	// Add ephemeral storage if available
 	if ephemeralStorage, ok := capacity["ephemeral-storage"]; ok {
 		properties["capacityEphemeralStorage"] = ephemeralStorage.String()
@@ -113,31 +133,19 @@ func (h *NodeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCl
 		}
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Node"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Node"}, properties, "uid", ownerRelationships("Node", string(node.UID), node.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert node %s: %w", node.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if node.OwnerReferences != nil {
-		for _, ownerRef := range node.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Node", "uid", string(node.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Node %s and %s %s: %v\n", node.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	// Restore the requests of the pods scheduled on the node
+	if err := rollupRequestsForNode(ctx, neo4jClient, string(node.UID)); err != nil {
+		fmt.Printf("Warning: failed to roll up requests of node %s: %v\n", node.Name, err)
 	}
 
 	return nil
 }
 
-func (h *NodeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *NodeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	node, err := ConvertToTyped[*corev1.Node](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert node: %w", err)
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeMaintenanceProperties(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		required    bool
+		inProgress  bool
+		source      string
+	}{
+		{"no maintenance tooling", nil, nil, false, false, ""},
+		{"kured reboot needed", nil, map[string]string{
+			"kured.dev/kured-most-recent-reboot-needed": "2024-05-01T10:00:00Z",
+		}, true, false, "kured"},
+		{"legacy kured reboot in progress", nil, map[string]string{
+			"weave.works/kured-reboot-in-progress": "2024-05-01T10:05:00Z",
+		}, false, true, "kured"},
+		{"flatcar reboot needed", map[string]string{
+			"flatcar-linux-update.v1.flatcar-linux.net/version": "3815.2.0",
+		}, map[string]string{
+			"flatcar-linux-update.v1.flatcar-linux.net/reboot-needed": "true",
+			"flatcar-linux-update.v1.flatcar-linux.net/new-version":   "3815.2.2",
+		}, true, false, "flatcar-linux-update-operator"},
+		{"flatcar up to date", nil, map[string]string{
+			"flatcar-linux-update.v1.flatcar-linux.net/reboot-needed": "false",
+		}, false, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			properties := nodeMaintenanceProperties(test.labels, test.annotations)
+			if properties["rebootRequired"] != test.required {
+				t.Errorf("Expected rebootRequired %v, got %v", test.required, properties["rebootRequired"])
+			}
+			if properties["rebootInProgress"] != test.inProgress {
+				t.Errorf("Expected rebootInProgress %v, got %v", test.inProgress, properties["rebootInProgress"])
+			}
+			if properties["rebootSource"] != test.source {
+				t.Errorf("Expected rebootSource %q, got %q", test.source, properties["rebootSource"])
+			}
+		})
+	}
+
+	properties := nodeMaintenanceProperties(map[string]string{
+		"feature.node.kubernetes.io/system-os_release.ID":         "ubuntu",
+		"feature.node.kubernetes.io/system-os_release.VERSION_ID": "22.04",
+	}, nil)
+	if properties["osID"] != "ubuntu" || properties["osVersionID"] != "22.04" {
+		t.Errorf("Expected OS release from node-feature-discovery labels, got %v/%v", properties["osID"], properties["osVersionID"])
+	}
+}
+
+func TestNodeHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1", UID: "node-1"},
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+		},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("8"),
+				corev1.ResourceMemory: resource.MustParse("32Gi"),
+				"nvidia.com/gpu":      resource.MustParse("2"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.1.5"}},
+		},
+	}
+	if err := NewNodeHandler(testConfig()).HandleCreate(context.Background(), node, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Node", "node-1", map[string]interface{}{
+		"name":                   "worker-1",
+		"capacityCPU":            "8",
+		"capacityMemory":         "32Gi",
+		"capacitynvidia.com/gpu": "2",
+		"taints":                 []string{"dedicated=gpu:NoSchedule"},
+		"conditions":             map[string]string{"Ready": "True"},
+		"addresses":              map[string]string{"InternalIP": "10.0.1.5"},
+	})
+	// The requests of the pods scheduled on the node are rolled up again
+	if rollups := store.StatementsMatching("MATCH (n:Node {uid: $uid})"); len(rollups) == 0 || rollups[0].Params["uid"] != "node-1" {
+		t.Errorf("Expected the requests of the node to be rolled up, got %+v", store.Statements)
+	}
+}
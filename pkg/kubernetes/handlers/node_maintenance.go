@@ -0,0 +1,64 @@
+package handlers
+
+// Annotations set by reboot coordinators when a node needs a reboot
+const (
+	kuredRebootNeededAnnotation       = "kured.dev/kured-most-recent-reboot-needed"
+	kuredRebootInProgressAnnotation   = "kured.dev/kured-reboot-in-progress"
+	legacyKuredRebootNeededAnnotation = "weave.works/kured-most-recent-reboot-needed"
+	legacyKuredInProgressAnnotation   = "weave.works/kured-reboot-in-progress"
+	flatcarRebootNeededAnnotation     = "flatcar-linux-update.v1.flatcar-linux.net/reboot-needed"
+	flatcarRebootInProgressAnnotation = "flatcar-linux-update.v1.flatcar-linux.net/reboot-in-progress"
+	flatcarNewVersionAnnotation       = "flatcar-linux-update.v1.flatcar-linux.net/new-version"
+	flatcarVersionLabel               = "flatcar-linux-update.v1.flatcar-linux.net/version"
+)
+
+// Labels published by node-feature-discovery describing the operating system
+const (
+	nfdOSIDLabel        = "feature.node.kubernetes.io/system-os_release.ID"
+	nfdOSVersionIDLabel = "feature.node.kubernetes.io/system-os_release.VERSION_ID"
+	nfdKernelLabel      = "feature.node.kubernetes.io/kernel-version.full"
+)
+
+// nodeMaintenanceProperties derives the OS patch level and reboot state of a
+// node from the labels and annotations of common maintenance tools
+func nodeMaintenanceProperties(labels, annotations map[string]string) map[string]interface{} {
+	properties := map[string]interface{}{
+		"osID":             labels[nfdOSIDLabel],
+		"osVersionID":      labels[nfdOSVersionIDLabel],
+		"osKernelFull":     labels[nfdKernelLabel],
+		"osVersion":        labels[flatcarVersionLabel],
+		"rebootRequired":   false,
+		"rebootInProgress": false,
+		"rebootSource":     "",
+		"rebootRequiredAt": "",
+		"pendingOSVersion": "",
+	}
+
+	// kured records when it last saw the reboot sentinel; the annotation is
+	// removed once the node rebooted
+	for _, key := range []string{kuredRebootNeededAnnotation, legacyKuredRebootNeededAnnotation} {
+		if since, ok := annotations[key]; ok {
+			properties["rebootRequired"] = true
+			properties["rebootSource"] = "kured"
+			properties["rebootRequiredAt"] = since
+		}
+	}
+	for _, key := range []string{kuredRebootInProgressAnnotation, legacyKuredInProgressAnnotation} {
+		if _, ok := annotations[key]; ok {
+			properties["rebootInProgress"] = true
+			properties["rebootSource"] = "kured"
+		}
+	}
+
+	if annotations[flatcarRebootNeededAnnotation] == "true" {
+		properties["rebootRequired"] = true
+		properties["rebootSource"] = "flatcar-linux-update-operator"
+		properties["pendingOSVersion"] = annotations[flatcarNewVersionAnnotation]
+	}
+	if annotations[flatcarRebootInProgressAnnotation] == "true" {
+		properties["rebootInProgress"] = true
+		properties["rebootSource"] = "flatcar-linux-update-operator"
+	}
+
+	return properties
+}
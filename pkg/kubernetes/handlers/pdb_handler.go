@@ -4,33 +4,51 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type PodDisruptionBudgetHandler struct {
 	BaseHandler
+	clientset    *kubernetes.Clientset
 	instanceHash string
 }
 
-func NewPodDisruptionBudgetHandler(cfg *config.Config) *PodDisruptionBudgetHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "policy",
-		Version:  "v1",
-		Resource: "poddisruptionbudgets",
-	}
+var podDisruptionBudgetGVR = schema.GroupVersionResource{
+	Group:    "policy",
+	Version:  "v1",
+	Resource: "poddisruptionbudgets",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PodDisruptionBudget",
+		GVR:               podDisruptionBudgetGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPodDisruptionBudgetHandler(clientset, cfg)
+		},
+	})
+}
+
+func NewPodDisruptionBudgetHandler(clientset *kubernetes.Clientset, cfg *config.Config) *PodDisruptionBudgetHandler {
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("PodDisruptionBudget", "PodDisruptionBudget")
 	return &PodDisruptionBudgetHandler{
-		BaseHandler:  NewBaseHandler(gvr, "PodDisruptionBudget", cfg),
+		BaseHandler:  NewBaseHandler(podDisruptionBudgetGVR, "PodDisruptionBudget", cfg),
+		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *PodDisruptionBudgetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PodDisruptionBudgetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pdb, err := ConvertToTyped[*policyv1.PodDisruptionBudget](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert poddisruptionbudget: %w", err)
@@ -56,31 +74,34 @@ func (h *PodDisruptionBudgetHandler) HandleCreate(ctx context.Context, obj inter
 		"instanceHash":               h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"PodDisruptionBudget"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert poddisruptionbudget %s: %w", pdb.Name, err)
-	}
+	relationships := ownerRelationships("PodDisruptionBudget", string(pdb.UID), pdb.OwnerReferences)
 
-	// Create relationships based on owner references for all supported types
-	if pdb.OwnerReferences != nil {
-		for _, ownerRef := range pdb.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"PodDisruptionBudget", "uid", string(pdb.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between PodDisruptionBudget %s and %s %s: %v\n", pdb.Name, label, ownerRef.Name, err)
-				}
-			}
+	// Create relationships with the pods protected by the budget
+	if pdb.Spec.Selector != nil && h.clientset != nil {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return fmt.Errorf("failed to parse selector of poddisruptionbudget %s: %w", pdb.Name, err)
 		}
+		pods, err := h.clientset.CoreV1().Pods(pdb.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list pods for poddisruptionbudget %s: %w", pdb.Name, err)
+		}
+
+		for _, pod := range pods.Items {
+			relationships = append(relationships, relationship("PodDisruptionBudget", "uid", string(pdb.UID), "PROTECTS", "Pod", "uid", string(pod.UID)))
+		}
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"PodDisruptionBudget"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert poddisruptionbudget %s: %w", pdb.Name, err)
 	}
 
 	return nil
 }
 
-func (h *PodDisruptionBudgetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PodDisruptionBudgetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pdb, err := ConvertToTyped[*policyv1.PodDisruptionBudget](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert poddisruptionbudget: %w", err)
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PeerAuthenticationHandler tracks Istio PeerAuthentications with the mTLS
+// mode they require
+type PeerAuthenticationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var peerAuthenticationGVR = istioResource("security.istio.io", "peerauthentications")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PeerAuthentication",
+		GVR:               peerAuthenticationGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPeerAuthenticationHandler(cfg)
+		},
+	})
+}
+
+func NewPeerAuthenticationHandler(cfg *config.Config) *PeerAuthenticationHandler {
+	RegisterOwnerKind("PeerAuthentication", "PeerAuthentication")
+	return &PeerAuthenticationHandler{
+		BaseHandler:  NewBaseHandler(peerAuthenticationGVR, "PeerAuthentication", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *PeerAuthenticationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	pa, err := ConvertToTyped[*peerAuthentication](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert peerauthentication: %w", err)
+	}
+
+	// Without a selector the policy applies to the whole namespace
+	selector := map[string]string{}
+	if pa.Spec.Selector != nil && pa.Spec.Selector.MatchLabels != nil {
+		selector = pa.Spec.Selector.MatchLabels
+	}
+
+	properties := map[string]interface{}{
+		"name":              pa.Name,
+		"uid":               string(pa.UID),
+		"namespace":         pa.Namespace,
+		"creationTimestamp": pa.CreationTimestamp.String(),
+		"labels":            pa.Labels,
+		"annotations":       pa.Annotations,
+		"selector":          selector,
+		"namespaceWide":     len(selector) == 0,
+		"mtlsMode":          mtlsMode(pa),
+		"portLevelMtls":     portLevelMTLS(pa),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"PeerAuthentication"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert peerauthentication %s: %w", pa.Name, err)
+	}
+	return nil
+}
+
+func (h *PeerAuthenticationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	pa, err := ConvertToTyped[*peerAuthentication](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert peerauthentication: %w", err)
+	}
+	return HandleResourceDelete(ctx, "PeerAuthentication", string(pa.UID), neo4jClient)
+}
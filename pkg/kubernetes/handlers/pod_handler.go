@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 )
@@ -19,15 +22,29 @@ type PodHandler struct {
 	instanceHash string
 }
 
+var podGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "pods",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Pod",
+		GVR:               podGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPodHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewPodHandler(clientset *kubernetes.Clientset, cfg *config.Config) *PodHandler {
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Pod", "Pod")
 	return &PodHandler{
 		BaseHandler: BaseHandler{
-			gvr: schema.GroupVersionResource{
-				Version:  "v1",
-				Resource: "pods",
-			},
+			gvr:  podGVR,
 			kind: "Pod",
 		},
 		clientset:    clientset,
@@ -36,14 +53,14 @@ func NewPodHandler(clientset *kubernetes.Clientset, cfg *config.Config) *PodHand
 	}
 }
 
-func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pod, err := ConvertToTyped[*corev1.Pod](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert pod: %w", err)
 	}
 
 	if pod == nil {
-		return fmt.Errorf("pod is nil after conversion")
+		return failure.New(failure.ConversionError, fmt.Errorf("pod is nil after conversion"))
 	}
 
 	// Get pod conditions
@@ -62,8 +79,15 @@ func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 	// Container statuses and security contexts
 	containerStatuses := make([]string, 0)
 	containerSecurityContexts := make([]map[string]interface{}, 0)
+	containerPorts := make([]string, 0)
+	var restartCount int32
 	if pod.Spec.Containers != nil {
 		for _, container := range pod.Spec.Containers {
+			for _, port := range container.Ports {
+				containerPorts = append(containerPorts, fmt.Sprintf("container=%s;name=%s;containerPort=%d;protocol=%s",
+					container.Name, port.Name, port.ContainerPort, port.Protocol))
+			}
+
 			// Add container resources to totals
 			if container.Resources.Requests != nil {
 				for resourceName, quantity := range container.Resources.Requests {
@@ -113,6 +137,7 @@ func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 			}
 
 			if containerStatus != nil {
+				restartCount += containerStatus.RestartCount
 				containerInfo += fmt.Sprintf(";ready=%v;restartCount=%d;started=%v",
 					containerStatus.Ready,
 					containerStatus.RestartCount,
@@ -211,6 +236,11 @@ func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		}
 	}
 
+	secrets := podSecretReferences(pod.Spec)
+	configMaps := podConfigMapReferences(pod.Spec)
+	requestedCPU, requestedMemory := podRequests(pod.Spec)
+	limitCPU, limitMemory := podLimits(pod.Spec)
+
 	properties := map[string]interface{}{
 		"name":                      pod.Name,
 		"uid":                       string(pod.UID),
@@ -226,11 +256,19 @@ func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		"priority":                  pod.Spec.Priority,
 		"priorityClassName":         pod.Spec.PriorityClassName,
 		"serviceAccount":            pod.Spec.ServiceAccountName,
+		"secrets":                   secrets,
+		"configMaps":                configMaps,
 		"restartPolicy":             string(pod.Spec.RestartPolicy),
+		"restartCount":              restartCount,
 		"conditions":                conditions,
 		"resourceRequests":          requests,
 		"resourceLimits":            limits,
+		"requestedCPU":              requestedCPU,
+		"requestedMemory":           requestedMemory,
+		"limitCPU":                  limitCPU,
+		"limitMemory":               limitMemory,
 		"containers":                containerStatuses,
+		"containerPorts":            containerPorts,
 		"containerSecurityContexts": containerSecurityContexts,
 		"podSecurityContext":        podSecurityContext,
 		"nodeSelector":              pod.Spec.NodeSelector,
@@ -239,75 +277,118 @@ func (h *PodHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jCli
 		"instanceHash":              h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Pod"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert pod %s: %w", pod.Name, err)
+	if pod.Spec.PreemptionPolicy != nil {
+		properties["preemptionPolicy"] = string(*pod.Spec.PreemptionPolicy)
+	}
+	// Set by the scheduler on pending pods when it preempts pods to make room
+	if pod.Status.NominatedNodeName != "" {
+		properties["nominatedNodeName"] = pod.Status.NominatedNodeName
 	}
 
-	// Create relationships based on owner references for all supported types
-	if pod.OwnerReferences != nil {
-		for _, ownerRef := range pod.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Pod", "uid", string(pod.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Pod %s and %s %s: %v\n", pod.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	if pod.Spec.AutomountServiceAccountToken != nil {
+		properties["automountServiceAccountToken"] = *pod.Spec.AutomountServiceAccountToken
 	}
 
+	// Store the security posture as dedicated properties so it can be queried
+	// without matching the security context JSON
+	for key, value := range evaluatePodSecurity(pod.Annotations, pod.Spec).properties() {
+		properties[key] = value
+	}
+	for key, value := range probeProperties(pod.Spec) {
+		properties[key] = value
+	}
+	for key, value := range schedulingProperties(pod.Spec) {
+		properties[key] = value
+	}
+	// Keep the node constraints in a form the periodic resolution can evaluate again
+	constraints := podNodeConstraints(pod.Spec)
+	if constraints != nil {
+		properties["nodeConstraints"] = constraints
+	}
+
+	relationships := ownerRelationships("Pod", string(pod.UID), pod.OwnerReferences)
+
 	// Create relationships
 	if pod.Spec.NodeName != "" {
-		if err := neo4jClient.CreateRelationship(ctx, "Pod", "uid", string(pod.UID), "SCHEDULED_ON", "Node", "name", pod.Spec.NodeName); err != nil {
-			return fmt.Errorf("failed to create relationship between pod %s and node %s: %w", pod.Name, pod.Spec.NodeName, err)
-		}
+		relationships = append(relationships, relationship("Pod", "uid", string(pod.UID), "SCHEDULED_ON", "Node", "name", pod.Spec.NodeName))
+	}
+	if pod.Spec.PriorityClassName != "" {
+		relationships = append(relationships, relationship("Pod", "uid", string(pod.UID), "USES_PRIORITY_CLASS", "PriorityClass", "name", pod.Spec.PriorityClassName))
 	}
 
 	// Create relationships with PVCs
 	if pod.Spec.Volumes != nil {
 		for _, volume := range pod.Spec.Volumes {
 			if volume.PersistentVolumeClaim != nil {
-				if err := neo4jClient.CreateRelationship(ctx, "Pod", "uid", string(pod.UID), "USES", "PersistentVolumeClaim", "name", volume.PersistentVolumeClaim.ClaimName); err != nil {
-					return fmt.Errorf("failed to create relationship between pod %s and PVC %s: %w", pod.Name, volume.PersistentVolumeClaim.ClaimName, err)
-				}
+				relationships = append(relationships, relationship("Pod", "uid", string(pod.UID), "USES", "PersistentVolumeClaim", "name", volume.PersistentVolumeClaim.ClaimName))
 			}
 		}
 	}
 
-	// Create relationships with ConfigMaps
-	if pod.Spec.Volumes != nil {
-		for _, volume := range pod.Spec.Volumes {
-			if volume.ConfigMap != nil {
-				if err := neo4jClient.CreateRelationship(ctx, "Pod", "uid", string(pod.UID), "USES", "ConfigMap", "name", volume.ConfigMap.Name); err != nil {
-					return fmt.Errorf("failed to create relationship between pod %s and ConfigMap %s: %w", pod.Name, volume.ConfigMap.Name, err)
-				}
-			}
-		}
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Pod"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert pod %s: %w", pod.Name, err)
 	}
 
-	// Create relationships with Secrets
-	if pod.Spec.Volumes != nil {
-		for _, volume := range pod.Spec.Volumes {
-			if volume.Secret != nil {
-				if err := neo4jClient.CreateRelationship(ctx, "Pod", "uid", string(pod.UID), "USES", "Secret", "name", volume.Secret.SecretName); err != nil {
-					return fmt.Errorf("failed to create relationship between pod %s and Secret %s: %w", pod.Name, volume.Secret.SecretName, err)
-				}
-			}
+	// Pods created by a controller are linked to registries through their workload
+	if metav1.GetControllerOf(pod) == nil {
+		linkRegistries(ctx, neo4jClient, "Pod", string(pod.UID), "PULLS_FROM", h.clusterName, h.instanceHash, podSpecRegistries(pod.Spec))
+	}
+
+	linkImages(ctx, neo4jClient, "Pod", string(pod.UID), h.clusterName, podSpecImages(pod.Spec))
+
+	// Link the nodes the pod is allowed to run on and the tainted nodes it tolerates
+	if constraints != nil {
+		if err := resolvePodConstraints(ctx, neo4jClient, string(pod.UID), constraints, h.clusterName); err != nil {
+			fmt.Printf("Warning: failed to resolve node constraints of pod %s: %v\n", pod.Name, err)
 		}
 	}
+	if err := resolvePodTolerations(ctx, neo4jClient, string(pod.UID), pod.Spec.Tolerations, h.clusterName); err != nil {
+		fmt.Printf("Warning: failed to resolve tolerations of pod %s: %v\n", pod.Name, err)
+	}
+
+	// Pods without a service account name run as the default service account
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	if err := linkNamespacedTargets(ctx, neo4jClient, "Pod", string(pod.UID), "USES_SERVICE_ACCOUNT", "ServiceAccount", h.clusterName, []string{namespacedKey(pod.Namespace, serviceAccount)}); err != nil {
+		fmt.Printf("Warning: failed to create USES_SERVICE_ACCOUNT relationship between pod %s and ServiceAccount %s: %v\n", pod.Name, serviceAccount, err)
+	}
+
+	// Create relationships with ConfigMaps mounted or referenced from the environment
+	if err := linkReferences(ctx, neo4jClient, "Pod", string(pod.UID), pod.Namespace, h.clusterName, "ConfigMap", configMaps); err != nil {
+		return fmt.Errorf("failed to create relationships between pod %s and ConfigMaps %v: %w", pod.Name, configMaps, err)
+	}
+
+	// Create relationships with Secrets mounted, referenced from the environment or used to pull images
+	if err := linkReferences(ctx, neo4jClient, "Pod", string(pod.UID), pod.Namespace, h.clusterName, "Secret", secrets); err != nil {
+		return fmt.Errorf("failed to create relationships between pod %s and Secrets %v: %w", pod.Name, secrets, err)
+	}
+
+	// Update the storage of the workloads owning the pod
+	if err := rollupStorageForPod(ctx, neo4jClient, string(pod.UID), false); err != nil {
+		fmt.Printf("Warning: failed to roll up storage of pod %s: %v\n", pod.Name, err)
+	}
+
+	// Update the requests reserved on the node the pod is scheduled on
+	if err := rollupRequestsForPod(ctx, neo4jClient, string(pod.UID), false); err != nil {
+		fmt.Printf("Warning: failed to roll up requests of pod %s: %v\n", pod.Name, err)
+	}
 
 	return nil
 }
 
-func (h *PodHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PodHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pod, err := ConvertToTyped[*corev1.Pod](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert pod: %w", err)
 	}
+	if err := rollupStorageForPod(ctx, neo4jClient, string(pod.UID), true); err != nil {
+		fmt.Printf("Warning: failed to roll up storage of pod %s: %v\n", pod.Name, err)
+	}
+	if err := rollupRequestsForPod(ctx, neo4jClient, string(pod.UID), true); err != nil {
+		fmt.Printf("Warning: failed to roll up requests of pod %s: %v\n", pod.Name, err)
+	}
 	return HandleResourceDelete(ctx, "Pod", string(pod.UID), neo4jClient)
 }
 
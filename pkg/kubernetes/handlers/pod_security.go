@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pod Security Standards profiles, from the least to the most restrictive
+const (
+	pssPrivileged = "privileged"
+	pssBaseline   = "baseline"
+	pssRestricted = "restricted"
+)
+
+// baselineCapabilities are the capabilities the baseline profile allows adding
+var baselineCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true, "FSETID": true, "KILL": true, "MKNOD": true,
+	"NET_BIND_SERVICE": true, "SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// baselineSELinuxTypes are the SELinux types the baseline profile allows
+var baselineSELinuxTypes = map[string]bool{
+	"": true, "container_t": true, "container_init_t": true, "container_kvm_t": true, "container_engine_t": true,
+}
+
+// baselineSysctls are the sysctls the baseline profile considers safe
+var baselineSysctls = map[string]bool{
+	"kernel.shm_rmid_forced": true, "net.ipv4.ip_local_port_range": true, "net.ipv4.ip_unprivileged_port_start": true,
+	"net.ipv4.tcp_syncookies": true, "net.ipv4.ping_group_range": true, "net.ipv4.ip_local_reserved_ports": true,
+	"net.ipv4.tcp_keepalive_time": true, "net.ipv4.tcp_fin_timeout": true, "net.ipv4.tcp_keepalive_intvl": true,
+	"net.ipv4.tcp_keepalive_probes": true,
+}
+
+// appArmorAnnotationPrefix is the prefix of the deprecated per-container AppArmor annotations
+const appArmorAnnotationPrefix = "container.apparmor.security.beta.kubernetes.io/"
+
+// podContainer is a container of any type with the fields the profiles check
+type podContainer struct {
+	name            string
+	securityContext *corev1.SecurityContext
+	ports           []corev1.ContainerPort
+}
+
+func podContainers(spec corev1.PodSpec) []podContainer {
+	containers := make([]podContainer, 0, len(spec.InitContainers)+len(spec.Containers)+len(spec.EphemeralContainers))
+	for _, c := range spec.InitContainers {
+		containers = append(containers, podContainer{c.Name, c.SecurityContext, c.Ports})
+	}
+	for _, c := range spec.Containers {
+		containers = append(containers, podContainer{c.Name, c.SecurityContext, c.Ports})
+	}
+	for _, c := range spec.EphemeralContainers {
+		containers = append(containers, podContainer{c.Name, c.SecurityContext, c.Ports})
+	}
+	return containers
+}
+
+// podSecurity is the security posture of a pod, with the violations of the
+// Pod Security Standards profiles
+type podSecurity struct {
+	RunAsUser                *int64
+	RunsAsRoot               bool
+	RunAsNonRoot             bool
+	Privileged               bool
+	AllowPrivilegeEscalation bool
+	ReadOnlyRootFilesystem   bool
+	AddedCapabilities        []string
+	DropsAllCapabilities     bool
+	// BaselineViolations are the checks of the baseline profile the pod fails
+	BaselineViolations []string
+	// RestrictedViolations are the checks the restricted profile adds to the
+	// baseline profile that the pod fails
+	RestrictedViolations []string
+}
+
+// Level returns the most restrictive profile the pod satisfies
+func (s podSecurity) Level() string {
+	switch {
+	case len(s.BaselineViolations) > 0:
+		return pssPrivileged
+	case len(s.RestrictedViolations) > 0:
+		return pssBaseline
+	}
+	return pssRestricted
+}
+
+// properties returns the properties stored on the Pod node
+func (s podSecurity) properties() map[string]interface{} {
+	properties := map[string]interface{}{
+		"runsAsRoot":               s.RunsAsRoot,
+		"runAsNonRoot":             s.RunAsNonRoot,
+		"privileged":               s.Privileged,
+		"allowPrivilegeEscalation": s.AllowPrivilegeEscalation,
+		"readOnlyRootFilesystem":   s.ReadOnlyRootFilesystem,
+		"addedCapabilities":        s.AddedCapabilities,
+		"dropsAllCapabilities":     s.DropsAllCapabilities,
+		"pssLevel":                 s.Level(),
+		"pssBaselineViolations":    s.BaselineViolations,
+		"pssRestrictedViolations":  s.RestrictedViolations,
+	}
+	if s.RunAsUser != nil {
+		properties["runAsUser"] = *s.RunAsUser
+	}
+	return properties
+}
+
+// evaluatePodSecurity evaluates a pod against the baseline and restricted
+// Pod Security Standards, following the checks of the PodSecurity admission
+// controller. Container security contexts override the pod security context.
+func evaluatePodSecurity(annotations map[string]string, spec corev1.PodSpec) podSecurity {
+	podCtx := spec.SecurityContext
+	if podCtx == nil {
+		podCtx = &corev1.PodSecurityContext{}
+	}
+	containers := podContainers(spec)
+
+	s := podSecurity{
+		RunAsUser:              podCtx.RunAsUser,
+		RunAsNonRoot:           true,
+		ReadOnlyRootFilesystem: len(containers) > 0,
+		DropsAllCapabilities:   len(containers) > 0,
+	}
+	var baseline, restricted []string
+	added := make(map[string]bool)
+
+	// Host namespaces
+	if spec.HostNetwork {
+		baseline = append(baseline, "hostNetwork")
+	}
+	if spec.HostPID {
+		baseline = append(baseline, "hostPID")
+	}
+	if spec.HostIPC {
+		baseline = append(baseline, "hostIPC")
+	}
+
+	// Volumes
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			baseline = append(baseline, fmt.Sprintf("hostPath volume %s", volume.Name))
+		} else if !restrictedVolume(volume.VolumeSource) {
+			restricted = append(restricted, fmt.Sprintf("volume %s has a restricted type", volume.Name))
+		}
+	}
+
+	// Pod level settings
+	for _, sysctl := range podCtx.Sysctls {
+		if !baselineSysctls[sysctl.Name] {
+			baseline = append(baseline, fmt.Sprintf("unsafe sysctl %s", sysctl.Name))
+		}
+	}
+	if podCtx.WindowsOptions != nil && podCtx.WindowsOptions.HostProcess != nil && *podCtx.WindowsOptions.HostProcess {
+		baseline = append(baseline, "Windows HostProcess pod")
+	}
+	if violation := seLinuxViolation(podCtx.SELinuxOptions); violation != "" {
+		baseline = append(baseline, "pod "+violation)
+	}
+	if podCtx.SeccompProfile != nil && podCtx.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		baseline = append(baseline, "pod seccomp profile Unconfined")
+	}
+	if podCtx.AppArmorProfile != nil && podCtx.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		baseline = append(baseline, "pod AppArmor profile Unconfined")
+	}
+	if podCtx.RunAsUser != nil && *podCtx.RunAsUser == 0 {
+		restricted = append(restricted, "pod runAsUser 0")
+	}
+
+	for _, c := range containers {
+		ctx := c.securityContext
+		if ctx == nil {
+			ctx = &corev1.SecurityContext{}
+		}
+
+		if ctx.Privileged != nil && *ctx.Privileged {
+			s.Privileged = true
+			baseline = append(baseline, fmt.Sprintf("privileged container %s", c.name))
+		}
+		if ctx.WindowsOptions != nil && ctx.WindowsOptions.HostProcess != nil && *ctx.WindowsOptions.HostProcess {
+			baseline = append(baseline, fmt.Sprintf("Windows HostProcess container %s", c.name))
+		}
+		for _, port := range c.ports {
+			if port.HostPort != 0 {
+				baseline = append(baseline, fmt.Sprintf("hostPort %d in container %s", port.HostPort, c.name))
+			}
+		}
+		if violation := seLinuxViolation(ctx.SELinuxOptions); violation != "" {
+			baseline = append(baseline, fmt.Sprintf("container %s %s", c.name, violation))
+		}
+		if ctx.ProcMount != nil && *ctx.ProcMount != corev1.DefaultProcMount {
+			baseline = append(baseline, fmt.Sprintf("procMount %s in container %s", *ctx.ProcMount, c.name))
+		}
+		if profile := annotations[appArmorAnnotationPrefix+c.name]; profile != "" && profile != "runtime/default" && !strings.HasPrefix(profile, "localhost/") {
+			baseline = append(baseline, fmt.Sprintf("AppArmor profile %s in container %s", profile, c.name))
+		}
+		if ctx.AppArmorProfile != nil && ctx.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+			baseline = append(baseline, fmt.Sprintf("AppArmor profile Unconfined in container %s", c.name))
+		}
+
+		// Seccomp: Unconfined is never allowed, and restricted requires a
+		// profile on the pod or on every container
+		seccomp := podCtx.SeccompProfile
+		if ctx.SeccompProfile != nil {
+			seccomp = ctx.SeccompProfile
+		}
+		if ctx.SeccompProfile != nil && ctx.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			baseline = append(baseline, fmt.Sprintf("seccomp profile Unconfined in container %s", c.name))
+		} else if seccomp == nil {
+			restricted = append(restricted, fmt.Sprintf("no seccomp profile for container %s", c.name))
+		}
+
+		// Capabilities
+		dropsAll := false
+		if ctx.Capabilities != nil {
+			for _, capability := range ctx.Capabilities.Add {
+				name := strings.TrimPrefix(strings.ToUpper(string(capability)), "CAP_")
+				added[name] = true
+				if !baselineCapabilities[name] {
+					baseline = append(baseline, fmt.Sprintf("capability %s added to container %s", name, c.name))
+				} else if name != "NET_BIND_SERVICE" {
+					restricted = append(restricted, fmt.Sprintf("capability %s added to container %s", name, c.name))
+				}
+			}
+			for _, capability := range ctx.Capabilities.Drop {
+				if strings.ToUpper(string(capability)) == "ALL" {
+					dropsAll = true
+				}
+			}
+		}
+		if !dropsAll {
+			s.DropsAllCapabilities = false
+			restricted = append(restricted, fmt.Sprintf("container %s does not drop ALL capabilities", c.name))
+		}
+
+		// Privilege escalation is allowed unless disabled explicitly
+		if ctx.AllowPrivilegeEscalation == nil || *ctx.AllowPrivilegeEscalation {
+			s.AllowPrivilegeEscalation = true
+			restricted = append(restricted, fmt.Sprintf("container %s allows privilege escalation", c.name))
+		}
+
+		// User
+		runAsUser := podCtx.RunAsUser
+		if ctx.RunAsUser != nil {
+			runAsUser = ctx.RunAsUser
+			if *ctx.RunAsUser == 0 {
+				restricted = append(restricted, fmt.Sprintf("container %s runAsUser 0", c.name))
+			}
+		}
+		if runAsUser != nil && *runAsUser == 0 {
+			s.RunsAsRoot = true
+		}
+		runAsNonRoot := podCtx.RunAsNonRoot
+		if ctx.RunAsNonRoot != nil {
+			runAsNonRoot = ctx.RunAsNonRoot
+		}
+		if runAsNonRoot == nil || !*runAsNonRoot {
+			s.RunAsNonRoot = false
+			restricted = append(restricted, fmt.Sprintf("container %s does not set runAsNonRoot", c.name))
+		}
+
+		if ctx.ReadOnlyRootFilesystem == nil || !*ctx.ReadOnlyRootFilesystem {
+			s.ReadOnlyRootFilesystem = false
+		}
+	}
+
+	for name := range added {
+		s.AddedCapabilities = append(s.AddedCapabilities, name)
+	}
+	sort.Strings(s.AddedCapabilities)
+	s.BaselineViolations = baseline
+	s.RestrictedViolations = restricted
+	return s
+}
+
+// restrictedVolume reports whether the restricted profile allows a volume type
+func restrictedVolume(source corev1.VolumeSource) bool {
+	return source.ConfigMap != nil || source.CSI != nil || source.DownwardAPI != nil || source.EmptyDir != nil ||
+		source.Ephemeral != nil || source.PersistentVolumeClaim != nil || source.Projected != nil || source.Secret != nil
+}
+
+// seLinuxViolation describes SELinux options the baseline profile forbids, or
+// returns "" if they are allowed
+func seLinuxViolation(options *corev1.SELinuxOptions) string {
+	switch {
+	case options == nil:
+		return ""
+	case !baselineSELinuxTypes[options.Type]:
+		return fmt.Sprintf("SELinux type %s", options.Type)
+	case options.User != "" || options.Role != "":
+		return "sets a custom SELinux user or role"
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func boolPtr(b bool) *bool    { return &b }
+func int64Ptr(i int64) *int64 { return &i }
+
+// restrictedContainer returns a container satisfying the restricted profile
+func restrictedContainer(name string) corev1.Container {
+	return corev1.Container{
+		Name: name,
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			ReadOnlyRootFilesystem:   boolPtr(true),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+		},
+	}
+}
+
+func restrictedSpec() corev1.PodSpec {
+	return corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot:   boolPtr(true),
+			RunAsUser:      int64Ptr(1000),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		Containers: []corev1.Container{restrictedContainer("app")},
+		Volumes:    []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}}}},
+	}
+}
+
+func TestEvaluatePodSecurityRestricted(t *testing.T) {
+	s := evaluatePodSecurity(nil, restrictedSpec())
+	if s.Level() != pssRestricted {
+		t.Fatalf("Expected restricted, got %s with violations %v %v", s.Level(), s.BaselineViolations, s.RestrictedViolations)
+	}
+	if s.RunsAsRoot || !s.RunAsNonRoot || s.Privileged || s.AllowPrivilegeEscalation || !s.ReadOnlyRootFilesystem || !s.DropsAllCapabilities {
+		t.Errorf("Unexpected security posture %+v", s)
+	}
+	if !reflect.DeepEqual(s.AddedCapabilities, []string{"NET_BIND_SERVICE"}) {
+		t.Errorf("Unexpected added capabilities %v", s.AddedCapabilities)
+	}
+}
+
+func TestEvaluatePodSecurityBaseline(t *testing.T) {
+	// A pod with default security contexts satisfies baseline but not restricted
+	s := evaluatePodSecurity(nil, corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}})
+	if s.Level() != pssBaseline {
+		t.Fatalf("Expected baseline, got %s with violations %v", s.Level(), s.BaselineViolations)
+	}
+	expected := []string{
+		"no seccomp profile for container app",
+		"container app does not drop ALL capabilities",
+		"container app allows privilege escalation",
+		"container app does not set runAsNonRoot",
+	}
+	if !reflect.DeepEqual(s.RestrictedViolations, expected) {
+		t.Errorf("Expected %v, got %v", expected, s.RestrictedViolations)
+	}
+	if !s.AllowPrivilegeEscalation || s.ReadOnlyRootFilesystem {
+		t.Errorf("Unexpected security posture %+v", s)
+	}
+}
+
+func TestEvaluatePodSecurityViolations(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(annotations map[string]string, spec *corev1.PodSpec)
+		level    string
+		expected string
+	}{
+		{"host network", func(_ map[string]string, spec *corev1.PodSpec) { spec.HostNetwork = true }, pssPrivileged, "hostNetwork"},
+		{"privileged", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.Privileged = boolPtr(true)
+		}, pssPrivileged, "privileged container app"},
+		{"privileged init container", func(_ map[string]string, spec *corev1.PodSpec) {
+			init := restrictedContainer("init")
+			init.SecurityContext.Privileged = boolPtr(true)
+			spec.InitContainers = []corev1.Container{init}
+		}, pssPrivileged, "privileged container init"},
+		{"capability", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"CAP_SYS_ADMIN"}
+		}, pssPrivileged, "capability SYS_ADMIN added to container app"},
+		{"baseline capability", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"CHOWN"}
+		}, pssBaseline, "capability CHOWN added to container app"},
+		{"hostPath", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "docker", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run"}}})
+		}, pssPrivileged, "hostPath volume docker"},
+		{"restricted volume type", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "nfs", VolumeSource: corev1.VolumeSource{NFS: &corev1.NFSVolumeSource{}}})
+		}, pssBaseline, "volume nfs has a restricted type"},
+		{"host port", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 80, HostPort: 8080}}
+		}, pssPrivileged, "hostPort 8080 in container app"},
+		{"unconfined seccomp", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+		}, pssPrivileged, "seccomp profile Unconfined in container app"},
+		{"AppArmor annotation", func(annotations map[string]string, _ *corev1.PodSpec) {
+			annotations[appArmorAnnotationPrefix+"app"] = "unconfined"
+		}, pssPrivileged, "AppArmor profile unconfined in container app"},
+		{"unsafe sysctl", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "1"}}
+		}, pssPrivileged, "unsafe sysctl kernel.msgmax"},
+		{"root user", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.RunAsUser = int64Ptr(0)
+		}, pssBaseline, "container app runAsUser 0"},
+		{"runAsNonRoot disabled", func(_ map[string]string, spec *corev1.PodSpec) {
+			spec.Containers[0].SecurityContext.RunAsNonRoot = boolPtr(false)
+		}, pssBaseline, "container app does not set runAsNonRoot"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			annotations := map[string]string{}
+			spec := restrictedSpec()
+			test.mutate(annotations, &spec)
+			s := evaluatePodSecurity(annotations, spec)
+			if s.Level() != test.level {
+				t.Errorf("Expected level %s, got %s", test.level, s.Level())
+			}
+			violations := append(append([]string{}, s.BaselineViolations...), s.RestrictedViolations...)
+			found := false
+			for _, violation := range violations {
+				if violation == test.expected {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected violation %q, got %v", test.expected, violations)
+			}
+		})
+	}
+}
+
+func TestEvaluatePodSecurityRunsAsRoot(t *testing.T) {
+	spec := restrictedSpec()
+	spec.SecurityContext.RunAsUser = int64Ptr(0)
+	spec.Containers = append(spec.Containers, restrictedContainer("sidecar"))
+	spec.Containers[1].SecurityContext.RunAsUser = int64Ptr(1000)
+
+	// The app container inherits the pod user
+	s := evaluatePodSecurity(nil, spec)
+	if !s.RunsAsRoot {
+		t.Error("Expected the pod to run as root")
+	}
+	if properties := s.properties(); properties["runAsUser"] != int64(0) || properties["pssLevel"] != pssBaseline {
+		t.Errorf("Unexpected properties %v", properties)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// templateProperties returns the parts of a workload's pod template that
+// usually drift between environments, keyed by container name: images, the
+// names of environment variables (not their values, which may be secret),
+// resource requests and limits, probes and lifecycle hooks. templateHash
+// summarizes them so that workloads with the same template can be matched
+// cheaply.
+func templateProperties(spec corev1.PodSpec) map[string]interface{} {
+	env := make(map[string]string)
+	resources := make(map[string]string)
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		if names := envNames(container); names != "" {
+			env[container.Name] = names
+		}
+		if formatted := formatResources(container.Resources); formatted != "" {
+			resources[container.Name] = formatted
+		}
+	}
+
+	template := map[string]interface{}{
+		"containerImages":    containerImages(spec),
+		"containerEnv":       env,
+		"containerResources": resources,
+	}
+	for key, value := range probeProperties(spec) {
+		template[key] = value
+	}
+	// Maps are encoded with sorted keys, so the hash is stable
+	encoded, _ := json.Marshal(template)
+	sum := sha256.Sum256(encoded)
+	template["templateHash"] = hex.EncodeToString(sum[:8])
+	return template
+}
+
+// envNames returns the sorted names of the environment variables of a
+// container, with envFrom sources as configMap:<name> or secret:<name>
+func envNames(container corev1.Container) string {
+	names := make([]string, 0, len(container.Env)+len(container.EnvFrom))
+	for _, env := range container.Env {
+		names = append(names, env.Name)
+	}
+	for _, source := range container.EnvFrom {
+		switch {
+		case source.ConfigMapRef != nil:
+			names = append(names, source.Prefix+"configMap:"+source.ConfigMapRef.Name)
+		case source.SecretRef != nil:
+			names = append(names, source.Prefix+"secret:"+source.SecretRef.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// formatResources formats the requests and limits of a container as
+// "requests.cpu=100m,requests.memory=128Mi,limits.memory=256Mi"
+func formatResources(resources corev1.ResourceRequirements) string {
+	var parts []string
+	for _, list := range []struct {
+		prefix    string
+		resources corev1.ResourceList
+	}{{"requests", resources.Requests}, {"limits", resources.Limits}} {
+		names := make([]string, 0, len(list.resources))
+		for name := range list.resources {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			quantity := list.resources[corev1.ResourceName(name)]
+			parts = append(parts, fmt.Sprintf("%s.%s=%s", list.prefix, name, quantity.String()))
+		}
+	}
+	return strings.Join(parts, ",")
+}
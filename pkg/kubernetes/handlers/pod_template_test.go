@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestTemplateProperties(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "migrate", Image: "api:1.2"}},
+		Containers: []corev1.Container{{
+			Name:  "api",
+			Image: "api:1.2",
+			Env:   []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "DB_URL"}},
+			EnvFrom: []corev1.EnvFromSource{
+				{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-secrets"}}},
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi"), corev1.ResourceCPU: resource.MustParse("100m")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+			LivenessProbe: &corev1.Probe{
+				ProbeHandler:     corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080)}},
+				PeriodSeconds:    10,
+				FailureThreshold: 5,
+			},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}},
+			},
+		}},
+	}
+
+	properties := templateProperties(spec)
+	expected := map[string]interface{}{
+		"containerImages":    map[string]string{"migrate": "api:1.2", "api": "api:1.2"},
+		"containerEnv":       map[string]string{"api": "DB_URL,LOG_LEVEL,secret:api-secrets"},
+		"containerResources": map[string]string{"api": "requests.cpu=100m,requests.memory=128Mi,limits.memory=256Mi"},
+		"containerProbes":    map[string]string{"api": "liveness=httpGet:8080/healthz failures=5,readiness=tcpSocket:http"},
+	}
+	for key, value := range expected {
+		if !reflect.DeepEqual(properties[key], value) {
+			t.Errorf("Expected %s to be %v, got %v", key, value, properties[key])
+		}
+	}
+
+	// The hash only changes with the template
+	hash, _ := properties["templateHash"].(string)
+	if len(hash) != 16 || templateProperties(spec)["templateHash"] != hash {
+		t.Errorf("Expected a stable 16 character templateHash, got %q", hash)
+	}
+	spec.Containers[0].Image = "api:1.3"
+	if templateProperties(spec)["templateHash"] == hash {
+		t.Error("Expected templateHash to change with the image")
+	}
+}
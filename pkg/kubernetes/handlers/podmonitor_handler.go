@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PodMonitorHandler tracks Prometheus Operator PodMonitors, linking
+// them to the Pods they select
+type PodMonitorHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var podMonitorGVR = prometheusOperatorResource("podmonitors")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PodMonitor",
+		GVR:               podMonitorGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPodMonitorHandler(cfg)
+		},
+	})
+}
+
+func NewPodMonitorHandler(cfg *config.Config) *PodMonitorHandler {
+	RegisterOwnerKind("PodMonitor", "PodMonitor")
+	return &PodMonitorHandler{
+		BaseHandler:  NewBaseHandler(podMonitorGVR, "PodMonitor", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *PodMonitorHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	monitor, err := ConvertToTyped[*podMonitor](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert podmonitor: %w", err)
+	}
+
+	uid := string(monitor.UID)
+	properties := map[string]interface{}{
+		"name":              monitor.Name,
+		"uid":               uid,
+		"namespace":         monitor.Namespace,
+		"creationTimestamp": monitor.CreationTimestamp.String(),
+		"labels":            monitor.Labels,
+		"annotations":       monitor.Annotations,
+		"endpoints":         formatMonitorEndpoints(monitor.Spec.PodMetricsEndpoints),
+		"jobLabel":          monitor.Spec.JobLabel,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	selectionProperties, selection, resolvable := monitorProperties(monitor.Namespace, &monitor.Spec.Selector, monitor.Spec.NamespaceSelector)
+	for key, value := range selectionProperties {
+		properties[key] = value
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"PodMonitor"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert podmonitor %s: %w", monitor.Name, err)
+	}
+
+	if !resolvable {
+		fmt.Printf("Warning: PodMonitor %s has an invalid selector, not linking it\n", monitor.Name)
+		return nil
+	}
+	if err := linkMonitoredTargets(ctx, neo4jClient, "PodMonitor", uid, "Pod", selection, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to create MONITORS relationships for PodMonitor %s: %v\n", monitor.Name, err)
+	}
+
+	return nil
+}
+
+func (h *PodMonitorHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	monitor, err := ConvertToTyped[*podMonitor](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert podmonitor: %w", err)
+	}
+	return HandleResourceDelete(ctx, "PodMonitor", string(monitor.UID), neo4jClient)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PriorityClassHandler tracks PriorityClasses, which rank pods for scheduling
+// and decide which pods are preempted to make room for others
+type PriorityClassHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var priorityClassGVR = schema.GroupVersionResource{
+	Group:    "scheduling.k8s.io",
+	Version:  "v1",
+	Resource: "priorityclasses",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PriorityClass",
+		GVR:               priorityClassGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPriorityClassHandler(cfg)
+		},
+	})
+}
+
+func NewPriorityClassHandler(cfg *config.Config) *PriorityClassHandler {
+	return &PriorityClassHandler{
+		BaseHandler:  NewBaseHandler(priorityClassGVR, "PriorityClass", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *PriorityClassHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*schedulingv1.PriorityClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert priorityclass: %w", err)
+	}
+
+	// Pods of classes without a policy preempt lower priority pods
+	preemptionPolicy := "PreemptLowerPriority"
+	if class.PreemptionPolicy != nil {
+		preemptionPolicy = string(*class.PreemptionPolicy)
+	}
+
+	properties := map[string]interface{}{
+		"name":              class.Name,
+		"uid":               string(class.UID),
+		"creationTimestamp": class.CreationTimestamp.String(),
+		"labels":            class.Labels,
+		"annotations":       class.Annotations,
+		"value":             class.Value,
+		"globalDefault":     class.GlobalDefault,
+		"preemptionPolicy":  preemptionPolicy,
+		"description":       class.Description,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"PriorityClass"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert priorityclass %s: %w", class.Name, err)
+	}
+	return nil
+}
+
+func (h *PriorityClassHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	class, err := ConvertToTyped[*schedulingv1.PriorityClass](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert priorityclass: %w", err)
+	}
+	return HandleResourceDelete(ctx, "PriorityClass", string(class.UID), neo4jClient)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// probeProperties returns the probes and lifecycle hooks of the containers of
+// a pod spec by container name, and missingProbes listing the regular
+// containers without a readiness or liveness probe as "<container>/<probe>".
+// Init containers run to completion and are not expected to have probes.
+func probeProperties(spec corev1.PodSpec) map[string]interface{} {
+	probes := make(map[string]string)
+	lifecycle := make(map[string]string)
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		if formatted := formatProbes(container); formatted != "" {
+			probes[container.Name] = formatted
+		}
+		if formatted := formatLifecycle(container.Lifecycle); formatted != "" {
+			lifecycle[container.Name] = formatted
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, container := range spec.Containers {
+		if container.ReadinessProbe == nil {
+			missing = append(missing, container.Name+"/readiness")
+		}
+		if container.LivenessProbe == nil {
+			missing = append(missing, container.Name+"/liveness")
+		}
+	}
+
+	return map[string]interface{}{
+		"containerProbes":    probes,
+		"containerLifecycle": lifecycle,
+		"missingProbes":      missing,
+	}
+}
+
+// formatProbes formats the probes of a container as
+// "liveness=httpGet:8080/healthz,readiness=tcpSocket:5432"
+func formatProbes(container corev1.Container) string {
+	var parts []string
+	for _, probe := range []struct {
+		name  string
+		probe *corev1.Probe
+	}{{"liveness", container.LivenessProbe}, {"readiness", container.ReadinessProbe}, {"startup", container.StartupProbe}} {
+		if probe.probe != nil {
+			parts = append(parts, probe.name+"="+formatProbe(probe.probe))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatProbe describes the handler of a probe and, when not the defaults,
+// its timing
+func formatProbe(probe *corev1.Probe) string {
+	handler := formatProbeHandler(probe.ProbeHandler)
+
+	var timing []string
+	if probe.InitialDelaySeconds > 0 {
+		timing = append(timing, fmt.Sprintf("delay=%ds", probe.InitialDelaySeconds))
+	}
+	if probe.PeriodSeconds > 0 && probe.PeriodSeconds != 10 {
+		timing = append(timing, fmt.Sprintf("period=%ds", probe.PeriodSeconds))
+	}
+	if probe.TimeoutSeconds > 1 {
+		timing = append(timing, fmt.Sprintf("timeout=%ds", probe.TimeoutSeconds))
+	}
+	if probe.FailureThreshold > 0 && probe.FailureThreshold != 3 {
+		timing = append(timing, fmt.Sprintf("failures=%d", probe.FailureThreshold))
+	}
+	if len(timing) == 0 {
+		return handler
+	}
+	return handler + " " + strings.Join(timing, " ")
+}
+
+func formatProbeHandler(handler corev1.ProbeHandler) string {
+	switch {
+	case handler.HTTPGet != nil:
+		return fmt.Sprintf("httpGet:%s%s", handler.HTTPGet.Port.String(), handler.HTTPGet.Path)
+	case handler.TCPSocket != nil:
+		return "tcpSocket:" + handler.TCPSocket.Port.String()
+	case handler.GRPC != nil:
+		return fmt.Sprintf("grpc:%d", handler.GRPC.Port)
+	case handler.Exec != nil:
+		return "exec:" + strings.Join(handler.Exec.Command, " ")
+	}
+	return "unknown"
+}
+
+// formatLifecycle formats the lifecycle hooks of a container as
+// "postStart=exec:/init.sh,preStop=sleep:5s"
+func formatLifecycle(lifecycle *corev1.Lifecycle) string {
+	if lifecycle == nil {
+		return ""
+	}
+	var parts []string
+	for _, hook := range []struct {
+		name    string
+		handler *corev1.LifecycleHandler
+	}{{"postStart", lifecycle.PostStart}, {"preStop", lifecycle.PreStop}} {
+		if hook.handler == nil {
+			continue
+		}
+		var formatted string
+		switch {
+		case hook.handler.Exec != nil:
+			formatted = "exec:" + strings.Join(hook.handler.Exec.Command, " ")
+		case hook.handler.HTTPGet != nil:
+			formatted = fmt.Sprintf("httpGet:%s%s", hook.handler.HTTPGet.Port.String(), hook.handler.HTTPGet.Path)
+		case hook.handler.TCPSocket != nil:
+			formatted = "tcpSocket:" + hook.handler.TCPSocket.Port.String()
+		case hook.handler.Sleep != nil:
+			formatted = fmt.Sprintf("sleep:%ds", hook.handler.Sleep.Seconds)
+		default:
+			formatted = "unknown"
+		}
+		parts = append(parts, hook.name+"="+formatted)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestProbeProperties(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "migrate"}},
+		Containers: []corev1.Container{
+			{
+				Name: "api",
+				ReadinessProbe: &corev1.Probe{
+					ProbeHandler:        corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(8080)}},
+					InitialDelaySeconds: 5,
+					PeriodSeconds:       10,
+				},
+				StartupProbe: &corev1.Probe{
+					ProbeHandler:     corev1.ProbeHandler{Exec: &corev1.ExecAction{Command: []string{"cat", "/tmp/started"}}},
+					FailureThreshold: 30,
+				},
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.LifecycleHandler{Sleep: &corev1.SleepAction{Seconds: 5}},
+				},
+			},
+			{
+				Name:           "proxy",
+				LivenessProbe:  &corev1.Probe{ProbeHandler: corev1.ProbeHandler{GRPC: &corev1.GRPCAction{Port: 9090}}},
+				ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(15021)}}},
+			},
+		},
+	}
+
+	properties := probeProperties(spec)
+	expected := map[string]interface{}{
+		"containerProbes": map[string]string{
+			"api":   "readiness=httpGet:8080/ready delay=5s,startup=exec:cat /tmp/started failures=30",
+			"proxy": "liveness=grpc:9090,readiness=tcpSocket:15021",
+		},
+		"containerLifecycle": map[string]string{"api": "preStop=sleep:5s"},
+		"missingProbes":      []string{"api/liveness"},
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("Expected %v, got %v", expected, properties)
+	}
+}
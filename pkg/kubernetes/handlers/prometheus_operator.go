@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// prometheusOperatorResource returns the GVR of a monitoring.coreos.com resource
+func prometheusOperatorResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "monitoring.coreos.com",
+		Version:  "v1",
+		Resource: resource,
+	}
+}
+
+// The Prometheus Operator types are not part of client-go, so the handlers
+// decode the fields they need into the structs below
+
+// monitorNamespaceSelector selects the namespaces a monitor looks into. The
+// namespace of the monitor is used when neither field is set.
+type monitorNamespaceSelector struct {
+	Any        bool     `json:"any,omitempty"`
+	MatchNames []string `json:"matchNames,omitempty"`
+}
+
+// monitorEndpoint is a scrape endpoint of a ServiceMonitor or PodMonitor
+type monitorEndpoint struct {
+	Port       string              `json:"port,omitempty"`
+	TargetPort *intstr.IntOrString `json:"targetPort,omitempty"`
+	Path       string              `json:"path,omitempty"`
+	Interval   string              `json:"interval,omitempty"`
+	Scheme     string              `json:"scheme,omitempty"`
+}
+
+type serviceMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Selector          metav1.LabelSelector     `json:"selector"`
+		NamespaceSelector monitorNamespaceSelector `json:"namespaceSelector,omitempty"`
+		Endpoints         []monitorEndpoint        `json:"endpoints,omitempty"`
+		JobLabel          string                   `json:"jobLabel,omitempty"`
+	} `json:"spec"`
+}
+
+type podMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Selector            metav1.LabelSelector     `json:"selector"`
+		NamespaceSelector   monitorNamespaceSelector `json:"namespaceSelector,omitempty"`
+		PodMetricsEndpoints []monitorEndpoint        `json:"podMetricsEndpoints,omitempty"`
+		JobLabel            string                   `json:"jobLabel,omitempty"`
+	} `json:"spec"`
+}
+
+type prometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Groups []struct {
+			Name  string `json:"name"`
+			Rules []struct {
+				Alert  string `json:"alert,omitempty"`
+				Record string `json:"record,omitempty"`
+			} `json:"rules"`
+		} `json:"groups"`
+	} `json:"spec"`
+}
+
+// monitorSelection is what a ServiceMonitor or PodMonitor selects, with the
+// label selector in the string form accepted by labels.Parse
+type monitorSelection struct {
+	Namespace     string
+	Selector      string
+	AllNamespaces bool
+	Namespaces    []string
+}
+
+// monitorTarget is a Service or Pod as read from the graph for monitor resolution
+type monitorTarget struct {
+	UID       string
+	Namespace string
+	Labels    map[string]string
+}
+
+// formatMonitorEndpoint describes an endpoint as "port=...;path=...;interval=...;scheme=..."
+func formatMonitorEndpoint(endpoint monitorEndpoint) string {
+	parts := make([]string, 0, 4)
+	if endpoint.Port != "" {
+		parts = append(parts, "port="+endpoint.Port)
+	} else if endpoint.TargetPort != nil {
+		parts = append(parts, "targetPort="+endpoint.TargetPort.String())
+	}
+	if endpoint.Path != "" {
+		parts = append(parts, "path="+endpoint.Path)
+	}
+	if endpoint.Interval != "" {
+		parts = append(parts, "interval="+endpoint.Interval)
+	}
+	if endpoint.Scheme != "" {
+		parts = append(parts, "scheme="+endpoint.Scheme)
+	}
+	return strings.Join(parts, ";")
+}
+
+// formatMonitorEndpoints describes the endpoints of a monitor
+func formatMonitorEndpoints(endpoints []monitorEndpoint) []string {
+	result := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		result = append(result, formatMonitorEndpoint(endpoint))
+	}
+	return result
+}
+
+// selectMonitorTargets returns the uids of the targets a monitor selects
+func selectMonitorTargets(selection monitorSelection, targets []monitorTarget) []string {
+	namespaces := map[string]bool{}
+	if len(selection.Namespaces) == 0 {
+		namespaces[selection.Namespace] = true
+	}
+	for _, namespace := range selection.Namespaces {
+		namespaces[namespace] = true
+	}
+
+	selected := make([]string, 0)
+	for _, target := range targets {
+		if !selection.AllNamespaces && !namespaces[target.Namespace] {
+			continue
+		}
+		if matchesSelector(selection.Selector, target.Labels) {
+			selected = append(selected, target.UID)
+		}
+	}
+	sort.Strings(selected)
+	return selected
+}
+
+// loadMonitorTargets reads the Services or the running Pods of a cluster
+func loadMonitorTargets(ctx context.Context, neo4jClient graph.Store, targetLabel, clusterName string) ([]monitorTarget, error) {
+	records, err := neo4jClient.Read(ctx, fmt.Sprintf(`
+		MATCH (t:%s {clusterName: $clusterName})
+		WHERE NOT coalesce(t.status, '') IN ['Succeeded', 'Failed']
+		RETURN t.uid AS uid, t.namespace AS namespace, t.labels AS labels`, targetLabel),
+		map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]monitorTarget, 0, len(records))
+	for _, record := range records {
+		var targetLabels map[string]string
+		if value := record["labels"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &targetLabels)
+		}
+		targets = append(targets, monitorTarget{UID: fmt.Sprint(record["uid"]), Namespace: fmt.Sprint(record["namespace"]), Labels: targetLabels})
+	}
+	return targets, nil
+}
+
+// writeMonitorEdges replaces the MONITORS relationships of a monitor
+func writeMonitorEdges(ctx context.Context, neo4jClient graph.Store, label, uid, targetLabel string, targetUIDs []string) error {
+	params := map[string]interface{}{"uid": uid, "targets": targetUIDs}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (m:%s {uid: $uid})-[old:MONITORS]->(t)`, label) +
+			graph.DeleteRelationship("old", "m", "uid", "t", "uid"),
+		Params: params,
+	}, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (m:%s {uid: $uid})
+			MATCH (t:%s) WHERE t.uid IN $targets`, label, targetLabel) +
+			graph.MergeRelationship("m", "uid", "MONITORS", "t", "uid"),
+		Params: params,
+	})
+}
+
+// linkMonitoredTargets creates the MONITORS relationships of one monitor
+func linkMonitoredTargets(ctx context.Context, neo4jClient graph.Store, label, uid, targetLabel string, selection monitorSelection, clusterName string) error {
+	targets, err := loadMonitorTargets(ctx, neo4jClient, targetLabel, clusterName)
+	if err != nil {
+		return err
+	}
+	return writeMonitorEdges(ctx, neo4jClient, label, uid, targetLabel, selectMonitorTargets(selection, targets))
+}
+
+// monitorProperties returns the properties describing what a monitor selects.
+// ok is false when the label selector cannot be converted, in which case the
+// monitor is stored without them and not linked.
+func monitorProperties(namespace string, selector *metav1.LabelSelector, namespaceSelector monitorNamespaceSelector) (map[string]interface{}, monitorSelection, bool) {
+	converted, ok := selectorString(selector)
+	if !ok {
+		return map[string]interface{}{}, monitorSelection{}, false
+	}
+	namespaces := namespaceSelector.MatchNames
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+	selection := monitorSelection{
+		Namespace:     namespace,
+		Selector:      converted,
+		AllNamespaces: namespaceSelector.Any,
+		Namespaces:    namespaces,
+	}
+	return map[string]interface{}{
+		"selector":          converted,
+		"allNamespaces":     namespaceSelector.Any,
+		"monitorNamespaces": namespaces,
+	}, selection, true
+}
+
+// ResolveMonitors links every ServiceMonitor and PodMonitor of a cluster to
+// the Services and Pods they select. The handlers link a monitor when it
+// changes; running this periodically follows Services and Pods created or
+// relabeled since.
+func ResolveMonitors(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	failed := make([]string, 0)
+	for _, monitor := range []struct{ label, targetLabel string }{
+		{"ServiceMonitor", "Service"},
+		{"PodMonitor", "Pod"},
+	} {
+		records, err := neo4jClient.Read(ctx, fmt.Sprintf(`
+			MATCH (m:%s {clusterName: $clusterName})
+			WHERE m.selector IS NOT NULL
+			RETURN m.uid AS uid, m.name AS name, m.namespace AS namespace, m.selector AS selector,
+			       m.allNamespaces AS allNamespaces, m.monitorNamespaces AS monitorNamespaces`, monitor.label),
+			map[string]interface{}{"clusterName": clusterName})
+		if err != nil {
+			return err
+		}
+		targets, err := loadMonitorTargets(ctx, neo4jClient, monitor.targetLabel, clusterName)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			values := make(map[string]string)
+			for _, key := range []string{"uid", "name", "namespace", "selector", "allNamespaces", "monitorNamespaces"} {
+				if value := record[key]; value != nil {
+					values[key] = fmt.Sprint(value)
+				}
+			}
+			selection := monitorSelection{
+				Namespace:     values["namespace"],
+				Selector:      values["selector"],
+				AllNamespaces: values["allNamespaces"] == "true",
+			}
+			if values["monitorNamespaces"] != "" {
+				json.Unmarshal([]byte(values["monitorNamespaces"]), &selection.Namespaces)
+			}
+			selected := selectMonitorTargets(selection, targets)
+			if err := writeMonitorEdges(ctx, neo4jClient, monitor.label, values["uid"], monitor.targetLabel, selected); err != nil {
+				failed = append(failed, monitor.label+" "+values["namespace"]+"/"+values["name"])
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to link monitors %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestServiceMonitorProperties(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "monitoring", "uid": "sm-1"},
+		"spec": map[string]interface{}{
+			"selector":          map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+			"namespaceSelector": map[string]interface{}{"matchNames": []interface{}{"payments", "orders"}},
+			"endpoints": []interface{}{
+				map[string]interface{}{"port": "metrics", "interval": "30s"},
+				map[string]interface{}{"targetPort": int64(9090), "path": "/stats", "scheme": "https"},
+			},
+		},
+	}}
+
+	monitor, err := ConvertToTyped[*serviceMonitor](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert servicemonitor: %v", err)
+	}
+	expectedEndpoints := []string{"port=metrics;interval=30s", "targetPort=9090;path=/stats;scheme=https"}
+	if endpoints := formatMonitorEndpoints(monitor.Spec.Endpoints); !reflect.DeepEqual(endpoints, expectedEndpoints) {
+		t.Errorf("Expected endpoints %v, got %v", expectedEndpoints, endpoints)
+	}
+
+	properties, selection, ok := monitorProperties(monitor.Namespace, &monitor.Spec.Selector, monitor.Spec.NamespaceSelector)
+	if !ok {
+		t.Fatal("Expected the selector to be resolvable")
+	}
+	if properties["selector"] != "app=api" || properties["allNamespaces"] != false {
+		t.Errorf("Unexpected properties %v", properties)
+	}
+	if !reflect.DeepEqual(selection.Namespaces, []string{"payments", "orders"}) {
+		t.Errorf("Unexpected namespaces %v", selection.Namespaces)
+	}
+
+	invalid := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}}}
+	if _, _, ok := monitorProperties("monitoring", invalid, monitorNamespaceSelector{}); ok {
+		t.Error("Expected an invalid selector not to be resolvable")
+	}
+}
+
+func TestSelectMonitorTargets(t *testing.T) {
+	targets := []monitorTarget{
+		{UID: "api-payments", Namespace: "payments", Labels: map[string]string{"app": "api"}},
+		{UID: "api-monitoring", Namespace: "monitoring", Labels: map[string]string{"app": "api"}},
+		{UID: "web-payments", Namespace: "payments", Labels: map[string]string{"app": "web"}},
+		{UID: "api-orders", Namespace: "orders", Labels: map[string]string{"app": "api"}},
+	}
+
+	tests := []struct {
+		name      string
+		selection monitorSelection
+		expected  []string
+	}{
+		{"own namespace", monitorSelection{Namespace: "monitoring", Selector: "app=api"}, []string{"api-monitoring"}},
+		{"named namespaces", monitorSelection{Namespace: "monitoring", Selector: "app=api", Namespaces: []string{"payments", "orders"}}, []string{"api-orders", "api-payments"}},
+		{"any namespace", monitorSelection{Namespace: "monitoring", Selector: "app=api", AllNamespaces: true}, []string{"api-monitoring", "api-orders", "api-payments"}},
+		{"empty selector", monitorSelection{Namespace: "payments", Selector: ""}, []string{"api-payments", "web-payments"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if selected := selectMonitorTargets(tt.selection, targets); !reflect.DeepEqual(selected, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, selected)
+			}
+		})
+	}
+}
+
+func TestServiceMonitorHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	store.Respond("MATCH (t:Service",
+		map[string]interface{}{"uid": "svc-1", "namespace": "shop", "labels": `{"app":"api"}`},
+		map[string]interface{}{"uid": "svc-2", "namespace": "shop", "labels": `{"app":"web"}`},
+		map[string]interface{}{"uid": "svc-3", "namespace": "staging", "labels": `{"app":"api"}`},
+	)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "ServiceMonitor",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop", "uid": "sm-1"},
+		"spec": map[string]interface{}{
+			"selector":  map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+			"endpoints": []interface{}{map[string]interface{}{"port": "metrics"}},
+		},
+	}}
+	if err := NewServiceMonitorHandler(testConfig()).HandleCreate(context.Background(), obj, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "ServiceMonitor", "sm-1", map[string]interface{}{
+		"selector":  "app=api",
+		"endpoints": []string{"port=metrics"},
+	})
+	// Without a namespace selector only the services of the monitor's namespace are selected
+	monitors := store.StatementsMatching("MERGE (m)-[:MONITORS]->(t)")
+	if len(monitors) != 1 || !reflect.DeepEqual(monitors[0].Params["targets"], []string{"svc-1"}) {
+		t.Errorf("Expected the monitor to monitor svc-1, got %+v", monitors)
+	}
+}
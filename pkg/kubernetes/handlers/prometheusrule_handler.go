@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PrometheusRuleHandler tracks Prometheus Operator PrometheusRules with the
+// alerts and recording rules they define
+type PrometheusRuleHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var prometheusRuleGVR = prometheusOperatorResource("prometheusrules")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PrometheusRule",
+		GVR:               prometheusRuleGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPrometheusRuleHandler(cfg)
+		},
+	})
+}
+
+func NewPrometheusRuleHandler(cfg *config.Config) *PrometheusRuleHandler {
+	RegisterOwnerKind("PrometheusRule", "PrometheusRule")
+	return &PrometheusRuleHandler{
+		BaseHandler:  NewBaseHandler(prometheusRuleGVR, "PrometheusRule", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *PrometheusRuleHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	rule, err := ConvertToTyped[*prometheusRule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert prometheusrule: %w", err)
+	}
+
+	groups := make([]string, 0, len(rule.Spec.Groups))
+	alerts := make([]string, 0)
+	records := make([]string, 0)
+	for _, group := range rule.Spec.Groups {
+		groups = append(groups, group.Name)
+		for _, groupRule := range group.Rules {
+			if groupRule.Alert != "" {
+				alerts = append(alerts, groupRule.Alert)
+			}
+			if groupRule.Record != "" {
+				records = append(records, groupRule.Record)
+			}
+		}
+	}
+
+	properties := map[string]interface{}{
+		"name":              rule.Name,
+		"uid":               string(rule.UID),
+		"namespace":         rule.Namespace,
+		"creationTimestamp": rule.CreationTimestamp.String(),
+		"labels":            rule.Labels,
+		"annotations":       rule.Annotations,
+		"groups":            groups,
+		"alerts":            alerts,
+		"recordingRules":    records,
+		"ruleCount":         len(alerts) + len(records),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"PrometheusRule"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert prometheusrule %s: %w", rule.Name, err)
+	}
+	return nil
+}
+
+func (h *PrometheusRuleHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	rule, err := ConvertToTyped[*prometheusRule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert prometheusrule: %w", err)
+	}
+	return HandleResourceDelete(ctx, "PrometheusRule", string(rule.UID), neo4jClient)
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type PVHandler struct {
@@ -16,20 +18,33 @@ type PVHandler struct {
 	instanceHash string
 }
 
+var persistentVolumeGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "persistentvolumes",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "PersistentVolume",
+		GVR:               persistentVolumeGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewPVHandler(cfg)
+		},
+	})
+}
+
 func NewPVHandler(cfg *config.Config) *PVHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "persistentvolumes",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("PersistentVolume", "PersistentVolume")
 	return &PVHandler{
-		BaseHandler:  NewBaseHandler(gvr, "PersistentVolume", cfg),
+		BaseHandler:  NewBaseHandler(persistentVolumeGVR, "PersistentVolume", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *PVHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PVHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pv, err := ConvertToTyped[*corev1.PersistentVolume](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert persistent volume: %w", err)
@@ -50,38 +65,28 @@ func (h *PVHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClie
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"PersistentVolume"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert persistent volume %s: %w", pv.Name, err)
-	}
+	relationships := ownerRelationships("PersistentVolume", string(pv.UID), pv.OwnerReferences)
 
-	// Create relationships based on owner references for all supported types
-	if pv.OwnerReferences != nil {
-		for _, ownerRef := range pv.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"PersistentVolume", "uid", string(pv.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between PersistentVolume %s and %s %s: %v\n", pv.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	// Link CSI volumes to the driver provisioning them
+	if csi := pv.Spec.CSI; csi != nil {
+		properties["csiDriver"] = csi.Driver
+		properties["volumeHandle"] = csi.VolumeHandle
+		relationships = append(relationships, relationship("PersistentVolume", "uid", string(pv.UID), "USES_DRIVER", "CSIDriver", "name", csi.Driver))
 	}
 
 	// Create relationship with PVC if bound
 	if pv.Spec.ClaimRef != nil {
-		if err := neo4jClient.CreateRelationship(ctx, "PersistentVolume", "uid", string(pv.UID), "BOUND_TO", "PersistentVolumeClaim", "uid", string(pv.Spec.ClaimRef.UID)); err != nil {
-			return fmt.Errorf("failed to create relationship between PV %s and PVC %s: %w", pv.Name, pv.Spec.ClaimRef.Name, err)
-		}
+		relationships = append(relationships, relationship("PersistentVolume", "uid", string(pv.UID), "BOUND_TO", "PersistentVolumeClaim", "uid", string(pv.Spec.ClaimRef.UID)))
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"PersistentVolume"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert persistent volume %s: %w", pv.Name, err)
 	}
 
 	return nil
 }
 
-func (h *PVHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *PVHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	pv, err := ConvertToTyped[*corev1.PersistentVolume](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert persistent volume: %w", err)
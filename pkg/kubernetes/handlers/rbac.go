@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// rbacResource returns the GVR of an RBAC resource
+func rbacResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: rbacv1.GroupName, Version: "v1", Resource: resource}
+}
+
+// Risk levels of roles
+const (
+	riskCritical = "critical"
+	riskHigh     = "high"
+	riskNone     = "none"
+)
+
+// roleRisk summarizes the dangerous permissions granted by the rules of a role
+type roleRisk struct {
+	Level             string
+	Reasons           []string
+	WildcardVerbs     bool
+	WildcardResources bool
+	WildcardAPIGroups bool
+}
+
+// escalationVerbs let a subject gain permissions it was not granted directly
+var escalationVerbs = map[string]string{
+	"escalate":    "can escalate roles",
+	"bind":        "can bind roles",
+	"impersonate": "can impersonate subjects",
+}
+
+func containsAny(values []string, candidates ...string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// analyzeRules flags wildcard rules and rules granting access that amounts to
+// full control: reading Secrets, exec into pods and escalation verbs. A rule
+// granting every verb on every resource makes the role critical.
+func analyzeRules(rules []rbacv1.PolicyRule) roleRisk {
+	risk := roleRisk{Level: riskNone, Reasons: make([]string, 0)}
+	seen := make(map[string]bool)
+	addReason := func(reason string) {
+		if !seen[reason] {
+			seen[reason] = true
+			risk.Reasons = append(risk.Reasons, reason)
+		}
+	}
+
+	for _, rule := range rules {
+		wildcardVerbs := containsAny(rule.Verbs, rbacv1.VerbAll)
+		wildcardResources := containsAny(rule.Resources, rbacv1.ResourceAll)
+		wildcardAPIGroups := containsAny(rule.APIGroups, rbacv1.APIGroupAll)
+		risk.WildcardVerbs = risk.WildcardVerbs || wildcardVerbs
+		risk.WildcardResources = risk.WildcardResources || wildcardResources
+		risk.WildcardAPIGroups = risk.WildcardAPIGroups || wildcardAPIGroups
+
+		if wildcardVerbs && wildcardResources && wildcardAPIGroups {
+			addReason("all verbs on all resources")
+			continue
+		}
+		if wildcardVerbs {
+			addReason("wildcard verbs")
+		}
+		if wildcardResources {
+			addReason("wildcard resources")
+		}
+
+		coreGroup := containsAny(rule.APIGroups, "", rbacv1.APIGroupAll)
+		// Rules restricted to named objects do not grant access to every Secret
+		if coreGroup && len(rule.ResourceNames) == 0 &&
+			containsAny(rule.Resources, "secrets", rbacv1.ResourceAll) &&
+			containsAny(rule.Verbs, "get", "list", "watch", rbacv1.VerbAll) {
+			addReason("reads secrets")
+		}
+		if coreGroup && containsAny(rule.Resources, "pods/exec", rbacv1.ResourceAll) &&
+			containsAny(rule.Verbs, "create", rbacv1.VerbAll) {
+			addReason("exec into pods")
+		}
+		for _, verb := range rule.Verbs {
+			if reason, ok := escalationVerbs[verb]; ok {
+				addReason(reason)
+			}
+		}
+	}
+
+	switch {
+	case seen["all verbs on all resources"]:
+		risk.Level = riskCritical
+	case len(risk.Reasons) > 0:
+		risk.Level = riskHigh
+	}
+	return risk
+}
+
+// formatRule describes a policy rule as "apiGroups=...;resources=...;verbs=..."
+func formatRule(rule rbacv1.PolicyRule) string {
+	parts := make([]string, 0, 5)
+	if len(rule.NonResourceURLs) > 0 {
+		parts = append(parts, "nonResourceURLs="+strings.Join(rule.NonResourceURLs, ","))
+	} else {
+		parts = append(parts,
+			"apiGroups="+strings.Join(rule.APIGroups, ","),
+			"resources="+strings.Join(rule.Resources, ","))
+	}
+	if len(rule.ResourceNames) > 0 {
+		parts = append(parts, "resourceNames="+strings.Join(rule.ResourceNames, ","))
+	}
+	parts = append(parts, "verbs="+strings.Join(rule.Verbs, ","))
+	return strings.Join(parts, ";")
+}
+
+// roleProperties returns the rule and risk properties shared by Roles and ClusterRoles
+func roleProperties(rules []rbacv1.PolicyRule) map[string]interface{} {
+	formatted := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		formatted = append(formatted, formatRule(rule))
+	}
+	risk := analyzeRules(rules)
+	return map[string]interface{}{
+		"rules":             formatted,
+		"riskLevel":         risk.Level,
+		"riskReasons":       risk.Reasons,
+		"wildcardVerbs":     risk.WildcardVerbs,
+		"wildcardResources": risk.WildcardResources,
+		"wildcardAPIGroups": risk.WildcardAPIGroups,
+	}
+}
+
+// formatSubject identifies a binding subject as "<kind>:<name>", with the
+// name of ServiceAccounts qualified by their namespace
+func formatSubject(subject rbacv1.Subject, bindingNamespace string) string {
+	if subject.Kind == rbacv1.ServiceAccountKind {
+		return subject.Kind + ":" + namespacedKey(defaultNamespace(subject.Namespace, bindingNamespace), subject.Name)
+	}
+	return subject.Kind + ":" + subject.Name
+}
+
+// bindingSubjects returns the formatted subjects of a binding and the
+// namespace/name keys of the ServiceAccounts among them
+func bindingSubjects(subjects []rbacv1.Subject, bindingNamespace string) ([]string, []string) {
+	formatted := make([]string, 0, len(subjects))
+	serviceAccounts := make([]string, 0)
+	for _, subject := range subjects {
+		formatted = append(formatted, formatSubject(subject, bindingNamespace))
+		if subject.Kind == rbacv1.ServiceAccountKind {
+			serviceAccounts = append(serviceAccounts, namespacedKey(defaultNamespace(subject.Namespace, bindingNamespace), subject.Name))
+		}
+	}
+	return formatted, serviceAccounts
+}
+
+// linkBinding links a RoleBinding or ClusterRoleBinding to the role it grants
+// and the ServiceAccounts it binds. Roles referenced by a RoleBinding are in
+// its namespace; ClusterRoles are cluster-wide.
+func linkBinding(ctx context.Context, neo4jClient graph.Store, label, uid, namespace, clusterName string, roleRef rbacv1.RoleRef, serviceAccounts []string) {
+	match := "MATCH (r:ClusterRole {name: $roleName, clusterName: $clusterName})"
+	if roleRef.Kind == "Role" {
+		match = "MATCH (r:Role {name: $roleName, namespace: $namespace, clusterName: $clusterName})"
+	}
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (b:%s {uid: $uid})
+			%s`, label, match) +
+			graph.MergeRelationship("b", "uid", "GRANTS", "r", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"roleName":    roleRef.Name,
+			"namespace":   namespace,
+			"clusterName": clusterName,
+		},
+	})
+	if err != nil {
+		fmt.Printf("Warning: failed to create GRANTS relationship between %s %s and %s %s: %v\n", label, uid, roleRef.Kind, roleRef.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, label, uid, "BINDS", "ServiceAccount", clusterName, serviceAccounts); err != nil {
+		fmt.Printf("Warning: failed to create BINDS relationships for %s %s: %v\n", label, uid, err)
+	}
+}
+
+// linkRoleGrants creates the GRANTS relationships to a Role or ClusterRole
+// from the bindings that were synced before it
+func linkRoleGrants(ctx context.Context, neo4jClient graph.Store, kind, uid, name, namespace, clusterName string) error {
+	bindingMatch := "(b:RoleBinding OR b:ClusterRoleBinding)"
+	if kind == "Role" {
+		bindingMatch = "b:RoleBinding AND b.namespace = $namespace"
+	}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (r:%s {uid: $uid})
+			MATCH (b)
+			WHERE %s AND b.clusterName = $clusterName AND b.roleRefKind = $kind AND b.roleRefName = $name`, kind, bindingMatch) +
+			graph.MergeRelationship("b", "uid", "GRANTS", "r", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"kind":        kind,
+			"name":        name,
+			"namespace":   namespace,
+			"clusterName": clusterName,
+		},
+	})
+}
+
+// linkServiceAccountBindings creates the BINDS relationships to a
+// ServiceAccount from the bindings that were synced before it
+func linkServiceAccountBindings(ctx context.Context, neo4jClient graph.Store, uid, name, namespace, clusterName string) error {
+	quotedSubject, _ := json.Marshal(rbacv1.ServiceAccountKind + ":" + namespacedKey(namespace, name))
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (sa:ServiceAccount {uid: $uid})
+			MATCH (b)
+			WHERE (b:RoleBinding OR b:ClusterRoleBinding) AND b.clusterName = $clusterName AND b.subjects CONTAINS $quotedSubject` +
+			graph.MergeRelationship("b", "uid", "BINDS", "sa", "uid"),
+		Params: map[string]interface{}{
+			"uid":           uid,
+			"clusterName":   clusterName,
+			"quotedSubject": string(quotedSubject),
+		},
+	})
+	return err
+}
+
+// aggregationSelectors returns the label selectors of an aggregated ClusterRole
+func aggregationSelectors(rule *rbacv1.AggregationRule) []string {
+	if rule == nil {
+		return []string{}
+	}
+	selectors := make([]string, 0, len(rule.ClusterRoleSelectors))
+	for i := range rule.ClusterRoleSelectors {
+		selectors = append(selectors, metav1.FormatLabelSelector(&rule.ClusterRoleSelectors[i]))
+	}
+	return selectors
+}
+
+// matchesAnySelector reports whether the labels match one of the selectors
+func matchesAnySelector(selectors []string, roleLabels map[string]string) bool {
+	for _, selector := range selectors {
+		parsed, err := labels.Parse(selector)
+		if err != nil || parsed.Empty() {
+			continue
+		}
+		if parsed.Matches(labels.Set(roleLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkAggregation creates the AGGREGATES relationships of a ClusterRole: from
+// it to the ClusterRoles its aggregation rule selects, and to it from the
+// aggregated ClusterRoles selecting it. The controller copies the rules of the
+// selected roles into the aggregated role, so these relationships show where
+// its permissions come from.
+func linkAggregation(ctx context.Context, neo4jClient graph.Store, role *rbacv1.ClusterRole, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (r:ClusterRole {clusterName: $clusterName})
+		WHERE r.uid <> $uid
+		RETURN r.uid AS uid, r.labels AS labels, r.aggregationSelectors AS selectors`,
+		map[string]interface{}{"uid": string(role.UID), "clusterName": clusterName})
+	if err != nil {
+		return err
+	}
+
+	selectors := aggregationSelectors(role.AggregationRule)
+	aggregates := make([]string, 0)
+	aggregatedBy := make([]string, 0)
+	for _, record := range records {
+		otherUID, _ := record["uid"].(string)
+		var otherLabels map[string]string
+		if value := record["labels"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &otherLabels)
+		}
+		var otherSelectors []string
+		if value := record["selectors"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &otherSelectors)
+		}
+
+		if matchesAnySelector(selectors, otherLabels) {
+			aggregates = append(aggregates, otherUID)
+		}
+		if matchesAnySelector(otherSelectors, role.Labels) {
+			aggregatedBy = append(aggregatedBy, otherUID)
+		}
+	}
+
+	params := map[string]interface{}{
+		"uid":          string(role.UID),
+		"aggregates":   aggregates,
+		"aggregatedBy": aggregatedBy,
+	}
+	queries := []string{
+		`MATCH (r:ClusterRole {uid: $uid})-[old:AGGREGATES]->(selected:ClusterRole)` +
+			graph.DeleteRelationship("old", "r", "uid", "selected", "uid"),
+		`MATCH (r:ClusterRole {uid: $uid})<-[old:AGGREGATES]-(aggregator:ClusterRole)` +
+			graph.DeleteRelationship("old", "aggregator", "uid", "r", "uid"),
+		`MATCH (r:ClusterRole {uid: $uid})
+		 MATCH (selected:ClusterRole) WHERE selected.uid IN $aggregates` +
+			graph.MergeRelationship("r", "uid", "AGGREGATES", "selected", "uid"),
+		`MATCH (r:ClusterRole {uid: $uid})
+		 MATCH (aggregator:ClusterRole) WHERE aggregator.uid IN $aggregatedBy` +
+			graph.MergeRelationship("aggregator", "uid", "AGGREGATES", "r", "uid"),
+	}
+	statements := make([]graph.Statement, 0, len(queries))
+	for _, query := range queries {
+		statements = append(statements, graph.Statement{Query: query, Params: params})
+	}
+	return neo4jClient.Write(ctx, statements...)
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnalyzeRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []rbacv1.PolicyRule
+		level   string
+		reasons []string
+	}{
+		{
+			name:    "cluster admin",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+			level:   riskCritical,
+			reasons: []string{"all verbs on all resources"},
+		},
+		{
+			name:    "read only",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{"", "apps"}, Resources: []string{"pods", "deployments"}, Verbs: []string{"get", "list", "watch"}}},
+			level:   riskNone,
+			reasons: []string{},
+		},
+		{
+			name:    "wildcard verbs on deployments",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"*"}}},
+			level:   riskHigh,
+			reasons: []string{"wildcard verbs"},
+		},
+		{
+			name:    "reads secrets",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"list"}}},
+			level:   riskHigh,
+			reasons: []string{"reads secrets"},
+		},
+		{
+			name:    "reads a named secret",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"tls"}, Verbs: []string{"get"}}},
+			level:   riskNone,
+			reasons: []string{},
+		},
+		{
+			name:    "wildcard resources in core group",
+			rules:   []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, Verbs: []string{"get", "create"}}},
+			level:   riskHigh,
+			reasons: []string{"wildcard resources", "reads secrets", "exec into pods"},
+		},
+		{
+			name: "escalation",
+			rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"bind", "escalate"}},
+				{APIGroups: []string{""}, Resources: []string{"users"}, Verbs: []string{"impersonate"}},
+			},
+			level:   riskHigh,
+			reasons: []string{"can bind roles", "can escalate roles", "can impersonate subjects"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := analyzeRules(tt.rules)
+			if risk.Level != tt.level {
+				t.Errorf("Expected level %s, got %s", tt.level, risk.Level)
+			}
+			if !reflect.DeepEqual(risk.Reasons, tt.reasons) {
+				t.Errorf("Expected reasons %v, got %v", tt.reasons, risk.Reasons)
+			}
+		})
+	}
+}
+
+func TestAnalyzeRulesWildcardFlags(t *testing.T) {
+	risk := analyzeRules([]rbacv1.PolicyRule{
+		{APIGroups: []string{"*"}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"*"}},
+	})
+	if !risk.WildcardAPIGroups || !risk.WildcardVerbs || risk.WildcardResources {
+		t.Errorf("Unexpected wildcard flags %+v", risk)
+	}
+}
+
+func TestFormatRule(t *testing.T) {
+	tests := []struct {
+		rule     rbacv1.PolicyRule
+		expected string
+	}{
+		{
+			rbacv1.PolicyRule{APIGroups: []string{"", "apps"}, Resources: []string{"pods", "deployments"}, Verbs: []string{"get", "list"}},
+			"apiGroups=,apps;resources=pods,deployments;verbs=get,list",
+		},
+		{
+			rbacv1.PolicyRule{APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"settings"}, Verbs: []string{"get"}},
+			"apiGroups=;resources=configmaps;resourceNames=settings;verbs=get",
+		},
+		{
+			rbacv1.PolicyRule{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+			"nonResourceURLs=/metrics;verbs=get",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := formatRule(tt.rule); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
+
+func TestBindingSubjects(t *testing.T) {
+	subjects, serviceAccounts := bindingSubjects([]rbacv1.Subject{
+		{Kind: "ServiceAccount", Name: "deployer"},
+		{Kind: "ServiceAccount", Name: "ci", Namespace: "tools"},
+		{Kind: "User", Name: "alice@example.com"},
+		{Kind: "Group", Name: "system:masters"},
+	}, "shop")
+
+	expectedSubjects := []string{"ServiceAccount:shop/deployer", "ServiceAccount:tools/ci", "User:alice@example.com", "Group:system:masters"}
+	if !reflect.DeepEqual(subjects, expectedSubjects) {
+		t.Errorf("Expected subjects %v, got %v", expectedSubjects, subjects)
+	}
+	expectedServiceAccounts := []string{"shop/deployer", "tools/ci"}
+	if !reflect.DeepEqual(serviceAccounts, expectedServiceAccounts) {
+		t.Errorf("Expected service accounts %v, got %v", expectedServiceAccounts, serviceAccounts)
+	}
+}
+
+func TestMatchesAnySelector(t *testing.T) {
+	selectors := aggregationSelectors(&rbacv1.AggregationRule{
+		ClusterRoleSelectors: []metav1.LabelSelector{
+			{MatchLabels: map[string]string{"rbac.authorization.k8s.io/aggregate-to-admin": "true"}},
+			{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: metav1.LabelSelectorOpIn, Values: []string{"payments"}}}},
+		},
+	})
+
+	tests := []struct {
+		labels   map[string]string
+		expected bool
+	}{
+		{map[string]string{"rbac.authorization.k8s.io/aggregate-to-admin": "true"}, true},
+		{map[string]string{"team": "payments", "tier": "backend"}, true},
+		{map[string]string{"rbac.authorization.k8s.io/aggregate-to-view": "true"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesAnySelector(selectors, tt.labels); got != tt.expected {
+			t.Errorf("matchesAnySelector(%v): expected %t, got %t", tt.labels, tt.expected, got)
+		}
+	}
+
+	if len(aggregationSelectors(nil)) != 0 {
+		t.Error("Expected no selectors for a role without aggregation rule")
+	}
+}
+
+func TestRoleBindingHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-reader", Namespace: "shop", UID: "rb-1"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "reader"},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Name: "api"},
+			{Kind: rbacv1.ServiceAccountKind, Name: "prometheus", Namespace: "monitoring"},
+			{Kind: rbacv1.GroupKind, Name: "developers"},
+		},
+	}
+	if err := NewRoleBindingHandler(testConfig()).HandleCreate(context.Background(), binding, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "RoleBinding", "rb-1", map[string]interface{}{
+		"roleRefKind": "Role",
+		"roleRefName": "reader",
+		"subjects":    []string{"ServiceAccount:shop/api", "ServiceAccount:monitoring/prometheus", "Group:developers"},
+	})
+	grants := store.StatementsMatching("MERGE (b)-[:GRANTS]->(r)")
+	if len(grants) != 1 || !strings.Contains(grants[0].Query, "MATCH (r:Role {name: $roleName, namespace: $namespace") || grants[0].Params["namespace"] != "shop" {
+		t.Errorf("Expected the binding to grant the Role of its namespace, got %+v", grants)
+	}
+	binds := store.StatementsMatching("MERGE (from)-[:BINDS]->(target)")
+	expected := []map[string]interface{}{{"namespace": "shop", "name": "api"}, {"namespace": "monitoring", "name": "prometheus"}}
+	if len(binds) != 1 || !reflect.DeepEqual(binds[0].Params["targets"], expected) {
+		t.Errorf("Expected the binding to bind its service accounts, got %+v", binds)
+	}
+}
+
+func TestClusterRoleBindingHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "prometheus", UID: "crb-1"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "prometheus", Namespace: "monitoring"}},
+	}
+	if err := NewClusterRoleBindingHandler(testConfig()).HandleCreate(context.Background(), binding, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "ClusterRoleBinding", "crb-1", map[string]interface{}{"subjects": []string{"ServiceAccount:monitoring/prometheus"}})
+	grants := store.StatementsMatching("MERGE (b)-[:GRANTS]->(r)")
+	if len(grants) != 1 || !strings.Contains(grants[0].Query, "MATCH (r:ClusterRole {name: $roleName, clusterName: $clusterName})") || grants[0].Params["roleName"] != "view" {
+		t.Errorf("Expected the binding to grant the ClusterRole, got %+v", grants)
+	}
+}
+
+func TestRoleHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "reader", Namespace: "shop", UID: "role-1"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+	}
+	if err := NewRoleHandler(testConfig()).HandleCreate(context.Background(), role, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "Role", "role-1", map[string]interface{}{"wildcardVerbs": true})
+	// RoleBindings synced before the role are linked to it
+	grants := store.StatementsMatching("MERGE (b)-[:GRANTS]->(r)")
+	if len(grants) != 1 || grants[0].Params["kind"] != "Role" || grants[0].Params["name"] != "reader" || grants[0].Params["namespace"] != "shop" {
+		t.Errorf("Expected the bindings of the role to be linked to it, got %+v", grants)
+	}
+}
+
+func TestClusterRoleHandlerLinksAggregation(t *testing.T) {
+	store := graphtest.NewStore()
+	store.Respond("RETURN r.uid AS uid, r.labels AS labels",
+		map[string]interface{}{"uid": "cr-view", "labels": `{"rbac.example.com/aggregate-to-admin":"true"}`, "selectors": `[]`},
+		map[string]interface{}{"uid": "cr-edit", "labels": `{}`, "selectors": `[]`},
+		map[string]interface{}{"uid": "cr-super", "labels": `{}`, "selectors": `["team=platform"]`},
+	)
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "admin", UID: "cr-admin", Labels: map[string]string{"team": "platform"}},
+		AggregationRule: &rbacv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+			{MatchLabels: map[string]string{"rbac.example.com/aggregate-to-admin": "true"}},
+		}},
+	}
+	if err := NewClusterRoleHandler(testConfig()).HandleCreate(context.Background(), role, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "ClusterRole", "cr-admin", map[string]interface{}{
+		"aggregated":           true,
+		"aggregationSelectors": []string{"rbac.example.com/aggregate-to-admin=true"},
+	})
+	aggregates := store.StatementsMatching("MERGE (r)-[:AGGREGATES]->(selected)")
+	if len(aggregates) != 1 {
+		t.Fatalf("Expected one AGGREGATES statement, got %+v", aggregates)
+	}
+	if got := aggregates[0].Params["aggregates"]; !reflect.DeepEqual(got, []string{"cr-view"}) {
+		t.Errorf("Expected the role to aggregate cr-view, got %v", got)
+	}
+	if got := aggregates[0].Params["aggregatedBy"]; !reflect.DeepEqual(got, []string{"cr-super"}) {
+		t.Errorf("Expected the role to be aggregated by cr-super, got %v", got)
+	}
+}
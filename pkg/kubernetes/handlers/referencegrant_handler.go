@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ReferenceGrantHandler tracks Gateway API ReferenceGrants, which allow
+// routes in other namespaces to reference resources in the grant's namespace
+type ReferenceGrantHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var referenceGrantGVR = gatewayAPIResource("referencegrants")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ReferenceGrant",
+		GVR:               referenceGrantGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewReferenceGrantHandler(cfg)
+		},
+	})
+}
+
+func NewReferenceGrantHandler(cfg *config.Config) *ReferenceGrantHandler {
+	RegisterOwnerKind("ReferenceGrant", "ReferenceGrant")
+	return &ReferenceGrantHandler{
+		BaseHandler:  NewBaseHandler(referenceGrantGVR, "ReferenceGrant", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ReferenceGrantHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	grant, err := ConvertToTyped[*referenceGrant](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert referencegrant: %w", err)
+	}
+
+	// "from" entries read as <kind>/<namespace>, "to" entries as <kind>[/<name>]
+	from := make([]string, 0, len(grant.Spec.From))
+	for _, f := range grant.Spec.From {
+		from = append(from, namespacedKey(f.Kind, f.Namespace))
+	}
+	to := make([]string, 0, len(grant.Spec.To))
+	for _, t := range grant.Spec.To {
+		if t.Name == "" {
+			to = append(to, t.Kind)
+			continue
+		}
+		to = append(to, namespacedKey(t.Kind, t.Name))
+	}
+
+	properties := map[string]interface{}{
+		"name":              grant.Name,
+		"uid":               string(grant.UID),
+		"namespace":         grant.Namespace,
+		"creationTimestamp": grant.CreationTimestamp.String(),
+		"labels":            grant.Labels,
+		"annotations":       grant.Annotations,
+		"from":              from,
+		"to":                to,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"ReferenceGrant"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert referencegrant %s: %w", grant.Name, err)
+	}
+	return nil
+}
+
+func (h *ReferenceGrantHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	grant, err := ConvertToTyped[*referenceGrant](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert referencegrant: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ReferenceGrant", string(grant.UID), neo4jClient)
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// podSecretReferences returns the names of the Secrets a pod spec references
+// through volumes, environment variables and image pull secrets
+func podSecretReferences(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" {
+			seen[name] = true
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			add(volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil {
+					add(source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	addContainer := func(env []corev1.EnvVar, envFrom []corev1.EnvFromSource) {
+		for _, variable := range env {
+			if variable.ValueFrom != nil && variable.ValueFrom.SecretKeyRef != nil {
+				add(variable.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+		for _, source := range envFrom {
+			if source.SecretRef != nil {
+				add(source.SecretRef.Name)
+			}
+		}
+	}
+	for _, container := range spec.InitContainers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+	for _, container := range spec.Containers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+	for _, container := range spec.EphemeralContainers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+
+	for _, name := range pullSecretNames(spec) {
+		add(name)
+	}
+	return sortedKeys(seen)
+}
+
+// podConfigMapReferences returns the names of the ConfigMaps a pod spec
+// references through volumes and environment variables
+func podConfigMapReferences(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" {
+			seen[name] = true
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			add(volume.ConfigMap.Name)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(source.ConfigMap.Name)
+				}
+			}
+		}
+	}
+
+	addContainer := func(env []corev1.EnvVar, envFrom []corev1.EnvFromSource) {
+		for _, variable := range env {
+			if variable.ValueFrom != nil && variable.ValueFrom.ConfigMapKeyRef != nil {
+				add(variable.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+		for _, source := range envFrom {
+			if source.ConfigMapRef != nil {
+				add(source.ConfigMapRef.Name)
+			}
+		}
+	}
+	for _, container := range spec.InitContainers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+	for _, container := range spec.Containers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+	for _, container := range spec.EphemeralContainers {
+		addContainer(container.Env, container.EnvFrom)
+	}
+	return sortedKeys(seen)
+}
+
+// linkReferences creates USES relationships from a resource to the Secrets or
+// ConfigMaps with the given names in its namespace. Targets that do not exist
+// yet are skipped; they link their users when they are synced.
+func linkReferences(ctx context.Context, neo4jClient graph.Store, label, uid, namespace, clusterName, targetLabel string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (from:%s {uid: $uid})
+			UNWIND $names AS name
+			MATCH (target:%s {name: name, namespace: $namespace, clusterName: $clusterName})`, label, targetLabel) +
+			graph.MergeRelationship("from", "uid", "USES", "target", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"names":       names,
+			"namespace":   namespace,
+			"clusterName": clusterName,
+		},
+	})
+}
+
+// linkPodUsers creates the USES relationships to a Secret or ConfigMap from
+// the pods that were synced before it. The pods list the names they reference
+// in property as a JSON list.
+func linkPodUsers(ctx context.Context, neo4jClient graph.Store, targetLabel, uid, name, namespace, clusterName, property string) error {
+	quotedName, _ := json.Marshal(name)
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (target:%s {uid: $uid})
+			MATCH (p:Pod {namespace: $namespace, clusterName: $clusterName})
+			WHERE p.%s CONTAINS $quotedName`, targetLabel, property) +
+			graph.MergeRelationship("p", "uid", "USES", "target", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"namespace":   namespace,
+			"clusterName": clusterName,
+			"quotedName":  string(quotedName),
+		},
+	})
+}
+
+// linkServiceAccountPods creates the USES_SERVICE_ACCOUNT relationships to a
+// ServiceAccount from the pods that were synced before it
+func linkServiceAccountPods(ctx context.Context, neo4jClient graph.Store, uid, name, namespace, clusterName string) error {
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (sa:ServiceAccount {uid: $uid})
+			MATCH (p:Pod {namespace: $namespace, clusterName: $clusterName})
+			WHERE coalesce(p.serviceAccount, '') = $name OR ($name = 'default' AND coalesce(p.serviceAccount, '') = '')` +
+			graph.MergeRelationship("p", "uid", "USES_SERVICE_ACCOUNT", "sa", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"name":        name,
+			"namespace":   namespace,
+			"clusterName": clusterName,
+		},
+	})
+	return err
+}
+
+// linkSecretUsers creates the USES relationships to a Secret from the pods
+// that were synced before it, and from the service account of a token secret
+func linkSecretUsers(ctx context.Context, neo4jClient graph.Store, secret *corev1.Secret, clusterName string) error {
+	if err := linkPodUsers(ctx, neo4jClient, "Secret", string(secret.UID), secret.Name, secret.Namespace, clusterName, "secrets"); err != nil {
+		return err
+	}
+	if secret.Type != corev1.SecretTypeServiceAccountToken {
+		return nil
+	}
+
+	params := map[string]interface{}{
+		"uid":            string(secret.UID),
+		"namespace":      secret.Namespace,
+		"clusterName":    clusterName,
+		"serviceAccount": secret.Annotations[corev1.ServiceAccountNameKey],
+	}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (s:Secret {uid: $uid})
+			MATCH (sa:ServiceAccount {name: $serviceAccount, namespace: $namespace, clusterName: $clusterName})` +
+			graph.MergeRelationship("sa", "uid", "USES", "s", "uid"),
+		Params: params,
+	})
+}
+
+// secretDataHash returns a hash of the data of a Secret, so rotations can be
+// detected without storing the data. The UID is part of the hash so equal
+// values in different Secrets do not produce equal hashes.
+func secretDataHash(secret *corev1.Secret) string {
+	if len(secret.Data) == 0 {
+		return ""
+	}
+	// json.Marshal sorts map keys, so equal data produces equal hashes
+	data, err := json.Marshal(secret.Data)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(secret.UID), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// namespacedKey identifies a resource referenced across namespaces
+func namespacedKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func defaultNamespace(namespace, fallback string) string {
+	if namespace == "" {
+		return fallback
+	}
+	return namespace
+}
+
+// linkNamespacedTargets replaces the relationships of a resource to the
+// targets identified by namespace/name keys. Targets that are not synced yet
+// are linked by their own handler when they are, with linkReferrers.
+func linkNamespacedTargets(ctx context.Context, neo4jClient graph.Store, label, uid, relationshipType, targetLabel, clusterName string, keys []string) error {
+	targets := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		namespace, name, _ := strings.Cut(key, "/")
+		targets = append(targets, map[string]interface{}{"namespace": namespace, "name": name})
+	}
+
+	params := map[string]interface{}{
+		"uid":         uid,
+		"targets":     targets,
+		"clusterName": clusterName,
+	}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (from:%s {uid: $uid})-[old:%s]->(target:%s)`, label, relationshipType, targetLabel) +
+			graph.DeleteRelationship("old", "from", "uid", "target", "uid"),
+		Params: params,
+	}, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (from:%s {uid: $uid})
+			UNWIND $targets AS t
+			MATCH (target:%s {namespace: t.namespace, name: t.name, clusterName: $clusterName})`, label, targetLabel) +
+			graph.MergeRelationship("from", "uid", relationshipType, "target", "uid"),
+		Params: params,
+	})
+}
+
+// linkReferrers creates the relationships to a resource from the referrers
+// that were synced before it. The referrers list the namespace/name keys they
+// reference in property as a JSON list.
+func linkReferrers(ctx context.Context, neo4jClient graph.Store, referrerLabel, targetLabel, uid, name, namespace, clusterName, relationshipType, property string) error {
+	quotedKey, _ := json.Marshal(namespacedKey(namespace, name))
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+			MATCH (target:%s {uid: $uid})
+			MATCH (r:%s {clusterName: $clusterName})
+			WHERE r.%s CONTAINS $quotedKey`, targetLabel, referrerLabel, property) +
+			graph.MergeRelationship("r", "uid", relationshipType, "target", "uid"),
+		Params: map[string]interface{}{
+			"uid":         uid,
+			"clusterName": clusterName,
+			"quotedKey":   string(quotedKey),
+		},
+	})
+}
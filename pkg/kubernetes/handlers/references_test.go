@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodSecretReferences(t *testing.T) {
+	spec := corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "tls"}}},
+			{VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+				{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected"}}},
+			}}}},
+		},
+		InitContainers: []corev1.Container{{
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "init-env"}}}},
+		}},
+		Containers: []corev1.Container{{
+			Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "x"},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password",
+				}}},
+			},
+		}},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}, {Name: "tls"}},
+	}
+
+	expected := []string{"db", "init-env", "projected", "registry", "tls"}
+	if got := podSecretReferences(spec); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestSecretDataHash(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("a"), "user": []byte("b")}}
+	secret.UID = "uid-1"
+	rotated := secret.DeepCopy()
+	rotated.Data["password"] = []byte("c")
+	other := secret.DeepCopy()
+	other.UID = "uid-2"
+
+	if secretDataHash(secret) != secretDataHash(secret.DeepCopy()) {
+		t.Error("Expected equal data to produce equal hashes")
+	}
+	if secretDataHash(secret) == secretDataHash(rotated) {
+		t.Error("Expected rotated data to produce a different hash")
+	}
+	if secretDataHash(secret) == secretDataHash(other) {
+		t.Error("Expected equal data in different Secrets to produce different hashes")
+	}
+	if secretDataHash(&corev1.Secret{}) != "" {
+		t.Error("Expected no hash for a Secret without data")
+	}
+}
+
+func TestPodConfigMapReferences(t *testing.T) {
+	ref := func(name string) corev1.LocalObjectReference { return corev1.LocalObjectReference{Name: name} }
+	spec := corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: ref("app-config")}}},
+			{VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+				{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: ref("kube-root-ca.crt")}},
+			}}}},
+		},
+		Containers: []corev1.Container{{
+			Env: []corev1.EnvVar{
+				{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: ref("logging"), Key: "level"}}},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: ref("db"), Key: "password"}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: ref("app-config")}}},
+		}},
+	}
+
+	expected := []string{"app-config", "kube-root-ca.crt", "logging"}
+	if got := podConfigMapReferences(spec); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
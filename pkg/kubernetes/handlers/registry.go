@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// dockerHubRegistry is the registry of images without a registry host
+const dockerHubRegistry = "docker.io"
+
+// imageRegistry returns the registry host of an image reference. Following
+// the Docker rules, the first path component is only a registry host if it
+// contains a "." or ":" or is "localhost"; otherwise the image is on Docker Hub.
+func imageRegistry(image string) string {
+	slash := strings.Index(image, "/")
+	if slash < 0 {
+		return dockerHubRegistry
+	}
+	host := image[:slash]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubRegistry
+	}
+	return normalizeRegistry(host)
+}
+
+// normalizeRegistry maps the aliases of a registry to one host, so image
+// references and pull secret entries for the same registry match
+func normalizeRegistry(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	if slash := strings.Index(host, "/"); slash >= 0 {
+		host = host[:slash]
+	}
+	host = strings.ToLower(host)
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// podSpecRegistries returns the sorted registries the containers of a pod spec pull from
+func podSpecRegistries(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	for _, container := range spec.InitContainers {
+		seen[imageRegistry(container.Image)] = true
+	}
+	for _, container := range spec.Containers {
+		seen[imageRegistry(container.Image)] = true
+	}
+	for _, container := range spec.EphemeralContainers {
+		seen[imageRegistry(container.Image)] = true
+	}
+	return sortedKeys(seen)
+}
+
+// pullSecretNames returns the names of the image pull secrets of a pod spec
+func pullSecretNames(spec corev1.PodSpec) []string {
+	names := make([]string, 0, len(spec.ImagePullSecrets))
+	for _, ref := range spec.ImagePullSecrets {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// dockerConfigRegistries returns the registries an image pull secret holds
+// credentials for. Only the registry hosts are read, never the credentials.
+func dockerConfigRegistries(secret *corev1.Secret) []string {
+	var auths map[string]json.RawMessage
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]json.RawMessage `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for host := range auths {
+		seen[normalizeRegistry(host)] = true
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// registryKey identifies the Registry node of a host within a cluster
+func registryKey(clusterName, host string) string {
+	return clusterName + "/" + host
+}
+
+// upsertRegistry creates the Registry node of a host
+func upsertRegistry(ctx context.Context, neo4jClient graph.Store, clusterName, instanceHash, host string) error {
+	properties := map[string]interface{}{
+		"key":          registryKey(clusterName, host),
+		"name":         host,
+		"clusterName":  clusterName,
+		"instanceHash": instanceHash,
+	}
+	return neo4jClient.UpsertNode(ctx, []string{"Registry"}, properties, "key")
+}
+
+// linkRegistries creates the Registry nodes of the hosts and a relationship
+// from the resource to each of them
+func linkRegistries(ctx context.Context, neo4jClient graph.Store, label, uid, relationshipType, clusterName, instanceHash string, hosts []string) {
+	for _, host := range hosts {
+		if err := upsertRegistry(ctx, neo4jClient, clusterName, instanceHash, host); err != nil {
+			fmt.Printf("Warning: failed to upsert Registry %s: %v\n", host, err)
+			continue
+		}
+		if err := neo4jClient.UpsertRelationship(ctx, relationship(label, "uid", uid, relationshipType, "Registry", "key", registryKey(clusterName, host))); err != nil {
+			fmt.Printf("Warning: failed to create %s relationship between %s %s and Registry %s: %v\n", relationshipType, label, uid, host, err)
+		}
+	}
+}
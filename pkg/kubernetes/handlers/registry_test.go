@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImageRegistry(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx", "docker.io"},
+		{"nginx:1.25", "docker.io"},
+		{"bitnami/redis:7.2", "docker.io"},
+		{"docker.io/library/nginx", "docker.io"},
+		{"index.docker.io/library/nginx", "docker.io"},
+		{"ghcr.io/org/app:v1", "ghcr.io"},
+		{"registry.example.com:5000/team/app@sha256:abc", "registry.example.com:5000"},
+		{"localhost/app", "localhost"},
+		{"localhost:5000/app", "localhost:5000"},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com/app", "123456789012.dkr.ecr.eu-west-1.amazonaws.com"},
+	}
+
+	for _, test := range tests {
+		if got := imageRegistry(test.image); got != test.expected {
+			t.Errorf("imageRegistry(%q): expected %q, got %q", test.image, test.expected, got)
+		}
+	}
+}
+
+func TestPodSpecRegistries(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Image: "busybox"}},
+		Containers:     []corev1.Container{{Image: "ghcr.io/org/app:v1"}, {Image: "nginx"}},
+	}
+	expected := []string{"docker.io", "ghcr.io"}
+	if got := podSpecRegistries(spec); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestDockerConfigRegistries(t *testing.T) {
+	secret := &corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"x"},"GHCR.io":{"auth":"y"}}}`),
+		},
+	}
+	expected := []string{"docker.io", "ghcr.io"}
+	if got := dockerConfigRegistries(secret); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	legacy := &corev1.Secret{
+		Type: corev1.SecretTypeDockercfg,
+		Data: map[string][]byte{
+			corev1.DockerConfigKey: []byte(`{"quay.io":{"auth":"z"}}`),
+		},
+	}
+	if got := dockerConfigRegistries(legacy); !reflect.DeepEqual(got, []string{"quay.io"}) {
+		t.Errorf("Expected [quay.io], got %v", got)
+	}
+
+	opaque := &corev1.Secret{Type: corev1.SecretTypeOpaque}
+	if got := dockerConfigRegistries(opaque); got != nil {
+		t.Errorf("Expected no registries for an opaque secret, got %v", got)
+	}
+}
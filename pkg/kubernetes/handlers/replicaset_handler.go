@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,22 +20,35 @@ type ReplicaSetHandler struct {
 	instanceHash string
 }
 
+var replicaSetGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "replicasets",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ReplicaSet",
+		GVR:               replicaSetGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewReplicaSetHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewReplicaSetHandler(clientset *kubernetes.Clientset, cfg *config.Config) *ReplicaSetHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "apps",
-		Version:  "v1",
-		Resource: "replicasets",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("ReplicaSet", "ReplicaSet")
 	return &ReplicaSetHandler{
-		BaseHandler:  NewBaseHandler(gvr, "ReplicaSet", cfg),
+		BaseHandler:  NewBaseHandler(replicaSetGVR, "ReplicaSet", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *ReplicaSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ReplicaSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	rs, err := ConvertToTyped[*appsv1.ReplicaSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert replicaset: %w", err)
@@ -51,33 +65,16 @@ func (h *ReplicaSetHandler) HandleCreate(ctx context.Context, obj interface{}, n
 		"availableReplicas": rs.Status.AvailableReplicas,
 		"readyReplicas":     rs.Status.ReadyReplicas,
 		"selector":          rs.Spec.Selector.MatchLabels,
+		"revision":          replicaSetRevision(rs.Annotations),
+		"containerImages":   containerImages(rs.Spec.Template.Spec),
 		"clusterName":       h.GetClusterName(),
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"ReplicaSet"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert replicaset %s: %w", rs.Name, err)
-	}
-
-	// Create relationships based on owner references for all supported types
-	if rs.OwnerReferences != nil {
-		for _, ownerRef := range rs.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"ReplicaSet", "uid", string(rs.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between ReplicaSet %s and %s %s: %v\n", rs.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("ReplicaSet", string(rs.UID), rs.OwnerReferences)
 
 	// Create relationships with pods
-	if rs.Spec.Selector != nil {
+	if rs.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(rs.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(rs.Spec.Selector),
 		})
@@ -86,16 +83,20 @@ func (h *ReplicaSetHandler) HandleCreate(ctx context.Context, obj interface{}, n
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "ReplicaSet", "uid", string(rs.UID), "MANAGES", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between replicaset %s and pod %s: %w", rs.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("ReplicaSet", "uid", string(rs.UID), "MANAGES", "Pod", "uid", string(pod.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"ReplicaSet"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert replicaset %s: %w", rs.Name, err)
+	}
+
+	rollupStorageForWorkload(ctx, neo4jClient, "ReplicaSet", string(rs.UID), rs.Name)
+
 	return nil
 }
 
-func (h *ReplicaSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ReplicaSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	rs, err := ConvertToTyped[*appsv1.ReplicaSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert replicaset: %w", err)
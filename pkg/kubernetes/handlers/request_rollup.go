@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+
+	"k8s-graph/pkg/graph"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// requestRollupQuery recomputes requestedCPU (millicores) and requestedMemory
+// (bytes) of the nodes bound to n from the pods scheduled on them. Pods that
+// terminated no longer hold their requests, and pods with the uid $exclude are
+// left out so a pod being deleted no longer counts. The properties are set
+// outside of UpsertNode and are stored as integers.
+const requestRollupQuery = `
+	CALL {
+		WITH n
+		OPTIONAL MATCH (n)<-[:SCHEDULED_ON]-(pod:Pod)
+		WHERE pod.uid <> $exclude AND NOT pod.status IN ['Succeeded', 'Failed']
+		RETURN sum(coalesce(toInteger(pod.requestedCPU), 0)) AS cpu, sum(coalesce(toInteger(pod.requestedMemory), 0)) AS memory
+	}
+	SET n.requestedCPU = cpu, n.requestedMemory = memory`
+
+// podRequests returns the CPU (millicores) and memory (bytes) the scheduler
+// reserves for a pod: the larger of the sum of its containers and of its
+// largest init container, plus the pod overhead
+func podRequests(spec corev1.PodSpec) (int64, int64) {
+	var cpu, memory int64
+	for _, container := range spec.Containers {
+		cpu += quantityMilli(container.Resources.Requests, corev1.ResourceCPU)
+		memory += quantityValue(container.Resources.Requests, corev1.ResourceMemory)
+	}
+	for _, container := range spec.InitContainers {
+		cpu = max(cpu, quantityMilli(container.Resources.Requests, corev1.ResourceCPU))
+		memory = max(memory, quantityValue(container.Resources.Requests, corev1.ResourceMemory))
+	}
+	cpu += quantityMilli(spec.Overhead, corev1.ResourceCPU)
+	memory += quantityValue(spec.Overhead, corev1.ResourceMemory)
+	return cpu, memory
+}
+
+// podLimits returns the CPU (millicores) and memory (bytes) limits of the
+// containers of a pod, containers without a limit not being counted
+func podLimits(spec corev1.PodSpec) (int64, int64) {
+	var cpu, memory int64
+	for _, container := range spec.Containers {
+		cpu += quantityMilli(container.Resources.Limits, corev1.ResourceCPU)
+		memory += quantityValue(container.Resources.Limits, corev1.ResourceMemory)
+	}
+	return cpu, memory
+}
+
+func quantityMilli(resources corev1.ResourceList, name corev1.ResourceName) int64 {
+	if quantity, ok := resources[name]; ok {
+		return quantity.MilliValue()
+	}
+	return 0
+}
+
+func quantityValue(resources corev1.ResourceList, name corev1.ResourceName) int64 {
+	if quantity, ok := resources[name]; ok {
+		return quantity.Value()
+	}
+	return 0
+}
+
+// rollupRequests runs the request rollup for the nodes matched by match,
+// which must bind n
+func rollupRequests(ctx context.Context, neo4jClient graph.Store, match string, params map[string]interface{}) error {
+	if _, ok := params["exclude"]; !ok {
+		params["exclude"] = ""
+	}
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query:  match + "\nWITH DISTINCT n" + requestRollupQuery,
+		Params: params,
+	})
+	return err
+}
+
+// rollupRequestsForPod updates the node a pod is scheduled on. When the pod is
+// being deleted, removed is true and its requests are no longer counted.
+func rollupRequestsForPod(ctx context.Context, neo4jClient graph.Store, podUID string, removed bool) error {
+	params := map[string]interface{}{"uid": podUID}
+	if removed {
+		params["exclude"] = podUID
+	}
+	return rollupRequests(ctx, neo4jClient, "MATCH (:Pod {uid: $uid})-[:SCHEDULED_ON]->(n:Node)", params)
+}
+
+// rollupRequestsForNode restores requestedCPU and requestedMemory of a node
+// after it was upserted, which replaces all of its properties
+func rollupRequestsForNode(ctx context.Context, neo4jClient graph.Store, uid string) error {
+	return rollupRequests(ctx, neo4jClient, "MATCH (n:Node {uid: $uid})", map[string]interface{}{"uid": uid})
+}
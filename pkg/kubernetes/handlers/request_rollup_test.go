@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func withRequests(cpu, memory string) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}}
+}
+
+func TestPodRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		spec           corev1.PodSpec
+		expectedCPU    int64
+		expectedMemory int64
+	}{
+		{
+			name:           "no requests",
+			spec:           corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			expectedCPU:    0,
+			expectedMemory: 0,
+		},
+		{
+			name: "containers are summed",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Resources: withRequests("250m", "256Mi")},
+				{Name: "sidecar", Resources: withRequests("50m", "64Mi")},
+			}},
+			expectedCPU:    300,
+			expectedMemory: 320 * 1024 * 1024,
+		},
+		{
+			name: "largest init container wins",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "migrate", Resources: withRequests("1", "128Mi")}},
+				Containers:     []corev1.Container{{Name: "app", Resources: withRequests("250m", "256Mi")}},
+			},
+			expectedCPU:    1000,
+			expectedMemory: 256 * 1024 * 1024,
+		},
+		{
+			name: "overhead is added",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Resources: withRequests("100m", "1Gi")}},
+				Overhead:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+			},
+			expectedCPU:    110,
+			expectedMemory: 1024 * 1024 * 1024,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu, memory := podRequests(test.spec)
+			if cpu != test.expectedCPU || memory != test.expectedMemory {
+				t.Errorf("Expected %dm and %d bytes, got %dm and %d bytes", test.expectedCPU, test.expectedMemory, cpu, memory)
+			}
+		})
+	}
+}
+
+func TestPodLimits(t *testing.T) {
+	spec := corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		}}},
+		{Name: "sidecar", Resources: withRequests("50m", "64Mi")},
+	}}
+
+	cpu, memory := podLimits(spec)
+	if cpu != 500 || memory != 512*1024*1024 {
+		t.Errorf("Expected 500m and 512Mi, got %dm and %d bytes", cpu, memory)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RoleHandler tracks namespaced Roles and flags the ones granting wildcard or
+// otherwise dangerous permissions
+type RoleHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var roleGVR = rbacResource("roles")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Role",
+		GVR:               roleGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewRoleHandler(cfg)
+		},
+	})
+}
+
+func NewRoleHandler(cfg *config.Config) *RoleHandler {
+	RegisterOwnerKind("Role", "Role")
+	return &RoleHandler{
+		BaseHandler:  NewBaseHandler(roleGVR, "Role", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *RoleHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	role, err := ConvertToTyped[*rbacv1.Role](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert role: %w", err)
+	}
+
+	properties := roleProperties(role.Rules)
+	properties["name"] = role.Name
+	properties["uid"] = string(role.UID)
+	properties["namespace"] = role.Namespace
+	properties["creationTimestamp"] = role.CreationTimestamp.String()
+	properties["labels"] = role.Labels
+	properties["annotations"] = role.Annotations
+	properties["clusterName"] = h.GetClusterName()
+	properties["instanceHash"] = h.instanceHash
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Role"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert role %s: %w", role.Name, err)
+	}
+
+	if err := linkRoleGrants(ctx, neo4jClient, "Role", string(role.UID), role.Name, role.Namespace, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to link RoleBindings to Role %s: %v\n", role.Name, err)
+	}
+
+	return nil
+}
+
+func (h *RoleHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	role, err := ConvertToTyped[*rbacv1.Role](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert role: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Role", string(role.UID), neo4jClient)
+}
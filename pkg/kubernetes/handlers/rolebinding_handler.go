@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RoleBindingHandler tracks RoleBindings, linking them to the Role or
+// ClusterRole they grant within their namespace and to the ServiceAccounts
+// they bind
+type RoleBindingHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var roleBindingGVR = rbacResource("rolebindings")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "RoleBinding",
+		GVR:               roleBindingGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewRoleBindingHandler(cfg)
+		},
+	})
+}
+
+func NewRoleBindingHandler(cfg *config.Config) *RoleBindingHandler {
+	RegisterOwnerKind("RoleBinding", "RoleBinding")
+	return &RoleBindingHandler{
+		BaseHandler:  NewBaseHandler(roleBindingGVR, "RoleBinding", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *RoleBindingHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	binding, err := ConvertToTyped[*rbacv1.RoleBinding](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert rolebinding: %w", err)
+	}
+
+	subjects, serviceAccounts := bindingSubjects(binding.Subjects, binding.Namespace)
+	properties := map[string]interface{}{
+		"name":              binding.Name,
+		"uid":               string(binding.UID),
+		"namespace":         binding.Namespace,
+		"creationTimestamp": binding.CreationTimestamp.String(),
+		"labels":            binding.Labels,
+		"annotations":       binding.Annotations,
+		"roleRefKind":       binding.RoleRef.Kind,
+		"roleRefName":       binding.RoleRef.Name,
+		"subjects":          subjects,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"RoleBinding"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert rolebinding %s: %w", binding.Name, err)
+	}
+
+	linkBinding(ctx, neo4jClient, "RoleBinding", string(binding.UID), binding.Namespace, h.GetClusterName(), binding.RoleRef, serviceAccounts)
+	return nil
+}
+
+func (h *RoleBindingHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	binding, err := ConvertToTyped[*rbacv1.RoleBinding](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert rolebinding: %w", err)
+	}
+	return HandleResourceDelete(ctx, "RoleBinding", string(binding.UID), neo4jClient)
+}
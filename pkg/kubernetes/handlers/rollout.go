@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// revisionAnnotation holds the rollout revision the Deployment controller assigns to a ReplicaSet
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// containerImages returns the image of every init and regular container of a
+// pod spec by container name, which identifies what a rollout changed
+func containerImages(spec corev1.PodSpec) map[string]string {
+	images := make(map[string]string)
+	for _, container := range spec.InitContainers {
+		images[container.Name] = container.Image
+	}
+	for _, container := range spec.Containers {
+		images[container.Name] = container.Image
+	}
+	return images
+}
+
+// replicaSetRevision returns the rollout revision of a ReplicaSet, or 0 when it
+// is not managed by a Deployment
+func replicaSetRevision(annotations map[string]string) int64 {
+	revision, err := strconv.ParseInt(annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// revisionTemplate is the part of a ControllerRevision's data holding the pod
+// template of StatefulSets and DaemonSets
+type revisionTemplate struct {
+	Spec struct {
+		Template struct {
+			Spec corev1.PodSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// revisionImages returns the container images of the pod template stored in a
+// ControllerRevision, or nil when the data holds no pod template
+func revisionImages(data []byte) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+	var template revisionTemplate
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil
+	}
+	images := containerImages(template.Spec.Template.Spec)
+	if len(images) == 0 {
+		return nil
+	}
+	return images
+}
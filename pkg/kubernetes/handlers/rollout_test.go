@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReplicaSetRevision(t *testing.T) {
+	if got := replicaSetRevision(map[string]string{revisionAnnotation: "7"}); got != 7 {
+		t.Errorf("Expected revision 7, got %d", got)
+	}
+	if got := replicaSetRevision(nil); got != 0 {
+		t.Errorf("Expected revision 0 without annotation, got %d", got)
+	}
+}
+
+func TestControllerRevisionImages(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "ControllerRevision",
+		"metadata":   map[string]interface{}{"name": "db-7c9f8d", "namespace": "default", "uid": "rev-1"},
+		"revision":   int64(3),
+		"data": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"$patch": "replace",
+					"spec": map[string]interface{}{
+						"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "busybox:1.36"}},
+						"containers":     []interface{}{map[string]interface{}{"name": "db", "image": "postgres:16"}},
+					},
+				},
+			},
+		},
+	}}
+
+	cr, err := ConvertToTyped[*appsv1.ControllerRevision](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert revision: %v", err)
+	}
+	if cr.Revision != 3 {
+		t.Errorf("Expected revision 3, got %d", cr.Revision)
+	}
+	expected := map[string]string{"init": "busybox:1.36", "db": "postgres:16"}
+	if got := revisionImages(cr.Data.Raw); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected images %v, got %v", expected, got)
+	}
+	if got := revisionImages([]byte(`{"spec":{}}`)); got != nil {
+		t.Errorf("Expected no images without a pod template, got %v", got)
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// nodeConstraints is what restricts the nodes a pod can be scheduled on. It is
+// stored on the Pod node so the constraints can be resolved again when nodes
+// are added or relabeled.
+type nodeConstraints struct {
+	NodeSelector map[string]string         `json:"nodeSelector,omitempty"`
+	Terms        []corev1.NodeSelectorTerm `json:"terms,omitempty"`
+}
+
+// podNodeConstraints returns the node constraints of a pod spec, or nil when
+// it can run on any node
+func podNodeConstraints(spec corev1.PodSpec) *nodeConstraints {
+	constraints := &nodeConstraints{NodeSelector: spec.NodeSelector}
+	if affinity := spec.Affinity; affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		constraints.Terms = affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	}
+	if len(constraints.NodeSelector) == 0 && len(constraints.Terms) == 0 {
+		return nil
+	}
+	return constraints
+}
+
+// matches reports whether a node satisfies the constraints: all of the
+// nodeSelector labels and at least one of the node selector terms
+func (c *nodeConstraints) matches(nodeName string, nodeLabels map[string]string) bool {
+	if !labels.SelectorFromSet(c.NodeSelector).Matches(labels.Set(nodeLabels)) {
+		return false
+	}
+	if len(c.Terms) == 0 {
+		return true
+	}
+	for _, term := range c.Terms {
+		if termMatches(term, nodeName, nodeLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+// termMatches reports whether a node satisfies all the requirements of a
+// node selector term. A term without requirements matches no node.
+func termMatches(term corev1.NodeSelectorTerm, nodeName string, nodeLabels map[string]string) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, requirement := range term.MatchExpressions {
+		if !requirementMatches(requirement, labels.Set(nodeLabels)) {
+			return false
+		}
+	}
+	// metadata.name is the only field supported by the scheduler
+	for _, requirement := range term.MatchFields {
+		if requirement.Key != "metadata.name" || !requirementMatches(requirement, labels.Set{"metadata.name": nodeName}) {
+			return false
+		}
+	}
+	return true
+}
+
+func requirementMatches(requirement corev1.NodeSelectorRequirement, set labels.Set) bool {
+	var operator selection.Operator
+	switch requirement.Operator {
+	case corev1.NodeSelectorOpIn:
+		operator = selection.In
+	case corev1.NodeSelectorOpNotIn:
+		operator = selection.NotIn
+	case corev1.NodeSelectorOpExists:
+		operator = selection.Exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		operator = selection.DoesNotExist
+	case corev1.NodeSelectorOpGt:
+		operator = selection.GreaterThan
+	case corev1.NodeSelectorOpLt:
+		operator = selection.LessThan
+	default:
+		return false
+	}
+	parsed, err := labels.NewRequirement(requirement.Key, operator, requirement.Values)
+	if err != nil {
+		return false
+	}
+	return parsed.Matches(set)
+}
+
+// schedulingProperties returns the scheduling constraints of a pod spec in a
+// readable form: required and preferred node affinity, pod affinity and
+// anti-affinity, and topology spread constraints
+func schedulingProperties(spec corev1.PodSpec) map[string]interface{} {
+	required := make([]string, 0)
+	preferred := make([]string, 0)
+	podAffinity := make([]string, 0)
+	podAntiAffinity := make([]string, 0)
+	if affinity := spec.Affinity; affinity != nil {
+		if nodeAffinity := affinity.NodeAffinity; nodeAffinity != nil {
+			if selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; selector != nil {
+				for _, term := range selector.NodeSelectorTerms {
+					required = append(required, formatNodeSelectorTerm(term))
+				}
+			}
+			for _, term := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				preferred = append(preferred, fmt.Sprintf("weight=%d %s", term.Weight, formatNodeSelectorTerm(term.Preference)))
+			}
+		}
+		if affinity.PodAffinity != nil {
+			podAffinity = formatPodAffinity(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution, affinity.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		}
+		if affinity.PodAntiAffinity != nil {
+			podAntiAffinity = formatPodAffinity(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		}
+	}
+
+	spread := make([]string, 0, len(spec.TopologySpreadConstraints))
+	for _, constraint := range spec.TopologySpreadConstraints {
+		spread = append(spread, fmt.Sprintf("topologyKey=%s maxSkew=%d whenUnsatisfiable=%s selector=%s",
+			constraint.TopologyKey, constraint.MaxSkew, constraint.WhenUnsatisfiable, formatLabelSelector(constraint.LabelSelector)))
+	}
+
+	return map[string]interface{}{
+		"requiredNodeAffinity":      required,
+		"preferredNodeAffinity":     preferred,
+		"podAffinity":               podAffinity,
+		"podAntiAffinity":           podAntiAffinity,
+		"topologySpreadConstraints": spread,
+	}
+}
+
+// formatNodeSelectorTerm formats a node selector term as
+// "topology.kubernetes.io/zone in (a,b), gpu exists"
+func formatNodeSelectorTerm(term corev1.NodeSelectorTerm) string {
+	parts := make([]string, 0, len(term.MatchExpressions)+len(term.MatchFields))
+	for _, requirements := range [][]corev1.NodeSelectorRequirement{term.MatchExpressions, term.MatchFields} {
+		for _, requirement := range requirements {
+			part := requirement.Key + " " + strings.ToLower(string(requirement.Operator))
+			if len(requirement.Values) > 0 {
+				part += " (" + strings.Join(requirement.Values, ",") + ")"
+			}
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatPodAffinity formats pod affinity or anti-affinity terms as
+// "required topologyKey=<key> selector=<selector>" or "preferred weight=<n> ..."
+func formatPodAffinity(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) []string {
+	formatted := make([]string, 0, len(required)+len(preferred))
+	format := func(term corev1.PodAffinityTerm) string {
+		result := fmt.Sprintf("topologyKey=%s selector=%s", term.TopologyKey, formatLabelSelector(term.LabelSelector))
+		if len(term.Namespaces) > 0 {
+			result += " namespaces=" + strings.Join(term.Namespaces, ",")
+		}
+		if term.NamespaceSelector != nil {
+			result += " namespaceSelector=" + formatLabelSelector(term.NamespaceSelector)
+		}
+		return result
+	}
+	for _, term := range required {
+		formatted = append(formatted, "required "+format(term))
+	}
+	for _, term := range preferred {
+		formatted = append(formatted, fmt.Sprintf("preferred weight=%d %s", term.Weight, format(term.PodAffinityTerm)))
+	}
+	return formatted
+}
+
+// formatLabelSelector formats a label selector in its labels.Parse form, with
+// "<all>" for a selector matching everything and "<none>" for a nil one
+func formatLabelSelector(selector *metav1.LabelSelector) string {
+	if selector == nil {
+		return "<none>"
+	}
+	if formatted := metav1.FormatLabelSelector(selector); formatted != "" {
+		return formatted
+	}
+	return "<all>"
+}
+
+// schedulingNode is a Node as read from the graph for constraint resolution
+type schedulingNode struct {
+	Name   string
+	Labels map[string]string
+}
+
+// loadSchedulingNodes reads the nodes of a cluster with their labels
+func loadSchedulingNodes(ctx context.Context, neo4jClient graph.Store, clusterName string) ([]schedulingNode, error) {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (n:Node {clusterName: $clusterName})
+		RETURN n.name AS name, n.labels AS labels`, map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]schedulingNode, 0, len(records))
+	for _, record := range records {
+		node := schedulingNode{Name: fmt.Sprint(record["name"])}
+		if value := record["labels"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &node.Labels)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// allowedNodes returns the names of the nodes satisfying the constraints, in order
+func allowedNodes(constraints *nodeConstraints, nodes []schedulingNode) []string {
+	names := make([]string, 0)
+	for _, node := range nodes {
+		if constraints.matches(node.Name, node.Labels) {
+			names = append(names, node.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeConstrainedTo replaces the CONSTRAINED_TO relationships of a pod,
+// pointing to every Node its nodeSelector and required node affinity allow
+func writeConstrainedTo(ctx context.Context, neo4jClient graph.Store, uid, clusterName string, nodeNames []string) error {
+	params := map[string]interface{}{"uid": uid, "clusterName": clusterName, "nodes": nodeNames}
+	statements := []graph.Statement{{
+		Query: `
+			MATCH (p:Pod {uid: $uid})-[old:CONSTRAINED_TO]->(n:Node)` +
+			graph.DeleteRelationship("old", "p", "uid", "n", "uid"),
+		Params: params,
+	}}
+	if len(nodeNames) > 0 {
+		statements = append(statements, graph.Statement{
+			Query: `
+			MATCH (p:Pod {uid: $uid})
+			MATCH (n:Node {clusterName: $clusterName})
+			WHERE n.name IN $nodes` +
+				graph.CreateRelationship("p", "uid", "CONSTRAINED_TO", "", "n", "uid"),
+			Params: params,
+		})
+	}
+	return neo4jClient.Write(ctx, statements...)
+}
+
+// resolvePodConstraints materializes the CONSTRAINED_TO relationships of one pod
+func resolvePodConstraints(ctx context.Context, neo4jClient graph.Store, uid string, constraints *nodeConstraints, clusterName string) error {
+	nodes, err := loadSchedulingNodes(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+	return writeConstrainedTo(ctx, neo4jClient, uid, clusterName, allowedNodes(constraints, nodes))
+}
+
+// ResolveNodeConstraints materializes the CONSTRAINED_TO relationships of
+// every constrained pod of a cluster from the constraints stored on the pods.
+// It is run periodically so the relationships follow node changes.
+func ResolveNodeConstraints(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (p:Pod {clusterName: $clusterName})
+		WHERE p.nodeConstraints IS NOT NULL AND NOT coalesce(p.status, '') IN ['Succeeded', 'Failed']
+		RETURN p.uid AS uid, p.namespace AS namespace, p.name AS name, p.nodeConstraints AS nodeConstraints`,
+		map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return err
+	}
+
+	nodes, err := loadSchedulingNodes(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, record := range records {
+		values := make(map[string]string)
+		for _, key := range []string{"uid", "namespace", "name", "nodeConstraints"} {
+			if value := record[key]; value != nil {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		var constraints nodeConstraints
+		if err := json.Unmarshal([]byte(values["nodeConstraints"]), &constraints); err != nil {
+			failed = append(failed, values["namespace"]+"/"+values["name"])
+			continue
+		}
+		if err := writeConstrainedTo(ctx, neo4jClient, values["uid"], clusterName, allowedNodes(&constraints, nodes)); err != nil {
+			failed = append(failed, values["namespace"]+"/"+values["name"])
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to resolve node constraints of pods %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
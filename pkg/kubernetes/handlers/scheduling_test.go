@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeConstraints(t *testing.T) {
+	spec := corev1.PodSpec{
+		NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+		Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+					{Key: "node.kubernetes.io/spot", Operator: corev1.NodeSelectorOpDoesNotExist},
+				}},
+				{MatchFields: []corev1.NodeSelectorRequirement{
+					{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"pinned"}},
+				}},
+			}},
+		}},
+	}
+	nodes := []schedulingNode{
+		{Name: "zone-a", Labels: map[string]string{"kubernetes.io/os": "linux", "topology.kubernetes.io/zone": "a"}},
+		{Name: "zone-c", Labels: map[string]string{"kubernetes.io/os": "linux", "topology.kubernetes.io/zone": "c"}},
+		{Name: "spot", Labels: map[string]string{"kubernetes.io/os": "linux", "topology.kubernetes.io/zone": "b", "node.kubernetes.io/spot": "true"}},
+		{Name: "pinned", Labels: map[string]string{"kubernetes.io/os": "linux"}},
+		{Name: "windows", Labels: map[string]string{"kubernetes.io/os": "windows", "topology.kubernetes.io/zone": "a"}},
+	}
+
+	constraints := podNodeConstraints(spec)
+	if constraints == nil {
+		t.Fatal("Expected constraints")
+	}
+	if got, expected := allowedNodes(constraints, nodes), []string{"pinned", "zone-a"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected nodes %v, got %v", expected, got)
+	}
+
+	// The constraints are stored as JSON and resolved again from it
+	encoded, _ := json.Marshal(constraints)
+	var decoded nodeConstraints
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to decode constraints: %v", err)
+	}
+	if got := allowedNodes(&decoded, nodes); !reflect.DeepEqual(got, []string{"pinned", "zone-a"}) {
+		t.Errorf("Expected decoded constraints to allow the same nodes, got %v", got)
+	}
+
+	if podNodeConstraints(corev1.PodSpec{}) != nil {
+		t.Error("Expected no constraints for an unconstrained pod")
+	}
+}
+
+func TestSchedulingProperties(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	spec := corev1.PodSpec{
+		Affinity: &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+					Weight: 50,
+					Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "disktype", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+					}},
+				}},
+			},
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+					{TopologyKey: "kubernetes.io/hostname", LabelSelector: selector},
+				},
+			},
+		},
+		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     selector,
+		}},
+	}
+
+	expected := map[string]interface{}{
+		"requiredNodeAffinity":      []string{},
+		"preferredNodeAffinity":     []string{"weight=50 disktype in (ssd)"},
+		"podAffinity":               []string{},
+		"podAntiAffinity":           []string{"required topologyKey=kubernetes.io/hostname selector=app=web"},
+		"topologySpreadConstraints": []string{"topologyKey=topology.kubernetes.io/zone maxSkew=1 whenUnsatisfiable=DoNotSchedule selector=app=web"},
+	}
+	if properties := schedulingProperties(spec); !reflect.DeepEqual(properties, expected) {
+		t.Errorf("Expected %v, got %v", expected, properties)
+	}
+}
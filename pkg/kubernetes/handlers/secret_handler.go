@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type SecretHandler struct {
@@ -16,25 +18,40 @@ type SecretHandler struct {
 	instanceHash string
 }
 
+var secretGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "secrets",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Secret",
+		GVR:               secretGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewSecretHandler(cfg)
+		},
+	})
+}
+
 func NewSecretHandler(cfg *config.Config) *SecretHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "secrets",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Secret", "Secret")
 	return &SecretHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Secret", cfg),
+		BaseHandler:  NewBaseHandler(secretGVR, "Secret", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *SecretHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *SecretHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	secret, err := ConvertToTyped[*corev1.Secret](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert secret: %w", err)
 	}
 
+	registries := dockerConfigRegistries(secret)
+
 	properties := map[string]interface{}{
 		"name":              secret.Name,
 		"uid":               string(secret.UID),
@@ -43,35 +60,27 @@ func (h *SecretHandler) HandleCreate(ctx context.Context, obj interface{}, neo4j
 		"labels":            secret.Labels,
 		"annotations":       secret.Annotations,
 		"type":              string(secret.Type),
+		"registries":        registries,
+		"dataHash":          secretDataHash(secret),
 		"clusterName":       h.GetClusterName(),
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Secret"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Secret"}, properties, "uid", ownerRelationships("Secret", string(secret.UID), secret.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert secret %s: %w", secret.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if secret.OwnerReferences != nil {
-		for _, ownerRef := range secret.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Secret", "uid", string(secret.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Secret %s and %s %s: %v\n", secret.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	if err := linkSecretUsers(ctx, neo4jClient, secret, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to create relationships to Secret %s: %v\n", secret.Name, err)
 	}
 
+	// Link image pull secrets to the registries they hold credentials for
+	linkRegistries(ctx, neo4jClient, "Secret", string(secret.UID), "AUTHENTICATES_TO", h.GetClusterName(), h.instanceHash, registries)
+
 	return nil
 }
 
-func (h *SecretHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *SecretHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	secret, err := ConvertToTyped[*corev1.Secret](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert secret: %w", err)
@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,21 +20,34 @@ type ServiceHandler struct {
 	instanceHash string
 }
 
+var serviceGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "services",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Service",
+		GVR:               serviceGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewServiceHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewServiceHandler(clientset *kubernetes.Clientset, cfg *config.Config) *ServiceHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "services",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("Service", "Service")
 	return &ServiceHandler{
-		BaseHandler:  NewBaseHandler(gvr, "Service", cfg),
+		BaseHandler:  NewBaseHandler(serviceGVR, "Service", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *ServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	svc, err := ConvertToTyped[*corev1.Service](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert service: %w", err)
@@ -90,29 +104,10 @@ func (h *ServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		properties["loadBalancerIngress"] = ingress
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"Service"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert service %s: %w", svc.Name, err)
-	}
-
-	// Create relationships based on owner references for all supported types
-	if svc.OwnerReferences != nil {
-		for _, ownerRef := range svc.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"Service", "uid", string(svc.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between Service %s and %s %s: %v\n", svc.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("Service", string(svc.UID), svc.OwnerReferences)
 
 	// Create relationships with pods based on selector
-	if svc.Spec.Selector != nil {
+	if svc.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{MatchLabels: svc.Spec.Selector}),
 		})
@@ -121,16 +116,36 @@ func (h *ServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "Service", "uid", string(svc.UID), "SELECTS", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between service %s and pod %s: %w", svc.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("Service", "uid", string(svc.UID), "SELECTS", "Pod", "uid", string(pod.UID)))
+		}
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"Service"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert service %s: %w", svc.Name, err)
+	}
+
+	// HTTPRoutes synced before the service
+	if err := linkReferrers(ctx, neo4jClient, "HTTPRoute", "Service", string(svc.UID), svc.Name, svc.Namespace, h.GetClusterName(), "ROUTES_TO", "backendServices"); err != nil {
+		fmt.Printf("Warning: failed to link HTTPRoutes to Service %s: %v\n", svc.Name, err)
+	}
+	// Istio resources synced before the service
+	if err := linkReferrers(ctx, neo4jClient, "VirtualService", "Service", string(svc.UID), svc.Name, svc.Namespace, h.GetClusterName(), "ROUTES_TO", "destinationServices"); err != nil {
+		fmt.Printf("Warning: failed to link VirtualServices to Service %s: %v\n", svc.Name, err)
+	}
+	if err := linkReferrers(ctx, neo4jClient, "DestinationRule", "Service", string(svc.UID), svc.Name, svc.Namespace, h.GetClusterName(), "APPLIES_TO", "services"); err != nil {
+		fmt.Printf("Warning: failed to link DestinationRules to Service %s: %v\n", svc.Name, err)
+	}
+	// Admission webhooks and aggregated APIs synced before the service
+	for _, label := range []string{"ValidatingWebhookConfiguration", "MutatingWebhookConfiguration", "APIService"} {
+		if err := linkReferrers(ctx, neo4jClient, label, "Service", string(svc.UID), svc.Name, svc.Namespace, h.GetClusterName(), "CALLS", "services"); err != nil {
+			fmt.Printf("Warning: failed to link %ss to Service %s: %v\n", label, svc.Name, err)
 		}
 	}
 
 	return nil
 }
 
-func (h *ServiceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ServiceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	svc, err := ConvertToTyped[*corev1.Service](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert service: %w", err)
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type ServiceAccountHandler struct {
@@ -16,20 +18,33 @@ type ServiceAccountHandler struct {
 	instanceHash string
 }
 
+var serviceAccountGVR = schema.GroupVersionResource{
+	Version:  "v1",
+	Resource: "serviceaccounts",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ServiceAccount",
+		GVR:               serviceAccountGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewServiceAccountHandler(cfg)
+		},
+	})
+}
+
 func NewServiceAccountHandler(cfg *config.Config) *ServiceAccountHandler {
-	gvr := schema.GroupVersionResource{
-		Version:  "v1",
-		Resource: "serviceaccounts",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("ServiceAccount", "ServiceAccount")
 	return &ServiceAccountHandler{
-		BaseHandler:  NewBaseHandler(gvr, "ServiceAccount", cfg),
+		BaseHandler:  NewBaseHandler(serviceAccountGVR, "ServiceAccount", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *ServiceAccountHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ServiceAccountHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sa, err := ConvertToTyped[*corev1.ServiceAccount](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert serviceaccount: %w", err)
@@ -44,36 +59,45 @@ func (h *ServiceAccountHandler) HandleCreate(ctx context.Context, obj interface{
 		"annotations":       sa.Annotations,
 		"secrets":           sa.Secrets,
 		"imagePullSecrets":  sa.ImagePullSecrets,
-		"automountToken":    sa.AutomountServiceAccountToken,
 		"clusterName":       h.GetClusterName(),
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"ServiceAccount"}, properties, "uid"); err != nil {
+	// Unset means the token is mounted unless the pod disables it
+	if sa.AutomountServiceAccountToken != nil {
+		properties["automountToken"] = *sa.AutomountServiceAccountToken
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"ServiceAccount"}, properties, "uid", ownerRelationships("ServiceAccount", string(sa.UID), sa.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert serviceaccount %s: %w", sa.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if sa.OwnerReferences != nil {
-		for _, ownerRef := range sa.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"ServiceAccount", "uid", string(sa.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between ServiceAccount %s and %s %s: %v\n", sa.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
+	// Link token and image pull secrets of the service account
+	secrets := make([]string, 0, len(sa.Secrets)+len(sa.ImagePullSecrets))
+	for _, ref := range sa.Secrets {
+		secrets = append(secrets, ref.Name)
+	}
+	for _, ref := range sa.ImagePullSecrets {
+		secrets = append(secrets, ref.Name)
+	}
+	if err := linkReferences(ctx, neo4jClient, "ServiceAccount", string(sa.UID), sa.Namespace, h.GetClusterName(), "Secret", secrets); err != nil {
+		fmt.Printf("Warning: failed to create relationships between ServiceAccount %s and its Secrets: %v\n", sa.Name, err)
+	}
+
+	// Pods synced before the service account
+	if err := linkServiceAccountPods(ctx, neo4jClient, string(sa.UID), sa.Name, sa.Namespace, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to link pods to ServiceAccount %s: %v\n", sa.Name, err)
+	}
+
+	// RoleBindings and ClusterRoleBindings synced before the service account
+	if err := linkServiceAccountBindings(ctx, neo4jClient, string(sa.UID), sa.Name, sa.Namespace, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to link bindings to ServiceAccount %s: %v\n", sa.Name, err)
 	}
 
 	return nil
 }
 
-func (h *ServiceAccountHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *ServiceAccountHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sa, err := ConvertToTyped[*corev1.ServiceAccount](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert serviceaccount: %w", err)
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodHandlerLinksServiceAccount(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name      string
+		spec      corev1.PodSpec
+		account   string
+		automount interface{}
+	}{
+		{"default", corev1.PodSpec{}, "default", nil},
+		{"named", corev1.PodSpec{ServiceAccountName: "api", AutomountServiceAccountToken: &disabled}, "api", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := graphtest.NewStore()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "shop", UID: "pod-1"},
+				Spec:       test.spec,
+			}
+			if err := NewPodHandler(nil, testConfig()).HandleCreate(context.Background(), pod, store); err != nil {
+				t.Fatalf("HandleCreate failed: %v", err)
+			}
+
+			node := expectNode(t, store, "Pod", "pod-1", nil)
+			if automount, ok := node.Properties["automountServiceAccountToken"]; automount != test.automount || ok != (test.automount != nil) {
+				t.Errorf("Expected automountServiceAccountToken %v, got %v", test.automount, automount)
+			}
+			statements := store.StatementsMatching("MERGE (from)-[:USES_SERVICE_ACCOUNT]->(target)")
+			if len(statements) != 1 {
+				t.Fatalf("Expected one USES_SERVICE_ACCOUNT statement, got %+v", statements)
+			}
+			expected := []map[string]interface{}{{"namespace": "shop", "name": test.account}}
+			if params := statements[0].Params; params["uid"] != "pod-1" || params["clusterName"] != "test-cluster" || !reflect.DeepEqual(params["targets"], expected) {
+				t.Errorf("Expected the pod to be linked to ServiceAccount shop/%s, got %+v", test.account, params)
+			}
+			if stale := store.StatementsMatching("-[old:USES_SERVICE_ACCOUNT]->(target:ServiceAccount)"); len(stale) != 1 {
+				t.Errorf("Expected the previous USES_SERVICE_ACCOUNT relationship to be removed, got %+v", stale)
+			}
+		})
+	}
+}
+
+func TestServiceAccountHandlerWrites(t *testing.T) {
+	store := graphtest.NewStore()
+	disabled := false
+	sa := &corev1.ServiceAccount{
+		ObjectMeta:                   metav1.ObjectMeta{Name: "default", Namespace: "shop", UID: "sa-1"},
+		AutomountServiceAccountToken: &disabled,
+	}
+	if err := NewServiceAccountHandler(testConfig()).HandleCreate(context.Background(), sa, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	expectNode(t, store, "ServiceAccount", "sa-1", map[string]interface{}{
+		"name":           "default",
+		"namespace":      "shop",
+		"automountToken": false,
+	})
+	// Pods synced before the service account are linked to it, including the
+	// pods without a service account name when it is the default one
+	statements := store.StatementsMatching("MERGE (p)-[:USES_SERVICE_ACCOUNT]->(sa)")
+	if len(statements) != 1 {
+		t.Fatalf("Expected one statement linking the pods, got %+v", statements)
+	}
+	expected := map[string]interface{}{"uid": "sa-1", "name": "default", "namespace": "shop", "clusterName": "test-cluster"}
+	if !reflect.DeepEqual(statements[0].Params, expected) {
+		t.Errorf("Expected parameters %v, got %v", expected, statements[0].Params)
+	}
+}
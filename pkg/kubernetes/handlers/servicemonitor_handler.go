@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ServiceMonitorHandler tracks Prometheus Operator ServiceMonitors, linking
+// them to the Services they select
+type ServiceMonitorHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var serviceMonitorGVR = prometheusOperatorResource("servicemonitors")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ServiceMonitor",
+		GVR:               serviceMonitorGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewServiceMonitorHandler(cfg)
+		},
+	})
+}
+
+func NewServiceMonitorHandler(cfg *config.Config) *ServiceMonitorHandler {
+	RegisterOwnerKind("ServiceMonitor", "ServiceMonitor")
+	return &ServiceMonitorHandler{
+		BaseHandler:  NewBaseHandler(serviceMonitorGVR, "ServiceMonitor", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ServiceMonitorHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	monitor, err := ConvertToTyped[*serviceMonitor](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert servicemonitor: %w", err)
+	}
+
+	uid := string(monitor.UID)
+	properties := map[string]interface{}{
+		"name":              monitor.Name,
+		"uid":               uid,
+		"namespace":         monitor.Namespace,
+		"creationTimestamp": monitor.CreationTimestamp.String(),
+		"labels":            monitor.Labels,
+		"annotations":       monitor.Annotations,
+		"endpoints":         formatMonitorEndpoints(monitor.Spec.Endpoints),
+		"jobLabel":          monitor.Spec.JobLabel,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	selectionProperties, selection, resolvable := monitorProperties(monitor.Namespace, &monitor.Spec.Selector, monitor.Spec.NamespaceSelector)
+	for key, value := range selectionProperties {
+		properties[key] = value
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"ServiceMonitor"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert servicemonitor %s: %w", monitor.Name, err)
+	}
+
+	if !resolvable {
+		fmt.Printf("Warning: ServiceMonitor %s has an invalid selector, not linking it\n", monitor.Name)
+		return nil
+	}
+	if err := linkMonitoredTargets(ctx, neo4jClient, "ServiceMonitor", uid, "Service", selection, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to create MONITORS relationships for ServiceMonitor %s: %v\n", monitor.Name, err)
+	}
+
+	return nil
+}
+
+func (h *ServiceMonitorHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	monitor, err := ConvertToTyped[*serviceMonitor](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert servicemonitor: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ServiceMonitor", string(monitor.UID), neo4jClient)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+
+	"k8s-graph/pkg/graph"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The StatefulSet controller names the PVCs of its volumeClaimTemplates
+// <template>-<statefulset>-<ordinal>, and the names of both may contain dashes,
+// so a claim is matched against the templates of the StatefulSets of its
+// namespace rather than split.
+var ordinalClaimName = regexp.MustCompile(`^(.+)-[0-9]+$`)
+
+// claimTemplateNames returns the names of the volumeClaimTemplates of a StatefulSet
+func claimTemplateNames(sts *appsv1.StatefulSet) []string {
+	names := make([]string, 0, len(sts.Spec.VolumeClaimTemplates))
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		names = append(names, template.Name)
+	}
+	return names
+}
+
+// statefulSetClaimRelationships returns the USED_BY relationships of a PVC to
+// the StatefulSets owning it, which the controller sets when the
+// persistentVolumeClaimRetentionPolicy deletes claims with their StatefulSet
+func statefulSetClaimRelationships(uid string, owners []metav1.OwnerReference) []graph.Relationship {
+	var relationships []graph.Relationship
+	for _, ownerRef := range owners {
+		if ownerRef.Kind == "StatefulSet" {
+			relationships = append(relationships, relationship("PersistentVolumeClaim", "uid", uid, "USED_BY", "StatefulSet", "uid", string(ownerRef.UID)))
+		}
+	}
+	return relationships
+}
+
+// linkStatefulSetOfClaim links a PVC to the StatefulSet of its namespace whose
+// volumeClaimTemplates it was created from. The templates are stored as a
+// JSON list, of names that need no escaping.
+func linkStatefulSetOfClaim(ctx context.Context, neo4jClient graph.Store, uid, name string) error {
+	match := ordinalClaimName.FindStringSubmatch(name)
+	if match == nil {
+		return nil
+	}
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (pvc:PersistentVolumeClaim {uid: $uid})
+			MATCH (sts:StatefulSet)
+			WHERE sts.namespace = pvc.namespace AND sts.clusterName = pvc.clusterName
+			  AND $base ENDS WITH '-' + sts.name
+			  AND sts.volumeClaimTemplates CONTAINS '"' + substring($base, 0, size($base) - size(sts.name) - 1) + '"'` +
+			graph.MergeRelationship("pvc", "uid", "USED_BY", "sts", "uid"),
+		Params: map[string]interface{}{"uid": uid, "base": match[1]},
+	})
+	return err
+}
+
+// linkClaimsOfStatefulSet links the PVCs created from the volumeClaimTemplates
+// of a StatefulSet, including the claims of ordinals it was scaled down from
+func linkClaimsOfStatefulSet(ctx context.Context, neo4jClient graph.Store, sts *appsv1.StatefulSet, clusterName string) error {
+	templates := claimTemplateNames(sts)
+	if len(templates) == 0 {
+		return nil
+	}
+	prefixes := make([]string, 0, len(templates))
+	for _, template := range templates {
+		prefixes = append(prefixes, template+"-"+sts.Name+"-")
+	}
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MATCH (sts:StatefulSet {uid: $uid})
+			MATCH (pvc:PersistentVolumeClaim {clusterName: $clusterName, namespace: $namespace})
+			WHERE any(prefix IN $prefixes WHERE pvc.name STARTS WITH prefix AND substring(pvc.name, size(prefix)) =~ '[0-9]+')` +
+			graph.MergeRelationship("pvc", "uid", "USED_BY", "sts", "uid"),
+		Params: map[string]interface{}{"uid": string(sts.UID), "clusterName": clusterName, "namespace": sts.Namespace, "prefixes": prefixes},
+	})
+	return err
+}
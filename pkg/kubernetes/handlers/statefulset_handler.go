@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,65 +20,68 @@ type StatefulSetHandler struct {
 	instanceHash string
 }
 
+var statefulSetGVR = schema.GroupVersionResource{
+	Group:    "apps",
+	Version:  "v1",
+	Resource: "statefulsets",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "StatefulSet",
+		GVR:               statefulSetGVR,
+		Namespaced:        true,
+		RequiresClientset: true,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewStatefulSetHandler(clientset, cfg)
+		},
+	})
+}
+
 func NewStatefulSetHandler(clientset *kubernetes.Clientset, cfg *config.Config) *StatefulSetHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "apps",
-		Version:  "v1",
-		Resource: "statefulsets",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("StatefulSet", "StatefulSet")
 	return &StatefulSetHandler{
-		BaseHandler:  NewBaseHandler(gvr, "StatefulSet", cfg),
+		BaseHandler:  NewBaseHandler(statefulSetGVR, "StatefulSet", cfg),
 		clientset:    clientset,
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *StatefulSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *StatefulSetHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sts, err := ConvertToTyped[*appsv1.StatefulSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert statefulset: %w", err)
 	}
 
+	podSpec := sts.Spec.Template.Spec
+	registries := podSpecRegistries(podSpec)
+
 	properties := map[string]interface{}{
-		"name":              sts.Name,
-		"uid":               string(sts.UID),
-		"namespace":         sts.Namespace,
-		"creationTimestamp": sts.CreationTimestamp.String(),
-		"labels":            sts.Labels,
-		"annotations":       sts.Annotations,
-		"replicas":          sts.Spec.Replicas,
-		"serviceName":       sts.Spec.ServiceName,
-		"selector":          sts.Spec.Selector.MatchLabels,
-		"updateStrategy":    string(sts.Spec.UpdateStrategy.Type),
-		"clusterName":       h.GetClusterName(),
-		"instanceHash":      h.instanceHash,
+		"name":                 sts.Name,
+		"uid":                  string(sts.UID),
+		"namespace":            sts.Namespace,
+		"creationTimestamp":    sts.CreationTimestamp.String(),
+		"labels":               sts.Labels,
+		"annotations":          sts.Annotations,
+		"replicas":             sts.Spec.Replicas,
+		"serviceName":          sts.Spec.ServiceName,
+		"selector":             sts.Spec.Selector.MatchLabels,
+		"updateStrategy":       string(sts.Spec.UpdateStrategy.Type),
+		"volumeClaimTemplates": claimTemplateNames(sts),
+		"clusterName":          h.GetClusterName(),
+		"registries":           registries,
+		"imagePullSecrets":     pullSecretNames(podSpec),
+		"instanceHash":         h.instanceHash,
 	}
-
-	if err := neo4jClient.UpsertNode(ctx, []string{"StatefulSet"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert statefulset %s: %w", sts.Name, err)
+	for key, value := range templateProperties(podSpec) {
+		properties[key] = value
 	}
 
-	// Create relationships based on owner references for all supported types
-	if sts.OwnerReferences != nil {
-		for _, ownerRef := range sts.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"StatefulSet", "uid", string(sts.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between StatefulSet %s and %s %s: %v\n", sts.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("StatefulSet", string(sts.UID), sts.OwnerReferences)
 
 	// Create relationships with pods
-	if sts.Spec.Selector != nil {
+	if sts.Spec.Selector != nil && h.clientset != nil {
 		pods, err := h.clientset.CoreV1().Pods(sts.Namespace).List(ctx, metav1.ListOptions{
 			LabelSelector: metav1.FormatLabelSelector(sts.Spec.Selector),
 		})
@@ -86,26 +90,36 @@ func (h *StatefulSetHandler) HandleCreate(ctx context.Context, obj interface{},
 		}
 
 		for _, pod := range pods.Items {
-			if err := neo4jClient.CreateRelationship(ctx, "StatefulSet", "uid", string(sts.UID), "MANAGES", "Pod", "uid", string(pod.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between statefulset %s and pod %s: %w", sts.Name, pod.Name, err)
-			}
+			relationships = append(relationships, relationship("StatefulSet", "uid", string(sts.UID), "MANAGES", "Pod", "uid", string(pod.UID)))
 		}
 	}
 
 	// Create relationship with service if it exists
-	if sts.Spec.ServiceName != "" {
+	if sts.Spec.ServiceName != "" && h.clientset != nil {
 		svc, err := h.clientset.CoreV1().Services(sts.Namespace).Get(ctx, sts.Spec.ServiceName, metav1.GetOptions{})
 		if err == nil {
-			if err := neo4jClient.CreateRelationship(ctx, "StatefulSet", "uid", string(sts.UID), "USES", "Service", "uid", string(svc.UID)); err != nil {
-				return fmt.Errorf("failed to create relationship between statefulset %s and service %s: %w", sts.Name, svc.Name, err)
-			}
+			relationships = append(relationships, relationship("StatefulSet", "uid", string(sts.UID), "USES", "Service", "uid", string(svc.UID)))
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"StatefulSet"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert statefulset %s: %w", sts.Name, err)
+	}
+
+	rollupStorageForWorkload(ctx, neo4jClient, "StatefulSet", string(sts.UID), sts.Name)
+
+	// Link the PVCs created from the volumeClaimTemplates
+	if err := linkClaimsOfStatefulSet(ctx, neo4jClient, sts, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to link the PVCs of StatefulSet %s: %v\n", sts.Name, err)
+	}
+
+	// Link the registries the pod template pulls images from
+	linkRegistries(ctx, neo4jClient, "StatefulSet", string(sts.UID), "PULLS_FROM", h.GetClusterName(), h.instanceHash, registries)
+
 	return nil
 }
 
-func (h *StatefulSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *StatefulSetHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sts, err := ConvertToTyped[*appsv1.StatefulSet](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert statefulset: %w", err)
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+)
+
+// storageRollupWorkloads are the labels that receive the totalStorageBytes of
+// the PVCs used by their pods. Pods are at most two OWNED_BY hops away, e.g.
+// Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob.
+const storageRollupWorkloads = "w:Deployment OR w:StatefulSet OR w:DaemonSet OR w:ReplicaSet OR w:Job OR w:CronJob"
+
+// storageRollupQuery recomputes totalStorageBytes of the workloads bound to w.
+// Pods with the uid $exclude are left out, so a pod being deleted no longer counts.
+// The property is set outside of UpsertNode and is stored as an integer.
+const storageRollupQuery = `
+	CALL {
+		WITH w
+		OPTIONAL MATCH (w)<-[:OWNED_BY*1..2]-(pod:Pod)
+		WHERE pod.uid <> $exclude
+		OPTIONAL MATCH (pod)-[:USES]->(pvc:PersistentVolumeClaim)
+		WHERE pvc.namespace = pod.namespace AND pvc.clusterName = pod.clusterName
+		WITH DISTINCT pvc
+		RETURN sum(coalesce(toInteger(pvc.capacityBytes), 0)) AS total
+	}
+	SET w.totalStorageBytes = total`
+
+// rollupStorage runs the storage rollup for the workloads matched by match,
+// which must bind w
+func rollupStorage(ctx context.Context, neo4jClient graph.Store, match string, params map[string]interface{}) error {
+	if _, ok := params["exclude"]; !ok {
+		params["exclude"] = ""
+	}
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query:  fmt.Sprintf("%s\nWITH DISTINCT w%s", match, storageRollupQuery),
+		Params: params,
+	})
+	return err
+}
+
+// rollupStorageForPod updates the workloads owning a pod. When the pod is being
+// deleted, removed is true and its PVCs are no longer counted.
+func rollupStorageForPod(ctx context.Context, neo4jClient graph.Store, podUID string, removed bool) error {
+	params := map[string]interface{}{"uid": podUID}
+	if removed {
+		params["exclude"] = podUID
+	}
+	return rollupStorage(ctx, neo4jClient, fmt.Sprintf(`
+		MATCH (:Pod {uid: $uid})-[:OWNED_BY*1..2]->(w)
+		WHERE %s`, storageRollupWorkloads), params)
+}
+
+// rollupStorageForClaim updates the workloads whose pods use a PVC
+func rollupStorageForClaim(ctx context.Context, neo4jClient graph.Store, claimUID string) error {
+	return rollupStorage(ctx, neo4jClient, fmt.Sprintf(`
+		MATCH (:PersistentVolumeClaim {uid: $uid})<-[:USES]-(:Pod)-[:OWNED_BY*1..2]->(w)
+		WHERE %s`, storageRollupWorkloads), map[string]interface{}{"uid": claimUID})
+}
+
+// rollupStorageForWorkload restores totalStorageBytes of a workload after its
+// node was upserted. Upserts replace all properties, so every workload handler
+// calls it after writing its node; failures only leave the total stale.
+func rollupStorageForWorkload(ctx context.Context, neo4jClient graph.Store, label, uid, name string) {
+	err := rollupStorage(ctx, neo4jClient, fmt.Sprintf("MATCH (w:%s {uid: $uid})", label), map[string]interface{}{"uid": uid})
+	if err != nil {
+		fmt.Printf("Warning: failed to roll up storage of %s %s: %v\n", label, name, err)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type StorageClassHandler struct {
@@ -16,21 +18,34 @@ type StorageClassHandler struct {
 	instanceHash string
 }
 
+var storageClassGVR = schema.GroupVersionResource{
+	Group:    "storage.k8s.io",
+	Version:  "v1",
+	Resource: "storageclasses",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "StorageClass",
+		GVR:               storageClassGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewStorageClassHandler(cfg)
+		},
+	})
+}
+
 func NewStorageClassHandler(cfg *config.Config) *StorageClassHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "storage.k8s.io",
-		Version:  "v1",
-		Resource: "storageclasses",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("StorageClass", "StorageClass")
 	return &StorageClassHandler{
-		BaseHandler:  NewBaseHandler(gvr, "StorageClass", cfg),
+		BaseHandler:  NewBaseHandler(storageClassGVR, "StorageClass", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *StorageClassHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *StorageClassHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sc, err := ConvertToTyped[*storagev1.StorageClass](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert storage class: %w", err)
@@ -55,31 +70,14 @@ func (h *StorageClassHandler) HandleCreate(ctx context.Context, obj interface{},
 		properties["parameters"] = sc.Parameters
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"StorageClass"}, properties, "uid"); err != nil {
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"StorageClass"}, properties, "uid", ownerRelationships("StorageClass", string(sc.UID), sc.OwnerReferences)); err != nil {
 		return fmt.Errorf("failed to upsert storage class %s: %w", sc.Name, err)
 	}
 
-	// Create relationships based on owner references for all supported types
-	if sc.OwnerReferences != nil {
-		for _, ownerRef := range sc.OwnerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"StorageClass", "uid", string(sc.UID),
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between StorageClass %s and %s %s: %v\n", sc.Name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
-
 	return nil
 }
 
-func (h *StorageClassHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *StorageClassHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	sc, err := ConvertToTyped[*storagev1.StorageClass](obj)
 	if err != nil {
 		return fmt.Errorf("failed to convert storage class: %w", err)
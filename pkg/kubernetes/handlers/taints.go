@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// parseTaint parses a taint in the "key=value:effect" form stored on Nodes
+func parseTaint(formatted string) corev1.Taint {
+	rest, effect, _ := cutLast(formatted, ":")
+	key, value, _ := strings.Cut(rest, "=")
+	return corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)}
+}
+
+// parseToleration parses a toleration in the "key=value:effect(operator)"
+// form stored on Pods, see formatTolerations
+func parseToleration(formatted string) corev1.Toleration {
+	var operator string
+	if strings.HasSuffix(formatted, ")") {
+		if i := strings.LastIndex(formatted, "("); i >= 0 {
+			operator = formatted[i+1 : len(formatted)-1]
+			formatted = formatted[:i]
+		}
+	}
+	taint := parseTaint(formatted)
+	return corev1.Toleration{Key: taint.Key, Value: taint.Value, Effect: taint.Effect, Operator: corev1.TolerationOperator(operator)}
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// hardTaints returns the taints keeping pods that do not tolerate them off a
+// node. PreferNoSchedule taints are only avoided by the scheduler.
+func hardTaints(taints []corev1.Taint) []corev1.Taint {
+	hard := make([]corev1.Taint, 0, len(taints))
+	for _, taint := range taints {
+		if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+			hard = append(hard, taint)
+		}
+	}
+	return hard
+}
+
+// toleratesAll reports whether the tolerations tolerate every taint
+func toleratesAll(tolerations []corev1.Toleration, taints []corev1.Taint) bool {
+	for i := range taints {
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(&taints[i]) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// taintedNode is a Node with hard taints as read from the graph
+type taintedNode struct {
+	Name   string
+	Taints []corev1.Taint
+}
+
+// decodeTolerations parses the tolerations stored on a Pod
+func decodeTolerations(value interface{}) []corev1.Toleration {
+	var formatted []string
+	if value != nil {
+		json.Unmarshal([]byte(fmt.Sprint(value)), &formatted)
+	}
+	tolerations := make([]corev1.Toleration, 0, len(formatted))
+	for _, toleration := range formatted {
+		tolerations = append(tolerations, parseToleration(toleration))
+	}
+	return tolerations
+}
+
+// loadTaintedNodes reads the nodes of a cluster with NoSchedule or NoExecute taints
+func loadTaintedNodes(ctx context.Context, neo4jClient graph.Store, clusterName string) ([]taintedNode, error) {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (n:Node {clusterName: $clusterName})
+		WHERE n.taints IS NOT NULL AND n.taints <> '[]'
+		RETURN n.name AS name, n.taints AS taints`, map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]taintedNode, 0)
+	for _, record := range records {
+		var formatted []string
+		json.Unmarshal([]byte(fmt.Sprint(record["taints"])), &formatted)
+		taints := make([]corev1.Taint, 0, len(formatted))
+		for _, taint := range formatted {
+			taints = append(taints, parseTaint(taint))
+		}
+		if hard := hardTaints(taints); len(hard) > 0 {
+			nodes = append(nodes, taintedNode{Name: fmt.Sprint(record["name"]), Taints: hard})
+		}
+	}
+	return nodes, nil
+}
+
+// toleratedNodes returns the names of the tainted nodes whose taints the
+// tolerations all tolerate
+func toleratedNodes(tolerations []corev1.Toleration, nodes []taintedNode) []string {
+	names := make([]string, 0)
+	for _, node := range nodes {
+		if toleratesAll(tolerations, node.Taints) {
+			names = append(names, node.Name)
+		}
+	}
+	return names
+}
+
+// writeTolerates replaces the TOLERATES relationships of pods, given the
+// tainted nodes each pod tolerates by pod uid. A pod points to a Node with
+// NoSchedule or NoExecute taints when it tolerates all of them.
+func writeTolerates(ctx context.Context, neo4jClient graph.Store, clusterName string, edges map[string][]string) error {
+	rows := make([]map[string]interface{}, 0, len(edges))
+	for uid, nodes := range edges {
+		rows = append(rows, map[string]interface{}{"uid": uid, "nodes": nodes})
+	}
+	params := map[string]interface{}{"clusterName": clusterName, "rows": rows}
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			UNWIND $rows AS row
+			MATCH (p:Pod {uid: row.uid})-[old:TOLERATES]->(n:Node)` +
+			graph.DeleteRelationship("old", "p", "uid", "n", "uid"),
+		Params: params,
+	}, graph.Statement{
+		Query: `
+			UNWIND $rows AS row
+			MATCH (p:Pod {uid: row.uid})
+			MATCH (n:Node {clusterName: $clusterName})
+			WHERE n.name IN row.nodes` +
+			graph.CreateRelationship("p", "uid", "TOLERATES", "", "n", "uid"),
+		Params: params,
+	})
+}
+
+// resolvePodTolerations materializes the TOLERATES relationships of one pod
+func resolvePodTolerations(ctx context.Context, neo4jClient graph.Store, uid string, tolerations []corev1.Toleration, clusterName string) error {
+	nodes, err := loadTaintedNodes(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+	return writeTolerates(ctx, neo4jClient, clusterName, map[string][]string{uid: toleratedNodes(tolerations, nodes)})
+}
+
+// ResolveTolerations materializes the TOLERATES relationships of every pod of
+// a cluster from the tolerations stored on the pods. It is run periodically so
+// the relationships follow nodes being tainted and untainted.
+func ResolveTolerations(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (p:Pod {clusterName: $clusterName})
+		WHERE NOT coalesce(p.status, '') IN ['Succeeded', 'Failed']
+		RETURN p.uid AS uid, p.tolerations AS tolerations`,
+		map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return err
+	}
+
+	nodes, err := loadTaintedNodes(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+
+	edges := make(map[string][]string)
+	for _, record := range records {
+		edges[fmt.Sprint(record["uid"])] = toleratedNodes(decodeTolerations(record["tolerations"]), nodes)
+	}
+	if err := writeTolerates(ctx, neo4jClient, clusterName, edges); err != nil {
+		return fmt.Errorf("failed to resolve tolerations: %w", err)
+	}
+	return nil
+}
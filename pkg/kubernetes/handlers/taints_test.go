@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseTolerations(t *testing.T) {
+	formatted := formatTolerations([]corev1.Toleration{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule, Operator: corev1.TolerationOpEqual},
+		{Key: "node.kubernetes.io/not-ready", Effect: corev1.TaintEffectNoExecute, Operator: corev1.TolerationOpExists},
+		{Operator: corev1.TolerationOpExists},
+		{Key: "team", Value: "a"},
+	})
+	expected := []corev1.Toleration{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule, Operator: corev1.TolerationOpEqual},
+		{Key: "node.kubernetes.io/not-ready", Effect: corev1.TaintEffectNoExecute, Operator: corev1.TolerationOpExists},
+		{Operator: corev1.TolerationOpExists},
+		{Key: "team", Value: "a"},
+	}
+	parsed := make([]corev1.Toleration, 0, len(formatted))
+	for _, toleration := range formatted {
+		parsed = append(parsed, parseToleration(toleration))
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Expected %v, got %v", expected, parsed)
+	}
+
+	if taint := parseTaint("node-role.kubernetes.io/control-plane=:NoSchedule"); taint.Key != "node-role.kubernetes.io/control-plane" || taint.Value != "" || taint.Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("Unexpected taint %+v", taint)
+	}
+}
+
+func TestToleratedNodes(t *testing.T) {
+	nodes := []taintedNode{
+		{Name: "gpu", Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}},
+		{Name: "gpu-spot", Taints: []corev1.Taint{
+			{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "spot", Value: "true", Effect: corev1.TaintEffectNoExecute},
+		}},
+		{Name: "control-plane", Taints: []corev1.Taint{{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule}}},
+	}
+
+	tests := []struct {
+		name        string
+		tolerations []corev1.Toleration
+		expected    []string
+	}{
+		{"none", nil, []string{}},
+		{"gpu", []corev1.Toleration{{Key: "dedicated", Value: "gpu", Operator: corev1.TolerationOpEqual, Effect: corev1.TaintEffectNoSchedule}}, []string{"gpu"}},
+		{"gpu and spot", []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}, {Key: "spot", Operator: corev1.TolerationOpExists}}, []string{"gpu", "gpu-spot"}},
+		{"everything", []corev1.Toleration{{Operator: corev1.TolerationOpExists}}, []string{"gpu", "gpu-spot", "control-plane"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toleratedNodes(test.tolerations, nodes); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+
+	if hard := hardTaints([]corev1.Taint{{Key: "a", Effect: corev1.TaintEffectPreferNoSchedule}, {Key: "b", Effect: corev1.TaintEffectNoExecute}}); len(hard) != 1 || hard[0].Key != "b" {
+		t.Errorf("Expected only the NoExecute taint, got %v", hard)
+	}
+}
+
+func TestPodHandlerLinksToleratedNodes(t *testing.T) {
+	store := graphtest.NewStore()
+	store.Respond("RETURN n.name AS name, n.taints AS taints",
+		map[string]interface{}{"name": "gpu", "taints": `["dedicated=gpu:NoSchedule"]`},
+		map[string]interface{}{"name": "control-plane", "taints": `["node-role.kubernetes.io/control-plane=:NoSchedule"]`},
+	)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "train-0", Namespace: "ml", UID: "pod-1"},
+		Spec: corev1.PodSpec{Tolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		}},
+	}
+	if err := NewPodHandler(nil, testConfig()).HandleCreate(context.Background(), pod, store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+
+	tolerates := store.StatementsMatching("CREATE (p)-[:TOLERATES]->(n)")
+	expected := []map[string]interface{}{{"uid": "pod-1", "nodes": []string{"gpu"}}}
+	if len(tolerates) != 1 || !reflect.DeepEqual(tolerates[0].Params["rows"], expected) {
+		t.Errorf("Expected the pod to tolerate the gpu node only, got %+v", tolerates)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ValidatingWebhookConfigurationHandler tracks validating admission webhooks,
+// linking them to the Services backing them
+type ValidatingWebhookConfigurationHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var validatingWebhookConfigurationGVR = admissionWebhookResource("validatingwebhookconfigurations")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "ValidatingWebhookConfiguration",
+		GVR:               validatingWebhookConfigurationGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewValidatingWebhookConfigurationHandler(cfg)
+		},
+	})
+}
+
+func NewValidatingWebhookConfigurationHandler(cfg *config.Config) *ValidatingWebhookConfigurationHandler {
+	RegisterOwnerKind("ValidatingWebhookConfiguration", "ValidatingWebhookConfiguration")
+	return &ValidatingWebhookConfigurationHandler{
+		BaseHandler:  NewBaseHandler(validatingWebhookConfigurationGVR, "ValidatingWebhookConfiguration", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *ValidatingWebhookConfigurationHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	configuration, err := ConvertToTyped[*admissionregistrationv1.ValidatingWebhookConfiguration](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert validatingwebhookconfiguration: %w", err)
+	}
+
+	webhooks := make([]admissionWebhook, 0, len(configuration.Webhooks))
+	for _, webhook := range configuration.Webhooks {
+		webhooks = append(webhooks, admissionWebhook{
+			Name:           webhook.Name,
+			ClientConfig:   webhook.ClientConfig,
+			FailurePolicy:  webhook.FailurePolicy,
+			SideEffects:    webhook.SideEffects,
+			TimeoutSeconds: webhook.TimeoutSeconds,
+		})
+	}
+
+	uid := string(configuration.UID)
+	properties, services := webhookProperties(webhooks)
+	properties["name"] = configuration.Name
+	properties["uid"] = uid
+	properties["creationTimestamp"] = configuration.CreationTimestamp.String()
+	properties["labels"] = configuration.Labels
+	properties["annotations"] = configuration.Annotations
+	properties["clusterName"] = h.GetClusterName()
+	properties["instanceHash"] = h.instanceHash
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"ValidatingWebhookConfiguration"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert validatingwebhookconfiguration %s: %w", configuration.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "ValidatingWebhookConfiguration", uid, "CALLS", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create CALLS relationships for ValidatingWebhookConfiguration %s: %v\n", configuration.Name, err)
+	}
+
+	return nil
+}
+
+func (h *ValidatingWebhookConfigurationHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	configuration, err := ConvertToTyped[*admissionregistrationv1.ValidatingWebhookConfiguration](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert validatingwebhookconfiguration: %w", err)
+	}
+	return HandleResourceDelete(ctx, "ValidatingWebhookConfiguration", string(configuration.UID), neo4jClient)
+}
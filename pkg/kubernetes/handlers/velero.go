@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s-graph/pkg/graph"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// veleroResource returns the GVR of a Velero resource
+func veleroResource(resource string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: "velero.io", Version: "v1", Resource: resource}
+}
+
+// veleroScheduleLabel is set by Velero on the Backups created by a Schedule
+const veleroScheduleLabel = "velero.io/schedule-name"
+
+// The Velero types are not part of client-go, so the handlers decode the
+// fields they need into the structs below
+
+// veleroBackupSpec is the spec of a Backup and the template of a Schedule
+type veleroBackupSpec struct {
+	IncludedNamespaces []string                `json:"includedNamespaces,omitempty"`
+	ExcludedNamespaces []string                `json:"excludedNamespaces,omitempty"`
+	IncludedResources  []string                `json:"includedResources,omitempty"`
+	ExcludedResources  []string                `json:"excludedResources,omitempty"`
+	LabelSelector      *metav1.LabelSelector   `json:"labelSelector,omitempty"`
+	OrLabelSelectors   []*metav1.LabelSelector `json:"orLabelSelectors,omitempty"`
+	TTL                string                  `json:"ttl,omitempty"`
+	StorageLocation    string                  `json:"storageLocation,omitempty"`
+}
+
+type veleroBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              veleroBackupSpec `json:"spec"`
+	Status            struct {
+		Phase               string       `json:"phase,omitempty"`
+		StartTimestamp      *metav1.Time `json:"startTimestamp,omitempty"`
+		CompletionTimestamp *metav1.Time `json:"completionTimestamp,omitempty"`
+		Expiration          *metav1.Time `json:"expiration,omitempty"`
+		Errors              int          `json:"errors,omitempty"`
+		Warnings            int          `json:"warnings,omitempty"`
+	} `json:"status"`
+}
+
+type veleroSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		Schedule string           `json:"schedule"`
+		Template veleroBackupSpec `json:"template"`
+		Paused   bool             `json:"paused,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Phase      string       `json:"phase,omitempty"`
+		LastBackup *metav1.Time `json:"lastBackup,omitempty"`
+	} `json:"status"`
+}
+
+type veleroRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              struct {
+		BackupName         string   `json:"backupName,omitempty"`
+		ScheduleName       string   `json:"scheduleName,omitempty"`
+		IncludedNamespaces []string `json:"includedNamespaces,omitempty"`
+		ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		Phase               string       `json:"phase,omitempty"`
+		StartTimestamp      *metav1.Time `json:"startTimestamp,omitempty"`
+		CompletionTimestamp *metav1.Time `json:"completionTimestamp,omitempty"`
+		Errors              int          `json:"errors,omitempty"`
+		Warnings            int          `json:"warnings,omitempty"`
+	} `json:"status"`
+}
+
+// backupWorkloadResources are the workload kinds whose coverage by backups is
+// tracked, with the resource and group names used in Velero resource filters
+var backupWorkloadResources = map[string][2]string{
+	"Deployment":  {"deployments", "apps"},
+	"StatefulSet": {"statefulsets", "apps"},
+	"DaemonSet":   {"daemonsets", "apps"},
+	"CronJob":     {"cronjobs", "batch"},
+}
+
+// backupScope is what a Backup or Schedule includes, with the label selectors
+// in the string form accepted by labels.Parse. It is stored on the node so
+// the coverage can be resolved again when namespaces and workloads change.
+type backupScope struct {
+	IncludedNamespaces []string
+	ExcludedNamespaces []string
+	IncludedResources  []string
+	ExcludedResources  []string
+	LabelSelectors     []string
+}
+
+// backupWorkload is a workload as read from the graph for backup resolution
+type backupWorkload struct {
+	UID       string
+	Kind      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// newBackupScope returns the scope of a backup spec. ok is false when a label
+// selector cannot be converted.
+func newBackupScope(spec veleroBackupSpec) (backupScope, bool) {
+	scope := backupScope{
+		IncludedNamespaces: nonNilStrings(spec.IncludedNamespaces),
+		ExcludedNamespaces: nonNilStrings(spec.ExcludedNamespaces),
+		IncludedResources:  nonNilStrings(spec.IncludedResources),
+		ExcludedResources:  nonNilStrings(spec.ExcludedResources),
+		LabelSelectors:     []string{},
+	}
+	selectors := spec.OrLabelSelectors
+	if spec.LabelSelector != nil {
+		selectors = append([]*metav1.LabelSelector{spec.LabelSelector}, selectors...)
+	}
+	for _, selector := range selectors {
+		converted, ok := selectorString(selector)
+		if !ok {
+			return backupScope{}, false
+		}
+		scope.LabelSelectors = append(scope.LabelSelectors, converted)
+	}
+	return scope, true
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// properties returns the node properties describing the scope
+func (s backupScope) properties() map[string]interface{} {
+	return map[string]interface{}{
+		"includedNamespaces": s.IncludedNamespaces,
+		"excludedNamespaces": s.ExcludedNamespaces,
+		"includedResources":  s.IncludedResources,
+		"excludedResources":  s.ExcludedResources,
+		"labelSelectors":     s.LabelSelectors,
+	}
+}
+
+// matchesAnyPattern reports whether a name matches one of the patterns, which
+// may use the glob syntax Velero accepts in namespace filters
+func matchesAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// includesNamespace reports whether the scope includes a namespace. No
+// included namespaces means all of them.
+func (s backupScope) includesNamespace(namespace string) bool {
+	if len(s.IncludedNamespaces) > 0 && !matchesAnyPattern(s.IncludedNamespaces, namespace) {
+		return false
+	}
+	return !matchesAnyPattern(s.ExcludedNamespaces, namespace)
+}
+
+// matchesResource reports whether a resource filter names a workload kind,
+// by resource, resource.group or kind
+func matchesResource(filters []string, kind string) bool {
+	resource := backupWorkloadResources[kind]
+	for _, filter := range filters {
+		filter = strings.ToLower(filter)
+		if filter == "*" || filter == resource[0] || filter == resource[0]+"."+resource[1] || filter == strings.ToLower(kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// includesKind reports whether the scope includes a workload kind. No
+// included resources means all of them.
+func (s backupScope) includesKind(kind string) bool {
+	if len(s.IncludedResources) > 0 && !matchesResource(s.IncludedResources, kind) {
+		return false
+	}
+	return !matchesResource(s.ExcludedResources, kind)
+}
+
+// wholeNamespaces reports whether the scope backs up every resource of the
+// namespaces it includes
+func (s backupScope) wholeNamespaces() bool {
+	allResources := len(s.IncludedResources) == 0 || (len(s.IncludedResources) == 1 && s.IncludedResources[0] == "*")
+	return allResources && len(s.ExcludedResources) == 0 && len(s.LabelSelectors) == 0
+}
+
+// protectedTargets returns what a backup scope protects: whole namespaces
+// when it backs up everything in them, otherwise the workloads it includes
+func protectedTargets(scope backupScope, namespaces []string, workloads []backupWorkload) ([]string, []string) {
+	if scope.wholeNamespaces() {
+		protected := make(map[string]bool)
+		for _, namespace := range namespaces {
+			if scope.includesNamespace(namespace) {
+				protected[namespace] = true
+			}
+		}
+		return sortedKeys(protected), []string{}
+	}
+
+	protected := make(map[string]bool)
+	for _, workload := range workloads {
+		if !scope.includesNamespace(workload.Namespace) || !scope.includesKind(workload.Kind) {
+			continue
+		}
+		if len(scope.LabelSelectors) > 0 && !matchesAnySelector(scope.LabelSelectors, workload.Labels) {
+			continue
+		}
+		protected[workload.UID] = true
+	}
+	return []string{}, sortedKeys(protected)
+}
+
+// loadBackupTargets reads the namespaces and workloads of a cluster
+func loadBackupTargets(ctx context.Context, neo4jClient graph.Store, clusterName string) ([]string, []backupWorkload, error) {
+	params := map[string]interface{}{"clusterName": clusterName}
+	namespaceRecords, err := neo4jClient.Read(ctx, `
+		MATCH (n:Namespace {clusterName: $clusterName})
+		RETURN n.name AS name`, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloadRecords, err := neo4jClient.Read(ctx, `
+		MATCH (w {clusterName: $clusterName})
+		WHERE w:Deployment OR w:StatefulSet OR w:DaemonSet OR w:CronJob
+		RETURN w.uid AS uid, labels(w)[0] AS kind, w.namespace AS namespace, w.labels AS labels`, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namespaces := make([]string, 0, len(namespaceRecords))
+	for _, record := range namespaceRecords {
+		namespaces = append(namespaces, fmt.Sprint(record["name"]))
+	}
+	workloads := make([]backupWorkload, 0, len(workloadRecords))
+	for _, record := range workloadRecords {
+		workload := backupWorkload{}
+		for key, target := range map[string]*string{"uid": &workload.UID, "kind": &workload.Kind, "namespace": &workload.Namespace} {
+			*target = fmt.Sprint(record[key])
+		}
+		if value := record["labels"]; value != nil {
+			json.Unmarshal([]byte(fmt.Sprint(value)), &workload.Labels)
+		}
+		workloads = append(workloads, workload)
+	}
+	return namespaces, workloads, nil
+}
+
+// writeBackupEdges replaces the PROTECTS relationships of a Backup or Schedule
+func writeBackupEdges(ctx context.Context, neo4jClient graph.Store, label, uid, clusterName string, namespaces, workloadUIDs []string) error {
+	params := map[string]interface{}{
+		"uid":         uid,
+		"clusterName": clusterName,
+		"namespaces":  namespaces,
+		"workloads":   workloadUIDs,
+	}
+	queries := []string{
+		fmt.Sprintf(`MATCH (b:%s {uid: $uid})-[old:PROTECTS]->(target)`, label) +
+			graph.DeleteRelationship("old", "b", "uid", "target", "uid"),
+		fmt.Sprintf(`MATCH (b:%s {uid: $uid})
+		 MATCH (n:Namespace {clusterName: $clusterName}) WHERE n.name IN $namespaces`, label) +
+			graph.MergeRelationship("b", "uid", "PROTECTS", "n", "uid"),
+		fmt.Sprintf(`MATCH (b:%s {uid: $uid})
+		 MATCH (w {clusterName: $clusterName}) WHERE w.uid IN $workloads`, label) +
+			graph.MergeRelationship("b", "uid", "PROTECTS", "w", "uid"),
+	}
+	statements := make([]graph.Statement, 0, len(queries))
+	for _, query := range queries {
+		statements = append(statements, graph.Statement{Query: query, Params: params})
+	}
+	return neo4jClient.Write(ctx, statements...)
+}
+
+// linkProtectedTargets creates the PROTECTS relationships of one Backup or Schedule
+func linkProtectedTargets(ctx context.Context, neo4jClient graph.Store, label, uid string, scope backupScope, clusterName string) error {
+	namespaces, workloads, err := loadBackupTargets(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+	protectedNamespaces, protectedWorkloads := protectedTargets(scope, namespaces, workloads)
+	return writeBackupEdges(ctx, neo4jClient, label, uid, clusterName, protectedNamespaces, protectedWorkloads)
+}
+
+// backupProtects reports whether a Backup in a phase holds usable data
+func backupProtects(phase string) bool {
+	return phase == "Completed" || phase == "PartiallyFailed"
+}
+
+// ResolveBackups links every Schedule and usable Backup of a cluster to the
+// namespaces and workloads they protect. The handlers link them when they
+// change; running this periodically follows namespaces and workloads created
+// or relabeled since.
+func ResolveBackups(ctx context.Context, neo4jClient graph.Store, clusterName string) error {
+	records, err := neo4jClient.Read(ctx, `
+		MATCH (b {clusterName: $clusterName})
+		WHERE (b:Schedule OR (b:Backup AND b.phase IN ['Completed', 'PartiallyFailed']))
+		  AND b.labelSelectors IS NOT NULL
+		RETURN labels(b)[0] AS label, b.uid AS uid, b.name AS name, b.namespace AS namespace,
+		       b.includedNamespaces AS includedNamespaces, b.excludedNamespaces AS excludedNamespaces,
+		       b.includedResources AS includedResources, b.excludedResources AS excludedResources,
+		       b.labelSelectors AS labelSelectors`,
+		map[string]interface{}{"clusterName": clusterName})
+	if err != nil {
+		return err
+	}
+
+	namespaces, workloads, err := loadBackupTargets(ctx, neo4jClient, clusterName)
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, record := range records {
+		decode := func(key string) []string {
+			var values []string
+			if value := record[key]; value != nil {
+				json.Unmarshal([]byte(fmt.Sprint(value)), &values)
+			}
+			return values
+		}
+		scope := backupScope{
+			IncludedNamespaces: decode("includedNamespaces"),
+			ExcludedNamespaces: decode("excludedNamespaces"),
+			IncludedResources:  decode("includedResources"),
+			ExcludedResources:  decode("excludedResources"),
+			LabelSelectors:     decode("labelSelectors"),
+		}
+		label := fmt.Sprint(record["label"])
+		protectedNamespaces, protectedWorkloads := protectedTargets(scope, namespaces, workloads)
+		if err := writeBackupEdges(ctx, neo4jClient, label, fmt.Sprint(record["uid"]), clusterName, protectedNamespaces, protectedWorkloads); err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s/%s", label, record["namespace"], record["name"]))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to link backups %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
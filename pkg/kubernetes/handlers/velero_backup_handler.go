@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VeleroBackupHandler tracks Velero Backups, linking completed backups to the
+// namespaces and workloads they hold
+type VeleroBackupHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var backupGVR = veleroResource("backups")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Backup",
+		GVR:               backupGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVeleroBackupHandler(cfg)
+		},
+	})
+}
+
+func NewVeleroBackupHandler(cfg *config.Config) *VeleroBackupHandler {
+	RegisterOwnerKind("Backup", "Backup")
+	return &VeleroBackupHandler{
+		BaseHandler:  NewBaseHandler(backupGVR, "Backup", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VeleroBackupHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	backup, err := ConvertToTyped[*veleroBackup](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert backup: %w", err)
+	}
+
+	uid := string(backup.UID)
+	properties := map[string]interface{}{
+		"name":              backup.Name,
+		"uid":               uid,
+		"namespace":         backup.Namespace,
+		"creationTimestamp": backup.CreationTimestamp.String(),
+		"labels":            backup.Labels,
+		"annotations":       backup.Annotations,
+		"schedule":          backup.Labels[veleroScheduleLabel],
+		"ttl":               backup.Spec.TTL,
+		"storageLocation":   backup.Spec.StorageLocation,
+		"phase":             backup.Status.Phase,
+		"errors":            backup.Status.Errors,
+		"warnings":          backup.Status.Warnings,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	if backup.Status.StartTimestamp != nil {
+		properties["startTimestamp"] = backup.Status.StartTimestamp.String()
+	}
+	if backup.Status.CompletionTimestamp != nil {
+		properties["completionTimestamp"] = backup.Status.CompletionTimestamp.String()
+	}
+	if backup.Status.Expiration != nil {
+		properties["expiration"] = backup.Status.Expiration.String()
+	}
+	scope, resolvable := newBackupScope(backup.Spec)
+	if resolvable {
+		for key, value := range scope.properties() {
+			properties[key] = value
+		}
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Backup"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert backup %s: %w", backup.Name, err)
+	}
+
+	if !resolvable {
+		fmt.Printf("Warning: Backup %s has an invalid label selector, not linking it\n", backup.Name)
+		return nil
+	}
+	// Only backups holding data protect anything, so in-progress and failed backups have no relationships
+	if backupProtects(backup.Status.Phase) {
+		err = linkProtectedTargets(ctx, neo4jClient, "Backup", uid, scope, h.GetClusterName())
+	} else {
+		err = writeBackupEdges(ctx, neo4jClient, "Backup", uid, h.GetClusterName(), []string{}, []string{})
+	}
+	if err != nil {
+		fmt.Printf("Warning: failed to create PROTECTS relationships for Backup %s: %v\n", backup.Name, err)
+	}
+
+	return nil
+}
+
+func (h *VeleroBackupHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	backup, err := ConvertToTyped[*veleroBackup](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert backup: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Backup", string(backup.UID), neo4jClient)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VeleroRestoreHandler tracks Velero Restores, linking them to the Backup
+// they restore from
+type VeleroRestoreHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var restoreGVR = veleroResource("restores")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Restore",
+		GVR:               restoreGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVeleroRestoreHandler(cfg)
+		},
+	})
+}
+
+func NewVeleroRestoreHandler(cfg *config.Config) *VeleroRestoreHandler {
+	RegisterOwnerKind("Restore", "Restore")
+	return &VeleroRestoreHandler{
+		BaseHandler:  NewBaseHandler(restoreGVR, "Restore", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VeleroRestoreHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	restore, err := ConvertToTyped[*veleroRestore](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert restore: %w", err)
+	}
+
+	uid := string(restore.UID)
+	properties := map[string]interface{}{
+		"name":               restore.Name,
+		"uid":                uid,
+		"namespace":          restore.Namespace,
+		"creationTimestamp":  restore.CreationTimestamp.String(),
+		"labels":             restore.Labels,
+		"annotations":        restore.Annotations,
+		"backupName":         restore.Spec.BackupName,
+		"scheduleName":       restore.Spec.ScheduleName,
+		"includedNamespaces": nonNilStrings(restore.Spec.IncludedNamespaces),
+		"excludedNamespaces": nonNilStrings(restore.Spec.ExcludedNamespaces),
+		"phase":              restore.Status.Phase,
+		"errors":             restore.Status.Errors,
+		"warnings":           restore.Status.Warnings,
+		"clusterName":        h.GetClusterName(),
+		"instanceHash":       h.instanceHash,
+	}
+	if restore.Status.StartTimestamp != nil {
+		properties["startTimestamp"] = restore.Status.StartTimestamp.String()
+	}
+	if restore.Status.CompletionTimestamp != nil {
+		properties["completionTimestamp"] = restore.Status.CompletionTimestamp.String()
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Restore"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert restore %s: %w", restore.Name, err)
+	}
+
+	// Velero fills backupName in with the latest backup of the schedule for restores from a schedule
+	backups := make([]string, 0, 1)
+	if restore.Spec.BackupName != "" {
+		backups = append(backups, namespacedKey(restore.Namespace, restore.Spec.BackupName))
+	}
+	if err := linkNamespacedTargets(ctx, neo4jClient, "Restore", uid, "RESTORES", "Backup", h.GetClusterName(), backups); err != nil {
+		fmt.Printf("Warning: failed to create RESTORES relationship for Restore %s: %v\n", restore.Name, err)
+	}
+
+	return nil
+}
+
+func (h *VeleroRestoreHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	restore, err := ConvertToTyped[*veleroRestore](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert restore: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Restore", string(restore.UID), neo4jClient)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VeleroScheduleHandler tracks Velero Schedules, linking them to the
+// namespaces and workloads their backups include
+type VeleroScheduleHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var scheduleGVR = veleroResource("schedules")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "Schedule",
+		GVR:               scheduleGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVeleroScheduleHandler(cfg)
+		},
+	})
+}
+
+func NewVeleroScheduleHandler(cfg *config.Config) *VeleroScheduleHandler {
+	RegisterOwnerKind("Schedule", "Schedule")
+	return &VeleroScheduleHandler{
+		BaseHandler:  NewBaseHandler(scheduleGVR, "Schedule", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VeleroScheduleHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	schedule, err := ConvertToTyped[*veleroSchedule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert schedule: %w", err)
+	}
+
+	uid := string(schedule.UID)
+	properties := map[string]interface{}{
+		"name":              schedule.Name,
+		"uid":               uid,
+		"namespace":         schedule.Namespace,
+		"creationTimestamp": schedule.CreationTimestamp.String(),
+		"labels":            schedule.Labels,
+		"annotations":       schedule.Annotations,
+		"schedule":          schedule.Spec.Schedule,
+		"paused":            schedule.Spec.Paused,
+		"ttl":               schedule.Spec.Template.TTL,
+		"storageLocation":   schedule.Spec.Template.StorageLocation,
+		"phase":             schedule.Status.Phase,
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	if schedule.Status.LastBackup != nil {
+		properties["lastBackup"] = schedule.Status.LastBackup.String()
+	}
+	scope, resolvable := newBackupScope(schedule.Spec.Template)
+	if resolvable {
+		for key, value := range scope.properties() {
+			properties[key] = value
+		}
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"Schedule"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert schedule %s: %w", schedule.Name, err)
+	}
+
+	if !resolvable {
+		fmt.Printf("Warning: Schedule %s has an invalid label selector, not linking it\n", schedule.Name)
+		return nil
+	}
+	if err := linkProtectedTargets(ctx, neo4jClient, "Schedule", uid, scope, h.GetClusterName()); err != nil {
+		fmt.Printf("Warning: failed to create PROTECTS relationships for Schedule %s: %v\n", schedule.Name, err)
+	}
+
+	return nil
+}
+
+func (h *VeleroScheduleHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	schedule, err := ConvertToTyped[*veleroSchedule](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert schedule: %w", err)
+	}
+	return HandleResourceDelete(ctx, "Schedule", string(schedule.UID), neo4jClient)
+}
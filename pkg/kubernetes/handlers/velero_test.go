@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s-graph/pkg/graph/graphtest"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNewBackupScope(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "velero.io/v1",
+		"kind":       "Schedule",
+		"metadata":   map[string]interface{}{"name": "daily", "namespace": "velero", "uid": "schedule-1"},
+		"spec": map[string]interface{}{
+			"schedule": "0 2 * * *",
+			"template": map[string]interface{}{
+				"includedNamespaces": []interface{}{"payments"},
+				"labelSelector":      map[string]interface{}{"matchLabels": map[string]interface{}{"backup": "true"}},
+				"orLabelSelectors": []interface{}{
+					map[string]interface{}{"matchLabels": map[string]interface{}{"tier": "db"}},
+				},
+				"ttl": "720h0m0s",
+			},
+		},
+	}}
+
+	schedule, err := ConvertToTyped[*veleroSchedule](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert schedule: %v", err)
+	}
+	scope, ok := newBackupScope(schedule.Spec.Template)
+	if !ok {
+		t.Fatal("Expected the scope to be resolvable")
+	}
+	if !reflect.DeepEqual(scope.LabelSelectors, []string{"backup=true", "tier=db"}) {
+		t.Errorf("Unexpected label selectors %v", scope.LabelSelectors)
+	}
+	if scope.wholeNamespaces() {
+		t.Error("Expected a scope with label selectors not to protect whole namespaces")
+	}
+}
+
+func TestProtectedTargets(t *testing.T) {
+	namespaces := []string{"kube-system", "payments", "orders", "velero"}
+	workloads := []backupWorkload{
+		{UID: "api", Kind: "Deployment", Namespace: "payments", Labels: map[string]string{"backup": "true"}},
+		{UID: "db", Kind: "StatefulSet", Namespace: "payments", Labels: map[string]string{"tier": "db"}},
+		{UID: "worker", Kind: "Deployment", Namespace: "orders", Labels: map[string]string{}},
+		{UID: "agent", Kind: "DaemonSet", Namespace: "kube-system", Labels: map[string]string{"backup": "true"}},
+	}
+
+	tests := []struct {
+		name               string
+		scope              backupScope
+		expectedNamespaces []string
+		expectedWorkloads  []string
+	}{
+		{
+			name:               "all namespaces",
+			scope:              backupScope{},
+			expectedNamespaces: []string{"kube-system", "orders", "payments", "velero"},
+			expectedWorkloads:  []string{},
+		},
+		{
+			name:               "included and excluded namespaces",
+			scope:              backupScope{IncludedNamespaces: []string{"*"}, ExcludedNamespaces: []string{"kube-*", "velero"}},
+			expectedNamespaces: []string{"orders", "payments"},
+			expectedWorkloads:  []string{},
+		},
+		{
+			name:               "label selectors",
+			scope:              backupScope{IncludedNamespaces: []string{"payments", "kube-system"}, LabelSelectors: []string{"backup=true", "tier=db"}},
+			expectedNamespaces: []string{},
+			expectedWorkloads:  []string{"agent", "api", "db"},
+		},
+		{
+			name:               "included resources",
+			scope:              backupScope{IncludedResources: []string{"statefulsets.apps", "persistentvolumeclaims"}},
+			expectedNamespaces: []string{},
+			expectedWorkloads:  []string{"db"},
+		},
+		{
+			name:               "excluded resources",
+			scope:              backupScope{ExcludedResources: []string{"Deployment"}, ExcludedNamespaces: []string{"kube-system"}},
+			expectedNamespaces: []string{},
+			expectedWorkloads:  []string{"db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protectedNamespaces, protectedWorkloads := protectedTargets(tt.scope, namespaces, workloads)
+			if !reflect.DeepEqual(protectedNamespaces, tt.expectedNamespaces) {
+				t.Errorf("Expected namespaces %v, got %v", tt.expectedNamespaces, protectedNamespaces)
+			}
+			if !reflect.DeepEqual(protectedWorkloads, tt.expectedWorkloads) {
+				t.Errorf("Expected workloads %v, got %v", tt.expectedWorkloads, protectedWorkloads)
+			}
+		})
+	}
+}
+
+func TestVeleroBackupHandlerWrites(t *testing.T) {
+	backup := func(phase string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "velero.io/v1",
+			"kind":       "Backup",
+			"metadata": map[string]interface{}{
+				"name":      "daily-20240101",
+				"namespace": "velero",
+				"uid":       "backup-1",
+				"labels":    map[string]interface{}{veleroScheduleLabel: "daily"},
+			},
+			"spec":   map[string]interface{}{"includedNamespaces": []interface{}{"payments"}},
+			"status": map[string]interface{}{"phase": phase},
+		}}
+	}
+	respond := func(store *graphtest.Store) {
+		store.Respond("RETURN n.name AS name", map[string]interface{}{"name": "payments"}, map[string]interface{}{"name": "orders"})
+	}
+
+	store := graphtest.NewStore()
+	respond(store)
+	if err := NewVeleroBackupHandler(testConfig()).HandleCreate(context.Background(), backup("Completed"), store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+	expectNode(t, store, "Backup", "backup-1", map[string]interface{}{"schedule": "daily", "phase": "Completed"})
+	protects := store.StatementsMatching("MERGE (b)-[:PROTECTS]->(n)")
+	if len(protects) != 1 || !reflect.DeepEqual(protects[0].Params["namespaces"], []string{"payments"}) {
+		t.Errorf("Expected the backup to protect the payments namespace, got %+v", protects)
+	}
+
+	// Backups without usable data lose their relationships
+	store = graphtest.NewStore()
+	respond(store)
+	if err := NewVeleroBackupHandler(testConfig()).HandleCreate(context.Background(), backup("InProgress"), store); err != nil {
+		t.Fatalf("HandleCreate failed: %v", err)
+	}
+	protects = store.StatementsMatching("MERGE (b)-[:PROTECTS]->(n)")
+	if len(protects) != 1 || !reflect.DeepEqual(protects[0].Params["namespaces"], []string{}) {
+		t.Errorf("Expected an in-progress backup to protect nothing, got %+v", protects)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VirtualServiceHandler tracks Istio VirtualServices, linking them to the
+// Services they route to and the Istio Gateways they are bound to
+type VirtualServiceHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var virtualServiceGVR = istioResource("networking.istio.io", "virtualservices")
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "VirtualService",
+		GVR:               virtualServiceGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVirtualServiceHandler(cfg)
+		},
+	})
+}
+
+func NewVirtualServiceHandler(cfg *config.Config) *VirtualServiceHandler {
+	RegisterOwnerKind("VirtualService", "VirtualService")
+	return &VirtualServiceHandler{
+		BaseHandler:  NewBaseHandler(virtualServiceGVR, "VirtualService", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VirtualServiceHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	vs, err := ConvertToTyped[*virtualService](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert virtualservice: %w", err)
+	}
+
+	uid := string(vs.UID)
+	services := virtualServiceDestinations(vs)
+	gateways := virtualServiceGateways(vs)
+	properties := map[string]interface{}{
+		"name":                vs.Name,
+		"uid":                 uid,
+		"namespace":           vs.Namespace,
+		"creationTimestamp":   vs.CreationTimestamp.String(),
+		"labels":              vs.Labels,
+		"annotations":         vs.Annotations,
+		"hosts":               vs.Spec.Hosts,
+		"gateways":            vs.Spec.Gateways,
+		"istioGateways":       gateways,
+		"destinationServices": services,
+		"clusterName":         h.GetClusterName(),
+		"instanceHash":        h.instanceHash,
+	}
+
+	if err := neo4jClient.UpsertNode(ctx, []string{"VirtualService"}, properties, "uid"); err != nil {
+		return fmt.Errorf("failed to upsert virtualservice %s: %w", vs.Name, err)
+	}
+
+	if err := linkNamespacedTargets(ctx, neo4jClient, "VirtualService", uid, "ROUTES_TO", "Service", h.GetClusterName(), services); err != nil {
+		fmt.Printf("Warning: failed to create ROUTES_TO relationships for VirtualService %s: %v\n", vs.Name, err)
+	}
+	if err := linkNamespacedTargets(ctx, neo4jClient, "VirtualService", uid, "ATTACHED_TO", "IstioGateway", h.GetClusterName(), gateways); err != nil {
+		fmt.Printf("Warning: failed to create ATTACHED_TO relationships for VirtualService %s: %v\n", vs.Name, err)
+	}
+
+	return nil
+}
+
+func (h *VirtualServiceHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	vs, err := ConvertToTyped[*virtualService](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert virtualservice: %w", err)
+	}
+	return HandleResourceDelete(ctx, "VirtualService", string(vs.UID), neo4jClient)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// VolumeAttachmentHandler tracks the requests to attach PersistentVolumes to
+// Nodes and their outcome, which is where stuck attachments show up
+type VolumeAttachmentHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var volumeAttachmentGVR = schema.GroupVersionResource{
+	Group:    "storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumeattachments",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "VolumeAttachment",
+		GVR:               volumeAttachmentGVR,
+		Namespaced:        false,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVolumeAttachmentHandler(cfg)
+		},
+	})
+}
+
+func NewVolumeAttachmentHandler(cfg *config.Config) *VolumeAttachmentHandler {
+	return &VolumeAttachmentHandler{
+		BaseHandler:  NewBaseHandler(volumeAttachmentGVR, "VolumeAttachment", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VolumeAttachmentHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	va, err := ConvertToTyped[*storagev1.VolumeAttachment](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert volumeattachment: %w", err)
+	}
+
+	uid := string(va.UID)
+	pvName := ""
+	if va.Spec.Source.PersistentVolumeName != nil {
+		pvName = *va.Spec.Source.PersistentVolumeName
+	}
+
+	properties := map[string]interface{}{
+		"name":                 va.Name,
+		"uid":                  uid,
+		"creationTimestamp":    va.CreationTimestamp.String(),
+		"labels":               va.Labels,
+		"annotations":          va.Annotations,
+		"attacher":             va.Spec.Attacher,
+		"nodeName":             va.Spec.NodeName,
+		"persistentVolumeName": pvName,
+		"attached":             va.Status.Attached,
+		"clusterName":          h.GetClusterName(),
+		"instanceHash":         h.instanceHash,
+	}
+	if va.Status.AttachError != nil {
+		properties["attachError"] = va.Status.AttachError.Message
+		properties["attachErrorTime"] = va.Status.AttachError.Time.String()
+	}
+	if va.Status.DetachError != nil {
+		properties["detachError"] = va.Status.DetachError.Message
+		properties["detachErrorTime"] = va.Status.DetachError.Time.String()
+	}
+
+	relationships := ownerRelationships("VolumeAttachment", uid, va.OwnerReferences)
+	relationships = append(relationships,
+		relationship("VolumeAttachment", "uid", uid, "ATTACHED_TO", "Node", "name", va.Spec.NodeName),
+		relationship("VolumeAttachment", "uid", uid, "USES_DRIVER", "CSIDriver", "name", va.Spec.Attacher),
+	)
+	if pvName != "" {
+		relationships = append(relationships, relationship("VolumeAttachment", "uid", uid, "ATTACHES", "PersistentVolume", "name", pvName))
+	}
+
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"VolumeAttachment"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert volumeattachment %s: %w", va.Name, err)
+	}
+	return nil
+}
+
+func (h *VolumeAttachmentHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	va, err := ConvertToTyped[*storagev1.VolumeAttachment](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert volumeattachment: %w", err)
+	}
+	return HandleResourceDelete(ctx, "VolumeAttachment", string(va.UID), neo4jClient)
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"kubegraph/config"
-	"kubegraph/pkg/neo4j"
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 )
 
 type VerticalPodAutoscalerHandler struct {
@@ -16,25 +19,38 @@ type VerticalPodAutoscalerHandler struct {
 	instanceHash string
 }
 
+var verticalPodAutoscalerGVR = schema.GroupVersionResource{
+	Group:    "autoscaling.k8s.io",
+	Version:  "v1",
+	Resource: "verticalpodautoscalers",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "VerticalPodAutoscaler",
+		GVR:               verticalPodAutoscalerGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVerticalPodAutoscalerHandler(cfg)
+		},
+	})
+}
+
 func NewVerticalPodAutoscalerHandler(cfg *config.Config) *VerticalPodAutoscalerHandler {
-	gvr := schema.GroupVersionResource{
-		Group:    "autoscaling.k8s.io",
-		Version:  "v1",
-		Resource: "verticalpodautoscalers",
-	}
 	// Register this handler's kind for owner references
 	RegisterOwnerKind("VerticalPodAutoscaler", "VerticalPodAutoscaler")
 	return &VerticalPodAutoscalerHandler{
-		BaseHandler:  NewBaseHandler(gvr, "VerticalPodAutoscaler", cfg),
+		BaseHandler:  NewBaseHandler(verticalPodAutoscalerGVR, "VerticalPodAutoscaler", cfg),
 		instanceHash: cfg.InstanceHash,
 	}
 }
 
-func (h *VerticalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *VerticalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	// Since VPA is not in the standard Kubernetes API, we'll work with unstructured objects
 	unstructuredObj, ok := obj.(*unstructured.Unstructured)
 	if !ok {
-		return fmt.Errorf("object is not *unstructured.Unstructured")
+		return failure.New(failure.ConversionError, fmt.Errorf("object is not *unstructured.Unstructured"))
 	}
 
 	// Extract basic properties
@@ -62,27 +78,8 @@ func (h *VerticalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj int
 		"instanceHash":      h.instanceHash,
 	}
 
-	if err := neo4jClient.UpsertNode(ctx, []string{"VerticalPodAutoscaler"}, properties, "uid"); err != nil {
-		return fmt.Errorf("failed to upsert verticalpodautoscaler %s: %w", name, err)
-	}
-
 	// Create relationships based on owner references for all supported types
-	ownerReferences := unstructuredObj.GetOwnerReferences()
-	if ownerReferences != nil {
-		for _, ownerRef := range ownerReferences {
-			if label, ok := ownerKindToLabel[ownerRef.Kind]; ok {
-				err := neo4jClient.CreateRelationship(
-					ctx,
-					"VerticalPodAutoscaler", "uid", uid,
-					"OWNED_BY",
-					label, "uid", string(ownerRef.UID),
-				)
-				if err != nil {
-					fmt.Printf("Warning: failed to create relationship between VerticalPodAutoscaler %s and %s %s: %v\n", name, label, ownerRef.Name, err)
-				}
-			}
-		}
-	}
+	relationships := ownerRelationships("VerticalPodAutoscaler", uid, unstructuredObj.GetOwnerReferences())
 
 	// Create relationship to the target resource (Deployment, StatefulSet, etc.)
 	if spec != nil {
@@ -90,28 +87,24 @@ func (h *VerticalPodAutoscalerHandler) HandleCreate(ctx context.Context, obj int
 			if targetName, ok := targetRef["name"].(string); ok && targetName != "" {
 				if targetKind, ok := targetRef["kind"].(string); ok {
 					if label, ok := ownerKindToLabel[targetKind]; ok {
-						err := neo4jClient.CreateRelationship(
-							ctx,
-							"VerticalPodAutoscaler", "uid", uid,
-							"SCALES",
-							label, "name", targetName,
-						)
-						if err != nil {
-							fmt.Printf("Warning: failed to create SCALES relationship between VerticalPodAutoscaler %s and %s %s: %v\n", name, targetKind, targetName, err)
-						}
+						relationships = append(relationships, relationship("VerticalPodAutoscaler", "uid", uid, "SCALES", label, "name", targetName))
 					}
 				}
 			}
 		}
 	}
 
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"VerticalPodAutoscaler"}, properties, "uid", relationships); err != nil {
+		return fmt.Errorf("failed to upsert verticalpodautoscaler %s: %w", name, err)
+	}
+
 	return nil
 }
 
-func (h *VerticalPodAutoscalerHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient *neo4j.Client) error {
+func (h *VerticalPodAutoscalerHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
 	unstructuredObj, ok := obj.(*unstructured.Unstructured)
 	if !ok {
-		return fmt.Errorf("object is not *unstructured.Unstructured")
+		return failure.New(failure.ConversionError, fmt.Errorf("object is not *unstructured.Unstructured"))
 	}
 	return HandleResourceDelete(ctx, "VerticalPodAutoscaler", string(unstructuredObj.GetUID()), neo4jClient)
 }
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/registry"
+	"k8s-graph/pkg/vulns"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// trivyVulnerabilityReport is the VulnerabilityReport of the trivy-operator,
+// which is not part of client-go
+type trivyVulnerabilityReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Report            struct {
+		UpdateTimestamp metav1.Time `json:"updateTimestamp"`
+		Scanner         struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"scanner"`
+		Registry struct {
+			Server string `json:"server"`
+		} `json:"registry"`
+		Artifact struct {
+			Repository string `json:"repository"`
+			Tag        string `json:"tag"`
+			Digest     string `json:"digest"`
+		} `json:"artifact"`
+		Summary struct {
+			CriticalCount int `json:"criticalCount"`
+			HighCount     int `json:"highCount"`
+			MediumCount   int `json:"mediumCount"`
+			LowCount      int `json:"lowCount"`
+			UnknownCount  int `json:"unknownCount"`
+		} `json:"summary"`
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"vulnerabilityID"`
+			Resource        string `json:"resource"`
+			FixedVersion    string `json:"fixedVersion"`
+			Severity        string `json:"severity"`
+		} `json:"vulnerabilities"`
+	} `json:"report"`
+}
+
+// image returns the reference of the scanned image. Pods usually reference
+// images by tag, so the tag is preferred over the digest.
+func (r *trivyVulnerabilityReport) image() string {
+	artifact := r.Report.Artifact
+	image := artifact.Repository
+	if r.Report.Registry.Server != "" {
+		image = r.Report.Registry.Server + "/" + image
+	}
+	if artifact.Tag != "" {
+		return image + ":" + artifact.Tag
+	}
+	if artifact.Digest != "" {
+		return image + "@" + artifact.Digest
+	}
+	return image
+}
+
+// vulnsReport converts the report, keeping the summary counts as they also
+// cover vulnerabilities the operator may leave out of the list
+func (r *trivyVulnerabilityReport) vulnsReport() vulns.Report {
+	summary := r.Report.Summary
+	report := vulns.Report{
+		Image:     r.image(),
+		Scanner:   "trivy-operator",
+		ScannedAt: r.Report.UpdateTimestamp.Time,
+		Counts: map[string]int{
+			"CRITICAL": summary.CriticalCount,
+			"HIGH":     summary.HighCount,
+			"MEDIUM":   summary.MediumCount,
+			"LOW":      summary.LowCount,
+			"UNKNOWN":  summary.UnknownCount,
+		},
+	}
+	for _, vuln := range r.Report.Vulnerabilities {
+		report.Vulnerabilities = append(report.Vulnerabilities, vulns.Vulnerability{
+			ID:           vuln.VulnerabilityID,
+			Severity:     vuln.Severity,
+			Package:      vuln.Resource,
+			FixedVersion: vuln.FixedVersion,
+		})
+	}
+	return report
+}
+
+// VulnerabilityReportHandler tracks the VulnerabilityReports of the
+// trivy-operator and attaches their counts to Image nodes
+type VulnerabilityReportHandler struct {
+	BaseHandler
+	instanceHash string
+}
+
+var vulnerabilityReportGVR = schema.GroupVersionResource{
+	Group:    "aquasecurity.github.io",
+	Version:  "v1alpha1",
+	Resource: "vulnerabilityreports",
+}
+
+func init() {
+	registry.Register(registry.Registration{
+		Kind:              "VulnerabilityReport",
+		GVR:               vulnerabilityReportGVR,
+		Namespaced:        true,
+		RequiresClientset: false,
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) registry.Handler {
+			return NewVulnerabilityReportHandler(cfg)
+		},
+	})
+}
+
+func NewVulnerabilityReportHandler(cfg *config.Config) *VulnerabilityReportHandler {
+	RegisterOwnerKind("VulnerabilityReport", "VulnerabilityReport")
+	return &VulnerabilityReportHandler{
+		BaseHandler:  NewBaseHandler(vulnerabilityReportGVR, "VulnerabilityReport", cfg),
+		instanceHash: cfg.InstanceHash,
+	}
+}
+
+func (h *VulnerabilityReportHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	vr, err := ConvertToTyped[*trivyVulnerabilityReport](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert vulnerabilityreport: %w", err)
+	}
+
+	report := vr.vulnsReport()
+	if err := vulns.Write(ctx, neo4jClient, h.GetClusterName(), report); err != nil {
+		return err
+	}
+
+	uid := string(vr.UID)
+	image := vulns.NormalizeImage(report.Image)
+	properties := map[string]interface{}{
+		"name":              vr.Name,
+		"uid":               uid,
+		"namespace":         vr.Namespace,
+		"creationTimestamp": vr.CreationTimestamp.String(),
+		"labels":            vr.Labels,
+		"annotations":       vr.Annotations,
+		"image":             image,
+		"scanner":           vr.Report.Scanner.Name,
+		"scannerVersion":    vr.Report.Scanner.Version,
+		"updateTimestamp":   vr.Report.UpdateTimestamp.String(),
+		"clusterName":       h.GetClusterName(),
+		"instanceHash":      h.instanceHash,
+	}
+	for key, value := range report.Properties() {
+		if key != "scanner" {
+			properties[key] = value
+		}
+	}
+
+	scans := relationship("VulnerabilityReport", "uid", uid, "SCANS", "Image", "key", vulns.ImageKey(h.GetClusterName(), image))
+	if err := neo4jClient.UpsertNodeWithRelationships(ctx, []string{"VulnerabilityReport"}, properties, "uid", []graph.Relationship{scans}); err != nil {
+		return fmt.Errorf("failed to upsert vulnerabilityreport %s/%s: %w", vr.Namespace, vr.Name, err)
+	}
+
+	return nil
+}
+
+func (h *VulnerabilityReportHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	vr, err := ConvertToTyped[*trivyVulnerabilityReport](obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert vulnerabilityreport: %w", err)
+	}
+	// The counts stay on the Image node until the image is scanned again
+	return HandleResourceDelete(ctx, "VulnerabilityReport", string(vr.UID), neo4jClient)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPodSpecImages(t *testing.T) {
+	spec := corev1.PodSpec{
+		InitContainers: []corev1.Container{{Image: "busybox"}},
+		Containers:     []corev1.Container{{Image: "ghcr.io/org/app:v1"}, {Image: "nginx"}, {Image: "docker.io/library/nginx:latest"}},
+	}
+	expected := []string{"docker.io/library/busybox:latest", "docker.io/library/nginx:latest", "ghcr.io/org/app:v1"}
+	if got := podSpecImages(spec); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestTrivyVulnerabilityReport(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "aquasecurity.github.io/v1alpha1",
+		"kind":       "VulnerabilityReport",
+		"metadata":   map[string]interface{}{"name": "replicaset-web-6d4cf56db6-nginx", "namespace": "default", "uid": "report-1"},
+		"report": map[string]interface{}{
+			"registry": map[string]interface{}{"server": "index.docker.io"},
+			"artifact": map[string]interface{}{"repository": "library/nginx", "tag": "1.16", "digest": "sha256:abc"},
+			"scanner":  map[string]interface{}{"name": "Trivy", "version": "0.50.0"},
+			"summary":  map[string]interface{}{"criticalCount": int64(2), "highCount": int64(5), "lowCount": int64(1)},
+			"vulnerabilities": []interface{}{
+				map[string]interface{}{"vulnerabilityID": "CVE-2023-0001", "severity": "HIGH", "resource": "openssl", "fixedVersion": "3.0.9"},
+				map[string]interface{}{"vulnerabilityID": "CVE-2023-0002", "severity": "CRITICAL", "resource": "zlib"},
+			},
+		},
+	}}
+
+	vr, err := ConvertToTyped[*trivyVulnerabilityReport](obj)
+	if err != nil {
+		t.Fatalf("Failed to convert report: %v", err)
+	}
+	report := vr.vulnsReport()
+	if report.Image != "index.docker.io/library/nginx:1.16" {
+		t.Errorf("Unexpected image %q", report.Image)
+	}
+
+	properties := report.Properties()
+	expected := map[string]interface{}{
+		"criticalCount": 2, "highCount": 5, "mediumCount": 0, "lowCount": 1, "unknownCount": 0, "fixableCount": 1,
+		"topVulnerabilities": `["CVE-2023-0002","CVE-2023-0001"]`,
+	}
+	for key, value := range expected {
+		if properties[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, properties[key])
+		}
+	}
+}
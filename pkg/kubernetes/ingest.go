@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/handlers"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ErrUnsupportedKind is returned by Ingest for objects of kinds without a handler
+var ErrUnsupportedKind = errors.New("unsupported kind")
+
+// Ingest writes an object pushed by an external agent for another cluster to
+// store, or removes it when deleted. Ingested objects take the path of the
+// events of the watched cluster: disabled and paused handlers reject them, they
+// wait for an open circuit breaker, and failed writes are kept as dead letters.
+func (c *Client) Ingest(ctx context.Context, store graph.Store, clusterName string, obj *unstructured.Unstructured, deleted bool) error {
+	kind := obj.GetKind()
+	h, ok := c.ingestHandlers(clusterName)[kind]
+	if !ok {
+		return ErrUnsupportedKind
+	}
+	if c.isDisabled(kind) {
+		return fmt.Errorf("handler %s is disabled", kind)
+	}
+	if deleted {
+		return c.processDelete(ctx, h, obj, store, clusterName)
+	}
+	return c.processCreate(ctx, h, obj, store, clusterName, "Ingest")
+}
+
+// ingestHandlers returns the handlers attributing resources to the given
+// cluster, creating them on first use
+func (c *Client) ingestHandlers(clusterName string) map[string]handlers.ResourceHandler {
+	c.ingestMu.Lock()
+	defer c.ingestMu.Unlock()
+
+	if handlerSet, ok := c.ingested[clusterName]; ok {
+		return handlerSet
+	}
+	if c.ingested == nil {
+		c.ingested = make(map[string]map[string]handlers.ResourceHandler)
+	}
+
+	clusterCfg := *c.config
+	clusterCfg.Kubernetes.ClusterName = clusterName
+
+	handlerSet := make(map[string]handlers.ResourceHandler)
+	for _, handler := range NewResourceHandlers(nil, &clusterCfg) {
+		handlerSet[handler.GetKind()] = handler
+	}
+	c.ingested[clusterName] = handlerSet
+	return handlerSet
+}
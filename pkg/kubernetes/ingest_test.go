@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/graph/graphtest"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// failingStore fails the node writes like an unreachable Neo4j
+type failingStore struct {
+	*graphtest.Store
+}
+
+func (s failingStore) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	return &neo4j.ConnectivityError{Inner: errors.New("reset")}
+}
+
+func (s failingStore) UpsertNodeWithRelationships(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string, relationships []graph.Relationship) error {
+	return &neo4j.ConnectivityError{Inner: errors.New("reset")}
+}
+
+func newIngestClient(t *testing.T) *Client {
+	cfg := config.NewConfig()
+	cfg.Kubernetes.ClusterName = "local"
+	deadLetters, err := NewDeadLetterStore(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newReloadClient()
+	c.config = cfg
+	c.changes = NewChangeDetector(10)
+	c.deadLetters = deadLetters
+	return c
+}
+
+func ingestedPod(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "shop", "uid": "uid-" + name},
+	}}
+}
+
+func TestIngestWritesWithTheClusterOfTheSource(t *testing.T) {
+	logger.Init(logger.ERROR)
+	c := newIngestClient(t)
+	store := graphtest.NewStore()
+
+	if err := c.Ingest(context.Background(), store, "edge", ingestedPod("api-0"), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node, ok := store.Node("Pod", "uid-api-0")
+	if !ok || node.Properties["clusterName"] != "edge" {
+		t.Errorf("expected the pod to be written for cluster edge, got %+v", node)
+	}
+
+	if err := c.Ingest(context.Background(), store, "edge", ingestedPod("api-0"), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.Deleted) != 1 || store.Deleted[0] != "Pod/uid-api-0" {
+		t.Errorf("expected the pod to be deleted, got %v", store.Deleted)
+	}
+
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Widget"}}
+	if err := c.Ingest(context.Background(), store, "edge", widget, false); !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("expected an unsupported kind, got %v", err)
+	}
+}
+
+func TestIngestFollowsTheHandlerState(t *testing.T) {
+	logger.Init(logger.ERROR)
+	c := newIngestClient(t)
+	store := graphtest.NewStore()
+
+	if err := c.SetDisabledHandlers(context.Background(), []string{"Pod"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Ingest(context.Background(), store, "edge", ingestedPod("api-0"), false); err == nil {
+		t.Error("expected the disabled Pod handler to reject the pod")
+	}
+	if err := c.SetDisabledHandlers(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.PauseHandler(context.Background(), "Pod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Ingest(context.Background(), store, "edge", ingestedPod("api-0"), false); err == nil {
+		t.Error("expected the paused Pod handler to reject the pod")
+	}
+	c.gate.Resume("Pod")
+	if len(store.Nodes) != 0 {
+		t.Errorf("expected nothing to be written, got %+v", store.Nodes)
+	}
+}
+
+func TestIngestKeepsFailedWritesAsDeadLetters(t *testing.T) {
+	logger.Init(logger.ERROR)
+	c := newIngestClient(t)
+
+	if err := c.Ingest(context.Background(), failingStore{graphtest.NewStore()}, "edge", ingestedPod("api-0"), false); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	letters := c.deadLetters.List()
+	if len(letters) != 1 || letters[0].ClusterName != "edge" || len(letters[0].Object) == 0 {
+		t.Fatalf("expected a dead letter with the object of cluster edge, got %+v", letters)
+	}
+
+	// The replay writes the stored object with the handler of its cluster
+	store := graphtest.NewStore()
+	c.neo4jClient = store
+	if succeeded, failed := c.deadLetters.Retry(context.Background(), 3, c.replayDeadLetter); succeeded != 1 || failed != 0 {
+		t.Fatalf("expected the dead letter to be replayed, got %d succeeded and %d failed", succeeded, failed)
+	}
+	if node, ok := store.Node("Pod", "uid-api-0"); !ok || node.Properties["clusterName"] != "edge" {
+		t.Errorf("expected the pod to be written for cluster edge, got %+v", node)
+	}
+}
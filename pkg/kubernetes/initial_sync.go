@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/logger"
+)
+
+// States of the initial sync of a kind
+const (
+	InitialSyncPending  = "pending"  // Waiting for the kinds it links to
+	InitialSyncListing  = "listing"  // The informer is listing the objects
+	InitialSyncWriting  = "writing"  // The listed objects are being written
+	InitialSyncComplete = "complete" // Every listed object was handled
+)
+
+// InitialSyncStatus reports how far the initial list and write of a kind has
+// progressed after startup
+type InitialSyncStatus struct {
+	State       string     `json:"state"`
+	Listed      int        `json:"listed"`    // Objects returned by the initial list
+	Written     int        `json:"written"`   // Objects handled, including those skipped by the namespace filter
+	Remaining   int        `json:"remaining"` // Listed objects not handled yet
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// initialSync tracks the initial sync of one kind
+type initialSync struct {
+	status InitialSyncStatus
+	listed func() (int, bool) // Objects in the informer cache, and whether the list is done
+	synced func() bool        // Whether the handler has been called for every listed object
+}
+
+// InitialSyncTracker tracks the initial sync of every watched kind
+type InitialSyncTracker struct {
+	mu    sync.Mutex
+	kinds map[string]*initialSync
+}
+
+// NewInitialSyncTracker creates a tracker without watched kinds
+func NewInitialSyncTracker() *InitialSyncTracker {
+	return &InitialSyncTracker{kinds: make(map[string]*initialSync)}
+}
+
+// Expect reports kind as pending until its informer is set up with Track
+func (t *InitialSyncTracker) Expect(kind string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.kinds[kind]; !ok {
+		t.kinds[kind] = &initialSync{status: InitialSyncStatus{State: InitialSyncPending}}
+	}
+}
+
+// Untrack stops tracking kind, e.g. when its resource is not available
+func (t *InitialSyncTracker) Untrack(kind string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.kinds, kind)
+}
+
+// Track starts tracking the initial sync of kind. listed reports the number of
+// listed objects once the list is done, and synced whether the handler was
+// called for all of them.
+func (t *InitialSyncTracker) Track(kind string, listed func() (int, bool), synced func() bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.kinds[kind] = &initialSync{
+		status: InitialSyncStatus{State: InitialSyncListing, StartedAt: &now},
+		listed: listed,
+		synced: synced,
+	}
+}
+
+// Written counts an object of kind handled during its initial sync
+func (t *InitialSyncTracker) Written(kind string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.kinds[kind]; ok && s.status.State != InitialSyncComplete {
+		s.status.Written++
+	}
+}
+
+// Status returns the initial sync of every tracked kind
+func (t *InitialSyncTracker) Status() map[string]InitialSyncStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make(map[string]InitialSyncStatus, len(t.kinds))
+	for kind, s := range t.kinds {
+		s.refresh()
+		statuses[kind] = s.status
+	}
+	return statuses
+}
+
+// Complete reports whether the initial sync of kind is complete. Kinds that
+// are not tracked have nothing to sync.
+func (t *InitialSyncTracker) Complete(kind string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.kinds[kind]
+	if !ok {
+		return true
+	}
+	s.refresh()
+	return s.status.State == InitialSyncComplete
+}
+
+// Pending returns the sorted kinds whose initial sync is not complete
+func (t *InitialSyncTracker) Pending() []string {
+	pending := make([]string, 0)
+	for kind, status := range t.Status() {
+		if status.State != InitialSyncComplete {
+			pending = append(pending, kind)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// refresh advances the state of s from its informer
+func (s *initialSync) refresh() {
+	if s.status.State == InitialSyncComplete || s.status.State == InitialSyncPending {
+		return
+	}
+	if s.status.State == InitialSyncListing {
+		listed, done := s.listed()
+		if !done {
+			return
+		}
+		s.status.State = InitialSyncWriting
+		s.status.Listed = listed
+	}
+	s.status.Remaining = s.status.Listed - s.status.Written
+	if s.status.Remaining < 0 {
+		// Objects created during the sync are counted as written too
+		s.status.Remaining = 0
+	}
+	if s.synced() {
+		now := time.Now()
+		s.status.State = InitialSyncComplete
+		s.status.Remaining = 0
+		s.status.CompletedAt = &now
+	}
+}
+
+// InitialSync returns the initial sync of every watched kind
+func (c *Client) InitialSync() map[string]InitialSyncStatus {
+	return c.initialSync.Status()
+}
+
+// InitialSyncPending returns the watched kinds whose initial sync is not
+// complete, or nil when all are. Before watching starts no kind is complete,
+// which is reported as ["*"].
+func (c *Client) InitialSyncPending() []string {
+	if c.watchCtx == nil {
+		return []string{"*"}
+	}
+	pending := c.initialSync.Pending()
+	if len(pending) == 0 {
+		return nil
+	}
+	return pending
+}
+
+// reportInitialSync writes the initial sync of every kind to SyncStatus nodes
+// every interval until all kinds are complete, so that the CLI can show the
+// progress of a cluster
+func (c *Client) reportInitialSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	first := true
+	for {
+		statuses := c.initialSync.Status()
+		if err := c.writeInitialSync(ctx, statuses, first); err != nil {
+			logger.Warn("[SYNC] Failed to write initial sync status: %v", err)
+		} else {
+			first = false
+		}
+
+		pending := 0
+		for _, status := range statuses {
+			if status.State != InitialSyncComplete {
+				pending++
+			}
+		}
+		if pending == 0 {
+			logger.Info("[SYNC] Initial sync of %d kinds complete", len(statuses))
+			return
+		}
+		logger.Info("[SYNC] Initial sync in progress: %d of %d kinds pending", pending, len(statuses))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeInitialSync upserts a SyncStatus node per kind. The first write removes
+// the nodes of previous instances of the cluster.
+func (c *Client) writeInitialSync(ctx context.Context, statuses map[string]InitialSyncStatus, first bool) error {
+	params := map[string]interface{}{
+		"clusterName":  c.config.Kubernetes.ClusterName,
+		"instanceHash": c.config.InstanceHash,
+		"now":          time.Now().UTC().Format(time.RFC3339),
+	}
+	statements := make([]graph.Statement, 0, 2)
+	if first {
+		statements = append(statements, graph.Statement{
+			Query: `
+			MATCH (s:SyncStatus {clusterName: $clusterName})
+			WHERE s.instanceHash <> $instanceHash
+			DELETE s`,
+			Params: params,
+		})
+	}
+
+	rows := make([]map[string]interface{}, 0, len(statuses))
+	for kind, status := range statuses {
+		row := map[string]interface{}{
+			"kind":        kind,
+			"state":       status.State,
+			"listed":      status.Listed,
+			"written":     status.Written,
+			"remaining":   status.Remaining,
+			"startedAt":   nil,
+			"completedAt": nil,
+		}
+		if status.StartedAt != nil {
+			row["startedAt"] = status.StartedAt.UTC().Format(time.RFC3339)
+		}
+		if status.CompletedAt != nil {
+			row["completedAt"] = status.CompletedAt.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, row)
+	}
+	params["statuses"] = rows
+
+	statements = append(statements, graph.Statement{
+		Query: `
+		UNWIND $statuses AS status
+		MERGE (s:SyncStatus {clusterName: $clusterName, kind: status.kind})
+		SET s += status, s.instanceHash = $instanceHash, s.updatedAt = $now`,
+		Params: params,
+	})
+	return c.neo4jClient.Write(ctx, statements...)
+}
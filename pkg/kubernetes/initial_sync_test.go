@@ -0,0 +1,47 @@
+package kubernetes
+
+import "testing"
+
+func TestInitialSyncTracker(t *testing.T) {
+	tracker := NewInitialSyncTracker()
+	tracker.Expect("Pod")
+	if tracker.Complete("Pod") || tracker.Status()["Pod"].State != InitialSyncPending {
+		t.Errorf("expected Pod to be pending, got %+v", tracker.Status()["Pod"])
+	}
+	if !tracker.Complete("Secret") {
+		t.Error("expected an untracked kind to be complete")
+	}
+
+	listed, listDone, synced := 0, false, false
+	tracker.Track("Pod", func() (int, bool) { return listed, listDone }, func() bool { return synced })
+
+	tracker.Written("Pod")
+	if status := tracker.Status()["Pod"]; status.State != InitialSyncListing || status.Written != 1 {
+		t.Errorf("expected Pod to be listing with 1 written, got %+v", status)
+	}
+
+	listed, listDone = 3, true
+	status := tracker.Status()["Pod"]
+	if status.State != InitialSyncWriting || status.Listed != 3 || status.Remaining != 2 {
+		t.Errorf("expected Pod to be writing with 2 remaining, got %+v", status)
+	}
+	if pending := tracker.Pending(); len(pending) != 1 || pending[0] != "Pod" {
+		t.Errorf("expected Pod to be pending, got %v", pending)
+	}
+
+	tracker.Written("Pod")
+	synced = true
+	status = tracker.Status()["Pod"]
+	if status.State != InitialSyncComplete || status.Remaining != 0 || status.CompletedAt == nil {
+		t.Errorf("expected Pod to be complete, got %+v", status)
+	}
+	if pending := tracker.Pending(); len(pending) != 0 {
+		t.Errorf("expected no pending kinds, got %v", pending)
+	}
+
+	// Objects added after the initial sync are not counted
+	tracker.Written("Pod")
+	if status := tracker.Status()["Pod"]; status.Written != 2 {
+		t.Errorf("expected 2 written, got %d", status.Written)
+	}
+}
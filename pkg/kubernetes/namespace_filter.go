@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespaceFilter selects the namespaces whose resources are synchronized.
+// Cluster-scoped resources always pass, except Namespaces, which are filtered
+// by their name.
+type NamespaceFilter struct {
+	include map[string]bool // Empty includes every namespace
+	exclude map[string]bool
+}
+
+// NewNamespaceFilter creates a filter passing the namespaces of include (all
+// if empty) that are not in exclude
+func NewNamespaceFilter(include, exclude []string) *NamespaceFilter {
+	f := &NamespaceFilter{include: make(map[string]bool), exclude: make(map[string]bool)}
+	for _, namespace := range include {
+		f.include[namespace] = true
+	}
+	for _, namespace := range exclude {
+		f.exclude[namespace] = true
+	}
+	return f
+}
+
+// Allows reports whether obj is synchronized
+func (f *NamespaceFilter) Allows(obj interface{}) bool {
+	if f == nil || (len(f.include) == 0 && len(f.exclude) == 0) {
+		return true
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+	namespace := accessor.GetNamespace()
+	if namespace == "" {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GetKind() != "Namespace" {
+			return true
+		}
+		namespace = u.GetName()
+	}
+	if f.exclude[namespace] {
+		return false
+	}
+	return len(f.include) == 0 || f.include[namespace]
+}
@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newObject(kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *NamespaceFilter
+		obj     interface{}
+		allowed bool
+	}{
+		{"no filter", nil, newObject("Pod", "kube-system", "dns"), true},
+		{"empty filter", NewNamespaceFilter(nil, nil), newObject("Pod", "kube-system", "dns"), true},
+		{"excluded", NewNamespaceFilter(nil, []string{"kube-system"}), newObject("Pod", "kube-system", "dns"), false},
+		{"not excluded", NewNamespaceFilter(nil, []string{"kube-system"}), newObject("Pod", "payments", "api"), true},
+		{"included", NewNamespaceFilter([]string{"payments"}, nil), newObject("Pod", "payments", "api"), true},
+		{"not included", NewNamespaceFilter([]string{"payments"}, nil), newObject("Pod", "default", "web"), false},
+		{"cluster-scoped", NewNamespaceFilter([]string{"payments"}, nil), newObject("Node", "", "worker-1"), true},
+		{"namespace by name", NewNamespaceFilter(nil, []string{"kube-system"}), newObject("Namespace", "", "kube-system"), false},
+		{"tombstone", NewNamespaceFilter(nil, []string{"kube-system"}), cache.DeletedFinalStateUnknown{Obj: newObject("Pod", "kube-system", "dns")}, false},
+	}
+
+	for _, tt := range tests {
+		if allowed := tt.filter.Allows(tt.obj); allowed != tt.allowed {
+			t.Errorf("%s: expected Allows to return %v, got %v", tt.name, tt.allowed, allowed)
+		}
+	}
+}
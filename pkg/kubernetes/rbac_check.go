@@ -0,0 +1,188 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	authorizationclient "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// informerVerbs are the verbs the informers of the handlers need
+var informerVerbs = []string{"list", "watch"}
+
+// clientsetPermissions are the lookups of the handlers given a clientset:
+// they list the Pods and Jobs of workloads and get the Service of StatefulSets
+var clientsetPermissions = []permission{
+	{GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, Verb: "list"},
+	{GVR: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, Verb: "list"},
+	{GVR: schema.GroupVersionResource{Version: "v1", Resource: "services"}, Verb: "get"},
+}
+
+// tokenReviewPermission lets the HTTP server authenticate bearer tokens with
+// the TokenReview API when --http-token-review is set
+var tokenReviewPermission = permission{
+	Kind: "HTTP server",
+	GVR:  schema.GroupVersionResource{Group: "authentication.k8s.io", Version: "v1", Resource: "tokenreviews"},
+	Verb: "create",
+}
+
+// permission is a verb kubegraph uses on a resource for the handler of Kind
+type permission struct {
+	Kind string
+	GVR  schema.GroupVersionResource
+	Verb string
+}
+
+// permissions returns the permissions the handlers of the registrations and
+// the features enabled in cfg need, each verb on a resource once
+func permissions(registrations []registry.Registration, cfg *config.Config) []permission {
+	var needed []permission
+	seen := make(map[string]bool)
+	add := func(p permission) {
+		key := p.GVR.Group + "/" + p.GVR.Resource + "/" + p.Verb
+		if !seen[key] {
+			seen[key] = true
+			needed = append(needed, p)
+		}
+	}
+	for _, registration := range registrations {
+		for _, verb := range informerVerbs {
+			add(permission{Kind: registration.Kind, GVR: registration.GVR, Verb: verb})
+		}
+	}
+	for _, registration := range registrations {
+		if !registration.RequiresClientset {
+			continue
+		}
+		for _, p := range clientsetPermissions {
+			p.Kind = registration.Kind
+			add(p)
+		}
+	}
+	if cfg != nil && cfg.HTTP.Enabled && cfg.HTTP.Auth.TokenReview {
+		add(tokenReviewPermission)
+	}
+	return needed
+}
+
+// RBACCheck is the result of checking that kubegraph may use a verb on a
+// resource the handler of Kind needs
+type RBACCheck struct {
+	Kind string
+	GVR  schema.GroupVersionResource
+	Verb string
+	// Served is false when the cluster does not serve the resource, e.g.
+	// because its CRD is not installed; its permissions are not checked
+	Served  bool
+	Allowed bool
+	Reason  string
+}
+
+// CheckRBAC checks with SelfSubjectAccessReviews that kubegraph has the
+// permissions the handlers of the registrations and the features enabled in
+// its configuration need in all namespaces
+func (c *Client) CheckRBAC(ctx context.Context, registrations []registry.Registration) ([]RBACCheck, error) {
+	return checkRBAC(ctx, c.discovery, c.clientset.AuthorizationV1().SelfSubjectAccessReviews(), permissions(registrations, c.config))
+}
+
+func checkRBAC(ctx context.Context, discovery *ResourceDiscovery, reviews authorizationclient.SelfSubjectAccessReviewInterface, needed []permission) ([]RBACCheck, error) {
+	checks := make([]RBACCheck, 0, len(needed))
+	for _, p := range needed {
+		_, served, err := discovery.Lookup(p.GVR)
+		if err != nil {
+			return nil, err
+		}
+		if !served {
+			checks = append(checks, RBACCheck{Kind: p.Kind, GVR: p.GVR, Verb: p.Verb})
+			continue
+		}
+		review, err := reviews.Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Verb:     p.Verb,
+					Group:    p.GVR.Group,
+					Version:  p.GVR.Version,
+					Resource: p.GVR.Resource,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review access to %s: %w", p.GVR.String(), err)
+		}
+		checks = append(checks, RBACCheck{
+			Kind:    p.Kind,
+			GVR:     p.GVR,
+			Verb:    p.Verb,
+			Served:  true,
+			Allowed: review.Status.Allowed,
+			Reason:  review.Status.Reason,
+		})
+	}
+	return checks, nil
+}
+
+// WriteClusterRole writes the YAML of a ClusterRole named name granting
+// exactly the permissions the handlers of the registrations and the features
+// enabled in cfg need, with one rule per API group and set of verbs
+func WriteClusterRole(w io.Writer, name string, registrations []registry.Registration, cfg *config.Config) error {
+	verbs := make(map[string]map[string][]string) // by group and resource
+	for _, p := range permissions(registrations, cfg) {
+		if verbs[p.GVR.Group] == nil {
+			verbs[p.GVR.Group] = make(map[string][]string)
+		}
+		verbs[p.GVR.Group][p.GVR.Resource] = append(verbs[p.GVR.Group][p.GVR.Resource], p.Verb)
+	}
+	groups := make([]string, 0, len(verbs))
+	for group := range verbs {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var b strings.Builder
+	b.WriteString("# Minimal ClusterRole for the handlers enabled in kubegraph\n")
+	b.WriteString("apiVersion: rbac.authorization.k8s.io/v1\n")
+	b.WriteString("kind: ClusterRole\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	b.WriteString("rules:\n")
+	for _, group := range groups {
+		// Resources with the same verbs share a rule
+		resources := make(map[string][]string)
+		var verbSets []string
+		for resource, resourceVerbs := range verbs[group] {
+			sort.Strings(resourceVerbs)
+			set := quoteList(resourceVerbs)
+			if resources[set] == nil {
+				verbSets = append(verbSets, set)
+			}
+			resources[set] = append(resources[set], resource)
+		}
+		sort.Strings(verbSets)
+		for _, set := range verbSets {
+			sort.Strings(resources[set])
+			fmt.Fprintf(&b, "  - apiGroups: [%q]\n", group)
+			fmt.Fprintf(&b, "    resources: [%s]\n", quoteList(resources[set]))
+			fmt.Fprintf(&b, "    verbs: [%s]\n", set)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteList formats values as the items of a YAML flow sequence
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var rbacRegistrations = []registry.Registration{
+	{Kind: "Pod", GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+	{Kind: "Node", GVR: schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, RequiresClientset: true},
+	{Kind: "ClusterWidget", GVR: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "clusterwidgets"}},
+	{Kind: "Thing", GVR: schema.GroupVersionResource{Group: "missing.io", Version: "v1", Resource: "things"}},
+}
+
+func TestCheckRBAC(t *testing.T) {
+	clientset := fakeclientset.NewSimpleClientset()
+	// Only watching nodes is denied
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Resource != "nodes" || attributes.Verb != "watch"
+		if !review.Status.Allowed {
+			review.Status.Reason = "no RBAC policy matched"
+		}
+		return true, review, nil
+	})
+
+	checks, err := checkRBAC(context.Background(), NewResourceDiscovery(newFakeDiscovery()), clientset.AuthorizationV1().SelfSubjectAccessReviews(), permissions(rbacRegistrations, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// list and watch of the 4 resources, and the lookups of the Node handler
+	// not covered by the Pod handler: get services and list jobs
+	if len(checks) != 10 {
+		t.Fatalf("expected 10 checks, got %d: %+v", len(checks), checks)
+	}
+	for _, check := range checks {
+		switch {
+		case check.GVR.Resource == "things" || check.GVR.Resource == "jobs":
+			if check.Served {
+				t.Errorf("expected %s not to be served", check.GVR.Resource)
+			}
+		case check.Kind == "Node" && check.Verb == "watch":
+			if check.Allowed || check.Reason == "" {
+				t.Errorf("expected watching nodes to be denied with a reason, got %+v", check)
+			}
+		default:
+			if !check.Served || !check.Allowed {
+				t.Errorf("expected %s %s to be allowed, got %+v", check.Verb, check.Kind, check)
+			}
+		}
+	}
+}
+
+func TestWriteClusterRole(t *testing.T) {
+	var b strings.Builder
+	if err := WriteClusterRole(&b, "kubegraph", rbacRegistrations, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `# Minimal ClusterRole for the handlers enabled in kubegraph
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: kubegraph
+rules:
+  - apiGroups: [""]
+    resources: ["services"]
+    verbs: ["get"]
+  - apiGroups: [""]
+    resources: ["nodes", "pods"]
+    verbs: ["list", "watch"]
+  - apiGroups: ["batch"]
+    resources: ["jobs"]
+    verbs: ["list"]
+  - apiGroups: ["example.com"]
+    resources: ["clusterwidgets"]
+    verbs: ["list", "watch"]
+  - apiGroups: ["missing.io"]
+    resources: ["things"]
+    verbs: ["list", "watch"]
+`
+	if b.String() != expected {
+		t.Errorf("unexpected ClusterRole:\n%s", b.String())
+	}
+}
+
+func TestWriteClusterRoleWithTokenReview(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.HTTP.Auth.TokenReview = true
+	rule := `  - apiGroups: ["authentication.k8s.io"]
+    resources: ["tokenreviews"]
+    verbs: ["create"]
+`
+	for _, enabled := range []bool{false, true} {
+		cfg.HTTP.Enabled = enabled
+		var b strings.Builder
+		if err := WriteClusterRole(&b, "kubegraph", rbacRegistrations, cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(b.String(), rule) != enabled {
+			t.Errorf("expected the tokenreviews rule only with the HTTP server enabled (enabled %v):\n%s", enabled, b.String())
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Types of recorded watch events, as in the Kubernetes watch API
+const (
+	EventAdded    = "ADDED"
+	EventModified = "MODIFIED"
+	EventDeleted  = "DELETED"
+)
+
+var recordedEventsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_recorded_events_total",
+		Help: "Total number of watch events recorded to the event recording file",
+	},
+	[]string{"kind", "type"},
+)
+
+// RecordedEvent is a watch event as received by an informer, one JSON line of
+// an event recording
+type RecordedEvent struct {
+	Time   time.Time              `json:"time"`
+	Type   string                 `json:"type"`
+	Kind   string                 `json:"kind"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// EventRecorder appends the watch events received by the informers to a
+// file, before namespace filters and change detection, so they can be
+// replayed through the handlers
+type EventRecorder struct {
+	mu  sync.Mutex
+	out *os.File
+}
+
+// NewEventRecorder creates a recorder appending to path, or nil if path is empty
+func NewEventRecorder(path string) (*EventRecorder, error) {
+	if path == "" {
+		return nil, nil
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event recording: %w", err)
+	}
+	logger.Info("Recording watch events to %s", path)
+	return &EventRecorder{out: out}, nil
+}
+
+// Record appends an event of kind for obj
+func (r *EventRecorder) Record(eventType, kind string, obj interface{}) {
+	if r == nil {
+		return
+	}
+	object, err := recordedObject(kind, obj)
+	if err != nil {
+		logger.Warn("Failed to record %s event of %s: %v", eventType, kind, err)
+		return
+	}
+	line, err := json.Marshal(RecordedEvent{Time: time.Now().UTC(), Type: eventType, Kind: kind, Object: object})
+	if err != nil {
+		logger.Warn("Failed to record %s event of %s: %v", eventType, kind, err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.out.Write(append(line, '\n')); err != nil {
+		logger.Warn("Failed to write event recording: %v", err)
+		return
+	}
+	recordedEventsTotal.WithLabelValues(kind, eventType).Inc()
+}
+
+// Close closes the recording file
+func (r *EventRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.out.Close()
+}
+
+// recordedObject returns obj as a map. The values of Secrets are redacted, so
+// recordings can be shared in bug reports.
+func recordedObject(kind string, obj interface{}) (map[string]interface{}, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	var object map[string]interface{}
+	switch o := obj.(type) {
+	case *unstructured.Unstructured:
+		object = o.DeepCopy().Object
+	case runtime.Object:
+		converted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+		if err != nil {
+			return nil, err
+		}
+		object = converted
+	default:
+		return nil, fmt.Errorf("unsupported object %T", obj)
+	}
+
+	if kind == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			if values, ok := object[field].(map[string]interface{}); ok {
+				for key := range values {
+					values[key] = ""
+				}
+			}
+		}
+	}
+	return object, nil
+}
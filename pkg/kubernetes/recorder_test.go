@@ -0,0 +1,71 @@
+package kubernetes
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-graph/pkg/logger"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestEventRecorder(t *testing.T) {
+	logger.Init(logger.ERROR)
+	if r, err := NewEventRecorder(""); r != nil || err != nil {
+		t.Fatalf("Expected no recorder without a path, got %v (%v)", r, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	r, err := NewEventRecorder(path)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	secret := newObject("Secret", "default", "db")
+	secret.Object["data"] = map[string]interface{}{"password": "c2VjcmV0"}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}}
+
+	r.Record(EventAdded, "Secret", secret)
+	r.Record(EventModified, "Pod", pod)
+	r.Record(EventDeleted, "Pod", cache.DeletedFinalStateUnknown{Key: "default/api", Obj: pod})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Failed to close recorder: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open recording: %v", err)
+	}
+	defer file.Close()
+	var events []RecordedEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid recorded event: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if data := events[0].Object["data"].(map[string]interface{}); data["password"] != "" {
+		t.Errorf("Expected the secret value to be redacted, got %v", data["password"])
+	}
+	if secret.Object["data"].(map[string]interface{})["password"] != "c2VjcmV0" {
+		t.Error("Expected the informer's object to be left unchanged")
+	}
+	for i, expected := range []string{EventAdded, EventModified, EventDeleted} {
+		if events[i].Type != expected {
+			t.Errorf("Event %d: expected %s, got %s", i, expected, events[i].Type)
+		}
+	}
+	if metadata := events[2].Object["metadata"].(map[string]interface{}); metadata["name"] != "api" {
+		t.Errorf("Expected the tombstone's object to be recorded, got %v", metadata)
+	}
+}
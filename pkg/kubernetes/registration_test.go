@@ -0,0 +1,38 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes/registry"
+)
+
+// TestRegistrations checks that every handler reports the kind and resource
+// it is registered with, which the informers and the namespaced lookup use
+func TestRegistrations(t *testing.T) {
+	cfg := config.NewConfig()
+	for _, registration := range registry.All() {
+		handler := registration.New(nil, cfg)
+		if handler.GetKind() != registration.Kind {
+			t.Errorf("%s handler reports kind %s", registration.Kind, handler.GetKind())
+		}
+		if handler.GetGVR() != registration.GVR {
+			t.Errorf("%s handler watches %v, registered as %v", registration.Kind, handler.GetGVR(), registration.GVR)
+		}
+	}
+}
+
+func TestIsNamespacedResource(t *testing.T) {
+	c := &Client{}
+	pods, _ := registry.Lookup("Pod")
+	nodes, _ := registry.Lookup("Node")
+	if !c.isNamespacedResource(pods.GVR) {
+		t.Error("expected pods to be namespaced")
+	}
+	if c.isNamespacedResource(nodes.GVR) {
+		t.Error("expected nodes to be cluster-scoped")
+	}
+	if !c.isNamespacedResource(registry.Registration{}.GVR) {
+		t.Error("expected resources without a handler to be assumed namespaced")
+	}
+}
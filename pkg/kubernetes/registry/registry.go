@@ -0,0 +1,133 @@
+// Package registry holds the resource handlers, which register themselves with
+// the metadata of the kind they watch. The agent, the ingest receiver, the
+// replayer, the CLI and /info all read the handlers from here, so adding a
+// handler is a matter of registering it from its own file.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Handler defines the interface for handling Kubernetes resources
+type Handler interface {
+	// GetGVR returns the GroupVersionResource for this handler
+	GetGVR() schema.GroupVersionResource
+	// GetKind returns the kind of resource this handler manages
+	GetKind() string
+	// HandleCreate handles creation/update events
+	HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error
+	// HandleDelete handles deletion events
+	HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error
+}
+
+// Registration describes a handler and how to create it
+type Registration struct {
+	Kind string
+	GVR  schema.GroupVersionResource
+	// Namespaced is false for cluster-scoped kinds
+	Namespaced bool
+	// RequiresClientset is true for handlers that query the API server while
+	// handling objects. They are given a nil clientset when objects do not
+	// come from the local cluster and skip those lookups.
+	RequiresClientset bool
+	// Enabled reports whether the handler is created for the configuration,
+	// nil for handlers that always are
+	Enabled func(cfg *config.Config) bool
+	// New creates the handler, the clientset may be nil
+	New func(clientset *kubernetes.Clientset, cfg *config.Config) Handler
+}
+
+var (
+	mu            sync.RWMutex
+	registrations = make(map[string]Registration)
+)
+
+// Register registers a handler. It is called from the init function of the
+// handler's file and panics when the registration is incomplete or the kind
+// is registered twice.
+func Register(registration Registration) {
+	if registration.Kind == "" || registration.GVR.Resource == "" || registration.New == nil {
+		panic(fmt.Sprintf("registry: incomplete registration of %q", registration.Kind))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := registrations[registration.Kind]; exists {
+		panic(fmt.Sprintf("registry: %s is registered twice", registration.Kind))
+	}
+	registrations[registration.Kind] = registration
+}
+
+// All returns the registrations sorted by kind
+func All() []Registration {
+	mu.RLock()
+	defer mu.RUnlock()
+	all := make([]Registration, 0, len(registrations))
+	for _, registration := range registrations {
+		all = append(all, registration)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Kind < all[j].Kind })
+	return all
+}
+
+// Lookup returns the registration of a kind
+func Lookup(kind string) (Registration, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	registration, ok := registrations[kind]
+	return registration, ok
+}
+
+// ForGVR returns the registration of the handler watching a resource. Istio
+// and Gateway API Gateways share a resource name, so the group and version
+// are compared too.
+func ForGVR(gvr schema.GroupVersionResource) (Registration, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, registration := range registrations {
+		if registration.GVR == gvr {
+			return registration, true
+		}
+	}
+	return Registration{}, false
+}
+
+// Enabled returns the registrations of the handlers the configuration enables,
+// without the kinds disabled by Handlers.Disabled, sorted by kind
+func Enabled(cfg *config.Config) []Registration {
+	disabled := make(map[string]bool, len(cfg.Handlers.Disabled))
+	for _, kind := range cfg.Handlers.Disabled {
+		disabled[kind] = true
+	}
+	all := All()
+	enabled := make([]Registration, 0, len(all))
+	for _, registration := range all {
+		if disabled[registration.Kind] || (registration.Enabled != nil && !registration.Enabled(cfg)) {
+			continue
+		}
+		enabled = append(enabled, registration)
+	}
+	return enabled
+}
+
+// Handlers creates the handlers enabled for the configuration, sorted by kind.
+// The clientset may be nil when objects do not come from the local cluster.
+func Handlers(clientset *kubernetes.Clientset, cfg *config.Config) []Handler {
+	all := All()
+	handlers := make([]Handler, 0, len(all))
+	for _, registration := range all {
+		if registration.Enabled != nil && !registration.Enabled(cfg) {
+			continue
+		}
+		handlers = append(handlers, registration.New(clientset, cfg))
+	}
+	return handlers
+}
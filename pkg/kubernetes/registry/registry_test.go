@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+type stubHandler struct {
+	kind string
+}
+
+func (h stubHandler) GetGVR() schema.GroupVersionResource { return schema.GroupVersionResource{} }
+func (h stubHandler) GetKind() string                     { return h.kind }
+func (h stubHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return nil
+}
+func (h stubHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return nil
+}
+
+func stubRegistration(kind, resource string) Registration {
+	return Registration{
+		Kind: kind,
+		GVR:  schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: resource},
+		New: func(clientset *kubernetes.Clientset, cfg *config.Config) Handler {
+			return stubHandler{kind: kind}
+		},
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Register(stubRegistration("Widget", "widgets"))
+	cluster := stubRegistration("Gadget", "gadgets")
+	cluster.Enabled = func(cfg *config.Config) bool { return cfg.EventTTLDays > 0 }
+	Register(cluster)
+
+	if registration, ok := Lookup("Widget"); !ok || registration.GVR.Resource != "widgets" {
+		t.Errorf("Lookup(Widget) = %+v, %t", registration, ok)
+	}
+	if registration, ok := ForGVR(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "gadgets"}); !ok || registration.Kind != "Gadget" {
+		t.Errorf("ForGVR(gadgets) = %+v, %t", registration, ok)
+	}
+	if _, ok := ForGVR(schema.GroupVersionResource{Version: "v1", Resource: "gadgets"}); ok {
+		t.Error("expected no registration for gadgets of the core group")
+	}
+
+	all := All()
+	if len(all) != 2 || all[0].Kind != "Gadget" || all[1].Kind != "Widget" {
+		t.Errorf("expected Gadget and Widget sorted by kind, got %+v", all)
+	}
+
+	cfg := config.NewConfig()
+	cfg.EventTTLDays = 0
+	if handlers := Handlers(nil, cfg); len(handlers) != 1 || handlers[0].GetKind() != "Widget" {
+		t.Errorf("expected only Widget to be enabled, got %v", handlers)
+	}
+	cfg.EventTTLDays = 7
+	if handlers := Handlers(nil, cfg); len(handlers) != 2 {
+		t.Errorf("expected both handlers to be enabled, got %v", handlers)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	Register(stubRegistration("Gizmo", "gizmos"))
+	for name, registration := range map[string]Registration{
+		"duplicate":   stubRegistration("Gizmo", "gizmos"),
+		"no kind":     stubRegistration("", "things"),
+		"no resource": stubRegistration("Thing", ""),
+		"no New":      {Kind: "Thing", GVR: schema.GroupVersionResource{Version: "v1", Resource: "things"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected Register to panic")
+				}
+			}()
+			Register(registration)
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	Register(stubRegistration("Sprocket", "sprockets"))
+	cfg := config.NewConfig()
+	cfg.Handlers.Disabled = []string{"Sprocket"}
+	for _, registration := range Enabled(cfg) {
+		if registration.Kind == "Sprocket" {
+			t.Error("expected the disabled Sprocket not to be enabled")
+		}
+	}
+	cfg.Handlers.Disabled = nil
+	if _, ok := find(Enabled(cfg), "Sprocket"); !ok {
+		t.Error("expected Sprocket to be enabled")
+	}
+}
+
+func find(registrations []Registration, kind string) (Registration, bool) {
+	for _, registration := range registrations {
+		if registration.Kind == kind {
+			return registration, true
+		}
+	}
+	return Registration{}, false
+}
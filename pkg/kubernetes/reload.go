@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/logger"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// knownKinds returns the set of kinds that have a handler, warning about the
+// unknown ones
+func (c *Client) knownKinds(kinds []string) map[string]bool {
+	known := make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		if _, ok := c.handlers[kind]; !ok {
+			logger.Warn("Unknown handler %s in disabled handlers, expected a kind such as Pod or Deployment", kind)
+			continue
+		}
+		known[kind] = true
+	}
+	return known
+}
+
+// isDisabled reports whether the handler of kind is disabled
+func (c *Client) isDisabled(kind string) bool {
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+	return c.disabled[kind]
+}
+
+// handler returns the handler of kind if it exists and is enabled
+func (c *Client) handler(kind string) (handlers.ResourceHandler, error) {
+	h, ok := c.handlers[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown handler %s", kind)
+	}
+	if c.isDisabled(kind) {
+		return nil, fmt.Errorf("handler %s is disabled", kind)
+	}
+	return h, nil
+}
+
+// SetDisabledHandlers changes the disabled handlers at runtime. Newly disabled
+// handlers are paused; their informers keep running but events are dropped.
+// Enabled handlers are resumed and reconciled, or start watching if they were
+// disabled at startup.
+func (c *Client) SetDisabledHandlers(ctx context.Context, kinds []string) error {
+	disabled := c.knownKinds(kinds)
+
+	c.handlersMu.Lock()
+	previous := c.disabled
+	c.disabled = disabled
+	c.handlersMu.Unlock()
+
+	var errs []error
+	for kind := range disabled {
+		if previous[kind] {
+			continue
+		}
+		if _, err := c.gate.Pause(ctx, kind); err != nil {
+			errs = append(errs, fmt.Errorf("handler %s disabled but in-flight events did not drain: %w", kind, err))
+		}
+		logger.Info("[RELOAD] Handler for %s disabled", kind)
+	}
+	for kind := range previous {
+		if disabled[kind] {
+			continue
+		}
+		logger.Info("[RELOAD] Handler for %s enabled", kind)
+		resumed := c.gate.Resume(kind)
+		if c.watchCtx == nil {
+			// StartWatching has not run yet and picks the handler up
+			continue
+		}
+		c.informersMu.RLock()
+		_, watched := c.informers[kind]
+		c.informersMu.RUnlock()
+		if !watched {
+			go c.startHandler(c.watchCtx, c.handlers[kind])
+		} else if resumed && c.neo4jClient != nil {
+			go c.reconcile(c.watchCtx, c.handlers[kind])
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startHandler starts watching a handler enabled after startup. The informer
+// delivers the existing objects as Add events once started.
+func (c *Client) startHandler(ctx context.Context, h handlers.ResourceHandler) {
+	informer := c.watchHandler(ctx, h)
+	if informer == nil {
+		return
+	}
+	// Start only starts the informers that are not running yet
+	c.informerFactory.Start(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		logger.Error("[RELOAD] Failed to sync cache for %s", h.GetKind())
+		return
+	}
+	logger.Info("[RELOAD] Watching %s", h.GetKind())
+}
+
+// SetNamespaceFilter replaces the namespace filter at runtime. The watched
+// handlers are reconciled in the background, writing the objects of newly
+// included namespaces and removing the nodes of newly filtered ones.
+func (c *Client) SetNamespaceFilter(include, exclude []string) {
+	c.namespaces.Store(NewNamespaceFilter(include, exclude))
+	logger.Info("[RELOAD] Namespace filter changed (namespaces: %v, excluded: %v)", include, exclude)
+	if c.watchCtx == nil || c.neo4jClient == nil {
+		return
+	}
+
+	c.informersMu.RLock()
+	kinds := make([]string, 0, len(c.informers))
+	for kind := range c.informers {
+		kinds = append(kinds, kind)
+	}
+	c.informersMu.RUnlock()
+
+	go func() {
+		for _, kind := range kinds {
+			if h, err := c.handler(kind); err == nil {
+				c.reconcile(c.watchCtx, h)
+			}
+		}
+	}()
+}
@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/logger"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+)
+
+type stubHandler struct {
+	kind string
+}
+
+func (h stubHandler) GetGVR() schema.GroupVersionResource { return schema.GroupVersionResource{} }
+func (h stubHandler) GetKind() string                     { return h.kind }
+func (h stubHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return nil
+}
+func (h stubHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	return nil
+}
+
+func newReloadClient(disabled ...string) *Client {
+	c := &Client{
+		handlers:    make(map[string]handlers.ResourceHandler),
+		gate:        NewHandlerGate(),
+		informers:   make(map[string]cache.SharedInformer),
+		initialSync: NewInitialSyncTracker(),
+	}
+	for _, kind := range []string{"Pod", "Secret", "Event"} {
+		c.handlers[kind] = stubHandler{kind: kind}
+	}
+	c.disabled = c.knownKinds(disabled)
+	return c
+}
+
+func TestSetDisabledHandlers(t *testing.T) {
+	logger.Init(logger.ERROR)
+	c := newReloadClient("Event")
+
+	if err := c.SetDisabledHandlers(context.Background(), []string{"Secret", "Unknown"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	enabled := c.GetHandlers()
+	if _, ok := enabled["Secret"]; ok {
+		t.Error("expected Secret to be disabled")
+	}
+	if _, ok := enabled["Event"]; !ok {
+		t.Error("expected Event to be enabled")
+	}
+	if len(enabled) != 2 {
+		t.Errorf("expected 2 enabled handlers, got %d", len(enabled))
+	}
+	if c.gate.Enter("Secret") {
+		t.Error("expected events of the disabled Secret handler to be dropped")
+	}
+	if err := c.PauseHandler(context.Background(), "Secret"); err == nil {
+		t.Error("expected pausing a disabled handler to fail")
+	}
+
+	// Enabling Secret again resumes it
+	if err := c.SetDisabledHandlers(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.gate.Enter("Secret") {
+		t.Error("expected events of the enabled Secret handler to be processed")
+	}
+	c.gate.Leave("Secret")
+}
+
+func TestSetNamespaceFilter(t *testing.T) {
+	logger.Init(logger.ERROR)
+	c := newReloadClient()
+	c.namespaces.Store(NewNamespaceFilter(nil, []string{"kube-system"}))
+
+	c.SetNamespaceFilter([]string{"payments"}, nil)
+
+	if c.namespaces.Load().Allows(newObject("Pod", "default", "web")) {
+		t.Error("expected default to be filtered after the change")
+	}
+	if !c.namespaces.Load().Allows(newObject("Pod", "payments", "api")) {
+		t.Error("expected payments to be included after the change")
+	}
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s-graph/pkg/kubernetes/handlers"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// startupTierTimeout bounds how long a tier waits for the initial sync of the
+// tiers before it. The order only avoids relationship warnings, so a slow tier
+// does not hold up the others for longer.
+const startupTierTimeout = 2 * time.Minute
+
+// startupTiers orders the kinds whose nodes others point at before them, so
+// that on a cold start relationships find the nodes they link to. Kinds not
+// listed start in the last tier.
+var startupTiers = map[string]int{
+	// Cluster-scoped nodes most resources link to
+	"Namespace":        0,
+	"Node":             0,
+	"StorageClass":     0,
+	"PersistentVolume": 0,
+	"IngressClass":     0,
+	"GatewayClass":     0,
+	"ClusterRole":      0,
+	// Namespaced resources referenced by workloads and bindings
+	"ServiceAccount":         1,
+	"ConfigMap":              1,
+	"Secret":                 1,
+	"PersistentVolumeClaim":  1,
+	"Role":                   1,
+	"LimitRange":             1,
+	"HierarchyConfiguration": 1,
+	// Workload controllers, then the objects they own, then pods
+	"Deployment":  2,
+	"StatefulSet": 2,
+	"DaemonSet":   2,
+	"CronJob":     2,
+	"ReplicaSet":  3,
+	"Job":         3,
+	"Pod":         4,
+}
+
+// lastStartupTier is the tier of the kinds that are not ordered
+const lastStartupTier = 5
+
+// orderHandlers groups handlers into startup tiers, in the order they start.
+// Without ordering all handlers start at once.
+func orderHandlers(hs []handlers.ResourceHandler, ordered bool) [][]handlers.ResourceHandler {
+	if !ordered {
+		return [][]handlers.ResourceHandler{hs}
+	}
+
+	byTier := make(map[int][]handlers.ResourceHandler)
+	for _, h := range hs {
+		tier, ok := startupTiers[h.GetKind()]
+		if !ok {
+			tier = lastStartupTier
+		}
+		byTier[tier] = append(byTier[tier], h)
+	}
+
+	tiers := make([][]handlers.ResourceHandler, 0, len(byTier))
+	for tier := 0; tier <= lastStartupTier; tier++ {
+		if len(byTier[tier]) > 0 {
+			tiers = append(tiers, byTier[tier])
+		}
+	}
+	return tiers
+}
+
+// waitInitialSync waits until the initial sync of kinds is complete, up to
+// timeout, and returns the kinds still pending
+func (c *Client) waitInitialSync(ctx context.Context, kinds []string, timeout time.Duration) []string {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cache.WaitForCacheSync(waitCtx.Done(), func() bool {
+		for _, kind := range kinds {
+			if !c.initialSync.Complete(kind) {
+				return false
+			}
+		}
+		return true
+	})
+
+	pending := make([]string, 0)
+	for _, kind := range kinds {
+		if !c.initialSync.Complete(kind) {
+			pending = append(pending, kind)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// initialAddLimiter returns the limiter throttling the Add events of a kind
+// during its initial sync, or nil if they are not throttled
+func (c *Client) initialAddLimiter() flowcontrol.RateLimiter {
+	rate := c.config.Sync.InitialAddRate
+	if rate <= 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(rate), rate)
+}
@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s-graph/pkg/kubernetes/handlers"
+)
+
+func TestOrderHandlers(t *testing.T) {
+	hs := []handlers.ResourceHandler{
+		stubHandler{kind: "Service"},
+		stubHandler{kind: "Pod"},
+		stubHandler{kind: "ReplicaSet"},
+		stubHandler{kind: "Namespace"},
+		stubHandler{kind: "Deployment"},
+		stubHandler{kind: "Node"},
+	}
+
+	tiers := orderHandlers(hs, true)
+	expected := [][]string{{"Namespace", "Node"}, {"Deployment"}, {"ReplicaSet"}, {"Pod"}, {"Service"}}
+	if len(tiers) != len(expected) {
+		t.Fatalf("expected %d tiers, got %d", len(expected), len(tiers))
+	}
+	for i, tier := range tiers {
+		kinds := make(map[string]bool)
+		for _, h := range tier {
+			kinds[h.GetKind()] = true
+		}
+		for _, kind := range expected[i] {
+			if !kinds[kind] {
+				t.Errorf("expected %s in tier %d, got %v", kind, i, kinds)
+			}
+		}
+		if len(kinds) != len(expected[i]) {
+			t.Errorf("expected %d kinds in tier %d, got %v", len(expected[i]), i, kinds)
+		}
+	}
+
+	if tiers := orderHandlers(hs, false); len(tiers) != 1 || len(tiers[0]) != len(hs) {
+		t.Errorf("expected a single tier without ordering, got %d", len(tiers))
+	}
+}
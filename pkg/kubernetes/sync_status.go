@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	"math"
+)
+
+// SyncStatus compares the objects of a kind in the informer cache with the
+// nodes written for them by this instance
+type SyncStatus struct {
+	Cached       int     `json:"cached"`
+	Synced       int     `json:"synced"`
+	Completeness float64 `json:"completeness"` // percentage of cached objects present in the graph
+}
+
+// untrackedKinds are not written with the instanceHash of the current run, so
+// their graph nodes cannot be compared with the informer cache
+var untrackedKinds = map[string]bool{
+	"Event": true,
+}
+
+// NewSyncStatus computes the completeness of a kind from its cached and synced counts
+func NewSyncStatus(cached, synced int) SyncStatus {
+	completeness := 100.0
+	if cached > 0 {
+		completeness = math.Min(100, math.Round(float64(synced)*1000/float64(cached))/10)
+	}
+	return SyncStatus{Cached: cached, Synced: synced, Completeness: completeness}
+}
+
+// CachedCounts returns the number of objects in the informer cache of every
+// watched kind. Kinds whose informer was skipped or has not synced yet are
+// omitted.
+func (c *Client) CachedCounts() map[string]int {
+	c.informersMu.RLock()
+	defer c.informersMu.RUnlock()
+
+	counts := make(map[string]int, len(c.informers))
+	for kind, informer := range c.informers {
+		if untrackedKinds[kind] || !informer.HasSynced() {
+			continue
+		}
+		counts[kind] = len(informer.GetStore().ListKeys())
+	}
+	return counts
+}
@@ -0,0 +1,32 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestNewSyncStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		cached   int
+		synced   int
+		expected float64
+	}{
+		{"empty cache is complete", 0, 0, 100},
+		{"caught up", 40, 40, 100},
+		{"partially synced", 3, 1, 33.3},
+		{"more nodes than cached objects", 10, 12, 100},
+		{"nothing synced", 10, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := NewSyncStatus(test.cached, test.synced)
+			if status.Completeness != test.expected {
+				t.Errorf("Expected completeness %v, got %v", test.expected, status.Completeness)
+			}
+			if status.Cached != test.cached || status.Synced != test.synced {
+				t.Errorf("Expected counts %d/%d, got %d/%d", test.synced, test.cached, status.Synced, status.Cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// Markers the vcluster syncer puts on the host objects it creates
+const (
+	vclusterManagedByLabel            = "vcluster.loft.sh/managed-by"
+	vclusterNamespaceLabel            = "vcluster.loft.sh/namespace"
+	vclusterObjectNameAnnotation      = "vcluster.loft.sh/object-name"
+	vclusterObjectNamespaceAnnotation = "vcluster.loft.sh/object-namespace"
+)
+
+// virtualClusterInfo identifies the virtual cluster object a host object was
+// synced from
+type virtualClusterInfo struct {
+	VirtualCluster   string
+	VirtualName      string
+	VirtualNamespace string
+}
+
+// virtualClusterOf returns the virtual cluster an object belongs to, if it
+// was synced to the host cluster by vcluster
+func virtualClusterOf(obj interface{}) (virtualClusterInfo, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return virtualClusterInfo{}, false
+	}
+
+	labels := accessor.GetLabels()
+	annotations := accessor.GetAnnotations()
+	info := virtualClusterInfo{
+		VirtualCluster:   labels[vclusterManagedByLabel],
+		VirtualName:      annotations[vclusterObjectNameAnnotation],
+		VirtualNamespace: annotations[vclusterObjectNamespaceAnnotation],
+	}
+	if info.VirtualNamespace == "" {
+		info.VirtualNamespace = labels[vclusterNamespaceLabel]
+	}
+
+	if info.VirtualCluster == "" && info.VirtualName == "" && info.VirtualNamespace == "" {
+		return virtualClusterInfo{}, false
+	}
+	return info, true
+}
+
+// tagVirtualCluster marks the node of an object synced by vcluster with the
+// virtual cluster and the name it has inside of it
+func tagVirtualCluster(ctx context.Context, kind string, obj interface{}, neo4jClient graph.Store) error {
+	info, ok := virtualClusterOf(obj)
+	if !ok {
+		return nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	return neo4jClient.Write(ctx, graph.Statement{
+		Query: fmt.Sprintf(`
+		MATCH (n:%s {uid: $uid})
+		SET n.virtualCluster = $virtualCluster, n.virtualName = $virtualName, n.virtualNamespace = $virtualNamespace`, kind),
+		Params: map[string]interface{}{
+			"uid":              string(accessor.GetUID()),
+			"virtualCluster":   info.VirtualCluster,
+			"virtualName":      info.VirtualName,
+			"virtualNamespace": info.VirtualNamespace,
+		},
+	})
+}
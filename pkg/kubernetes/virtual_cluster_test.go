@@ -0,0 +1,34 @@
+package kubernetes
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVirtualClusterOf(t *testing.T) {
+	synced := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web-x-default-x-dev",
+		Namespace: "vcluster-dev",
+		Labels:    map[string]string{"vcluster.loft.sh/managed-by": "dev"},
+		Annotations: map[string]string{
+			"vcluster.loft.sh/object-name":      "web",
+			"vcluster.loft.sh/object-namespace": "default",
+		},
+	}}
+
+	info, ok := virtualClusterOf(synced)
+	if !ok {
+		t.Fatal("Expected synced pod to belong to a virtual cluster")
+	}
+	expected := virtualClusterInfo{VirtualCluster: "dev", VirtualName: "web", VirtualNamespace: "default"}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+
+	host := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if _, ok := virtualClusterOf(host); ok {
+		t.Error("Expected host pod not to belong to a virtual cluster")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync/atomic"
 )
 
 // LogLevel represents the logging level
@@ -18,10 +19,14 @@ const (
 )
 
 var (
-	currentLevel LogLevel = INFO
+	currentLevel atomic.Int32 // LogLevel, changed at runtime by configuration reloads
 	logger       *log.Logger
 )
 
+func init() {
+	currentLevel.Store(int32(INFO))
+}
+
 // String returns the string representation of the log level
 func (l LogLevel) String() string {
 	switch l {
@@ -56,10 +61,15 @@ func ParseLogLevel(level string) LogLevel {
 
 // Init initializes the logger with the specified level
 func Init(level LogLevel) {
-	currentLevel = level
+	SetLevel(level)
 	logger = log.New(os.Stdout, "", log.LstdFlags)
 }
 
+// SetLevel changes the log level of an initialized logger
+func SetLevel(level LogLevel) {
+	currentLevel.Store(int32(level))
+}
+
 // InitFromEnv initializes the logger from environment variable
 func InitFromEnv() {
 	levelStr := os.Getenv("KUBEGRAPH_LOG_LEVEL")
@@ -71,7 +81,7 @@ func InitFromEnv() {
 
 // shouldLog checks if the given level should be logged
 func shouldLog(level LogLevel) bool {
-	return level >= currentLevel
+	return level >= GetLevel()
 }
 
 // logf formats and logs a message if the level is enabled
@@ -104,7 +114,7 @@ func Error(format string, args ...interface{}) {
 
 // GetLevel returns the current log level
 func GetLevel() LogLevel {
-	return currentLevel
+	return LogLevel(currentLevel.Load())
 }
 
 // IsDebugEnabled returns true if debug logging is enabled
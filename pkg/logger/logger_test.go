@@ -206,3 +206,14 @@ func TestShouldLog(t *testing.T) {
 		t.Error("Expected shouldLog(ERROR) to return true when level is ERROR")
 	}
 }
+
+func TestSetLevel(t *testing.T) {
+	Init(INFO)
+	SetLevel(DEBUG)
+	if GetLevel() != DEBUG {
+		t.Errorf("Expected GetLevel() to return DEBUG after SetLevel(DEBUG), got %d", GetLevel())
+	}
+	if !IsDebugEnabled() {
+		t.Error("Expected IsDebugEnabled() to return true after SetLevel(DEBUG)")
+	}
+}
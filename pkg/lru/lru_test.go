@@ -0,0 +1,50 @@
+package lru
+
+import "testing"
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[string, int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+
+	// Touch "a" so "b" becomes the eviction candidate
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Expected a=1, got %d (found %t)", v, ok)
+	}
+
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("Expected a to be retained")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestCacheUpdateAndRemove(t *testing.T) {
+	c := New[string, string](10)
+	c.Add("k", "v1")
+	c.Add("k", "v2")
+	if v, _ := c.Get("k"); v != "v2" {
+		t.Errorf("Expected updated value v2, got %s", v)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", c.Len())
+	}
+
+	c.Remove("k")
+	if _, ok := c.Get("k"); ok {
+		t.Error("Expected k to be removed")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := New[string, int](0)
+	c.Add("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected disabled cache to store nothing")
+	}
+}
@@ -0,0 +1,187 @@
+// Package manifests generates the Kubernetes manifests deploying the sync
+// binary with the settings of the current run.
+package manifests
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/kubernetes/registry"
+)
+
+// Options parameterize the generated manifests
+type Options struct {
+	// Name of every generated object
+	Name      string
+	Namespace string
+	Image     string
+	// Args are the command-line flags of the container
+	Args          []string
+	Neo4jUsername string
+	Neo4jPassword string
+	// HTTPPort is the port of the status server, 0 when it is disabled and
+	// no Service or ServiceMonitor is generated
+	HTTPPort int
+	// Registrations are the enabled handlers, whose permissions the
+	// ClusterRole grants
+	Registrations []registry.Registration
+	// Config holds the settings of the flags passed on, which may need
+	// permissions besides those of the handlers, e.g. --http-token-review
+	Config *config.Config
+}
+
+// credentialsPath is where the Neo4j credentials Secret is mounted, so that
+// rotated credentials are picked up without a restart
+const credentialsPath = "/etc/kubegraph/neo4j-credentials"
+
+var manifests = template.Must(template.New("manifests").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{ .Namespace }}
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+---
+{{ .ClusterRole }}---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: {{ .Name }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: {{ .Name }}
+subjects:
+  - kind: ServiceAccount
+    name: {{ .Name }}
+    namespace: {{ .Namespace }}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Name }}-neo4j
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+type: Opaque
+stringData:
+  username: {{ quote .Neo4jUsername }}
+  password: {{ quote .Neo4jPassword }}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{ .Name }}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{ .Name }}
+    spec:
+      serviceAccountName: {{ .Name }}
+      containers:
+        - name: kubegraph
+          image: {{ quote .Image }}
+          args:
+{{- range .Args }}
+            - {{ quote . }}
+{{- end }}
+          env:
+            - name: NEO4J_USERNAME_FILE
+              value: {{ .CredentialsPath }}/username
+            - name: NEO4J_PASSWORD_FILE
+              value: {{ .CredentialsPath }}/password
+{{- if .HTTPPort }}
+          ports:
+            - name: http
+              containerPort: {{ .HTTPPort }}
+          readinessProbe:
+            httpGet:
+              path: /readyz
+              port: http
+{{- end }}
+          resources:
+            requests:
+              cpu: 100m
+              memory: 128Mi
+            limits:
+              cpu: 500m
+              memory: 512Mi
+          volumeMounts:
+            - name: neo4j-credentials
+              mountPath: {{ .CredentialsPath }}
+              readOnly: true
+      volumes:
+        - name: neo4j-credentials
+          secret:
+            secretName: {{ .Name }}-neo4j
+{{- if .HTTPPort }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+spec:
+  selector:
+    app.kubernetes.io/name: {{ .Name }}
+  ports:
+    - name: http
+      port: {{ .HTTPPort }}
+      targetPort: http
+---
+# Needs the Prometheus Operator; leave it out of clusters without it
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Name }}
+spec:
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{ .Name }}
+  endpoints:
+    - port: http
+      path: /metrics
+{{- end }}
+`))
+
+// Write writes the manifests as a multi-document YAML stream
+func Write(w io.Writer, opts Options) error {
+	var clusterRole strings.Builder
+	if err := kubernetes.WriteClusterRole(&clusterRole, opts.Name, opts.Registrations, opts.Config); err != nil {
+		return err
+	}
+	data := struct {
+		Options
+		ClusterRole     string
+		CredentialsPath string
+	}{opts, clusterRole.String(), credentialsPath}
+	if err := manifests.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to generate the manifests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package manifests
+
+import (
+	"strings"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes/registry"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func testOptions() Options {
+	return Options{
+		Name:          "kubegraph",
+		Namespace:     "graph",
+		Image:         "dcarias/kubegraph:1.0.0",
+		Args:          []string{"--cluster-name=prod", "--namespaces=a,b"},
+		Neo4jUsername: "neo4j",
+		Neo4jPassword: `p"ss`,
+		HTTPPort:      8080,
+		Registrations: []registry.Registration{
+			{Kind: "Pod", GVR: schema.GroupVersionResource{Version: "v1", Resource: "pods"}},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b strings.Builder
+	if err := Write(&b, testOptions()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	var kinds []string
+	for _, document := range strings.Split(out, "---\n") {
+		for _, line := range strings.Split(document, "\n") {
+			if strings.HasPrefix(line, "kind: ") {
+				kinds = append(kinds, strings.TrimPrefix(line, "kind: "))
+				break
+			}
+		}
+	}
+	want := "Namespace ServiceAccount ClusterRole ClusterRoleBinding Secret Deployment Service ServiceMonitor"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Errorf("kinds = %s, want %s", got, want)
+	}
+	for _, expected := range []string{
+		"  namespace: graph\n",
+		`    resources: ["pods"]` + "\n",
+		`  password: "p\"ss"` + "\n",
+		`          image: "dcarias/kubegraph:1.0.0"` + "\n",
+		`            - "--namespaces=a,b"` + "\n",
+		"              value: /etc/kubegraph/neo4j-credentials/password\n",
+		"              containerPort: 8080\n",
+		"          readinessProbe:\n            httpGet:\n              path: /readyz\n              port: http\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("manifests do not contain %q", expected)
+		}
+	}
+}
+
+func TestWriteWithoutHTTP(t *testing.T) {
+	opts := testOptions()
+	opts.HTTPPort = 0
+	var b strings.Builder
+	if err := Write(&b, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, unexpected := range []string{"kind: Service\n", "kind: ServiceMonitor\n", "readinessProbe"} {
+		if strings.Contains(b.String(), unexpected) {
+			t.Errorf("manifests contain %q without the HTTP server", unexpected)
+		}
+	}
+}
+
+func TestWriteWithTokenReview(t *testing.T) {
+	opts := testOptions()
+	opts.Args = append(opts.Args, "--http-token-review=true")
+	opts.Config = config.NewConfig()
+	opts.Config.HTTP.Enabled = true
+	opts.Config.HTTP.Auth.TokenReview = true
+	var b strings.Builder
+	if err := Write(&b, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `    resources: ["tokenreviews"]`+"\n") {
+		t.Errorf("ClusterRole does not grant creating tokenreviews:\n%s", b.String())
+	}
+}
@@ -0,0 +1,95 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Operations recorded in the audit trail
+const (
+	OperationUpsert = "upsert"
+	OperationDelete = "delete"
+)
+
+// auditEnabled reports whether graph mutations should be recorded as GraphChange nodes
+func (c *Client) auditEnabled() bool {
+	return c.config != nil && c.config.Audit.Enabled
+}
+
+// RecordChange publishes a mutation of a resource node on the change feed and
+// appends a GraphChange node describing it. Failures are logged and never fail
+// the mutation itself.
+func (c *Client) RecordChange(ctx context.Context, kind, uid, operation string) {
+	c.recordChange(ctx, kind, uid, "", "", operation)
+}
+
+// recordChange publishes a mutation on the change feed and appends a
+// GraphChange node, including the resource's name and namespace when they are known
+func (c *Client) recordChange(ctx context.Context, kind, uid, name, namespace, operation string) {
+	c.publishNode(kind, uid, name, namespace, operation)
+	if !c.auditEnabled() {
+		return
+	}
+
+	err := c.executeWithMetrics(ctx, "record_change", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		query := `CREATE (c:GraphChange) SET c = $change`
+		params := map[string]interface{}{
+			"change": map[string]interface{}{
+				"kind":         kind,
+				"uid":          uid,
+				"name":         name,
+				"namespace":    namespace,
+				"operation":    operation,
+				"timestamp":    time.Now().UTC().Format(time.RFC3339),
+				"clusterName":  c.config.Kubernetes.ClusterName,
+				"instanceHash": c.config.InstanceHash,
+			},
+		}
+
+		_, err := session.Run(ctx, query, params)
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to record %s of %s %s in audit trail: %v", operation, kind, uid, err)
+	}
+}
+
+// PruneGraphChanges deletes GraphChange nodes older than the given TTL (in days)
+func (c *Client) PruneGraphChanges(ctx context.Context, ttlDays int) error {
+	if ttlDays <= 0 {
+		return nil
+	}
+
+	return c.executeWithMetrics(ctx, "prune_graph_changes", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		cutoff := time.Now().UTC().Add(-time.Duration(ttlDays) * 24 * time.Hour).Format(time.RFC3339)
+		query := `MATCH (c:GraphChange) WHERE c.timestamp < $cutoff DELETE c RETURN count(c)`
+
+		result, err := session.Run(ctx, query, map[string]interface{}{"cutoff": cutoff})
+		if err != nil {
+			return fmt.Errorf("failed to prune graph changes: %w", err)
+		}
+		record, err := result.Single(ctx)
+		if errors.Is(err, ErrDryRun) {
+			// The dry run skipped the statement, so nothing was pruned
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune graph changes: %w", err)
+		}
+		pruned, _ := record.Values[0].(int64)
+		RecordPruned("GraphChange", "ttl", pruned)
+		return nil
+	})
+}
@@ -0,0 +1,150 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// relationshipGroup holds the relationships sharing labels, keys and type,
+// which are created with a single statement
+type relationshipGroup struct {
+	fromLabel, fromKey, relationshipType, toLabel, toKey string
+	relationships                                        []graph.Relationship
+}
+
+// groupRelationships groups relationships by their labels, keys and type in
+// the order they first appear, dropping duplicates
+func groupRelationships(relationships []graph.Relationship) []*relationshipGroup {
+	var groups []*relationshipGroup
+	byPattern := make(map[string]*relationshipGroup)
+	seen := make(map[graph.Relationship]bool, len(relationships))
+	for _, r := range relationships {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		pattern := fmt.Sprintf("%s/%s/%s/%s/%s", r.FromLabel, r.FromKey, r.Type, r.ToLabel, r.ToKey)
+		group, ok := byPattern[pattern]
+		if !ok {
+			group = &relationshipGroup{fromLabel: r.FromLabel, fromKey: r.FromKey, relationshipType: r.Type, toLabel: r.ToLabel, toKey: r.ToKey}
+			byPattern[pattern] = group
+			groups = append(groups, group)
+		}
+		group.relationships = append(group.relationships, r)
+	}
+	return groups
+}
+
+// query returns the statement creating the relationships of the group and
+// returning the ends of those whose nodes both exist, and whether the
+// relationship did not exist before
+func (g *relationshipGroup) query() string {
+	return fmt.Sprintf(`
+		UNWIND $relationships AS rel
+		MATCH (from:%s {%s: rel.from})
+		MATCH (to:%s {%s: rel.to})
+		OPTIONAL MATCH (from)-[existing:%[5]s]->(to)
+		WITH rel, from, to, existing IS NULL AS created
+		MERGE (from)-[:%[5]s]->(to)
+		RETURN DISTINCT rel.from AS from, rel.to AS to, created`, g.fromLabel, g.fromKey, g.toLabel, g.toKey, g.relationshipType)
+}
+
+func (g *relationshipGroup) params() map[string]interface{} {
+	rows := make([]map[string]interface{}, len(g.relationships))
+	for i, r := range g.relationships {
+		rows[i] = map[string]interface{}{"from": r.FromValue, "to": r.ToValue}
+	}
+	return map[string]interface{}{"relationships": rows}
+}
+
+// serializedBatchKey picks the node whose worker runs a batch: the node itself
+// if its label is serialized, or else the first serialized end of a relationship
+func (c *Client) serializedBatchKey(label string, properties map[string]interface{}, uniqueKey string, relationships []graph.Relationship) (string, interface{}) {
+	if c.writes.serializes(label) {
+		return label, serializedNodeKey(properties, uniqueKey)
+	}
+	for _, r := range relationships {
+		if endLabel, endValue := c.writes.serializedEndpoint(r.FromLabel, r.FromValue, r.ToLabel, r.ToValue); c.writes.serializes(endLabel) {
+			return endLabel, endValue
+		}
+	}
+	return label, properties[uniqueKey]
+}
+
+// UpsertNodeWithRelationships creates or updates a node and creates its
+// relationships in a single transaction, instead of a round-trip per write.
+// When the properties are identical to the last write of the node only the
+// relationships are written. Relationships whose nodes do not both exist are
+// deferred like with CreateRelationship.
+func (c *Client) UpsertNodeWithRelationships(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string, relationships []graph.Relationship) error {
+	properties, enrichments := c.enrich(ctx, labels, properties)
+	convertedProperties := convertMapPropertiesToJSON(properties)
+	key := nodeKey(labels, properties[uniqueKey])
+	hash := hashProperties(convertedProperties)
+	unchanged := c.isUnchanged(key, hash)
+	if unchanged {
+		neo4jUpsertsSkippedTotal.Inc()
+		if len(relationships) == 0 {
+			return nil
+		}
+	}
+
+	groups := groupRelationships(relationships)
+	matched := make(map[graph.Relationship]bool, len(relationships))
+	created := make(map[graph.Relationship]bool, len(relationships))
+	label, value := c.serializedBatchKey(labels[0], properties, uniqueKey, relationships)
+	err := c.writes.run(ctx, label, value, func() error {
+		return c.writeTransaction(ctx, "upsert_node_with_relationships", func(tx neo4j.ManagedTransaction) error {
+			// The transaction may be retried, so only its last attempt counts
+			clear(matched)
+			clear(created)
+			if !unchanged {
+				params := map[string]interface{}{
+					uniqueKey:    properties[uniqueKey],
+					"properties": convertedProperties,
+				}
+				if _, err := tx.Run(ctx, buildUpsertQuery(labels, convertedProperties, uniqueKey), params); err != nil {
+					return err
+				}
+			}
+
+			for _, group := range groups {
+				result, err := tx.Run(ctx, group.query(), group.params())
+				if err != nil {
+					return err
+				}
+				for result.Next(ctx) {
+					from, _ := result.Record().Values[0].(string)
+					to, _ := result.Record().Values[1].(string)
+					isNew, _ := result.Record().Values[2].(bool)
+					r := graph.Relationship{FromLabel: group.fromLabel, FromKey: group.fromKey, FromValue: from,
+						Type: group.relationshipType, ToLabel: group.toLabel, ToKey: group.toKey, ToValue: to}
+					matched[r] = true
+					created[r] = created[r] || isNew
+				}
+				if err := result.Err(); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		for _, r := range group.relationships {
+			c.settleRelationship(r, matched[r], created[r])
+		}
+	}
+	if !unchanged {
+		c.rememberHash(key, hash)
+		c.afterUpsert(ctx, labels, properties, convertedProperties, uniqueKey, enrichments)
+	}
+	return nil
+}
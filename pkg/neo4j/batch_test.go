@@ -0,0 +1,32 @@
+package neo4j
+
+import (
+	"testing"
+
+	"k8s-graph/pkg/graph"
+)
+
+func TestGroupRelationships(t *testing.T) {
+	owner := func(kind, uid string) graph.Relationship {
+		return graph.Relationship{FromLabel: "Pod", FromKey: "uid", FromValue: "pod-1",
+			Type: "OWNED_BY", ToLabel: kind, ToKey: "uid", ToValue: uid}
+	}
+	node := graph.Relationship{FromLabel: "Pod", FromKey: "uid", FromValue: "pod-1",
+		Type: "SCHEDULED_ON", ToLabel: "Node", ToKey: "name", ToValue: "node-1"}
+
+	groups := groupRelationships([]graph.Relationship{owner("ReplicaSet", "rs-1"), node, owner("ReplicaSet", "rs-2"), owner("ReplicaSet", "rs-1")})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].toLabel != "ReplicaSet" || len(groups[0].relationships) != 2 {
+		t.Errorf("expected the 2 distinct owners in the first group, got %+v", groups[0])
+	}
+	if groups[1].relationshipType != "SCHEDULED_ON" || len(groups[1].relationships) != 1 {
+		t.Errorf("expected the node relationship in the second group, got %+v", groups[1])
+	}
+
+	rows := groups[0].params()["relationships"].([]map[string]interface{})
+	if len(rows) != 2 || rows[0]["to"] != "rs-1" || rows[1]["to"] != "rs-2" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
@@ -0,0 +1,185 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrCircuitOpen is returned by operations rejected while Neo4j is unreachable
+var ErrCircuitOpen = errors.New("neo4j circuit breaker is open")
+
+var (
+	neo4jBreakerOpen = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "neo4j_circuit_breaker_open",
+			Help: "Whether the Neo4j circuit breaker is open (1) or closed (0)",
+		},
+	)
+
+	neo4jBreakerTripsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "neo4j_circuit_breaker_trips_total",
+			Help: "Total number of times the Neo4j circuit breaker opened",
+		},
+	)
+
+	neo4jBreakerRejectedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "neo4j_circuit_breaker_rejected_total",
+			Help: "Total number of Neo4j operations rejected while the circuit breaker was open",
+		},
+	)
+)
+
+// BreakerState describes the state of the Neo4j circuit breaker
+type BreakerState struct {
+	State               string    `json:"state"` // closed, open or disabled
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenedAt            time.Time `json:"openedAt,omitempty"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+// breaker opens after threshold consecutive connectivity failures. While open,
+// operations are rejected and the connection is probed every interval; the
+// breaker closes as soon as a probe succeeds.
+type breaker struct {
+	threshold int
+	interval  time.Duration
+	probe     func(context.Context) error
+
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	lastError string
+	closed    chan struct{} // closed when the breaker closes, nil while closed
+}
+
+// newBreaker creates a closed breaker (threshold 0 disables it)
+func newBreaker(threshold int, interval time.Duration, probe func(context.Context) error) *breaker {
+	return &breaker{threshold: threshold, interval: interval, probe: probe}
+}
+
+// allow returns ErrCircuitOpen, tagged as a transient Neo4j failure, while
+// the breaker is open
+func (b *breaker) allow() error {
+	if b == nil || b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed != nil {
+		neo4jBreakerRejectedTotal.Inc()
+		return failure.New(failure.Neo4jTransient, ErrCircuitOpen)
+	}
+	return nil
+}
+
+// record counts the outcome of an operation. Only connectivity failures count:
+// a rejected query means Neo4j is up.
+func (b *breaker) record(err error) {
+	if b == nil || b.threshold <= 0 || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil || !neo4j.IsConnectivityError(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	b.lastError = err.Error()
+	if b.failures < b.threshold || b.closed != nil {
+		return
+	}
+
+	b.openedAt = time.Now()
+	b.closed = make(chan struct{})
+	neo4jBreakerOpen.Set(1)
+	neo4jBreakerTripsTotal.Inc()
+	logger.Error("[NEO4J] Circuit breaker opened after %d consecutive connectivity failures: %s", b.failures, b.lastError)
+	go b.probeUntilClosed()
+}
+
+// probeUntilClosed probes the connection every interval until a probe succeeds
+func (b *breaker) probeUntilClosed() {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), b.interval)
+		err := b.probe(ctx)
+		cancel()
+		if err != nil {
+			logger.Debug("[NEO4J] Health probe failed, circuit breaker stays open: %v", err)
+			b.mu.Lock()
+			b.lastError = err.Error()
+			b.mu.Unlock()
+			continue
+		}
+
+		b.mu.Lock()
+		outage := time.Since(b.openedAt)
+		b.failures = 0
+		close(b.closed)
+		b.closed = nil
+		b.mu.Unlock()
+		neo4jBreakerOpen.Set(0)
+		logger.Info("[NEO4J] Health probe succeeded, circuit breaker closed after %s", outage.Round(time.Second))
+		return
+	}
+}
+
+// wait blocks while the breaker is open, until it closes or ctx is done
+func (b *breaker) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	closed := b.closed
+	b.mu.Unlock()
+	if closed == nil {
+		return nil
+	}
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// state returns the current state of the breaker
+func (b *breaker) state() BreakerState {
+	if b == nil || b.threshold <= 0 {
+		return BreakerState{State: "disabled"}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{State: "closed", ConsecutiveFailures: b.failures, LastError: b.lastError}
+	if b.closed != nil {
+		state.State = "open"
+		state.OpenedAt = b.openedAt
+	}
+	return state
+}
+
+// WaitAvailable blocks while the circuit breaker is open, so that callers
+// buffer their work instead of failing during a Neo4j outage. It returns early
+// with the error of ctx when ctx is done.
+func (c *Client) WaitAvailable(ctx context.Context) error {
+	return c.breaker.wait(ctx)
+}
+
+// BreakerState returns the state of the circuit breaker
+func (c *Client) BreakerState() BreakerState {
+	return c.breaker.state()
+}
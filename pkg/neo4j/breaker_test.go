@@ -0,0 +1,88 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+func connectivityError() error {
+	return &neo4j.ConnectivityError{Inner: errors.New("connection refused")}
+}
+
+func TestBreakerOpensAfterConsecutiveConnectivityFailures(t *testing.T) {
+	logger.Init(logger.ERROR)
+	var reachable atomic.Bool
+	b := newBreaker(3, 10*time.Millisecond, func(context.Context) error {
+		if reachable.Load() {
+			return nil
+		}
+		return connectivityError()
+	})
+
+	b.record(connectivityError())
+	b.record(connectivityError())
+	// Errors of a reachable Neo4j reset the count
+	b.record(&neo4j.Neo4jError{Code: "Neo.ClientError.Statement.SyntaxError"})
+	b.record(connectivityError())
+	b.record(connectivityError())
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected the breaker to stay closed, got %v", err)
+	}
+
+	b.record(connectivityError())
+	err := b.allow()
+	if !errors.Is(err, ErrCircuitOpen) || failure.Classify(err) != failure.Neo4jTransient {
+		t.Fatalf("Expected a transient ErrCircuitOpen, got %v", err)
+	}
+	if state := b.state(); state.State != "open" || state.ConsecutiveFailures != 3 || state.OpenedAt.IsZero() {
+		t.Errorf("Expected an open state, got %+v", state)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected wait to time out while Neo4j is unreachable, got %v", err)
+	}
+
+	reachable.Store(true)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.wait(ctx); err != nil {
+		t.Fatalf("Expected the breaker to close after a successful probe, got %v", err)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("Expected operations to be allowed again, got %v", err)
+	}
+	if state := b.state(); state.State != "closed" || state.ConsecutiveFailures != 0 {
+		t.Errorf("Expected a closed state, got %+v", state)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, time.Millisecond, nil)
+	for i := 0; i < 10; i++ {
+		b.record(connectivityError())
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("Expected a disabled breaker to allow operations, got %v", err)
+	}
+	if state := b.state(); state.State != "disabled" {
+		t.Errorf("Expected disabled, got %+v", state)
+	}
+
+	var missing *breaker
+	if err := missing.allow(); err != nil {
+		t.Errorf("Expected a nil breaker to allow operations, got %v", err)
+	}
+	if err := missing.wait(context.Background()); err != nil {
+		t.Errorf("Expected a nil breaker not to block, got %v", err)
+	}
+}
@@ -2,17 +2,25 @@ package neo4j
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"k8s-graph/config"
+	"k8s-graph/pkg/enrich"
+	"k8s-graph/pkg/graph"
 	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/lru"
+	"k8s-graph/pkg/tracing"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Metrics holds all Prometheus metrics for the Neo4j client
@@ -41,37 +49,102 @@ var (
 		},
 	)
 
+	// The connection pool gauges report the primary pool, as before read pools
+	// existed, and the pool gauges report every pool with a pool label
 	neo4jConnectionPoolSize = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "neo4j_connection_pool_size",
-			Help: "Current size of the Neo4j connection pool",
+			Help: "Maximum size of the primary Neo4j connection pool",
 		},
 	)
 
 	neo4jConnectionPoolInUse = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "neo4j_connection_pool_in_use",
-			Help: "Number of connections currently in use in the Neo4j connection pool",
+			Help: "Number of sessions currently holding a connection of the primary Neo4j connection pool",
 		},
 	)
 
 	neo4jConnectionPoolIdle = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "neo4j_connection_pool_idle",
-			Help: "Number of idle connections in the Neo4j connection pool",
+			Help: "Number of connections of the primary Neo4j connection pool available to new sessions",
+		},
+	)
+
+	neo4jPoolSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "neo4j_pool_size",
+			Help: "Maximum size of a Neo4j connection pool",
+		},
+		[]string{"pool"},
+	)
+
+	neo4jPoolInUse = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "neo4j_pool_in_use",
+			Help: "Number of sessions currently holding a connection of a Neo4j connection pool",
+		},
+		[]string{"pool"},
+	)
+
+	neo4jPoolIdle = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "neo4j_pool_idle",
+			Help: "Number of connections of a Neo4j connection pool available to new sessions",
+		},
+		[]string{"pool"},
+	)
+
+	neo4jUpsertsSkippedTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "neo4j_upserts_skipped_total",
+			Help: "Total number of node upserts skipped because the properties were unchanged",
 		},
 	)
 )
 
 // Client represents a Neo4j client with connection pooling and metrics
 type Client struct {
-	driver neo4j.DriverWithContext
-	config *config.Config
-	mu     sync.RWMutex
+	driver   neo4j.DriverWithContext
+	primary  *pool // serves writes, and reads unless a read pool is configured
+	read     *pool // serves read sessions, the primary pool unless configured
+	config   *config.Config
+	mu       sync.RWMutex
+	hashes   *lru.Cache[string, string] // hash of the last written properties, keyed by node
+	writes   *writeWorkers              // serializes writes to hot nodes, nil when disabled
+	batches  *writeBatcher              // groups node writes into shared transactions, nil when disabled
+	breaker  *breaker                   // rejects operations during an outage
+	pending  *pendingRelationships      // relationships waiting for a missing node, nil when disabled
+	teams    *teams                     // stamps the team owning every node, nil when not configured
+	promoted *promoted                  // copies promoted label and annotation values into properties, nil when none are
+
+	stopCredentials context.CancelFunc // stops reloading the credential files
+
+	enrichers *enrich.Chain // run on every upserted node, nil when none are enabled
+
+	feed *mutationFeed // publishes the mutations written by the client
 }
 
 // NewClient creates a new Neo4j client with optimized connection pooling
 func NewClient(cfg *config.Config) (*Client, error) {
+	if err := graph.ValidateBackend(cfg.Neo4j.Backend); err != nil {
+		return nil, err
+	}
+	if cfg.Neo4j.Backend == graph.BackendMemgraph && cfg.Neo4j.Database != "" {
+		return nil, fmt.Errorf("named databases are not supported by the memgraph backend")
+	}
+
+	if err := resolveSecretFiles(cfg); err != nil {
+		return nil, err
+	}
+	creds := newCredentials(cfg)
+
+	driverTLS, err := tlsConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Configure connection pooling
 	driverConfig := neo4j.Config{
 		MaxConnectionPoolSize:          cfg.Neo4j.MaxConnectionPoolSize,
@@ -79,11 +152,12 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		ConnectionLivenessCheckTimeout: time.Duration(cfg.Neo4j.ConnectionLivenessCheckTimeout) * time.Second,
 		MaxConnectionLifetime:          time.Duration(cfg.Neo4j.MaxConnectionLifetime) * time.Hour,
 		MaxTransactionRetryTime:        time.Duration(cfg.Neo4j.MaxTransactionRetryTime) * time.Second,
+		TlsConfig:                      driverTLS,
 	}
 
 	driver, err := neo4j.NewDriverWithContext(
-		cfg.Neo4j.URI,
-		neo4j.BasicAuth(cfg.Neo4j.Username, cfg.Neo4j.Password, ""),
+		driverURI(cfg, cfg.Neo4j.URI),
+		creds,
 		func(config *neo4j.Config) {
 			*config = driverConfig
 		},
@@ -101,14 +175,53 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to verify neo4j connectivity: %w", err)
 	}
 
+	primary := &pool{name: PoolPrimary, driver: driver, size: driverConfig.MaxConnectionPoolSize}
+	pending := newPendingRelationships(cfg.Sync.PendingRelationships,
+		time.Duration(cfg.Sync.PendingRelationshipTTLSeconds)*time.Second)
+	if cfg.DryRun.Enabled {
+		if primary.dryRun, err = newDryRun(cfg.DryRun.Output); err != nil {
+			driver.Close(ctx)
+			return nil, err
+		}
+		// No node is written, so relationships would all wait for their nodes
+		pending = nil
+	}
+	read := primary
+	if readPoolEnabled(cfg) {
+		if read, err = newReadPool(ctx, cfg, creds, driverConfig); err != nil {
+			primary.dryRun.close()
+			driver.Close(ctx)
+			return nil, err
+		}
+		read.dryRun = primary.dryRun
+	}
+
 	client := &Client{
-		driver: driver,
-		config: cfg,
+		driver:   driver,
+		primary:  primary,
+		read:     read,
+		config:   cfg,
+		hashes:   lru.New[string, string](cfg.Sync.ChangeCacheSize),
+		writes:   newWriteWorkers(cfg.Sync.WriteWorkers, cfg.Sync.SerializedLabels),
+		pending:  pending,
+		teams:    newTeams(cfg.Teams.Label, cfg.Teams.Namespaces),
+		promoted: newPromoted(cfg.Promoted.Labels, cfg.Promoted.Annotations),
+		breaker: newBreaker(cfg.Neo4j.BreakerThreshold,
+			time.Duration(cfg.Neo4j.BreakerProbeIntervalSeconds)*time.Second, driver.VerifyConnectivity),
+		feed: newMutationFeed(),
 	}
+	batch := cfg.Neo4j.Batch
+	client.batches = newWriteBatcher(batch.MinSize, batch.MaxSize,
+		time.Duration(batch.FlushIntervalMs)*time.Millisecond, client.flushBatch)
 
 	// Start metrics collection goroutine
 	go client.collectMetrics()
 
+	// Pick up rotated credentials from their files
+	credentialsCtx, stopCredentials := context.WithCancel(context.Background())
+	client.stopCredentials = stopCredentials
+	go creds.watch(credentialsCtx, time.Duration(cfg.Neo4j.CredentialReloadSeconds)*time.Second)
+
 	return client, nil
 }
 
@@ -125,24 +238,45 @@ func (c *Client) collectMetrics() {
 	}
 }
 
-// updateConnectionPoolMetrics updates connection pool related metrics
+// updateConnectionPoolMetrics updates connection pool related metrics. The
+// Neo4j Go driver doesn't expose its pool statistics, so the connections in
+// use are approximated by the open sessions, which hold at most one each.
 func (c *Client) updateConnectionPoolMetrics() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	for _, p := range c.pools() {
+		inUse := p.inUse.Load()
+		idle := max(int64(p.size)-inUse, 0)
+		neo4jPoolSize.WithLabelValues(p.name).Set(float64(p.size))
+		neo4jPoolInUse.WithLabelValues(p.name).Set(float64(inUse))
+		neo4jPoolIdle.WithLabelValues(p.name).Set(float64(idle))
+		if p == c.primary {
+			neo4jConnectionPoolSize.Set(float64(p.size))
+			neo4jConnectionPoolInUse.Set(float64(inUse))
+			neo4jConnectionPoolIdle.Set(float64(idle))
+		}
+	}
+}
 
-	// Get connection pool statistics
-	stats, err := c.driver.GetServerInfo(ctx)
-	if err == nil && stats != nil {
-		// Note: The Neo4j Go driver doesn't expose detailed connection pool metrics
-		// We'll use reasonable defaults and update when available
-		neo4jConnectionPoolSize.Set(float64(c.config.Neo4j.MaxConnectionPoolSize))
-		neo4jConnectionPoolInUse.Set(0)                                            // Will be updated when we have better metrics
-		neo4jConnectionPoolIdle.Set(float64(c.config.Neo4j.MaxConnectionPoolSize)) // Will be updated when we have better metrics
+// pools returns the distinct connection pools of the client
+func (c *Client) pools() []*pool {
+	if c.read == c.primary {
+		return []*pool{c.primary}
 	}
+	return []*pool{c.primary, c.read}
 }
 
 // Close closes the Neo4j driver and all connections
 func (c *Client) Close(ctx context.Context) error {
+	if c.stopCredentials != nil {
+		c.stopCredentials()
+	}
+	c.writes.close()
+	c.batches.close()
+	if err := c.primary.dryRun.close(); err != nil {
+		logger.Warn("Failed to close dry-run output: %v", err)
+	}
+	if c.read != c.primary {
+		c.read.driver.Close(ctx)
+	}
 	return c.driver.Close(ctx)
 }
 
@@ -182,17 +316,28 @@ func convertMapPropertiesToJSON(properties map[string]interface{}) map[string]in
 	return result
 }
 
-// executeWithMetrics executes a Neo4j operation with metrics collection
+// executeWithMetrics executes a Neo4j operation with metrics collection and
+// traces it as a span of the operation in ctx
 func (c *Client) executeWithMetrics(ctx context.Context, operation string, fn func() error) error {
+	if err := c.breaker.allow(); err != nil {
+		neo4jOperationsTotal.WithLabelValues(operation, "rejected").Inc()
+		return err
+	}
 	start := time.Now()
 	neo4jActiveSessions.Inc()
+	_, span := tracing.Start(ctx, "neo4j."+operation,
+		attribute.String("db.system", "neo4j"),
+		attribute.String("db.operation", operation))
+	var err error
 	defer func() {
 		neo4jActiveSessions.Dec()
 		duration := time.Since(start).Seconds()
 		neo4jOperationDuration.WithLabelValues(operation).Observe(duration)
+		tracing.End(span, err)
 	}()
 
-	err := fn()
+	err = fn()
+	c.breaker.record(err)
 	if err != nil {
 		neo4jOperationsTotal.WithLabelValues(operation, "error").Inc()
 		return err
@@ -202,38 +347,107 @@ func (c *Client) executeWithMetrics(ctx context.Context, operation string, fn fu
 	return nil
 }
 
-// UpsertNode creates or updates a node with the given labels and properties
-func (c *Client) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
-	return c.executeWithMetrics(ctx, "upsert_node", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
-		defer session.Close(ctx)
+// nodeKey identifies a node in the upsert hash cache
+func nodeKey(labels []string, value interface{}) string {
+	return fmt.Sprintf("%s/%v", strings.Join(labels, ":"), value)
+}
 
-		// Convert map properties to JSON strings
-		convertedProperties := convertMapPropertiesToJSON(properties)
+// hashProperties returns a stable hash of converted node properties
+func hashProperties(properties map[string]interface{}) string {
+	// json.Marshal sorts map keys, so equal maps produce equal hashes
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
 
-		query := buildUpsertQuery(labels, convertedProperties, uniqueKey)
-		params := map[string]interface{}{
-			uniqueKey:    properties[uniqueKey], // Use original value for unique key
-			"properties": convertedProperties,
+// isUnchanged reports whether the node was last written with the same properties hash
+func (c *Client) isUnchanged(key, hash string) bool {
+	if hash == "" || c.hashes == nil {
+		return false
+	}
+	last, ok := c.hashes.Get(key)
+	return ok && last == hash
+}
+
+// rememberHash records the properties hash of a successful write
+func (c *Client) rememberHash(key, hash string) {
+	if c.hashes == nil {
+		return
+	}
+	c.hashes.Add(key, hash)
+}
+
+// ForgetNode drops the cached properties hash of a node so the next upsert is always written
+func (c *Client) ForgetNode(label string, value interface{}) {
+	if c.hashes == nil {
+		return
+	}
+	c.hashes.Remove(nodeKey([]string{label}, value))
+}
+
+// UpsertNode creates or updates a node with the given labels and properties.
+// The write is skipped when the properties are identical to the last write of the node.
+func (c *Client) UpsertNode(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	properties, enrichments := c.enrich(ctx, labels, properties)
+
+	// Convert map properties to JSON strings
+	convertedProperties := convertMapPropertiesToJSON(properties)
+	key := nodeKey(labels, properties[uniqueKey])
+	hash := hashProperties(convertedProperties)
+	if c.isUnchanged(key, hash) {
+		neo4jUpsertsSkippedTotal.Inc()
+		return nil
+	}
+
+	err := c.writes.run(ctx, labels[0], serializedNodeKey(properties, uniqueKey), func() error {
+		if c.batches != nil {
+			return c.batches.run(ctx, func(tx neo4j.ManagedTransaction) error {
+				_, err := tx.Run(ctx, buildUpsertQuery(labels, convertedProperties, uniqueKey), map[string]interface{}{
+					uniqueKey:    properties[uniqueKey],
+					"properties": convertedProperties,
+				})
+				return err
+			})
 		}
+		return c.executeWithMetrics(ctx, "upsert_node", func() error {
+			session := c.NewSession(ctx, neo4j.AccessModeWrite)
+			defer session.Close(ctx)
 
-		_, err := session.Run(ctx, query, params)
-		return err
+			query := buildUpsertQuery(labels, convertedProperties, uniqueKey)
+			params := map[string]interface{}{
+				uniqueKey:    properties[uniqueKey], // Use original value for unique key
+				"properties": convertedProperties,
+			}
+
+			_, err := session.Run(ctx, query, params)
+			return err
+		})
 	})
+	if err != nil {
+		return err
+	}
+	c.rememberHash(key, hash)
+	c.afterUpsert(ctx, labels, properties, convertedProperties, uniqueKey, enrichments)
+	return nil
 }
 
-// UpsertNodeWithTransaction creates or updates a node within a transaction
+// UpsertNodeWithTransaction creates or updates a node within a transaction.
+// The write is skipped when the properties are identical to the last write of the node.
 func (c *Client) UpsertNodeWithTransaction(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
-	return c.executeWithMetrics(ctx, "upsert_node_transaction", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
-		defer session.Close(ctx)
+	properties, enrichments := c.enrich(ctx, labels, properties)
+	convertedProperties := convertMapPropertiesToJSON(properties)
+	key := nodeKey(labels, properties[uniqueKey])
+	hash := hashProperties(convertedProperties)
+	if c.isUnchanged(key, hash) {
+		neo4jUpsertsSkippedTotal.Inc()
+		return nil
+	}
 
-		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-			convertedProperties := convertMapPropertiesToJSON(properties)
+	err := c.writes.run(ctx, labels[0], serializedNodeKey(properties, uniqueKey), func() error {
+		return c.writeTransaction(ctx, "upsert_node_transaction", func(tx neo4j.ManagedTransaction) error {
 			query := buildUpsertQuery(labels, convertedProperties, uniqueKey)
 			params := map[string]interface{}{
 				uniqueKey:    properties[uniqueKey],
@@ -241,13 +455,76 @@ func (c *Client) UpsertNodeWithTransaction(ctx context.Context, labels []string,
 			}
 
 			_, err := tx.Run(ctx, query, params)
-			return nil, err
+			return err
 		})
+	})
+	if err != nil {
+		return err
+	}
+	c.rememberHash(key, hash)
+	c.afterUpsert(ctx, labels, properties, convertedProperties, uniqueKey, enrichments)
+	return nil
+}
 
+// writeTransaction runs fn in a batch shared with the writes of other callers
+// when batching is enabled, or else in a transaction of its own
+func (c *Client) writeTransaction(ctx context.Context, operation string, fn func(tx neo4j.ManagedTransaction) error) error {
+	if c.batches != nil {
+		return c.batches.run(ctx, fn)
+	}
+	return c.executeWithMetrics(ctx, operation, func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return nil, fn(tx)
+		})
 		return err
 	})
 }
 
+// flushBatch runs the writes of a batch in one transaction. The transaction
+// outlives the contexts of the writers, which only cancel their own writes.
+func (c *Client) flushBatch(writes []batchedWrite) error {
+	ctx := context.Background()
+	return c.executeWithMetrics(ctx, "write_batch", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			for _, write := range writes {
+				if err := write.fn(tx); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		})
+		return err
+	})
+}
+
+// afterUpsert records a written node in the audit trail and history, writes
+// its enrichments and creates the relationships that were waiting for it
+func (c *Client) afterUpsert(ctx context.Context, labels []string, properties, convertedProperties map[string]interface{}, uniqueKey string, enrichments []enrich.Result) {
+	if err := c.writeEnrichments(ctx, labels[0], uniqueKey, properties[uniqueKey], enrichments); err != nil {
+		logger.Warn("Failed to write enriched relationships of %s %v: %v", strings.Join(labels, ":"), properties[uniqueKey], err)
+	}
+	if isWatchedResource(convertedProperties) {
+		name, _ := properties["name"].(string)
+		namespace, _ := properties["namespace"].(string)
+		c.recordChange(ctx, labels[0], fmt.Sprintf("%v", properties[uniqueKey]), name, namespace, OperationUpsert)
+	}
+	if err := c.recordVersion(ctx, labels, convertedProperties, uniqueKey); err != nil {
+		logger.Warn("Failed to record version of %s %v: %v", strings.Join(labels, ":"), properties[uniqueKey], err)
+	}
+	c.retryPending(ctx, labels[0], convertedProperties)
+	if labels[0] == "Namespace" {
+		if previous, current := c.teams.observeNamespace(properties); previous != current {
+			c.propagateTeam(ctx, properties, previous, current)
+		}
+	}
+}
+
 func buildUpsertQuery(labels []string, properties map[string]interface{}, uniqueKey string) string {
 	labelStr := ""
 	for _, label := range labels {
@@ -256,64 +533,105 @@ func buildUpsertQuery(labels []string, properties map[string]interface{}, unique
 	return fmt.Sprintf("MERGE (n%s {%s: $%s}) SET n = $properties", labelStr, uniqueKey, uniqueKey)
 }
 
-// CreateRelationship creates a relationship between two nodes
-func (c *Client) CreateRelationship(ctx context.Context, fromNodeLabel, fromNodeKey, fromNodeValue, relationshipType, toNodeLabel, toNodeKey, toNodeValue string) error {
-	return c.executeWithMetrics(ctx, "create_relationship", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
-		defer session.Close(ctx)
-
-		query := fmt.Sprintf(`
-			MATCH (from:%s {%s: $fromValue})
-			MATCH (to:%s {%s: $toValue})
-			MERGE (from)-[r:%s]->(to)
-			RETURN r`, fromNodeLabel, fromNodeKey, toNodeLabel, toNodeKey, relationshipType)
+// relationshipQuery returns the statement merging a relationship between the
+// nodes with $fromValue and $toValue, returning a row when both exist with
+// whether the relationship did not exist before
+func relationshipQuery(fromNodeLabel, fromNodeKey, relationshipType, toNodeLabel, toNodeKey string) string {
+	return fmt.Sprintf(`
+		MATCH (from:%s {%s: $fromValue})
+		MATCH (to:%s {%s: $toValue})
+		OPTIONAL MATCH (from)-[existing:%[5]s]->(to)
+		WITH from, to, existing IS NULL AS created
+		MERGE (from)-[:%[5]s]->(to)
+		RETURN created`, fromNodeLabel, fromNodeKey, toNodeLabel, toNodeKey, relationshipType)
+}
 
-		params := map[string]interface{}{
-			"fromValue": fromNodeValue,
-			"toValue":   toNodeValue,
+// readRelationshipResult reports whether the statement of relationshipQuery
+// found both nodes and whether it created the relationship
+func readRelationshipResult(ctx context.Context, result neo4j.ResultWithContext) (matched, created bool) {
+	for result.Next(ctx) {
+		matched = true
+		if isNew, _ := result.Record().Values[0].(bool); isNew {
+			created = true
 		}
-
-		_, err := session.Run(ctx, query, params)
-		return err
-	})
+	}
+	return matched, created
 }
 
-// CreateRelationshipWithTransaction creates a relationship within a transaction
-func (c *Client) CreateRelationshipWithTransaction(ctx context.Context, fromNodeLabel, fromNodeKey, fromNodeValue, relationshipType, toNodeLabel, toNodeKey, toNodeValue string) error {
-	return c.executeWithMetrics(ctx, "create_relationship_transaction", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
-		defer session.Close(ctx)
-
-		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-			query := fmt.Sprintf(`
-				MATCH (from:%s {%s: $fromValue})
-				MATCH (to:%s {%s: $toValue})
-				MERGE (from)-[r:%s]->(to)
-				RETURN r`, fromNodeLabel, fromNodeKey, toNodeLabel, toNodeKey, relationshipType)
+// CreateRelationship creates a relationship between two nodes. When either
+// node does not exist yet, the relationship is deferred until it is written.
+func (c *Client) CreateRelationship(ctx context.Context, fromNodeLabel, fromNodeKey, fromNodeValue, relationshipType, toNodeLabel, toNodeKey, toNodeValue string) error {
+	label, value := c.writes.serializedEndpoint(fromNodeLabel, fromNodeValue, toNodeLabel, toNodeValue)
+	var matched, created bool
+	err := c.writes.run(ctx, label, value, func() error {
+		return c.executeWithMetrics(ctx, "create_relationship", func() error {
+			session := c.NewSession(ctx, neo4j.AccessModeWrite)
+			defer session.Close(ctx)
 
 			params := map[string]interface{}{
 				"fromValue": fromNodeValue,
 				"toValue":   toNodeValue,
 			}
 
-			_, err := tx.Run(ctx, query, params)
-			return nil, err
+			result, err := session.Run(ctx, relationshipQuery(fromNodeLabel, fromNodeKey, relationshipType, toNodeLabel, toNodeKey), params)
+			if err != nil {
+				return err
+			}
+			matched, created = readRelationshipResult(ctx, result)
+			return result.Err()
 		})
-
+	})
+	if err != nil {
 		return err
+	}
+	c.settleRelationship(graph.Relationship{FromLabel: fromNodeLabel, FromKey: fromNodeKey, FromValue: fromNodeValue,
+		Type: relationshipType, ToLabel: toNodeLabel, ToKey: toNodeKey, ToValue: toNodeValue}, matched, created)
+	return nil
+}
+
+// CreateRelationshipWithTransaction creates a relationship within a
+// transaction, deferring it like CreateRelationship when a node is missing
+func (c *Client) CreateRelationshipWithTransaction(ctx context.Context, fromNodeLabel, fromNodeKey, fromNodeValue, relationshipType, toNodeLabel, toNodeKey, toNodeValue string) error {
+	label, value := c.writes.serializedEndpoint(fromNodeLabel, fromNodeValue, toNodeLabel, toNodeValue)
+	var matched, created bool
+	err := c.writes.run(ctx, label, value, func() error {
+		return c.executeWithMetrics(ctx, "create_relationship_transaction", func() error {
+			session := c.NewSession(ctx, neo4j.AccessModeWrite)
+			defer session.Close(ctx)
+
+			_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+				params := map[string]interface{}{
+					"fromValue": fromNodeValue,
+					"toValue":   toNodeValue,
+				}
+
+				result, err := tx.Run(ctx, relationshipQuery(fromNodeLabel, fromNodeKey, relationshipType, toNodeLabel, toNodeKey), params)
+				if err != nil {
+					return nil, err
+				}
+				matched, created = readRelationshipResult(ctx, result)
+				return nil, result.Err()
+			})
+
+			return err
+		})
 	})
+	if err != nil {
+		return err
+	}
+	c.settleRelationship(graph.Relationship{FromLabel: fromNodeLabel, FromKey: fromNodeKey, FromValue: fromNodeValue,
+		Type: relationshipType, ToLabel: toNodeLabel, ToKey: toNodeKey, ToValue: toNodeValue}, matched, created)
+	return nil
 }
 
-// ExecuteRead executes a read operation with proper session management
+// ExecuteRead executes a read operation with proper session management. It
+// runs on the primary pool, so handlers read the writes they just made.
 func (c *Client) ExecuteRead(ctx context.Context, fn func(neo4j.ManagedTransaction) (any, error)) (any, error) {
 	var result any
 	err := c.executeWithMetrics(ctx, "execute_read", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeRead,
+		session := c.primary.session(ctx, neo4j.SessionConfig{
+			AccessMode:   neo4j.AccessModeRead,
+			DatabaseName: c.config.Neo4j.Database,
 		})
 		defer session.Close(ctx)
 
@@ -324,13 +642,26 @@ func (c *Client) ExecuteRead(ctx context.Context, fn func(neo4j.ManagedTransacti
 	return result, err
 }
 
+// ExecuteQueryRead executes a read operation on the read pool, for queries
+// such as those of the HTTP API that do not need the latest writes
+func (c *Client) ExecuteQueryRead(ctx context.Context, fn func(neo4j.ManagedTransaction) (any, error)) (any, error) {
+	var result any
+	err := c.executeWithMetrics(ctx, "execute_query_read", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeRead)
+		defer session.Close(ctx)
+
+		var execErr error
+		result, execErr = session.ExecuteRead(ctx, fn)
+		return execErr
+	})
+	return result, err
+}
+
 // ExecuteWrite executes a write operation with proper session management
 func (c *Client) ExecuteWrite(ctx context.Context, fn func(neo4j.ManagedTransaction) (any, error)) (any, error) {
 	var result any
 	err := c.executeWithMetrics(ctx, "execute_write", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
 		defer session.Close(ctx)
 
 		var execErr error
@@ -340,17 +671,30 @@ func (c *Client) ExecuteWrite(ctx context.Context, fn func(neo4j.ManagedTransact
 	return result, err
 }
 
-// Driver returns the underlying Neo4j driver
+// Driver returns the underlying Neo4j driver. Sessions should be opened with
+// NewSession so they use the configured database.
 func (c *Client) Driver() neo4j.DriverWithContext {
 	return c.driver
 }
 
+// NewSession opens a session on the configured database, or on the default
+// database of the server if none is configured. Read sessions use the read
+// pool when one is configured.
+func (c *Client) NewSession(ctx context.Context, accessMode neo4j.AccessMode) neo4j.SessionWithContext {
+	p := c.primary
+	if accessMode == neo4j.AccessModeRead {
+		p = c.read
+	}
+	return p.session(ctx, neo4j.SessionConfig{
+		AccessMode:   accessMode,
+		DatabaseName: c.config.Neo4j.Database,
+	})
+}
+
 // DeleteOldClustersByName deletes clusters with the same name but different instance hashes
 func (c *Client) DeleteOldClustersByName(ctx context.Context, clusterName, currentInstanceHash string) error {
 	return c.executeWithMetrics(ctx, "delete_old_clusters", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
 		defer session.Close(ctx)
 
 		// Delete clusters with the same name but different instance hash
@@ -372,9 +716,7 @@ func (c *Client) DeleteOldClustersByName(ctx context.Context, clusterName, curre
 // DeleteOldResourcesByClusterName deletes resources with the same cluster name but different instance hashes
 func (c *Client) DeleteOldResourcesByClusterName(ctx context.Context, resourceType, clusterName, currentInstanceHash string) error {
 	return c.executeWithMetrics(ctx, "delete_old_resources", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
 		defer session.Close(ctx)
 
 		// Delete resources with the same cluster name but different instance hash
@@ -394,22 +736,24 @@ func (c *Client) DeleteOldResourcesByClusterName(ctx context.Context, resourceTy
 }
 
 // CleanupDuplicateClusters removes all nodes with the same cluster name but different instance hashes
-// Events are excluded from this cleanup as they should be preserved across runs
+// Events, audit records and images are excluded from this cleanup as they should be preserved across runs
 func (c *Client) CleanupDuplicateClusters(ctx context.Context, clusterName, currentInstanceHash string) error {
 	return c.executeWithMetrics(ctx, "cleanup_duplicate_clusters", func() error {
-		session := c.driver.NewSession(ctx, neo4j.SessionConfig{
-			AccessMode: neo4j.AccessModeWrite,
-		})
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
 		defer session.Close(ctx)
 
 		// Clean up all resource types that have clusterName and instanceHash properties
-		// Events are excluded as they should be preserved across runs
+		// Events are excluded as they should be preserved across runs, and
+		// images written with an instance hash by earlier releases as they hold
+		// the imported vulnerability counts
 		query := `
 			MATCH (n)
 			WHERE n.clusterName = $clusterName 
 			AND n.instanceHash IS NOT NULL 
 			AND n.instanceHash <> $currentInstanceHash
 			AND NOT n:Event
+			AND NOT n:GraphChange
+			AND NOT n:Image
 			DETACH DELETE n`
 
 		params := map[string]interface{}{
@@ -3,10 +3,13 @@ package neo4j
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"k8s-graph/config"
+	"k8s-graph/pkg/lru"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
@@ -580,3 +583,222 @@ func TestMetricsCollection(t *testing.T) {
 	// Note: In a real test environment, you might want to check actual metric values
 	// For now, we just ensure the client doesn't crash during metrics collection
 }
+
+func TestHashProperties(t *testing.T) {
+	a := convertMapPropertiesToJSON(map[string]interface{}{
+		"name":   "test",
+		"labels": map[string]string{"app": "web", "tier": "frontend"},
+	})
+	b := convertMapPropertiesToJSON(map[string]interface{}{
+		"labels": map[string]string{"tier": "frontend", "app": "web"},
+		"name":   "test",
+	})
+	c := convertMapPropertiesToJSON(map[string]interface{}{
+		"name":   "test",
+		"labels": map[string]string{"app": "api"},
+	})
+
+	if hashProperties(a) != hashProperties(b) {
+		t.Error("Expected equal properties to produce equal hashes")
+	}
+	if hashProperties(a) == hashProperties(c) {
+		t.Error("Expected different properties to produce different hashes")
+	}
+}
+
+func TestUnchangedUpsertTracking(t *testing.T) {
+	client := &Client{hashes: lru.New[string, string](10)}
+	key := nodeKey([]string{"Pod"}, "uid-1")
+	hash := hashProperties(map[string]interface{}{"name": "pod-1"})
+
+	if client.isUnchanged(key, hash) {
+		t.Error("Expected unknown node to be reported as changed")
+	}
+
+	client.rememberHash(key, hash)
+	if !client.isUnchanged(key, hash) {
+		t.Error("Expected node with same hash to be reported as unchanged")
+	}
+	if client.isUnchanged(key, hashProperties(map[string]interface{}{"name": "pod-2"})) {
+		t.Error("Expected node with different hash to be reported as changed")
+	}
+
+	client.ForgetNode("Pod", "uid-1")
+	if client.isUnchanged(key, hash) {
+		t.Error("Expected forgotten node to be reported as changed")
+	}
+}
+
+func TestVersionHashIgnoresInstanceHash(t *testing.T) {
+	a := map[string]interface{}{"name": "pod-1", "status": "Running", "instanceHash": "run-1"}
+	b := map[string]interface{}{"name": "pod-1", "status": "Running", "instanceHash": "run-2"}
+	c := map[string]interface{}{"name": "pod-1", "status": "Failed", "instanceHash": "run-2"}
+
+	if versionHash(a) != versionHash(b) {
+		t.Error("Expected versions differing only in instanceHash to have the same hash")
+	}
+	if versionHash(b) == versionHash(c) {
+		t.Error("Expected versions with different content to have different hashes")
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	previous := map[string]interface{}{"name": "pod-1", "status": "Running", "podIP": "10.0.0.1", "instanceHash": "run-1"}
+	current := map[string]interface{}{"name": "pod-1", "status": "Failed", "nodeName": "node-1", "instanceHash": "run-2"}
+
+	changed := changedKeys(previous, current)
+	expected := []string{"nodeName", "podIP", "status"}
+	if fmt.Sprint(changed) != fmt.Sprint(expected) {
+		t.Errorf("Expected changed keys %v, got %v", expected, changed)
+	}
+}
+
+func TestIsWatchedResource(t *testing.T) {
+	if !isWatchedResource(map[string]interface{}{"instanceHash": "run-1"}) {
+		t.Error("Expected resources with instanceHash to be versioned")
+	}
+	if isWatchedResource(map[string]interface{}{"name": "event-1"}) {
+		t.Error("Expected nodes without instanceHash not to be versioned")
+	}
+}
+
+func TestDecodeExportRecord(t *testing.T) {
+	line := []byte(`{"type":"node","id":"4:abc:1","labels":["Pod"],"properties":{"name":"web","priority":100,"ratio":0.5,"ports":[80,443]}}`)
+
+	record, err := decodeExportRecord(line)
+	if err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if record.Type != ExportTypeNode || record.ID != "4:abc:1" || len(record.Labels) != 1 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if _, ok := record.Properties["priority"].(int64); !ok {
+		t.Errorf("Expected integer property to decode as int64, got %T", record.Properties["priority"])
+	}
+	if _, ok := record.Properties["ratio"].(float64); !ok {
+		t.Errorf("Expected fractional property to decode as float64, got %T", record.Properties["ratio"])
+	}
+	if ports, _ := record.Properties["ports"].([]interface{}); len(ports) != 2 || ports[0] != int64(80) {
+		t.Errorf("Expected integer list to decode as int64 values, got %v", record.Properties["ports"])
+	}
+
+	if _, err := decodeExportRecord([]byte(`{"type":`)); err == nil {
+		t.Error("Expected error for malformed record")
+	}
+}
+
+func TestImportBatches(t *testing.T) {
+	batches := newImportBatches(2)
+
+	if full := batches.add(ExportTypeNode, "pods", map[string]interface{}{"name": "a"}); full != nil {
+		t.Fatal("Expected batch not to be full after one row")
+	}
+	batches.add(ExportTypeNode, "services", map[string]interface{}{"name": "b"})
+	full := batches.add(ExportTypeNode, "pods", map[string]interface{}{"name": "c"})
+	if full == nil || len(full.rows) != 2 {
+		t.Fatalf("Expected full batch of 2 rows, got %+v", full)
+	}
+	batches.add(ExportTypeRelationship, "selects", map[string]interface{}{"start": "1", "end": "2"})
+
+	nodes := batches.drainType(ExportTypeNode)
+	if len(nodes) != 1 || nodes[0].query != "services" {
+		t.Errorf("Expected only the pending services batch, got %+v", nodes)
+	}
+	relationships := batches.drainType(ExportTypeRelationship)
+	if len(relationships) != 1 || len(relationships[0].rows) != 1 {
+		t.Errorf("Expected one pending relationship batch, got %+v", relationships)
+	}
+}
+
+func TestQuoteIdentifiers(t *testing.T) {
+	if got := quoteIdentifiers([]string{"Pod", "Bad`Label"}, ":"); got != "`Pod`:`Bad``Label`" {
+		t.Errorf("Unexpected quoted identifiers %s", got)
+	}
+}
+
+func TestWriteWorkersSerializeSameNode(t *testing.T) {
+	workers := newWriteWorkers(4, []string{"Node"})
+	defer workers.close()
+
+	var active, maxActive int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := workers.run(context.Background(), "Node", "worker-1", func() error {
+				n := atomic.AddInt32(&active, 1)
+				if n > atomic.LoadInt32(&maxActive) {
+					atomic.StoreInt32(&maxActive, n)
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&active, -1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("Expected writes to the same node to run one at a time, got %d concurrent", maxActive)
+	}
+}
+
+func TestWriteWorkersAssignment(t *testing.T) {
+	workers := newWriteWorkers(8, []string{"Node", "Namespace"})
+	defer workers.close()
+
+	if workers.workerFor("Node", "worker-1") != workers.workerFor("Node", "worker-1") {
+		t.Error("Expected the same node to always be assigned to the same worker")
+	}
+	if !workers.serializes("Namespace") || workers.serializes("Pod") {
+		t.Error("Expected only the configured labels to be serialized")
+	}
+
+	label, value := workers.serializedEndpoint("Pod", "uid-1", "Node", "worker-1")
+	if label != "Node" || value != "worker-1" {
+		t.Errorf("Expected relationship to be assigned by its Node endpoint, got %s/%s", label, value)
+	}
+
+	if key := serializedNodeKey(map[string]interface{}{"uid": "uid-1", "name": "worker-1"}, "uid"); key != "worker-1" {
+		t.Errorf("Expected nodes to be assigned by name, got %v", key)
+	}
+}
+
+func TestWriteWorkersDisabled(t *testing.T) {
+	var workers *writeWorkers
+	if newWriteWorkers(0, []string{"Node"}) != nil {
+		t.Error("Expected no workers when the worker count is 0")
+	}
+
+	called := false
+	if err := workers.run(context.Background(), "Node", "worker-1", func() error {
+		called = true
+		return nil
+	}); err != nil || !called {
+		t.Errorf("Expected write to run directly when serialization is disabled, called=%v err=%v", called, err)
+	}
+}
+
+// BenchmarkWriteWorkers measures the overhead of queueing writes to hot
+// nodes on their workers, without Neo4j
+func BenchmarkWriteWorkers(b *testing.B) {
+	workers := newWriteWorkers(8, []string{"Node"})
+	defer workers.close()
+
+	var i atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			node := fmt.Sprintf("node-%d", i.Add(1)%50)
+			if err := workers.run(context.Background(), "Node", node, func() error { return nil }); err != nil {
+				b.Errorf("Unexpected error: %v", err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "writes/s")
+}
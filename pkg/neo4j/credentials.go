@@ -0,0 +1,171 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var neo4jCredentialReloadsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "neo4j_credential_reloads_total",
+		Help: "Total number of reloads of the Neo4j credential files, by result",
+	},
+	[]string{"result"},
+)
+
+// readSecretFile reads a value from a file such as a mounted Secret, without
+// the trailing newline editors and kubectl add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveSecretFiles replaces the URI, username and password of cfg with the
+// content of their files, if set
+func resolveSecretFiles(cfg *config.Config) error {
+	files := []struct {
+		name  string
+		path  string
+		value *string
+	}{
+		{"URI", cfg.Neo4j.URIFile, &cfg.Neo4j.URI},
+		{"username", cfg.Neo4j.UsernameFile, &cfg.Neo4j.Username},
+		{"password", cfg.Neo4j.PasswordFile, &cfg.Neo4j.Password},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		value, err := readSecretFile(file.path)
+		if err != nil {
+			return fmt.Errorf("failed to read neo4j %s file: %w", file.name, err)
+		}
+		if value == "" {
+			return fmt.Errorf("neo4j %s file %s is empty", file.name, file.path)
+		}
+		*file.value = value
+	}
+	return nil
+}
+
+// credentials provides the basic auth token of the driver from the username
+// and password files, so that rotated credentials are used without a restart.
+// The files are polled, which also follows the symlink swaps of Secret volumes.
+type credentials struct {
+	usernameFile string
+	passwordFile string
+	uriFile      string
+
+	mu       sync.RWMutex
+	username string
+	password string
+	uri      string
+}
+
+// newCredentials creates the credentials of cfg, whose files must have been
+// resolved with resolveSecretFiles
+func newCredentials(cfg *config.Config) *credentials {
+	return &credentials{
+		usernameFile: cfg.Neo4j.UsernameFile,
+		passwordFile: cfg.Neo4j.PasswordFile,
+		uriFile:      cfg.Neo4j.URIFile,
+		username:     cfg.Neo4j.Username,
+		password:     cfg.Neo4j.Password,
+		uri:          cfg.Neo4j.URI,
+	}
+}
+
+// GetAuthToken returns the token of the current credentials
+func (c *credentials) GetAuthToken(ctx context.Context) (neo4j.AuthToken, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return neo4j.BasicAuth(c.username, c.password, ""), nil
+}
+
+// HandleSecurityException reloads the files when Neo4j rejects the token, in
+// case the credentials were rotated since the last poll. The failed operation
+// is retried if the credentials changed.
+func (c *credentials) HandleSecurityException(ctx context.Context, token neo4j.AuthToken, securityException *db.Neo4jError) (bool, error) {
+	if securityException.Code != "Neo.ClientError.Security.Unauthorized" {
+		return false, nil
+	}
+	return c.reload(), nil
+}
+
+// reload reads the files again and reports whether the credentials changed
+func (c *credentials) reload() bool {
+	c.mu.RLock()
+	username, password, uri := c.username, c.password, c.uri
+	c.mu.RUnlock()
+
+	files := []struct {
+		path  string
+		value *string
+	}{
+		{c.usernameFile, &username},
+		{c.passwordFile, &password},
+		{c.uriFile, &uri},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		value, err := readSecretFile(file.path)
+		if err == nil && value == "" {
+			err = fmt.Errorf("file is empty")
+		}
+		if err != nil {
+			// A Secret volume is briefly empty while it is updated
+			logger.Warn("[NEO4J] Failed to reload credential file %s: %v", file.path, err)
+			neo4jCredentialReloadsTotal.WithLabelValues("failure").Inc()
+			return false
+		}
+		*file.value = value
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if uri != c.uri {
+		logger.Warn("[NEO4J] URI file %s changed, restart to connect to %s", c.uriFile, uri)
+		c.uri = uri
+	}
+	if username == c.username && password == c.password {
+		return false
+	}
+	c.username, c.password = username, password
+	neo4jCredentialReloadsTotal.WithLabelValues("success").Inc()
+	logger.Info("[NEO4J] Reloaded credentials from their files")
+	return true
+}
+
+// watch reloads the files every interval until ctx is done
+func (c *credentials) watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || (c.usernameFile == "" && c.passwordFile == "" && c.uriFile == "") {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reload()
+		}
+	}
+}
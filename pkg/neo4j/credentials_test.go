@@ -0,0 +1,92 @@
+package neo4j
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/db"
+)
+
+func writeSecretFile(t *testing.T, dir, name, value string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(value), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestResolveSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewConfig()
+	cfg.Neo4j.URIFile = writeSecretFile(t, dir, "uri", "neo4j+s://graph.example.com:7687\n")
+	cfg.Neo4j.UsernameFile = writeSecretFile(t, dir, "username", "kubegraph")
+	cfg.Neo4j.PasswordFile = writeSecretFile(t, dir, "password", " s3cr3t \n")
+
+	if err := resolveSecretFiles(cfg); err != nil {
+		t.Fatalf("Failed to resolve secret files: %v", err)
+	}
+	if cfg.Neo4j.URI != "neo4j+s://graph.example.com:7687" || cfg.Neo4j.Username != "kubegraph" || cfg.Neo4j.Password != " s3cr3t " {
+		t.Errorf("Unexpected values read from files: %q %q %q", cfg.Neo4j.URI, cfg.Neo4j.Username, cfg.Neo4j.Password)
+	}
+
+	cfg.Neo4j.PasswordFile = writeSecretFile(t, dir, "empty", "\n")
+	if err := resolveSecretFiles(cfg); err == nil {
+		t.Error("Expected an error for an empty password file")
+	}
+	cfg.Neo4j.PasswordFile = filepath.Join(dir, "missing")
+	if err := resolveSecretFiles(cfg); err == nil {
+		t.Error("Expected an error for a missing password file")
+	}
+}
+
+func TestCredentialsReload(t *testing.T) {
+	logger.Init(logger.ERROR)
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := config.NewConfig()
+	cfg.Neo4j.UsernameFile = writeSecretFile(t, dir, "username", "kubegraph")
+	cfg.Neo4j.PasswordFile = writeSecretFile(t, dir, "password", "old")
+	if err := resolveSecretFiles(cfg); err != nil {
+		t.Fatalf("Failed to resolve secret files: %v", err)
+	}
+	creds := newCredentials(cfg)
+
+	token, _ := creds.GetAuthToken(ctx)
+	if token.Tokens["credentials"] != "old" {
+		t.Fatalf("Expected the initial password, got %v", token.Tokens["credentials"])
+	}
+	if creds.reload() {
+		t.Error("Expected no change when the files are unchanged")
+	}
+
+	writeSecretFile(t, dir, "password", "new")
+	unauthorized := &db.Neo4jError{Code: "Neo.ClientError.Security.Unauthorized"}
+	if handled, _ := creds.HandleSecurityException(ctx, token, unauthorized); !handled {
+		t.Error("Expected a rejected token to be retried after the password changed")
+	}
+	token, _ = creds.GetAuthToken(ctx)
+	if token.Tokens["credentials"] != "new" || token.Tokens["principal"] != "kubegraph" {
+		t.Errorf("Expected the rotated password, got %v", token.Tokens)
+	}
+
+	forbidden := &db.Neo4jError{Code: "Neo.ClientError.Security.Forbidden"}
+	if handled, _ := creds.HandleSecurityException(ctx, token, forbidden); handled {
+		t.Error("Expected other security errors not to be retried")
+	}
+
+	// A file that is briefly empty while the Secret is updated keeps the credentials
+	writeSecretFile(t, dir, "password", "")
+	if creds.reload() {
+		t.Error("Expected an empty file to be ignored")
+	}
+	token, _ = creds.GetAuthToken(ctx)
+	if token.Tokens["credentials"] != "new" {
+		t.Errorf("Expected the password to be kept, got %v", token.Tokens["credentials"])
+	}
+}
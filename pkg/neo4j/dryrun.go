@@ -0,0 +1,189 @@
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrDryRun is returned when the record of a statement skipped by a dry run
+// is read
+var ErrDryRun = errors.New("statement not executed in dry-run mode")
+
+var dryRunStatementsTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "neo4j_dry_run_statements_total",
+		Help: "Total number of write statements skipped by the dry run",
+	},
+)
+
+// writeClause matches the Cypher clauses that modify the graph or the schema
+var writeClause = regexp.MustCompile(`(?i)\b(CREATE|MERGE|SET|DELETE|REMOVE|DROP|LOAD\s+CSV)\b`)
+
+// isWrite reports whether query modifies the graph
+func isWrite(query string) bool {
+	return writeClause.MatchString(query)
+}
+
+// DryRunStatement is a write statement skipped by the dry run, as written to
+// the output file
+type DryRunStatement struct {
+	Time   time.Time              `json:"time"`
+	Query  string                 `json:"query"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// dryRun logs the write statements it skips, and appends them to a file
+type dryRun struct {
+	mu  sync.Mutex
+	out *os.File // nil when statements are only logged
+}
+
+// newDryRun creates a dry run writing the skipped statements to path as JSON
+// lines, or only logging them if path is empty
+func newDryRun(path string) (*dryRun, error) {
+	d := &dryRun{}
+	if path == "" {
+		return d, nil
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dry-run output: %w", err)
+	}
+	d.out = out
+	return d, nil
+}
+
+// record logs a skipped statement
+func (d *dryRun) record(query string, params map[string]interface{}) {
+	dryRunStatementsTotal.Inc()
+	query = strings.Join(strings.Fields(query), " ")
+	data, err := json.Marshal(params)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", params))
+	}
+	logger.Info("[DRY RUN] %s %s", query, data)
+
+	if d.out == nil {
+		return
+	}
+	line, err := json.Marshal(DryRunStatement{Time: time.Now().UTC(), Query: query, Params: params})
+	if err != nil {
+		logger.Warn("Failed to encode dry-run statement: %v", err)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.out.Write(append(line, '\n')); err != nil {
+		logger.Warn("Failed to write dry-run output: %v", err)
+	}
+}
+
+// close closes the output file
+func (d *dryRun) close() error {
+	if d == nil || d.out == nil {
+		return nil
+	}
+	return d.out.Close()
+}
+
+// dryRunSession is a write session that runs read statements and skips
+// write statements
+type dryRunSession struct {
+	neo4j.SessionWithContext
+	dryRun *dryRun
+}
+
+// Run runs a read statement, or records a write statement
+func (s *dryRunSession) Run(ctx context.Context, cypher string, params map[string]any, configurers ...func(*neo4j.TransactionConfig)) (neo4j.ResultWithContext, error) {
+	if !isWrite(cypher) {
+		return s.SessionWithContext.Run(ctx, cypher, params, configurers...)
+	}
+	s.dryRun.record(cypher, params)
+	return skippedResult{}, nil
+}
+
+// ExecuteWrite runs work in a transaction whose write statements are skipped
+func (s *dryRunSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, configurers ...func(*neo4j.TransactionConfig)) (any, error) {
+	return s.SessionWithContext.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return work(&dryRunTransaction{ManagedTransaction: tx, dryRun: s.dryRun})
+	}, configurers...)
+}
+
+// BeginTransaction is not supported by the dry run, since explicit
+// transactions cannot be intercepted
+func (s *dryRunSession) BeginTransaction(ctx context.Context, configurers ...func(*neo4j.TransactionConfig)) (neo4j.ExplicitTransaction, error) {
+	return nil, ErrDryRun
+}
+
+// dryRunTransaction is a managed transaction that skips write statements
+type dryRunTransaction struct {
+	neo4j.ManagedTransaction
+	dryRun *dryRun
+}
+
+// Run runs a read statement, or records a write statement
+func (tx *dryRunTransaction) Run(ctx context.Context, cypher string, params map[string]any) (neo4j.ResultWithContext, error) {
+	if !isWrite(cypher) {
+		return tx.ManagedTransaction.Run(ctx, cypher, params)
+	}
+	tx.dryRun.record(cypher, params)
+	return skippedResult{}, nil
+}
+
+// skippedResult is the empty result of a skipped statement
+type skippedResult struct {
+	neo4j.ResultWithContext
+}
+
+func (skippedResult) Keys() ([]string, error)                                    { return nil, nil }
+func (skippedResult) NextRecord(ctx context.Context, record **neo4j.Record) bool { return false }
+func (skippedResult) Next(ctx context.Context) bool                              { return false }
+func (skippedResult) PeekRecord(ctx context.Context, record **neo4j.Record) bool { return false }
+func (skippedResult) Peek(ctx context.Context) bool                              { return false }
+func (skippedResult) Err() error                                                 { return nil }
+func (skippedResult) Record() *neo4j.Record                                      { return nil }
+func (skippedResult) Collect(ctx context.Context) ([]*neo4j.Record, error)       { return nil, nil }
+func (skippedResult) Single(ctx context.Context) (*neo4j.Record, error)          { return nil, ErrDryRun }
+func (skippedResult) Consume(ctx context.Context) (neo4j.ResultSummary, error) {
+	return skippedSummary{}, nil
+}
+func (skippedResult) IsOpen() bool { return false }
+
+// skippedSummary is the summary of a skipped statement, which changed nothing
+type skippedSummary struct {
+	neo4j.ResultSummary
+}
+
+func (skippedSummary) Counters() neo4j.Counters            { return skippedCounters{} }
+func (skippedSummary) Notifications() []neo4j.Notification { return nil }
+
+// skippedCounters are the zero counters of a skipped statement
+type skippedCounters struct{}
+
+func (skippedCounters) ContainsUpdates() bool       { return false }
+func (skippedCounters) NodesCreated() int           { return 0 }
+func (skippedCounters) NodesDeleted() int           { return 0 }
+func (skippedCounters) RelationshipsCreated() int   { return 0 }
+func (skippedCounters) RelationshipsDeleted() int   { return 0 }
+func (skippedCounters) PropertiesSet() int          { return 0 }
+func (skippedCounters) LabelsAdded() int            { return 0 }
+func (skippedCounters) LabelsRemoved() int          { return 0 }
+func (skippedCounters) IndexesAdded() int           { return 0 }
+func (skippedCounters) IndexesRemoved() int         { return 0 }
+func (skippedCounters) ConstraintsAdded() int       { return 0 }
+func (skippedCounters) ConstraintsRemoved() int     { return 0 }
+func (skippedCounters) SystemUpdates() int          { return 0 }
+func (skippedCounters) ContainsSystemUpdates() bool { return false }
@@ -0,0 +1,143 @@
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// recordingTransaction is a transaction that records the statements it runs
+type recordingTransaction struct {
+	neo4j.ManagedTransaction
+	queries []string
+}
+
+func (tx *recordingTransaction) Run(ctx context.Context, cypher string, params map[string]any) (neo4j.ResultWithContext, error) {
+	tx.queries = append(tx.queries, cypher)
+	return skippedResult{}, nil
+}
+
+// writeDriver is a driver whose sessions run their statements on a
+// recordingTransaction
+type writeDriver struct {
+	neo4j.DriverWithContext
+	tx recordingTransaction
+}
+
+func (d *writeDriver) NewSession(ctx context.Context, config neo4j.SessionConfig) neo4j.SessionWithContext {
+	return &writeSession{tx: &d.tx}
+}
+
+type writeSession struct {
+	neo4j.SessionWithContext
+	tx *recordingTransaction
+}
+
+func (s *writeSession) Run(ctx context.Context, cypher string, params map[string]any, configurers ...func(*neo4j.TransactionConfig)) (neo4j.ResultWithContext, error) {
+	return s.tx.Run(ctx, cypher, params)
+}
+
+func (s *writeSession) ExecuteWrite(ctx context.Context, work neo4j.ManagedTransactionWork, configurers ...func(*neo4j.TransactionConfig)) (any, error) {
+	return work(s.tx)
+}
+
+func (s *writeSession) Close(ctx context.Context) error { return nil }
+
+func TestIsWrite(t *testing.T) {
+	tests := map[string]bool{
+		"MERGE (n:Pod {uid: $uid}) SET n = $properties":               true,
+		"MATCH (n:Event) WHERE n.createdAt < $cutoff DETACH DELETE n": true,
+		"match (n) remove n.legacy":                                   true,
+		"CREATE INDEX pod_uid IF NOT EXISTS FOR (n:Pod) ON (n.uid)":   true,
+		"MATCH (n:Pod) RETURN n.name AS name":                         false,
+		"MATCH (n) WHERE n.offset > 0 RETURN n":                       false,
+		"MATCH (n:Dataset) RETURN count(n)":                           false,
+	}
+	for query, expected := range tests {
+		if got := isWrite(query); got != expected {
+			t.Errorf("%q: expected %t, got %t", query, expected, got)
+		}
+	}
+}
+
+func TestDryRunTransaction(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "dry-run.jsonl")
+	d, err := newDryRun(path)
+	if err != nil {
+		t.Fatalf("Failed to create dry run: %v", err)
+	}
+
+	inner := &recordingTransaction{}
+	tx := &dryRunTransaction{ManagedTransaction: inner, dryRun: d}
+	ctx := context.Background()
+
+	tx.Run(ctx, "MATCH (n:Pod {uid: $uid}) RETURN n", map[string]any{"uid": "a"})
+	result, err := tx.Run(ctx, "MERGE (n:Pod {uid: $uid})\n\tSET n = $properties", map[string]any{"uid": "b"})
+	if err != nil {
+		t.Fatalf("Expected the write to be skipped without error, got %v", err)
+	}
+	if len(inner.queries) != 1 || !strings.HasPrefix(inner.queries[0], "MATCH") {
+		t.Errorf("Expected only the read to run, got %v", inner.queries)
+	}
+	if result.Next(ctx) {
+		t.Error("Expected a skipped statement to return no records")
+	}
+	if _, err := result.Single(ctx); !errors.Is(err, ErrDryRun) {
+		t.Errorf("Expected ErrDryRun, got %v", err)
+	}
+	if err := d.close(); err != nil {
+		t.Fatalf("Failed to close dry run: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one statement in the output, got %d", len(lines))
+	}
+	var statement DryRunStatement
+	if err := json.Unmarshal([]byte(lines[0]), &statement); err != nil {
+		t.Fatalf("Failed to decode statement: %v", err)
+	}
+	if statement.Query != "MERGE (n:Pod {uid: $uid}) SET n = $properties" || statement.Params["uid"] != "b" {
+		t.Errorf("Unexpected statement %+v", statement)
+	}
+}
+
+func TestDryRunCleanup(t *testing.T) {
+	logger.Init(logger.ERROR)
+	d, err := newDryRun("")
+	if err != nil {
+		t.Fatalf("Failed to create dry run: %v", err)
+	}
+	driver := &writeDriver{}
+	primary := &pool{name: PoolPrimary, driver: driver, dryRun: d}
+	client := &Client{primary: primary, read: primary, config: config.NewConfig()}
+	ctx := context.Background()
+
+	if err := client.CleanupDuplicateClusters(ctx, "production", "abc"); err != nil {
+		t.Errorf("Expected the skipped cleanup to delete nothing, got %v", err)
+	}
+	if err := client.PruneGraphChanges(ctx, 7); err != nil {
+		t.Errorf("Expected the skipped prune to delete nothing, got %v", err)
+	}
+	rows, err := client.Query(ctx, "MATCH (e:Event) WHERE e.createdAt < $cutoff DETACH DELETE e RETURN count(e) AS pruned", nil)
+	if err != nil || len(rows) != 0 {
+		t.Errorf("Expected a skipped query to return no rows, got %v, %v", rows, err)
+	}
+	if len(driver.tx.queries) != 0 {
+		t.Errorf("Expected no write to run, got %v", driver.tx.queries)
+	}
+}
@@ -0,0 +1,72 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/enrich"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// SetEnrichers sets the enrichers run on every node written through UpsertNode
+func (c *Client) SetEnrichers(chain *enrich.Chain) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enrichers = chain
+}
+
+// enrich stamps the team and the promoted keys of a node about to be written
+// and runs the enrichers on its properties, returning the enriched properties
+func (c *Client) enrich(ctx context.Context, labels []string, properties map[string]interface{}) (map[string]interface{}, []enrich.Result) {
+	properties = c.teams.stamp(labels[0], properties)
+	properties = c.promoted.stamp(properties)
+	c.mu.RLock()
+	chain := c.enrichers
+	c.mu.RUnlock()
+	if chain.Len() == 0 {
+		return properties, nil
+	}
+	resource := &enrich.Resource{Labels: labels, Properties: properties}
+	results := chain.Apply(ctx, resource)
+	return resource.Properties, results
+}
+
+// writeEnrichments replaces the relationships each enricher created from a
+// node. The relationships carry the name of their enricher, so an enricher
+// only replaces its own.
+func (c *Client) writeEnrichments(ctx context.Context, label, uniqueKey string, value interface{}, results []enrich.Result) error {
+	if len(results) == 0 {
+		return nil
+	}
+	_, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		for _, result := range results {
+			params := map[string]interface{}{"value": value, "enricher": result.Enricher}
+			if _, err := tx.Run(ctx, fmt.Sprintf(`
+				MATCH (n:%s {%s: $value})-[r]->()
+				WHERE r.enricher = $enricher
+				DELETE r`, label, uniqueKey), params); err != nil {
+				return nil, err
+			}
+			for _, relationship := range result.Relationships {
+				target := "MATCH"
+				if relationship.TargetProperties != nil {
+					target = "MERGE"
+				}
+				query := fmt.Sprintf(`
+					MATCH (n:%s {%s: $value})
+					%s (t:%s {%s: $targetValue})
+					SET t += $targetProperties
+					MERGE (n)-[:%s {enricher: $enricher}]->(t)`,
+					label, uniqueKey, target, relationship.TargetLabel, relationship.TargetKey, relationship.Type)
+				params["targetValue"] = relationship.TargetValue
+				params["targetProperties"] = convertMapPropertiesToJSON(relationship.TargetProperties)
+				if _, err := tx.Run(ctx, query, params); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
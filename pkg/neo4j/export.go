@@ -0,0 +1,398 @@
+package neo4j
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Record types of the export format
+const (
+	ExportTypeNode         = "node"
+	ExportTypeRelationship = "relationship"
+)
+
+const (
+	importLabel   = "KubegraphImport"
+	importIDKey   = "_kubegraphImportId"
+	importIndex   = "kubegraph_import_id"
+	maxExportLine = 64 * 1024 * 1024
+)
+
+// ExportRecord is one line of an export file. Nodes are written before the
+// relationships between them; IDs are only meaningful within one file.
+type ExportRecord struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Labels     []string               `json:"labels,omitempty"`
+	RelType    string                 `json:"relType,omitempty"`
+	Start      string                 `json:"start,omitempty"`
+	End        string                 `json:"end,omitempty"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GraphStats counts the nodes and relationships of an export or import
+type GraphStats struct {
+	Nodes         int
+	Relationships int
+}
+
+// ExportGraph writes all nodes and relationships as JSON lines to w. If
+// clusterName or team is set, only nodes of that cluster and team and the
+// relationships between them are exported.
+func (c *Client) ExportGraph(ctx context.Context, w io.Writer, clusterName, team string) (GraphStats, error) {
+	var stats GraphStats
+	session := c.NewSession(ctx, neo4j.AccessModeRead)
+	defer session.Close(ctx)
+
+	encoder := json.NewEncoder(w)
+	params := map[string]interface{}{"clusterName": clusterName, "team": team}
+
+	nodeQuery := fmt.Sprintf(`
+		MATCH (n)
+		WHERE ($clusterName = '' OR n.clusterName = $clusterName) AND ($team = '' OR n.team = $team)
+		RETURN %s, labels(n), properties(n)`, c.ElementID("n"))
+	result, err := session.Run(ctx, nodeQuery, params)
+	if err != nil {
+		return stats, fmt.Errorf("failed to export nodes: %w", err)
+	}
+	for result.Next(ctx) {
+		values := result.Record().Values
+		record := ExportRecord{
+			Type:       ExportTypeNode,
+			ID:         values[0].(string),
+			Labels:     toStrings(values[1]),
+			Properties: values[2].(map[string]interface{}),
+		}
+		if err := encoder.Encode(record); err != nil {
+			return stats, fmt.Errorf("failed to write node: %w", err)
+		}
+		stats.Nodes++
+	}
+	if err := result.Err(); err != nil {
+		return stats, fmt.Errorf("failed to export nodes: %w", err)
+	}
+
+	relationshipQuery := fmt.Sprintf(`
+		MATCH (a)-[r]->(b)
+		WHERE ($clusterName = '' OR (a.clusterName = $clusterName AND b.clusterName = $clusterName))
+		  AND ($team = '' OR (a.team = $team AND b.team = $team))
+		RETURN %s, type(r), %s, %s, properties(r)`, c.ElementID("r"), c.ElementID("a"), c.ElementID("b"))
+	result, err = session.Run(ctx, relationshipQuery, params)
+	if err != nil {
+		return stats, fmt.Errorf("failed to export relationships: %w", err)
+	}
+	for result.Next(ctx) {
+		values := result.Record().Values
+		record := ExportRecord{
+			Type:       ExportTypeRelationship,
+			ID:         values[0].(string),
+			RelType:    values[1].(string),
+			Start:      values[2].(string),
+			End:        values[3].(string),
+			Properties: values[4].(map[string]interface{}),
+		}
+		if err := encoder.Encode(record); err != nil {
+			return stats, fmt.Errorf("failed to write relationship: %w", err)
+		}
+		stats.Relationships++
+	}
+	if err := result.Err(); err != nil {
+		return stats, fmt.Errorf("failed to export relationships: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ImportGraph bulk-loads an export file into database (the configured database
+// if empty) in batches of batchSize. Unless force is set, the target database
+// must be empty.
+func (c *Client) ImportGraph(ctx context.Context, r io.Reader, database string, batchSize int, force bool) (GraphStats, error) {
+	var stats GraphStats
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if database == "" {
+		database = c.config.Neo4j.Database
+	}
+	if database != "" && c.memgraph() {
+		return stats, fmt.Errorf("named databases are not supported by the memgraph backend")
+	}
+
+	session := c.primary.session(ctx, neo4j.SessionConfig{
+		AccessMode:   neo4j.AccessModeWrite,
+		DatabaseName: database,
+	})
+	defer session.Close(ctx)
+
+	if !force {
+		result, err := session.Run(ctx, "MATCH (n) RETURN count(n) > 0 AS hasNodes", nil)
+		if err != nil {
+			return stats, fmt.Errorf("failed to check target database: %w", err)
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("failed to check target database: %w", err)
+		}
+		if hasNodes, _ := record.Values[0].(bool); hasNodes {
+			return stats, fmt.Errorf("target database is not empty")
+		}
+	}
+
+	// run executes a schema or maintenance query in an auto-commit transaction
+	run := func(query string) error {
+		result, err := session.Run(ctx, query, nil)
+		if err != nil {
+			return err
+		}
+		_, err = result.Consume(ctx)
+		return err
+	}
+
+	// Imported nodes are temporarily labeled and indexed by their export ID so
+	// relationships can be attached to them
+	if c.memgraph() {
+		// Memgraph builds label-property indexes synchronously
+		if err := run(fmt.Sprintf("CREATE INDEX ON :%s(%s)", importLabel, importIDKey)); err != nil {
+			return stats, fmt.Errorf("failed to create import index: %w", err)
+		}
+	} else {
+		if err := run(fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (n:%s) ON (n.%s)", importIndex, importLabel, importIDKey)); err != nil {
+			return stats, fmt.Errorf("failed to create import index: %w", err)
+		}
+		if err := run("CALL db.awaitIndexes()"); err != nil {
+			return stats, fmt.Errorf("failed to wait for import index: %w", err)
+		}
+	}
+
+	batches := newImportBatches(batchSize)
+	flush := func(query string, rows []map[string]interface{}) error {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, query, map[string]interface{}{"rows": rows})
+			return nil, err
+		})
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), maxExportLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		record, err := decodeExportRecord(scanner.Bytes())
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		switch record.Type {
+		case ExportTypeNode:
+			if len(record.Labels) == 0 {
+				return stats, fmt.Errorf("line %d: node %s has no labels", line, record.ID)
+			}
+			properties := record.Properties
+			if properties == nil {
+				properties = map[string]interface{}{}
+			}
+			properties[importIDKey] = record.ID
+			if full := batches.add(ExportTypeNode, nodeImportQuery(record.Labels), properties); full != nil {
+				if err := flush(full.query, full.rows); err != nil {
+					return stats, fmt.Errorf("failed to import nodes: %w", err)
+				}
+			}
+			stats.Nodes++
+		case ExportTypeRelationship:
+			// Nodes precede relationships, so all nodes must exist before the
+			// first relationship is written
+			for _, batch := range batches.drainType(ExportTypeNode) {
+				if err := flush(batch.query, batch.rows); err != nil {
+					return stats, fmt.Errorf("failed to import nodes: %w", err)
+				}
+			}
+			properties := record.Properties
+			if properties == nil {
+				properties = map[string]interface{}{}
+			}
+			row := map[string]interface{}{
+				"start":      record.Start,
+				"end":        record.End,
+				"properties": properties,
+			}
+			if full := batches.add(ExportTypeRelationship, relationshipImportQuery(record.RelType), row); full != nil {
+				if err := flush(full.query, full.rows); err != nil {
+					return stats, fmt.Errorf("failed to import relationships: %w", err)
+				}
+			}
+			stats.Relationships++
+		default:
+			return stats, fmt.Errorf("line %d: unknown record type %q", line, record.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read export file: %w", err)
+	}
+
+	for _, batch := range batches.drainType(ExportTypeNode) {
+		if err := flush(batch.query, batch.rows); err != nil {
+			return stats, fmt.Errorf("failed to import nodes: %w", err)
+		}
+	}
+	for _, batch := range batches.drainType(ExportTypeRelationship) {
+		if err := flush(batch.query, batch.rows); err != nil {
+			return stats, fmt.Errorf("failed to import relationships: %w", err)
+		}
+	}
+
+	cleanup := fmt.Sprintf(`
+		MATCH (n:%s)
+		CALL { WITH n REMOVE n:%s, n.%s } IN TRANSACTIONS OF %d ROWS`, importLabel, importLabel, importIDKey, batchSize)
+	dropIndex := fmt.Sprintf("DROP INDEX %s IF EXISTS", importIndex)
+	if c.memgraph() {
+		// Memgraph has no batched subqueries
+		cleanup = fmt.Sprintf("USING PERIODIC COMMIT %d MATCH (n:%s) REMOVE n:%s, n.%s", batchSize, importLabel, importLabel, importIDKey)
+		dropIndex = fmt.Sprintf("DROP INDEX ON :%s(%s)", importLabel, importIDKey)
+	}
+	if err := run(cleanup); err != nil {
+		return stats, fmt.Errorf("failed to remove import markers: %w", err)
+	}
+	if err := run(dropIndex); err != nil {
+		return stats, fmt.Errorf("failed to drop import index: %w", err)
+	}
+
+	return stats, nil
+}
+
+// importBatch is a pending batch of rows for one query
+type importBatch struct {
+	recordType string
+	query      string
+	rows       []map[string]interface{}
+}
+
+// importBatches groups import rows by query, since labels and relationship
+// types cannot be parameterized
+type importBatches struct {
+	size    int
+	order   []string
+	pending map[string]*importBatch
+}
+
+func newImportBatches(size int) *importBatches {
+	return &importBatches{size: size, pending: make(map[string]*importBatch)}
+}
+
+// add appends a row to the batch of query and returns the batch once it is full
+func (b *importBatches) add(recordType, query string, row map[string]interface{}) *importBatch {
+	batch, ok := b.pending[query]
+	if !ok {
+		batch = &importBatch{recordType: recordType, query: query}
+		b.pending[query] = batch
+		b.order = append(b.order, query)
+	}
+	batch.rows = append(batch.rows, row)
+	if len(batch.rows) < b.size {
+		return nil
+	}
+	full := *batch
+	batch.rows = nil
+	return &full
+}
+
+// drainType removes and returns the non-empty pending batches of a record type
+func (b *importBatches) drainType(recordType string) []importBatch {
+	drained := make([]importBatch, 0)
+	order := b.order[:0]
+	for _, query := range b.order {
+		batch := b.pending[query]
+		if batch.recordType != recordType {
+			order = append(order, query)
+			continue
+		}
+		if len(batch.rows) > 0 {
+			drained = append(drained, *batch)
+		}
+		delete(b.pending, query)
+	}
+	b.order = order
+	return drained
+}
+
+// nodeImportQuery returns the query creating a batch of nodes with labels
+func nodeImportQuery(labels []string) string {
+	return fmt.Sprintf(`
+		UNWIND $rows AS row
+		CREATE (n:%s:%s)
+		SET n = row`, importLabel, quoteIdentifiers(labels, ":"))
+}
+
+// relationshipImportQuery returns the query creating a batch of relationships
+// of relType between imported nodes
+func relationshipImportQuery(relType string) string {
+	return fmt.Sprintf(`
+		UNWIND $rows AS row
+		MATCH (a:%s {%s: row.start})
+		MATCH (b:%s {%s: row.end})
+		CREATE (a)-[r:%s]->(b)
+		SET r = row.properties`, importLabel, importIDKey, importLabel, importIDKey, quoteIdentifiers([]string{relType}, ""))
+}
+
+// quoteIdentifiers backtick-quotes Cypher identifiers and joins them with sep
+func quoteIdentifiers(identifiers []string, sep string) string {
+	quoted := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		quoted[i] = "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+	}
+	return strings.Join(quoted, sep)
+}
+
+// decodeExportRecord parses one line of an export file, keeping integers as
+// int64 so they are not imported as floats
+func decodeExportRecord(data []byte) (ExportRecord, error) {
+	var record ExportRecord
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil {
+		return record, fmt.Errorf("invalid export record: %w", err)
+	}
+	for k, v := range record.Properties {
+		record.Properties[k] = normalizeNumber(v)
+	}
+	return record, nil
+}
+
+// normalizeNumber converts JSON numbers, also within lists, to int64 or float64
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeNumber(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// toStrings converts a list returned by the driver to strings
+func toStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		result = append(result, fmt.Sprintf("%v", item))
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+package neo4j
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/graph"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Entities of a mutation
+const (
+	EntityNode         = "node"
+	EntityRelationship = "relationship"
+)
+
+var feedDroppedTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Name: "neo4j_feed_dropped_total",
+		Help: "Total number of graph mutations dropped because a subscriber of the change feed was too slow",
+	},
+)
+
+// Mutation describes a write to the graph as published on the change feed.
+// For relationships, Kind is the relationship type and From and To identify
+// its ends as Label/value.
+type Mutation struct {
+	Entity      string    `json:"entity"`
+	Operation   string    `json:"operation"`
+	Kind        string    `json:"kind"`
+	UID         string    `json:"uid,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	From        string    `json:"from,omitempty"`
+	To          string    `json:"to,omitempty"`
+	ClusterName string    `json:"clusterName,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// mutationFeed fans out mutations to subscribers. Publishing never blocks
+// the writes: a subscriber whose buffer is full misses mutations.
+type mutationFeed struct {
+	mu          sync.Mutex
+	subscribers map[chan Mutation]struct{}
+}
+
+func newMutationFeed() *mutationFeed {
+	return &mutationFeed{subscribers: make(map[chan Mutation]struct{})}
+}
+
+// subscribe registers a subscriber with the given buffer and returns its
+// channel and the function unregistering it, which closes the channel
+func (f *mutationFeed) subscribe(buffer int) (<-chan Mutation, func()) {
+	ch := make(chan Mutation, buffer)
+	f.mu.Lock()
+	f.subscribers[ch] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subscribers, ch)
+			f.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// publish sends a mutation to every subscriber that has room for it
+func (f *mutationFeed) publish(mutation Mutation) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers {
+		select {
+		case ch <- mutation:
+		default:
+			feedDroppedTotal.Inc()
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the mutations written from now on,
+// and the function to call once done with it. Mutations are dropped for the
+// subscriber while buffer mutations are waiting to be received.
+func (c *Client) Subscribe(buffer int) (<-chan Mutation, func()) {
+	return c.feed.subscribe(buffer)
+}
+
+// publishNode publishes a mutation of a resource node
+func (c *Client) publishNode(kind, uid, name, namespace, operation string) {
+	c.feed.publish(Mutation{
+		Entity:      EntityNode,
+		Operation:   operation,
+		Kind:        kind,
+		UID:         uid,
+		Name:        name,
+		Namespace:   namespace,
+		ClusterName: c.clusterName(),
+		Timestamp:   time.Now().UTC(),
+	})
+}
+
+// relationshipChange is a relationship created or deleted by a write, with
+// its ends as Label/value
+type relationshipChange struct {
+	Operation string
+	Type      string
+	From      string
+	To        string
+}
+
+// publishRelationship publishes the creation of a relationship
+func (c *Client) publishRelationship(r graph.Relationship) {
+	c.publishRelationshipChange(relationshipChange{
+		Operation: OperationUpsert,
+		Type:      r.Type,
+		From:      fmt.Sprintf("%s/%s", r.FromLabel, r.FromValue),
+		To:        fmt.Sprintf("%s/%s", r.ToLabel, r.ToValue),
+	})
+}
+
+// publishRelationshipChange publishes the creation or deletion of a relationship
+func (c *Client) publishRelationshipChange(change relationshipChange) {
+	c.feed.publish(Mutation{
+		Entity:      EntityRelationship,
+		Operation:   change.Operation,
+		Kind:        change.Type,
+		From:        change.From,
+		To:          change.To,
+		ClusterName: c.clusterName(),
+		Timestamp:   time.Now().UTC(),
+	})
+}
+
+// statementChanges returns the relationship changes of the rows of a
+// statement, which returns them in graph.ChangeColumn
+func statementChanges(rows []map[string]interface{}) []relationshipChange {
+	var changes []relationshipChange
+	for _, row := range rows {
+		change, ok := row[graph.ChangeColumn].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		changes = append(changes, relationshipChange{
+			Operation: fmt.Sprint(change["operation"]),
+			Type:      fmt.Sprint(change["type"]),
+			From:      fmt.Sprint(change["from"]),
+			To:        fmt.Sprint(change["to"]),
+		})
+	}
+	return changes
+}
+
+// publishNetChanges publishes the relationship changes of a transaction. A
+// relationship deleted and created again by the transaction did not change,
+// nor did one created and deleted again, so only the relationships whose first
+// and last change agree are published.
+func (c *Client) publishNetChanges(changes []relationshipChange) {
+	type key struct{ Type, From, To string }
+	first := make(map[key]string, len(changes))
+	last := make(map[key]string, len(changes))
+	var order []key
+	for _, change := range changes {
+		k := key{change.Type, change.From, change.To}
+		if _, seen := first[k]; !seen {
+			first[k] = change.Operation
+			order = append(order, k)
+		}
+		last[k] = change.Operation
+	}
+	for _, k := range order {
+		if first[k] == last[k] {
+			c.publishRelationshipChange(relationshipChange{Operation: first[k], Type: k.Type, From: k.From, To: k.To})
+		}
+	}
+}
+
+// clusterName returns the name of the cluster the client writes
+func (c *Client) clusterName() string {
+	if c.config == nil {
+		return ""
+	}
+	return c.config.Kubernetes.ClusterName
+}
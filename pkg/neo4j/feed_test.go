@@ -0,0 +1,84 @@
+package neo4j
+
+import (
+	"testing"
+
+	"k8s-graph/pkg/graph"
+)
+
+func TestMutationFeed(t *testing.T) {
+	client := &Client{feed: newMutationFeed()}
+	fast, unsubscribeFast := client.Subscribe(10)
+	defer unsubscribeFast()
+	slow, unsubscribeSlow := client.Subscribe(1)
+
+	client.publishNode("Pod", "uid-1", "api-0", "payments", OperationUpsert)
+	client.publishRelationship(graph.Relationship{FromLabel: "Pod", FromKey: "uid", FromValue: "uid-1",
+		Type: "SCHEDULED_ON", ToLabel: "Node", ToKey: "name", ToValue: "worker-1"})
+
+	if len(fast) != 2 {
+		t.Fatalf("Expected 2 mutations, got %d", len(fast))
+	}
+	node := <-fast
+	if node.Entity != EntityNode || node.Kind != "Pod" || node.UID != "uid-1" || node.Operation != OperationUpsert {
+		t.Errorf("Unexpected node mutation %+v", node)
+	}
+	relationship := <-fast
+	if relationship.Entity != EntityRelationship || relationship.Kind != "SCHEDULED_ON" ||
+		relationship.From != "Pod/uid-1" || relationship.To != "Node/worker-1" {
+		t.Errorf("Unexpected relationship mutation %+v", relationship)
+	}
+
+	// The slow subscriber misses what does not fit in its buffer
+	if len(slow) != 1 {
+		t.Errorf("Expected the slow subscriber to keep 1 mutation, got %d", len(slow))
+	}
+	unsubscribeSlow()
+	unsubscribeSlow()
+	client.publishNode("Pod", "uid-1", "api-0", "payments", OperationDelete)
+	<-slow
+	if _, open := <-slow; open {
+		t.Error("Expected the channel to be closed once unsubscribed")
+	}
+}
+
+func TestMutationFeedNil(t *testing.T) {
+	// Clients created without a feed do not publish
+	client := &Client{}
+	client.publishNode("Pod", "uid-1", "api-0", "payments", OperationUpsert)
+}
+
+func TestPublishNetChanges(t *testing.T) {
+	client := &Client{feed: newMutationFeed()}
+	mutations, unsubscribe := client.Subscribe(10)
+	defer unsubscribe()
+
+	rows := []map[string]interface{}{
+		{graph.ChangeColumn: map[string]interface{}{"operation": graph.ChangeDelete, "type": "TOLERATES", "from": "Pod/uid-1", "to": "Node/node-1"}},
+		{graph.ChangeColumn: map[string]interface{}{"operation": graph.ChangeDelete, "type": "TOLERATES", "from": "Pod/uid-1", "to": "Node/node-2"}},
+		{"other": "ignored"},
+	}
+	changes := statementChanges(rows)
+	changes = append(changes, statementChanges([]map[string]interface{}{
+		{graph.ChangeColumn: map[string]interface{}{"operation": graph.ChangeUpsert, "type": "TOLERATES", "from": "Pod/uid-1", "to": "Node/node-1"}},
+		{graph.ChangeColumn: map[string]interface{}{"operation": graph.ChangeUpsert, "type": "TOLERATES", "from": "Pod/uid-1", "to": "Node/node-3"}},
+	})...)
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changes, got %+v", changes)
+	}
+	client.publishNetChanges(changes)
+
+	// The relationship to node-1 was replaced, so it did not change
+	if len(mutations) != 2 {
+		t.Fatalf("Expected 2 mutations, got %d", len(mutations))
+	}
+	deleted := <-mutations
+	if deleted.Entity != EntityRelationship || deleted.Operation != OperationDelete || deleted.Kind != "TOLERATES" ||
+		deleted.From != "Pod/uid-1" || deleted.To != "Node/node-2" {
+		t.Errorf("Unexpected deletion %+v", deleted)
+	}
+	created := <-mutations
+	if created.Operation != OperationUpsert || created.To != "Node/node-3" {
+		t.Errorf("Unexpected creation %+v", created)
+	}
+}
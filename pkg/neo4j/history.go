@@ -0,0 +1,214 @@
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s-graph/config"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// historyEnabled reports whether updates should also be recorded as versions
+func (c *Client) historyEnabled() bool {
+	return c.config != nil && c.config.History.Enabled
+}
+
+// isWatchedResource reports whether a node represents a watched Kubernetes
+// resource, which carries an instanceHash. Events, anomalies and other records
+// are historical already and are neither versioned nor audited.
+func isWatchedResource(properties map[string]interface{}) bool {
+	instanceHash, _ := properties["instanceHash"].(string)
+	return instanceHash != ""
+}
+
+// versionHash returns the hash of a node's content, ignoring the instance
+// hash so that restarts do not create new versions
+func versionHash(properties map[string]interface{}) string {
+	content := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		if k != "instanceHash" {
+			content[k] = v
+		}
+	}
+	return hashProperties(content)
+}
+
+// changedKeys returns the sorted property keys that differ between two versions
+func changedKeys(previous, current map[string]interface{}) []string {
+	keys := make([]string, 0)
+	for k, v := range current {
+		if k == "instanceHash" {
+			continue
+		}
+		if prev, ok := previous[k]; !ok || fmt.Sprintf("%v", prev) != fmt.Sprintf("%v", v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range previous {
+		if _, ok := current[k]; !ok && k != "instanceHash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordVersion closes the open version of a node and appends a new one,
+// unless the node's content did not change since the open version
+func (c *Client) recordVersion(ctx context.Context, labels []string, properties map[string]interface{}, uniqueKey string) error {
+	if !c.historyEnabled() || len(labels) == 0 || !isWatchedResource(properties) {
+		return nil
+	}
+
+	kind := labels[0]
+	resourceUID := fmt.Sprintf("%v", properties[uniqueKey])
+	hash := versionHash(properties)
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return fmt.Errorf("failed to marshal version properties: %w", err)
+	}
+
+	return c.executeWithMetrics(ctx, "record_version", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			params := map[string]interface{}{
+				"kind":        kind,
+				"resourceUid": resourceUID,
+			}
+
+			result, err := tx.Run(ctx, `
+				MATCH (v:ResourceVersion {kind: $kind, resourceUid: $resourceUid})
+				WHERE v.validTo IS NULL
+				RETURN v.contentHash AS contentHash, v.properties AS properties
+				LIMIT 1`, params)
+			if err != nil {
+				return nil, err
+			}
+
+			previous := map[string]interface{}{}
+			if result.Next(ctx) {
+				record := result.Record()
+				if previousHash, _ := record.Values[0].(string); previousHash == hash {
+					return nil, nil
+				}
+				if previousData, ok := record.Values[1].(string); ok {
+					json.Unmarshal([]byte(previousData), &previous)
+				}
+			}
+
+			now := time.Now().UTC()
+			params["now"] = now.Format(time.RFC3339)
+			params["version"] = map[string]interface{}{
+				"uid":         fmt.Sprintf("%s/%s/%d", kind, resourceUID, now.UnixNano()),
+				"kind":        kind,
+				"resourceUid": resourceUID,
+				"name":        properties["name"],
+				"namespace":   properties["namespace"],
+				"clusterName": properties["clusterName"],
+				"validFrom":   params["now"],
+				"contentHash": hash,
+				"changedKeys": changedKeys(previous, properties),
+				"properties":  string(data),
+			}
+
+			_, err = tx.Run(ctx, `
+				OPTIONAL MATCH (prev:ResourceVersion {kind: $kind, resourceUid: $resourceUid})
+				WHERE prev.validTo IS NULL
+				WITH collect(prev) AS open
+				FOREACH (p IN open | SET p.validTo = $now)
+				CREATE (v:ResourceVersion)
+				SET v = $version`, params)
+			return nil, err
+		})
+		return err
+	})
+}
+
+// CloseVersion marks the open version of a deleted resource as ending now
+func (c *Client) CloseVersion(ctx context.Context, label, uid string) error {
+	if !c.historyEnabled() {
+		return nil
+	}
+
+	return c.executeWithMetrics(ctx, "close_version", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		query := `
+			MATCH (v:ResourceVersion {kind: $kind, resourceUid: $resourceUid})
+			WHERE v.validTo IS NULL
+			SET v.validTo = $now`
+		params := map[string]interface{}{
+			"kind":        label,
+			"resourceUid": uid,
+			"now":         time.Now().UTC().Format(time.RFC3339),
+		}
+
+		_, err := session.Run(ctx, query, params)
+		return err
+	})
+}
+
+// PruneHistory deletes versions that ended more than the retention of their
+// kind ago: the HistoryDays of its retention rule, or retentionDays for kinds
+// without one
+func (c *Client) PruneHistory(ctx context.Context, retentionDays int, rules []config.RetentionRule) error {
+	now := time.Now().UTC()
+	defaultCutoff, cutoffs := historyCutoffs(now, retentionDays, rules)
+	if defaultCutoff == "" && len(cutoffs) == 0 {
+		return nil
+	}
+
+	return c.executeWithMetrics(ctx, "prune_history", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		// An empty cutoff keeps the versions of the kind forever
+		query := `
+			MATCH (v:ResourceVersion)
+			WHERE v.validTo IS NOT NULL
+			WITH v, coalesce($cutoffs[v.kind], $defaultCutoff) AS cutoff
+			WHERE cutoff <> '' AND v.validTo < cutoff
+			WITH v.kind AS kind, collect(v) AS versions
+			FOREACH (v IN versions | DELETE v)
+			RETURN kind, size(versions) AS pruned`
+		result, err := session.Run(ctx, query, map[string]interface{}{
+			"cutoffs":       cutoffs,
+			"defaultCutoff": defaultCutoff,
+		})
+		if err != nil {
+			return err
+		}
+		for result.Next(ctx) {
+			kind, _ := result.Record().Values[0].(string)
+			pruned, _ := result.Record().Values[1].(int64)
+			RecordPruned(kind, "history", pruned)
+		}
+		return result.Err()
+	})
+}
+
+// historyCutoffs returns the validTo before which versions are pruned, for the
+// kinds without a retention rule and by kind, empty where versions are kept
+// forever
+func historyCutoffs(now time.Time, retentionDays int, rules []config.RetentionRule) (string, map[string]interface{}) {
+	cutoff := func(days int) string {
+		if days <= 0 {
+			return ""
+		}
+		return now.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+	}
+	cutoffs := make(map[string]interface{})
+	for _, rule := range rules {
+		if rule.HistoryDays != nil {
+			cutoffs[rule.Kind] = cutoff(*rule.HistoryDays)
+		}
+	}
+	return cutoff(retentionDays), cutoffs
+}
@@ -0,0 +1,170 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/version"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// SchemaVersion is the version of the data model written by this release
+const SchemaVersion = 2
+
+// MetaLabel is the label of the node recording the schema version of the graph
+const MetaLabel = "KubeGraphMeta"
+
+// ErrSchemaTooNew is returned when the graph was migrated by a later release,
+// whose data model this release would corrupt
+var ErrSchemaTooNew = errors.New("graph schema is newer than this release")
+
+// Migration upgrades the graph from the previous schema version to Version
+type Migration struct {
+	Version     int
+	Description string
+	// Statements run in one write transaction together with the update of the
+	// schema version, so a failed migration is retried as a whole on the next
+	// start
+	Statements []string
+}
+
+// migrations upgrade the data model one version at a time. A change to the
+// labels, properties or relationship types written by the handlers adds a
+// migration converting the existing graph and increments SchemaVersion.
+var migrations = []Migration{
+	{Version: 1, Description: "Record the schema version of graphs written before versioning"},
+	{
+		Version:     2,
+		Description: "Remove the PVC to StatefulSet relationships guessed from the names of the Neo4j operator",
+		// The graph may hold other clusters, whose agents do not resync when
+		// this one migrates, so the relationships the handlers would write
+		// again are kept: those of owner references, and those of claims named
+		// <template>-<statefulset>-<ordinal> after a volumeClaimTemplate
+		Statements: []string{`
+			MATCH (pvc:PersistentVolumeClaim)-[r:USED_BY]->(sts:StatefulSet)
+			WHERE r.enricher IS NULL AND NOT (pvc)-[:OWNED_BY]->(sts)
+			WITH r, pvc, sts, split(pvc.name, '-') AS parts
+			WITH r, pvc, sts, CASE WHEN size(parts) > 1 AND parts[size(parts) - 1] =~ '[0-9]+'
+				THEN substring(pvc.name, 0, size(pvc.name) - size(parts[size(parts) - 1]) - 1) END AS base
+			WITH r, pvc, sts, CASE WHEN base ENDS WITH '-' + sts.name AND size(base) > size(sts.name) + 1
+				THEN substring(base, 0, size(base) - size(sts.name) - 1) END AS template
+			WHERE template IS NULL OR pvc.namespace <> sts.namespace OR pvc.clusterName <> sts.clusterName
+				OR NOT coalesce(sts.volumeClaimTemplates, '') CONTAINS '"' + template + '"'
+			DELETE r`,
+		},
+	},
+}
+
+// pendingMigrations returns the migrations upgrading a graph at version current
+func pendingMigrations(current int) []Migration {
+	var pending []Migration
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// GetSchemaVersion returns the schema version recorded in the graph, 0 when
+// none is recorded
+func (c *Client) GetSchemaVersion(ctx context.Context) (int, error) {
+	result, err := c.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, fmt.Sprintf("MATCH (m:%s) RETURN m.schemaVersion AS version", MetaLabel), nil)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Next(ctx) {
+			return int64(0), result.Err()
+		}
+		version, _ := result.Record().Values[0].(int64)
+		return version, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the schema version: %w", err)
+	}
+	return int(result.(int64)), nil
+}
+
+// Migrate upgrades the graph to SchemaVersion and returns the migrations it
+// applied. An empty graph is only stamped with the version. It fails with
+// ErrSchemaTooNew when a later release already migrated the graph.
+func (c *Client) Migrate(ctx context.Context) ([]Migration, error) {
+	current, err := c.GetSchemaVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current > SchemaVersion {
+		return nil, fmt.Errorf("%w: the graph has schema version %d, this release writes version %d", ErrSchemaTooNew, current, SchemaVersion)
+	}
+	if current == SchemaVersion {
+		return nil, nil
+	}
+
+	if current == 0 {
+		empty, err := c.isEmpty(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if empty {
+			_, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+				return nil, setSchemaVersion(ctx, tx, SchemaVersion)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to record the schema version: %w", err)
+			}
+			return nil, nil
+		}
+	}
+
+	pending := pendingMigrations(current)
+	for i, migration := range pending {
+		_, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			for _, statement := range migration.Statements {
+				if _, err := tx.Run(ctx, statement, nil); err != nil {
+					return nil, err
+				}
+			}
+			return nil, setSchemaVersion(ctx, tx, migration.Version)
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("failed to migrate the graph to schema version %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		logger.Info("Migrated the graph to schema version %d: %s", migration.Version, migration.Description)
+	}
+	return pending, nil
+}
+
+// isEmpty reports whether the graph holds no node besides the schema version
+func (c *Client) isEmpty(ctx context.Context) (bool, error) {
+	result, err := c.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, fmt.Sprintf("MATCH (n) WHERE NOT n:%s RETURN n LIMIT 1", MetaLabel), nil)
+		if err != nil {
+			return nil, err
+		}
+		found := result.Next(ctx)
+		return !found, result.Err()
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether the graph is empty: %w", err)
+	}
+	return result.(bool), nil
+}
+
+// setSchemaVersion records the schema version of the graph and the release
+// that wrote it
+func setSchemaVersion(ctx context.Context, tx neo4j.ManagedTransaction, schemaVersion int) error {
+	_, err := tx.Run(ctx, fmt.Sprintf(`
+		MERGE (m:%s {id: "schema"})
+		SET m.schemaVersion = $version, m.release = $release, m.migratedAt = $migratedAt`, MetaLabel),
+		map[string]interface{}{
+			"version":    schemaVersion,
+			"release":    version.Version,
+			"migratedAt": time.Now().UTC().Format(time.RFC3339),
+		})
+	return err
+}
@@ -0,0 +1,26 @@
+package neo4j
+
+import "testing"
+
+func TestMigrationsAreConsecutive(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, expected %d", i, migration.Version, i+1)
+		}
+		if migration.Description == "" {
+			t.Errorf("migration to version %d has no description", migration.Version)
+		}
+	}
+	if last := migrations[len(migrations)-1].Version; last != SchemaVersion {
+		t.Errorf("the last migration upgrades to version %d, but SchemaVersion is %d", last, SchemaVersion)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	if pending := pendingMigrations(0); len(pending) != len(migrations) {
+		t.Errorf("expected every migration for an unversioned graph, got %d", len(pending))
+	}
+	if pending := pendingMigrations(SchemaVersion); len(pending) != 0 {
+		t.Errorf("expected no migration for a current graph, got %v", pending)
+	}
+}
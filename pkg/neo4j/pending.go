@@ -0,0 +1,247 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	neo4jPendingRelationships = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "neo4j_pending_relationships",
+			Help: "Number of relationships waiting for one of their nodes to be written",
+		},
+	)
+
+	neo4jPendingRelationshipsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "neo4j_pending_relationships_total",
+			Help: "Total number of relationships deferred because a node was missing, by outcome: deferred, resolved, expired or dropped",
+		},
+		[]string{"outcome"},
+	)
+)
+
+// pendingRelationship is a relationship whose nodes did not both exist when
+// it was created
+type pendingRelationship struct {
+	relationship graph.Relationship
+	deferred     time.Time
+}
+
+// pendingRelationships holds the relationships waiting for a missing node. A
+// relationship is retried when a node matching either of its ends is written,
+// since the query creating it does not tell which end was missing.
+type pendingRelationships struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*pendingRelationship
+	ends     map[string]map[string]bool // relationship keys by the key of their end nodes
+	keys     map[string]map[string]int  // properties the ends of each label are matched on, with their use count
+	expired  time.Time                  // last time the expired relationships were dropped
+	now      func() time.Time
+}
+
+// newPendingRelationships creates a queue of up to capacity relationships
+// retried for ttl. It returns nil when deferring is disabled.
+func newPendingRelationships(capacity int, ttl time.Duration) *pendingRelationships {
+	if capacity <= 0 {
+		return nil
+	}
+	return &pendingRelationships{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*pendingRelationship),
+		ends:     make(map[string]map[string]bool),
+		keys:     make(map[string]map[string]int),
+		now:      time.Now,
+	}
+}
+
+func relationshipKey(r graph.Relationship) string {
+	return fmt.Sprintf("%s{%s=%s}-%s->%s{%s=%s}", r.FromLabel, r.FromKey, r.FromValue, r.Type, r.ToLabel, r.ToKey, r.ToValue)
+}
+
+func endKey(label, key string, value interface{}) string {
+	return fmt.Sprintf("%s{%s=%v}", label, key, value)
+}
+
+// add defers r until one of its nodes is written. When full, the oldest
+// relationship is dropped.
+func (p *pendingRelationships) add(r graph.Relationship) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := relationshipKey(r)
+	if entry, ok := p.entries[key]; ok {
+		// Keep the original time so that a relationship retried by every
+		// resync still expires
+		entry.relationship = r
+		return
+	}
+	p.expire()
+	if len(p.entries) >= p.capacity {
+		p.removeOldest()
+	}
+
+	p.entries[key] = &pendingRelationship{relationship: r, deferred: p.now()}
+	p.index(key, r.FromLabel, r.FromKey, r.FromValue)
+	p.index(key, r.ToLabel, r.ToKey, r.ToValue)
+	neo4jPendingRelationshipsTotal.WithLabelValues("deferred").Inc()
+	neo4jPendingRelationships.Set(float64(len(p.entries)))
+}
+
+// resolved drops r once it was created
+func (p *pendingRelationships) resolved(r graph.Relationship) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.remove(relationshipKey(r)) {
+		neo4jPendingRelationshipsTotal.WithLabelValues("resolved").Inc()
+	}
+}
+
+// matching returns the relationships with an end matching the node of label
+// with properties. They stay pending until created.
+func (p *pendingRelationships) matching(label string, properties map[string]interface{}) []graph.Relationship {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var matching []graph.Relationship
+	for key := range p.keys[label] {
+		value, ok := properties[key]
+		if !ok {
+			continue
+		}
+		for relKey := range p.ends[endKey(label, key, value)] {
+			matching = append(matching, p.entries[relKey].relationship)
+		}
+	}
+	return matching
+}
+
+// len returns the number of pending relationships
+func (p *pendingRelationships) len() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
+
+func (p *pendingRelationships) index(relKey, label, key string, value interface{}) {
+	end := endKey(label, key, value)
+	if p.ends[end] == nil {
+		p.ends[end] = make(map[string]bool)
+	}
+	p.ends[end][relKey] = true
+	if p.keys[label] == nil {
+		p.keys[label] = make(map[string]int)
+	}
+	p.keys[label][key]++
+}
+
+func (p *pendingRelationships) unindex(relKey, label, key string, value interface{}) {
+	end := endKey(label, key, value)
+	delete(p.ends[end], relKey)
+	if len(p.ends[end]) == 0 {
+		delete(p.ends, end)
+	}
+	p.keys[label][key]--
+	if p.keys[label][key] <= 0 {
+		delete(p.keys[label], key)
+	}
+	if len(p.keys[label]) == 0 {
+		delete(p.keys, label)
+	}
+}
+
+// remove drops the relationship with key and reports whether it was pending
+func (p *pendingRelationships) remove(key string) bool {
+	entry, ok := p.entries[key]
+	if !ok {
+		return false
+	}
+	r := entry.relationship
+	delete(p.entries, key)
+	p.unindex(key, r.FromLabel, r.FromKey, r.FromValue)
+	p.unindex(key, r.ToLabel, r.ToKey, r.ToValue)
+	neo4jPendingRelationships.Set(float64(len(p.entries)))
+	return true
+}
+
+// expire drops the relationships deferred for longer than the TTL; their
+// nodes are not expected to be written anymore. The relationships are scanned
+// at most once a second.
+func (p *pendingRelationships) expire() {
+	now := p.now()
+	if p.ttl <= 0 || now.Sub(p.expired) < time.Second {
+		return
+	}
+	p.expired = now
+	cutoff := now.Add(-p.ttl)
+	for key, entry := range p.entries {
+		if entry.deferred.Before(cutoff) {
+			logger.Debug("Dropping relationship %s, a node is still missing after %v", key, p.ttl)
+			p.remove(key)
+			neo4jPendingRelationshipsTotal.WithLabelValues("expired").Inc()
+		}
+	}
+}
+
+func (p *pendingRelationships) removeOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range p.entries {
+		if oldestKey == "" || entry.deferred.Before(oldest) {
+			oldestKey, oldest = key, entry.deferred
+		}
+	}
+	if oldestKey != "" {
+		p.remove(oldestKey)
+		neo4jPendingRelationshipsTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// settleRelationship defers a relationship whose nodes did not both exist, or
+// drops it from the pending relationships once it was written, publishing it
+// when it did not exist before
+func (c *Client) settleRelationship(r graph.Relationship, matched, created bool) {
+	if !matched {
+		c.pending.add(r)
+		return
+	}
+	c.pending.resolved(r)
+	if created {
+		c.publishRelationship(r)
+	}
+}
+
+// retryPending creates the relationships deferred until the node of label
+// with properties was written. Those whose other node is still missing stay
+// pending.
+func (c *Client) retryPending(ctx context.Context, label string, properties map[string]interface{}) {
+	for _, r := range c.pending.matching(label, properties) {
+		if err := c.UpsertRelationship(ctx, r); err != nil {
+			logger.Warn("Failed to create deferred relationship %s: %v", relationshipKey(r), err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package neo4j
+
+import (
+	"testing"
+	"time"
+
+	"k8s-graph/pkg/graph"
+)
+
+func TestPendingRelationships(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	p := newPendingRelationships(2, 10*time.Minute)
+	p.now = func() time.Time { return now }
+
+	bound := graph.Relationship{FromLabel: "PersistentVolumeClaim", FromKey: "uid", FromValue: "pvc-1",
+		Type: "BOUND_TO", ToLabel: "PersistentVolume", ToKey: "name", ToValue: "pv-1"}
+	p.add(bound)
+	p.add(bound)
+	if p.len() != 1 {
+		t.Fatalf("expected 1 pending relationship, got %d", p.len())
+	}
+
+	if matching := p.matching("PersistentVolume", map[string]interface{}{"name": "pv-2"}); len(matching) != 0 {
+		t.Errorf("expected no relationship for another volume, got %v", matching)
+	}
+	if matching := p.matching("PersistentVolume", map[string]interface{}{"name": "pv-1", "uid": "u"}); len(matching) != 1 || matching[0] != bound {
+		t.Errorf("expected the BOUND_TO relationship, got %v", matching)
+	}
+	if matching := p.matching("PersistentVolumeClaim", map[string]interface{}{"uid": "pvc-1"}); len(matching) != 1 {
+		t.Errorf("expected the relationship to match its source too, got %v", matching)
+	}
+
+	p.resolved(bound)
+	if p.len() != 0 || len(p.keys) != 0 || len(p.ends) != 0 {
+		t.Errorf("expected the resolved relationship to be dropped, got %d entries, %v, %v", p.len(), p.keys, p.ends)
+	}
+}
+
+func TestPendingRelationshipsEviction(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	p := newPendingRelationships(2, 10*time.Minute)
+	p.now = func() time.Time { return now }
+
+	owned := func(pod string) graph.Relationship {
+		return graph.Relationship{FromLabel: "Pod", FromKey: "uid", FromValue: pod,
+			Type: "OWNED_BY", ToLabel: "ReplicaSet", ToKey: "uid", ToValue: "rs-1"}
+	}
+	p.add(owned("a"))
+	now = now.Add(time.Minute)
+	p.add(owned("b"))
+	now = now.Add(time.Minute)
+	p.add(owned("c"))
+	if p.len() != 2 {
+		t.Fatalf("expected the capacity to be kept, got %d", p.len())
+	}
+	if matching := p.matching("Pod", map[string]interface{}{"uid": "a"}); len(matching) != 0 {
+		t.Error("expected the oldest relationship to be dropped")
+	}
+
+	now = now.Add(20 * time.Minute)
+	p.add(owned("d"))
+	if p.len() != 1 {
+		t.Errorf("expected the expired relationships to be dropped, got %d", p.len())
+	}
+
+	if newPendingRelationships(0, time.Minute) != nil {
+		t.Error("expected deferring to be disabled without capacity")
+	}
+	var disabled *pendingRelationships
+	disabled.add(owned("e"))
+	if disabled.matching("Pod", map[string]interface{}{"uid": "e"}) != nil {
+		t.Error("expected nothing to be pending when disabled")
+	}
+}
@@ -0,0 +1,82 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"k8s-graph/config"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+const (
+	// PoolPrimary serves writes, and reads unless a read pool is configured
+	PoolPrimary = "primary"
+	// PoolRead serves the read sessions of queries when configured
+	PoolRead = "read"
+)
+
+// pool is a driver and the sessions open on it
+type pool struct {
+	name   string
+	driver neo4j.DriverWithContext
+	size   int
+	inUse  atomic.Int64
+	dryRun *dryRun // skips the write statements of write sessions, nil when disabled
+}
+
+// session opens a session on the pool and counts it until it is closed
+func (p *pool) session(ctx context.Context, sessionConfig neo4j.SessionConfig) neo4j.SessionWithContext {
+	p.inUse.Add(1)
+	session := p.driver.NewSession(ctx, sessionConfig)
+	if p.dryRun != nil && sessionConfig.AccessMode == neo4j.AccessModeWrite {
+		session = &dryRunSession{SessionWithContext: session, dryRun: p.dryRun}
+	}
+	return &pooledSession{SessionWithContext: session, pool: p}
+}
+
+// pooledSession is a session that releases its pool slot when closed
+type pooledSession struct {
+	neo4j.SessionWithContext
+	pool   *pool
+	closed atomic.Bool
+}
+
+// Close closes the session
+func (s *pooledSession) Close(ctx context.Context) error {
+	if s.closed.CompareAndSwap(false, true) {
+		s.pool.inUse.Add(-1)
+	}
+	return s.SessionWithContext.Close(ctx)
+}
+
+// readPoolEnabled reports whether reads get their own pool: when a read URI,
+// such as the address of read replicas, or a read pool size is configured
+func readPoolEnabled(cfg *config.Config) bool {
+	return cfg.Neo4j.ReadURI != "" || cfg.Neo4j.ReadMaxConnectionPoolSize > 0
+}
+
+// newReadPool creates the read pool, which uses the URI and pool size of the
+// primary pool when only one of them is overridden
+func newReadPool(ctx context.Context, cfg *config.Config, creds *credentials, driverConfig neo4j.Config) (*pool, error) {
+	uri := cfg.Neo4j.URI
+	if cfg.Neo4j.ReadURI != "" {
+		uri = cfg.Neo4j.ReadURI
+	}
+	if cfg.Neo4j.ReadMaxConnectionPoolSize > 0 {
+		driverConfig.MaxConnectionPoolSize = cfg.Neo4j.ReadMaxConnectionPoolSize
+	}
+
+	driver, err := neo4j.NewDriverWithContext(driverURI(cfg, uri), creds, func(config *neo4j.Config) {
+		*config = driverConfig
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create neo4j read driver: %w", err)
+	}
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		driver.Close(ctx)
+		return nil, fmt.Errorf("failed to verify neo4j read connectivity: %w", err)
+	}
+	return &pool{name: PoolRead, driver: driver, size: driverConfig.MaxConnectionPoolSize}, nil
+}
@@ -0,0 +1,65 @@
+package neo4j
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/config"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// fakeSession is a session that only records whether it was closed
+type fakeSession struct {
+	neo4j.SessionWithContext
+	closed int
+}
+
+func (s *fakeSession) Close(ctx context.Context) error {
+	s.closed++
+	return nil
+}
+
+func TestPooledSession(t *testing.T) {
+	p := &pool{name: PoolRead, size: 10}
+	p.inUse.Add(1)
+	inner := &fakeSession{}
+	session := &pooledSession{SessionWithContext: inner, pool: p}
+
+	session.Close(context.Background())
+	session.Close(context.Background())
+	if got := p.inUse.Load(); got != 0 {
+		t.Errorf("Expected closing twice to release the session once, got %d in use", got)
+	}
+	if inner.closed != 2 {
+		t.Errorf("Expected the session to be closed, got %d calls", inner.closed)
+	}
+}
+
+func TestReadPoolEnabled(t *testing.T) {
+	cfg := config.NewConfig()
+	if readPoolEnabled(cfg) {
+		t.Error("Expected reads to share the pool by default")
+	}
+	cfg.Neo4j.ReadMaxConnectionPoolSize = 10
+	if !readPoolEnabled(cfg) {
+		t.Error("Expected a read pool size to enable the read pool")
+	}
+	cfg = config.NewConfig()
+	cfg.Neo4j.ReadURI = "neo4j://replicas:7687"
+	if !readPoolEnabled(cfg) {
+		t.Error("Expected a read URI to enable the read pool")
+	}
+}
+
+func TestPools(t *testing.T) {
+	primary := &pool{name: PoolPrimary}
+	shared := &Client{primary: primary, read: primary}
+	if len(shared.pools()) != 1 {
+		t.Errorf("Expected a shared pool to be reported once, got %d", len(shared.pools()))
+	}
+	separate := &Client{primary: primary, read: &pool{name: PoolRead}}
+	if len(separate.pools()) != 2 {
+		t.Errorf("Expected both pools, got %d", len(separate.pools()))
+	}
+}
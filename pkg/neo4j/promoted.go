@@ -0,0 +1,157 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// Prefixes of the properties holding promoted label and annotation values
+const (
+	promotedLabelPrefix      = "label_"
+	promotedAnnotationPrefix = "annotation_"
+)
+
+// LabelProperty returns the node property holding the value of a promoted
+// label key, e.g. label_app_kubernetes_io_name for app.kubernetes.io/name
+func LabelProperty(key string) string {
+	return promotedLabelPrefix + propertySafe(key)
+}
+
+// AnnotationProperty returns the node property holding the value of a
+// promoted annotation key
+func AnnotationProperty(key string) string {
+	return promotedAnnotationPrefix + propertySafe(key)
+}
+
+// propertySafe replaces the characters of a label or annotation key that may
+// not appear in an unquoted property name
+func propertySafe(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// promoted copies the values of selected label and annotation keys into
+// properties of their own, which unlike the labels and annotations JSON
+// strings can be indexed and matched exactly
+type promoted struct {
+	labels      map[string]string // property by label key
+	annotations map[string]string // property by annotation key
+}
+
+// newPromoted returns nil when no key is promoted
+func newPromoted(labels, annotations []string) *promoted {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return nil
+	}
+	p := &promoted{labels: make(map[string]string), annotations: make(map[string]string)}
+	for _, key := range labels {
+		p.labels[key] = LabelProperty(key)
+	}
+	for _, key := range annotations {
+		p.annotations[key] = AnnotationProperty(key)
+	}
+	return p
+}
+
+// properties returns the promoted properties, sorted
+func (p *promoted) properties() []string {
+	var properties []string
+	for _, keys := range []map[string]string{p.labels, p.annotations} {
+		for _, property := range keys {
+			properties = append(properties, property)
+		}
+	}
+	slices.Sort(properties)
+	return slices.Compact(properties)
+}
+
+// stamp returns the properties of a node with the values of its promoted
+// keys, leaving the properties of the handler untouched
+func (p *promoted) stamp(properties map[string]interface{}) map[string]interface{} {
+	if p == nil {
+		return properties
+	}
+	values := make(map[string]interface{})
+	for _, source := range []struct {
+		field string
+		keys  map[string]string
+	}{{"labels", p.labels}, {"annotations", p.annotations}} {
+		for key, property := range source.keys {
+			if value, ok := mapValue(properties[source.field], key); ok {
+				values[property] = value
+			}
+		}
+	}
+	if len(values) == 0 {
+		return properties
+	}
+	stamped := make(map[string]interface{}, len(properties)+len(values))
+	for key, value := range properties {
+		stamped[key] = value
+	}
+	for property, value := range values {
+		stamped[property] = value
+	}
+	return stamped
+}
+
+// mapValue returns the value of key in a labels or annotations property,
+// still a map when the handler returns it
+func mapValue(m interface{}, key string) (string, bool) {
+	switch m := m.(type) {
+	case map[string]string:
+		value, ok := m[key]
+		return value, ok
+	case map[string]interface{}:
+		value, ok := m[key].(string)
+		return value, ok
+	}
+	return "", false
+}
+
+// CreatePromotedIndexes indexes the promoted properties of the nodes of kinds.
+// Indexes of keys no longer promoted are left in place.
+func (c *Client) CreatePromotedIndexes(ctx context.Context, kinds []string) error {
+	if c.promoted == nil {
+		return nil
+	}
+	session := c.NewSession(ctx, neo4j.AccessModeWrite)
+	defer session.Close(ctx)
+
+	// Schema queries run in auto-commit transactions, which Memgraph requires
+	properties := c.promoted.properties()
+	for _, kind := range kinds {
+		for _, property := range properties {
+			query := fmt.Sprintf("CREATE INDEX %s IF NOT EXISTS FOR (n:%s) ON (n.%s)",
+				promotedIndexName(kind, property), kind, property)
+			if c.memgraph() {
+				// Memgraph ignores the creation of an existing index
+				query = fmt.Sprintf("CREATE INDEX ON :%s(%s)", kind, property)
+			}
+			result, err := session.Run(ctx, query, nil)
+			if err == nil {
+				_, err = result.Consume(ctx)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to index %s.%s: %w", kind, property, err)
+			}
+		}
+	}
+	logger.Info("Indexed %d promoted properties on %d kinds", len(properties), len(kinds))
+	return nil
+}
+
+// promotedIndexName returns the name of the index of a promoted property
+func promotedIndexName(kind, property string) string {
+	return "promoted_" + strings.ToLower(propertySafe(kind)) + "_" + property
+}
@@ -0,0 +1,51 @@
+package neo4j
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPromotedProperties(t *testing.T) {
+	if property := LabelProperty("app.kubernetes.io/name"); property != "label_app_kubernetes_io_name" {
+		t.Errorf("Unexpected label property %q", property)
+	}
+	if property := AnnotationProperty("example.com/owner-email"); property != "annotation_example_com_owner_email" {
+		t.Errorf("Unexpected annotation property %q", property)
+	}
+	if name := promotedIndexName("StatefulSet", "label_app"); name != "promoted_statefulset_label_app" {
+		t.Errorf("Unexpected index name %q", name)
+	}
+
+	p := newPromoted([]string{"app", "env", "app"}, []string{"owner"})
+	if properties := p.properties(); !slices.Equal(properties, []string{"annotation_owner", "label_app", "label_env"}) {
+		t.Errorf("Unexpected promoted properties %v", properties)
+	}
+}
+
+func TestPromotedStamp(t *testing.T) {
+	p := newPromoted([]string{"app", "env"}, []string{"owner"})
+	properties := map[string]interface{}{
+		"name":        "web-0",
+		"labels":      map[string]string{"app": "web", "tier": "frontend"},
+		"annotations": map[string]interface{}{"owner": "storefront"},
+	}
+
+	stamped := p.stamp(properties)
+	expected := map[string]interface{}{"label_app": "web", "annotation_owner": "storefront"}
+	for property, value := range expected {
+		if stamped[property] != value {
+			t.Errorf("Expected %s = %v, got %v", property, value, stamped[property])
+		}
+	}
+	if _, ok := stamped["label_env"]; ok {
+		t.Error("Expected no property for a missing label")
+	}
+	if _, ok := properties["label_app"]; ok {
+		t.Error("Expected the properties of the handler to be left untouched")
+	}
+
+	disabled := newPromoted(nil, nil)
+	if stamped := disabled.stamp(properties); len(stamped) != len(properties) {
+		t.Errorf("Expected the properties unchanged without promoted keys, got %v", stamped)
+	}
+}
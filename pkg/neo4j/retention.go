@@ -0,0 +1,95 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var prunedNodesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_pruned_nodes_total",
+		Help: "Total number of nodes deleted by the background cleanup, by kind and retention rule",
+	},
+	[]string{"kind", "rule"},
+)
+
+// RecordPruned counts nodes of kind deleted by the background cleanup under a
+// retention rule, such as ttl, history or generations
+func RecordPruned(kind, rule string, pruned int64) {
+	if pruned > 0 {
+		prunedNodesTotal.WithLabelValues(kind, rule).Add(float64(pruned))
+	}
+}
+
+// PruneGenerations deletes the nodes of the kinds with a KeepGenerations
+// retention rule that are older than the newest KeepGenerations of their owner
+// and scaled to zero, such as the ReplicaSets of past Deployment rollouts
+func (c *Client) PruneGenerations(ctx context.Context, rules []config.RetentionRule) error {
+	for _, rule := range rules {
+		if rule.KeepGenerations <= 0 {
+			continue
+		}
+		pruned, err := c.pruneGenerations(ctx, rule.Kind, rule.KeepGenerations)
+		if err != nil {
+			return fmt.Errorf("failed to prune generations of %s: %w", rule.Kind, err)
+		}
+		if pruned > 0 {
+			logger.Info("[RETENTION] Pruned %d %s nodes beyond %d generations per owner", pruned, rule.Kind, rule.KeepGenerations)
+		}
+	}
+	return nil
+}
+
+// pruneGenerations deletes the old generations of kind and returns how many
+func (c *Client) pruneGenerations(ctx context.Context, kind string, keep int) (int, error) {
+	var uids []string
+	err := c.executeWithMetrics(ctx, "prune_generations", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		// Only nodes written by this instance are pruned; nodes without replicas,
+		// such as Jobs, count as scaled to zero
+		query := fmt.Sprintf(`
+			MATCH (n:%s {clusterName: $clusterName, instanceHash: $instanceHash})-[:OWNED_BY]->(owner)
+			WITH owner, n ORDER BY n.creationTimestamp DESC
+			WITH owner, collect(n) AS generations
+			UNWIND generations[$keep..] AS n
+			WITH n
+			WHERE coalesce(toInteger(n.replicas), 0) = 0
+			WITH n, n.uid AS uid
+			DETACH DELETE n
+			RETURN uid`, kind)
+		result, err := session.Run(ctx, query, map[string]interface{}{
+			"clusterName":  c.config.Kubernetes.ClusterName,
+			"instanceHash": c.config.InstanceHash,
+			"keep":         keep,
+		})
+		if err != nil {
+			return err
+		}
+		uids = uids[:0]
+		for result.Next(ctx) {
+			if uid, ok := result.Record().Values[0].(string); ok {
+				uids = append(uids, uid)
+			}
+		}
+		return result.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, uid := range uids {
+		// Written again when the object changes, if it still exists
+		c.ForgetNode(kind, uid)
+	}
+	RecordPruned(kind, "generations", int64(len(uids)))
+	return len(uids), nil
+}
@@ -0,0 +1,37 @@
+package neo4j
+
+import (
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+)
+
+func TestHistoryCutoffs(t *testing.T) {
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)
+	seven, forever := 7, 0
+	rules := []config.RetentionRule{
+		{Kind: "Pod", HistoryDays: &seven},
+		{Kind: "Secret", HistoryDays: &forever},
+		{Kind: "ReplicaSet", KeepGenerations: 3},
+	}
+
+	defaultCutoff, cutoffs := historyCutoffs(now, 30, rules)
+	if defaultCutoff != "2024-05-01T12:00:00Z" {
+		t.Errorf("Expected the default cutoff 30 days ago, got %q", defaultCutoff)
+	}
+	if cutoffs["Pod"] != "2024-05-24T12:00:00Z" {
+		t.Errorf("Expected the Pod cutoff 7 days ago, got %v", cutoffs["Pod"])
+	}
+	if cutoffs["Secret"] != "" {
+		t.Errorf("Expected Secret versions to be kept forever, got cutoff %v", cutoffs["Secret"])
+	}
+	if _, ok := cutoffs["ReplicaSet"]; ok {
+		t.Error("Expected ReplicaSet to use the default cutoff")
+	}
+
+	defaultCutoff, cutoffs = historyCutoffs(now, 0, nil)
+	if defaultCutoff != "" || len(cutoffs) != 0 {
+		t.Errorf("Expected nothing to be pruned, got %q and %v", defaultCutoff, cutoffs)
+	}
+}
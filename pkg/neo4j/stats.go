@@ -0,0 +1,197 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// bookkeepingLabels are the nodes kubegraph writes besides resources, which
+// are never connected to other nodes and are not counted as orphans
+var bookkeepingLabels = []string{"GraphChange", "ResourceVersion", MetaLabel}
+
+// Statistics summarizes the contents of the graph
+type Statistics struct {
+	Nodes         map[string]int64 `json:"nodes"`         // Nodes by first label
+	Relationships map[string]int64 `json:"relationships"` // Relationships by type
+	// LastWrites holds the time of the last recorded write per kind. It is
+	// read from the audit trail and empty when the audit trail is disabled.
+	LastWrites map[string]string `json:"lastWrites"`
+	Orphans    map[string]int64  `json:"orphans"` // Nodes without relationships by first label
+	Schema     Schema            `json:"schema"`
+}
+
+// Schema lists the indexes and constraints of the database
+type Schema struct {
+	Version     int           `json:"version"` // Schema version of the data model, 0 when not recorded
+	Indexes     []SchemaEntry `json:"indexes"`
+	Constraints []SchemaEntry `json:"constraints"`
+}
+
+// SchemaEntry is an index or constraint
+type SchemaEntry struct {
+	Name       string   `json:"name,omitempty"`
+	Type       string   `json:"type"`
+	Labels     []string `json:"labels"`
+	Properties []string `json:"properties"`
+}
+
+// Statistics counts the nodes, relationships and orphans of the graph and
+// reads the last writes and the schema. If clusterName or team is set, only
+// nodes of that cluster and team and the relationships starting from them are
+// counted, and only the last writes of resources of the team are read.
+func (c *Client) Statistics(ctx context.Context, clusterName, team string) (*Statistics, error) {
+	params := map[string]interface{}{"clusterName": clusterName, "team": team, "bookkeeping": bookkeepingLabels}
+	stats := &Statistics{}
+
+	var err error
+	if stats.Nodes, err = c.countBy(ctx, `
+		MATCH (n)
+		WHERE ($clusterName = '' OR n.clusterName = $clusterName) AND ($team = '' OR n.team = $team)
+		RETURN labels(n)[0] AS key, count(n) AS count`, params); err != nil {
+		return nil, fmt.Errorf("failed to count nodes: %w", err)
+	}
+	if stats.Relationships, err = c.countBy(ctx, `
+		MATCH (n)-[r]->()
+		WHERE ($clusterName = '' OR n.clusterName = $clusterName) AND ($team = '' OR n.team = $team)
+		RETURN type(r) AS key, count(r) AS count`, params); err != nil {
+		return nil, fmt.Errorf("failed to count relationships: %w", err)
+	}
+	if stats.Orphans, err = c.countBy(ctx, `
+		MATCH (n)
+		WHERE ($clusterName = '' OR n.clusterName = $clusterName) AND ($team = '' OR n.team = $team)
+		  AND NOT labels(n)[0] IN $bookkeeping AND NOT (n)--()
+		RETURN labels(n)[0] AS key, count(n) AS count`, params); err != nil {
+		return nil, fmt.Errorf("failed to count orphans: %w", err)
+	}
+
+	rows, err := c.readRows(ctx, `
+		MATCH (c:GraphChange)
+		WHERE ($clusterName = '' OR c.clusterName = $clusterName)
+		  AND ($team = '' OR EXISTS { MATCH (n {uid: c.uid}) WHERE n.team = $team })
+		RETURN c.kind AS key, max(c.timestamp) AS value`, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last writes: %w", err)
+	}
+	stats.LastWrites = make(map[string]string, len(rows))
+	for _, row := range rows {
+		if kind, ok := row["key"].(string); ok {
+			stats.LastWrites[kind] = fmt.Sprint(row["value"])
+		}
+	}
+
+	if stats.Schema, err = c.schema(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if stats.Schema.Version, err = c.GetSchemaVersion(ctx); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// countBy runs a query returning key and count columns
+func (c *Client) countBy(ctx context.Context, query string, params map[string]interface{}) (map[string]int64, error) {
+	rows, err := c.readRows(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		key, _ := row["key"].(string)
+		count, _ := row["count"].(int64)
+		counts[key] += count
+	}
+	return counts, nil
+}
+
+// readRows runs a read query and returns its records as maps
+func (c *Client) readRows(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	result, err := c.ExecuteQueryRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		records, err := tx.Run(ctx, query, params)
+		if err != nil {
+			return nil, err
+		}
+		collected, err := records.Collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]map[string]interface{}, 0, len(collected))
+		for _, record := range collected {
+			rows = append(rows, record.AsMap())
+		}
+		return rows, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]map[string]interface{}), nil
+}
+
+// schema reads the indexes and constraints. Memgraph has its own commands
+// listing them, with a single label per entry.
+func (c *Client) schema(ctx context.Context) (Schema, error) {
+	indexQuery := "SHOW INDEXES YIELD name, type, labelsOrTypes, properties RETURN name, type, labelsOrTypes AS labels, properties"
+	constraintQuery := "SHOW CONSTRAINTS YIELD name, type, labelsOrTypes, properties RETURN name, type, labelsOrTypes AS labels, properties"
+	if c.memgraph() {
+		indexQuery = "SHOW INDEX INFO"
+		constraintQuery = "SHOW CONSTRAINT INFO"
+	}
+
+	var schema Schema
+	for _, target := range []struct {
+		query   string
+		entries *[]SchemaEntry
+	}{{indexQuery, &schema.Indexes}, {constraintQuery, &schema.Constraints}} {
+		rows, err := c.readRows(ctx, target.query, nil)
+		if err != nil {
+			return Schema{}, err
+		}
+		*target.entries = make([]SchemaEntry, 0, len(rows))
+		for _, row := range rows {
+			*target.entries = append(*target.entries, schemaEntry(row))
+		}
+	}
+	return schema, nil
+}
+
+// schemaEntry converts a row of SHOW INDEXES, SHOW CONSTRAINTS or their
+// Memgraph counterparts
+func schemaEntry(row map[string]interface{}) SchemaEntry {
+	entry := SchemaEntry{Labels: []string{}, Properties: []string{}}
+	entry.Name, _ = row["name"].(string)
+	entry.Type, _ = row["type"].(string)
+	if entry.Type == "" {
+		entry.Type = fmt.Sprint(firstNonNil(row["index type"], row["constraint type"]))
+	}
+	entry.Labels = append(entry.Labels, stringList(firstNonNil(row["labels"], row["label"]))...)
+	entry.Properties = append(entry.Properties, stringList(firstNonNil(row["properties"], row["property"]))...)
+	return entry
+}
+
+// firstNonNil returns the first value that is not nil
+func firstNonNil(values ...interface{}) interface{} {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// stringList converts a string or a list of values to a list of strings
+func stringList(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if item != nil {
+				list = append(list, fmt.Sprint(item))
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package neo4j
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		row      map[string]interface{}
+		expected SchemaEntry
+	}{
+		{
+			name: "neo4j index",
+			row: map[string]interface{}{"name": "pod_uid", "type": "RANGE",
+				"labels": []interface{}{"Pod"}, "properties": []interface{}{"uid"}},
+			expected: SchemaEntry{Name: "pod_uid", Type: "RANGE", Labels: []string{"Pod"}, Properties: []string{"uid"}},
+		},
+		{
+			name:     "neo4j lookup index",
+			row:      map[string]interface{}{"name": "index_343aff4e", "type": "LOOKUP", "labels": nil, "properties": nil},
+			expected: SchemaEntry{Name: "index_343aff4e", Type: "LOOKUP", Labels: []string{}, Properties: []string{}},
+		},
+		{
+			name:     "memgraph index",
+			row:      map[string]interface{}{"index type": "label+property", "label": "Pod", "property": "uid", "count": int64(12)},
+			expected: SchemaEntry{Type: "label+property", Labels: []string{"Pod"}, Properties: []string{"uid"}},
+		},
+		{
+			name: "memgraph constraint",
+			row: map[string]interface{}{"constraint type": "unique", "label": "Node",
+				"properties": []interface{}{"clusterName", "name"}},
+			expected: SchemaEntry{Type: "unique", Labels: []string{"Node"}, Properties: []string{"clusterName", "name"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if entry := schemaEntry(test.row); !reflect.DeepEqual(entry, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, entry)
+			}
+		})
+	}
+}
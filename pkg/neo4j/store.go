@@ -0,0 +1,119 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-graph/pkg/graph"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// The client serves every backend of the graph and is the store of the
+// resource handlers
+var _ graph.Store = (*Client)(nil)
+
+// UpsertRelationship creates a relationship unless it exists
+func (c *Client) UpsertRelationship(ctx context.Context, relationship graph.Relationship) error {
+	return c.CreateRelationship(ctx, relationship.FromLabel, relationship.FromKey, relationship.FromValue,
+		relationship.Type, relationship.ToLabel, relationship.ToKey, relationship.ToValue)
+}
+
+// Delete removes the node with the given key and its relationships
+func (c *Client) Delete(ctx context.Context, label, key string, value interface{}) error {
+	err := c.executeWithMetrics(ctx, "delete_node", func() error {
+		session := c.NewSession(ctx, neo4j.AccessModeWrite)
+		defer session.Close(ctx)
+
+		query := fmt.Sprintf("MATCH (n:%s {%s: $value}) DETACH DELETE n", label, key)
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, query, map[string]interface{}{"value": value})
+			return nil, err
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.ForgetNode(label, value)
+	return nil
+}
+
+// Query runs a Cypher statement in a write transaction and returns its records
+// as maps keyed by the returned columns
+func (c *Client) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	result, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return collectRows(ctx, tx, query, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]map[string]interface{}), nil
+}
+
+// Read runs a read-only statement on the primary, so it sees the latest
+// writes of the handlers, and returns its records as maps keyed by the
+// returned columns
+func (c *Client) Read(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	result, err := c.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return collectRows(ctx, tx, query, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]map[string]interface{}), nil
+}
+
+// Write runs statements in order in one write transaction and publishes the
+// relationships they created or deleted on the change feed
+func (c *Client) Write(ctx context.Context, statements ...graph.Statement) error {
+	var changes []relationshipChange
+	_, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		// The transaction may be retried, so only its last attempt counts
+		changes = changes[:0]
+		for _, statement := range statements {
+			rows, err := collectRows(ctx, tx, statement.Query, statement.Params)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, statementChanges(rows)...)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	c.publishNetChanges(changes)
+	return nil
+}
+
+// collectRows runs a statement in tx and returns its records as maps
+func collectRows(ctx context.Context, tx neo4j.ManagedTransaction, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, record.AsMap())
+	}
+	return rows, nil
+}
+
+// memgraph reports whether the graph is stored in Memgraph
+func (c *Client) memgraph() bool {
+	return c.config.Neo4j.Backend == graph.BackendMemgraph
+}
+
+// ElementID returns the Cypher expression of the string ID of the node or
+// relationship bound to variable. Memgraph has no elementId function.
+func (c *Client) ElementID(variable string) string {
+	if c.memgraph() {
+		return fmt.Sprintf("toString(id(%s))", variable)
+	}
+	return fmt.Sprintf("elementId(%s)", variable)
+}
@@ -0,0 +1,50 @@
+package neo4j
+
+import (
+	"testing"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+)
+
+func TestElementID(t *testing.T) {
+	cfg := config.NewConfig()
+	client := &Client{config: cfg}
+	if got := client.ElementID("n"); got != "elementId(n)" {
+		t.Errorf("Expected elementId(n) for Neo4j, got %s", got)
+	}
+
+	cfg.Neo4j.Backend = graph.BackendMemgraph
+	if got := client.ElementID("startNode(r)"); got != "toString(id(startNode(r)))" {
+		t.Errorf("Expected toString(id(startNode(r))) for Memgraph, got %s", got)
+	}
+}
+
+func TestDriverURIMemgraph(t *testing.T) {
+	tests := map[string]string{
+		"neo4j://memgraph:7687":   "bolt://memgraph:7687",
+		"neo4j+s://memgraph:7687": "bolt+s://memgraph:7687",
+		"bolt://memgraph:7687":    "bolt://memgraph:7687",
+	}
+	for uri, expected := range tests {
+		cfg := config.NewConfig()
+		cfg.Neo4j.Backend = graph.BackendMemgraph
+		if got := driverURI(cfg, uri); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, uri, got)
+		}
+	}
+}
+
+func TestNewClientRejectsUnsupportedBackends(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.Neo4j.Backend = "neptune"
+	if _, err := NewClient(cfg); err == nil {
+		t.Error("Expected an error for an unsupported backend")
+	}
+
+	cfg.Neo4j.Backend = graph.BackendMemgraph
+	cfg.Neo4j.Database = "staging"
+	if _, err := NewClient(cfg); err == nil {
+		t.Error("Expected an error for a named database on Memgraph")
+	}
+}
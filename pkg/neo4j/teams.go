@@ -0,0 +1,141 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// TeamProperty is the property holding the team owning a node
+const TeamProperty = "team"
+
+// teams resolves the team of the nodes written by the client: the value of
+// the team label of the resource, or else of its namespace, or else the team
+// of the first rule matching its namespace
+type teams struct {
+	label string
+	rules []config.TeamRule
+
+	mu         sync.RWMutex
+	namespaces map[string]string // team label of the namespaces seen, by cluster and name
+}
+
+// newTeams returns nil when no team label or rule is configured
+func newTeams(label string, rules []config.TeamRule) *teams {
+	if label == "" && len(rules) == 0 {
+		return nil
+	}
+	return &teams{label: label, rules: rules, namespaces: make(map[string]string)}
+}
+
+// labelValue returns the team label of a labels property, still a map when
+// the handler returns it
+func (t *teams) labelValue(labels interface{}) string {
+	if t.label == "" {
+		return ""
+	}
+	value, _ := mapValue(labels, t.label)
+	return value
+}
+
+// namespaceTeam returns the team of the resources of a namespace without a
+// team label of their own
+func (t *teams) namespaceTeam(cluster, namespace string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.namespaceTeamLocked(t.namespaces[cluster+"/"+namespace], namespace)
+}
+
+// resolve returns the team of a node about to be written
+func (t *teams) resolve(kind string, properties map[string]interface{}) string {
+	if team := t.labelValue(properties["labels"]); team != "" {
+		return team
+	}
+	cluster, _ := properties["clusterName"].(string)
+	namespace, _ := properties["namespace"].(string)
+	if kind == "Namespace" {
+		namespace, _ = properties["name"].(string)
+	}
+	if namespace == "" {
+		return ""
+	}
+	return t.namespaceTeam(cluster, namespace)
+}
+
+// stamp returns the properties of a node with its team, leaving the
+// properties of the handler untouched
+func (t *teams) stamp(kind string, properties map[string]interface{}) map[string]interface{} {
+	if t == nil {
+		return properties
+	}
+	team := t.resolve(kind, properties)
+	if team == "" {
+		return properties
+	}
+	stamped := make(map[string]interface{}, len(properties)+1)
+	for key, value := range properties {
+		stamped[key] = value
+	}
+	stamped[TeamProperty] = team
+	return stamped
+}
+
+// observeNamespace remembers the team label of a written Namespace and
+// returns the team of its resources without a team label of their own
+// before and after the write. Resources written before their namespace got
+// the team of the rules, so they follow too when the namespace is first seen.
+func (t *teams) observeNamespace(properties map[string]interface{}) (previous, current string) {
+	if t == nil {
+		return "", ""
+	}
+	cluster, _ := properties["clusterName"].(string)
+	name, _ := properties["name"].(string)
+	key := cluster + "/" + name
+	label := t.labelValue(properties["labels"])
+
+	t.mu.Lock()
+	previous = t.namespaceTeamLocked(t.namespaces[key], name)
+	t.namespaces[key] = label
+	t.mu.Unlock()
+	return previous, t.namespaceTeamLocked(label, name)
+}
+
+// namespaceTeamLocked returns the team given by the team label of a
+// namespace, or else by the rules
+func (t *teams) namespaceTeamLocked(label, namespace string) string {
+	if label != "" {
+		return label
+	}
+	for _, rule := range t.rules {
+		if rule.Matches(namespace) {
+			return rule.Team
+		}
+	}
+	return ""
+}
+
+// propagateTeam moves the nodes of a namespace that had its previous team to
+// its new team. Nodes with a team label of their own keep their team unless
+// it was the previous team of the namespace, until they are written again.
+func (c *Client) propagateTeam(ctx context.Context, properties map[string]interface{}, previous, current string) {
+	cluster, _ := properties["clusterName"].(string)
+	namespace, _ := properties["name"].(string)
+	_, err := c.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, fmt.Sprintf(`
+			MATCH (n {clusterName: $clusterName, namespace: $namespace})
+			WHERE coalesce(n.%[1]s, '') = $previous
+			SET n.%[1]s = CASE WHEN $team = '' THEN null ELSE $team END`, TeamProperty),
+			map[string]interface{}{"clusterName": cluster, "namespace": namespace, "previous": previous, "team": current})
+		return nil, err
+	})
+	if err != nil {
+		logger.Warn("Failed to move the resources of namespace %s from team %q to %q: %v", namespace, previous, current, err)
+		return
+	}
+	logger.Info("Moved the resources of namespace %s from team %q to %q", namespace, previous, current)
+}
@@ -0,0 +1,118 @@
+package neo4j
+
+import (
+	"testing"
+
+	"k8s-graph/config"
+)
+
+func TestTeamsStamp(t *testing.T) {
+	teams := newTeams("team", []config.TeamRule{
+		{Namespace: "payments-*", Team: "payments"},
+		{Namespace: "*", Team: "platform"},
+	})
+	teams.observeNamespace(map[string]interface{}{
+		"clusterName": "prod",
+		"name":        "shop",
+		"labels":      map[string]string{"team": "storefront"},
+	})
+
+	tests := []struct {
+		name       string
+		kind       string
+		properties map[string]interface{}
+		team       string
+	}{
+		{
+			name:       "own label",
+			kind:       "Pod",
+			properties: map[string]interface{}{"clusterName": "prod", "namespace": "shop", "labels": map[string]string{"team": "search"}},
+			team:       "search",
+		},
+		{
+			name:       "own label as generic map",
+			kind:       "Pod",
+			properties: map[string]interface{}{"clusterName": "prod", "namespace": "shop", "labels": map[string]interface{}{"team": "search"}},
+			team:       "search",
+		},
+		{
+			name:       "namespace label",
+			kind:       "Pod",
+			properties: map[string]interface{}{"clusterName": "prod", "namespace": "shop"},
+			team:       "storefront",
+		},
+		{
+			name:       "namespace label of another cluster",
+			kind:       "Pod",
+			properties: map[string]interface{}{"clusterName": "staging", "namespace": "shop"},
+			team:       "platform",
+		},
+		{
+			name:       "first matching rule",
+			kind:       "Service",
+			properties: map[string]interface{}{"clusterName": "prod", "namespace": "payments-eu"},
+			team:       "payments",
+		},
+		{
+			name:       "namespace node",
+			kind:       "Namespace",
+			properties: map[string]interface{}{"clusterName": "prod", "name": "payments-us"},
+			team:       "payments",
+		},
+		{
+			name:       "cluster-scoped",
+			kind:       "Node",
+			properties: map[string]interface{}{"clusterName": "prod", "name": "node-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stamped := teams.stamp(tt.kind, tt.properties)
+			team, _ := stamped[TeamProperty].(string)
+			if team != tt.team {
+				t.Errorf("expected team %q, got %q", tt.team, team)
+			}
+			if _, ok := tt.properties[TeamProperty]; ok {
+				t.Error("expected the properties of the handler to be left untouched")
+			}
+		})
+	}
+}
+
+func TestTeamsObserveNamespace(t *testing.T) {
+	teams := newTeams("team", []config.TeamRule{{Namespace: "*", Team: "platform"}})
+	namespace := func(team string) map[string]interface{} {
+		properties := map[string]interface{}{"clusterName": "prod", "name": "shop"}
+		if team != "" {
+			properties["labels"] = map[string]string{"team": team}
+		}
+		return properties
+	}
+
+	steps := []struct {
+		team              string
+		previous, current string
+	}{
+		{"storefront", "platform", "storefront"}, // first seen, resources written before follow
+		{"storefront", "storefront", "storefront"},
+		{"search", "storefront", "search"},
+		{"", "search", "platform"}, // label removed, back to the rules
+	}
+	for i, step := range steps {
+		previous, current := teams.observeNamespace(namespace(step.team))
+		if previous != step.previous || current != step.current {
+			t.Errorf("step %d: expected %q -> %q, got %q -> %q", i, step.previous, step.current, previous, current)
+		}
+	}
+}
+
+func TestTeamsDisabled(t *testing.T) {
+	teams := newTeams("", nil)
+	if teams != nil {
+		t.Fatal("expected no team resolution without a label or rule")
+	}
+	properties := map[string]interface{}{"namespace": "shop"}
+	if stamped := teams.stamp("Pod", properties); len(stamped) != 1 {
+		t.Errorf("expected the properties unchanged, got %v", stamped)
+	}
+}
@@ -0,0 +1,78 @@
+package neo4j
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/graph"
+)
+
+// driverURI returns the URI the driver connects to for uri. The driver only uses TLS
+// for the +s and +ssc schemes and derives certificate verification from the
+// scheme, so TLS options upgrade a plain neo4j:// or bolt:// URI and skipping
+// verification selects +ssc. Memgraph does not route, so neo4j:// becomes
+// bolt:// for it.
+func driverURI(cfg *config.Config, uri string) string {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return uri
+	}
+	base, _, _ := strings.Cut(scheme, "+")
+	if base != "neo4j" && base != "bolt" {
+		return uri
+	}
+	if cfg.Neo4j.Backend == graph.BackendMemgraph && base == "neo4j" {
+		base = "bolt"
+		scheme = strings.Replace(scheme, "neo4j", "bolt", 1)
+		uri = scheme + "://" + rest
+	}
+
+	switch {
+	case cfg.Neo4j.TLSSkipVerify:
+		return base + "+ssc://" + rest
+	case scheme == base && (cfg.Neo4j.CACertPath != "" || cfg.Neo4j.ClientCertPath != ""):
+		return base + "+s://" + rest
+	}
+	return uri
+}
+
+// tlsConfig returns the TLS configuration of the driver for a custom CA or a
+// client certificate, or nil to use the system CAs
+func tlsConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.Neo4j.CACertPath == "" && cfg.Neo4j.ClientCertPath == "" && cfg.Neo4j.ClientKeyPath == "" {
+		return nil, nil
+	}
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.Neo4j.CACertPath != "" {
+		pem, err := os.ReadFile(cfg.Neo4j.CACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read neo4j CA certificate: %w", err)
+		}
+		// The custom CA is trusted in addition to the system CAs
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in neo4j CA certificate %s", cfg.Neo4j.CACertPath)
+		}
+		conf.RootCAs = pool
+	}
+
+	if cfg.Neo4j.ClientCertPath != "" || cfg.Neo4j.ClientKeyPath != "" {
+		if cfg.Neo4j.ClientCertPath == "" || cfg.Neo4j.ClientKeyPath == "" {
+			return nil, fmt.Errorf("neo4j client certificate and key must be set together")
+		}
+		certificate, err := tls.LoadX509KeyPair(cfg.Neo4j.ClientCertPath, cfg.Neo4j.ClientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load neo4j client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{certificate}
+	}
+	return conf, nil
+}
@@ -0,0 +1,108 @@
+package neo4j
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+)
+
+func TestDriverURI(t *testing.T) {
+	tests := []struct {
+		name       string
+		uri        string
+		caCert     string
+		skipVerify bool
+		expected   string
+	}{
+		{"plain", "neo4j://localhost:7687", "", false, "neo4j://localhost:7687"},
+		{"secure scheme kept", "neo4j+s://db.example.com", "", false, "neo4j+s://db.example.com"},
+		{"custom CA upgrades", "neo4j://db.example.com:7687", "/ca.crt", false, "neo4j+s://db.example.com:7687"},
+		{"custom CA on bolt", "bolt://db.example.com:7687", "/ca.crt", false, "bolt+s://db.example.com:7687"},
+		{"skip verify", "neo4j+s://db.example.com", "", true, "neo4j+ssc://db.example.com"},
+		{"skip verify upgrades", "bolt://db.example.com", "", true, "bolt+ssc://db.example.com"},
+		{"unknown scheme", "http://db.example.com", "/ca.crt", true, "http://db.example.com"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.NewConfig()
+			cfg.Neo4j.CACertPath = test.caCert
+			cfg.Neo4j.TLSSkipVerify = test.skipVerify
+			if got := driverURI(cfg, test.uri); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+// writeCertificate writes a self-signed certificate and its key to dir
+func writeCertificate(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubegraph"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath = filepath.Join(dir, "tls.crt")
+	keyPath = filepath.Join(dir, "tls.key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certPath, keyPath
+}
+
+func TestTLSConfig(t *testing.T) {
+	certPath, keyPath := writeCertificate(t, t.TempDir())
+
+	cfg := config.NewConfig()
+	if loaded, err := tlsConfig(cfg); err != nil || loaded != nil {
+		t.Errorf("Expected no TLS configuration by default, got %v, %v", loaded, err)
+	}
+
+	cfg.Neo4j.CACertPath = certPath
+	cfg.Neo4j.ClientCertPath = certPath
+	cfg.Neo4j.ClientKeyPath = keyPath
+	loaded, err := tlsConfig(cfg)
+	if err != nil {
+		t.Fatalf("Expected a TLS configuration, got %v", err)
+	}
+	if loaded.RootCAs == nil || len(loaded.Certificates) != 1 {
+		t.Errorf("Expected the CA and the client certificate to be loaded, got %+v", loaded)
+	}
+
+	cfg.Neo4j.ClientKeyPath = ""
+	if _, err := tlsConfig(cfg); err == nil {
+		t.Error("Expected an error for a client certificate without key")
+	}
+
+	cfg.Neo4j.ClientCertPath = ""
+	cfg.Neo4j.CACertPath = keyPath
+	if _, err := tlsConfig(cfg); err == nil {
+		t.Error("Expected an error for a CA file without certificate")
+	}
+}
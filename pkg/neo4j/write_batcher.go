@@ -0,0 +1,204 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	neo4jBatchFlushDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "neo4j_batch_flush_duration_seconds",
+			Help:    "Time taken to write a batch of node writes in one transaction in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	neo4jBatchFlushSize = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "neo4j_batch_flush_size",
+			Help:    "Number of writes per flushed batch",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+	)
+
+	neo4jBatchTargetSize = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "neo4j_batch_target_size",
+			Help: "Number of writes at which a batch is flushed, adapted to the backlog",
+		},
+	)
+
+	neo4jBatchFallbacksTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "neo4j_batch_fallbacks_total",
+			Help: "Total number of failed batches retried one write at a time",
+		},
+	)
+)
+
+var errWriteBatcherStopped = errors.New("write batcher stopped")
+
+// batchedWrite is a write waiting for its batch to be flushed
+type batchedWrite struct {
+	ctx  context.Context
+	fn   func(tx neo4j.ManagedTransaction) error
+	done chan error
+}
+
+// writeBatcher groups the writes of concurrent callers into shared
+// transactions. A batch is flushed once it holds size writes or its flush
+// interval has passed. The size adapts to the load: it doubles while writes
+// queue up behind full batches, so that a backlog is written in fewer
+// transactions, and halves when batches are flushed well below it.
+type writeBatcher struct {
+	flush    func(writes []batchedWrite) error // runs the writes in one transaction
+	queue    chan batchedWrite
+	minSize  int
+	maxSize  int
+	size     int // only used by the loop
+	interval time.Duration
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// newWriteBatcher starts a batcher flushing batches of minSize to maxSize
+// writes with flush. It returns nil when batching is disabled.
+func newWriteBatcher(minSize, maxSize int, interval time.Duration, flush func(writes []batchedWrite) error) *writeBatcher {
+	if maxSize <= 0 {
+		return nil
+	}
+	minSize = max(1, min(minSize, maxSize))
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	b := &writeBatcher{
+		flush:    flush,
+		queue:    make(chan batchedWrite, maxSize),
+		minSize:  minSize,
+		maxSize:  maxSize,
+		size:     minSize,
+		interval: interval,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	neo4jBatchTargetSize.Set(float64(b.size))
+	go b.loop()
+	return b
+}
+
+func (b *writeBatcher) loop() {
+	defer close(b.stopped)
+	for {
+		var batch []batchedWrite
+		select {
+		case write := <-b.queue:
+			batch = append(batch, write)
+		case <-b.stop:
+			return
+		}
+
+		deadline := time.After(b.interval)
+	collect:
+		for len(batch) < b.size {
+			select {
+			case write := <-b.queue:
+				batch = append(batch, write)
+			case <-deadline:
+				break collect
+			case <-b.stop:
+				break collect
+			}
+		}
+		b.execute(batch)
+		b.adapt(len(batch), len(b.queue))
+	}
+}
+
+// execute flushes a batch and reports the outcome to every write. A failing
+// write fails the whole transaction, so the writes of a failed batch are
+// retried one at a time to report errors to the writes causing them only.
+func (b *writeBatcher) execute(batch []batchedWrite) {
+	live := batch[:0]
+	for _, write := range batch {
+		// Writes whose caller gave up are dropped
+		if err := write.ctx.Err(); err != nil {
+			write.done <- err
+			continue
+		}
+		live = append(live, write)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	start := time.Now()
+	err := b.flush(live)
+	neo4jBatchFlushDuration.Observe(time.Since(start).Seconds())
+	neo4jBatchFlushSize.Observe(float64(len(live)))
+	if err == nil || len(live) == 1 {
+		for _, write := range live {
+			write.done <- err
+		}
+		return
+	}
+
+	neo4jBatchFallbacksTotal.Inc()
+	for _, write := range live {
+		write.done <- b.flush([]batchedWrite{write})
+	}
+}
+
+// adapt doubles the batch size when a full batch left writes queued, and
+// halves it when a batch was flushed at less than a quarter of it
+func (b *writeBatcher) adapt(flushed, backlog int) {
+	switch {
+	case flushed >= b.size && backlog > 0:
+		b.size = min(b.size*2, b.maxSize)
+	case flushed < b.size/4:
+		b.size = max(b.size/2, b.minSize)
+	}
+	neo4jBatchTargetSize.Set(float64(b.size))
+}
+
+// run queues fn for the next batch and waits for the batch to be flushed.
+// fn may run more than once when the transaction is retried, and must not
+// issue batched writes itself.
+func (b *writeBatcher) run(ctx context.Context, fn func(tx neo4j.ManagedTransaction) error) error {
+	write := batchedWrite{ctx: ctx, fn: fn, done: make(chan error, 1)}
+	select {
+	case b.queue <- write:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.stop:
+		return errWriteBatcherStopped
+	}
+
+	select {
+	case err := <-write.done:
+		return err
+	case <-b.stopped:
+		// The last batch may have been flushed just before the loop stopped
+		select {
+		case err := <-write.done:
+			return err
+		default:
+			return errWriteBatcherStopped
+		}
+	}
+}
+
+// close stops the batcher after flushing the batch being collected; writes
+// still queued fail with errWriteBatcherStopped
+func (b *writeBatcher) close() {
+	if b != nil {
+		close(b.stop)
+		<-b.stopped
+	}
+}
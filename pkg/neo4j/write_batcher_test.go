@@ -0,0 +1,133 @@
+package neo4j
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingFlush records the sizes of the flushed batches and fails the
+// batches containing a write whose context carries failKey
+type recordingFlush struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+type failKey struct{}
+
+var errBadWrite = errors.New("bad write")
+
+func (f *recordingFlush) flush(writes []batchedWrite) error {
+	f.mu.Lock()
+	f.sizes = append(f.sizes, len(writes))
+	f.mu.Unlock()
+	for _, write := range writes {
+		if write.ctx.Value(failKey{}) != nil {
+			return errBadWrite
+		}
+	}
+	return nil
+}
+
+func runConcurrently(b *writeBatcher, contexts ...context.Context) []error {
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i, ctx := range contexts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = b.run(ctx, nil)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestWriteBatcherGroupsWrites(t *testing.T) {
+	f := &recordingFlush{}
+	b := newWriteBatcher(4, 4, time.Hour, f.flush)
+	defer b.close()
+
+	ctx := context.Background()
+	for i, err := range runConcurrently(b, ctx, ctx, ctx, ctx) {
+		if err != nil {
+			t.Errorf("write %d failed: %v", i, err)
+		}
+	}
+	if len(f.sizes) != 1 || f.sizes[0] != 4 {
+		t.Errorf("expected one batch of 4 writes, got %v", f.sizes)
+	}
+}
+
+func TestWriteBatcherFlushesAfterInterval(t *testing.T) {
+	f := &recordingFlush{}
+	b := newWriteBatcher(8, 8, 10*time.Millisecond, f.flush)
+	defer b.close()
+
+	if err := b.run(context.Background(), nil); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if len(f.sizes) != 1 || f.sizes[0] != 1 {
+		t.Errorf("expected the lone write to be flushed, got %v", f.sizes)
+	}
+}
+
+func TestWriteBatcherFallback(t *testing.T) {
+	f := &recordingFlush{}
+	b := newWriteBatcher(3, 3, time.Hour, f.flush)
+	defer b.close()
+
+	ctx := context.Background()
+	errs := runConcurrently(b, ctx, context.WithValue(ctx, failKey{}, true), ctx)
+	failed := 0
+	for _, err := range errs {
+		if errors.Is(err, errBadWrite) {
+			failed++
+		} else if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected only the bad write to fail, got %v", errs)
+	}
+	if len(f.sizes) != 4 {
+		t.Errorf("expected the failed batch to be retried one write at a time, got %v", f.sizes)
+	}
+}
+
+func TestWriteBatcherAdapt(t *testing.T) {
+	b := &writeBatcher{minSize: 2, maxSize: 16, size: 2}
+	steps := []struct {
+		flushed, backlog, size int
+	}{
+		{2, 5, 4},   // full batch with writes queued grows
+		{4, 1, 8},   //
+		{8, 0, 8},   // full batch without backlog keeps the size
+		{8, 9, 16},  //
+		{16, 9, 16}, // capped at the maximum
+		{5, 0, 16},  // a batch over a quarter full keeps the size
+		{3, 0, 8},   // low load shrinks
+		{1, 0, 4},   //
+		{0, 0, 2},   //
+		{0, 0, 2},   // floored at the minimum
+	}
+	for i, step := range steps {
+		b.adapt(step.flushed, step.backlog)
+		if b.size != step.size {
+			t.Fatalf("step %d: expected size %d, got %d", i, step.size, b.size)
+		}
+	}
+}
+
+func TestWriteBatcherClose(t *testing.T) {
+	b := newWriteBatcher(1, 1, time.Millisecond, (&recordingFlush{}).flush)
+	b.close()
+	if err := b.run(context.Background(), nil); !errors.Is(err, errWriteBatcherStopped) {
+		t.Errorf("expected errWriteBatcherStopped, got %v", err)
+	}
+	if newWriteBatcher(16, 0, time.Millisecond, nil) != nil {
+		t.Error("expected batching to be disabled without a maximum size")
+	}
+}
@@ -0,0 +1,257 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Triggers of a reload
+const (
+	TriggerSignal = "signal"
+	TriggerFile   = "file"
+)
+
+var configReloadsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_config_reloads_total",
+		Help: "Total number of reloads of the configuration file, by result",
+	},
+	[]string{"result"},
+)
+
+// Settings are the settings of the configuration file that are applied without
+// a restart
+type Settings struct {
+	LogLevel          string   `json:"logLevel"`
+	DisabledHandlers  []string `json:"disabledHandlers"`
+	Namespaces        []string `json:"namespaces"`
+	ExcludeNamespaces []string `json:"excludeNamespaces"`
+	EventTTLDays      int      `json:"eventTTLDays"`
+	HistoryDays       int      `json:"historyDays"`
+	AuditDays         int      `json:"auditDays"`
+}
+
+// merge returns s with the settings of file applied, except those whose flag
+// is pinned. Settings that cannot change at runtime keep their value and are
+// reported in the error.
+func (s Settings) merge(file *config.File, pinned map[string]bool) (Settings, error) {
+	next := s
+	var errs []error
+	for name, value := range file.Flags() {
+		if pinned[name] {
+			continue
+		}
+		switch name {
+		case "log-level":
+			next.LogLevel = strings.ToUpper(value)
+		case "disabled-handlers":
+			next.DisabledHandlers = splitList(value)
+		case "namespaces":
+			next.Namespaces = splitList(value)
+		case "exclude-namespaces":
+			next.ExcludeNamespaces = splitList(value)
+		case "event-ttl-days":
+			days, _ := strconv.Atoi(value)
+			// The Event handler is only registered at startup when events are kept
+			if (days > 0) != (s.EventTTLDays > 0) {
+				errs = append(errs, fmt.Errorf("ttl.eventDays: switching event handling on or off requires a restart"))
+				continue
+			}
+			next.EventTTLDays = days
+		case "history-retention-days":
+			next.HistoryDays, _ = strconv.Atoi(value)
+		case "audit-ttl-days":
+			next.AuditDays, _ = strconv.Atoi(value)
+		}
+	}
+	return next, errors.Join(errs...)
+}
+
+// changes describes the settings that differ between s and next by their path
+// in the configuration file
+func (s Settings) changes(next Settings) []string {
+	changes := make([]string, 0)
+	changed := func(path string, from, to interface{}) {
+		if fmt.Sprint(from) != fmt.Sprint(to) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", path, from, to))
+		}
+	}
+	changed("logLevel", s.LogLevel, next.LogLevel)
+	changed("handlers.disabled", s.DisabledHandlers, next.DisabledHandlers)
+	changed("filters.namespaces", s.Namespaces, next.Namespaces)
+	changed("filters.excludeNamespaces", s.ExcludeNamespaces, next.ExcludeNamespaces)
+	changed("ttl.eventDays", s.EventTTLDays, next.EventTTLDays)
+	changed("ttl.historyDays", s.HistoryDays, next.HistoryDays)
+	changed("ttl.auditDays", s.AuditDays, next.AuditDays)
+	return changes
+}
+
+// Status describes the last reload
+type Status struct {
+	Time     time.Time `json:"time"`
+	Trigger  string    `json:"trigger"`
+	Applied  []string  `json:"applied"`
+	Error    string    `json:"error,omitempty"`
+	Settings Settings  `json:"settings"`
+}
+
+// ApplyFunc applies changed settings to the running components
+type ApplyFunc func(ctx context.Context, previous, next Settings) error
+
+// Reloader reloads the configuration file on SIGHUP and when the file changes,
+// applying the settings that can change at runtime
+type Reloader struct {
+	path   string
+	pinned map[string]bool
+	apply  ApplyFunc
+
+	reloadMu sync.Mutex // Serializes reloads
+
+	mu       sync.RWMutex
+	settings Settings
+	status   *Status
+}
+
+// NewReloader creates a reloader of the configuration file at path, starting
+// from the current settings. Settings whose flag is pinned were given by a flag
+// or environment variable and are not reloaded, as they take precedence over
+// the file.
+func NewReloader(path string, settings Settings, pinned map[string]bool, apply ApplyFunc) *Reloader {
+	return &Reloader{
+		path:     path,
+		pinned:   pinned,
+		apply:    apply,
+		settings: settings,
+	}
+}
+
+// Settings returns the current settings
+func (r *Reloader) Settings() Settings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.settings
+}
+
+// Status returns the last reload, or nil if there was none
+func (r *Reloader) Status() *Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.status == nil {
+		return nil
+	}
+	status := *r.status
+	return &status
+}
+
+// Reload reads the configuration file and applies the changed settings. An
+// invalid file leaves the settings unchanged.
+func (r *Reloader) Reload(ctx context.Context, trigger string) Status {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	previous := r.Settings()
+	next := previous
+	status := Status{Time: time.Now(), Trigger: trigger, Applied: []string{}}
+
+	file, err := config.LoadFile(r.path)
+	if err == nil {
+		next, err = previous.merge(file, r.pinned)
+		status.Applied = previous.changes(next)
+		if len(status.Applied) > 0 {
+			err = errors.Join(err, r.apply(ctx, previous, next))
+		}
+	}
+	status.Settings = next
+
+	if err != nil {
+		status.Error = err.Error()
+		configReloadsTotal.WithLabelValues("failure").Inc()
+		logger.Error("[RELOAD] Failed to reload config file %s: %v", r.path, err)
+	} else {
+		configReloadsTotal.WithLabelValues("success").Inc()
+	}
+	if len(status.Applied) > 0 {
+		logger.Info("[RELOAD] Applied changes of config file %s: %s", r.path, strings.Join(status.Applied, "; "))
+	} else if err == nil {
+		logger.Info("[RELOAD] Config file %s reloaded, nothing changed", r.path)
+	}
+
+	r.mu.Lock()
+	r.settings = next
+	r.status = &status
+	r.mu.Unlock()
+	return status
+}
+
+// Run reloads the configuration file on SIGHUP, and when its modification time
+// or size changed, checked every interval (never if 0), until ctx is done
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	if r.path == "" {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	version := r.fileVersion()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			logger.Info("[RELOAD] Received SIGHUP")
+			version = r.fileVersion()
+			r.Reload(ctx, TriggerSignal)
+		case <-ticks:
+			// ConfigMap volumes replace the file through a symlink, which Stat
+			// follows; a file missing during the swap is checked again later
+			if current := r.fileVersion(); current != "" && current != version {
+				version = current
+				r.Reload(ctx, TriggerFile)
+			}
+		}
+	}
+}
+
+// fileVersion identifies the content of the configuration file by its
+// modification time and size, or is empty if it cannot be read
+func (r *Reloader) fileVersion() string {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// splitList splits a comma-separated list of flag values
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
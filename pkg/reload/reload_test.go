@@ -0,0 +1,164 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s-graph/pkg/logger"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+}
+
+func initialSettings() Settings {
+	return Settings{
+		LogLevel:          "INFO",
+		DisabledHandlers:  []string{"Event"},
+		ExcludeNamespaces: []string{"kube-system"},
+		EventTTLDays:      7,
+		HistoryDays:       30,
+		AuditDays:         3,
+	}
+}
+
+func TestReloadAppliesChanges(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `
+logLevel: debug
+handlers:
+  disabled: [Secret]
+filters:
+  namespaces: [payments]
+ttl:
+  auditDays: 1
+`)
+
+	var applied []Settings
+	r := NewReloader(path, initialSettings(), nil, func(ctx context.Context, previous, next Settings) error {
+		applied = append(applied, next)
+		return nil
+	})
+
+	status := r.Reload(context.Background(), TriggerSignal)
+	if status.Error != "" {
+		t.Fatalf("unexpected error: %s", status.Error)
+	}
+	want := Settings{
+		LogLevel:          "DEBUG",
+		DisabledHandlers:  []string{"Secret"},
+		Namespaces:        []string{"payments"},
+		ExcludeNamespaces: []string{"kube-system"},
+		EventTTLDays:      7,
+		HistoryDays:       30,
+		AuditDays:         1,
+	}
+	if len(applied) != 1 || !reflect.DeepEqual(applied[0], want) {
+		t.Fatalf("expected %+v to be applied once, got %+v", want, applied)
+	}
+	if !reflect.DeepEqual(r.Settings(), want) {
+		t.Errorf("expected settings %+v, got %+v", want, r.Settings())
+	}
+	wantApplied := []string{
+		"logLevel: INFO -> DEBUG",
+		"handlers.disabled: [Event] -> [Secret]",
+		"filters.namespaces: [] -> [payments]",
+		"ttl.auditDays: 3 -> 1",
+	}
+	if !reflect.DeepEqual(status.Applied, wantApplied) {
+		t.Errorf("expected applied changes %v, got %v", wantApplied, status.Applied)
+	}
+	if got := r.Status(); got == nil || got.Trigger != TriggerSignal {
+		t.Errorf("expected the status of the reload, got %+v", got)
+	}
+
+	// Reloading the same file changes nothing
+	status = r.Reload(context.Background(), TriggerFile)
+	if len(status.Applied) != 0 || len(applied) != 1 {
+		t.Errorf("expected no changes on the second reload, got %v", status.Applied)
+	}
+}
+
+func TestReloadSkipsPinnedSettings(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "logLevel: DEBUG\nttl:\n  historyDays: 10\n")
+
+	r := NewReloader(path, initialSettings(), map[string]bool{"log-level": true}, func(ctx context.Context, previous, next Settings) error {
+		return nil
+	})
+	r.Reload(context.Background(), TriggerFile)
+
+	settings := r.Settings()
+	if settings.LogLevel != "INFO" {
+		t.Errorf("expected the pinned log level to stay INFO, got %s", settings.LogLevel)
+	}
+	if settings.HistoryDays != 10 {
+		t.Errorf("expected historyDays 10, got %d", settings.HistoryDays)
+	}
+}
+
+func TestReloadInvalidFile(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "ttl:\n  auditDays: -1\n")
+
+	r := NewReloader(path, initialSettings(), nil, func(ctx context.Context, previous, next Settings) error {
+		t.Error("expected an invalid file not to be applied")
+		return nil
+	})
+	status := r.Reload(context.Background(), TriggerFile)
+	if !strings.Contains(status.Error, "ttl.auditDays") {
+		t.Errorf("expected the invalid setting in the error, got %q", status.Error)
+	}
+	if !reflect.DeepEqual(r.Settings(), initialSettings()) {
+		t.Errorf("expected the settings to be unchanged, got %+v", r.Settings())
+	}
+}
+
+func TestReloadRejectsEventHandlingSwitch(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "logLevel: WARN\nttl:\n  eventDays: 0\n")
+
+	r := NewReloader(path, initialSettings(), nil, func(ctx context.Context, previous, next Settings) error {
+		return nil
+	})
+	status := r.Reload(context.Background(), TriggerFile)
+	if !strings.Contains(status.Error, "requires a restart") {
+		t.Errorf("expected a restart to be required, got %q", status.Error)
+	}
+	settings := r.Settings()
+	if settings.EventTTLDays != 7 {
+		t.Errorf("expected eventDays to stay 7, got %d", settings.EventTTLDays)
+	}
+	if settings.LogLevel != "WARN" {
+		t.Errorf("expected the other settings to be applied, got log level %s", settings.LogLevel)
+	}
+}
+
+func TestReloadReportsApplyError(t *testing.T) {
+	logger.Init(logger.ERROR)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "handlers:\n  disabled: [Pod]\n")
+
+	r := NewReloader(path, initialSettings(), nil, func(ctx context.Context, previous, next Settings) error {
+		return errors.New("in-flight events did not drain")
+	})
+	status := r.Reload(context.Background(), TriggerFile)
+	if status.Error != "in-flight events did not drain" {
+		t.Errorf("expected the apply error, got %q", status.Error)
+	}
+	if len(status.Applied) != 1 {
+		t.Errorf("expected the change to be reported, got %v", status.Applied)
+	}
+}
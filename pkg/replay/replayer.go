@@ -0,0 +1,164 @@
+// Package replay feeds watch events recorded with --record-events back
+// through the resource handlers, without a cluster.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/failure"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Summary reports the outcome of a replay
+type Summary struct {
+	Events   int           // Events read from the recording
+	Applied  int           // Events handled successfully
+	Failed   int           // Events whose handler failed
+	Skipped  int           // Events of disabled or unknown kinds, or of filtered namespaces
+	Duration time.Duration // Time the replay took
+}
+
+// Replayer feeds recorded watch events through the resource handlers
+type Replayer struct {
+	neo4jClient *neo4j.Client
+	handlers    map[string]handlers.ResourceHandler
+	namespaces  *kubernetes.NamespaceFilter
+	speed       float64
+}
+
+// NewReplayer creates a replayer applying the handlers, disabled handlers and
+// namespace filters of cfg
+func NewReplayer(cfg *config.Config, neo4jClient *neo4j.Client) *Replayer {
+	disabled := make(map[string]bool)
+	for _, kind := range cfg.Handlers.Disabled {
+		disabled[kind] = true
+	}
+	handlerSet := make(map[string]handlers.ResourceHandler)
+	for _, handler := range kubernetes.NewResourceHandlers(nil, cfg) {
+		if !disabled[handler.GetKind()] {
+			handlerSet[handler.GetKind()] = handler
+		}
+	}
+	return &Replayer{
+		neo4jClient: neo4jClient,
+		handlers:    handlerSet,
+		namespaces:  kubernetes.NewNamespaceFilter(cfg.Filters.Namespaces, cfg.Filters.ExcludeNamespaces),
+		speed:       cfg.Replay.Speed,
+	}
+}
+
+// ReplayFile replays the recording at path
+func (r *Replayer) ReplayFile(ctx context.Context, path string) (Summary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+	return r.Replay(ctx, file)
+}
+
+// Replay replays the events of a recording in order, spacing them as they
+// were recorded divided by the speed of the replayer
+func (r *Replayer) Replay(ctx context.Context, in io.Reader) (Summary, error) {
+	var summary Summary
+	start := time.Now()
+	var first time.Time
+
+	reader := bufio.NewReader(in)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return summary, fmt.Errorf("failed to read recording: %w", err)
+		}
+		if data := bytes.TrimSpace(data); len(data) > 0 {
+			var event kubernetes.RecordedEvent
+			if jsonErr := json.Unmarshal(data, &event); jsonErr != nil {
+				return summary, fmt.Errorf("line %d: invalid event: %w", line, jsonErr)
+			}
+			if first.IsZero() {
+				first = event.Time
+			}
+			if err := sleep(ctx, delay(first, event.Time, time.Since(start), r.speed)); err != nil {
+				summary.Duration = time.Since(start)
+				return summary, err
+			}
+			summary.Events++
+			r.apply(ctx, event, &summary)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	summary.Duration = time.Since(start)
+	return summary, nil
+}
+
+// apply passes event to the handler of its kind
+func (r *Replayer) apply(ctx context.Context, event kubernetes.RecordedEvent, summary *Summary) {
+	obj := &unstructured.Unstructured{Object: event.Object}
+	handler, ok := r.handlers[event.Kind]
+	if !ok || !r.namespaces.Allows(obj) {
+		summary.Skipped++
+		return
+	}
+
+	var err error
+	switch event.Type {
+	case kubernetes.EventAdded, kubernetes.EventModified:
+		err = handler.HandleCreate(ctx, obj, r.neo4jClient)
+	case kubernetes.EventDeleted:
+		err = handler.HandleDelete(ctx, obj, r.neo4jClient)
+	default:
+		logger.Warn("[REPLAY] Skipping %s %s/%s: unsupported event type %q", event.Kind, obj.GetNamespace(), obj.GetName(), event.Type)
+		summary.Skipped++
+		return
+	}
+	if err != nil {
+		category := failure.Record(event.Kind, err)
+		logger.Error("[REPLAY] Failed to handle %s of %s %s/%s (%s): %v", event.Type, event.Kind, obj.GetNamespace(), obj.GetName(), category, err)
+		summary.Failed++
+		return
+	}
+	summary.Applied++
+}
+
+// delay returns how long to wait before replaying an event recorded at
+// recorded, elapsed after the start of the replay. A speed of 0 or less
+// replays without waiting.
+func delay(first, recorded time.Time, elapsed time.Duration, speed float64) time.Duration {
+	if speed <= 0 {
+		return 0
+	}
+	due := time.Duration(float64(recorded.Sub(first)) / speed)
+	return max(due-elapsed, 0)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s-graph/pkg/graph"
+	"k8s-graph/pkg/kubernetes"
+	"k8s-graph/pkg/kubernetes/handlers"
+	"k8s-graph/pkg/logger"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeHandler records the events it handles
+type fakeHandler struct {
+	kind   string
+	events []string
+}
+
+func (h *fakeHandler) GetGVR() schema.GroupVersionResource { return schema.GroupVersionResource{} }
+func (h *fakeHandler) GetKind() string                     { return h.kind }
+
+func (h *fakeHandler) HandleCreate(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	name := obj.(*unstructured.Unstructured).GetName()
+	h.events = append(h.events, "create "+name)
+	if name == "broken" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (h *fakeHandler) HandleDelete(ctx context.Context, obj interface{}, neo4jClient graph.Store) error {
+	h.events = append(h.events, "delete "+obj.(*unstructured.Unstructured).GetName())
+	return nil
+}
+
+const recording = `{"time":"2026-10-15T10:00:00Z","type":"ADDED","kind":"Pod","object":{"kind":"Pod","metadata":{"name":"api","namespace":"default"}}}
+{"time":"2026-10-15T10:00:01Z","type":"ADDED","kind":"Pod","object":{"kind":"Pod","metadata":{"name":"dns","namespace":"kube-system"}}}
+
+{"time":"2026-10-15T10:00:02Z","type":"ADDED","kind":"Widget","object":{"kind":"Widget","metadata":{"name":"w","namespace":"default"}}}
+{"time":"2026-10-15T10:00:03Z","type":"MODIFIED","kind":"Pod","object":{"kind":"Pod","metadata":{"name":"broken","namespace":"default"}}}
+{"time":"2026-10-15T10:00:04Z","type":"DELETED","kind":"Pod","object":{"kind":"Pod","metadata":{"name":"api","namespace":"default"}}}
+`
+
+func TestReplay(t *testing.T) {
+	logger.Init(logger.ERROR)
+	pods := &fakeHandler{kind: "Pod"}
+	r := &Replayer{
+		handlers:   map[string]handlers.ResourceHandler{"Pod": pods},
+		namespaces: kubernetes.NewNamespaceFilter(nil, []string{"kube-system"}),
+		speed:      0,
+	}
+
+	summary, err := r.Replay(context.Background(), strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if summary.Events != 5 || summary.Applied != 2 || summary.Failed != 1 || summary.Skipped != 2 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	expected := []string{"create api", "create broken", "delete api"}
+	if strings.Join(pods.events, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, pods.events)
+	}
+
+	if _, err := r.Replay(context.Background(), strings.NewReader("{not json}\n")); err == nil {
+		t.Error("Expected an invalid line to fail the replay")
+	}
+}
+
+func TestReplayCanceled(t *testing.T) {
+	r := &Replayer{handlers: map[string]handlers.ResourceHandler{}, namespaces: kubernetes.NewNamespaceFilter(nil, nil), speed: 1}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// The second event is due an hour after the first
+	in := `{"time":"2026-10-15T10:00:00Z","type":"ADDED","kind":"Pod","object":{}}
+{"time":"2026-10-15T11:00:00Z","type":"ADDED","kind":"Pod","object":{}}
+`
+	summary, err := r.Replay(ctx, strings.NewReader(in))
+	if !errors.Is(err, context.DeadlineExceeded) || summary.Events != 1 {
+		t.Errorf("Expected the replay to stop while waiting, got %d events (%v)", summary.Events, err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	first := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		recorded time.Duration
+		elapsed  time.Duration
+		speed    float64
+		expected time.Duration
+	}{
+		{10 * time.Second, 0, 1, 10 * time.Second},
+		{10 * time.Second, 4 * time.Second, 1, 6 * time.Second},
+		{10 * time.Second, 0, 10, time.Second},
+		{10 * time.Second, 0, 0.5, 20 * time.Second},
+		{10 * time.Second, 15 * time.Second, 1, 0},
+		{10 * time.Second, 0, 0, 0},
+	}
+	for _, test := range tests {
+		if got := delay(first, first.Add(test.recorded), test.elapsed, test.speed); got != test.expected {
+			t.Errorf("delay(%v, elapsed %v, speed %v): expected %v, got %v", test.recorded, test.elapsed, test.speed, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,470 @@
+// Explores the graph served by the agent: resources found by a search are
+// added to a force-directed layout on a canvas and expanded on demand with
+// their neighbors. Shares the layout of graph.js but loads data from the
+// /api/v1/graph endpoints instead of an embedded subgraph.
+(function () {
+  "use strict";
+
+  var canvas = document.getElementById("canvas");
+  var context = canvas.getContext("2d");
+  var radius = 7;
+
+  var nodes = [], edges = [], byId = {}, edgeIds = {}, expanded = {};
+  var kinds = {}, types = {};
+  var view = { x: 0, y: 0, scale: 1 };
+  var selected = null;
+  var alpha = 1;
+
+  function element(tag, text) {
+    var el = document.createElement(tag);
+    if (text !== undefined) {
+      el.textContent = text;
+    }
+    return el;
+  }
+
+  // color gives kinds a stable color derived from their name
+  function color(kind) {
+    var hash = 0;
+    for (var i = 0; i < kind.length; i++) {
+      hash = (hash * 31 + kind.charCodeAt(i)) % 360;
+    }
+    return "hsl(" + hash + ", 65%, 50%)";
+  }
+
+  function request(path, params) {
+    var query = Object.keys(params).filter(function (key) { return params[key] !== ""; }).map(function (key) {
+      return encodeURIComponent(key) + "=" + encodeURIComponent(params[key]);
+    }).join("&");
+    return fetch(path + (query ? "?" + query : "")).then(function (response) {
+      if (!response.ok) {
+        return response.text().then(function (text) { throw new Error(text || response.statusText); });
+      }
+      return response.json();
+    });
+  }
+
+  // addNode adds a resource near the given position, or returns the existing one
+  function addNode(data, near) {
+    if (byId[data.id]) {
+      return byId[data.id];
+    }
+    var angle = Math.random() * 2 * Math.PI;
+    var node = Object.assign({ vx: 0, vy: 0, edges: [] }, data);
+    node.x = (near ? near.x : 0) + 40 * Math.cos(angle);
+    node.y = (near ? near.y : 0) + 40 * Math.sin(angle);
+    nodes.push(node);
+    byId[node.id] = node;
+    if (!kinds[node.kind]) {
+      kinds[node.kind] = { color: color(node.kind), count: 0, visible: true };
+    }
+    kinds[node.kind].count++;
+    return node;
+  }
+
+  function addEdge(data) {
+    var key = data.source + "|" + data.type + "|" + data.target;
+    var source = byId[data.source], target = byId[data.target];
+    if (edgeIds[key] || !source || !target) {
+      return;
+    }
+    edgeIds[key] = true;
+    var link = { source: source, target: target, type: data.type };
+    edges.push(link);
+    source.edges.push(link);
+    target.edges.push(link);
+    if (!types[data.type]) {
+      types[data.type] = { count: 0, visible: true };
+    }
+    types[data.type].count++;
+  }
+
+  function visible(node) {
+    return kinds[node.kind].visible;
+  }
+
+  function shown(edge) {
+    return types[edge.type].visible && visible(edge.source) && visible(edge.target);
+  }
+
+  // simulate advances the layout by one step: nodes repel each other,
+  // relationships pull their ends together and gravity keeps the graph centered
+  function simulate() {
+    var active = nodes.filter(visible);
+    for (var i = 0; i < active.length; i++) {
+      var a = active[i];
+      for (var j = i + 1; j < active.length; j++) {
+        var b = active[j];
+        var dx = b.x - a.x, dy = b.y - a.y;
+        var d2 = dx * dx + dy * dy || 0.01;
+        if (d2 > 90000) {
+          continue;
+        }
+        var force = 400 * alpha / d2;
+        a.vx -= dx * force; a.vy -= dy * force;
+        b.vx += dx * force; b.vy += dy * force;
+      }
+    }
+    edges.forEach(function (edge) {
+      if (!shown(edge)) {
+        return;
+      }
+      var dx = edge.target.x - edge.source.x, dy = edge.target.y - edge.source.y;
+      var d = Math.sqrt(dx * dx + dy * dy) || 0.01;
+      var force = (d - 60) / d * 0.05 * alpha;
+      edge.source.vx += dx * force; edge.source.vy += dy * force;
+      edge.target.vx -= dx * force; edge.target.vy -= dy * force;
+    });
+    active.forEach(function (node) {
+      node.vx -= node.x * 0.002 * alpha;
+      node.vy -= node.y * 0.002 * alpha;
+      if (node !== dragging) {
+        node.x += node.vx; node.y += node.vy;
+      }
+      node.vx *= 0.6; node.vy *= 0.6;
+    });
+    alpha = Math.max(alpha * 0.99, 0);
+  }
+
+  function resize() {
+    var ratio = window.devicePixelRatio || 1;
+    canvas.width = canvas.clientWidth * ratio;
+    canvas.height = canvas.clientHeight * ratio;
+    context.setTransform(ratio, 0, 0, ratio, 0, 0);
+  }
+
+  function toScreen(node) {
+    return { x: (node.x - view.x) * view.scale + canvas.clientWidth / 2, y: (node.y - view.y) * view.scale + canvas.clientHeight / 2 };
+  }
+
+  function toWorld(x, y) {
+    return { x: (x - canvas.clientWidth / 2) / view.scale + view.x, y: (y - canvas.clientHeight / 2) / view.scale + view.y };
+  }
+
+  function neighbors(node) {
+    var result = {};
+    if (node) {
+      node.edges.forEach(function (edge) {
+        if (shown(edge)) {
+          result[edge.source.id] = true;
+          result[edge.target.id] = true;
+        }
+      });
+    }
+    return result;
+  }
+
+  function draw() {
+    context.clearRect(0, 0, canvas.clientWidth, canvas.clientHeight);
+    var near = neighbors(selected);
+
+    edges.forEach(function (edge) {
+      if (!shown(edge)) {
+        return;
+      }
+      var from = toScreen(edge.source), to = toScreen(edge.target);
+      var highlighted = selected && (edge.source === selected || edge.target === selected);
+      context.strokeStyle = highlighted ? "#0969da" : selected ? "rgba(140,149,159,0.2)" : "rgba(140,149,159,0.6)";
+      context.lineWidth = highlighted ? 2 : 1;
+      context.beginPath();
+      context.moveTo(from.x, from.y);
+      context.lineTo(to.x, to.y);
+      context.stroke();
+
+      // Arrow head at the target
+      var angle = Math.atan2(to.y - from.y, to.x - from.x);
+      var tip = { x: to.x - Math.cos(angle) * radius, y: to.y - Math.sin(angle) * radius };
+      context.fillStyle = context.strokeStyle;
+      context.beginPath();
+      context.moveTo(tip.x, tip.y);
+      context.lineTo(tip.x - 8 * Math.cos(angle - 0.4), tip.y - 8 * Math.sin(angle - 0.4));
+      context.lineTo(tip.x - 8 * Math.cos(angle + 0.4), tip.y - 8 * Math.sin(angle + 0.4));
+      context.fill();
+
+      if (highlighted || view.scale > 1.6) {
+        context.fillStyle = "#656d76";
+        context.font = "10px sans-serif";
+        context.fillText(edge.type, (from.x + to.x) / 2 + 3, (from.y + to.y) / 2 - 3);
+      }
+    });
+
+    nodes.forEach(function (node) {
+      if (!visible(node)) {
+        return;
+      }
+      var point = toScreen(node);
+      var dimmed = selected && !near[node.id] && node !== selected;
+      context.globalAlpha = dimmed ? 0.25 : 1;
+      context.fillStyle = kinds[node.kind].color;
+      context.beginPath();
+      context.arc(point.x, point.y, radius, 0, 2 * Math.PI);
+      context.fill();
+      // Resources whose neighbors are not loaded yet get a dashed ring
+      if (node === selected || !expanded[node.id]) {
+        context.strokeStyle = "#1f2328";
+        context.lineWidth = node === selected ? 3 : 1;
+        context.setLineDash(node === selected ? [] : [2, 2]);
+        context.stroke();
+        context.setLineDash([]);
+      }
+      if (node === selected || near[node.id] || view.scale > 1.2 || nodes.length < 30) {
+        context.fillStyle = "#1f2328";
+        context.font = "11px sans-serif";
+        context.fillText(node.name, point.x + radius + 3, point.y + 4);
+      }
+      context.globalAlpha = 1;
+    });
+  }
+
+  function frame() {
+    if (alpha > 0.005) {
+      simulate();
+    }
+    draw();
+    window.requestAnimationFrame(frame);
+  }
+
+  function nodeAt(x, y) {
+    var point = toWorld(x, y);
+    var best = null, bestDistance = (radius + 3) / view.scale;
+    nodes.forEach(function (node) {
+      if (!visible(node)) {
+        return;
+      }
+      var d = Math.sqrt((node.x - point.x) * (node.x - point.x) + (node.y - point.y) * (node.y - point.y));
+      if (d < bestDistance) {
+        best = node;
+        bestDistance = d;
+      }
+    });
+    return best;
+  }
+
+  function row(table, key, value) {
+    var tr = element("tr");
+    tr.appendChild(element("td", key));
+    var td = element("td");
+    if (value instanceof Node) {
+      td.appendChild(value);
+    } else {
+      td.textContent = value;
+    }
+    tr.appendChild(td);
+    table.appendChild(tr);
+  }
+
+  function select(node) {
+    selected = node;
+    var details = document.getElementById("details");
+    details.textContent = "";
+    if (!node) {
+      details.appendChild(element("p", "Click a resource to see its properties and relationships."));
+      details.firstChild.className = "hint";
+      return;
+    }
+
+    var expand = element("button", expanded[node.id] ? "Reload neighbors" : "Expand");
+    expand.onclick = function () { expandNode(node); };
+    details.appendChild(expand);
+
+    var table = element("table");
+    row(table, "kind", node.kind);
+    row(table, "name", node.name);
+    if (node.namespace) {
+      row(table, "namespace", node.namespace);
+    }
+    Object.keys(node.properties || {}).sort().forEach(function (key) {
+      if (key !== "name" && key !== "namespace") {
+        row(table, key, String(node.properties[key]));
+      }
+    });
+    details.appendChild(table);
+
+    var related = node.edges.filter(shown);
+    details.appendChild(element("h2", "Relationships (" + related.length + ")"));
+    var list = element("table");
+    related.forEach(function (edge) {
+      var other = edge.source === node ? edge.target : edge.source;
+      var link = element("a", other.kind + " " + other.name);
+      link.onclick = function () { focus(other); };
+      row(list, edge.source === node ? edge.type + " →" : "← " + edge.type, link);
+    });
+    details.appendChild(list);
+  }
+
+  function focus(node) {
+    if (!visible(node)) {
+      kinds[node.kind].visible = true;
+      render();
+    }
+    view.x = node.x;
+    view.y = node.y;
+    view.scale = Math.max(view.scale, 1.5);
+    select(node);
+  }
+
+  // expandNode loads the neighbors of a resource and adds them around it
+  function expandNode(node) {
+    status("Loading neighbors of " + node.kind + " " + node.name + "…");
+    request("../api/v1/graph/neighborhood", { id: node.id }).then(function (graph) {
+      graph.nodes.forEach(function (data) { addNode(data, node); });
+      graph.edges.forEach(addEdge);
+      expanded[node.id] = true;
+      alpha = Math.max(alpha, 0.5);
+      render();
+      select(node);
+      if (graph.truncated) {
+        status("Only the first " + graph.nodes.length + " neighbors of " + node.name + " were loaded");
+      }
+    }).catch(function (error) {
+      status("Failed to load neighbors: " + error.message, true);
+    });
+  }
+
+  function legend(id, entries, colors, onchange) {
+    var container = document.getElementById(id);
+    container.textContent = "";
+    Object.keys(entries).sort().forEach(function (name) {
+      var label = element("label");
+      var checkbox = element("input");
+      checkbox.type = "checkbox";
+      checkbox.checked = entries[name].visible;
+      checkbox.onchange = function () {
+        entries[name].visible = checkbox.checked;
+        onchange();
+      };
+      label.appendChild(checkbox);
+      if (colors) {
+        var swatch = element("span");
+        swatch.className = "swatch";
+        swatch.style.background = entries[name].color;
+        label.appendChild(swatch);
+      }
+      label.appendChild(element("span", name));
+      var count = element("span", entries[name].count);
+      count.className = "count";
+      label.appendChild(count);
+      container.appendChild(label);
+    });
+  }
+
+  function filtersChanged() {
+    if (selected && !visible(selected)) {
+      select(null);
+    } else {
+      select(selected);
+    }
+    alpha = Math.max(alpha, 0.3);
+  }
+
+  function render() {
+    legend("kinds", kinds, true, filtersChanged);
+    legend("types", types, false, filtersChanged);
+    document.getElementById("empty").style.display = nodes.length ? "none" : "";
+    status();
+  }
+
+  function status(message, error) {
+    var summary = document.getElementById("summary");
+    summary.className = error ? "summary error" : "summary";
+    summary.textContent = message || nodes.length + " resources, " + edges.length + " relationships";
+  }
+
+  function showResults(found) {
+    var results = document.getElementById("results");
+    results.textContent = "";
+    if (found.nodes.length === 0) {
+      results.appendChild(element("p", "No matching resources."));
+      results.firstChild.className = "hint";
+      return;
+    }
+    found.nodes.forEach(function (data) {
+      var link = element("a", data.kind + " " + (data.namespace ? data.namespace + "/" : "") + data.name);
+      link.onclick = function () {
+        var node = addNode(data, null);
+        render();
+        focus(node);
+        if (!expanded[node.id]) {
+          expandNode(node);
+        }
+      };
+      results.appendChild(link);
+    });
+    if (found.truncated) {
+      var more = element("p", "Only the first " + found.nodes.length + " matches are listed, refine the search.");
+      more.className = "hint";
+      results.appendChild(more);
+    }
+  }
+
+  var dragging = null, panning = null, moved = false;
+
+  canvas.addEventListener("mousedown", function (event) {
+    moved = false;
+    dragging = nodeAt(event.offsetX, event.offsetY);
+    if (!dragging) {
+      panning = { x: event.offsetX, y: event.offsetY, viewX: view.x, viewY: view.y };
+    }
+  });
+  canvas.addEventListener("mousemove", function (event) {
+    if (dragging) {
+      var point = toWorld(event.offsetX, event.offsetY);
+      dragging.x = point.x;
+      dragging.y = point.y;
+      alpha = Math.max(alpha, 0.1);
+      moved = true;
+    } else if (panning) {
+      view.x = panning.viewX - (event.offsetX - panning.x) / view.scale;
+      view.y = panning.viewY - (event.offsetY - panning.y) / view.scale;
+      moved = true;
+    }
+  });
+  window.addEventListener("mouseup", function (event) {
+    if (!moved && event.target === canvas) {
+      select(nodeAt(event.offsetX, event.offsetY));
+    }
+    dragging = null;
+    panning = null;
+  });
+  canvas.addEventListener("dblclick", function (event) {
+    var node = nodeAt(event.offsetX, event.offsetY);
+    if (node) {
+      expandNode(node);
+    }
+  });
+  canvas.addEventListener("wheel", function (event) {
+    event.preventDefault();
+    var before = toWorld(event.offsetX, event.offsetY);
+    view.scale = Math.min(8, Math.max(0.1, view.scale * Math.exp(-event.deltaY * 0.001)));
+    var after = toWorld(event.offsetX, event.offsetY);
+    view.x += before.x - after.x;
+    view.y += before.y - after.y;
+  }, { passive: false });
+
+  document.getElementById("search").addEventListener("submit", function (event) {
+    event.preventDefault();
+    var params = {};
+    ["name", "namespace", "kind", "cluster"].forEach(function (field) {
+      params[field] = document.getElementById(field).value.trim();
+    });
+    status("Searching…");
+    request("../api/v1/graph/search", params).then(function (found) {
+      showResults(found);
+      status();
+    }).catch(function (error) {
+      status("Search failed: " + error.message, true);
+    });
+  });
+
+  document.getElementById("clear").addEventListener("click", function () {
+    nodes = []; edges = []; byId = {}; edgeIds = {}; expanded = {}; kinds = {}; types = {};
+    view = { x: 0, y: 0, scale: 1 };
+    select(null);
+    render();
+  });
+
+  window.addEventListener("resize", resize);
+  resize();
+  render();
+  window.requestAnimationFrame(frame);
+})();
@@ -0,0 +1,348 @@
+// Renders the exported subgraph in KUBEGRAPH with a force-directed layout on
+// a canvas. Kept dependency-free so the page works offline from a single file.
+(function () {
+  "use strict";
+
+  var data = KUBEGRAPH;
+  var canvas = document.getElementById("canvas");
+  var context = canvas.getContext("2d");
+  var radius = 7;
+
+  var nodes = data.nodes.map(function (node, i) {
+    var angle = i * 2.399963; // golden angle spreads the initial positions evenly
+    var distance = 12 * Math.sqrt(i + 1);
+    return Object.assign({ x: distance * Math.cos(angle), y: distance * Math.sin(angle), vx: 0, vy: 0, edges: [] }, node);
+  });
+  var byId = {};
+  nodes.forEach(function (node) { byId[node.id] = node; });
+  var edges = [];
+  data.edges.forEach(function (edge) {
+    var source = byId[edge.source], target = byId[edge.target];
+    if (!source || !target) {
+      return;
+    }
+    var link = { source: source, target: target, type: edge.type };
+    edges.push(link);
+    source.edges.push(link);
+    target.edges.push(link);
+  });
+
+  // Kinds get a stable color derived from their name
+  var kinds = {};
+  nodes.forEach(function (node) {
+    if (!kinds[node.kind]) {
+      var hash = 0;
+      for (var i = 0; i < node.kind.length; i++) {
+        hash = (hash * 31 + node.kind.charCodeAt(i)) % 360;
+      }
+      kinds[node.kind] = { color: "hsl(" + hash + ", 65%, 50%)", count: 0, visible: true };
+    }
+    kinds[node.kind].count++;
+  });
+
+  var view = { x: 0, y: 0, scale: 1 };
+  var selected = null;
+  var matches = [];
+  var alpha = 1;
+
+  function visible(node) {
+    return kinds[node.kind].visible;
+  }
+
+  // simulate advances the layout by one step: nodes repel each other,
+  // relationships pull their ends together and gravity keeps the graph centered
+  function simulate() {
+    var active = nodes.filter(visible);
+    for (var i = 0; i < active.length; i++) {
+      var a = active[i];
+      for (var j = i + 1; j < active.length; j++) {
+        var b = active[j];
+        var dx = b.x - a.x, dy = b.y - a.y;
+        var d2 = dx * dx + dy * dy || 0.01;
+        if (d2 > 90000) {
+          continue;
+        }
+        var force = 400 * alpha / d2;
+        a.vx -= dx * force; a.vy -= dy * force;
+        b.vx += dx * force; b.vy += dy * force;
+      }
+    }
+    edges.forEach(function (edge) {
+      if (!visible(edge.source) || !visible(edge.target)) {
+        return;
+      }
+      var dx = edge.target.x - edge.source.x, dy = edge.target.y - edge.source.y;
+      var d = Math.sqrt(dx * dx + dy * dy) || 0.01;
+      var force = (d - 60) / d * 0.05 * alpha;
+      edge.source.vx += dx * force; edge.source.vy += dy * force;
+      edge.target.vx -= dx * force; edge.target.vy -= dy * force;
+    });
+    active.forEach(function (node) {
+      node.vx -= node.x * 0.002 * alpha;
+      node.vy -= node.y * 0.002 * alpha;
+      if (node !== dragging) {
+        node.x += node.vx; node.y += node.vy;
+      }
+      node.vx *= 0.6; node.vy *= 0.6;
+    });
+    alpha = Math.max(alpha * 0.99, 0);
+  }
+
+  function resize() {
+    var ratio = window.devicePixelRatio || 1;
+    canvas.width = canvas.clientWidth * ratio;
+    canvas.height = canvas.clientHeight * ratio;
+    context.setTransform(ratio, 0, 0, ratio, 0, 0);
+  }
+
+  function toScreen(node) {
+    return { x: (node.x - view.x) * view.scale + canvas.clientWidth / 2, y: (node.y - view.y) * view.scale + canvas.clientHeight / 2 };
+  }
+
+  function toWorld(x, y) {
+    return { x: (x - canvas.clientWidth / 2) / view.scale + view.x, y: (y - canvas.clientHeight / 2) / view.scale + view.y };
+  }
+
+  function neighbors(node) {
+    var result = {};
+    if (node) {
+      node.edges.forEach(function (edge) {
+        result[edge.source.id] = true;
+        result[edge.target.id] = true;
+      });
+    }
+    return result;
+  }
+
+  function draw() {
+    context.clearRect(0, 0, canvas.clientWidth, canvas.clientHeight);
+    var near = neighbors(selected);
+
+    edges.forEach(function (edge) {
+      if (!visible(edge.source) || !visible(edge.target)) {
+        return;
+      }
+      var from = toScreen(edge.source), to = toScreen(edge.target);
+      var highlighted = selected && (edge.source === selected || edge.target === selected);
+      context.strokeStyle = highlighted ? "#0969da" : selected ? "rgba(140,149,159,0.2)" : "rgba(140,149,159,0.6)";
+      context.lineWidth = highlighted ? 2 : 1;
+      context.beginPath();
+      context.moveTo(from.x, from.y);
+      context.lineTo(to.x, to.y);
+      context.stroke();
+
+      // Arrow head at the target
+      var angle = Math.atan2(to.y - from.y, to.x - from.x);
+      var tip = { x: to.x - Math.cos(angle) * radius, y: to.y - Math.sin(angle) * radius };
+      context.fillStyle = context.strokeStyle;
+      context.beginPath();
+      context.moveTo(tip.x, tip.y);
+      context.lineTo(tip.x - 8 * Math.cos(angle - 0.4), tip.y - 8 * Math.sin(angle - 0.4));
+      context.lineTo(tip.x - 8 * Math.cos(angle + 0.4), tip.y - 8 * Math.sin(angle + 0.4));
+      context.fill();
+
+      if (highlighted || view.scale > 1.6) {
+        context.fillStyle = "#656d76";
+        context.font = "10px sans-serif";
+        context.fillText(edge.type, (from.x + to.x) / 2 + 3, (from.y + to.y) / 2 - 3);
+      }
+    });
+
+    nodes.forEach(function (node) {
+      if (!visible(node)) {
+        return;
+      }
+      var point = toScreen(node);
+      var dimmed = selected && !near[node.id] && node !== selected;
+      context.globalAlpha = dimmed ? 0.25 : 1;
+      context.fillStyle = kinds[node.kind].color;
+      context.beginPath();
+      context.arc(point.x, point.y, radius, 0, 2 * Math.PI);
+      context.fill();
+      if (node === selected || matches.indexOf(node) >= 0) {
+        context.strokeStyle = "#1f2328";
+        context.lineWidth = 3;
+        context.stroke();
+      }
+      if (node === selected || near[node.id] || matches.indexOf(node) >= 0 || view.scale > 1.2) {
+        context.fillStyle = "#1f2328";
+        context.font = "11px sans-serif";
+        context.fillText(node.name, point.x + radius + 3, point.y + 4);
+      }
+      context.globalAlpha = 1;
+    });
+  }
+
+  function frame() {
+    if (alpha > 0.005) {
+      simulate();
+    }
+    draw();
+    window.requestAnimationFrame(frame);
+  }
+
+  function nodeAt(x, y) {
+    var point = toWorld(x, y);
+    var best = null, bestDistance = (radius + 3) / view.scale;
+    nodes.forEach(function (node) {
+      if (!visible(node)) {
+        return;
+      }
+      var d = Math.sqrt((node.x - point.x) * (node.x - point.x) + (node.y - point.y) * (node.y - point.y));
+      if (d < bestDistance) {
+        best = node;
+        bestDistance = d;
+      }
+    });
+    return best;
+  }
+
+  function element(tag, text) {
+    var el = document.createElement(tag);
+    if (text !== undefined) {
+      el.textContent = text;
+    }
+    return el;
+  }
+
+  function row(table, key, value) {
+    var tr = element("tr");
+    tr.appendChild(element("td", key));
+    var td = element("td");
+    if (value instanceof Node) {
+      td.appendChild(value);
+    } else {
+      td.textContent = value;
+    }
+    tr.appendChild(td);
+    table.appendChild(tr);
+  }
+
+  function select(node) {
+    selected = node;
+    var details = document.getElementById("details");
+    details.textContent = "";
+    if (!node) {
+      details.appendChild(element("p", "Click a resource to see its properties and relationships."));
+      details.firstChild.className = "hint";
+      return;
+    }
+
+    var table = element("table");
+    row(table, "kind", node.kind);
+    row(table, "name", node.name);
+    if (node.namespace) {
+      row(table, "namespace", node.namespace);
+    }
+    Object.keys(node.properties || {}).sort().forEach(function (key) {
+      if (key !== "name" && key !== "namespace") {
+        row(table, key, String(node.properties[key]));
+      }
+    });
+    details.appendChild(table);
+
+    details.appendChild(element("h2", "Relationships (" + node.edges.length + ")"));
+    var related = element("table");
+    node.edges.forEach(function (edge) {
+      var other = edge.source === node ? edge.target : edge.source;
+      var link = element("a", other.kind + " " + other.name);
+      link.onclick = function () { focus(other); };
+      row(related, edge.source === node ? edge.type + " →" : "← " + edge.type, link);
+    });
+    details.appendChild(related);
+  }
+
+  function focus(node) {
+    if (!visible(node)) {
+      kinds[node.kind].visible = true;
+      renderLegend();
+    }
+    view.x = node.x;
+    view.y = node.y;
+    view.scale = Math.max(view.scale, 1.5);
+    select(node);
+  }
+
+  function renderLegend() {
+    var legend = document.getElementById("legend");
+    legend.textContent = "";
+    Object.keys(kinds).sort().forEach(function (kind) {
+      var label = element("label");
+      var checkbox = element("input");
+      checkbox.type = "checkbox";
+      checkbox.checked = kinds[kind].visible;
+      checkbox.onchange = function () {
+        kinds[kind].visible = checkbox.checked;
+        if (selected && !visible(selected)) {
+          select(null);
+        }
+        alpha = Math.max(alpha, 0.3);
+      };
+      var swatch = element("span");
+      swatch.className = "swatch";
+      swatch.style.background = kinds[kind].color;
+      var count = element("span", kinds[kind].count);
+      count.className = "count";
+      label.appendChild(checkbox);
+      label.appendChild(swatch);
+      label.appendChild(element("span", kind));
+      label.appendChild(count);
+      legend.appendChild(label);
+    });
+  }
+
+  var dragging = null, panning = null, moved = false;
+
+  canvas.addEventListener("mousedown", function (event) {
+    moved = false;
+    dragging = nodeAt(event.offsetX, event.offsetY);
+    if (!dragging) {
+      panning = { x: event.offsetX, y: event.offsetY, viewX: view.x, viewY: view.y };
+    }
+  });
+  canvas.addEventListener("mousemove", function (event) {
+    if (dragging) {
+      var point = toWorld(event.offsetX, event.offsetY);
+      dragging.x = point.x;
+      dragging.y = point.y;
+      alpha = Math.max(alpha, 0.1);
+      moved = true;
+    } else if (panning) {
+      view.x = panning.viewX - (event.offsetX - panning.x) / view.scale;
+      view.y = panning.viewY - (event.offsetY - panning.y) / view.scale;
+      moved = true;
+    }
+  });
+  window.addEventListener("mouseup", function (event) {
+    if (!moved && event.target === canvas) {
+      select(nodeAt(event.offsetX, event.offsetY));
+    }
+    dragging = null;
+    panning = null;
+  });
+  canvas.addEventListener("wheel", function (event) {
+    event.preventDefault();
+    var before = toWorld(event.offsetX, event.offsetY);
+    view.scale = Math.min(8, Math.max(0.1, view.scale * Math.exp(-event.deltaY * 0.001)));
+    var after = toWorld(event.offsetX, event.offsetY);
+    view.x += before.x - after.x;
+    view.y += before.y - after.y;
+  }, { passive: false });
+
+  document.getElementById("search").addEventListener("input", function (event) {
+    var term = event.target.value.trim().toLowerCase();
+    matches = term === "" ? [] : nodes.filter(function (node) {
+      return node.name.toLowerCase().indexOf(term) >= 0;
+    });
+    if (matches.length > 0) {
+      focus(matches[0]);
+    }
+  });
+
+  document.getElementById("summary").textContent = nodes.length + " resources, " + edges.length +
+    " relationships, exported " + new Date(data.generatedAt).toLocaleString();
+  window.addEventListener("resize", resize);
+  resize();
+  renderLegend();
+  window.requestAnimationFrame(frame);
+})();
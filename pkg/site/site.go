@@ -0,0 +1,131 @@
+// Package site renders an exported subgraph as a self-contained HTML page
+// that can be explored in a browser without access to Neo4j.
+package site
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//go:embed assets/index.html assets/graph.js assets/explorer.html assets/explorer.js
+var assets embed.FS
+
+// omittedProperties hold configuration payloads that should not end up in a
+// page meant to be shared
+var omittedProperties = map[string]bool{
+	"data":       true,
+	"binaryData": true,
+}
+
+// Node is a resource of the exported subgraph
+type Node struct {
+	ID         string                 `json:"id"`
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Edge is a relationship between two nodes of the exported subgraph
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// NewNode converts a graph node with the given ID, labels and properties,
+// leaving out configuration payloads. The first label is the kind.
+func NewNode(id string, labels []string, properties map[string]interface{}) Node {
+	kept := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		if !omittedProperties[key] {
+			kept[key] = value
+		}
+	}
+	node := Node{ID: id, Properties: kept}
+	if len(labels) > 0 {
+		node.Kind = labels[0]
+	}
+	node.Name, _ = properties["name"].(string)
+	node.Namespace, _ = properties["namespace"].(string)
+	return node
+}
+
+// Graph is the subgraph embedded in the page
+type Graph struct {
+	Title       string    `json:"title"`
+	Cluster     string    `json:"cluster,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Nodes       []Node    `json:"nodes"`
+	Edges       []Edge    `json:"edges"`
+}
+
+// Render returns the HTML page with the graph and the renderer inlined, so
+// the page works when opened from disk
+func Render(graph Graph) ([]byte, error) {
+	page, err := assets.ReadFile("assets/index.html")
+	if err != nil {
+		return nil, err
+	}
+	script, err := assets.ReadFile("assets/graph.js")
+	if err != nil {
+		return nil, err
+	}
+	// json.Marshal escapes <, > and &, so the data cannot close the script element
+	data, err := json.Marshal(graph)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode graph: %w", err)
+	}
+
+	replacer := strings.NewReplacer(
+		"{{TITLE}}", html.EscapeString(graph.Title),
+		"{{GRAPH}}", string(data),
+		"{{SCRIPT}}", string(script),
+	)
+	return []byte(replacer.Replace(string(page))), nil
+}
+
+// Explorer returns the page of the graph explorer served by the agent, which
+// loads resources from its /api/v1/graph endpoints instead of embedding them
+func Explorer() ([]byte, error) {
+	page, err := assets.ReadFile("assets/explorer.html")
+	if err != nil {
+		return nil, err
+	}
+	script, err := assets.ReadFile("assets/explorer.js")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Replace(string(page), "{{SCRIPT}}", string(script), 1)), nil
+}
+
+// Write creates dir with index.html and graph.json, the raw subgraph for
+// use with other tools
+func Write(dir string, graph Graph) error {
+	page, err := Render(graph)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode graph: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), page, 0644); err != nil {
+		return fmt.Errorf("failed to write index.html: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "graph.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write graph.json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testGraph() Graph {
+	return Graph{
+		Title:       "payments <prod>",
+		Namespace:   "payments",
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Nodes: []Node{
+			{ID: "1", Kind: "Pod", Name: "api-0", Namespace: "payments", Properties: map[string]interface{}{"phase": "</script><script>alert(1)"}},
+			{ID: "2", Kind: "Node", Name: "worker-1"},
+		},
+		Edges: []Edge{{Source: "1", Target: "2", Type: "SCHEDULED_ON"}},
+	}
+}
+
+func TestRenderInlinesGraphAndScript(t *testing.T) {
+	page, err := Render(testGraph())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	content := string(page)
+
+	if strings.Contains(content, "{{") {
+		t.Error("Expected all placeholders to be replaced")
+	}
+	if !strings.Contains(content, "<title>payments &lt;prod&gt;</title>") {
+		t.Error("Expected the title to be HTML escaped")
+	}
+	if !strings.Contains(content, `"type":"SCHEDULED_ON"`) {
+		t.Error("Expected the graph data to be embedded")
+	}
+	if !strings.Contains(content, "requestAnimationFrame") {
+		t.Error("Expected the renderer to be embedded")
+	}
+	if strings.Count(content, "</script>") != 2 {
+		t.Errorf("Expected property values not to close the script element, found %d closing tags", strings.Count(content, "</script>"))
+	}
+}
+
+func TestWriteCreatesBundle(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "site")
+	if err := Write(dir, testGraph()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err != nil {
+		t.Errorf("Expected index.html: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "graph.json"))
+	if err != nil {
+		t.Fatalf("Expected graph.json: %v", err)
+	}
+	var graph Graph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		t.Fatalf("Failed to decode graph.json: %v", err)
+	}
+	if len(graph.Nodes) != 2 || len(graph.Edges) != 1 {
+		t.Errorf("Expected 2 nodes and 1 edge, got %d and %d", len(graph.Nodes), len(graph.Edges))
+	}
+}
+
+func TestNewNodeOmitsPayloads(t *testing.T) {
+	node := NewNode("4:abc:1", []string{"ConfigMap"}, map[string]interface{}{
+		"name": "settings", "namespace": "payments", "data": `{"password":"secret"}`, "uid": "1234",
+	})
+
+	if node.Kind != "ConfigMap" || node.Name != "settings" || node.Namespace != "payments" {
+		t.Errorf("Expected ConfigMap payments/settings, got %s %s/%s", node.Kind, node.Namespace, node.Name)
+	}
+	if _, ok := node.Properties["data"]; ok {
+		t.Error("Expected data to be omitted")
+	}
+	if node.Properties["uid"] != "1234" {
+		t.Errorf("Expected uid to be kept, got %v", node.Properties["uid"])
+	}
+}
+
+func TestExplorerInlinesScript(t *testing.T) {
+	page, err := Explorer()
+	if err != nil {
+		t.Fatalf("Explorer failed: %v", err)
+	}
+	content := string(page)
+	if strings.Contains(content, "{{SCRIPT}}") {
+		t.Error("Expected the script placeholder to be replaced")
+	}
+	if !strings.Contains(content, "/api/v1/graph/neighborhood") {
+		t.Error("Expected the explorer script to be embedded")
+	}
+}
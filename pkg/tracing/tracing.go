@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s-graph/pkg/version"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by k8s-graph itself
+const instrumentationName = "k8s-graph"
+
+// Enabled reports whether tracing is configured through the standard
+// OpenTelemetry environment variables: an OTLP endpoint is set and the SDK
+// and trace exporter are not disabled
+func Enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" && exporter != "otlp" {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init installs a tracer provider exporting spans over OTLP/HTTP when tracing
+// is enabled. The exporter, sampler and resource read the standard OTEL_
+// environment variables (OTEL_EXPORTER_OTLP_*, OTEL_TRACES_SAMPLER*,
+// OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES). The returned function flushes
+// and stops the provider; it does nothing when tracing is disabled.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	if protocol := tracesProtocol(); protocol != "" && protocol != "http/protobuf" {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q: only http/protobuf is supported", protocol)
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	// Attributes from the environment take precedence over the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "k8s-graph"),
+			attribute.String("service.version", version.Version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// tracesProtocol returns the configured OTLP protocol for traces
+func tracesProtocol() string {
+	if protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"); protocol != "" {
+		return protocol
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+}
+
+// Start starts a span as a child of the span in ctx. Spans are dropped by the
+// default no-op provider when tracing is disabled.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Handler wraps an HTTP handler so every request is traced, continuing the
+// trace of the caller when the request carries one
+func Handler(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, "http",
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}))
+}
+
+// Transport wraps the transport of a Kubernetes client so API calls are
+// traced. Watches stay open for minutes and are left out.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt,
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Query().Get("watch") != "true"
+		}),
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return "kubernetes " + r.Method + " " + r.URL.Path
+		}))
+}
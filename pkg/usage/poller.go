@@ -0,0 +1,217 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/logger"
+	"k8s-graph/pkg/neo4j"
+
+	driverneo4j "github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	nodeMetricsResource = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+	podMetricsResource  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+)
+
+var usageSamplesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "kubegraph_usage_samples_total",
+		Help: "Total number of metrics-server usage samples written to the graph, by kind",
+	},
+	[]string{"kind"},
+)
+
+// Sample is the usage of a Node or Pod reported by metrics-server
+type Sample struct {
+	Namespace string // Empty for Nodes
+	Name      string
+	CPU       int64 // Millicores
+	Memory    int64 // Bytes
+	SampledAt string
+}
+
+// Poller periodically reads the usage of Nodes and Pods from the
+// metrics.k8s.io API and writes it onto their nodes as usageCPU (millicores),
+// usageMemory (bytes) and sampledAt
+type Poller struct {
+	config        *config.Config
+	dynamicClient dynamic.Interface
+	neo4jClient   *neo4j.Client
+}
+
+// NewPoller creates a new usage poller
+func NewPoller(cfg *config.Config, dynamicClient dynamic.Interface, neo4jClient *neo4j.Client) *Poller {
+	return &Poller{
+		config:        cfg,
+		dynamicClient: dynamicClient,
+		neo4jClient:   neo4jClient,
+	}
+}
+
+// Start polls every configured interval until ctx is done
+func (p *Poller) Start(ctx context.Context) {
+	interval := time.Duration(p.config.Usage.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		logger.Warn("[USAGE] Invalid interval %v, usage polling not started", interval)
+		return
+	}
+
+	if err := p.Run(ctx); err != nil {
+		logger.Error("[USAGE] Polling failed: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Run(ctx); err != nil {
+				logger.Error("[USAGE] Polling failed: %v", err)
+			}
+		}
+	}
+}
+
+// Run reads the current usage of all Nodes and Pods once and writes it to the graph
+func (p *Poller) Run(ctx context.Context) error {
+	nodes, err := p.list(ctx, nodeMetricsResource)
+	if err != nil {
+		return fmt.Errorf("failed to list node metrics (is metrics-server installed?): %w", err)
+	}
+	if err := p.write(ctx, "Node", nodes); err != nil {
+		return fmt.Errorf("failed to write node usage: %w", err)
+	}
+
+	pods, err := p.list(ctx, podMetricsResource)
+	if err != nil {
+		return fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	if err := p.write(ctx, "Pod", pods); err != nil {
+		return fmt.Errorf("failed to write pod usage: %w", err)
+	}
+
+	logger.Debug("[USAGE] Wrote usage of %d nodes and %d pods", len(nodes), len(pods))
+	return nil
+}
+
+// list returns the samples of all objects of a metrics.k8s.io resource
+func (p *Poller) list(ctx context.Context, gvr schema.GroupVersionResource) ([]Sample, error) {
+	list, err := p.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, 0, len(list.Items))
+	for i := range list.Items {
+		sample, err := sampleFromMetrics(&list.Items[i])
+		if err != nil {
+			logger.Warn("[USAGE] Skipping metrics of %s %s/%s: %v", gvr.Resource, list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+// sampleFromMetrics converts a NodeMetrics or PodMetrics object into a
+// sample. NodeMetrics carry their usage directly, PodMetrics per container.
+func sampleFromMetrics(obj *unstructured.Unstructured) (Sample, error) {
+	sample := Sample{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	if timestamp, found, _ := unstructured.NestedString(obj.Object, "timestamp"); found {
+		sample.SampledAt = timestamp
+	} else {
+		sample.SampledAt = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	usages := make([]map[string]interface{}, 0)
+	if usage, found, _ := unstructured.NestedMap(obj.Object, "usage"); found {
+		usages = append(usages, usage)
+	}
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "containers")
+	for _, container := range containers {
+		if fields, ok := container.(map[string]interface{}); ok {
+			if usage, found, _ := unstructured.NestedMap(fields, "usage"); found {
+				usages = append(usages, usage)
+			}
+		}
+	}
+
+	for _, usage := range usages {
+		cpu, err := parseQuantity(usage, "cpu")
+		if err != nil {
+			return Sample{}, err
+		}
+		memory, err := parseQuantity(usage, "memory")
+		if err != nil {
+			return Sample{}, err
+		}
+		sample.CPU += cpu.MilliValue()
+		sample.Memory += memory.Value()
+	}
+	return sample, nil
+}
+
+// parseQuantity parses a resource quantity of a usage map, missing quantities being zero
+func parseQuantity(usage map[string]interface{}, name string) (resource.Quantity, error) {
+	value, ok := usage[name].(string)
+	if !ok {
+		return resource.Quantity{}, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid %s usage %q: %w", name, value, err)
+	}
+	return quantity, nil
+}
+
+// write sets the usage properties of the Node or Pod nodes of the samples.
+// The properties are set in place so the rest of the node is left as the
+// handlers wrote it.
+func (p *Poller) write(ctx context.Context, label string, samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, 0, len(samples))
+	for _, sample := range samples {
+		rows = append(rows, map[string]interface{}{
+			"namespace": sample.Namespace,
+			"name":      sample.Name,
+			"cpu":       sample.CPU,
+			"memory":    sample.Memory,
+			"sampledAt": sample.SampledAt,
+		})
+	}
+
+	match := "MATCH (n:Node {name: row.name, clusterName: $clusterName})"
+	if label == "Pod" {
+		match = "MATCH (n:Pod {namespace: row.namespace, name: row.name, clusterName: $clusterName})"
+	}
+	_, err := p.neo4jClient.ExecuteWrite(ctx, func(tx driverneo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			UNWIND $rows AS row
+			`+match+`
+			SET n.usageCPU = row.cpu, n.usageMemory = row.memory, n.sampledAt = row.sampledAt`,
+			map[string]interface{}{"rows": rows, "clusterName": p.config.Kubernetes.ClusterName})
+		return nil, err
+	})
+	if err != nil {
+		return err
+	}
+	usageSamplesTotal.WithLabelValues(label).Add(float64(len(samples)))
+	return nil
+}
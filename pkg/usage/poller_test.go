@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSampleFromNodeMetrics(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata":  map[string]interface{}{"name": "worker-1"},
+		"timestamp": "2024-05-01T10:00:00Z",
+		"usage":     map[string]interface{}{"cpu": "1250m", "memory": "2Gi"},
+	}}
+
+	sample, err := sampleFromMetrics(obj)
+	if err != nil {
+		t.Fatalf("Failed to convert node metrics: %v", err)
+	}
+	if sample.Name != "worker-1" || sample.Namespace != "" {
+		t.Errorf("Expected node worker-1, got %s/%s", sample.Namespace, sample.Name)
+	}
+	if sample.CPU != 1250 {
+		t.Errorf("Expected 1250 millicores, got %d", sample.CPU)
+	}
+	if sample.Memory != 2*1024*1024*1024 {
+		t.Errorf("Expected 2Gi in bytes, got %d", sample.Memory)
+	}
+	if sample.SampledAt != "2024-05-01T10:00:00Z" {
+		t.Errorf("Expected the metrics timestamp, got %s", sample.SampledAt)
+	}
+}
+
+func TestSampleFromPodMetricsSumsContainers(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "api-0", "namespace": "shop"},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "api", "usage": map[string]interface{}{"cpu": "120500000n", "memory": "64Mi"}},
+			map[string]interface{}{"name": "sidecar", "usage": map[string]interface{}{"cpu": "5m", "memory": "16Mi"}},
+		},
+	}}
+
+	sample, err := sampleFromMetrics(obj)
+	if err != nil {
+		t.Fatalf("Failed to convert pod metrics: %v", err)
+	}
+	if sample.Namespace != "shop" || sample.Name != "api-0" {
+		t.Errorf("Expected pod shop/api-0, got %s/%s", sample.Namespace, sample.Name)
+	}
+	// 120500000n rounds up to 121m
+	if sample.CPU != 126 {
+		t.Errorf("Expected 126 millicores, got %d", sample.CPU)
+	}
+	if sample.Memory != 80*1024*1024 {
+		t.Errorf("Expected 80Mi in bytes, got %d", sample.Memory)
+	}
+	if sample.SampledAt == "" {
+		t.Error("Expected sampledAt to default to the current time")
+	}
+}
+
+func TestSampleFromMetricsRejectsInvalidQuantities(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "worker-1"},
+		"usage":    map[string]interface{}{"cpu": "lots"},
+	}}
+	if _, err := sampleFromMetrics(obj); err == nil {
+		t.Error("Expected an error for an invalid quantity")
+	}
+}
@@ -0,0 +1,75 @@
+package vulns
+
+import "strings"
+
+// dockerHubRegistry is the registry of images without a registry host
+const dockerHubRegistry = "docker.io"
+
+// ImageRef is an image reference split into its parts
+type ImageRef struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImage splits an image reference following the Docker rules: the first
+// path component is only a registry host if it contains a "." or ":" or is
+// "localhost", official Docker Hub images live under "library/", and an image
+// without a tag or digest is "latest".
+func ParseImage(image string) ImageRef {
+	var ref ImageRef
+	image = strings.TrimSpace(image)
+	if at := strings.Index(image, "@"); at >= 0 {
+		ref.Digest = image[at+1:]
+		image = image[:at]
+	}
+	// A ":" after the last "/" separates the tag, one before it a registry port
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		ref.Tag = image[colon+1:]
+		image = image[:colon]
+	}
+
+	ref.Registry = dockerHubRegistry
+	if slash := strings.Index(image, "/"); slash >= 0 {
+		host := image[:slash]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = strings.ToLower(host)
+			image = image[slash+1:]
+		}
+	}
+	switch ref.Registry {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		ref.Registry = dockerHubRegistry
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(image, "/") {
+		image = "library/" + image
+	}
+	ref.Repository = image
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref
+}
+
+// String returns the canonical reference. The digest identifies the content
+// that runs, so it wins over the tag when both are set.
+func (r ImageRef) String() string {
+	name := r.Registry + "/" + r.Repository
+	if r.Digest != "" {
+		return name + "@" + r.Digest
+	}
+	return name + ":" + r.Tag
+}
+
+// NormalizeImage returns the canonical form of an image reference, so the
+// images of pods and scanner reports written differently match
+func NormalizeImage(image string) string {
+	return ParseImage(image).String()
+}
+
+// ImageKey identifies the Image node of a normalized reference within a cluster
+func ImageKey(clusterName, ref string) string {
+	return clusterName + "/" + ref
+}
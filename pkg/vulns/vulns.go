@@ -0,0 +1,265 @@
+package vulns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s-graph/pkg/graph"
+)
+
+// Severities are the severity levels counted on Image nodes, most severe first
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "UNKNOWN"}
+
+// topVulnerabilityCount is the number of vulnerability IDs kept on an Image node
+const topVulnerabilityCount = 10
+
+// Vulnerability is a single finding of a scanner
+type Vulnerability struct {
+	ID           string
+	Severity     string
+	Package      string
+	FixedVersion string
+}
+
+// Report is the vulnerability report of one image
+type Report struct {
+	Image           string
+	Scanner         string
+	ScannedAt       time.Time
+	Vulnerabilities []Vulnerability
+	// Counts overrides the counts computed from Vulnerabilities, for
+	// reports that only carry a summary
+	Counts map[string]int
+}
+
+// SeverityCounts returns the number of vulnerabilities per severity
+func (r Report) SeverityCounts() map[string]int {
+	counts := make(map[string]int, len(Severities))
+	for _, severity := range Severities {
+		counts[severity] = 0
+	}
+	if r.Counts != nil {
+		for severity, count := range r.Counts {
+			counts[normalizeSeverity(severity)] += count
+		}
+		return counts
+	}
+	for _, vuln := range r.Vulnerabilities {
+		counts[normalizeSeverity(vuln.Severity)]++
+	}
+	return counts
+}
+
+// FixableCount returns the number of vulnerabilities with a fixed version
+func (r Report) FixableCount() int {
+	fixable := 0
+	for _, vuln := range r.Vulnerabilities {
+		if vuln.FixedVersion != "" {
+			fixable++
+		}
+	}
+	return fixable
+}
+
+// TopVulnerabilities returns the IDs of the most severe vulnerabilities,
+// without duplicates
+func (r Report) TopVulnerabilities() []string {
+	sorted := make([]Vulnerability, len(r.Vulnerabilities))
+	copy(sorted, r.Vulnerabilities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return severityRank(sorted[i].Severity) < severityRank(sorted[j].Severity)
+	})
+	seen := make(map[string]bool)
+	ids := make([]string, 0, topVulnerabilityCount)
+	for _, vuln := range sorted {
+		if len(ids) == topVulnerabilityCount {
+			break
+		}
+		if vuln.ID == "" || seen[vuln.ID] {
+			continue
+		}
+		seen[vuln.ID] = true
+		ids = append(ids, vuln.ID)
+	}
+	return ids
+}
+
+func normalizeSeverity(severity string) string {
+	severity = strings.ToUpper(strings.TrimSpace(severity))
+	if severity == "NEGLIGIBLE" {
+		return "LOW"
+	}
+	for _, known := range Severities {
+		if severity == known {
+			return severity
+		}
+	}
+	return "UNKNOWN"
+}
+
+func severityRank(severity string) int {
+	normalized := normalizeSeverity(severity)
+	for i, known := range Severities {
+		if normalized == known {
+			return i
+		}
+	}
+	return len(Severities)
+}
+
+// ParseReports decodes a Trivy or Grype JSON report. Trivy reports are
+// recognized by their ArtifactName and Grype reports by their matches.
+func ParseReports(data []byte) ([]Report, error) {
+	var probe struct {
+		ArtifactName *string          `json:"ArtifactName"`
+		Matches      *json.RawMessage `json:"matches"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch {
+	case probe.ArtifactName != nil:
+		report, err := parseTrivy(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Report{report}, nil
+	case probe.Matches != nil:
+		report, err := parseGrype(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Report{report}, nil
+	}
+	return nil, fmt.Errorf("unrecognized report: expected a Trivy or Grype JSON report")
+}
+
+func parseTrivy(data []byte) (Report, error) {
+	var trivy struct {
+		ArtifactName string    `json:"ArtifactName"`
+		ArtifactType string    `json:"ArtifactType"`
+		CreatedAt    time.Time `json:"CreatedAt"`
+		Results      []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID string `json:"VulnerabilityID"`
+				PkgName         string `json:"PkgName"`
+				FixedVersion    string `json:"FixedVersion"`
+				Severity        string `json:"Severity"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(data, &trivy); err != nil {
+		return Report{}, fmt.Errorf("invalid Trivy report: %w", err)
+	}
+	if trivy.ArtifactType != "" && trivy.ArtifactType != "container_image" {
+		return Report{}, fmt.Errorf("unsupported Trivy artifact type %q: only container images can be linked to Image nodes", trivy.ArtifactType)
+	}
+	report := Report{Image: trivy.ArtifactName, Scanner: "trivy", ScannedAt: trivy.CreatedAt}
+	for _, result := range trivy.Results {
+		for _, vuln := range result.Vulnerabilities {
+			report.Vulnerabilities = append(report.Vulnerabilities, Vulnerability{
+				ID:           vuln.VulnerabilityID,
+				Severity:     vuln.Severity,
+				Package:      vuln.PkgName,
+				FixedVersion: vuln.FixedVersion,
+			})
+		}
+	}
+	return report, nil
+}
+
+func parseGrype(data []byte) (Report, error) {
+	var grype struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string `json:"id"`
+				Severity string `json:"severity"`
+				Fix      struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name string `json:"name"`
+			} `json:"artifact"`
+		} `json:"matches"`
+		Source struct {
+			Type   string `json:"type"`
+			Target struct {
+				UserInput string `json:"userInput"`
+			} `json:"target"`
+		} `json:"source"`
+		Descriptor struct {
+			Timestamp time.Time `json:"timestamp"`
+		} `json:"descriptor"`
+	}
+	if err := json.Unmarshal(data, &grype); err != nil {
+		return Report{}, fmt.Errorf("invalid Grype report: %w", err)
+	}
+	if grype.Source.Type != "" && grype.Source.Type != "image" {
+		return Report{}, fmt.Errorf("unsupported Grype source type %q: only container images can be linked to Image nodes", grype.Source.Type)
+	}
+	report := Report{Image: grype.Source.Target.UserInput, Scanner: "grype", ScannedAt: grype.Descriptor.Timestamp}
+	for _, match := range grype.Matches {
+		vuln := Vulnerability{
+			ID:       match.Vulnerability.ID,
+			Severity: match.Vulnerability.Severity,
+			Package:  match.Artifact.Name,
+		}
+		if len(match.Vulnerability.Fix.Versions) > 0 {
+			vuln.FixedVersion = match.Vulnerability.Fix.Versions[0]
+		}
+		report.Vulnerabilities = append(report.Vulnerabilities, vuln)
+	}
+	return report, nil
+}
+
+// Properties returns the vulnerability properties written to the Image node
+// of a report
+func (r Report) Properties() map[string]interface{} {
+	counts := r.SeverityCounts()
+	scannedAt := r.ScannedAt
+	if scannedAt.IsZero() {
+		scannedAt = time.Now()
+	}
+	top, _ := json.Marshal(r.TopVulnerabilities())
+	return map[string]interface{}{
+		"criticalCount":      counts["CRITICAL"],
+		"highCount":          counts["HIGH"],
+		"mediumCount":        counts["MEDIUM"],
+		"lowCount":           counts["LOW"],
+		"unknownCount":       counts["UNKNOWN"],
+		"fixableCount":       r.FixableCount(),
+		"topVulnerabilities": string(top),
+		"scanner":            r.Scanner,
+		"scannedAt":          scannedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// Write attaches the counts of a report to the Image node of its image in a
+// cluster, creating the node if no pod runs the image yet
+func Write(ctx context.Context, neo4jClient graph.Store, clusterName string, report Report) error {
+	if report.Image == "" {
+		return fmt.Errorf("report has no image")
+	}
+	ref := NormalizeImage(report.Image)
+	err := neo4jClient.Write(ctx, graph.Statement{
+		Query: `
+			MERGE (i:Image {key: $key})
+			ON CREATE SET i.name = $name, i.clusterName = $clusterName
+			SET i += $properties`,
+		Params: map[string]interface{}{
+			"key":         ImageKey(clusterName, ref),
+			"name":        ref,
+			"clusterName": clusterName,
+			"properties":  report.Properties(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write vulnerabilities of image %s: %w", ref, err)
+	}
+	return nil
+}
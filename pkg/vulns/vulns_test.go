@@ -0,0 +1,109 @@
+package vulns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"nginx", "docker.io/library/nginx:latest"},
+		{"nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"index.docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"bitnami/redis:7.2", "docker.io/bitnami/redis:7.2"},
+		{"ghcr.io/org/app:v1", "ghcr.io/org/app:v1"},
+		{"Registry.Example.com:5000/team/app", "registry.example.com:5000/team/app:latest"},
+		{"localhost:5000/app:dev", "localhost:5000/app:dev"},
+		{"nginx:1.25@sha256:abc", "docker.io/library/nginx@sha256:abc"},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeImage(test.image); got != test.expected {
+			t.Errorf("NormalizeImage(%q): expected %q, got %q", test.image, test.expected, got)
+		}
+	}
+}
+
+func TestParseTrivyReport(t *testing.T) {
+	data := []byte(`{
+		"ArtifactName": "nginx:1.25",
+		"ArtifactType": "container_image",
+		"Results": [
+			{"Vulnerabilities": [
+				{"VulnerabilityID": "CVE-1", "PkgName": "openssl", "Severity": "LOW"},
+				{"VulnerabilityID": "CVE-2", "PkgName": "zlib", "Severity": "CRITICAL", "FixedVersion": "1.3"}
+			]},
+			{"Vulnerabilities": [
+				{"VulnerabilityID": "CVE-3", "PkgName": "lodash", "Severity": "HIGH", "FixedVersion": "4.17.21"},
+				{"VulnerabilityID": "CVE-2", "PkgName": "zlib", "Severity": "CRITICAL"}
+			]}
+		]
+	}`)
+
+	reports, err := ParseReports(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Scanner != "trivy" || reports[0].Image != "nginx:1.25" {
+		t.Fatalf("Unexpected reports %+v", reports)
+	}
+	report := reports[0]
+	expected := map[string]int{"CRITICAL": 2, "HIGH": 1, "MEDIUM": 0, "LOW": 1, "UNKNOWN": 0}
+	if got := report.SeverityCounts(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, got)
+	}
+	if got := report.FixableCount(); got != 2 {
+		t.Errorf("Expected 2 fixable vulnerabilities, got %d", got)
+	}
+	if got := report.TopVulnerabilities(); !reflect.DeepEqual(got, []string{"CVE-2", "CVE-3", "CVE-1"}) {
+		t.Errorf("Unexpected top vulnerabilities %v", got)
+	}
+}
+
+func TestParseGrypeReport(t *testing.T) {
+	data := []byte(`{
+		"matches": [
+			{"vulnerability": {"id": "CVE-1", "severity": "Negligible"}, "artifact": {"name": "bash"}},
+			{"vulnerability": {"id": "CVE-2", "severity": "High", "fix": {"versions": ["2.0"]}}, "artifact": {"name": "curl"}}
+		],
+		"source": {"type": "image", "target": {"userInput": "ghcr.io/org/app:v1"}},
+		"descriptor": {"timestamp": "2024-05-01T10:00:00Z"}
+	}`)
+
+	reports, err := ParseReports(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report := reports[0]
+	if report.Scanner != "grype" || report.Image != "ghcr.io/org/app:v1" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	counts := report.SeverityCounts()
+	if counts["HIGH"] != 1 || counts["LOW"] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+	if report.FixableCount() != 1 {
+		t.Errorf("Expected 1 fixable vulnerability, got %d", report.FixableCount())
+	}
+	if got := report.Properties()["scannedAt"]; got != "2024-05-01T10:00:00Z" {
+		t.Errorf("Unexpected scannedAt %v", got)
+	}
+}
+
+func TestParseReportsErrors(t *testing.T) {
+	tests := []string{
+		`{not json`,
+		`{"foo": "bar"}`,
+		`{"ArtifactName": ".", "ArtifactType": "filesystem"}`,
+		`{"matches": [], "source": {"type": "directory"}}`,
+	}
+	for _, data := range tests {
+		if _, err := ParseReports([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %s", data)
+		}
+	}
+}
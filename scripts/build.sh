@@ -13,7 +13,7 @@ echo "Git commit: $GIT_COMMIT"
 echo "Git branch: $GIT_BRANCH"
 
 # Build with version information
-go build -ldflags "-X 'kubegraph/pkg/version.GitCommit=$GIT_COMMIT' -X 'kubegraph/pkg/version.GitBranch=$GIT_BRANCH'" -o kubegraph .
+go build -ldflags "-X 'k8s-graph/pkg/version.GitCommit=$GIT_COMMIT' -X 'k8s-graph/pkg/version.GitBranch=$GIT_BRANCH'" -o kubegraph .
 
 echo "Build complete!"
 echo "Run './kubegraph --help' for usage information" 
@@ -0,0 +1,218 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"k8s-graph/pkg/kubernetes/handlers"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// workload is a Deployment with its ReplicaSet, Pods and Service, created in
+// the API server the way the controllers would
+type workload struct {
+	namespace  *corev1.Namespace
+	node       *corev1.Node
+	deployment *appsv1.Deployment
+	replicaSet *appsv1.ReplicaSet
+	pods       []*corev1.Pod
+	service    *corev1.Service
+}
+
+func createWorkload(t *testing.T, namespace string) *workload {
+	t.Helper()
+	ctx := context.Background()
+	labels := map[string]string{"app": "api"}
+	controller := true
+	owner := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: &controller}}
+	}
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "api",
+			Image: "registry.example.com/shop/api:1.2",
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}},
+		}}},
+	}
+
+	w := &workload{}
+	var err error
+	if w.namespace, err = clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	if w.node, err = clientset.CoreV1().Nodes().Create(ctx, &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace + "-worker"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if w.deployment, err = clientset.AppsV1().Deployments(namespace).Create(ctx, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Labels: labels},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create deployment: %v", err)
+	}
+	if w.replicaSet, err = clientset.AppsV1().ReplicaSets(namespace).Create(ctx, &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-7d4b9c", Labels: labels, OwnerReferences: owner("Deployment", "api", w.deployment.UID)},
+		Spec:       appsv1.ReplicaSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}, Template: template},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create replica set: %v", err)
+	}
+	for _, name := range []string{"api-7d4b9c-a", "api-7d4b9c-b"} {
+		spec := template.Spec
+		spec.NodeName = w.node.Name
+		pod, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, OwnerReferences: owner("ReplicaSet", w.replicaSet.Name, w.replicaSet.UID)},
+			Spec:       spec,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create pod: %v", err)
+		}
+		w.pods = append(w.pods, pod)
+	}
+	if w.service, err = clientset.CoreV1().Services(namespace).Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "api"},
+		Spec:       corev1.ServiceSpec{Selector: labels, Ports: []corev1.ServicePort{{Port: 80}}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	return w
+}
+
+// handle runs HandleCreate of the handler on every object
+func handle(t *testing.T, handler handlers.ResourceHandler, objects ...interface{}) {
+	t.Helper()
+	for _, obj := range objects {
+		if err := handler.HandleCreate(context.Background(), obj, graphClient); err != nil {
+			t.Fatalf("%s handler failed: %v", handler.GetKind(), err)
+		}
+	}
+}
+
+func TestWorkloadGraph(t *testing.T) {
+	resetGraph(t)
+	w := createWorkload(t, "shop")
+
+	// Pods are handled before their ReplicaSet, as happens on a cold start,
+	// so their OWNED_BY relationships wait for the ReplicaSet node
+	handle(t, handlers.NewNamespaceHandler(cfg), w.namespace)
+	handle(t, handlers.NewNodeHandler(cfg), w.node)
+	handle(t, handlers.NewPodHandler(clientset, cfg), w.pods[0], w.pods[1])
+	handle(t, handlers.NewReplicaSetHandler(clientset, cfg), w.replicaSet)
+	handle(t, handlers.NewDeploymentHandler(clientset, cfg), w.deployment)
+	handle(t, handlers.NewServiceHandler(clientset, cfg), w.service)
+
+	params := map[string]interface{}{"clusterName": clusterName, "namespace": "shop"}
+	expectCount(t, "pods", 2,
+		`MATCH (p:Pod {namespace: $namespace, clusterName: $clusterName}) RETURN count(p)`, params)
+	expectCount(t, "pods scheduled on the node", 2,
+		`MATCH (:Pod {namespace: $namespace})-[:SCHEDULED_ON]->(n:Node) RETURN count(n)`, params)
+	expectCount(t, "pods owned by the replica set", 2,
+		`MATCH (:Pod {namespace: $namespace})-[:OWNED_BY]->(rs:ReplicaSet) RETURN count(rs)`, params)
+	expectCount(t, "replica sets owned by the deployment", 1,
+		`MATCH (:ReplicaSet {namespace: $namespace})-[:OWNED_BY]->(d:Deployment) RETURN count(d)`, params)
+	expectCount(t, "pods managed by the replica set", 2,
+		`MATCH (:ReplicaSet {namespace: $namespace})-[:MANAGES]->(p:Pod) RETURN count(p)`, params)
+	expectCount(t, "pods managed by the deployment", 2,
+		`MATCH (:Deployment {namespace: $namespace})-[:MANAGES]->(p:Pod) RETURN count(p)`, params)
+	expectCount(t, "pods selected by the service", 2,
+		`MATCH (:Service {namespace: $namespace})-[:SELECTS]->(p:Pod) RETURN count(p)`, params)
+	expectCount(t, "images run by the pods", 1,
+		`MATCH (:Pod {namespace: $namespace})-[:RUNS_IMAGE]->(i:Image) RETURN count(DISTINCT i)`, params)
+	expectCount(t, "pods with the promoted app label", 2,
+		`MATCH (p:Pod {namespace: $namespace, label_app: 'api'}) RETURN count(p)`, params)
+	// Pod requests are stored as strings, the rollup onto the node as integers
+	expectCount(t, "millicores requested on the node", 500,
+		`MATCH (n:Node {uid: $uid}) RETURN n.requestedCPU`, map[string]interface{}{"uid": string(w.node.UID)})
+	expectCount(t, "bytes requested on the node", 2*64*1024*1024,
+		`MATCH (n:Node {uid: $uid}) RETURN n.requestedMemory`, map[string]interface{}{"uid": string(w.node.UID)})
+}
+
+func TestStatefulSetClaims(t *testing.T) {
+	resetGraph(t)
+	claim := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "db", UID: types.UID("pvc-" + name)}}
+	}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "db", UID: "sts-pg"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"app": "pg"}},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}},
+		},
+	}
+
+	// Claims are linked whether they are handled before or after their StatefulSet
+	claims := handlers.NewPVCHandler(cfg)
+	handle(t, claims, claim("data-pg-0"), claim("logs-pg-0"), claim("data-pg-x"))
+	handle(t, handlers.NewStatefulSetHandler(clientset, cfg), sts)
+	handle(t, claims, claim("data-pg-1"))
+
+	params := map[string]interface{}{"uid": "sts-pg"}
+	expectCount(t, "claims of the volumeClaimTemplates", 2,
+		`MATCH (pvc:PersistentVolumeClaim)-[:USED_BY]->(:StatefulSet {uid: $uid})
+		 WHERE pvc.name IN ['data-pg-0', 'data-pg-1'] RETURN count(pvc)`, params)
+	expectCount(t, "claims used by the StatefulSet", 2,
+		`MATCH (pvc:PersistentVolumeClaim)-[:USED_BY]->(:StatefulSet {uid: $uid}) RETURN count(pvc)`, params)
+}
+
+func TestCreatePromotedIndexes(t *testing.T) {
+	// Creating the indexes again is a no-op
+	for i := 0; i < 2; i++ {
+		if err := graphClient.CreatePromotedIndexes(context.Background(), []string{"Pod", "Deployment"}); err != nil {
+			t.Fatalf("Failed to create the promoted indexes: %v", err)
+		}
+	}
+}
+
+func TestDeleteRemovesNodeAndRelationships(t *testing.T) {
+	resetGraph(t)
+	w := createWorkload(t, "billing")
+
+	pods := handlers.NewPodHandler(clientset, cfg)
+	handle(t, handlers.NewNodeHandler(cfg), w.node)
+	handle(t, pods, w.pods[0], w.pods[1])
+	handle(t, handlers.NewServiceHandler(clientset, cfg), w.service)
+
+	if err := pods.HandleDelete(context.Background(), w.pods[0], graphClient); err != nil {
+		t.Fatalf("Pod handler failed to delete: %v", err)
+	}
+
+	params := map[string]interface{}{"namespace": "billing", "uid": string(w.pods[0].UID)}
+	expectCount(t, "deleted pods", 0, `MATCH (p:Pod {uid: $uid}) RETURN count(p)`, params)
+	expectCount(t, "remaining pods", 1, `MATCH (p:Pod {namespace: $namespace}) RETURN count(p)`, params)
+	expectCount(t, "pods selected by the service", 1,
+		`MATCH (:Service {namespace: $namespace})-[:SELECTS]->(p:Pod) RETURN count(p)`, params)
+	expectCount(t, "pods scheduled on the node", 1,
+		`MATCH (:Pod {namespace: $namespace})-[:SCHEDULED_ON]->(n:Node) RETURN count(n)`, params)
+}
+
+func TestUnchangedObjectKeepsGraph(t *testing.T) {
+	resetGraph(t)
+	w := createWorkload(t, "search")
+
+	// Handling the same objects again, as a resync does, must not duplicate
+	// nodes or relationships
+	for i := 0; i < 2; i++ {
+		handle(t, handlers.NewNodeHandler(cfg), w.node)
+		handle(t, handlers.NewPodHandler(clientset, cfg), w.pods[0], w.pods[1])
+		handle(t, handlers.NewServiceHandler(clientset, cfg), w.service)
+	}
+
+	params := map[string]interface{}{"namespace": "search"}
+	expectCount(t, "pods", 2, `MATCH (p:Pod {namespace: $namespace}) RETURN count(p)`, params)
+	expectCount(t, "nodes", 1, `MATCH (n:Node {name: "search-worker"}) RETURN count(n)`, params)
+	expectCount(t, "SELECTS relationships", 2,
+		`MATCH (:Service {namespace: $namespace})-[r:SELECTS]->(:Pod) RETURN count(r)`, params)
+}
@@ -0,0 +1,104 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s-graph/pkg/neo4j"
+)
+
+func schemaVersion(t *testing.T) int {
+	t.Helper()
+	version, err := graphClient.GetSchemaVersion(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read the schema version: %v", err)
+	}
+	return version
+}
+
+func TestMigrateEmptyGraph(t *testing.T) {
+	resetGraph(t)
+
+	applied, err := graphClient.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected an empty graph to be stamped without migrations, got %v", applied)
+	}
+	if version := schemaVersion(t); version != neo4j.SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", neo4j.SchemaVersion, version)
+	}
+}
+
+func TestMigrateUnversionedGraph(t *testing.T) {
+	resetGraph(t)
+	if _, err := graphClient.Query(context.Background(), `CREATE (:Pod {uid: "legacy"})`, nil); err != nil {
+		t.Fatalf("Failed to create a legacy node: %v", err)
+	}
+
+	applied, err := graphClient.Migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	if len(applied) != neo4j.SchemaVersion {
+		t.Errorf("Expected %d migrations, got %v", neo4j.SchemaVersion, applied)
+	}
+	if version := schemaVersion(t); version != neo4j.SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", neo4j.SchemaVersion, version)
+	}
+	expectCount(t, "meta nodes", 1, `MATCH (m:KubeGraphMeta) RETURN count(m)`, nil)
+
+	// Migrating again is a no-op
+	if applied, err := graphClient.Migrate(context.Background()); err != nil || len(applied) != 0 {
+		t.Errorf("Expected no migration on a current graph, got %v, %v", applied, err)
+	}
+}
+
+func TestMigrateRejectsNewerGraph(t *testing.T) {
+	resetGraph(t)
+	if _, err := graphClient.Query(context.Background(), `CREATE (:KubeGraphMeta {id: "schema", schemaVersion: $version})`,
+		map[string]interface{}{"version": neo4j.SchemaVersion + 1}); err != nil {
+		t.Fatalf("Failed to create the meta node: %v", err)
+	}
+
+	if _, err := graphClient.Migrate(context.Background()); !errors.Is(err, neo4j.ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestMigrateKeepsClaimRelationshipsTheHandlersWrite(t *testing.T) {
+	resetGraph(t)
+	_, err := graphClient.Query(context.Background(), `
+		CREATE (:KubeGraphMeta {id: "schema", schemaVersion: 1})
+		CREATE (sts:StatefulSet {uid: "sts-1", name: "db", namespace: "shop", clusterName: "other", volumeClaimTemplates: '["data"]'})
+		CREATE (:PersistentVolumeClaim {uid: "pvc-template", name: "data-db-0", namespace: "shop", clusterName: "other"})-[:USED_BY]->(sts)
+		CREATE (owned:PersistentVolumeClaim {uid: "pvc-owned", name: "scratch", namespace: "shop", clusterName: "other"})-[:USED_BY]->(sts)
+		CREATE (owned)-[:OWNED_BY]->(sts)
+		CREATE (:PersistentVolumeClaim {uid: "pvc-enriched", name: "backup", namespace: "shop", clusterName: "other"})-[:USED_BY {enricher: "storage"}]->(sts)
+		CREATE (:PersistentVolumeClaim {uid: "pvc-guessed", name: "logs-db-0", namespace: "shop", clusterName: "other"})-[:USED_BY]->(sts)
+		CREATE (:PersistentVolumeClaim {uid: "pvc-namespace", name: "data-db-1", namespace: "billing", clusterName: "other"})-[:USED_BY]->(sts)`, nil)
+	if err != nil {
+		t.Fatalf("Failed to create the claims: %v", err)
+	}
+
+	if _, err := graphClient.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	rows, err := graphClient.Query(context.Background(),
+		`MATCH (pvc:PersistentVolumeClaim)-[:USED_BY]->(:StatefulSet) RETURN pvc.uid AS uid ORDER BY uid`, nil)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var kept []string
+	for _, row := range rows {
+		kept = append(kept, row["uid"].(string))
+	}
+	if expected := []string{"pvc-enriched", "pvc-owned", "pvc-template"}; fmt.Sprint(kept) != fmt.Sprint(expected) {
+		t.Errorf("Expected the relationships of %v to be kept, got %v", expected, kept)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s-graph/config"
+	"k8s-graph/pkg/kubernetes/handlers"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPruneGenerations(t *testing.T) {
+	resetGraph(t)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "rollouts", UID: "deployment-api"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+	}
+	handle(t, handlers.NewDeploymentHandler(nil, cfg), deployment)
+
+	// Five rollouts, oldest first. The oldest still runs a replica, so it is
+	// kept although it is beyond the limit.
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	replicaSets := handlers.NewReplicaSetHandler(nil, cfg)
+	for i, replicas := range []int32{1, 0, 0, 0, 2} {
+		replicas := replicas
+		handle(t, replicaSets, &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("api-%d", i),
+				Namespace:         "rollouts",
+				UID:               types.UID(fmt.Sprintf("rs-%d", i)),
+				CreationTimestamp: metav1.NewTime(created.Add(time.Duration(i) * time.Hour)),
+				OwnerReferences:   []metav1.OwnerReference{{Kind: "Deployment", Name: "api", UID: deployment.UID}},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			},
+		})
+	}
+
+	rules := []config.RetentionRule{{Kind: "ReplicaSet", KeepGenerations: 2}}
+	if err := graphClient.PruneGenerations(context.Background(), rules); err != nil {
+		t.Fatalf("Failed to prune generations: %v", err)
+	}
+
+	expectCount(t, "remaining replica sets", 3,
+		`MATCH (rs:ReplicaSet) WHERE rs.uid IN ['rs-0', 'rs-3', 'rs-4'] RETURN count(rs)`, nil)
+	expectCount(t, "pruned replica sets", 0,
+		`MATCH (rs:ReplicaSet) WHERE rs.uid IN ['rs-1', 'rs-2'] RETURN count(rs)`, nil)
+}